@@ -0,0 +1,168 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package kivik
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+func (c *Client) backuper() (driver.Backuper, error) {
+	b, ok := c.driverClient.(driver.Backuper)
+	if !ok {
+		return nil, errBackupNotImplemented
+	}
+	return b, nil
+}
+
+// Backup writes a consistent snapshot of all databases to w, while the
+// client remains in use. The format is driver-specific, and may only be
+// restored by [Client.Restore] with the same driver. For a portable backup of
+// a single database, see [DB.Export].
+func (c *Client) Backup(ctx context.Context, w io.Writer, options ...Option) error {
+	endQuery, err := c.startQuery()
+	if err != nil {
+		return err
+	}
+	defer endQuery()
+	b, err := c.backuper()
+	if err != nil {
+		return err
+	}
+	return b.Backup(ctx, w, multiOptions(options))
+}
+
+// Restore replaces all databases with the snapshot read from r, as written
+// by [Client.Backup].
+func (c *Client) Restore(ctx context.Context, r io.Reader, options ...Option) error {
+	endQuery, err := c.startQuery()
+	if err != nil {
+		return err
+	}
+	defer endQuery()
+	b, err := c.backuper()
+	if err != nil {
+		return err
+	}
+	return b.Restore(ctx, r, multiOptions(options))
+}
+
+// importBatchSize is the number of documents written by each call to
+// [DB.BulkDocs] during [DB.Import].
+const importBatchSize = 100
+
+// Export writes every leaf revision of every document in the database to w,
+// including deleted documents, as newline-delimited JSON. Each line is a
+// document, with its revision history in the _revisions field, and its
+// attachments inline, as returned by [DB.OpenRevs] with the revs and
+// attachments options. Local documents are not exported.
+//
+// The output may be restored into a database of any driver with [DB.Import].
+// Unlike [Client.Backup], documents changed while the export runs may or may
+// not be included.
+func (db *DB) Export(ctx context.Context, w io.Writer) error {
+	if db.err != nil {
+		return db.err
+	}
+	// The document IDs are read first, so that the changes feed is not held
+	// open while the documents are read.
+	var docIDs []string
+	changes := db.Changes(ctx)
+	for changes.Next() {
+		docIDs = append(docIDs, changes.ID())
+	}
+	if err := changes.Err(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, docID := range docIDs {
+		rs := db.OpenRevs(ctx, docID, []string{"all"}, Params(map[string]any{
+			"revs":        true,
+			"attachments": true,
+		}))
+		for rs.Next() {
+			var doc json.RawMessage
+			if err := rs.ScanDoc(&doc); err != nil {
+				_ = rs.Close()
+				return err
+			}
+			buf.Reset()
+			if err := json.Compact(&buf, doc); err != nil {
+				_ = rs.Close()
+				return err
+			}
+			buf.WriteByte('\n')
+			if _, err := w.Write(buf.Bytes()); err != nil {
+				_ = rs.Close()
+				return err
+			}
+		}
+		// The document may have been purged since the changes were read.
+		if err := rs.Err(); err != nil && HTTPStatus(err) != http.StatusNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import writes the documents read from r, as written by [DB.Export], to the
+// database. Revisions are written with new_edits=false, so revision IDs and
+// histories are preserved, and revisions which already exist are ignored.
+func (db *DB) Import(ctx context.Context, r io.Reader) error {
+	if db.err != nil {
+		return db.err
+	}
+	dec := json.NewDecoder(r)
+	batch := make([]any, 0, importBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := db.BulkDocs(ctx, batch, Param("new_edits", false))
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if result.Error != nil {
+				return fmt.Errorf("import %s: %w", result.ID, result.Error)
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		var doc json.RawMessage
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return flush()
+		}
+		if err != nil {
+			return &internal.Error{Status: http.StatusBadRequest, Err: fmt.Errorf("invalid export: %w", err)}
+		}
+		batch = append(batch, doc)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package kivik
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+func TestBackup(t *testing.T) {
+	type tt struct {
+		client *Client
+		want   string
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("non-Backuper", tt{
+		client: &Client{
+			driverClient: &mock.Client{},
+		},
+		status: http.StatusNotImplemented,
+		err:    "kivik: driver does not support backup and restore",
+	})
+	tests.Add("error", tt{
+		client: &Client{
+			driverClient: &mock.Backuper{
+				BackupFunc: func(context.Context, io.Writer, driver.Options) error {
+					return errors.New("backup error")
+				},
+			},
+		},
+		status: http.StatusInternalServerError,
+		err:    "backup error",
+	})
+	tests.Add("success", tt{
+		client: &Client{
+			driverClient: &mock.Backuper{
+				BackupFunc: func(_ context.Context, w io.Writer, _ driver.Options) error {
+					_, err := io.WriteString(w, "snapshot")
+					return err
+				},
+			},
+		},
+		want: "snapshot",
+	})
+	tests.Add("closed", tt{
+		client: &Client{
+			closed: true,
+		},
+		status: http.StatusServiceUnavailable,
+		err:    "kivik: client closed",
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		var buf bytes.Buffer
+		err := tt.client.Backup(context.Background(), &buf)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("Unexpected backup: %q", got)
+		}
+	})
+}
+
+func TestRestore(t *testing.T) {
+	type tt struct {
+		client *Client
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("non-Backuper", tt{
+		client: &Client{
+			driverClient: &mock.Client{},
+		},
+		status: http.StatusNotImplemented,
+		err:    "kivik: driver does not support backup and restore",
+	})
+	tests.Add("success", tt{
+		client: &Client{
+			driverClient: &mock.Backuper{
+				RestoreFunc: func(_ context.Context, r io.Reader, _ driver.Options) error {
+					got, err := io.ReadAll(r)
+					if string(got) != "snapshot" {
+						return errors.New("unexpected snapshot")
+					}
+					return err
+				},
+			},
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		err := tt.client.Restore(context.Background(), strings.NewReader("snapshot"))
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+	})
+}
+
+func TestDBImport(t *testing.T) {
+	type tt struct {
+		db     *DB
+		input  string
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("invalid JSON", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.BulkDocer{},
+		},
+		input:  "{\"_id\":\"a\"}\nnot json\n",
+		status: http.StatusBadRequest,
+		err:    "invalid export: invalid character 'o' in literal null (expecting 'u')",
+	})
+	tests.Add("batches", func() any {
+		wantSizes := []int{importBatchSize, 1}
+		return tt{
+			db: &DB{
+				client: &Client{},
+				driverDB: &mock.BulkDocer{
+					BulkDocsFunc: func(_ context.Context, docs []any, options driver.Options) ([]driver.BulkResult, error) {
+						opts := map[string]any{}
+						options.Apply(opts)
+						if opts["new_edits"] != false {
+							return nil, errors.New("expected new_edits=false")
+						}
+						if len(wantSizes) == 0 || len(docs) != wantSizes[0] {
+							return nil, fmt.Errorf("unexpected batch of %d documents", len(docs))
+						}
+						wantSizes = wantSizes[1:]
+						return nil, nil
+					},
+				},
+			},
+			input: strings.Repeat("{\"_id\":\"a\",\"_rev\":\"1-abc\"}\n", importBatchSize+1),
+		}
+	})
+	tests.Add("document error", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.BulkDocer{
+				BulkDocsFunc: func(context.Context, []any, driver.Options) ([]driver.BulkResult, error) {
+					return []driver.BulkResult{
+						{ID: "a", Error: &internal.Error{Status: http.StatusForbidden, Message: "forbidden"}},
+					}, nil
+				},
+			},
+		},
+		input:  `{"_id":"a","_rev":"1-abc"}`,
+		status: http.StatusForbidden,
+		err:    "import a: forbidden",
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		err := tt.db.Import(context.Background(), strings.NewReader(tt.input))
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+	})
+}
@@ -50,6 +50,21 @@ func (d *db) PutAttachment(ctx context.Context, docID string, att *driver.Attach
 	chttpOpts.Body = att.Content
 	chttpOpts.ContentType = att.ContentType
 	chttpOpts.Query = query
+	// The header may belong to the caller, so don't modify it in place.
+	chttpOpts.Header = chttpOpts.Header.Clone()
+	if chttpOpts.Header == nil {
+		chttpOpts.Header = http.Header{}
+	}
+	// Give the server a chance to reject the upload, such as for a rev
+	// conflict or an authorization failure, before the body is sent.
+	chttpOpts.Header.Set("Expect", "100-continue")
+	if att.Size > 0 {
+		// The length of a compressed body isn't known in advance, so a known
+		// size is sent as-is, in favor of compression. See the package
+		// documentation.
+		chttpOpts.ContentLength = att.Size
+		chttpOpts.NoGzip = true
+	}
 	err = d.DoJSON(ctx, http.MethodPut, d.path(chttp.EncodeDocID(docID)+"/"+att.Filename), chttpOpts, &response)
 	if err != nil {
 		return "", err
@@ -71,7 +86,16 @@ func (d *db) GetAttachment(ctx context.Context, docID, filename string, options
 	if err != nil {
 		return nil, err
 	}
-	return decodeAttachment(resp)
+	att, err := decodeAttachment(resp)
+	if err != nil {
+		return nil, err
+	}
+	attOpts := &attachmentOptions{end: -1}
+	options.Apply(attOpts)
+	if attOpts.maxResumes > 0 {
+		att.Content = d.newResumableReader(ctx, docID, filename, options, attOpts, resp)
+	}
+	return att, nil
 }
 
 func (d *db) fetchAttachment(ctx context.Context, method, docID, filename string, options driver.Options) (*http.Response, error) {
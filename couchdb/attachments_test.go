@@ -25,6 +25,7 @@ import (
 	"gitlab.com/flimzy/testy"
 
 	kivik "github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/couchdb/chttp"
 	"github.com/go-kivik/kivik/v4/driver"
 	internal "github.com/go-kivik/kivik/v4/int/errors"
 	"github.com/go-kivik/kivik/v4/int/mock"
@@ -42,6 +43,16 @@ func (c *closer) Close() error {
 	return nil
 }
 
+// headerOption sets the request header to the provided map, without copying
+// it.
+type headerOption http.Header
+
+func (o headerOption) Apply(target any) {
+	if t, ok := target.(*chttp.Options); ok {
+		t.Header = http.Header(o)
+	}
+}
+
 func TestPutAttachment(t *testing.T) {
 	type paoTest struct {
 		name    string
@@ -212,6 +223,69 @@ func TestPutAttachment(t *testing.T) {
 			status: http.StatusBadGateway,
 			err:    "success",
 		},
+		{
+			name: "known size",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if err := consume(req.Body); err != nil {
+					return nil, err
+				}
+				if req.ContentLength != 13 {
+					return nil, fmt.Errorf("Unexpected Content-Length: %d", req.ContentLength)
+				}
+				if ce := req.Header.Get("Content-Encoding"); ce != "" {
+					return nil, fmt.Errorf("Unexpected Content-Encoding: %s", ce)
+				}
+				if expect := req.Header.Get("Expect"); expect != "100-continue" {
+					return nil, fmt.Errorf("Unexpected Expect: %s", expect)
+				}
+				return nil, errors.New("success")
+			}),
+			id: "foo",
+			att: &driver.Attachment{
+				Filename:    "foo.txt",
+				ContentType: "text/plain",
+				Content:     Body("Hello, World!"),
+				Size:        13,
+			},
+			options: kivik.Rev("1-xxx"),
+			status:  http.StatusBadGateway,
+			err:     "success",
+		},
+		func() paoTest {
+			header := http.Header{"X-Foo": []string{"bar"}}
+			return paoTest{
+				name: "caller's header not modified",
+				db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+					if err := consume(req.Body); err != nil {
+						return nil, err
+					}
+					if foo := req.Header.Get("X-Foo"); foo != "bar" {
+						return nil, fmt.Errorf("Unexpected X-Foo: %s", foo)
+					}
+					return &http.Response{
+						StatusCode: http.StatusCreated,
+						Header:     http.Header{"Content-Type": {"application/json"}},
+						Body:       Body(`{"ok":true,"id":"foo","rev":"2-yyy"}`),
+					}, nil
+				}),
+				id: "foo",
+				att: &driver.Attachment{
+					Filename:    "foo.txt",
+					ContentType: "text/plain",
+					Content:     Body("x"),
+				},
+				options: multiOptions{
+					kivik.Rev("1-xxx"),
+					headerOption(header),
+				},
+				newRev: "2-yyy",
+				final: func(t *testing.T) { //nolint:thelper // Not a helper
+					if len(header) != 1 {
+						t.Errorf("Caller's header was modified: %v", header)
+					}
+				},
+			}
+		}(),
 		func() paoTest {
 			body := &closer{Reader: strings.NewReader("x")}
 			return paoTest{
@@ -528,6 +602,36 @@ func TestFetchAttachment(t *testing.T) {
 			status:   http.StatusBadGateway,
 			err:      "success",
 		},
+		{
+			name: "range",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if rng := req.Header.Get("Range"); rng != "bytes=10-19" {
+					return nil, fmt.Errorf("Unexpected Range: %s", rng)
+				}
+				return nil, errors.New("success")
+			}),
+			method:   "GET",
+			id:       "foo",
+			filename: "foo.txt",
+			options:  OptionRange(10, 19),
+			status:   http.StatusBadGateway,
+			err:      "success",
+		},
+		{
+			name: "open range",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if rng := req.Header.Get("Range"); rng != "bytes=10-" {
+					return nil, fmt.Errorf("Unexpected Range: %s", rng)
+				}
+				return nil, errors.New("success")
+			}),
+			method:   "GET",
+			id:       "foo",
+			filename: "foo.txt",
+			options:  OptionRange(10, -1),
+			status:   http.StatusBadGateway,
+			err:      "success",
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
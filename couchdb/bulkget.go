@@ -38,6 +38,7 @@ func (d *db) BulkGet(ctx context.Context, docs []driver.BulkGetReference, option
 		Header: http.Header{
 			chttp.HeaderIdempotencyKey: []string{},
 		},
+		Idempotent: true,
 	}
 	resp, err := d.DoReq(ctx, http.MethodPost, d.path("_bulk_get"), chttpOpts)
 	if err != nil {
@@ -15,7 +15,6 @@ package couchdb
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"io"
 	"net/http"
 
@@ -29,15 +28,13 @@ func (d *db) Changes(ctx context.Context, options driver.Options) (driver.Change
 	opts := map[string]any{}
 	options.Apply(opts)
 	key := "results"
-	if f, ok := opts["feed"]; ok {
-		if f == "eventsource" {
-			return nil, &internal.Error{Status: http.StatusBadRequest, Err: errors.New("kivik: eventsource feed not supported, use 'continuous'")}
-		}
-		if f == "continuous" {
-			key = ""
-		}
+	feed, _ := opts["feed"].(string)
+	if feed == "continuous" || feed == "eventsource" {
+		key = ""
 	}
-	chttpOpts := new(chttp.Options)
+	chttpOpts := chttp.NewOptions(options)
+	// The changes feed is read-only, so the POST is safe to retry.
+	chttpOpts.Idempotent = true
 	if ids := opts["doc_ids"]; ids != nil {
 		delete(opts, "doc_ids")
 		chttpOpts.GetBody = chttp.BodyEncoder(map[string]any{
@@ -50,6 +47,14 @@ func (d *db) Changes(ctx context.Context, options driver.Options) (driver.Change
 		return nil, err
 	}
 
+	if feed == "eventsource" {
+		body, err := d.eventSource(ctx, http.MethodPost, d.path("_changes"), chttpOpts)
+		if err != nil {
+			return nil, err
+		}
+		return newChangesRows(ctx, key, body, ""), nil
+	}
+
 	resp, err := d.DoReq(ctx, http.MethodPost, d.path("_changes"), chttpOpts)
 	if err != nil {
 		return nil, err
@@ -100,6 +105,10 @@ func (m *changesMeta) parseMeta(key string, dec *json.Decoder) error {
 type changesRows struct {
 	*iter
 	etag string
+	// meta is nil for continuous and eventsource feeds, which have no
+	// metadata, in which case lastSeq tracks the sequence of the last change.
+	meta    *changesMeta
+	lastSeq string
 }
 
 func newChangesRows(ctx context.Context, key string, r io.ReadCloser, etag string) *changesRows {
@@ -110,6 +119,7 @@ func newChangesRows(ctx context.Context, key string, r io.ReadCloser, etag strin
 	return &changesRows{
 		iter: newIter(ctx, meta, key, r, &continuousChangesParser{}),
 		etag: etag,
+		meta: meta,
 	}
 }
 
@@ -122,17 +132,27 @@ type change struct {
 
 func (r *changesRows) Next(row *driver.Change) error {
 	row.Deleted = false
-	return r.next(row)
+	if err := r.next(row); err != nil {
+		return err
+	}
+	r.lastSeq = row.Seq
+	return nil
 }
 
 // LastSeq returns the last sequence ID.
 func (r *changesRows) LastSeq() string {
-	return string(r.iter.meta.(*changesMeta).lastSeq)
+	if r.meta == nil {
+		return r.lastSeq
+	}
+	return string(r.meta.lastSeq)
 }
 
 // Pending returns the pending count.
 func (r *changesRows) Pending() int64 {
-	return r.iter.meta.(*changesMeta).pending
+	if r.meta == nil {
+		return 0
+	}
+	return r.meta.pending
 }
 
 // ETag returns the unquoted ETag header for the CouchDB response, if any.
@@ -18,7 +18,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"gitlab.com/flimzy/testy"
@@ -78,9 +80,10 @@ func TestChanges(t *testing.T) {
 		},
 		{
 			name:    "eventsource",
+			db:      newTestDB(nil, errors.New("net error")),
 			options: kivik.Param("feed", "eventsource"),
-			status:  http.StatusBadRequest,
-			err:     "kivik: eventsource feed not supported, use 'continuous'",
+			status:  http.StatusBadGateway,
+			err:     `Post "?http://example.com/testdb/_changes\?feed=eventsource"?: net error`,
 		},
 		{
 			name:   "network error",
@@ -230,6 +233,80 @@ func TestChanges(t *testing.T) {
 	}
 }
 
+func TestChanges_eventsource(t *testing.T) {
+	var requests int
+	db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+		requests++
+		var body io.Reader
+		switch requests {
+		case 1:
+			if foo := req.Header.Get("X-Foo"); foo != "bar" {
+				return nil, fmt.Errorf("Unexpected X-Foo: %s", foo)
+			}
+			if since := req.URL.Query().Get("since"); since != "now" {
+				return nil, fmt.Errorf("Unexpected since: %s", since)
+			}
+			body = io.MultiReader(
+				strings.NewReader(`data: {"seq":"1-abc","id":"foo","changes":[{"rev":"1-xxx"}]}`+"\nid: 1-abc\n\n"),
+				iotest.ErrReader(errors.New("connection reset")),
+			)
+		case 2:
+			if foo := req.Header.Get("X-Foo"); foo != "bar" {
+				return nil, fmt.Errorf("Unexpected X-Foo on reconnect: %s", foo)
+			}
+			if id := req.Header.Get("Last-Event-ID"); id != "1-abc" {
+				return nil, fmt.Errorf("Unexpected Last-Event-ID: %s", id)
+			}
+			if since := req.URL.Query().Get("since"); since != "1-abc" {
+				return nil, fmt.Errorf("Unexpected since: %s", since)
+			}
+			body = strings.NewReader("event: heartbeat\ndata: \n\n" +
+				`data: {"seq":"2-def","id":"bar","changes":[{"rev":"1-yyy"}],"deleted":true}` + "\nid: 2-def\n\n")
+		default:
+			return nil, errors.New("unexpected request")
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/event-stream"}},
+			Body:       io.NopCloser(body),
+		}, nil
+	})
+
+	changes, err := db.Changes(context.Background(), multiOptions{
+		kivik.Params(map[string]any{
+			"feed":  "eventsource",
+			"since": "now",
+		}),
+		headerOption{"X-Foo": {"bar"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = changes.Close()
+	})
+	want := []string{"1-abc foo false", "2-def bar true"}
+	// The feed would be resumed again when the second body ends, so stop
+	// reading once the expected changes have arrived.
+	var got []string
+	for len(got) < len(want) {
+		ch := &driver.Change{}
+		if err := changes.Next(ch); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s %s %v", ch.Seq, ch.ID, ch.Deleted))
+	}
+	if d := testy.DiffInterface(want, got); d != nil {
+		t.Errorf("Unexpected changes:\n%s", d)
+	}
+	if seq := changes.LastSeq(); seq != "2-def" {
+		t.Errorf("Unexpected last seq: %s", seq)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
+
 func TestChangesNext(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -27,6 +27,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-kivik/kivik/v4"
 	"github.com/go-kivik/kivik/v4/driver"
@@ -55,6 +56,12 @@ type Client struct {
 
 	// noGzip will be set to true if the server fails on gzip-encoded requests.
 	noGzip bool
+
+	// retryPolicy, if set, enables automatic retries of failed requests.
+	retryPolicy *RetryPolicy
+
+	// limiter, if set, enforces concurrency and rate limits on requests.
+	limiter *limiter
 }
 
 // New returns a connection to a remote CouchDB server. If credentials are
@@ -247,10 +254,37 @@ func (c *Client) compressBody(path string, body io.Reader, opts *Options) (bool,
 // DoReq does an HTTP request. An error is returned only if there was an error
 // processing the request. In particular, an error status code, such as 400
 // or 500, does _not_ cause an error to be returned.
+//
+// If the client has a [RetryPolicy], requests which fail with a transient
+// error are retried when safe to do so.
 func (c *Client) DoReq(ctx context.Context, method, path string, opts *Options) (*http.Response, error) {
 	if method == "" {
 		return nil, errors.New("chttp: method required")
 	}
+	if c.retryPolicy == nil || !canRetry(method, path, opts) {
+		return c.doReq(ctx, method, path, opts)
+	}
+	for attempt := 0; ; attempt++ {
+		response, err := c.doReq(ctx, method, path, opts)
+		if attempt >= c.retryPolicy.maxRetries() || !shouldRetry(ctx, response, err) {
+			return response, err
+		}
+		delay := c.retryPolicy.backoff(attempt, response)
+		if response != nil {
+			CloseBody(response.Body)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, netError(ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// doReq sends a single HTTP request.
+func (c *Client) doReq(ctx context.Context, method, path string, opts *Options) (*http.Response, error) {
 	var body io.Reader
 	if opts != nil {
 		if opts.GetBody != nil {
@@ -282,7 +316,12 @@ func (c *Client) DoReq(ctx context.Context, method, path string, opts *Options)
 		trace.httpRequestBody(req)
 	}
 
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
 	response, err := c.Do(req)
+	holdUntilClosed(response, release)
 	if trace != nil {
 		trace.httpResponse(response)
 		trace.httpResponseBody(response)
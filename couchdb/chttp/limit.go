@@ -0,0 +1,295 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package chttp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kivik/kivik/v4"
+)
+
+// LimitStats reports the state of a client's request limits, as configured
+// with [OptionMaxConcurrentRequests] and [OptionRateLimit].
+type LimitStats struct {
+	// InFlight is the number of requests currently holding a concurrency
+	// slot.
+	InFlight int
+	// Queued is the number of requests currently waiting for a concurrency
+	// slot or a rate limit token.
+	Queued int
+	// Waits is the total number of requests which had to wait before being
+	// sent.
+	Waits int64
+	// WaitTime is the total time spent waiting by all requests.
+	WaitTime time.Duration
+	// MaxWaitTime is the longest time spent waiting by a single request.
+	MaxWaitTime time.Duration
+}
+
+// limiter enforces the concurrency and rate limits of a client.
+type limiter struct {
+	// slots is a semaphore with one entry per in-flight request. It is nil
+	// when concurrency is unlimited.
+	slots chan struct{}
+	// rate and burst configure the token bucket. rate is zero when the
+	// request rate is unlimited.
+	rate  float64
+	burst float64
+
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time
+	queued   int
+	waits    int64
+	waitTime time.Duration
+	maxWait  time.Duration
+}
+
+func (c *Client) limits() *limiter {
+	if c.limiter == nil {
+		c.limiter = &limiter{}
+	}
+	return c.limiter
+}
+
+// LimitStats returns the current state of the client's request limits.
+func (c *Client) LimitStats() LimitStats {
+	l := c.limiter
+	if l == nil {
+		return LimitStats{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return LimitStats{
+		InFlight:    len(l.slots),
+		Queued:      l.queued,
+		Waits:       l.waits,
+		WaitTime:    l.waitTime,
+		MaxWaitTime: l.maxWait,
+	}
+}
+
+// acquire waits until the client's request limits permit a request to be
+// sent, and returns a function which must be called when the request is
+// complete. The function is nil if the client has no limits.
+//
+// The session request made by cookie auth is not limited, as it is sent on
+// behalf of a request which already holds a slot. Waiting for another slot
+// could deadlock.
+func (c *Client) acquire(ctx context.Context) (func(), error) {
+	if c.limiter == nil {
+		return nil, nil
+	}
+	if inProg, _ := ctx.Value(authInProgress).(bool); inProg {
+		return nil, nil
+	}
+	release, err := c.limiter.acquire(ctx)
+	return release, netError(err)
+}
+
+// acquire waits for a concurrency slot and a rate limit token, and returns a
+// function which releases the slot. It returns an error if ctx is cancelled
+// while waiting.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	start := time.Now()
+	waited := false
+	release := func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			waited = true
+			l.setQueued(1)
+			select {
+			case l.slots <- struct{}{}:
+				l.setQueued(-1)
+			case <-ctx.Done():
+				l.setQueued(-1)
+				l.recordWait(start)
+				return nil, ctx.Err()
+			}
+		}
+		var once sync.Once
+		release = func() {
+			once.Do(func() { <-l.slots })
+		}
+	}
+	if delay := l.reserve(start); delay > 0 {
+		waited = true
+		l.setQueued(1)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			l.setQueued(-1)
+		case <-ctx.Done():
+			timer.Stop()
+			l.setQueued(-1)
+			l.unreserve()
+			release()
+			l.recordWait(start)
+			return nil, ctx.Err()
+		}
+	}
+	if waited {
+		l.recordWait(start)
+	}
+	return release, nil
+}
+
+func (l *limiter) setQueued(delta int) {
+	l.mu.Lock()
+	l.queued += delta
+	l.mu.Unlock()
+}
+
+func (l *limiter) recordWait(start time.Time) {
+	wait := time.Since(start)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.waits++
+	l.waitTime += wait
+	if wait > l.maxWait {
+		l.maxWait = wait
+	}
+}
+
+// reserve takes a token from the bucket, and returns how long the caller must
+// wait before the token may be used.
+func (l *limiter) reserve(now time.Time) time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.last.IsZero() {
+		l.tokens = l.burst
+	} else if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// unreserve returns an unused token to the bucket.
+func (l *limiter) unreserve() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// releaseOnClose wraps the response body, so that release is called when the
+// body is closed, or when it is read to completion.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseOnClose) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err == io.EOF {
+		r.release()
+	}
+	return n, err
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
+
+// holdUntilClosed arranges for release to be called once the response body
+// has been consumed. If there is no response, release is called immediately.
+// A nil release is ignored.
+func holdUntilClosed(resp *http.Response, release func()) {
+	if release == nil {
+		return
+	}
+	if resp == nil || resp.Body == nil {
+		release()
+		return
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+}
+
+type optionMaxConcurrentRequests int
+
+var _ kivik.Option = optionMaxConcurrentRequests(0)
+
+func (o optionMaxConcurrentRequests) Apply(target any) {
+	if client, ok := target.(*Client); ok && o > 0 {
+		client.limits().slots = make(chan struct{}, int(o))
+	}
+}
+
+func (o optionMaxConcurrentRequests) String() string {
+	return fmt.Sprintf("[MaxConcurrentRequests: %d]", int(o))
+}
+
+// OptionMaxConcurrentRequests limits the number of requests the client sends
+// concurrently to max. Further requests wait in a queue until a request
+// completes, or their context is cancelled. A request is complete once its
+// response body has been closed, so long-lived requests, such as continuous
+// changes feeds, hold their slot for as long as they are open. A session
+// request made by [CookieAuth] shares the slot of the request which triggered
+// it. Only honored when passed to [github.com/go-kivik/kivik/v4.New] or [New].
+func OptionMaxConcurrentRequests(max int) kivik.Option {
+	return optionMaxConcurrentRequests(max)
+}
+
+type optionRateLimit struct {
+	rate  float64
+	burst int
+}
+
+var _ kivik.Option = optionRateLimit{}
+
+func (o optionRateLimit) Apply(target any) {
+	if client, ok := target.(*Client); ok && o.rate > 0 {
+		l := client.limits()
+		l.rate = o.rate
+		l.burst = float64(o.burst)
+		if l.burst < 1 {
+			l.burst = 1
+		}
+	}
+}
+
+func (o optionRateLimit) String() string {
+	return fmt.Sprintf("[RateLimit: %g/s, burst %d]", o.rate, o.burst)
+}
+
+// OptionRateLimit limits the rate at which the client sends requests to
+// perSecond requests per second, using a token bucket which permits bursts
+// of up to burst requests. Requests which exceed the limit wait until they
+// may be sent, or until their context is cancelled. Retries are subject to
+// the limit. Only honored when passed to [github.com/go-kivik/kivik/v4.New]
+// or [New].
+func OptionRateLimit(perSecond float64, burst int) kivik.Option {
+	return optionRateLimit{rate: perSecond, burst: burst}
+}
@@ -0,0 +1,202 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package chttp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/internal/nettest"
+)
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	const max = 2
+	var mu sync.Mutex
+	var inFlight, peak int
+	release := make(chan struct{})
+	c := newCustomClient("", func(*http.Request) (*http.Response, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return &http.Response{StatusCode: http.StatusOK, Body: Body("")}, nil
+	})
+	OptionMaxConcurrentRequests(max).Apply(c)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.DoError(context.Background(), http.MethodGet, "/foo", nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	waitFor(t, func() bool {
+		stats := c.LimitStats()
+		return stats.InFlight == max && stats.Queued == 3
+	})
+	close(release)
+	wg.Wait()
+
+	if peak != max {
+		t.Errorf("Expected peak concurrency of %d, got %d", max, peak)
+	}
+	stats := c.LimitStats()
+	if stats.InFlight != 0 || stats.Queued != 0 {
+		t.Errorf("Unexpected stats after completion: %+v", stats)
+	}
+	if stats.Waits != 3 {
+		t.Errorf("Expected 3 waits, got %d", stats.Waits)
+	}
+	if stats.WaitTime <= 0 || stats.MaxWaitTime <= 0 || stats.MaxWaitTime > stats.WaitTime {
+		t.Errorf("Unexpected wait times: %+v", stats)
+	}
+}
+
+func TestMaxConcurrentRequestsHeldUntilBodyClosed(t *testing.T) {
+	c := newTestClient(&http.Response{StatusCode: http.StatusOK, Body: Body("foo")}, nil)
+	OptionMaxConcurrentRequests(1).Apply(c)
+
+	resp, err := c.DoReq(context.Background(), http.MethodGet, "/foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := c.LimitStats().InFlight; n != 1 {
+		t.Errorf("Expected 1 request in flight, got %d", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	t.Cleanup(cancel)
+	_, err = c.DoReq(ctx, http.MethodGet, "/foo", nil)
+	if d := internal.StatusErrorDiff("context deadline exceeded", http.StatusBadGateway, err); d != "" {
+		t.Error(d)
+	}
+
+	CloseBody(resp.Body)
+	if n := c.LimitStats().InFlight; n != 0 {
+		t.Errorf("Expected 0 requests in flight, got %d", n)
+	}
+}
+
+func TestMaxConcurrentRequestsCookieAuth(t *testing.T) {
+	s := nettest.NewHTTPTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/_session" {
+			w.Header().Add("Set-Cookie", "AuthSession=foo; Version=1; Path=/; HttpOnly")
+			_, _ = w.Write([]byte(`{"ok":true,"name":"admin","roles":["_admin"]}`))
+			return
+		}
+		if cookie := r.Header.Get("Cookie"); !strings.Contains(cookie, "AuthSession=foo") {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"unauthorized"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	c, err := New(&http.Client{}, s.URL, CookieAuth("admin", "abc123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	OptionMaxConcurrentRequests(1).Apply(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+	if _, err := c.DoError(ctx, http.MethodGet, "/foo", nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.LimitStats().InFlight; n != 0 {
+		t.Errorf("Expected 0 requests in flight, got %d", n)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	c := newCustomClient("", func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: Body("")}, nil
+	})
+	OptionRateLimit(100, 2).Apply(c)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := c.DoError(context.Background(), http.MethodGet, "/foo", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Two requests are sent immediately, from the burst, and the remaining
+	// two are each delayed by 10ms.
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("Requests were not rate limited; took %s", elapsed)
+	}
+	if waits := c.LimitStats().Waits; waits != 2 {
+		t.Errorf("Expected 2 waits, got %d", waits)
+	}
+}
+
+func TestRateLimitCanceled(t *testing.T) {
+	c := newTestClient(&http.Response{StatusCode: http.StatusOK, Body: Body("")}, nil)
+	OptionRateLimit(0.001, 1).Apply(c)
+
+	if _, err := c.DoError(context.Background(), http.MethodGet, "/foo", nil); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	t.Cleanup(cancel)
+	_, err := c.DoError(ctx, http.MethodGet, "/foo", nil)
+	if d := internal.StatusErrorDiff("context deadline exceeded", http.StatusBadGateway, err); d != "" {
+		t.Error(d)
+	}
+	if stats := c.LimitStats(); stats.Queued != 0 {
+		t.Errorf("Unexpected queue depth: %d", stats.Queued)
+	}
+}
+
+func TestLimiterReserve(t *testing.T) {
+	l := &limiter{rate: 10, burst: 2}
+	now := time.Now()
+	for i, want := range []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond} {
+		if got := l.reserve(now); got != want {
+			t.Errorf("reservation %d: expected delay %s, got %s", i, want, got)
+		}
+	}
+	// After a second, the bucket has refilled, but only up to the burst size.
+	now = now.Add(time.Second)
+	for i, want := range []time.Duration{0, 0, 100 * time.Millisecond} {
+		if got := l.reserve(now); got != want {
+			t.Errorf("refilled reservation %d: expected delay %s, got %s", i, want, got)
+		}
+	}
+}
+
+// waitFor polls cond until it returns true, failing the test after a second.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
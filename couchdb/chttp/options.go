@@ -66,6 +66,10 @@ type Options struct {
 
 	// NoGzip disables gzip compression on the request body.
 	NoGzip bool
+
+	// Idempotent marks a request as safe to retry, regardless of its method.
+	// See [RetryPolicy].
+	Idempotent bool
 }
 
 // NewOptions converts a kivik options map into
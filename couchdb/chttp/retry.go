@@ -0,0 +1,199 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package chttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-kivik/kivik/v4"
+)
+
+// Default values used for unset [RetryPolicy] fields.
+const (
+	DefaultMaxRetries = 3
+	DefaultMinBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// RetryPolicy configures the automatic retry of requests that fail with a
+// transient network error (a timeout, a reset or refused connection, or a
+// connection closed before the response was complete), or with a 429, 502,
+// 503 or 504 status.
+//
+// Requests with the GET, HEAD and OPTIONS methods are retried. Other requests
+// are retried only if they are safe to repeat, which is when they carry a
+// document revision, as in a PUT or DELETE with a rev, or when marked with
+// [Options.Idempotent]. In either case, a request body can only be re-sent
+// when [Options.GetBody] is set.
+//
+// Each attempt is reported to the [ClientTrace] hooks.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times a request is retried.
+	// Defaults to [DefaultMaxRetries].
+	MaxRetries int
+
+	// MinBackoff is the delay before the first retry. The delay doubles for
+	// each subsequent retry, up to MaxBackoff, and a random jitter of up to
+	// half the delay is subtracted. Defaults to [DefaultMinBackoff].
+	MinBackoff time.Duration
+
+	// MaxBackoff is the maximum delay between retries. Defaults to
+	// [DefaultMaxBackoff]. A Retry-After header sent by the server takes
+	// precedence, but is also limited to MaxBackoff.
+	MaxBackoff time.Duration
+}
+
+func (p *RetryPolicy) maxRetries() int {
+	if p.MaxRetries == 0 {
+		return DefaultMaxRetries
+	}
+	return p.MaxRetries
+}
+
+// backoff returns the delay before the retry following the given, zero-based
+// attempt.
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	minBackoff, maxBackoff := p.MinBackoff, p.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = DefaultMinBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	if delay, ok := retryAfter(resp); ok {
+		if delay > maxBackoff {
+			return maxBackoff
+		}
+		return delay
+	}
+	delay := minBackoff
+	for i := 0; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay - time.Duration(rand.Int63n(int64(delay)/2+1)) // nolint:gosec // jitter need not be secure
+}
+
+// retryAfter returns the delay requested by the response's Retry-After
+// header, which may be a number of seconds or an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// canRetry returns true if the request may safely be sent more than once.
+func canRetry(method, path string, opts *Options) bool {
+	if opts != nil && opts.Body != nil && opts.GetBody == nil {
+		return false
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	if opts != nil && opts.Idempotent {
+		return true
+	}
+	if method != http.MethodPut && method != http.MethodDelete {
+		return false
+	}
+	if opts != nil && (opts.Query.Get("rev") != "" || opts.Header.Get("If-Match") != "") {
+		return true
+	}
+	if reqURL, err := url.Parse(path); err == nil && reqURL.Query().Get("rev") != "" {
+		return true
+	}
+	return false
+}
+
+// shouldRetry returns true if the response or error indicate a transient
+// failure.
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return transientError(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// transientError returns true if err is a network error which may not recur
+// if the request is repeated. Errors such as a failed TLS handshake or an
+// unknown host are not retried.
+func transientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+type optionRetryPolicy RetryPolicy
+
+var _ kivik.Option = optionRetryPolicy{}
+
+func (o optionRetryPolicy) Apply(target any) {
+	if client, ok := target.(*Client); ok {
+		policy := RetryPolicy(o)
+		client.retryPolicy = &policy
+	}
+}
+
+func (o optionRetryPolicy) String() string {
+	return fmt.Sprintf("[RetryPolicy: %d retries, %s-%s backoff]", o.MaxRetries, o.MinBackoff, o.MaxBackoff)
+}
+
+// OptionRetryPolicy enables automatic retries of failed requests, according
+// to policy. Only honored when passed to
+// [github.com/go-kivik/kivik/v4.New] or [New].
+func OptionRetryPolicy(policy RetryPolicy) kivik.Option {
+	return optionRetryPolicy(policy)
+}
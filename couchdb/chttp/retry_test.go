@@ -0,0 +1,299 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package chttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"gitlab.com/flimzy/testy"
+
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+func TestDoReqRetry(t *testing.T) {
+	type tt struct {
+		method, path string
+		opts         *Options
+		// responses are returned in turn; a nil response returns netErr,
+		// or a connection reset if netErr is unset.
+		responses    []*http.Response
+		netErr       error
+		wantAttempts int
+		wantBodies   []string
+		status       int
+		err          string
+	}
+	resp := func(status int) *http.Response {
+		return &http.Response{StatusCode: status, Body: Body("")}
+	}
+
+	tests := testy.NewTable()
+	tests.Add("success", tt{
+		method:       http.MethodGet,
+		path:         "/foo",
+		responses:    []*http.Response{resp(http.StatusOK)},
+		wantAttempts: 1,
+	})
+	tests.Add("GET retried after 503", tt{
+		method:       http.MethodGet,
+		path:         "/foo",
+		responses:    []*http.Response{resp(http.StatusServiceUnavailable), resp(http.StatusTooManyRequests), resp(http.StatusOK)},
+		wantAttempts: 3,
+	})
+	tests.Add("GET retried after network error", tt{
+		method:       http.MethodGet,
+		path:         "/foo",
+		responses:    []*http.Response{nil, resp(http.StatusOK)},
+		wantAttempts: 2,
+	})
+	tests.Add("GET retried after timeout", tt{
+		method:       http.MethodGet,
+		path:         "/foo",
+		responses:    []*http.Response{nil, resp(http.StatusOK)},
+		netErr:       &net.DNSError{Err: "i/o timeout", IsTimeout: true},
+		wantAttempts: 2,
+	})
+	tests.Add("GET not retried after non-transient network error", tt{
+		method:       http.MethodGet,
+		path:         "/foo",
+		responses:    []*http.Response{nil, resp(http.StatusOK)},
+		netErr:       errors.New("x509: certificate signed by unknown authority"),
+		wantAttempts: 1,
+		status:       http.StatusBadGateway,
+		err:          `Get "http://example.com/foo": x509: certificate signed by unknown authority`,
+	})
+	tests.Add("retries exhausted", tt{
+		method:       http.MethodGet,
+		path:         "/foo",
+		responses:    []*http.Response{resp(http.StatusBadGateway), resp(http.StatusBadGateway), resp(http.StatusBadGateway), resp(http.StatusBadGateway)},
+		wantAttempts: 4,
+		status:       http.StatusBadGateway,
+		err:          "Bad Gateway",
+	})
+	tests.Add("client error not retried", tt{
+		method:       http.MethodGet,
+		path:         "/foo",
+		responses:    []*http.Response{resp(http.StatusNotFound), resp(http.StatusOK)},
+		wantAttempts: 1,
+		status:       http.StatusNotFound,
+		err:          "Not Found",
+	})
+	tests.Add("POST not retried", tt{
+		method:       http.MethodPost,
+		path:         "/foo",
+		opts:         &Options{GetBody: BodyEncoder(map[string]string{"a": "b"})},
+		responses:    []*http.Response{resp(http.StatusServiceUnavailable), resp(http.StatusOK)},
+		wantAttempts: 1,
+		status:       http.StatusServiceUnavailable,
+		err:          "Service Unavailable",
+	})
+	tests.Add("idempotent POST retried", tt{
+		method: http.MethodPost,
+		path:   "/foo/_find",
+		opts: &Options{
+			GetBody:    BodyEncoder(map[string]string{"a": "b"}),
+			Idempotent: true,
+			NoGzip:     true,
+		},
+		responses:    []*http.Response{resp(http.StatusServiceUnavailable), resp(http.StatusOK)},
+		wantAttempts: 2,
+		wantBodies:   []string{`{"a":"b"}` + "\n", `{"a":"b"}` + "\n"},
+	})
+	tests.Add("PUT without rev not retried", tt{
+		method:       http.MethodPut,
+		path:         "/foo/bar",
+		opts:         &Options{GetBody: BodyEncoder(map[string]string{"a": "b"})},
+		responses:    []*http.Response{resp(http.StatusServiceUnavailable), resp(http.StatusOK)},
+		wantAttempts: 1,
+		status:       http.StatusServiceUnavailable,
+		err:          "Service Unavailable",
+	})
+	tests.Add("PUT with rev retried", tt{
+		method: http.MethodPut,
+		path:   "/foo/bar",
+		opts: &Options{
+			GetBody: BodyEncoder(map[string]string{"a": "b"}),
+			Query:   url.Values{"rev": {"1-xxx"}},
+			NoGzip:  true,
+		},
+		responses:    []*http.Response{resp(http.StatusGatewayTimeout), resp(http.StatusOK)},
+		wantAttempts: 2,
+		wantBodies:   []string{`{"a":"b"}` + "\n", `{"a":"b"}` + "\n"},
+	})
+	tests.Add("PUT with rev, body can't be replayed", tt{
+		method: http.MethodPut,
+		path:   "/foo/bar",
+		opts: &Options{
+			Body:  Body("x"),
+			Query: url.Values{"rev": {"1-xxx"}},
+		},
+		responses:    []*http.Response{resp(http.StatusServiceUnavailable), resp(http.StatusOK)},
+		wantAttempts: 1,
+		status:       http.StatusServiceUnavailable,
+		err:          "Service Unavailable",
+	})
+	tests.Add("DELETE with rev in path retried", tt{
+		method:       http.MethodDelete,
+		path:         "/foo/bar?rev=1-xxx",
+		responses:    []*http.Response{resp(http.StatusServiceUnavailable), resp(http.StatusOK)},
+		wantAttempts: 2,
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		var attempts int
+		var bodies []string
+		c := newCustomClient("", func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if req.Body != nil {
+				body, err := io.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				bodies = append(bodies, string(body))
+			}
+			r := tt.responses[attempts-1]
+			if r == nil {
+				if tt.netErr != nil {
+					return nil, tt.netErr
+				}
+				return nil, syscall.ECONNRESET
+			}
+			r.Request = req
+			return r, nil
+		})
+		c.retryPolicy = &RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+		var traced int
+		ctx := WithClientTrace(context.Background(), &ClientTrace{
+			HTTPResponse: func(*http.Response) { traced++ },
+		})
+		_, err := c.DoError(ctx, tt.method, tt.path, tt.opts)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if attempts != tt.wantAttempts {
+			t.Errorf("Expected %d attempts, got %d", tt.wantAttempts, attempts)
+		}
+		if tt.wantBodies != nil {
+			if d := testy.DiffInterface(tt.wantBodies, bodies); d != nil {
+				t.Errorf("Unexpected request bodies:\n%s", d)
+			}
+		}
+		var wantTraced int
+		for _, r := range tt.responses[:attempts] {
+			if r != nil {
+				wantTraced++
+			}
+		}
+		if traced != wantTraced {
+			t.Errorf("Expected %d traced responses, got %d", wantTraced, traced)
+		}
+	})
+}
+
+func TestDoReqRetryCanceled(t *testing.T) {
+	c := newTestClient(&http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Retry-After": {"60"}},
+		Body:       Body(""),
+	}, nil)
+	c.retryPolicy = &RetryPolicy{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	t.Cleanup(cancel)
+	_, err := c.DoReq(ctx, http.MethodGet, "/foo", nil)
+	if d := internal.StatusErrorDiff("context deadline exceeded", http.StatusBadGateway, err); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	type tt struct {
+		policy   RetryPolicy
+		attempt  int
+		resp     *http.Response
+		min, max time.Duration
+	}
+
+	tests := testy.NewTable()
+	tests.Add("defaults, first attempt", tt{
+		min: DefaultMinBackoff / 2,
+		max: DefaultMinBackoff,
+	})
+	tests.Add("exponential", tt{
+		policy:  RetryPolicy{MinBackoff: time.Second, MaxBackoff: time.Minute},
+		attempt: 3,
+		min:     4 * time.Second,
+		max:     8 * time.Second,
+	})
+	tests.Add("capped", tt{
+		policy:  RetryPolicy{MinBackoff: time.Second, MaxBackoff: 5 * time.Second},
+		attempt: 10,
+		min:     2500 * time.Millisecond,
+		max:     5 * time.Second,
+	})
+	tests.Add("Retry-After seconds", tt{
+		policy: RetryPolicy{MinBackoff: time.Millisecond},
+		resp:   &http.Response{Header: http.Header{"Retry-After": {"7"}}},
+		min:    7 * time.Second,
+		max:    7 * time.Second,
+	})
+	tests.Add("Retry-After date", tt{
+		policy: RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Hour},
+		resp:   &http.Response{Header: http.Header{"Retry-After": {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}}},
+		min:    59 * time.Minute,
+		max:    time.Hour,
+	})
+	tests.Add("Retry-After capped", tt{
+		policy: RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Second},
+		resp:   &http.Response{Header: http.Header{"Retry-After": {"3600"}}},
+		min:    5 * time.Second,
+		max:    5 * time.Second,
+	})
+	tests.Add("Retry-After date capped", tt{
+		policy: RetryPolicy{MinBackoff: time.Millisecond},
+		resp:   &http.Response{Header: http.Header{"Retry-After": {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}}},
+		min:    DefaultMaxBackoff,
+		max:    DefaultMaxBackoff,
+	})
+	tests.Add("invalid Retry-After", tt{
+		policy: RetryPolicy{MinBackoff: time.Second, MaxBackoff: time.Second},
+		resp:   &http.Response{Header: http.Header{"Retry-After": {"soon"}}},
+		min:    500 * time.Millisecond,
+		max:    time.Second,
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got := tt.policy.backoff(tt.attempt, tt.resp)
+		if got < tt.min || got > tt.max {
+			t.Errorf("Backoff %s not in range %s-%s", got, tt.min, tt.max)
+		}
+	})
+}
+
+func TestOptionRetryPolicy(t *testing.T) {
+	c, err := New(&http.Client{}, "http://example.com/", OptionRetryPolicy(RetryPolicy{MaxRetries: 5}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.retryPolicy == nil || c.retryPolicy.maxRetries() != 5 {
+		t.Errorf("Unexpected retry policy: %v", c.retryPolicy)
+	}
+}
@@ -80,7 +80,11 @@ func (c *client) DBUpdates(ctx context.Context, opts driver.Options) (updates dr
 		delete(query, "since")
 	}
 	if query.Get("feed") == "eventsource" {
-		return nil, &internal.Error{Status: http.StatusBadRequest, Message: "kivik: eventsource feed type not supported by CouchDB driver"}
+		body, err := c.eventSource(ctx, http.MethodGet, "/_db_updates", &chttp.Options{Query: query})
+		if err != nil {
+			return nil, err
+		}
+		return newContinuousUpdates(ctx, body), nil
 	}
 	resp, err := c.DoReq(ctx, http.MethodGet, "/_db_updates", &chttp.Options{Query: query})
 	if err != nil {
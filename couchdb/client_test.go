@@ -408,12 +408,23 @@ func TestDBUpdates(t *testing.T) {
 		},
 		{
 			name: "eventsource",
+			client: newTestClient(&http.Response{
+				StatusCode: 200,
+				Header: http.Header{
+					"Content-Type": {"text/event-stream"},
+				},
+				Body: Body("data: {\"db_name\":\"mailbox\",\"type\":\"created\",\"seq\":\"1-g1AAAAFR\"}\nid: 1-g1AAAAFR\n\n" +
+					"event: heartbeat\ndata: \n\n" +
+					"data: {\"db_name\":\"mailbox\",\"type\":\"deleted\",\"seq\":\"2-g1AAAAFR\"}\nid: 2-g1AAAAFR\n\n"),
+			}, nil),
 			options: kivik.Params(map[string]any{
 				"feed":  "eventsource",
 				"since": "",
 			}),
-			wantStatus: http.StatusBadRequest,
-			wantErr:    "eventsource feed type not supported",
+			want: []driver.DBUpdate{
+				{DBName: "mailbox", Type: "created", Seq: "1-g1AAAAFR"},
+				{DBName: "mailbox", Type: "deleted", Seq: "2-g1AAAAFR"},
+			},
 		},
 		{
 			// Based on CI test failures, presumably from a race condition that
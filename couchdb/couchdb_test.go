@@ -13,6 +13,7 @@
 package couchdb
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -89,6 +90,32 @@ func TestNewClient(t *testing.T) {
 			t.Error("Unexpected *http.Client returned")
 		}
 	})
+	t.Run("limit monitor", func(t *testing.T) {
+		monitor := &LimitMonitor{}
+		opts := multiOptions{
+			OptionMaxConcurrentRequests(1),
+			OptionLimitMonitor(monitor),
+		}
+		driver := &couch{}
+		c, err := driver.NewClient("http://example.com/", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.(*client).Transport = customTransport(func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: Body("{}")}, nil
+		})
+		resp, err := c.(*client).DoReq(context.Background(), http.MethodGet, "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := monitor.LimitStats().InFlight; n != 1 {
+			t.Errorf("Expected 1 request in flight, got %d", n)
+		}
+		_ = resp.Body.Close()
+		if n := monitor.LimitStats().InFlight; n != 0 {
+			t.Errorf("Expected 0 requests in flight, got %d", n)
+		}
+	})
 }
 
 func TestDB(t *testing.T) {
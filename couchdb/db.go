@@ -119,6 +119,8 @@ func (d *db) rowsQuery(ctx context.Context, path string, options driver.Options)
 		chttpOpts.Header = http.Header{
 			chttp.HeaderIdempotencyKey: []string{},
 		}
+		// The POST only carries the query, so it's safe to retry.
+		chttpOpts.Idempotent = true
 	}
 	resp, err := d.DoReq(ctx, method, d.path(path), chttpOpts)
 	if err != nil {
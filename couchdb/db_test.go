@@ -2955,3 +2955,83 @@ func TestCreateMultipartGoroutineLeak(t *testing.T) {
 		t.Fatal("goroutine leak: input reader was never closed")
 	}
 }
+
+func TestReadOnlyPostRetried(t *testing.T) {
+	type tt struct {
+		query func(*db) (driver.Rows, error)
+		body  string
+	}
+
+	tests := testy.NewTable()
+	tests.Add("changes", tt{
+		query: func(d *db) (driver.Rows, error) {
+			changes, err := d.Changes(context.Background(), mock.NilOption)
+			if err != nil {
+				return nil, err
+			}
+			return nil, changes.Close()
+		},
+		body: `{"results":[],"last_seq":"1-abc"}`,
+	})
+	tests.Add("find", tt{
+		query: func(d *db) (driver.Rows, error) {
+			return d.Find(context.Background(), map[string]any{"selector": map[string]any{}}, mock.NilOption)
+		},
+		body: `{"docs":[]}`,
+	})
+	tests.Add("all docs with keys", tt{
+		query: func(d *db) (driver.Rows, error) {
+			return d.AllDocs(context.Background(), kivik.Param("keys", []string{"a", "b"}))
+		},
+		body: `{"rows":[]}`,
+	})
+	tests.Add("view with keys", tt{
+		query: func(d *db) (driver.Rows, error) {
+			return d.Query(context.Background(), "foo", "bar", kivik.Param("keys", []string{"a", "b"}))
+		},
+		body: `{"rows":[]}`,
+	})
+	tests.Add("bulk get", tt{
+		query: func(d *db) (driver.Rows, error) {
+			return d.BulkGet(context.Background(), []driver.BulkGetReference{{ID: "foo"}}, mock.NilOption)
+		},
+		body: `{"results":[]}`,
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		var requests int
+		d := newCustomDB(func(req *http.Request) (*http.Response, error) {
+			requests++
+			if err := consume(req.Body); err != nil {
+				return nil, err
+			}
+			if req.Method != http.MethodPost {
+				return nil, fmt.Errorf("Unexpected method: %s", req.Method)
+			}
+			if requests == 1 {
+				return &http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Body:       Body(""),
+				}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {typeJSON}},
+				Body:       Body(tt.body),
+			}, nil
+		})
+		retry := chttp.OptionRetryPolicy(chttp.RetryPolicy{MinBackoff: time.Millisecond})
+		retry.Apply(d.client.Client)
+
+		rows, err := tt.query(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows != nil {
+			_ = rows.Close()
+		}
+		if requests != 2 {
+			t.Errorf("Expected 2 requests, got %d", requests)
+		}
+	})
+}
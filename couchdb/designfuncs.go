@@ -0,0 +1,107 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package couchdb
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-kivik/kivik/v4/couchdb/chttp"
+	"github.com/go-kivik/kivik/v4/driver"
+)
+
+var (
+	_ driver.DesignFunctioner = &db{}
+	_ driver.UpdateResponder  = &db{}
+)
+
+func (d *db) Show(ctx context.Context, ddoc, funcName, docID string, options driver.Options) (*driver.DesignResponse, error) {
+	path := "_design/" + chttp.EncodeDocID(ddoc) + "/_show/" + chttp.EncodeDocID(funcName)
+	if docID != "" {
+		path += "/" + chttp.EncodeDocID(docID)
+	}
+	return d.designFunc(ctx, http.MethodGet, path, nil, options)
+}
+
+func (d *db) List(ctx context.Context, ddoc, funcName, view string, options driver.Options) (*driver.DesignResponse, error) {
+	path := "_design/" + chttp.EncodeDocID(ddoc) + "/_list/" + chttp.EncodeDocID(funcName)
+	for _, part := range strings.SplitN(view, "/", 2) {
+		path += "/" + chttp.EncodeDocID(part)
+	}
+	return d.designFunc(ctx, http.MethodGet, path, nil, options)
+}
+
+func (d *db) Rewrite(ctx context.Context, ddoc, method, path string, body io.Reader, options driver.Options) (*driver.DesignResponse, error) {
+	return d.designFunc(ctx, method, "_design/"+chttp.EncodeDocID(ddoc)+"/_rewrite/"+path, body, options)
+}
+
+// designFunc makes a request to a design function, and returns the raw
+// response. A function may respond with any status code, so responses with an
+// error status are returned as-is, rather than as an error.
+func (d *db) designFunc(ctx context.Context, method, path string, body io.Reader, options driver.Options) (*driver.DesignResponse, error) {
+	chttpOpts := chttp.NewOptions(options)
+	opts := map[string]any{}
+	options.Apply(opts)
+	var err error
+	chttpOpts.Query, err = optionsToParams(opts)
+	if err != nil {
+		return nil, err
+	}
+	if chttpOpts.Accept == "" {
+		chttpOpts.Accept = "*/*"
+	}
+	if body != nil {
+		chttpOpts.Body = io.NopCloser(body)
+	}
+	resp, err := d.DoReq(ctx, method, d.path(path), chttpOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &driver.DesignResponse{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Header:      resp.Header,
+		Body:        resp.Body,
+	}, nil
+}
+
+func (d *db) UpdateWithResponse(ctx context.Context, ddoc, funcName, docID string, doc any, options driver.Options) (*driver.UpdateResponse, error) {
+	opts, err := putOpts(doc, options)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Accept == "" {
+		opts.Accept = "*/*"
+	}
+	path := "_design/" + chttp.EncodeDocID(ddoc) + "/_update/" + chttp.EncodeDocID(funcName)
+	method := http.MethodPost
+	if docID != "" {
+		method = http.MethodPut
+		path += "/" + chttp.EncodeDocID(docID)
+	}
+	// As with show and list functions, a response with an error status is
+	// returned as-is.
+	resp, err := d.DoReq(ctx, method, d.path(path), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &driver.UpdateResponse{
+		Rev:         resp.Header.Get("X-Couch-Update-NewRev"),
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Header:      resp.Header,
+		Body:        resp.Body,
+	}, nil
+}
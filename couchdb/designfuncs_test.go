@@ -0,0 +1,291 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package couchdb
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gitlab.com/flimzy/testy"
+
+	kivik "github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+func TestDesignFuncs(t *testing.T) {
+	type tt struct {
+		db       *db
+		call     func(*db) (*driver.DesignResponse, error)
+		wantCode int
+		wantCT   string
+		wantBody string
+		status   int
+		err      string
+	}
+	// expect returns a db which checks the request method, path and raw
+	// query, and responds with an HTML page.
+	expect := func(method, path, query, body string) *db {
+		return newCustomDB(func(req *http.Request) (*http.Response, error) {
+			if req.Method != method {
+				return nil, fmt.Errorf("Unexpected method: %s", req.Method)
+			}
+			if req.URL.RawPath != path {
+				return nil, fmt.Errorf("Unexpected path: %s", req.URL.RawPath)
+			}
+			if req.URL.RawQuery != query {
+				return nil, fmt.Errorf("Unexpected query: %s", req.URL.RawQuery)
+			}
+			if accept := req.Header.Get("Accept"); accept != "*/*" {
+				return nil, fmt.Errorf("Unexpected Accept: %s", accept)
+			}
+			if body != "" {
+				if err := consumeBody(req, body); err != nil {
+					return nil, err
+				}
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+				Body:       io.NopCloser(strings.NewReader("<p>Hello</p>")),
+			}, nil
+		})
+	}
+
+	tests := testy.NewTable()
+	tests.Add("show network error", tt{
+		db: newTestDB(nil, errors.New("net error")),
+		call: func(d *db) (*driver.DesignResponse, error) {
+			return d.Show(context.Background(), "app", "page", "foo", mock.NilOption)
+		},
+		status: http.StatusBadGateway,
+		err:    `Get "?http://example.com/testdb/_design/app/_show/page/foo"?: net error`,
+	})
+	tests.Add("show", tt{
+		db: expect(http.MethodGet, "/testdb/_design/app/_show/page/foo%2Fbar", "format=html", ""),
+		call: func(d *db) (*driver.DesignResponse, error) {
+			return d.Show(context.Background(), "app", "page", "foo/bar", kivik.Param("format", "html"))
+		},
+		wantCT:   "text/html; charset=utf-8",
+		wantBody: "<p>Hello</p>",
+	})
+	tests.Add("show without doc", tt{
+		db: expect(http.MethodGet, "/testdb/_design/app/_show/page", "", ""),
+		call: func(d *db) (*driver.DesignResponse, error) {
+			return d.Show(context.Background(), "app", "page", "", mock.NilOption)
+		},
+		wantCT:   "text/html; charset=utf-8",
+		wantBody: "<p>Hello</p>",
+	})
+	tests.Add("show error status", tt{
+		db: newTestDB(&http.Response{
+			StatusCode: http.StatusNotFound,
+			Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+			Body:       Body(`<p>No such page</p>`),
+		}, nil),
+		call: func(d *db) (*driver.DesignResponse, error) {
+			return d.Show(context.Background(), "app", "page", "", mock.NilOption)
+		},
+		wantCode: http.StatusNotFound,
+		wantCT:   "text/html; charset=utf-8",
+		wantBody: "<p>No such page</p>\n",
+	})
+	tests.Add("list", tt{
+		db: expect(http.MethodGet, "/testdb/_design/app/_list/table/by_date", "limit=10&startkey=%222024%22", ""),
+		call: func(d *db) (*driver.DesignResponse, error) {
+			return d.List(context.Background(), "app", "table", "by_date", kivik.Params(map[string]any{
+				"startkey": "2024",
+				"limit":    10,
+			}))
+		},
+		wantCT:   "text/html; charset=utf-8",
+		wantBody: "<p>Hello</p>",
+	})
+	tests.Add("list with other ddoc", tt{
+		db: expect(http.MethodGet, "/testdb/_design/app/_list/table/other/by_date", "", ""),
+		call: func(d *db) (*driver.DesignResponse, error) {
+			return d.List(context.Background(), "app", "table", "other/by_date", mock.NilOption)
+		},
+		wantCT:   "text/html; charset=utf-8",
+		wantBody: "<p>Hello</p>",
+	})
+	tests.Add("rewrite", tt{
+		db: expect(http.MethodPost, "/testdb/_design/app/_rewrite/posts/new", "draft=true", `{"title":"x"}`),
+		call: func(d *db) (*driver.DesignResponse, error) {
+			return d.Rewrite(context.Background(), "app", http.MethodPost, "posts/new", strings.NewReader(`{"title":"x"}`), kivik.Param("draft", true))
+		},
+		wantCT:   "text/html; charset=utf-8",
+		wantBody: "<p>Hello</p>",
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		resp, err := tt.call(tt.db)
+		if d := internal.StatusErrorDiffRE(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		wantCode := tt.wantCode
+		if wantCode == 0 {
+			wantCode = http.StatusOK
+		}
+		if resp.StatusCode != wantCode {
+			t.Errorf("Unexpected status code: %d", resp.StatusCode)
+		}
+		if resp.ContentType != tt.wantCT {
+			t.Errorf("Unexpected Content-Type: %s", resp.ContentType)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != tt.wantBody {
+			t.Errorf("Unexpected body: %s", body)
+		}
+	})
+}
+
+// consumeBody reads the request body, decompressing it if necessary, and
+// compares it to want.
+func consumeBody(req *http.Request, want string) error {
+	r := req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		r = gz
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if string(body) != want {
+		return fmt.Errorf("Unexpected body: %s", body)
+	}
+	return nil
+}
+
+func TestUpdateWithResponse(t *testing.T) {
+	type tt struct {
+		db       *db
+		docID    string
+		doc      any
+		wantRev  string
+		wantCode int
+		wantBody string
+		status   int
+		err      string
+	}
+
+	tests := testy.NewTable()
+	tests.Add("network error", tt{
+		db:     newTestDB(nil, errors.New("net error")),
+		docID:  "foo",
+		status: http.StatusBadGateway,
+		err:    `Put "?http://example.com/testdb/_design/app/_update/bump/foo"?: net error`,
+	})
+	tests.Add("error status", tt{
+		db: newTestDB(&http.Response{
+			StatusCode: http.StatusConflict,
+			Header:     http.Header{"Content-Type": {"text/plain"}},
+			Body:       Body(`already bumped`),
+		}, nil),
+		docID:    "foo",
+		wantCode: http.StatusConflict,
+		wantBody: "already bumped\n",
+	})
+	tests.Add("invalid document", tt{
+		db:     newTestDB(nil, errors.New("unexpected request")),
+		docID:  "foo",
+		doc:    make(chan int),
+		status: http.StatusBadRequest,
+		err:    `Put "?http://example.com/testdb/_design/app/_update/bump/foo"?: json: unsupported type: chan int`,
+	})
+	tests.Add("success", tt{
+		db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodPut {
+				return nil, fmt.Errorf("Unexpected method: %s", req.Method)
+			}
+			if req.URL.RawPath != "/testdb/_design/app/_update/bump/foo%2Fbar" {
+				return nil, fmt.Errorf("Unexpected path: %s", req.URL.RawPath)
+			}
+			if err := consumeBody(req, `{"a":"b"}`+"\n"); err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusCreated,
+				Header: http.Header{
+					"Content-Type":          {"text/plain"},
+					"X-Couch-Update-Newrev": {"2-xxx"},
+				},
+				Body: io.NopCloser(strings.NewReader("bumped")),
+			}, nil
+		}),
+		docID:    "foo/bar",
+		doc:      map[string]string{"a": "b"},
+		wantRev:  "2-xxx",
+		wantCode: http.StatusCreated,
+		wantBody: "bumped",
+	})
+	tests.Add("null document", tt{
+		db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodPost {
+				return nil, fmt.Errorf("Unexpected method: %s", req.Method)
+			}
+			if req.URL.Path != "/testdb/_design/app/_update/bump" {
+				return nil, fmt.Errorf("Unexpected path: %s", req.URL.Path)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {"text/plain"}},
+				Body:       io.NopCloser(strings.NewReader("nothing to do")),
+			}, nil
+		}),
+		wantCode: http.StatusOK,
+		wantBody: "nothing to do",
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		resp, err := tt.db.UpdateWithResponse(context.Background(), "app", "bump", tt.docID, tt.doc, mock.NilOption)
+		if d := internal.StatusErrorDiffRE(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		if resp.Rev != tt.wantRev {
+			t.Errorf("Unexpected rev: %s", resp.Rev)
+		}
+		if resp.StatusCode != tt.wantCode {
+			t.Errorf("Unexpected status code: %d", resp.StatusCode)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != tt.wantBody {
+			t.Errorf("Unexpected body: %s", body)
+		}
+	})
+}
@@ -115,6 +115,20 @@ inline base-64 encoding the attachments.  Example:
 
 	rev, err := db.Put(ctx, "user123", doc", couchdb.OptionNoMultipartPut())
 
+# Attachment uploads
+
+[github.com/go-kivik/kivik/v4.DB.PutAttachment] sends an `Expect: 100-continue`
+header, so that the server may reject the upload, such as for a conflict or
+an authorization failure, before the body is sent.
+
+Request bodies are normally gzip-compressed, but the length of a compressed
+body isn't known until it has been sent. When
+[github.com/go-kivik/kivik/v4.Attachment.Size] is set, the attachment is
+instead sent uncompressed, with a fixed `Content-Length` header. This favors
+large or already-compressed content, such as images or archives. To have a
+compressible attachment gzip-compressed in transit, leave
+[github.com/go-kivik/kivik/v4.Attachment.Size] unset.
+
 # Server config for CouchDB 1.x
 
 CouchDB allows querying the server configuration via the /_config endpoint. This
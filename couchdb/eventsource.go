@@ -0,0 +1,195 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package couchdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kivik/kivik/v4/couchdb/chttp"
+)
+
+// maxEventSourceReconnects is the number of times in a row an eventsource
+// feed is reconnected without receiving an event, before giving up.
+const maxEventSourceReconnects = 3
+
+// eventSource requests an eventsource feed, and returns the events' data as
+// a stream of newline-delimited JSON values, in the same format as a
+// continuous feed. If the connection is lost, the feed is resumed from the
+// last received event ID.
+func (c *client) eventSource(ctx context.Context, method, path string, opts *chttp.Options) (io.ReadCloser, error) {
+	connect := func(ctx context.Context, lastEventID string) (io.ReadCloser, error) {
+		reqOpts := *opts
+		if lastEventID != "" {
+			reqOpts.Header = opts.Header.Clone()
+			if reqOpts.Header == nil {
+				reqOpts.Header = http.Header{}
+			}
+			reqOpts.Header.Set("Last-Event-ID", lastEventID)
+			reqOpts.Query = url.Values{}
+			for k, v := range opts.Query {
+				reqOpts.Query[k] = v
+			}
+			reqOpts.Query.Set("since", lastEventID)
+		}
+		resp, err := c.DoReq(ctx, method, path, &reqOpts)
+		if err != nil {
+			return nil, err
+		}
+		if err := chttp.ResponseError(resp); err != nil {
+			return nil, err
+		}
+		return resp.Body, nil
+	}
+	body, err := connect(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	return newEventSourceReader(ctx, body, connect), nil
+}
+
+// eventSourceReader parses a text/event-stream, and returns the data of each
+// event followed by a newline. Comments, heartbeats and named events are
+// skipped.
+type eventSourceReader struct {
+	ctx     context.Context
+	body    io.ReadCloser
+	r       *bufio.Reader
+	connect func(context.Context, string) (io.ReadCloser, error)
+
+	lastEventID string
+	retry       time.Duration
+	reconnects  int
+
+	// out holds parsed data not yet returned by Read.
+	out bytes.Buffer
+	// data and event hold the fields of the event being parsed.
+	data    bytes.Buffer
+	hasData bool
+	event   string
+}
+
+var _ io.ReadCloser = &eventSourceReader{}
+
+func newEventSourceReader(ctx context.Context, body io.ReadCloser, connect func(context.Context, string) (io.ReadCloser, error)) *eventSourceReader {
+	return &eventSourceReader{
+		ctx:     ctx,
+		body:    body,
+		r:       bufio.NewReader(body),
+		connect: connect,
+	}
+}
+
+func (r *eventSourceReader) Read(p []byte) (int, error) {
+	for r.out.Len() == 0 {
+		if err := r.readLine(); err != nil {
+			return 0, err
+		}
+	}
+	return r.out.Read(p)
+}
+
+// readLine reads and processes a single line of the event stream.
+func (r *eventSourceReader) readLine() error {
+	line, err := r.r.ReadString('\n')
+	if err != nil {
+		// The server closing the feed is treated like any other dropped
+		// connection, unless the feed can't be resumed.
+		if !errors.Is(err, io.EOF) || r.connect != nil {
+			return r.reconnect(err)
+		}
+		if line == "" {
+			return io.EOF
+		}
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if line == "" {
+		r.dispatch()
+		return nil
+	}
+	field, value, _ := strings.Cut(line, ":")
+	value = strings.TrimPrefix(value, " ")
+	switch field {
+	case "": // A comment
+	case "event":
+		r.event = value
+	case "data":
+		if r.hasData {
+			r.data.WriteByte('\n')
+		}
+		r.data.WriteString(value)
+		r.hasData = true
+	case "id":
+		if !strings.Contains(value, "\x00") {
+			r.lastEventID = value
+		}
+	case "retry":
+		if ms, err := strconv.Atoi(value); err == nil {
+			r.retry = time.Duration(ms) * time.Millisecond
+		}
+	}
+	return nil
+}
+
+// dispatch queues the data of the event parsed so far, and resets the event.
+func (r *eventSourceReader) dispatch() {
+	if r.hasData && (r.event == "" || r.event == "message") {
+		if data := bytes.TrimSpace(r.data.Bytes()); len(data) > 0 {
+			r.out.Write(data)
+			r.out.WriteByte('\n')
+			r.reconnects = 0
+		}
+	}
+	r.data.Reset()
+	r.hasData = false
+	r.event = ""
+}
+
+// reconnect replaces the failed connection with a new one, resuming from the
+// last event ID. cause is returned if the feed can't be resumed.
+func (r *eventSourceReader) reconnect(cause error) error {
+	_ = r.body.Close()
+	if r.connect == nil || r.ctx.Err() != nil || r.reconnects >= maxEventSourceReconnects {
+		return cause
+	}
+	r.reconnects++
+	if r.retry > 0 {
+		select {
+		case <-r.ctx.Done():
+			return r.ctx.Err()
+		case <-time.After(r.retry):
+		}
+	}
+	body, err := r.connect(r.ctx, r.lastEventID)
+	if err != nil {
+		return err
+	}
+	r.body = body
+	r.r.Reset(body)
+	r.data.Reset()
+	r.hasData = false
+	r.event = ""
+	return nil
+}
+
+func (r *eventSourceReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,126 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package couchdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"gitlab.com/flimzy/testy"
+
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+func TestEventSourceReader(t *testing.T) {
+	type tt struct {
+		body    io.Reader
+		connect func(context.Context, string) (io.ReadCloser, error)
+		want    string
+		status  int
+		err     string
+	}
+
+	// resumeAfterSeq1 serves the second event when resuming from the first,
+	// and then an empty feed, so the reader gives up once its reconnects are
+	// exhausted.
+	resumeAfterSeq1 := func(_ context.Context, lastEventID string) (io.ReadCloser, error) {
+		switch lastEventID {
+		case "1":
+			return io.NopCloser(strings.NewReader("data: {\"seq\":2}\nid: 2\n\n")), nil
+		case "2":
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		return nil, fmt.Errorf("Unexpected Last-Event-ID: %s", lastEventID)
+	}
+
+	tests := testy.NewTable()
+	tests.Add("empty", tt{
+		body: strings.NewReader(""),
+		want: "",
+	})
+	tests.Add("single event", tt{
+		body: strings.NewReader("data: {\"seq\":1}\nid: 1\n\n"),
+		want: "{\"seq\":1}\n",
+	})
+	tests.Add("CRLF line endings", tt{
+		body: strings.NewReader("data: {\"seq\":1}\r\n\r\ndata: {\"seq\":2}\r\n\r\n"),
+		want: "{\"seq\":1}\n{\"seq\":2}\n",
+	})
+	tests.Add("heartbeats and comments", tt{
+		body: strings.NewReader(": comment\n\nevent: heartbeat\ndata: \n\ndata: {\"seq\":1}\n\nevent: heartbeat\ndata:\n\n"),
+		want: "{\"seq\":1}\n",
+	})
+	tests.Add("multi-line data", tt{
+		body: strings.NewReader("data: {\"seq\":\ndata: 1}\n\n"),
+		want: "{\"seq\":\n1}\n",
+	})
+	tests.Add("incomplete final event", tt{
+		body: strings.NewReader("data: {\"seq\":1}\n\ndata: {\"seq\":2}"),
+		want: "{\"seq\":1}\n",
+	})
+	tests.Add("no reconnect", tt{
+		body:   io.MultiReader(strings.NewReader("data: {\"seq\":1}\nid: 1\n\n"), iotest.ErrReader(errors.New("connection reset"))),
+		want:   "{\"seq\":1}\n",
+		status: http.StatusInternalServerError,
+		err:    "connection reset",
+	})
+	tests.Add("reconnect", tt{
+		body:    io.MultiReader(strings.NewReader("retry: 1\ndata: {\"seq\":1}\nid: 1\n\ndata: {\"se"), iotest.ErrReader(errors.New("connection reset"))),
+		connect: resumeAfterSeq1,
+		want:    "{\"seq\":1}\n{\"seq\":2}\n",
+	})
+	tests.Add("reconnect after EOF", tt{
+		body:    strings.NewReader("data: {\"seq\":1}\nid: 1\n\ndata: {\"se"),
+		connect: resumeAfterSeq1,
+		want:    "{\"seq\":1}\n{\"seq\":2}\n",
+	})
+	tests.Add("reconnect after unexpected EOF", tt{
+		body:    io.MultiReader(strings.NewReader("data: {\"seq\":1}\nid: 1\n\n"), iotest.ErrReader(io.ErrUnexpectedEOF)),
+		connect: resumeAfterSeq1,
+		want:    "{\"seq\":1}\n{\"seq\":2}\n",
+	})
+	tests.Add("reconnect failure", tt{
+		body: io.MultiReader(strings.NewReader("data: {\"seq\":1}\nid: 1\n\n"), iotest.ErrReader(errors.New("connection reset"))),
+		connect: func(context.Context, string) (io.ReadCloser, error) {
+			return nil, &internal.Error{Status: http.StatusServiceUnavailable, Message: "unavailable"}
+		},
+		want:   "{\"seq\":1}\n",
+		status: http.StatusServiceUnavailable,
+		err:    "unavailable",
+	})
+	tests.Add("too many reconnects", tt{
+		body: iotest.ErrReader(errors.New("connection reset")),
+		connect: func(context.Context, string) (io.ReadCloser, error) {
+			return io.NopCloser(iotest.ErrReader(errors.New("connection reset again"))), nil
+		},
+		status: http.StatusInternalServerError,
+		err:    "connection reset again",
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		r := newEventSourceReader(context.Background(), io.NopCloser(tt.body), tt.connect)
+		got, err := io.ReadAll(r)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := testy.DiffText(tt.want, string(got)); d != nil {
+			t.Errorf("Unexpected output:\n%s", d)
+		}
+	})
+}
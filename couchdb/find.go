@@ -35,14 +35,17 @@ func (d *db) CreateIndex(ctx context.Context, ddoc, name string, index any, opti
 	if err != nil {
 		return err
 	}
+	indexType, _ := opts["type"].(string)
 	parameters := struct {
 		Index any    `json:"index"`
 		Ddoc  string `json:"ddoc,omitempty"`
 		Name  string `json:"name,omitempty"`
+		Type  string `json:"type,omitempty"`
 	}{
 		Index: indexObj,
 		Ddoc:  ddoc,
 		Name:  name,
+		Type:  indexType,
 	}
 	chttpOpts := &chttp.Options{
 		Body: chttp.EncodeBody(parameters),
@@ -89,6 +92,7 @@ func (d *db) Find(ctx context.Context, query any, options driver.Options) (drive
 		Header: http.Header{
 			chttp.HeaderIdempotencyKey: []string{},
 		},
+		Idempotent: true,
 	}
 	resp, err := d.DoReq(ctx, http.MethodPost, d.path(reqPath.String()), chttpOpts)
 	if err != nil {
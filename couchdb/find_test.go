@@ -215,6 +215,21 @@ func TestCreateIndex(t *testing.T) {
 				Body: Body(`{"result":"created","id":"_design/a7ee061f1a2c0c6882258b2f1e148b714e79ccea","name":"a7ee061f1a2c0c6882258b2f1e148b714e79ccea"}`),
 			}, nil),
 		},
+		{
+			name: "index type",
+			db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+				if err := consumeBody(req, `{"index":{"fields":["foo"]},"name":"bar","type":"text"}`+"\n"); err != nil {
+					return nil, err
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       Body(`{"result":"created","id":"_design/bar","name":"bar"}`),
+				}, nil
+			}),
+			indexName: "bar",
+			index:     `{"fields":["foo"]}`,
+			options:   kivik.Param("type", "text"),
+		},
 		{
 			name:    "partitioned query",
 			db:      newTestDB(nil, errors.New("expected")),
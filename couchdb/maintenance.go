@@ -0,0 +1,84 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package couchdb
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/kivik/v4/couchdb/chttp"
+	"github.com/go-kivik/kivik/v4/driver"
+)
+
+var _ driver.DBMaintainer = &db{}
+
+func (d *db) getLimit(ctx context.Context, path string) (int, error) {
+	var limit int
+	err := d.DoJSON(ctx, http.MethodGet, d.path(path), nil, &limit)
+	return limit, err
+}
+
+func (d *db) setLimit(ctx context.Context, path string, limit int) error {
+	opts := &chttp.Options{
+		GetBody: chttp.BodyEncoder(limit),
+		Header: http.Header{
+			chttp.HeaderIdempotencyKey: []string{},
+		},
+	}
+	_, err := d.DoError(ctx, http.MethodPut, d.path(path), opts)
+	return err
+}
+
+func (d *db) RevsLimit(ctx context.Context) (int, error) {
+	return d.getLimit(ctx, "/_revs_limit")
+}
+
+func (d *db) SetRevsLimit(ctx context.Context, limit int) error {
+	return d.setLimit(ctx, "/_revs_limit", limit)
+}
+
+func (d *db) PurgedInfosLimit(ctx context.Context) (int, error) {
+	return d.getLimit(ctx, "/_purged_infos_limit")
+}
+
+func (d *db) SetPurgedInfosLimit(ctx context.Context, limit int) error {
+	return d.setLimit(ctx, "/_purged_infos_limit", limit)
+}
+
+func (d *db) Shards(ctx context.Context) (map[string][]string, error) {
+	var result struct {
+		Shards map[string][]string `json:"shards"`
+	}
+	if err := d.DoJSON(ctx, http.MethodGet, d.path("/_shards"), nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Shards, nil
+}
+
+func (d *db) DocShards(ctx context.Context, docID string) (*driver.DocShards, error) {
+	var result driver.DocShards
+	if err := d.DoJSON(ctx, http.MethodGet, d.path("/_shards/"+chttp.EncodeDocID(docID)), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (d *db) SyncShards(ctx context.Context) error {
+	opts := &chttp.Options{
+		Header: http.Header{
+			chttp.HeaderIdempotencyKey: []string{},
+		},
+	}
+	_, err := d.DoError(ctx, http.MethodPost, d.path("/_sync_shards"), opts)
+	return err
+}
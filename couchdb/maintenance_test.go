@@ -0,0 +1,244 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package couchdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+func TestRevsLimit(t *testing.T) {
+	type tt struct {
+		db     *db
+		want   int
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("network error", tt{
+		db:     newTestDB(nil, errors.New("net error")),
+		status: http.StatusBadGateway,
+		err:    `Get "?http://example.com/testdb/_revs_limit"?: net error`,
+	})
+	tests.Add("success", tt{
+		db: newTestDB(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       Body("1000"),
+		}, nil),
+		want: 1000,
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.db.RevsLimit(context.Background())
+		if d := internal.StatusErrorDiffRE(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if got != tt.want {
+			t.Errorf("Unexpected result: want %d, got %d", tt.want, got)
+		}
+	})
+}
+
+func TestSetRevsLimit(t *testing.T) {
+	type tt struct {
+		db     *db
+		limit  int
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("network error", tt{
+		db:     newTestDB(nil, errors.New("net error")),
+		limit:  10,
+		status: http.StatusBadGateway,
+		err:    `Put "?http://example.com/testdb/_revs_limit"?: net error`,
+	})
+	tests.Add("bad request", tt{
+		db: newTestDB(&http.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       Body(`{"error":"bad_request","reason":"Limit must be a positive integer"}`),
+		}, nil),
+		limit:  -1,
+		status: http.StatusBadRequest,
+		err:    "Bad Request",
+	})
+	tests.Add("success", tt{
+		db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodPut {
+				return nil, fmt.Errorf("Unexpected method: %s", req.Method)
+			}
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			if got := strings.TrimSpace(string(body)); got != "10" {
+				return nil, fmt.Errorf("Unexpected body: %s", got)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       Body(`{"ok":true}`),
+			}, nil
+		}),
+		limit: 10,
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		err := tt.db.SetRevsLimit(context.Background(), tt.limit)
+		if d := internal.StatusErrorDiffRE(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+	})
+}
+
+func TestPurgedInfosLimit(t *testing.T) {
+	db := newCustomDB(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/testdb/_purged_infos_limit" {
+			return nil, fmt.Errorf("Unexpected path: %s", req.URL.Path)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       Body("500"),
+		}, nil
+	})
+	got, err := db.PurgedInfosLimit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 500 {
+		t.Errorf("Unexpected result: %d", got)
+	}
+}
+
+func TestShards(t *testing.T) {
+	type tt struct {
+		db     *db
+		want   map[string][]string
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("network error", tt{
+		db:     newTestDB(nil, errors.New("net error")),
+		status: http.StatusBadGateway,
+		err:    `Get "?http://example.com/testdb/_shards"?: net error`,
+	})
+	tests.Add("success", tt{
+		db: newTestDB(&http.Response{
+			StatusCode: http.StatusOK,
+			Body: Body(`{"shards":{
+				"00000000-7fffffff":["node1@127.0.0.1","node2@127.0.0.1"],
+				"80000000-ffffffff":["node1@127.0.0.1","node2@127.0.0.1"]
+			}}`),
+		}, nil),
+		want: map[string][]string{
+			"00000000-7fffffff": {"node1@127.0.0.1", "node2@127.0.0.1"},
+			"80000000-ffffffff": {"node1@127.0.0.1", "node2@127.0.0.1"},
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.db.Shards(context.Background())
+		if d := internal.StatusErrorDiffRE(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected result (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestDocShards(t *testing.T) {
+	type tt struct {
+		db     *db
+		docID  string
+		want   *driver.DocShards
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("network error", tt{
+		db:     newTestDB(nil, errors.New("net error")),
+		docID:  "foo",
+		status: http.StatusBadGateway,
+		err:    `Get "?http://example.com/testdb/_shards/foo"?: net error`,
+	})
+	tests.Add("escaped doc ID", tt{
+		db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+			if req.URL.RawPath != "/testdb/_shards/foo%2Fbar" {
+				return nil, fmt.Errorf("Unexpected path: %s", req.URL.RawPath)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       Body(`{"range":"e0000000-ffffffff","nodes":["node1@127.0.0.1"]}`),
+			}, nil
+		}),
+		docID: "foo/bar",
+		want: &driver.DocShards{
+			Range: "e0000000-ffffffff",
+			Nodes: []string{"node1@127.0.0.1"},
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.db.DocShards(context.Background(), tt.docID)
+		if d := internal.StatusErrorDiffRE(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected result (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestSyncShards(t *testing.T) {
+	type tt struct {
+		db     *db
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("network error", tt{
+		db:     newTestDB(nil, errors.New("net error")),
+		status: http.StatusBadGateway,
+		err:    `Post "?http://example.com/testdb/_sync_shards"?: net error`,
+	})
+	tests.Add("success", tt{
+		db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodPost {
+				return nil, fmt.Errorf("Unexpected method: %s", req.Method)
+			}
+			return &http.Response{
+				StatusCode: http.StatusAccepted,
+				Body:       Body(`{"ok":true}`),
+			}, nil
+		}),
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		err := tt.db.SyncShards(context.Background())
+		if d := internal.StatusErrorDiffRE(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+	})
+}
@@ -17,6 +17,7 @@ import (
 	"net/http"
 	"path"
 	"strings"
+	"sync"
 
 	kivik "github.com/go-kivik/kivik/v4"
 	"github.com/go-kivik/kivik/v4/couchdb/chttp"
@@ -54,6 +55,73 @@ func OptionUserAgent(ua string) kivik.Option {
 	return chttp.OptionUserAgent(ua)
 }
 
+// OptionRetryPolicy enables automatic retries, with exponential backoff, of
+// requests which fail due to a transient network error or server error
+// (429, 502, 503 or 504), honoring any Retry-After header sent by the server.
+// Only reads, and writes which are safe to repeat, are retried. See
+// [chttp.RetryPolicy] for details. Only honored by
+// [github.com/go-kivik/kivik/v4.New].
+func OptionRetryPolicy(policy chttp.RetryPolicy) kivik.Option {
+	return chttp.OptionRetryPolicy(policy)
+}
+
+// OptionMaxConcurrentRequests limits the number of requests sent
+// concurrently to the server. Further requests wait in a queue. See
+// [chttp.OptionMaxConcurrentRequests] for details. Only honored by
+// [github.com/go-kivik/kivik/v4.New].
+func OptionMaxConcurrentRequests(max int) kivik.Option {
+	return chttp.OptionMaxConcurrentRequests(max)
+}
+
+// OptionRateLimit limits the rate at which requests are sent to the server,
+// to perSecond requests per second with bursts of up to burst requests. See
+// [chttp.OptionRateLimit] for details. Only honored by
+// [github.com/go-kivik/kivik/v4.New].
+func OptionRateLimit(perSecond float64, burst int) kivik.Option {
+	return chttp.OptionRateLimit(perSecond, burst)
+}
+
+// LimitMonitor reports the state of the request limits of the client to which
+// it is passed with [OptionLimitMonitor].
+type LimitMonitor struct {
+	mu     sync.Mutex
+	client *chttp.Client
+}
+
+// LimitStats returns the current queue depth and wait-time statistics of the
+// client's request limits. It returns zero values if the monitor has not been
+// passed to a client.
+func (m *LimitMonitor) LimitStats() chttp.LimitStats {
+	m.mu.Lock()
+	client := m.client
+	m.mu.Unlock()
+	if client == nil {
+		return chttp.LimitStats{}
+	}
+	return client.LimitStats()
+}
+
+type optionLimitMonitor struct {
+	*LimitMonitor
+}
+
+func (o optionLimitMonitor) Apply(target any) {
+	if client, ok := target.(*chttp.Client); ok {
+		o.mu.Lock()
+		o.client = client
+		o.mu.Unlock()
+	}
+}
+
+func (optionLimitMonitor) String() string { return "[LimitMonitor]" }
+
+// OptionLimitMonitor attaches monitor to the client, so that it reports the
+// state of the limits set with [OptionMaxConcurrentRequests] and
+// [OptionRateLimit]. Only honored by [github.com/go-kivik/kivik/v4.New].
+func OptionLimitMonitor(monitor *LimitMonitor) kivik.Option {
+	return optionLimitMonitor{LimitMonitor: monitor}
+}
+
 // OptionFullCommit is the option key used to set the `X-Couch-Full-Commit`
 // header in the request when set to true.
 func OptionFullCommit() kivik.Option {
@@ -168,3 +236,67 @@ func (o multiOptions) String() string {
 	}
 	return strings.Join(parts, ",")
 }
+
+type optionRange struct {
+	start, end int64
+}
+
+func (o optionRange) Apply(target any) {
+	switch t := target.(type) {
+	case *chttp.Options:
+		if t.Header == nil {
+			t.Header = http.Header{}
+		}
+		t.Header.Set("Range", o.header())
+	case *attachmentOptions:
+		t.rangeSet = true
+		t.start, t.end = o.start, o.end
+	}
+}
+
+func (o optionRange) header() string {
+	if o.end < 0 {
+		return fmt.Sprintf("bytes=%d-", o.start)
+	}
+	return fmt.Sprintf("bytes=%d-%d", o.start, o.end)
+}
+
+func (o optionRange) String() string {
+	return "[Range: " + o.header() + "]"
+}
+
+// OptionRange requests only the bytes from start to end, inclusive, of an
+// attachment. A negative end requests everything from start to the end of
+// the attachment. Only honored by
+// [github.com/go-kivik/kivik/v4.DB.GetAttachment], and only for attachments
+// that are not stored compressed, as CouchDB does not support range requests
+// for compressed attachments.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/document/attachments.html#http-range-requests
+func OptionRange(start, end int64) kivik.Option {
+	return optionRange{start: start, end: end}
+}
+
+type optionResumableDownload int
+
+func (o optionResumableDownload) Apply(target any) {
+	if attOpts, ok := target.(*attachmentOptions); ok {
+		attOpts.maxResumes = int(o)
+	}
+}
+
+func (o optionResumableDownload) String() string {
+	return fmt.Sprintf("[ResumableDownload:%d]", int(o))
+}
+
+// OptionResumableDownload makes the content returned by
+// [github.com/go-kivik/kivik/v4.DB.GetAttachment] resume the download with a
+// range request, up to maxResumes times, when reading fails part way through.
+// When the entire attachment is downloaded, its MD5 digest is verified once
+// the end of the content is reached, and a mismatch is reported as an error
+// in place of [io.EOF].
+func OptionResumableDownload(maxResumes int) kivik.Option {
+	return optionResumableDownload(maxResumes)
+}
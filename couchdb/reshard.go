@@ -0,0 +1,120 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package couchdb
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/kivik/v4/couchdb/chttp"
+	"github.com/go-kivik/kivik/v4/driver"
+)
+
+var _ driver.Resharder = &client{}
+
+// reshardJob extends driver.ReshardJob with the state_info object, from which
+// the reason for the job's state is extracted.
+type reshardJob struct {
+	driver.ReshardJob
+	StateInfo struct {
+		Reason string `json:"reason"`
+	} `json:"state_info"`
+}
+
+func (j *reshardJob) toDriver() *driver.ReshardJob {
+	job := j.ReshardJob
+	job.Reason = j.StateInfo.Reason
+	return &job
+}
+
+func reshardJobPath(jobID string) string {
+	return "/_reshard/jobs/" + chttp.EncodeDocID(jobID)
+}
+
+func (c *client) ReshardSummary(ctx context.Context) (*driver.ReshardSummary, error) {
+	result := new(driver.ReshardSummary)
+	err := c.DoJSON(ctx, http.MethodGet, "/_reshard", nil, result)
+	return result, err
+}
+
+func (c *client) getReshardState(ctx context.Context, path string) (*driver.ReshardState, error) {
+	result := new(driver.ReshardState)
+	err := c.DoJSON(ctx, http.MethodGet, path, nil, result)
+	return result, err
+}
+
+func (c *client) setReshardState(ctx context.Context, path string, state *driver.ReshardState) error {
+	opts := &chttp.Options{
+		GetBody: chttp.BodyEncoder(state),
+		Header: http.Header{
+			chttp.HeaderIdempotencyKey: []string{},
+		},
+	}
+	_, err := c.DoError(ctx, http.MethodPut, path, opts)
+	return err
+}
+
+func (c *client) ReshardState(ctx context.Context) (*driver.ReshardState, error) {
+	return c.getReshardState(ctx, "/_reshard/state")
+}
+
+func (c *client) SetReshardState(ctx context.Context, state *driver.ReshardState) error {
+	return c.setReshardState(ctx, "/_reshard/state", state)
+}
+
+func (c *client) ReshardJobs(ctx context.Context) ([]*driver.ReshardJob, error) {
+	var result struct {
+		Jobs []*reshardJob `json:"jobs"`
+	}
+	if err := c.DoJSON(ctx, http.MethodGet, "/_reshard/jobs", nil, &result); err != nil {
+		return nil, err
+	}
+	jobs := make([]*driver.ReshardJob, len(result.Jobs))
+	for i, job := range result.Jobs {
+		jobs[i] = job.toDriver()
+	}
+	return jobs, nil
+}
+
+func (c *client) ReshardJob(ctx context.Context, jobID string) (*driver.ReshardJob, error) {
+	var result reshardJob
+	if err := c.DoJSON(ctx, http.MethodGet, reshardJobPath(jobID), nil, &result); err != nil {
+		return nil, err
+	}
+	return result.toDriver(), nil
+}
+
+func (c *client) CreateReshardJobs(ctx context.Context, req *driver.ReshardJobRequest) ([]*driver.ReshardJobResult, error) {
+	opts := &chttp.Options{
+		GetBody: chttp.BodyEncoder(req),
+		Header: http.Header{
+			chttp.HeaderIdempotencyKey: []string{},
+		},
+	}
+	var result []*driver.ReshardJobResult
+	err := c.DoJSON(ctx, http.MethodPost, "/_reshard/jobs", opts, &result)
+	return result, err
+}
+
+func (c *client) DeleteReshardJob(ctx context.Context, jobID string) error {
+	_, err := c.DoError(ctx, http.MethodDelete, reshardJobPath(jobID), nil)
+	return err
+}
+
+func (c *client) ReshardJobState(ctx context.Context, jobID string) (*driver.ReshardState, error) {
+	return c.getReshardState(ctx, reshardJobPath(jobID)+"/state")
+}
+
+func (c *client) SetReshardJobState(ctx context.Context, jobID string, state *driver.ReshardState) error {
+	return c.setReshardState(ctx, reshardJobPath(jobID)+"/state", state)
+}
@@ -0,0 +1,347 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+// GopherJS can't run a test server
+
+package couchdb
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	kivik "github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/internal/nettest"
+)
+
+func TestReshardJobs(t *testing.T) {
+	type tt struct {
+		client *client
+		want   []*driver.ReshardJob
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("network error", tt{
+		client: newTestClient(nil, errors.New("net error")),
+		status: http.StatusBadGateway,
+		err:    `Get "?http://example.com/_reshard/jobs"?: net error`,
+	})
+	tests.Add("success", func(t *testing.T) any {
+		return tt{
+			client: newTestClient(&http.Response{
+				StatusCode: http.StatusOK,
+				Body: Body(`{"jobs":[{
+					"history":[
+						{"detail":null,"timestamp":"2019-03-28T15:28:02Z","type":"new"},
+						{"detail":"Shard splitting stopped","timestamp":"2019-03-28T15:28:05Z","type":"stopped"}
+					],
+					"id":"001-171d1211418996ff47bd610b1d1257fc4ca2628868def4a05e63e8f8fe50694a",
+					"job_state":"stopped",
+					"node":"node1@127.0.0.1",
+					"source":"shards/00000000-1fffffff/d1.1553786862",
+					"split_state":"initial_copy",
+					"start_time":"2019-03-28T15:28:02Z",
+					"state_info":{"reason":"Shard splitting stopped"},
+					"target":["shards/00000000-0fffffff/d1.1553786862","shards/10000000-1fffffff/d1.1553786862"],
+					"type":"split",
+					"update_time":"2019-03-28T15:28:05Z"
+				}],"offset":0,"total_rows":1}`),
+			}, nil),
+			want: []*driver.ReshardJob{
+				{
+					ID:         "001-171d1211418996ff47bd610b1d1257fc4ca2628868def4a05e63e8f8fe50694a",
+					Type:       "split",
+					Node:       "node1@127.0.0.1",
+					Source:     "shards/00000000-1fffffff/d1.1553786862",
+					Targets:    []string{"shards/00000000-0fffffff/d1.1553786862", "shards/10000000-1fffffff/d1.1553786862"},
+					JobState:   "stopped",
+					SplitState: "initial_copy",
+					Reason:     "Shard splitting stopped",
+					History: []driver.ReshardJobEvent{
+						{Timestamp: parseTime(t, "2019-03-28T15:28:02Z"), Type: "new"},
+						{Timestamp: parseTime(t, "2019-03-28T15:28:05Z"), Type: "stopped", Detail: "Shard splitting stopped"},
+					},
+					StartTime:  parseTime(t, "2019-03-28T15:28:02Z"),
+					UpdateTime: parseTime(t, "2019-03-28T15:28:05Z"),
+				},
+			},
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.client.ReshardJobs(context.Background())
+		if d := internal.StatusErrorDiffRE(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected result (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestCreateReshardJobs(t *testing.T) {
+	type tt struct {
+		client *client
+		req    *driver.ReshardJobRequest
+		want   []*driver.ReshardJobResult
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("network error", tt{
+		client: newTestClient(nil, errors.New("net error")),
+		req:    &driver.ReshardJobRequest{Type: "split", DB: "db1"},
+		status: http.StatusBadGateway,
+		err:    `Post "?http://example.com/_reshard/jobs"?: net error`,
+	})
+	tests.Add("success", tt{
+		client: newCustomClient(func(req *http.Request) (*http.Response, error) {
+			var body map[string]string
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return nil, err
+			}
+			want := map[string]string{"type": "split", "db": "db1", "range": "80000000-ffffffff"}
+			if d := cmp.Diff(want, body); d != "" {
+				return nil, fmt.Errorf("Unexpected body: %s", d)
+			}
+			return &http.Response{
+				StatusCode: http.StatusCreated,
+				Body: Body(`[
+					{"id":"001-abc","node":"node1@127.0.0.1","ok":true,"shard":"shards/80000000-ffffffff/db1.1554148353"},
+					{"error":"conflict","node":"node2@127.0.0.1","reason":"Job already exists","shard":"shards/80000000-ffffffff/db1.1554148353"}
+				]`),
+			}, nil
+		}),
+		req: &driver.ReshardJobRequest{Type: "split", DB: "db1", Range: "80000000-ffffffff"},
+		want: []*driver.ReshardJobResult{
+			{ID: "001-abc", Node: "node1@127.0.0.1", Shard: "shards/80000000-ffffffff/db1.1554148353"},
+			{Node: "node2@127.0.0.1", Shard: "shards/80000000-ffffffff/db1.1554148353", Error: "conflict", Reason: "Job already exists"},
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.client.CreateReshardJobs(context.Background(), tt.req)
+		if d := internal.StatusErrorDiffRE(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected result (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestSetReshardJobState(t *testing.T) {
+	client := newCustomClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPut {
+			return nil, fmt.Errorf("Unexpected method: %s", req.Method)
+		}
+		if req.URL.RawPath != "/_reshard/jobs/001%2Fabc/state" {
+			return nil, fmt.Errorf("Unexpected path: %s", req.URL.RawPath)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		want := map[string]string{"state": "stopped", "reason": "maintenance"}
+		if d := cmp.Diff(want, body); d != "" {
+			return nil, fmt.Errorf("Unexpected body: %s", d)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       Body(`{"ok":true}`),
+		}, nil
+	})
+	err := client.SetReshardJobState(context.Background(), "001/abc", &driver.ReshardState{State: "stopped", Reason: "maintenance"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// reshardServer is a minimal stand-in for the CouchDB _reshard endpoints. Jobs
+// advance one step each time they are read while running, and complete after
+// three steps.
+type reshardServer struct {
+	mu     sync.Mutex
+	state  driver.ReshardState
+	jobs   map[string]*reshardJob
+	nextID int
+}
+
+func (s *reshardServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		body, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.Body = body
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/_reshard")
+	switch {
+	case path == "/state" && r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(s.state)
+	case path == "/state" && r.Method == http.MethodPut:
+		_ = json.NewDecoder(r.Body).Decode(&s.state)
+		s.setJobStates(s.state.State)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	case path == "/jobs" && r.Method == http.MethodGet:
+		jobs := make([]*reshardJob, 0, len(s.jobs))
+		for _, job := range s.jobs {
+			jobs = append(jobs, job)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"jobs": jobs})
+	case path == "/jobs" && r.Method == http.MethodPost:
+		var req driver.ReshardJobRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		s.nextID++
+		job := &reshardJob{ReshardJob: driver.ReshardJob{
+			ID:       fmt.Sprintf("%03d-%s", s.nextID, req.DB),
+			Type:     req.Type,
+			Node:     "node1@127.0.0.1",
+			Source:   "shards/00000000-ffffffff/" + req.DB,
+			JobState: kivik.ReshardJobStateRunning,
+			History:  []driver.ReshardJobEvent{{Type: "new"}},
+		}}
+		s.jobs[job.ID] = job
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode([]map[string]any{{"ok": true, "id": job.ID, "node": job.Node, "shard": job.Source}})
+	default:
+		jobID, suffix, _ := strings.Cut(strings.TrimPrefix(path, "/jobs/"), "/")
+		job, ok := s.jobs[jobID]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"not_found","reason":"missing"}`))
+			return
+		}
+		switch {
+		case suffix == "" && r.Method == http.MethodGet:
+			if job.JobState == kivik.ReshardJobStateRunning {
+				job.History = append(job.History, driver.ReshardJobEvent{Type: "checkpoint"})
+				if len(job.History) > 3 {
+					job.JobState = kivik.ReshardJobStateCompleted
+					job.SplitState = "completed"
+				}
+			}
+			_ = json.NewEncoder(w).Encode(job)
+		case suffix == "" && r.Method == http.MethodDelete:
+			delete(s.jobs, jobID)
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		case suffix == "state" && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(driver.ReshardState{State: job.JobState})
+		case suffix == "state" && r.Method == http.MethodPut:
+			var state driver.ReshardState
+			_ = json.NewDecoder(r.Body).Decode(&state)
+			job.JobState = state.State
+			job.StateInfo.Reason = state.Reason
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func (s *reshardServer) setJobStates(state string) {
+	for _, job := range s.jobs {
+		if job.JobState != kivik.ReshardJobStateCompleted {
+			job.JobState = state
+		}
+	}
+}
+
+func TestReshard_standIn(t *testing.T) {
+	s := nettest.NewHTTPTestServer(t, &reshardServer{
+		state: driver.ReshardState{State: kivik.ReshardStateRunning},
+		jobs:  map[string]*reshardJob{},
+	})
+	client, err := kivik.New("couch", s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	results, err := client.ReshardSplit(ctx, kivik.ReshardSplitRequest{DB: "db1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Error != nil {
+		t.Fatalf("Unexpected results: %v", results)
+	}
+	jobID := results[0].ID
+
+	if err := client.ReshardStopJob(ctx, jobID, "not yet"); err != nil {
+		t.Fatal(err)
+	}
+	job, err := client.ReshardJob(ctx, jobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.JobState != kivik.ReshardJobStateStopped || job.Reason != "not yet" {
+		t.Errorf("Unexpected job state: %s (%s)", job.JobState, job.Reason)
+	}
+
+	if err := client.ReshardStop(ctx, "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	state, err := client.ReshardState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(&kivik.ReshardState{State: kivik.ReshardStateStopped, Reason: "maintenance"}, state); d != "" {
+		t.Errorf("Unexpected state:\n%s", d)
+	}
+	if err := client.ReshardStart(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err = client.ReshardWaitForJob(ctx, jobID, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.JobState != kivik.ReshardJobStateCompleted {
+		t.Errorf("Unexpected job state: %s", job.JobState)
+	}
+	wantHistory := []string{"new", "checkpoint", "checkpoint", "checkpoint"}
+	gotHistory := make([]string, len(job.History))
+	for i, event := range job.History {
+		gotHistory[i] = event.Type
+	}
+	if d := cmp.Diff(wantHistory, gotHistory); d != "" {
+		t.Errorf("Unexpected history:\n%s", d)
+	}
+
+	if err := client.ReshardDeleteJob(ctx, jobID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.ReshardJob(ctx, jobID)
+	if status := kivik.HTTPStatus(err); status != http.StatusNotFound {
+		t.Errorf("Unexpected status after delete: %d", status)
+	}
+}
@@ -0,0 +1,199 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+
+	"github.com/go-kivik/kivik/v4/couchdb/chttp"
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+// attachmentOptions are the options which control how GetAttachment reads
+// the attachment content.
+type attachmentOptions struct {
+	rangeSet   bool
+	start, end int64
+	maxResumes int
+}
+
+// errAttachmentChanged is returned when the attachment's ETag changes between
+// the original request and a re-request.
+var errAttachmentChanged = &internal.Error{Status: http.StatusPreconditionFailed, Message: "attachment changed during download"}
+
+// resumableReader reads attachment content, re-requesting the remainder of
+// the attachment with a range request when a read fails.
+type resumableReader struct {
+	ctx             context.Context
+	d               *db
+	docID, filename string
+	options         driver.Options
+	etag            string
+	start, end      int64
+	resumesLeft     int
+
+	body io.ReadCloser
+	read int64
+	err  error
+
+	// hash and want are nil when the digest is not verified.
+	hash hash.Hash
+	want []byte
+}
+
+var _ io.ReadCloser = &resumableReader{}
+
+func (d *db) newResumableReader(ctx context.Context, docID, filename string, options driver.Options, attOpts *attachmentOptions, resp *http.Response) *resumableReader {
+	etag, _ := chttp.ETag(resp)
+	r := &resumableReader{
+		ctx:         ctx,
+		d:           d,
+		docID:       docID,
+		filename:    filename,
+		options:     options,
+		etag:        etag,
+		start:       attOpts.start,
+		end:         attOpts.end,
+		resumesLeft: attOpts.maxResumes,
+		body:        resp.Body,
+	}
+	// A partial download can't be checked against the attachment's digest.
+	if !attOpts.rangeSet {
+		if want := contentMD5(resp); want != nil {
+			r.hash = md5.New()
+			r.want = want
+		}
+	}
+	return r
+}
+
+// contentMD5 returns the MD5 digest of the attachment, from the Content-MD5
+// header if present, otherwise from the ETag, which CouchDB sets to the
+// base64-encoded digest. nil is returned if neither contains an MD5 digest.
+func contentMD5(resp *http.Response) []byte {
+	etag, _ := chttp.ETag(resp)
+	for _, v := range []string{resp.Header.Get("Content-MD5"), etag} {
+		sum, err := base64.StdEncoding.DecodeString(v)
+		if err == nil && len(sum) == md5.Size {
+			return sum
+		}
+	}
+	return nil
+}
+
+func (r *resumableReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	for {
+		n, err := r.body.Read(p)
+		r.read += int64(n)
+		if r.hash != nil {
+			_, _ = r.hash.Write(p[:n])
+		}
+		if err == nil {
+			return n, nil
+		}
+		if errors.Is(err, io.EOF) {
+			r.err = r.verify()
+			return n, r.err
+		}
+		if err := r.resume(err); err != nil {
+			r.err = err
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// verify returns io.EOF if the digest of the content read matches the
+// expected digest, or if there is nothing to verify.
+func (r *resumableReader) verify() error {
+	if r.hash == nil || bytes.Equal(r.hash.Sum(nil), r.want) {
+		return io.EOF
+	}
+	return &internal.Error{Status: http.StatusBadGateway, Message: "attachment digest mismatch"}
+}
+
+// resume replaces the failed response body with a new request for the
+// remainder of the attachment. A failed re-request is retried while resumes
+// remain. The most recent error is returned once no resumes remain.
+func (r *resumableReader) resume(cause error) error {
+	_ = r.body.Close()
+	for {
+		if r.resumesLeft <= 0 || r.ctx.Err() != nil {
+			return cause
+		}
+		r.resumesLeft--
+		body, err := r.reopen()
+		if err == nil {
+			r.body = body
+			return nil
+		}
+		if errors.Is(err, errAttachmentChanged) {
+			return err
+		}
+		cause = err
+	}
+}
+
+// reopen requests the remainder of the attachment, returning the new body.
+func (r *resumableReader) reopen() (io.ReadCloser, error) {
+	offset := r.start + r.read
+	resp, err := r.d.fetchAttachment(r.ctx, http.MethodGet, r.docID, r.filename, multiOptions{
+		r.options,
+		optionRange{start: offset, end: r.end},
+	})
+	if err != nil {
+		if resp != nil {
+			chttp.CloseBody(resp.Body)
+		}
+		return nil, err
+	}
+	if etag, _ := chttp.ETag(resp); etag != r.etag {
+		chttp.CloseBody(resp.Body)
+		return nil, errAttachmentChanged
+	}
+	if resp.StatusCode == http.StatusPartialContent {
+		return resp.Body, nil
+	}
+	// The server ignored the range, so skip what has already been read.
+	if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+		chttp.CloseBody(resp.Body)
+		return nil, err
+	}
+	if r.end < 0 {
+		return resp.Body, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.LimitReader(resp.Body, r.end-offset+1),
+		Closer: resp.Body,
+	}, nil
+}
+
+func (r *resumableReader) Close() error {
+	return r.body.Close()
+}
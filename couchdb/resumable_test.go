@@ -0,0 +1,200 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package couchdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"gitlab.com/flimzy/testy"
+
+	kivik "github.com/go-kivik/kivik/v4"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+func TestGetAttachmentResumable(t *testing.T) {
+	const digest = `"bNNVbesNpUvKBgtMOUeYOQ=="` // MD5 of "Hello, world!"
+
+	type tt struct {
+		db      *db
+		options kivik.Option
+		content string
+		status  int
+		err     string
+	}
+	// attResponse returns an attachment response with the given status, ETag
+	// and body.
+	attResponse := func(status int, etag string, body io.Reader) *http.Response {
+		return &http.Response{
+			StatusCode: status,
+			Header: http.Header{
+				"Content-Type": {"text/plain"},
+				"ETag":         {etag},
+			},
+			Body: io.NopCloser(body),
+		}
+	}
+	// failing returns a reader which returns content, then a network error.
+	failing := func(content string) io.Reader {
+		return io.MultiReader(strings.NewReader(content), iotest.ErrReader(errors.New("connection reset")))
+	}
+	// server returns a db that serves each of responses in turn, expecting
+	// the given Range header for each request.
+	server := func(t *testing.T, ranges []string, responses ...*http.Response) *db {
+		t.Helper()
+		var i int
+		return newCustomDB(func(req *http.Request) (*http.Response, error) {
+			if i >= len(responses) {
+				return nil, errors.New("unexpected request")
+			}
+			if rng := req.Header.Get("Range"); rng != ranges[i] {
+				return nil, fmt.Errorf("Unexpected Range for request %d: %q", i, rng)
+			}
+			resp := responses[i]
+			i++
+			return resp, nil
+		})
+	}
+
+	tests := testy.NewTable()
+	tests.Add("no error", func(t *testing.T) interface{} {
+		return tt{
+			db: server(t, []string{""},
+				attResponse(http.StatusOK, digest, strings.NewReader("Hello, world!")),
+			),
+			options: OptionResumableDownload(1),
+			content: "Hello, world!",
+		}
+	})
+	tests.Add("resumed", func(t *testing.T) interface{} {
+		return tt{
+			db: server(t, []string{"", "bytes=7-"},
+				attResponse(http.StatusOK, digest, failing("Hello, ")),
+				attResponse(http.StatusPartialContent, digest, strings.NewReader("world!")),
+			),
+			options: OptionResumableDownload(1),
+			content: "Hello, world!",
+		}
+	})
+	tests.Add("range ignored on resume", func(t *testing.T) interface{} {
+		return tt{
+			db: server(t, []string{"", "bytes=7-"},
+				attResponse(http.StatusOK, digest, failing("Hello, ")),
+				attResponse(http.StatusOK, digest, strings.NewReader("Hello, world!")),
+			),
+			options: OptionResumableDownload(1),
+			content: "Hello, world!",
+		}
+	})
+	tests.Add("resumes exhausted", func(t *testing.T) interface{} {
+		return tt{
+			db: server(t, []string{"", "bytes=7-"},
+				attResponse(http.StatusOK, digest, failing("Hello, ")),
+				attResponse(http.StatusPartialContent, digest, failing("wor")),
+			),
+			options: OptionResumableDownload(1),
+			content: "Hello, wor",
+			status:  http.StatusInternalServerError,
+			err:     "connection reset",
+		}
+	})
+	tests.Add("failed resume retried", func(t *testing.T) interface{} {
+		return tt{
+			db: server(t, []string{"", "bytes=7-", "bytes=7-"},
+				attResponse(http.StatusOK, digest, failing("Hello, ")),
+				attResponse(http.StatusServiceUnavailable, "", strings.NewReader(`{"error":"unavailable"}`)),
+				attResponse(http.StatusPartialContent, digest, strings.NewReader("world!")),
+			),
+			options: OptionResumableDownload(2),
+			content: "Hello, world!",
+		}
+	})
+	tests.Add("failed resume exhausted", func(t *testing.T) interface{} {
+		return tt{
+			db: server(t, []string{"", "bytes=7-"},
+				attResponse(http.StatusOK, digest, failing("Hello, ")),
+				attResponse(http.StatusServiceUnavailable, "", strings.NewReader(`{"error":"unavailable"}`)),
+			),
+			options: OptionResumableDownload(1),
+			content: "Hello, ",
+			status:  http.StatusServiceUnavailable,
+			err:     "Service Unavailable",
+		}
+	})
+	tests.Add("digest mismatch", func(t *testing.T) interface{} {
+		return tt{
+			db: server(t, []string{""},
+				attResponse(http.StatusOK, `"b8QiIzpAp1ofAo4Rw80RQA=="`, strings.NewReader("Hello, world!")),
+			),
+			options: OptionResumableDownload(1),
+			content: "Hello, world!",
+			status:  http.StatusBadGateway,
+			err:     "attachment digest mismatch",
+		}
+	})
+	tests.Add("attachment changed", func(t *testing.T) interface{} {
+		return tt{
+			db: server(t, []string{"", "bytes=7-"},
+				attResponse(http.StatusOK, digest, failing("Hello, ")),
+				attResponse(http.StatusPartialContent, `"b8QiIzpAp1ofAo4Rw80RQA=="`, strings.NewReader("world!")),
+			),
+			options: OptionResumableDownload(1),
+			content: "Hello, ",
+			status:  http.StatusPreconditionFailed,
+			err:     "attachment changed during download",
+		}
+	})
+	tests.Add("resumed range", func(t *testing.T) interface{} {
+		return tt{
+			db: server(t, []string{"bytes=2-9", "bytes=7-9"},
+				attResponse(http.StatusPartialContent, digest, failing("llo, ")),
+				attResponse(http.StatusPartialContent, digest, strings.NewReader("wor")),
+			),
+			options: multiOptions{OptionRange(2, 9), OptionResumableDownload(1)},
+			content: "llo, wor",
+		}
+	})
+	tests.Add("resumed range ignored", func(t *testing.T) interface{} {
+		return tt{
+			db: server(t, []string{"bytes=2-9", "bytes=7-9"},
+				attResponse(http.StatusPartialContent, digest, failing("llo, ")),
+				attResponse(http.StatusOK, digest, strings.NewReader("Hello, world!")),
+			),
+			options: multiOptions{OptionRange(2, 9), OptionResumableDownload(1)},
+			content: "llo, wor",
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		att, err := tt.db.GetAttachment(context.Background(), "foo", "foo.txt", tt.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_ = att.Content.Close()
+		})
+		content, err := io.ReadAll(att.Content)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := testy.DiffText(tt.content, string(content)); d != nil {
+			t.Errorf("Unexpected content:\n%s", d)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/go-kivik/kivik/v4/couchdb/chttp"
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+var _ driver.ViewIndexer = &db{}
+
+func (d *db) DesignDocInfo(ctx context.Context, ddoc string) (*driver.DesignDocInfo, error) {
+	var result struct {
+		driver.DesignDocInfo
+		ViewIndex struct {
+			driver.ViewIndexInfo
+			Sizes struct {
+				driver.ViewIndexSizes
+				// Some CouchDB versions report the file size as "disk".
+				Disk int64 `json:"disk"`
+			} `json:"sizes"`
+		} `json:"view_index"`
+	}
+	if err := d.DoJSON(ctx, http.MethodGet, d.path("/_design/"+chttp.EncodeDocID(ddoc)+"/_info"), nil, &result); err != nil {
+		return nil, err
+	}
+	info := result.DesignDocInfo
+	info.ViewIndex = result.ViewIndex.ViewIndexInfo
+	info.ViewIndex.Sizes = result.ViewIndex.Sizes.ViewIndexSizes
+	if info.ViewIndex.Sizes.File == 0 {
+		info.ViewIndex.Sizes.File = result.ViewIndex.Sizes.Disk
+	}
+	return &info, nil
+}
+
+// UpdateIndex triggers an index update by querying one of the design
+// document's views with update=lazy, which returns immediately, and updates
+// the index in the background. All views of a design document share a single
+// index.
+func (d *db) UpdateIndex(ctx context.Context, ddoc string) error {
+	var ddocBody struct {
+		Views map[string]json.RawMessage `json:"views"`
+	}
+	if err := d.DoJSON(ctx, http.MethodGet, d.path("/_design/"+chttp.EncodeDocID(ddoc)), nil, &ddocBody); err != nil {
+		return err
+	}
+	if len(ddocBody.Views) == 0 {
+		return &internal.Error{Status: http.StatusNotFound, Message: "design document has no views"}
+	}
+	views := make([]string, 0, len(ddocBody.Views))
+	for view := range ddocBody.Views {
+		views = append(views, view)
+	}
+	sort.Strings(views)
+	opts := &chttp.Options{
+		Query: url.Values{
+			"limit":  []string{"0"},
+			"update": []string{"lazy"},
+		},
+	}
+	_, err := d.DoError(ctx, http.MethodGet, d.path("/_design/"+chttp.EncodeDocID(ddoc)+"/_view/"+chttp.EncodeDocID(views[0])), opts)
+	return err
+}
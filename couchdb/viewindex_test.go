@@ -0,0 +1,143 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package couchdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+func TestDesignDocInfo(t *testing.T) {
+	type tt struct {
+		db     *db
+		ddoc   string
+		want   *driver.DesignDocInfo
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("network error", tt{
+		db:     newTestDB(nil, errors.New("net error")),
+		ddoc:   "foo",
+		status: http.StatusBadGateway,
+		err:    `Get "?http://example.com/testdb/_design/foo/_info"?: net error`,
+	})
+	tests.Add("success", tt{
+		db: newTestDB(&http.Response{
+			StatusCode: http.StatusOK,
+			Body: Body(`{"name":"foo","view_index":{
+				"compact_running":false,
+				"language":"javascript",
+				"purge_seq":0,
+				"signature":"a59a1bb13fdf8a8a584bc477919c97ac",
+				"sizes":{"active":926691,"external":1535701,"file":1982704},
+				"update_seq":19,
+				"updater_running":true,
+				"waiting_clients":0,
+				"waiting_commit":false
+			}}`),
+		}, nil),
+		ddoc: "foo",
+		want: &driver.DesignDocInfo{
+			Name: "foo",
+			ViewIndex: driver.ViewIndexInfo{
+				Signature:      "a59a1bb13fdf8a8a584bc477919c97ac",
+				Language:       "javascript",
+				Sizes:          driver.ViewIndexSizes{Active: 926691, External: 1535701, File: 1982704},
+				UpdateSeq:      19,
+				UpdaterRunning: true,
+			},
+		},
+	})
+	tests.Add("disk size", tt{
+		db: newTestDB(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       Body(`{"name":"foo","view_index":{"sizes":{"active":1,"disk":3,"external":2}}}`),
+		}, nil),
+		ddoc: "foo",
+		want: &driver.DesignDocInfo{
+			Name: "foo",
+			ViewIndex: driver.ViewIndexInfo{
+				Sizes: driver.ViewIndexSizes{Active: 1, External: 2, File: 3},
+			},
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.db.DesignDocInfo(context.Background(), tt.ddoc)
+		if d := internal.StatusErrorDiffRE(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected result (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestUpdateIndex(t *testing.T) {
+	type tt struct {
+		db     *db
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("network error", tt{
+		db:     newTestDB(nil, errors.New("net error")),
+		status: http.StatusBadGateway,
+		err:    `Get "?http://example.com/testdb/_design/foo"?: net error`,
+	})
+	tests.Add("no views", tt{
+		db: newTestDB(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       Body(`{"_id":"_design/foo","_rev":"1-xxx"}`),
+		}, nil),
+		status: http.StatusNotFound,
+		err:    "design document has no views",
+	})
+	tests.Add("success", tt{
+		db: newCustomDB(func(req *http.Request) (*http.Response, error) {
+			switch req.URL.Path {
+			case "/testdb/_design/foo":
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       Body(`{"_id":"_design/foo","views":{"zzz":{"map":"x"},"bar":{"map":"y"}}}`),
+				}, nil
+			case "/testdb/_design/foo/_view/bar":
+				if q := req.URL.Query(); q.Get("update") != "lazy" || q.Get("limit") != "0" {
+					return nil, fmt.Errorf("Unexpected query: %s", req.URL.RawQuery)
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       Body(`{"total_rows":0,"offset":0,"rows":[]}`),
+				}, nil
+			}
+			return nil, fmt.Errorf("Unexpected path: %s", req.URL.Path)
+		}),
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		err := tt.db.UpdateIndex(context.Background(), "foo")
+		if d := internal.StatusErrorDiffRE(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package kivik
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-kivik/kivik/v4/driver"
+)
+
+// DesignResponse is the raw response returned by a show, list or rewrite
+// function. A response with an error status code, such as a 404 set by the
+// function, is returned as a DesignResponse, rather than as an error.
+type DesignResponse struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// ContentType is the value of the Content-Type header.
+	ContentType string
+	// Header contains the response headers.
+	Header http.Header
+	// Body is the response body. It is the caller's responsibility to close
+	// Body.
+	Body io.ReadCloser
+}
+
+// UpdateResponse is the full response returned by an update function. As
+// with [DesignResponse], a response with an error status code is returned as
+// an UpdateResponse, rather than as an error.
+type UpdateResponse struct {
+	// Rev is the new revision of the updated document, or empty if the
+	// update function did not save a document.
+	Rev string
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// ContentType is the value of the Content-Type header.
+	ContentType string
+	// Header contains the response headers.
+	Header http.Header
+	// Body is the response body. It is the caller's responsibility to close
+	// Body.
+	Body io.ReadCloser
+}
+
+func (db *DB) designFunctioner() (driver.DesignFunctioner, error) {
+	if db.err != nil {
+		return nil, db.err
+	}
+	dfer, ok := db.driverDB.(driver.DesignFunctioner)
+	if !ok {
+		return nil, errDesignFuncsNotImplemented
+	}
+	return dfer, nil
+}
+
+// Show calls the named [show function] of the design document, with the
+// document docID, which may be empty. The "_design/" prefix of ddoc is
+// optional. Query parameters may be passed with [Param] or [Params].
+//
+// [show function]: https://docs.couchdb.org/en/stable/api/ddoc/render.html#get--db-_design-ddoc-_show-func-docid
+func (db *DB) Show(ctx context.Context, ddoc, funcName, docID string, options ...Option) (*DesignResponse, error) {
+	dfer, err := db.designFunctioner()
+	if err != nil {
+		return nil, err
+	}
+	ddoc = strings.TrimPrefix(ddoc, "_design/")
+	if ddoc == "" {
+		return nil, missingArg("ddoc")
+	}
+	if funcName == "" {
+		return nil, missingArg("funcName")
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	return designResponse(dfer.Show(ctx, ddoc, funcName, docID, multiOptions(options)))
+}
+
+// List calls the named [list function] of the design document, with the
+// results of view. view may be of the form "other-ddoc/view", to use a view
+// from another design document. The "_design/" prefix of ddoc is optional.
+// View options may be passed as [Param] or [Params].
+//
+// [list function]: https://docs.couchdb.org/en/stable/api/ddoc/render.html#get--db-_design-ddoc-_list-func-view
+func (db *DB) List(ctx context.Context, ddoc, funcName, view string, options ...Option) (*DesignResponse, error) {
+	dfer, err := db.designFunctioner()
+	if err != nil {
+		return nil, err
+	}
+	ddoc = strings.TrimPrefix(ddoc, "_design/")
+	if ddoc == "" {
+		return nil, missingArg("ddoc")
+	}
+	if funcName == "" {
+		return nil, missingArg("funcName")
+	}
+	if view == "" {
+		return nil, missingArg("view")
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	return designResponse(dfer.List(ctx, ddoc, funcName, view, multiOptions(options)))
+}
+
+// Rewrite sends a request with the given method, path and body, which may be
+// nil, to the design document's [rewrite] handler. An empty method defaults
+// to GET. The "_design/" prefix of ddoc is optional.
+//
+// [rewrite]: https://docs.couchdb.org/en/stable/api/ddoc/rewrites.html
+func (db *DB) Rewrite(ctx context.Context, ddoc, method, path string, body io.Reader, options ...Option) (*DesignResponse, error) {
+	dfer, err := db.designFunctioner()
+	if err != nil {
+		return nil, err
+	}
+	ddoc = strings.TrimPrefix(ddoc, "_design/")
+	if ddoc == "" {
+		return nil, missingArg("ddoc")
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	return designResponse(dfer.Rewrite(ctx, ddoc, method, strings.TrimPrefix(path, "/"), body, multiOptions(options)))
+}
+
+func designResponse(resp *driver.DesignResponse, err error) (*DesignResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	r := DesignResponse(*resp)
+	return &r, nil
+}
+
+// UpdateWithResponse works like [DB.Update], but returns the full response of
+// the [update function], including the status code, headers and body produced
+// by the function, as well as the new revision, if a document was saved. The
+// "_design/" prefix of ddoc is optional.
+//
+// [update function]: https://docs.couchdb.org/en/stable/ddocs/ddocs.html#updatefun
+func (db *DB) UpdateWithResponse(ctx context.Context, ddoc, funcName, docID string, doc any, options ...Option) (*UpdateResponse, error) {
+	if db.err != nil {
+		return nil, db.err
+	}
+	updater, ok := db.driverDB.(driver.UpdateResponder)
+	if !ok {
+		return nil, errUpdateRespNotImplemented
+	}
+	ddoc = strings.TrimPrefix(ddoc, "_design/")
+	if ddoc == "" {
+		return nil, missingArg("ddoc")
+	}
+	if funcName == "" {
+		return nil, missingArg("funcName")
+	}
+	if docID == "" {
+		return nil, missingArg("docID")
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	i, err := normalizeFromJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := updater.UpdateWithResponse(ctx, ddoc, funcName, docID, i, multiOptions(options))
+	if err != nil {
+		return nil, err
+	}
+	r := UpdateResponse(*resp)
+	return &r, nil
+}
@@ -0,0 +1,302 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package kivik
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+func TestDesignFuncs(t *testing.T) {
+	type tt struct {
+		db     *DB
+		call   func(*DB) (*DesignResponse, error)
+		want   *DesignResponse
+		status int
+		err    string
+	}
+	html := func() *driver.DesignResponse {
+		return &driver.DesignResponse{
+			StatusCode:  http.StatusOK,
+			ContentType: "text/html",
+			Header:      http.Header{"Content-Type": {"text/html"}},
+			Body:        io.NopCloser(strings.NewReader("<p>Hello</p>")),
+		}
+	}
+	want := &DesignResponse{
+		StatusCode:  http.StatusOK,
+		ContentType: "text/html",
+		Header:      http.Header{"Content-Type": {"text/html"}},
+	}
+
+	tests := testy.NewTable()
+	tests.Add("show, non-DesignFunctioner", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.DB{},
+		},
+		call: func(db *DB) (*DesignResponse, error) {
+			return db.Show(context.Background(), "app", "page", "foo")
+		},
+		status: http.StatusNotImplemented,
+		err:    "kivik: driver does not support show, list or rewrite functions",
+	})
+	tests.Add("show, db error", tt{
+		db: &DB{
+			err: errors.New("db error"),
+		},
+		call: func(db *DB) (*DesignResponse, error) {
+			return db.Show(context.Background(), "app", "page", "foo")
+		},
+		status: http.StatusInternalServerError,
+		err:    "db error",
+	})
+	tests.Add("show, missing ddoc", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.DesignFunctioner{},
+		},
+		call: func(db *DB) (*DesignResponse, error) {
+			return db.Show(context.Background(), "_design/", "page", "foo")
+		},
+		status: http.StatusBadRequest,
+		err:    "kivik: ddoc required",
+	})
+	tests.Add("show, missing funcName", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.DesignFunctioner{},
+		},
+		call: func(db *DB) (*DesignResponse, error) {
+			return db.Show(context.Background(), "app", "", "foo")
+		},
+		status: http.StatusBadRequest,
+		err:    "kivik: funcName required",
+	})
+	tests.Add("show, driver error", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.DesignFunctioner{
+				ShowFunc: func(context.Context, string, string, string, driver.Options) (*driver.DesignResponse, error) {
+					return nil, &internal.Error{Status: http.StatusNotFound, Message: "missing show function"}
+				},
+			},
+		},
+		call: func(db *DB) (*DesignResponse, error) {
+			return db.Show(context.Background(), "app", "page", "foo")
+		},
+		status: http.StatusNotFound,
+		err:    "missing show function",
+	})
+	tests.Add("show", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.DesignFunctioner{
+				ShowFunc: func(_ context.Context, ddoc, funcName, docID string, _ driver.Options) (*driver.DesignResponse, error) {
+					if ddoc != "app" || funcName != "page" || docID != "foo" {
+						return nil, fmt.Errorf("Unexpected args: %s %s %s", ddoc, funcName, docID)
+					}
+					return html(), nil
+				},
+			},
+		},
+		call: func(db *DB) (*DesignResponse, error) {
+			return db.Show(context.Background(), "_design/app", "page", "foo")
+		},
+		want: want,
+	})
+	tests.Add("list, missing view", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.DesignFunctioner{},
+		},
+		call: func(db *DB) (*DesignResponse, error) {
+			return db.List(context.Background(), "app", "table", "")
+		},
+		status: http.StatusBadRequest,
+		err:    "kivik: view required",
+	})
+	tests.Add("list", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.DesignFunctioner{
+				ListFunc: func(_ context.Context, ddoc, funcName, view string, _ driver.Options) (*driver.DesignResponse, error) {
+					if ddoc != "app" || funcName != "table" || view != "other/by_date" {
+						return nil, fmt.Errorf("Unexpected args: %s %s %s", ddoc, funcName, view)
+					}
+					return html(), nil
+				},
+			},
+		},
+		call: func(db *DB) (*DesignResponse, error) {
+			return db.List(context.Background(), "_design/app", "table", "other/by_date")
+		},
+		want: want,
+	})
+	tests.Add("rewrite", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.DesignFunctioner{
+				RewriteFunc: func(_ context.Context, ddoc, method, path string, body io.Reader, _ driver.Options) (*driver.DesignResponse, error) {
+					if ddoc != "app" || method != http.MethodGet || path != "posts/1" || body != nil {
+						return nil, fmt.Errorf("Unexpected args: %s %s %s %v", ddoc, method, path, body)
+					}
+					return html(), nil
+				},
+			},
+		},
+		call: func(db *DB) (*DesignResponse, error) {
+			return db.Rewrite(context.Background(), "app", "", "/posts/1", nil)
+		},
+		want: want,
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.call(tt.db)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := cmp.Diff(tt.want, got, cmpopts.IgnoreFields(DesignResponse{}, "Body")); d != "" {
+			t.Errorf("Unexpected result (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestUpdateWithResponse(t *testing.T) {
+	type tt struct {
+		db                    *DB
+		ddoc, funcName, docID string
+		doc                   any
+		want                  *UpdateResponse
+		status                int
+		err                   string
+	}
+
+	tests := testy.NewTable()
+	tests.Add("non-UpdateResponder", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.DB{},
+		},
+		ddoc:     "app",
+		funcName: "bump",
+		docID:    "foo",
+		status:   http.StatusNotImplemented,
+		err:      "kivik: driver does not support update function responses",
+	})
+	tests.Add("db error", tt{
+		db: &DB{
+			err: errors.New("db error"),
+		},
+		status: http.StatusInternalServerError,
+		err:    "db error",
+	})
+	tests.Add("missing ddoc", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.UpdateResponder{},
+		},
+		ddoc:   "_design/",
+		status: http.StatusBadRequest,
+		err:    "kivik: ddoc required",
+	})
+	tests.Add("missing funcName", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.UpdateResponder{},
+		},
+		ddoc:   "app",
+		status: http.StatusBadRequest,
+		err:    "kivik: funcName required",
+	})
+	tests.Add("missing docID", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.UpdateResponder{},
+		},
+		ddoc:     "app",
+		funcName: "bump",
+		status:   http.StatusBadRequest,
+		err:      "kivik: docID required",
+	})
+	tests.Add("driver error", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.UpdateResponder{
+				UpdateWithResponseFunc: func(context.Context, string, string, string, any, driver.Options) (*driver.UpdateResponse, error) {
+					return nil, &internal.Error{Status: http.StatusNotFound, Message: "missing update function"}
+				},
+			},
+		},
+		ddoc:     "app",
+		funcName: "bump",
+		docID:    "foo",
+		status:   http.StatusNotFound,
+		err:      "missing update function",
+	})
+	tests.Add("success", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.UpdateResponder{
+				UpdateWithResponseFunc: func(_ context.Context, ddoc, funcName, docID string, doc any, _ driver.Options) (*driver.UpdateResponse, error) {
+					if ddoc != "app" || funcName != "bump" || docID != "foo" {
+						return nil, fmt.Errorf("Unexpected args: %s %s %s", ddoc, funcName, docID)
+					}
+					if d := testy.DiffInterface(map[string]any{"a": "b"}, doc); d != nil {
+						return nil, fmt.Errorf("Unexpected doc:\n%s", d)
+					}
+					return &driver.UpdateResponse{
+						Rev:         "2-xxx",
+						StatusCode:  http.StatusCreated,
+						ContentType: "text/plain",
+						Header:      http.Header{"Content-Type": {"text/plain"}},
+						Body:        io.NopCloser(strings.NewReader("bumped")),
+					}, nil
+				},
+			},
+		},
+		ddoc:     "_design/app",
+		funcName: "bump",
+		docID:    "foo",
+		doc:      map[string]any{"a": "b"},
+		want: &UpdateResponse{
+			Rev:         "2-xxx",
+			StatusCode:  http.StatusCreated,
+			ContentType: "text/plain",
+			Header:      http.Header{"Content-Type": {"text/plain"}},
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.db.UpdateWithResponse(context.Background(), tt.ddoc, tt.funcName, tt.docID, tt.doc)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := cmp.Diff(tt.want, got, cmpopts.IgnoreFields(UpdateResponse{}, "Body")); d != "" {
+			t.Errorf("Unexpected result (-want +got):\n%s", d)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package driver
+
+import (
+	"context"
+	"io"
+)
+
+// Backuper is an optional interface that may be implemented by a [Client] to
+// support online backup and restore of all of its databases.
+type Backuper interface {
+	// Backup writes a consistent snapshot of all databases to w, in a
+	// driver-specific format. Backup must not block other reads or writes
+	// for its duration.
+	Backup(ctx context.Context, w io.Writer, options Options) error
+	// Restore replaces all databases with a snapshot read from r, as
+	// written by Backup.
+	Restore(ctx context.Context, r io.Reader, options Options) error
+}
@@ -0,0 +1,74 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package driver
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// DesignResponse is the raw response returned by a show, list or rewrite
+// function. A response with an error status code, such as a 404 set by the
+// function, should be returned as a DesignResponse, rather than as an error.
+type DesignResponse struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// ContentType is the value of the Content-Type header.
+	ContentType string
+	// Header contains the response headers.
+	Header http.Header
+	// Body is the response body. The caller must close it.
+	Body io.ReadCloser
+}
+
+// DesignFunctioner is an optional interface that may be implemented by a [DB]
+// to support invoking show, list and rewrite functions. The ddoc argument
+// never includes the "_design/" prefix.
+type DesignFunctioner interface {
+	// Show calls the named show function with the document docID, which may
+	// be empty.
+	Show(ctx context.Context, ddoc, funcName, docID string, options Options) (*DesignResponse, error)
+	// List calls the named list function with the results of view, which may
+	// be of the form "ddoc/view" to use a view from another design document.
+	List(ctx context.Context, ddoc, funcName, view string, options Options) (*DesignResponse, error)
+	// Rewrite sends a request with the given method, path and body, which may
+	// be nil, to the design document's rewrite handler.
+	Rewrite(ctx context.Context, ddoc, method, path string, body io.Reader, options Options) (*DesignResponse, error)
+}
+
+// UpdateResponse is the full response returned by an update function. As
+// with [DesignResponse], a response with an error status code should be
+// returned as an UpdateResponse, rather than as an error.
+type UpdateResponse struct {
+	// Rev is the new revision of the updated document, or empty if the
+	// update function did not save a document.
+	Rev string
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// ContentType is the value of the Content-Type header.
+	ContentType string
+	// Header contains the response headers.
+	Header http.Header
+	// Body is the response body. The caller must close it.
+	Body io.ReadCloser
+}
+
+// UpdateResponder is an optional interface that may be implemented by a [DB]
+// to support invoking update functions, and returning the full response. The
+// ddoc argument never includes the "_design/" prefix.
+type UpdateResponder interface {
+	// UpdateWithResponse calls the named update function with the provided
+	// document.
+	UpdateWithResponse(ctx context.Context, ddoc, funcName, docID string, doc any, options Options) (*UpdateResponse, error)
+}
@@ -0,0 +1,49 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package driver
+
+import "context"
+
+// DocShards describes the shard range and nodes responsible for storing a
+// single document, as returned by [DBMaintainer.DocShards].
+type DocShards struct {
+	Range string   `json:"range"`
+	Nodes []string `json:"nodes"`
+}
+
+// DBMaintainer is an optional interface that may be implemented by a [DB] to
+// support database maintenance operations, such as managing the revision
+// limit, the purged infos limit, and shard placement.
+type DBMaintainer interface {
+	// RevsLimit returns the maximum number of document revisions tracked by
+	// the database.
+	RevsLimit(ctx context.Context) (int, error)
+	// SetRevsLimit sets the maximum number of document revisions tracked by
+	// the database.
+	SetRevsLimit(ctx context.Context, limit int) error
+	// PurgedInfosLimit returns the maximum number of historical purges
+	// tracked by the database.
+	PurgedInfosLimit(ctx context.Context) (int, error)
+	// SetPurgedInfosLimit sets the maximum number of historical purges
+	// tracked by the database.
+	SetPurgedInfosLimit(ctx context.Context, limit int) error
+	// Shards returns the shard map of the database, keyed by shard range, with
+	// the list of nodes holding a copy of each shard as values.
+	Shards(ctx context.Context) (map[string][]string, error)
+	// DocShards returns the shard range and nodes that store the named
+	// document.
+	DocShards(ctx context.Context, docID string) (*DocShards, error)
+	// SyncShards forces a synchronization of all shard replicas of the
+	// database.
+	SyncShards(ctx context.Context) error
+}
@@ -0,0 +1,101 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package driver
+
+import (
+	"context"
+	"time"
+)
+
+// ReshardSummary is a summary of the resharding state of the cluster, as
+// returned by [Resharder.ReshardSummary].
+type ReshardSummary struct {
+	State       string `json:"state"`
+	StateReason string `json:"state_reason"`
+	Completed   int    `json:"completed"`
+	Failed      int    `json:"failed"`
+	Running     int    `json:"running"`
+	Stopped     int    `json:"stopped"`
+	Total       int    `json:"total"`
+}
+
+// ReshardState is the state of resharding, either globally or of a single
+// job.
+type ReshardState struct {
+	State  string `json:"state"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ReshardJobEvent is a single entry in the history of a resharding job.
+type ReshardJobEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Type      string    `json:"type"`
+	Detail    string    `json:"detail"`
+}
+
+// ReshardJob describes a single resharding job.
+type ReshardJob struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Node       string            `json:"node"`
+	Source     string            `json:"source"`
+	Targets    []string          `json:"target"`
+	JobState   string            `json:"job_state"`
+	SplitState string            `json:"split_state"`
+	Reason     string            `json:"-"`
+	History    []ReshardJobEvent `json:"history"`
+	StartTime  time.Time         `json:"start_time"`
+	UpdateTime time.Time         `json:"update_time"`
+}
+
+// ReshardJobRequest describes the resharding jobs to be created by
+// [Resharder.CreateReshardJobs].
+type ReshardJobRequest struct {
+	Type  string `json:"type"`
+	DB    string `json:"db,omitempty"`
+	Node  string `json:"node,omitempty"`
+	Range string `json:"range,omitempty"`
+	Shard string `json:"shard,omitempty"`
+}
+
+// ReshardJobResult is the result of creating a single resharding job.
+type ReshardJobResult struct {
+	ID     string `json:"id"`
+	Node   string `json:"node"`
+	Shard  string `json:"shard"`
+	Error  string `json:"error"`
+	Reason string `json:"reason"`
+}
+
+// Resharder is an optional interface that may be implemented by a [Client] to
+// support managing the resharding of database shards.
+type Resharder interface {
+	// ReshardSummary returns a summary of the resharding state and jobs.
+	ReshardSummary(ctx context.Context) (*ReshardSummary, error)
+	// ReshardState returns the global resharding state.
+	ReshardState(ctx context.Context) (*ReshardState, error)
+	// SetReshardState sets the global resharding state.
+	SetReshardState(ctx context.Context, state *ReshardState) error
+	// ReshardJobs returns all resharding jobs.
+	ReshardJobs(ctx context.Context) ([]*ReshardJob, error)
+	// ReshardJob returns a single resharding job.
+	ReshardJob(ctx context.Context, jobID string) (*ReshardJob, error)
+	// CreateReshardJobs creates one or more resharding jobs.
+	CreateReshardJobs(ctx context.Context, req *ReshardJobRequest) ([]*ReshardJobResult, error)
+	// DeleteReshardJob stops and removes a resharding job.
+	DeleteReshardJob(ctx context.Context, jobID string) error
+	// ReshardJobState returns the state of a single resharding job.
+	ReshardJobState(ctx context.Context, jobID string) (*ReshardState, error)
+	// SetReshardJobState sets the state of a single resharding job.
+	SetReshardJobState(ctx context.Context, jobID string, state *ReshardState) error
+}
@@ -45,3 +45,15 @@ type Searcher interface {
 	// SearchAnalyze tests the results of Lucene analyzer tokenization on sample text.
 	SearchAnalyze(ctx context.Context, text string) ([]string, error)
 }
+
+// SearchFacets is an optional interface that may be implemented by the [Rows]
+// returned by [Searcher.Search], to return the results of the counts and
+// ranges facet options.
+type SearchFacets interface {
+	// Counts returns the number of matching documents for each value of each
+	// field listed in the counts option, or nil if none were requested.
+	Counts() map[string]map[string]int64
+	// Ranges returns the number of matching documents within each labelled
+	// range of each field in the ranges option, or nil if none were requested.
+	Ranges() map[string]map[string]int64
+}
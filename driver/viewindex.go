@@ -0,0 +1,51 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package driver
+
+import "context"
+
+// DesignDocInfo contains information about a design document's view index,
+// as returned by [ViewIndexer.DesignDocInfo].
+type DesignDocInfo struct {
+	Name      string        `json:"name"`
+	ViewIndex ViewIndexInfo `json:"view_index"`
+}
+
+// ViewIndexInfo describes the state of a design document's view index.
+type ViewIndexInfo struct {
+	Signature      string         `json:"signature"`
+	Language       string         `json:"language"`
+	Sizes          ViewIndexSizes `json:"sizes"`
+	UpdateSeq      int64          `json:"update_seq"`
+	PurgeSeq       int64          `json:"purge_seq"`
+	UpdaterRunning bool           `json:"updater_running"`
+	CompactRunning bool           `json:"compact_running"`
+}
+
+// ViewIndexSizes contains the size, in bytes, of a view index.
+type ViewIndexSizes struct {
+	Active   int64 `json:"active"`
+	External int64 `json:"external"`
+	File     int64 `json:"file"`
+}
+
+// ViewIndexer is an optional interface that may be implemented by a [DB] to
+// report on, and trigger updates of, design document view indexes.
+type ViewIndexer interface {
+	// DesignDocInfo returns information about the view index of the named
+	// design document. ddoc is provided without the "_design/" prefix.
+	DesignDocInfo(ctx context.Context, ddoc string) (*DesignDocInfo, error)
+	// UpdateIndex triggers an update of the view index of the named design
+	// document. It may return before the update has completed.
+	UpdateIndex(ctx context.Context, ddoc string) error
+}
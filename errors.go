@@ -33,6 +33,12 @@ const (
 	errReplicationNotImplemented = internal.CompositeError("501 driver does not support replication")
 	errNoAttachments             = internal.CompositeError("404 no attachments")
 	errUpdateNotImplemented      = internal.CompositeError("501 driver does not support Update interface")
+	errMaintainerNotImplemented  = internal.CompositeError("501 driver does not support database maintenance operations")
+	errReshardNotImplemented     = internal.CompositeError("501 driver does not support resharding")
+	errViewIndexerNotImplemented = internal.CompositeError("501 driver does not support view index operations")
+	errDesignFuncsNotImplemented = internal.CompositeError("501 driver does not support show, list or rewrite functions")
+	errUpdateRespNotImplemented  = internal.CompositeError("501 driver does not support update function responses")
+	errBackupNotImplemented      = internal.CompositeError("501 driver does not support backup and restore")
 )
 
 // HTTPStatus returns the HTTP status code embedded in the error, or 500
@@ -93,6 +93,8 @@ func toQuery(query any, options ...Option) (json.RawMessage, error) {
 // CreateIndex creates an index if it doesn't already exist. ddoc and name may
 // be empty, in which case they will be auto-generated.  index must be
 // marshalable to a valid index object, as described in the [CouchDB documentation].
+// If index is an [IndexDefinition], such as a [*JSONIndex], its type is also
+// passed to the driver, as the "type" option.
 //
 // [CouchDB documentation]: http://docs.couchdb.org/en/stable/api/database/find.html#db-index
 func (db *DB) CreateIndex(ctx context.Context, ddoc, name string, index any, options ...Option) error {
@@ -105,6 +107,9 @@ func (db *DB) CreateIndex(ctx context.Context, ddoc, name string, index any, opt
 	}
 	defer endQuery()
 	if finder, ok := db.driverDB.(driver.Finder); ok {
+		if def, ok := index.(IndexDefinition); ok {
+			options = append([]Option{Param("type", def.IndexType())}, options...)
+		}
 		return finder.CreateIndex(ctx, ddoc, name, index, multiOptions(options))
 	}
 	return errFindNotImplemented
@@ -128,10 +133,13 @@ func (db *DB) DeleteIndex(ctx context.Context, ddoc, name string, options ...Opt
 
 // Index is a MonboDB-style index definition.
 type Index struct {
-	DesignDoc  string `json:"ddoc,omitempty"`
-	Name       string `json:"name"`
-	Type       string `json:"type"`
-	Definition any    `json:"def"`
+	DesignDoc string `json:"ddoc,omitempty"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	// Definition is the index definition. For json, text and nouveau
+	// indexes, it is a [*JSONIndex], [*TextIndex] or [*NouveauIndex],
+	// respectively.
+	Definition any `json:"def"`
 }
 
 // GetIndexes returns the indexes defined on the current database.
@@ -149,6 +157,7 @@ func (db *DB) GetIndexes(ctx context.Context, options ...Option) ([]Index, error
 		indexes := make([]Index, len(dIndexes))
 		for i, index := range dIndexes {
 			indexes[i] = Index(index)
+			indexes[i].Definition = typedIndexDefinition(index.Type, index.Definition)
 		}
 		return indexes, err
 	}
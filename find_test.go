@@ -213,6 +213,23 @@ func TestCreateIndex(t *testing.T) {
 			name:  "bar",
 			index: int(3),
 		},
+		{
+			testName: "typed definition",
+			db: &DB{
+				client: &Client{},
+				driverDB: &mock.Finder{
+					CreateIndexFunc: func(_ context.Context, _, _ string, _ any, options driver.Options) error {
+						opts := map[string]any{}
+						options.Apply(opts)
+						if opts["type"] != IndexTypeText {
+							return fmt.Errorf("Unexpected type: %v", opts["type"])
+						}
+						return nil
+					},
+				},
+			},
+			index: &TextIndex{Fields: []TextField{{Name: "foo", Type: "string"}}},
+		},
 		{
 			name: "closed",
 			db: &DB{
@@ -377,6 +394,61 @@ func TestGetIndexes(t *testing.T) {
 				},
 			},
 		},
+		{
+			testName: "typed definitions",
+			db: &DB{
+				client: &Client{},
+				driverDB: &mock.Finder{
+					GetIndexesFunc: func(context.Context, driver.Options) ([]driver.Index, error) {
+						return []driver.Index{
+							{
+								Name:       "_all_docs",
+								Type:       "special",
+								Definition: map[string]any{"fields": []any{map[string]any{"_id": "asc"}}},
+							},
+							{
+								DesignDoc: "_design/foo",
+								Name:      "foo",
+								Type:      "json",
+								Definition: map[string]any{
+									"fields":                  []any{map[string]any{"foo": "desc"}},
+									"partial_filter_selector": map[string]any{"type": "user"},
+								},
+							},
+							{
+								Name:       "bar",
+								Type:       "text",
+								Definition: json.RawMessage(`{"fields":[{"name":"bar","type":"string"}],"default_analyzer":"keyword"}`),
+							},
+						}, nil
+					},
+				},
+			},
+			expected: []Index{
+				{
+					Name:       "_all_docs",
+					Type:       "special",
+					Definition: map[string]any{"fields": []any{map[string]any{"_id": "asc"}}},
+				},
+				{
+					DesignDoc: "_design/foo",
+					Name:      "foo",
+					Type:      "json",
+					Definition: &JSONIndex{
+						Fields:                []IndexField{{Name: "foo", Direction: Descending}},
+						PartialFilterSelector: map[string]any{"type": "user"},
+					},
+				},
+				{
+					Name: "bar",
+					Type: "text",
+					Definition: &TextIndex{
+						Fields:          []TextField{{Name: "bar", Type: "string"}},
+						DefaultAnalyzer: "keyword",
+					},
+				},
+			},
+		},
 		{
 			testName: "client closed",
 			db: &DB{
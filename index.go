@@ -0,0 +1,179 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package kivik
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Mango index types, as reported by [Index.Type].
+const (
+	IndexTypeJSON    = "json"
+	IndexTypeText    = "text"
+	IndexTypeNouveau = "nouveau"
+	IndexTypeSpecial = "special"
+)
+
+// IndexDefinition is a typed Mango index definition. A value of this type may
+// be passed to [DB.CreateIndex] in place of a raw index object, in which case
+// the index type is sent to the server along with the definition. For
+// json, text and nouveau indexes, [DB.GetIndexes] returns a typed
+// definition in [Index.Definition].
+type IndexDefinition interface {
+	// IndexType returns the index type, one of [IndexTypeJSON],
+	// [IndexTypeText] or [IndexTypeNouveau].
+	IndexType() string
+}
+
+var (
+	_ IndexDefinition = (*JSONIndex)(nil)
+	_ IndexDefinition = (*TextIndex)(nil)
+	_ IndexDefinition = (*NouveauIndex)(nil)
+)
+
+// SortDirection is the sort direction of an indexed field.
+type SortDirection string
+
+// Sort directions for [IndexField].
+const (
+	Ascending  SortDirection = "asc"
+	Descending SortDirection = "desc"
+)
+
+// IndexField is a field of a [JSONIndex]. If Direction is empty, the field is
+// marshaled as a bare field name, which CouchDB treats as ascending.
+type IndexField struct {
+	Name      string
+	Direction SortDirection
+}
+
+// MarshalJSON satisfies the [encoding/json.Marshaler] interface.
+func (f IndexField) MarshalJSON() ([]byte, error) {
+	if f.Direction == "" {
+		return json.Marshal(f.Name)
+	}
+	return json.Marshal(map[string]SortDirection{f.Name: f.Direction})
+}
+
+// UnmarshalJSON satisfies the [encoding/json.Unmarshaler] interface. It
+// accepts both a bare field name, and an object of the form
+// {"field": "direction"}.
+func (f *IndexField) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		*f = IndexField{}
+		return json.Unmarshal(data, &f.Name)
+	}
+	var m map[string]SortDirection
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	if len(m) != 1 {
+		return fmt.Errorf("index field must have exactly one key, got %d", len(m))
+	}
+	for name, dir := range m {
+		*f = IndexField{Name: name, Direction: dir}
+	}
+	return nil
+}
+
+// JSONIndex is the definition of a json Mango index.
+type JSONIndex struct {
+	Fields []IndexField `json:"fields"`
+	// PartialFilterSelector, if set, restricts the index to documents which
+	// match the selector.
+	PartialFilterSelector map[string]any `json:"partial_filter_selector,omitempty"`
+}
+
+// IndexType returns [IndexTypeJSON].
+func (*JSONIndex) IndexType() string { return IndexTypeJSON }
+
+// TextField is a field of a [TextIndex] or [NouveauIndex].
+type TextField struct {
+	Name string `json:"name"`
+	// Type is one of "string", "number" or "boolean".
+	Type string `json:"type"`
+}
+
+// TextDefaultField configures the default field of a [TextIndex].
+type TextDefaultField struct {
+	Enabled  *bool  `json:"enabled,omitempty"`
+	Analyzer string `json:"analyzer,omitempty"`
+}
+
+// TextIndex is the definition of a text Mango index, as supported by CouchDB
+// servers with search enabled.
+type TextIndex struct {
+	// Fields lists the fields to index. If empty, all fields are indexed.
+	Fields          []TextField       `json:"fields,omitempty"`
+	DefaultAnalyzer string            `json:"default_analyzer,omitempty"`
+	DefaultField    *TextDefaultField `json:"default_field,omitempty"`
+	// Selector restricts the documents which are indexed.
+	Selector map[string]any `json:"selector,omitempty"`
+	// PartialFilterSelector, if set, restricts the index to documents which
+	// match the selector.
+	PartialFilterSelector map[string]any `json:"partial_filter_selector,omitempty"`
+	IndexArrayLengths     *bool          `json:"index_array_lengths,omitempty"`
+}
+
+// IndexType returns [IndexTypeText].
+func (*TextIndex) IndexType() string { return IndexTypeText }
+
+// NouveauIndex is the definition of a nouveau Mango index, as supported by
+// CouchDB servers with nouveau enabled.
+type NouveauIndex struct {
+	// Fields lists the fields to index. If empty, all fields are indexed.
+	Fields          []TextField `json:"fields,omitempty"`
+	DefaultAnalyzer string      `json:"default_analyzer,omitempty"`
+	// PartialFilterSelector, if set, restricts the index to documents which
+	// match the selector.
+	PartialFilterSelector map[string]any `json:"partial_filter_selector,omitempty"`
+}
+
+// IndexType returns [IndexTypeNouveau].
+func (*NouveauIndex) IndexType() string { return IndexTypeNouveau }
+
+// typedIndexDefinition converts an index definition, as returned by a driver,
+// to the typed definition for indexType. Definitions of unknown types, or
+// which cannot be converted, are returned unaltered.
+func typedIndexDefinition(indexType string, def any) any {
+	var typed IndexDefinition
+	switch indexType {
+	case IndexTypeJSON:
+		typed = &JSONIndex{}
+	case IndexTypeText:
+		typed = &TextIndex{}
+	case IndexTypeNouveau:
+		typed = &NouveauIndex{}
+	default:
+		return def
+	}
+	if _, ok := def.(IndexDefinition); ok {
+		return def
+	}
+	var raw []byte
+	switch t := def.(type) {
+	case json.RawMessage:
+		raw = t
+	default:
+		var err error
+		if raw, err = json.Marshal(def); err != nil {
+			return def
+		}
+	}
+	if err := json.Unmarshal(raw, typed); err != nil {
+		return def
+	}
+	return typed
+}
@@ -0,0 +1,84 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package kivik
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gitlab.com/flimzy/testy"
+)
+
+func TestIndexFieldJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  IndexField
+		json  string
+		err   string
+	}{
+		{
+			name:  "bare name",
+			input: `"foo"`,
+			want:  IndexField{Name: "foo"},
+			json:  `"foo"`,
+		},
+		{
+			name:  "with direction",
+			input: `{"foo":"desc"}`,
+			want:  IndexField{Name: "foo", Direction: Descending},
+			json:  `{"foo":"desc"}`,
+		},
+		{
+			name:  "too many keys",
+			input: `{"foo":"asc","bar":"asc"}`,
+			err:   "index field must have exactly one key, got 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got IndexField
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if !testy.ErrorMatches(tt.err, err) {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if err != nil {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Unexpected result: %+v", got)
+			}
+			out, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.json {
+				t.Errorf("Unexpected JSON: %s", out)
+			}
+		})
+	}
+}
+
+func TestJSONIndexMarshal(t *testing.T) {
+	idx := &JSONIndex{
+		Fields: []IndexField{
+			{Name: "name", Direction: Ascending},
+			{Name: "age"},
+		},
+		PartialFilterSelector: map[string]any{"type": "user"},
+	}
+	want := `{"fields":[{"name":"asc"},"age"],"partial_filter_selector":{"type":"user"}}`
+	if d := testy.DiffAsJSON([]byte(want), idx); d != nil {
+		t.Error(d)
+	}
+}
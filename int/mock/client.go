@@ -14,6 +14,7 @@ package mock
 
 import (
 	"context"
+	"io"
 
 	"github.com/go-kivik/kivik/v4/driver"
 )
@@ -194,3 +195,83 @@ func (c *Configer) SetConfigValue(ctx context.Context, node, section, key, value
 func (c *Configer) DeleteConfigKey(ctx context.Context, node, section, key string) (string, error) {
 	return c.DeleteConfigKeyFunc(ctx, node, section, key)
 }
+
+// Resharder mocks driver.Client and driver.Resharder
+type Resharder struct {
+	*Client
+	ReshardSummaryFunc     func(context.Context) (*driver.ReshardSummary, error)
+	ReshardStateFunc       func(context.Context) (*driver.ReshardState, error)
+	SetReshardStateFunc    func(context.Context, *driver.ReshardState) error
+	ReshardJobsFunc        func(context.Context) ([]*driver.ReshardJob, error)
+	ReshardJobFunc         func(context.Context, string) (*driver.ReshardJob, error)
+	CreateReshardJobsFunc  func(context.Context, *driver.ReshardJobRequest) ([]*driver.ReshardJobResult, error)
+	DeleteReshardJobFunc   func(context.Context, string) error
+	ReshardJobStateFunc    func(context.Context, string) (*driver.ReshardState, error)
+	SetReshardJobStateFunc func(context.Context, string, *driver.ReshardState) error
+}
+
+var _ driver.Resharder = &Resharder{}
+
+// ReshardSummary calls c.ReshardSummaryFunc
+func (c *Resharder) ReshardSummary(ctx context.Context) (*driver.ReshardSummary, error) {
+	return c.ReshardSummaryFunc(ctx)
+}
+
+// ReshardState calls c.ReshardStateFunc
+func (c *Resharder) ReshardState(ctx context.Context) (*driver.ReshardState, error) {
+	return c.ReshardStateFunc(ctx)
+}
+
+// SetReshardState calls c.SetReshardStateFunc
+func (c *Resharder) SetReshardState(ctx context.Context, state *driver.ReshardState) error {
+	return c.SetReshardStateFunc(ctx, state)
+}
+
+// ReshardJobs calls c.ReshardJobsFunc
+func (c *Resharder) ReshardJobs(ctx context.Context) ([]*driver.ReshardJob, error) {
+	return c.ReshardJobsFunc(ctx)
+}
+
+// ReshardJob calls c.ReshardJobFunc
+func (c *Resharder) ReshardJob(ctx context.Context, jobID string) (*driver.ReshardJob, error) {
+	return c.ReshardJobFunc(ctx, jobID)
+}
+
+// CreateReshardJobs calls c.CreateReshardJobsFunc
+func (c *Resharder) CreateReshardJobs(ctx context.Context, req *driver.ReshardJobRequest) ([]*driver.ReshardJobResult, error) {
+	return c.CreateReshardJobsFunc(ctx, req)
+}
+
+// DeleteReshardJob calls c.DeleteReshardJobFunc
+func (c *Resharder) DeleteReshardJob(ctx context.Context, jobID string) error {
+	return c.DeleteReshardJobFunc(ctx, jobID)
+}
+
+// ReshardJobState calls c.ReshardJobStateFunc
+func (c *Resharder) ReshardJobState(ctx context.Context, jobID string) (*driver.ReshardState, error) {
+	return c.ReshardJobStateFunc(ctx, jobID)
+}
+
+// SetReshardJobState calls c.SetReshardJobStateFunc
+func (c *Resharder) SetReshardJobState(ctx context.Context, jobID string, state *driver.ReshardState) error {
+	return c.SetReshardJobStateFunc(ctx, jobID, state)
+}
+
+// Backuper mocks driver.Client and driver.Backuper
+type Backuper struct {
+	*Client
+	BackupFunc  func(context.Context, io.Writer, driver.Options) error
+	RestoreFunc func(context.Context, io.Reader, driver.Options) error
+}
+
+var _ driver.Backuper = &Backuper{}
+
+// Backup calls c.BackupFunc
+func (c *Backuper) Backup(ctx context.Context, w io.Writer, options driver.Options) error {
+	return c.BackupFunc(ctx, w, options)
+}
+
+// Restore calls c.RestoreFunc
+func (c *Backuper) Restore(ctx context.Context, r io.Reader, options driver.Options) error {
+	return c.RestoreFunc(ctx, r, options)
+}
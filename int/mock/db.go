@@ -14,6 +14,7 @@ package mock
 
 import (
 	"context"
+	"io"
 
 	"github.com/go-kivik/kivik/v4/driver"
 )
@@ -51,6 +52,19 @@ func (db *Updater) Update(ctx context.Context, ddocID, funcName, docID string, d
 	return db.UpdateFunc(ctx, ddocID, funcName, docID, doc, options)
 }
 
+// UpdateResponder is a stub for a [github.com/go-kivik/v4/driver.UpdateResponder].
+type UpdateResponder struct {
+	DB
+	UpdateWithResponseFunc func(ctx context.Context, ddoc, funcName, docID string, doc any, options driver.Options) (*driver.UpdateResponse, error)
+}
+
+var _ driver.UpdateResponder = &UpdateResponder{}
+
+// UpdateWithResponse calls db.UpdateWithResponseFunc
+func (db *UpdateResponder) UpdateWithResponse(ctx context.Context, ddoc, funcName, docID string, doc any, options driver.Options) (*driver.UpdateResponse, error) {
+	return db.UpdateWithResponseFunc(ctx, ddoc, funcName, docID, doc, options)
+}
+
 // DocCreator is a stub for a [github.com/go-kivik/v4/driver.DocCreator].
 type DocCreator struct {
 	DB
@@ -333,3 +347,96 @@ type PartitionedDB struct {
 func (db *PartitionedDB) PartitionStats(ctx context.Context, name string) (*driver.PartitionStats, error) {
 	return db.PartitionStatsFunc(ctx, name)
 }
+
+// DBMaintainer mocks a driver.DB and a driver.DBMaintainer.
+type DBMaintainer struct {
+	*DB
+	RevsLimitFunc           func(context.Context) (int, error)
+	SetRevsLimitFunc        func(context.Context, int) error
+	PurgedInfosLimitFunc    func(context.Context) (int, error)
+	SetPurgedInfosLimitFunc func(context.Context, int) error
+	ShardsFunc              func(context.Context) (map[string][]string, error)
+	DocShardsFunc           func(context.Context, string) (*driver.DocShards, error)
+	SyncShardsFunc          func(context.Context) error
+}
+
+var _ driver.DBMaintainer = &DBMaintainer{}
+
+// RevsLimit calls db.RevsLimitFunc.
+func (db *DBMaintainer) RevsLimit(ctx context.Context) (int, error) {
+	return db.RevsLimitFunc(ctx)
+}
+
+// SetRevsLimit calls db.SetRevsLimitFunc.
+func (db *DBMaintainer) SetRevsLimit(ctx context.Context, limit int) error {
+	return db.SetRevsLimitFunc(ctx, limit)
+}
+
+// PurgedInfosLimit calls db.PurgedInfosLimitFunc.
+func (db *DBMaintainer) PurgedInfosLimit(ctx context.Context) (int, error) {
+	return db.PurgedInfosLimitFunc(ctx)
+}
+
+// SetPurgedInfosLimit calls db.SetPurgedInfosLimitFunc.
+func (db *DBMaintainer) SetPurgedInfosLimit(ctx context.Context, limit int) error {
+	return db.SetPurgedInfosLimitFunc(ctx, limit)
+}
+
+// Shards calls db.ShardsFunc.
+func (db *DBMaintainer) Shards(ctx context.Context) (map[string][]string, error) {
+	return db.ShardsFunc(ctx)
+}
+
+// DocShards calls db.DocShardsFunc.
+func (db *DBMaintainer) DocShards(ctx context.Context, docID string) (*driver.DocShards, error) {
+	return db.DocShardsFunc(ctx, docID)
+}
+
+// SyncShards calls db.SyncShardsFunc.
+func (db *DBMaintainer) SyncShards(ctx context.Context) error {
+	return db.SyncShardsFunc(ctx)
+}
+
+// ViewIndexer mocks a driver.DB and a driver.ViewIndexer.
+type ViewIndexer struct {
+	*DB
+	DesignDocInfoFunc func(context.Context, string) (*driver.DesignDocInfo, error)
+	UpdateIndexFunc   func(context.Context, string) error
+}
+
+var _ driver.ViewIndexer = &ViewIndexer{}
+
+// DesignDocInfo calls db.DesignDocInfoFunc.
+func (db *ViewIndexer) DesignDocInfo(ctx context.Context, ddoc string) (*driver.DesignDocInfo, error) {
+	return db.DesignDocInfoFunc(ctx, ddoc)
+}
+
+// UpdateIndex calls db.UpdateIndexFunc.
+func (db *ViewIndexer) UpdateIndex(ctx context.Context, ddoc string) error {
+	return db.UpdateIndexFunc(ctx, ddoc)
+}
+
+// DesignFunctioner mocks a driver.DB and a driver.DesignFunctioner.
+type DesignFunctioner struct {
+	*DB
+	ShowFunc    func(ctx context.Context, ddoc, funcName, docID string, options driver.Options) (*driver.DesignResponse, error)
+	ListFunc    func(ctx context.Context, ddoc, funcName, view string, options driver.Options) (*driver.DesignResponse, error)
+	RewriteFunc func(ctx context.Context, ddoc, method, path string, body io.Reader, options driver.Options) (*driver.DesignResponse, error)
+}
+
+var _ driver.DesignFunctioner = &DesignFunctioner{}
+
+// Show calls db.ShowFunc.
+func (db *DesignFunctioner) Show(ctx context.Context, ddoc, funcName, docID string, options driver.Options) (*driver.DesignResponse, error) {
+	return db.ShowFunc(ctx, ddoc, funcName, docID, options)
+}
+
+// List calls db.ListFunc.
+func (db *DesignFunctioner) List(ctx context.Context, ddoc, funcName, view string, options driver.Options) (*driver.DesignResponse, error) {
+	return db.ListFunc(ctx, ddoc, funcName, view, options)
+}
+
+// Rewrite calls db.RewriteFunc.
+func (db *DesignFunctioner) Rewrite(ctx context.Context, ddoc, method, path string, body io.Reader, options driver.Options) (*driver.DesignResponse, error) {
+	return db.RewriteFunc(ctx, ddoc, method, path, body, options)
+}
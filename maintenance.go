@@ -0,0 +1,196 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package kivik
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+func (db *DB) maintainer() (driver.DBMaintainer, error) {
+	if db.err != nil {
+		return nil, db.err
+	}
+	m, ok := db.driverDB.(driver.DBMaintainer)
+	if !ok {
+		return nil, errMaintainerNotImplemented
+	}
+	return m, nil
+}
+
+func validateLimit(limit int) error {
+	if limit < 1 {
+		return &internal.Error{Status: http.StatusBadRequest, Message: "kivik: limit must be a positive integer"}
+	}
+	return nil
+}
+
+// RevsLimit returns the maximum number of document revisions that will be
+// tracked by the database, even after compaction has occurred.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/database/misc.html#get--db-_revs_limit
+func (db *DB) RevsLimit(ctx context.Context) (int, error) {
+	m, err := db.maintainer()
+	if err != nil {
+		return 0, err
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return 0, err
+	}
+	defer endQuery()
+	return m.RevsLimit(ctx)
+}
+
+// SetRevsLimit sets the maximum number of document revisions that will be
+// tracked by the database, even after compaction has occurred.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/database/misc.html#put--db-_revs_limit
+func (db *DB) SetRevsLimit(ctx context.Context, limit int) error {
+	m, err := db.maintainer()
+	if err != nil {
+		return err
+	}
+	if err := validateLimit(limit); err != nil {
+		return err
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return err
+	}
+	defer endQuery()
+	return m.SetRevsLimit(ctx, limit)
+}
+
+// PurgedInfosLimit returns the maximum number of historical purges that will
+// be tracked by the database.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/database/misc.html#get--db-_purged_infos_limit
+func (db *DB) PurgedInfosLimit(ctx context.Context) (int, error) {
+	m, err := db.maintainer()
+	if err != nil {
+		return 0, err
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return 0, err
+	}
+	defer endQuery()
+	return m.PurgedInfosLimit(ctx)
+}
+
+// SetPurgedInfosLimit sets the maximum number of historical purges that will
+// be tracked by the database.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/database/misc.html#put--db-_purged_infos_limit
+func (db *DB) SetPurgedInfosLimit(ctx context.Context, limit int) error {
+	m, err := db.maintainer()
+	if err != nil {
+		return err
+	}
+	if err := validateLimit(limit); err != nil {
+		return err
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return err
+	}
+	defer endQuery()
+	return m.SetPurgedInfosLimit(ctx, limit)
+}
+
+// Shards returns the shard map of the database. The map key is the shard
+// range, and the value is the list of nodes holding a replica of that shard.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/database/shard.html#get--db-_shards
+func (db *DB) Shards(ctx context.Context) (map[string][]string, error) {
+	m, err := db.maintainer()
+	if err != nil {
+		return nil, err
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	return m.Shards(ctx)
+}
+
+// DocShards describes the shard range and nodes responsible for storing a
+// single document, as returned by [DB.DocShards].
+type DocShards struct {
+	// Range is the shard range which contains the document.
+	Range string `json:"range"`
+	// Nodes is the list of nodes holding a replica of the shard.
+	Nodes []string `json:"nodes"`
+}
+
+// DocShards returns the shard range and the list of nodes that store the
+// specified document.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/database/shard.html#get--db-_shards-docid
+func (db *DB) DocShards(ctx context.Context, docID string) (*DocShards, error) {
+	m, err := db.maintainer()
+	if err != nil {
+		return nil, err
+	}
+	if docID == "" {
+		return nil, missingArg("docID")
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	shards, err := m.DocShards(ctx, docID)
+	if err != nil {
+		return nil, err
+	}
+	s := DocShards(*shards)
+	return &s, nil
+}
+
+// SyncShards forces a synchronization of all shard replicas of the database.
+// This is normally only necessary after resharding, or after a node has been
+// offline for some time.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/database/shard.html#post--db-_sync_shards
+func (db *DB) SyncShards(ctx context.Context) error {
+	m, err := db.maintainer()
+	if err != nil {
+		return err
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return err
+	}
+	defer endQuery()
+	return m.SyncShards(ctx)
+}
@@ -0,0 +1,272 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package kivik
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+func TestRevsLimit(t *testing.T) {
+	type tt struct {
+		db     *DB
+		want   int
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("non-DBMaintainer", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.DB{},
+		},
+		status: http.StatusNotImplemented,
+		err:    "kivik: driver does not support database maintenance operations",
+	})
+	tests.Add("error", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.DBMaintainer{
+				RevsLimitFunc: func(context.Context) (int, error) {
+					return 0, &internal.Error{Status: http.StatusBadGateway, Err: errors.New("limit error")}
+				},
+			},
+		},
+		status: http.StatusBadGateway,
+		err:    "limit error",
+	})
+	tests.Add("success", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.DBMaintainer{
+				RevsLimitFunc: func(context.Context) (int, error) {
+					return 1000, nil
+				},
+			},
+		},
+		want: 1000,
+	})
+	tests.Add("client closed", tt{
+		db: &DB{
+			client: &Client{
+				closed: true,
+			},
+			driverDB: &mock.DBMaintainer{},
+		},
+		status: http.StatusServiceUnavailable,
+		err:    "kivik: client closed",
+	})
+	tests.Add("db error", tt{
+		db: &DB{
+			err: errors.New("db error"),
+		},
+		status: http.StatusInternalServerError,
+		err:    "db error",
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.db.RevsLimit(context.Background())
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if got != tt.want {
+			t.Errorf("Unexpected result: want %d, got %d", tt.want, got)
+		}
+	})
+}
+
+func TestSetRevsLimit(t *testing.T) {
+	type tt struct {
+		db     *DB
+		limit  int
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("non-DBMaintainer", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.DB{},
+		},
+		limit:  10,
+		status: http.StatusNotImplemented,
+		err:    "kivik: driver does not support database maintenance operations",
+	})
+	tests.Add("zero limit", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.DBMaintainer{},
+		},
+		status: http.StatusBadRequest,
+		err:    "kivik: limit must be a positive integer",
+	})
+	tests.Add("success", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.DBMaintainer{
+				SetRevsLimitFunc: func(_ context.Context, limit int) error {
+					if limit != 10 {
+						return fmt.Errorf("Unexpected limit: %d", limit)
+					}
+					return nil
+				},
+			},
+		},
+		limit: 10,
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		err := tt.db.SetRevsLimit(context.Background(), tt.limit)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+	})
+}
+
+func TestSetPurgedInfosLimit(t *testing.T) {
+	type tt struct {
+		db     *DB
+		limit  int
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("negative limit", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.DBMaintainer{},
+		},
+		limit:  -1,
+		status: http.StatusBadRequest,
+		err:    "kivik: limit must be a positive integer",
+	})
+	tests.Add("success", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.DBMaintainer{
+				SetPurgedInfosLimitFunc: func(_ context.Context, limit int) error {
+					if limit != 5 {
+						return fmt.Errorf("Unexpected limit: %d", limit)
+					}
+					return nil
+				},
+			},
+		},
+		limit: 5,
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		err := tt.db.SetPurgedInfosLimit(context.Background(), tt.limit)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+	})
+}
+
+func TestDocShards(t *testing.T) {
+	type tt struct {
+		db     *DB
+		docID  string
+		want   *DocShards
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("missing docID", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.DBMaintainer{},
+		},
+		status: http.StatusBadRequest,
+		err:    "kivik: docID required",
+	})
+	tests.Add("error", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.DBMaintainer{
+				DocShardsFunc: func(context.Context, string) (*driver.DocShards, error) {
+					return nil, &internal.Error{Status: http.StatusNotFound, Err: errors.New("not found")}
+				},
+			},
+		},
+		docID:  "foo",
+		status: http.StatusNotFound,
+		err:    "not found",
+	})
+	tests.Add("success", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.DBMaintainer{
+				DocShardsFunc: func(_ context.Context, docID string) (*driver.DocShards, error) {
+					if docID != "foo" {
+						return nil, fmt.Errorf("Unexpected docID: %s", docID)
+					}
+					return &driver.DocShards{
+						Range: "e0000000-ffffffff",
+						Nodes: []string{"node1@127.0.0.1"},
+					}, nil
+				},
+			},
+		},
+		docID: "foo",
+		want: &DocShards{
+			Range: "e0000000-ffffffff",
+			Nodes: []string{"node1@127.0.0.1"},
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.db.DocShards(context.Background(), tt.docID)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected result (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestShards(t *testing.T) {
+	db := &DB{
+		client: &Client{},
+		driverDB: &mock.DBMaintainer{
+			ShardsFunc: func(context.Context) (map[string][]string, error) {
+				return map[string][]string{
+					"00000000-7fffffff": {"node1@127.0.0.1"},
+					"80000000-ffffffff": {"node1@127.0.0.1"},
+				}, nil
+			},
+		},
+	}
+	got, err := db.Shards(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"00000000-7fffffff": {"node1@127.0.0.1"},
+		"80000000-ffffffff": {"node1@127.0.0.1"},
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Unexpected result (-want +got):\n%s", d)
+	}
+}
@@ -33,6 +33,8 @@ var (
 	_ driver.Sessioner     = &driverClient{}
 	_ driver.Configer      = &driverClient{}
 	_ driver.AllDBsStatser = &driverClient{}
+	_ driver.Resharder     = &driverClient{}
+	_ driver.Backuper      = &driverClient{}
 )
 
 func (c *driverClient) CreateDB(ctx context.Context, name string, options driver.Options) error {
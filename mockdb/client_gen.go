@@ -4,11 +4,13 @@ package mockdb
 
 import (
 	"context"
+	"io"
 
 	"github.com/go-kivik/kivik/v4/driver"
 )
 
 var _ = (*driver.Attachment)(nil)
+var _ = io.EOF
 
 func (c *driverClient) AllDBs(ctx context.Context, options driver.Options) ([]string, error) {
 	expected := &ExpectedAllDBs{
@@ -25,6 +27,22 @@ func (c *driverClient) AllDBs(ctx context.Context, options driver.Options) ([]st
 	return expected.ret0, expected.wait(ctx)
 }
 
+func (c *driverClient) Backup(ctx context.Context, arg0 io.Writer, options driver.Options) error {
+	expected := &ExpectedBackup{
+		arg0: arg0,
+		commonExpectation: commonExpectation{
+			options: options,
+		},
+	}
+	if err := c.nextExpectation(expected); err != nil {
+		return err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0, options)
+	}
+	return expected.wait(ctx)
+}
+
 func (c *driverClient) Close() error {
 	expected := &ExpectedClose{}
 	if err := c.nextExpectation(expected); err != nil {
@@ -137,6 +155,22 @@ func (c *driverClient) Ping(ctx context.Context) (bool, error) {
 	return expected.ret0, expected.wait(ctx)
 }
 
+func (c *driverClient) Restore(ctx context.Context, arg0 io.Reader, options driver.Options) error {
+	expected := &ExpectedRestore{
+		arg0: arg0,
+		commonExpectation: commonExpectation{
+			options: options,
+		},
+	}
+	if err := c.nextExpectation(expected); err != nil {
+		return err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0, options)
+	}
+	return expected.wait(ctx)
+}
+
 func (c *driverClient) SetConfigValue(ctx context.Context, arg0 string, arg1 string, arg2 string, arg3 string) (string, error) {
 	expected := &ExpectedSetConfigValue{
 		arg0: arg0,
@@ -195,6 +229,19 @@ func (c *driverClient) ConfigSection(ctx context.Context, arg0 string, arg1 stri
 	return expected.ret0, expected.wait(ctx)
 }
 
+func (c *driverClient) CreateReshardJobs(ctx context.Context, arg0 *driver.ReshardJobRequest) ([]*driver.ReshardJobResult, error) {
+	expected := &ExpectedCreateReshardJobs{
+		arg0: arg0,
+	}
+	if err := c.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
 func (c *driverClient) DB(arg0 string, options driver.Options) (driver.DB, error) {
 	expected := &ExpectedDB{
 		arg0: arg0,
@@ -242,6 +289,19 @@ func (c *driverClient) DBsStats(ctx context.Context, arg0 []string) ([]*driver.D
 	return expected.ret0, expected.wait(ctx)
 }
 
+func (c *driverClient) DeleteReshardJob(ctx context.Context, arg0 string) error {
+	expected := &ExpectedDeleteReshardJob{
+		arg0: arg0,
+	}
+	if err := c.nextExpectation(expected); err != nil {
+		return err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0)
+	}
+	return expected.wait(ctx)
+}
+
 func (c *driverClient) GetReplications(ctx context.Context, options driver.Options) ([]driver.Replication, error) {
 	expected := &ExpectedGetReplications{
 		commonExpectation: commonExpectation{
@@ -285,6 +345,65 @@ func (c *driverClient) Replicate(ctx context.Context, arg0 string, arg1 string,
 	return &driverReplication{Replication: expected.ret0}, expected.wait(ctx)
 }
 
+func (c *driverClient) ReshardJob(ctx context.Context, arg0 string) (*driver.ReshardJob, error) {
+	expected := &ExpectedReshardJob{
+		arg0: arg0,
+	}
+	if err := c.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
+func (c *driverClient) ReshardJobState(ctx context.Context, arg0 string) (*driver.ReshardState, error) {
+	expected := &ExpectedReshardJobState{
+		arg0: arg0,
+	}
+	if err := c.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
+func (c *driverClient) ReshardJobs(ctx context.Context) ([]*driver.ReshardJob, error) {
+	expected := &ExpectedReshardJobs{}
+	if err := c.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
+func (c *driverClient) ReshardState(ctx context.Context) (*driver.ReshardState, error) {
+	expected := &ExpectedReshardState{}
+	if err := c.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
+func (c *driverClient) ReshardSummary(ctx context.Context) (*driver.ReshardSummary, error) {
+	expected := &ExpectedReshardSummary{}
+	if err := c.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
 func (c *driverClient) Session(ctx context.Context) (*driver.Session, error) {
 	expected := &ExpectedSession{}
 	if err := c.nextExpectation(expected); err != nil {
@@ -296,6 +415,33 @@ func (c *driverClient) Session(ctx context.Context) (*driver.Session, error) {
 	return expected.ret0, expected.wait(ctx)
 }
 
+func (c *driverClient) SetReshardJobState(ctx context.Context, arg0 string, arg1 *driver.ReshardState) error {
+	expected := &ExpectedSetReshardJobState{
+		arg0: arg0,
+		arg1: arg1,
+	}
+	if err := c.nextExpectation(expected); err != nil {
+		return err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0, arg1)
+	}
+	return expected.wait(ctx)
+}
+
+func (c *driverClient) SetReshardState(ctx context.Context, arg0 *driver.ReshardState) error {
+	expected := &ExpectedSetReshardState{
+		arg0: arg0,
+	}
+	if err := c.nextExpectation(expected); err != nil {
+		return err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0)
+	}
+	return expected.wait(ctx)
+}
+
 func (c *driverClient) Version(ctx context.Context) (*driver.Version, error) {
 	expected := &ExpectedVersion{}
 	if err := c.nextExpectation(expected); err != nil {
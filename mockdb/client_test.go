@@ -13,8 +13,11 @@
 package mockdb
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -1339,3 +1342,140 @@ func TestGetReplications(t *testing.T) {
 	})
 	tests.Run(t, testMock)
 }
+
+func TestReshardState(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("unexpected", mockTest{
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			_, err := c.ReshardState(context.TODO())
+			if !testy.ErrorMatches("call to ReshardState() was not expected, all expectations already fulfilled", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+	})
+	tests.Add("success", mockTest{
+		setup: func(m *Client) {
+			m.ExpectReshardState().WillReturn(&driver.ReshardState{State: "running"})
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			state, err := c.ReshardState(context.TODO())
+			if !testy.ErrorMatches("", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if state.State != "running" {
+				t.Errorf("Unexpected state: %s", state.State)
+			}
+		},
+	})
+	tests.Run(t, testMock)
+}
+
+func TestReshardStop(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("success", mockTest{
+		setup: func(m *Client) {
+			m.ExpectSetReshardState().WithState(&driver.ReshardState{State: "stopped", Reason: "maintenance"})
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			err := c.ReshardStop(context.TODO(), "maintenance")
+			if !testy.ErrorMatches("", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+	})
+	tests.Add("wrong state", mockTest{
+		setup: func(m *Client) {
+			m.ExpectSetReshardState().WithState(&driver.ReshardState{State: "running"})
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			err := c.ReshardStop(context.TODO(), "maintenance")
+			if !testy.ErrorMatchesRE(`Actual: call to SetReshardState\(\) which:\s+- has state: stopped`, err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+		err: `there is a remaining unmet expectation: call to SetReshardState\(\) which:\s+- has state: running`,
+	})
+	tests.Run(t, testMock)
+}
+
+func TestReshardSplit(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("success", mockTest{
+		setup: func(m *Client) {
+			m.ExpectCreateReshardJobs().
+				WithRequest(&driver.ReshardJobRequest{Type: "split", DB: "foo"}).
+				WillReturn([]*driver.ReshardJobResult{{ID: "001-abc"}})
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			results, err := c.ReshardSplit(context.TODO(), kivik.ReshardSplitRequest{DB: "foo"})
+			if !testy.ErrorMatches("", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if len(results) != 1 || results[0].ID != "001-abc" {
+				t.Errorf("Unexpected results: %v", results)
+			}
+		},
+	})
+	tests.Run(t, testMock)
+}
+
+func TestBackup(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("error", mockTest{
+		setup: func(m *Client) {
+			m.ExpectBackup().WillReturnError(errors.New("foo err"))
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			err := c.Backup(context.TODO(), &bytes.Buffer{})
+			if !testy.ErrorMatches("foo err", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+	})
+	tests.Add("success", mockTest{
+		setup: func(m *Client) {
+			m.ExpectBackup().WillExecute(func(_ context.Context, w io.Writer, _ driver.Options) error {
+				_, err := w.Write([]byte("snapshot"))
+				return err
+			})
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			buf := &bytes.Buffer{}
+			err := c.Backup(context.TODO(), buf)
+			if !testy.ErrorMatches("", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if buf.String() != "snapshot" {
+				t.Errorf("Unexpected backup: %s", buf.String())
+			}
+		},
+	})
+	tests.Run(t, testMock)
+}
+
+func TestRestore(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("error", mockTest{
+		setup: func(m *Client) {
+			m.ExpectRestore().WillReturnError(errors.New("foo err"))
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			err := c.Restore(context.TODO(), strings.NewReader("snapshot"))
+			if !testy.ErrorMatches("foo err", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+	})
+	tests.Add("success", mockTest{
+		setup: func(m *Client) {
+			m.ExpectRestore()
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			err := c.Restore(context.TODO(), strings.NewReader("snapshot"))
+			if !testy.ErrorMatches("", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+	})
+	tests.Run(t, testMock)
+}
@@ -5,6 +5,7 @@ package mockdb
 import (
 	"context"
 	"fmt"
+	"io"
 	"reflect"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 
 var _ = &driver.Attachment{}
 var _ = reflect.Int
+var _ = io.EOF
 
 // ExpectedAllDBs represents an expectation for a call to AllDBs().
 type ExpectedAllDBs struct {
@@ -66,6 +68,58 @@ func (e *ExpectedAllDBs) method(v bool) string {
 	return fmt.Sprintf("AllDBs(ctx, %s)", options)
 }
 
+// ExpectedBackup represents an expectation for a call to Backup().
+type ExpectedBackup struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 io.Writer, options driver.Options) error
+	arg0     io.Writer
+}
+
+// WithOptions sets the expected options for the call to Backup().
+func (e *ExpectedBackup) WithOptions(options ...kivik.Option) *ExpectedBackup {
+	e.options = multiOptions{e.options, multiOptions(options)}
+	return e
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedBackup) WillExecute(cb func(ctx context.Context, arg0 io.Writer, options driver.Options) error) *ExpectedBackup {
+	e.callback = cb
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to Backup().
+func (e *ExpectedBackup) WillReturnError(err error) *ExpectedBackup {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to Backup() to delay.
+func (e *ExpectedBackup) WillDelay(delay time.Duration) *ExpectedBackup {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedBackup) met(ex expectation) bool {
+	exp := ex.(*ExpectedBackup)
+	if exp.arg0 != nil && !reflect.DeepEqual(exp.arg0, e.arg0) {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedBackup) method(v bool) string {
+	if !v {
+		return "Backup()"
+	}
+	arg0, options := "?", formatOptions(e.options)
+	if e.arg0 != nil {
+		arg0 = fmt.Sprintf("%v", e.arg0)
+	}
+	return fmt.Sprintf("Backup(ctx, %s, %s)", arg0, options)
+}
+
 // ExpectedClose represents an expectation for a call to Close().
 type ExpectedClose struct {
 	commonExpectation
@@ -483,6 +537,58 @@ func (e *ExpectedPing) method(v bool) string {
 	return fmt.Sprintf("Ping(ctx)")
 }
 
+// ExpectedRestore represents an expectation for a call to Restore().
+type ExpectedRestore struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 io.Reader, options driver.Options) error
+	arg0     io.Reader
+}
+
+// WithOptions sets the expected options for the call to Restore().
+func (e *ExpectedRestore) WithOptions(options ...kivik.Option) *ExpectedRestore {
+	e.options = multiOptions{e.options, multiOptions(options)}
+	return e
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedRestore) WillExecute(cb func(ctx context.Context, arg0 io.Reader, options driver.Options) error) *ExpectedRestore {
+	e.callback = cb
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to Restore().
+func (e *ExpectedRestore) WillReturnError(err error) *ExpectedRestore {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to Restore() to delay.
+func (e *ExpectedRestore) WillDelay(delay time.Duration) *ExpectedRestore {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedRestore) met(ex expectation) bool {
+	exp := ex.(*ExpectedRestore)
+	if exp.arg0 != nil && !reflect.DeepEqual(exp.arg0, e.arg0) {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedRestore) method(v bool) string {
+	if !v {
+		return "Restore()"
+	}
+	arg0, options := "?", formatOptions(e.options)
+	if e.arg0 != nil {
+		arg0 = fmt.Sprintf("%v", e.arg0)
+	}
+	return fmt.Sprintf("Restore(ctx, %s, %s)", arg0, options)
+}
+
 // ExpectedSetConfigValue represents an expectation for a call to SetConfigValue().
 type ExpectedSetConfigValue struct {
 	commonExpectation
@@ -721,6 +827,59 @@ func (e *ExpectedConfigSection) method(v bool) string {
 	return fmt.Sprintf("ConfigSection(ctx, %s, %s)", arg0, arg1)
 }
 
+// ExpectedCreateReshardJobs represents an expectation for a call to CreateReshardJobs().
+type ExpectedCreateReshardJobs struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 *driver.ReshardJobRequest) ([]*driver.ReshardJobResult, error)
+	arg0     *driver.ReshardJobRequest
+	ret0     []*driver.ReshardJobResult
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedCreateReshardJobs) WillExecute(cb func(ctx context.Context, arg0 *driver.ReshardJobRequest) ([]*driver.ReshardJobResult, error)) *ExpectedCreateReshardJobs {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to CreateReshardJobs().
+func (e *ExpectedCreateReshardJobs) WillReturn(ret0 []*driver.ReshardJobResult) *ExpectedCreateReshardJobs {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to CreateReshardJobs().
+func (e *ExpectedCreateReshardJobs) WillReturnError(err error) *ExpectedCreateReshardJobs {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to CreateReshardJobs() to delay.
+func (e *ExpectedCreateReshardJobs) WillDelay(delay time.Duration) *ExpectedCreateReshardJobs {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedCreateReshardJobs) met(ex expectation) bool {
+	exp := ex.(*ExpectedCreateReshardJobs)
+	if exp.arg0 != nil && !reflect.DeepEqual(exp.arg0, e.arg0) {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedCreateReshardJobs) method(v bool) string {
+	if !v {
+		return "CreateReshardJobs()"
+	}
+	arg0 := "?"
+	if e.arg0 != nil {
+		arg0 = fmt.Sprintf("%v", e.arg0)
+	}
+	return fmt.Sprintf("CreateReshardJobs(ctx, %s)", arg0)
+}
+
 // ExpectedDB represents an expectation for a call to DB().
 type ExpectedDB struct {
 	commonExpectation
@@ -878,6 +1037,52 @@ func (e *ExpectedDBsStats) method(v bool) string {
 	return fmt.Sprintf("DBsStats(ctx, %s)", arg0)
 }
 
+// ExpectedDeleteReshardJob represents an expectation for a call to DeleteReshardJob().
+type ExpectedDeleteReshardJob struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 string) error
+	arg0     string
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedDeleteReshardJob) WillExecute(cb func(ctx context.Context, arg0 string) error) *ExpectedDeleteReshardJob {
+	e.callback = cb
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DeleteReshardJob().
+func (e *ExpectedDeleteReshardJob) WillReturnError(err error) *ExpectedDeleteReshardJob {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DeleteReshardJob() to delay.
+func (e *ExpectedDeleteReshardJob) WillDelay(delay time.Duration) *ExpectedDeleteReshardJob {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedDeleteReshardJob) met(ex expectation) bool {
+	exp := ex.(*ExpectedDeleteReshardJob)
+	if exp.arg0 != "" && exp.arg0 != e.arg0 {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedDeleteReshardJob) method(v bool) string {
+	if !v {
+		return "DeleteReshardJob()"
+	}
+	arg0 := "?"
+	if e.arg0 != "" {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	return fmt.Sprintf("DeleteReshardJob(ctx, %s)", arg0)
+}
+
 // ExpectedGetReplications represents an expectation for a call to GetReplications().
 type ExpectedGetReplications struct {
 	commonExpectation
@@ -1039,6 +1244,244 @@ func (e *ExpectedReplicate) method(v bool) string {
 	return fmt.Sprintf("Replicate(ctx, %s, %s, %s)", arg0, arg1, options)
 }
 
+// ExpectedReshardJob represents an expectation for a call to ReshardJob().
+type ExpectedReshardJob struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 string) (*driver.ReshardJob, error)
+	arg0     string
+	ret0     *driver.ReshardJob
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedReshardJob) WillExecute(cb func(ctx context.Context, arg0 string) (*driver.ReshardJob, error)) *ExpectedReshardJob {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to ReshardJob().
+func (e *ExpectedReshardJob) WillReturn(ret0 *driver.ReshardJob) *ExpectedReshardJob {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to ReshardJob().
+func (e *ExpectedReshardJob) WillReturnError(err error) *ExpectedReshardJob {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to ReshardJob() to delay.
+func (e *ExpectedReshardJob) WillDelay(delay time.Duration) *ExpectedReshardJob {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedReshardJob) met(ex expectation) bool {
+	exp := ex.(*ExpectedReshardJob)
+	if exp.arg0 != "" && exp.arg0 != e.arg0 {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedReshardJob) method(v bool) string {
+	if !v {
+		return "ReshardJob()"
+	}
+	arg0 := "?"
+	if e.arg0 != "" {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	return fmt.Sprintf("ReshardJob(ctx, %s)", arg0)
+}
+
+// ExpectedReshardJobState represents an expectation for a call to ReshardJobState().
+type ExpectedReshardJobState struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 string) (*driver.ReshardState, error)
+	arg0     string
+	ret0     *driver.ReshardState
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedReshardJobState) WillExecute(cb func(ctx context.Context, arg0 string) (*driver.ReshardState, error)) *ExpectedReshardJobState {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to ReshardJobState().
+func (e *ExpectedReshardJobState) WillReturn(ret0 *driver.ReshardState) *ExpectedReshardJobState {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to ReshardJobState().
+func (e *ExpectedReshardJobState) WillReturnError(err error) *ExpectedReshardJobState {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to ReshardJobState() to delay.
+func (e *ExpectedReshardJobState) WillDelay(delay time.Duration) *ExpectedReshardJobState {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedReshardJobState) met(ex expectation) bool {
+	exp := ex.(*ExpectedReshardJobState)
+	if exp.arg0 != "" && exp.arg0 != e.arg0 {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedReshardJobState) method(v bool) string {
+	if !v {
+		return "ReshardJobState()"
+	}
+	arg0 := "?"
+	if e.arg0 != "" {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	return fmt.Sprintf("ReshardJobState(ctx, %s)", arg0)
+}
+
+// ExpectedReshardJobs represents an expectation for a call to ReshardJobs().
+type ExpectedReshardJobs struct {
+	commonExpectation
+	callback func(ctx context.Context) ([]*driver.ReshardJob, error)
+	ret0     []*driver.ReshardJob
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedReshardJobs) WillExecute(cb func(ctx context.Context) ([]*driver.ReshardJob, error)) *ExpectedReshardJobs {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to ReshardJobs().
+func (e *ExpectedReshardJobs) WillReturn(ret0 []*driver.ReshardJob) *ExpectedReshardJobs {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to ReshardJobs().
+func (e *ExpectedReshardJobs) WillReturnError(err error) *ExpectedReshardJobs {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to ReshardJobs() to delay.
+func (e *ExpectedReshardJobs) WillDelay(delay time.Duration) *ExpectedReshardJobs {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedReshardJobs) met(_ expectation) bool {
+	return true
+}
+
+func (e *ExpectedReshardJobs) method(v bool) string {
+	if !v {
+		return "ReshardJobs()"
+	}
+	return fmt.Sprintf("ReshardJobs(ctx)")
+}
+
+// ExpectedReshardState represents an expectation for a call to ReshardState().
+type ExpectedReshardState struct {
+	commonExpectation
+	callback func(ctx context.Context) (*driver.ReshardState, error)
+	ret0     *driver.ReshardState
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedReshardState) WillExecute(cb func(ctx context.Context) (*driver.ReshardState, error)) *ExpectedReshardState {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to ReshardState().
+func (e *ExpectedReshardState) WillReturn(ret0 *driver.ReshardState) *ExpectedReshardState {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to ReshardState().
+func (e *ExpectedReshardState) WillReturnError(err error) *ExpectedReshardState {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to ReshardState() to delay.
+func (e *ExpectedReshardState) WillDelay(delay time.Duration) *ExpectedReshardState {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedReshardState) met(_ expectation) bool {
+	return true
+}
+
+func (e *ExpectedReshardState) method(v bool) string {
+	if !v {
+		return "ReshardState()"
+	}
+	return fmt.Sprintf("ReshardState(ctx)")
+}
+
+// ExpectedReshardSummary represents an expectation for a call to ReshardSummary().
+type ExpectedReshardSummary struct {
+	commonExpectation
+	callback func(ctx context.Context) (*driver.ReshardSummary, error)
+	ret0     *driver.ReshardSummary
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedReshardSummary) WillExecute(cb func(ctx context.Context) (*driver.ReshardSummary, error)) *ExpectedReshardSummary {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to ReshardSummary().
+func (e *ExpectedReshardSummary) WillReturn(ret0 *driver.ReshardSummary) *ExpectedReshardSummary {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to ReshardSummary().
+func (e *ExpectedReshardSummary) WillReturnError(err error) *ExpectedReshardSummary {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to ReshardSummary() to delay.
+func (e *ExpectedReshardSummary) WillDelay(delay time.Duration) *ExpectedReshardSummary {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedReshardSummary) met(_ expectation) bool {
+	return true
+}
+
+func (e *ExpectedReshardSummary) method(v bool) string {
+	if !v {
+		return "ReshardSummary()"
+	}
+	return fmt.Sprintf("ReshardSummary(ctx)")
+}
+
 // ExpectedSession represents an expectation for a call to Session().
 type ExpectedSession struct {
 	commonExpectation
@@ -1083,6 +1526,105 @@ func (e *ExpectedSession) method(v bool) string {
 	return fmt.Sprintf("Session(ctx)")
 }
 
+// ExpectedSetReshardJobState represents an expectation for a call to SetReshardJobState().
+type ExpectedSetReshardJobState struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 string, arg1 *driver.ReshardState) error
+	arg0     string
+	arg1     *driver.ReshardState
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedSetReshardJobState) WillExecute(cb func(ctx context.Context, arg0 string, arg1 *driver.ReshardState) error) *ExpectedSetReshardJobState {
+	e.callback = cb
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to SetReshardJobState().
+func (e *ExpectedSetReshardJobState) WillReturnError(err error) *ExpectedSetReshardJobState {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to SetReshardJobState() to delay.
+func (e *ExpectedSetReshardJobState) WillDelay(delay time.Duration) *ExpectedSetReshardJobState {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedSetReshardJobState) met(ex expectation) bool {
+	exp := ex.(*ExpectedSetReshardJobState)
+	if exp.arg0 != "" && exp.arg0 != e.arg0 {
+		return false
+	}
+	if exp.arg1 != nil && !reflect.DeepEqual(exp.arg1, e.arg1) {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedSetReshardJobState) method(v bool) string {
+	if !v {
+		return "SetReshardJobState()"
+	}
+	arg0, arg1 := "?", "?"
+	if e.arg0 != "" {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	if e.arg1 != nil {
+		arg1 = fmt.Sprintf("%v", e.arg1)
+	}
+	return fmt.Sprintf("SetReshardJobState(ctx, %s, %s)", arg0, arg1)
+}
+
+// ExpectedSetReshardState represents an expectation for a call to SetReshardState().
+type ExpectedSetReshardState struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 *driver.ReshardState) error
+	arg0     *driver.ReshardState
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedSetReshardState) WillExecute(cb func(ctx context.Context, arg0 *driver.ReshardState) error) *ExpectedSetReshardState {
+	e.callback = cb
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to SetReshardState().
+func (e *ExpectedSetReshardState) WillReturnError(err error) *ExpectedSetReshardState {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to SetReshardState() to delay.
+func (e *ExpectedSetReshardState) WillDelay(delay time.Duration) *ExpectedSetReshardState {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedSetReshardState) met(ex expectation) bool {
+	exp := ex.(*ExpectedSetReshardState)
+	if exp.arg0 != nil && !reflect.DeepEqual(exp.arg0, e.arg0) {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedSetReshardState) method(v bool) string {
+	if !v {
+		return "SetReshardState()"
+	}
+	arg0 := "?"
+	if e.arg0 != nil {
+		arg0 = fmt.Sprintf("%v", e.arg0)
+	}
+	return fmt.Sprintf("SetReshardState(ctx, %s)", arg0)
+}
+
 // ExpectedVersion represents an expectation for a call to Version().
 type ExpectedVersion struct {
 	commonExpectation
@@ -3,12 +3,15 @@
 package mockdb
 
 import (
+	"io"
+
 	kivik "github.com/go-kivik/kivik/v4"
 	"github.com/go-kivik/kivik/v4/driver"
 )
 
 var _ = kivik.EndKeySuffix // To ensure a reference to kivik package
 var _ = (*driver.Attachment)(nil)
+var _ = io.EOF
 
 // ExpectAllDBs queues an expectation that AllDBs will be called.
 func (c *Client) ExpectAllDBs() *ExpectedAllDBs {
@@ -17,6 +20,13 @@ func (c *Client) ExpectAllDBs() *ExpectedAllDBs {
 	return e
 }
 
+// ExpectBackup queues an expectation that Backup will be called.
+func (c *Client) ExpectBackup() *ExpectedBackup {
+	e := &ExpectedBackup{}
+	c.expected = append(c.expected, e)
+	return e
+}
+
 // ExpectClose queues an expectation that Close will be called.
 func (c *Client) ExpectClose() *ExpectedClose {
 	e := &ExpectedClose{}
@@ -73,6 +83,13 @@ func (c *Client) ExpectPing() *ExpectedPing {
 	return e
 }
 
+// ExpectRestore queues an expectation that Restore will be called.
+func (c *Client) ExpectRestore() *ExpectedRestore {
+	e := &ExpectedRestore{}
+	c.expected = append(c.expected, e)
+	return e
+}
+
 // ExpectSetConfigValue queues an expectation that SetConfigValue will be called.
 func (c *Client) ExpectSetConfigValue() *ExpectedSetConfigValue {
 	e := &ExpectedSetConfigValue{}
@@ -101,6 +118,13 @@ func (c *Client) ExpectConfigSection() *ExpectedConfigSection {
 	return e
 }
 
+// ExpectCreateReshardJobs queues an expectation that CreateReshardJobs will be called.
+func (c *Client) ExpectCreateReshardJobs() *ExpectedCreateReshardJobs {
+	e := &ExpectedCreateReshardJobs{}
+	c.expected = append(c.expected, e)
+	return e
+}
+
 // ExpectDB queues an expectation that DB will be called.
 func (c *Client) ExpectDB() *ExpectedDB {
 	e := &ExpectedDB{
@@ -126,6 +150,13 @@ func (c *Client) ExpectDBsStats() *ExpectedDBsStats {
 	return e
 }
 
+// ExpectDeleteReshardJob queues an expectation that DeleteReshardJob will be called.
+func (c *Client) ExpectDeleteReshardJob() *ExpectedDeleteReshardJob {
+	e := &ExpectedDeleteReshardJob{}
+	c.expected = append(c.expected, e)
+	return e
+}
+
 // ExpectGetReplications queues an expectation that GetReplications will be called.
 func (c *Client) ExpectGetReplications() *ExpectedGetReplications {
 	e := &ExpectedGetReplications{}
@@ -147,6 +178,41 @@ func (c *Client) ExpectReplicate() *ExpectedReplicate {
 	return e
 }
 
+// ExpectReshardJob queues an expectation that ReshardJob will be called.
+func (c *Client) ExpectReshardJob() *ExpectedReshardJob {
+	e := &ExpectedReshardJob{}
+	c.expected = append(c.expected, e)
+	return e
+}
+
+// ExpectReshardJobState queues an expectation that ReshardJobState will be called.
+func (c *Client) ExpectReshardJobState() *ExpectedReshardJobState {
+	e := &ExpectedReshardJobState{}
+	c.expected = append(c.expected, e)
+	return e
+}
+
+// ExpectReshardJobs queues an expectation that ReshardJobs will be called.
+func (c *Client) ExpectReshardJobs() *ExpectedReshardJobs {
+	e := &ExpectedReshardJobs{}
+	c.expected = append(c.expected, e)
+	return e
+}
+
+// ExpectReshardState queues an expectation that ReshardState will be called.
+func (c *Client) ExpectReshardState() *ExpectedReshardState {
+	e := &ExpectedReshardState{}
+	c.expected = append(c.expected, e)
+	return e
+}
+
+// ExpectReshardSummary queues an expectation that ReshardSummary will be called.
+func (c *Client) ExpectReshardSummary() *ExpectedReshardSummary {
+	e := &ExpectedReshardSummary{}
+	c.expected = append(c.expected, e)
+	return e
+}
+
 // ExpectSession queues an expectation that Session will be called.
 func (c *Client) ExpectSession() *ExpectedSession {
 	e := &ExpectedSession{}
@@ -154,6 +220,20 @@ func (c *Client) ExpectSession() *ExpectedSession {
 	return e
 }
 
+// ExpectSetReshardJobState queues an expectation that SetReshardJobState will be called.
+func (c *Client) ExpectSetReshardJobState() *ExpectedSetReshardJobState {
+	e := &ExpectedSetReshardJobState{}
+	c.expected = append(c.expected, e)
+	return e
+}
+
+// ExpectSetReshardState queues an expectation that SetReshardState will be called.
+func (c *Client) ExpectSetReshardState() *ExpectedSetReshardState {
+	e := &ExpectedSetReshardState{}
+	c.expected = append(c.expected, e)
+	return e
+}
+
 // ExpectVersion queues an expectation that Version will be called.
 func (c *Client) ExpectVersion() *ExpectedVersion {
 	e := &ExpectedVersion{}
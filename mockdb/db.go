@@ -21,9 +21,12 @@ type driverDB struct {
 }
 
 var (
-	_ driver.DB         = &driverDB{}
-	_ driver.BulkGetter = &driverDB{}
-	_ driver.Finder     = &driverDB{}
+	_ driver.DB               = &driverDB{}
+	_ driver.BulkGetter       = &driverDB{}
+	_ driver.Finder           = &driverDB{}
+	_ driver.ViewIndexer      = &driverDB{}
+	_ driver.DesignFunctioner = &driverDB{}
+	_ driver.UpdateResponder  = &driverDB{}
 )
 
 func (db *driverDB) Close() error {
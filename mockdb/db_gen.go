@@ -4,11 +4,13 @@ package mockdb
 
 import (
 	"context"
+	"io"
 
 	"github.com/go-kivik/kivik/v4/driver"
 )
 
 var _ = (*driver.Attachment)(nil)
+var _ = io.EOF
 
 func (db *driverDB) Compact(ctx context.Context) error {
 	expected := &ExpectedCompact{
@@ -145,6 +147,21 @@ func (db *driverDB) GetRev(ctx context.Context, arg0 string, options driver.Opti
 	return expected.ret0, expected.wait(ctx)
 }
 
+func (db *driverDB) PurgedInfosLimit(ctx context.Context) (int, error) {
+	expected := &ExpectedPurgedInfosLimit{
+		commonExpectation: commonExpectation{
+			db: db.DB,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return 0, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
 func (db *driverDB) Put(ctx context.Context, arg0 string, arg1 any, options driver.Options) (string, error) {
 	expected := &ExpectedPut{
 		arg0: arg0,
@@ -163,6 +180,83 @@ func (db *driverDB) Put(ctx context.Context, arg0 string, arg1 any, options driv
 	return expected.ret0, expected.wait(ctx)
 }
 
+func (db *driverDB) RevsLimit(ctx context.Context) (int, error) {
+	expected := &ExpectedRevsLimit{
+		commonExpectation: commonExpectation{
+			db: db.DB,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return 0, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
+func (db *driverDB) SetPurgedInfosLimit(ctx context.Context, arg0 int) error {
+	expected := &ExpectedSetPurgedInfosLimit{
+		arg0: arg0,
+		commonExpectation: commonExpectation{
+			db: db.DB,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0)
+	}
+	return expected.wait(ctx)
+}
+
+func (db *driverDB) SetRevsLimit(ctx context.Context, arg0 int) error {
+	expected := &ExpectedSetRevsLimit{
+		arg0: arg0,
+		commonExpectation: commonExpectation{
+			db: db.DB,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0)
+	}
+	return expected.wait(ctx)
+}
+
+func (db *driverDB) Shards(ctx context.Context) (map[string][]string, error) {
+	expected := &ExpectedShards{
+		commonExpectation: commonExpectation{
+			db: db.DB,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
+func (db *driverDB) SyncShards(ctx context.Context) error {
+	expected := &ExpectedSyncShards{
+		commonExpectation: commonExpectation{
+			db: db.DB,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx)
+	}
+	return expected.wait(ctx)
+}
+
 func (db *driverDB) Update(ctx context.Context, arg0 string, arg1 string, arg2 string, arg3 any, options driver.Options) (string, error) {
 	expected := &ExpectedUpdate{
 		arg0: arg0,
@@ -299,6 +393,22 @@ func (db *driverDB) DeleteAttachment(ctx context.Context, arg0 string, arg1 stri
 	return expected.ret0, expected.wait(ctx)
 }
 
+func (db *driverDB) DesignDocInfo(ctx context.Context, arg0 string) (*driver.DesignDocInfo, error) {
+	expected := &ExpectedDesignDocInfo{
+		arg0: arg0,
+		commonExpectation: commonExpectation{
+			db: db.DB,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
 func (db *driverDB) DesignDocs(ctx context.Context, options driver.Options) (driver.Rows, error) {
 	expected := &ExpectedDesignDocs{
 		commonExpectation: commonExpectation{
@@ -315,6 +425,22 @@ func (db *driverDB) DesignDocs(ctx context.Context, options driver.Options) (dri
 	return &driverRows{Context: ctx, Rows: coalesceRows(expected.ret0)}, expected.wait(ctx)
 }
 
+func (db *driverDB) DocShards(ctx context.Context, arg0 string) (*driver.DocShards, error) {
+	expected := &ExpectedDocShards{
+		arg0: arg0,
+		commonExpectation: commonExpectation{
+			db: db.DB,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
 func (db *driverDB) Explain(ctx context.Context, arg0 any, options driver.Options) (*driver.QueryPlan, error) {
 	expected := &ExpectedExplain{
 		arg0: arg0,
@@ -418,6 +544,25 @@ func (db *driverDB) GetIndexes(ctx context.Context, options driver.Options) ([]d
 	return expected.ret0, expected.wait(ctx)
 }
 
+func (db *driverDB) List(ctx context.Context, arg0 string, arg1 string, arg2 string, options driver.Options) (*driver.DesignResponse, error) {
+	expected := &ExpectedList{
+		arg0: arg0,
+		arg1: arg1,
+		arg2: arg2,
+		commonExpectation: commonExpectation{
+			db:      db.DB,
+			options: options,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0, arg1, arg2, options)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
 func (db *driverDB) LocalDocs(ctx context.Context, options driver.Options) (driver.Rows, error) {
 	expected := &ExpectedLocalDocs{
 		commonExpectation: commonExpectation{
@@ -536,6 +681,26 @@ func (db *driverDB) RevsDiff(ctx context.Context, arg0 any) (driver.Rows, error)
 	return &driverRows{Context: ctx, Rows: coalesceRows(expected.ret0)}, expected.wait(ctx)
 }
 
+func (db *driverDB) Rewrite(ctx context.Context, arg0 string, arg1 string, arg2 string, arg3 io.Reader, options driver.Options) (*driver.DesignResponse, error) {
+	expected := &ExpectedRewrite{
+		arg0: arg0,
+		arg1: arg1,
+		arg2: arg2,
+		arg3: arg3,
+		commonExpectation: commonExpectation{
+			db:      db.DB,
+			options: options,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0, arg1, arg2, arg3, options)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
 func (db *driverDB) Security(ctx context.Context) (*driver.Security, error) {
 	expected := &ExpectedSecurity{
 		commonExpectation: commonExpectation{
@@ -567,6 +732,25 @@ func (db *driverDB) SetSecurity(ctx context.Context, arg0 *driver.Security) erro
 	return expected.wait(ctx)
 }
 
+func (db *driverDB) Show(ctx context.Context, arg0 string, arg1 string, arg2 string, options driver.Options) (*driver.DesignResponse, error) {
+	expected := &ExpectedShow{
+		arg0: arg0,
+		arg1: arg1,
+		arg2: arg2,
+		commonExpectation: commonExpectation{
+			db:      db.DB,
+			options: options,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0, arg1, arg2, options)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
+
 func (db *driverDB) Stats(ctx context.Context) (*driver.DBStats, error) {
 	expected := &ExpectedStats{
 		commonExpectation: commonExpectation{
@@ -581,3 +765,39 @@ func (db *driverDB) Stats(ctx context.Context) (*driver.DBStats, error) {
 	}
 	return expected.ret0, expected.wait(ctx)
 }
+
+func (db *driverDB) UpdateIndex(ctx context.Context, arg0 string) error {
+	expected := &ExpectedUpdateIndex{
+		arg0: arg0,
+		commonExpectation: commonExpectation{
+			db: db.DB,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0)
+	}
+	return expected.wait(ctx)
+}
+
+func (db *driverDB) UpdateWithResponse(ctx context.Context, arg0 string, arg1 string, arg2 string, arg3 any, options driver.Options) (*driver.UpdateResponse, error) {
+	expected := &ExpectedUpdateWithResponse{
+		arg0: arg0,
+		arg1: arg1,
+		arg2: arg2,
+		arg3: arg3,
+		commonExpectation: commonExpectation{
+			db:      db.DB,
+			options: options,
+		},
+	}
+	if err := db.client.nextExpectation(expected); err != nil {
+		return nil, err
+	}
+	if expected.callback != nil {
+		return expected.callback(ctx, arg0, arg1, arg2, arg3, options)
+	}
+	return expected.ret0, expected.wait(ctx)
+}
@@ -15,6 +15,7 @@ package mockdb
 import (
 	"context"
 	"errors"
+	"io"
 	"strings"
 	"testing"
 	"time"
@@ -3401,3 +3402,229 @@ func TestPartitionStats(t *testing.T) {
 
 	tests.Run(t, testMock)
 }
+
+func TestDesignDocInfo(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("error", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectDesignDocInfo().WillReturnError(errors.New("foo err"))
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			_, err := c.DB("foo").DesignDocInfo(context.TODO(), "bar")
+			if !testy.ErrorMatches("foo err", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+	})
+	tests.Add("success", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectDesignDocInfo().WithDDoc("bar").
+				WillReturn(&driver.DesignDocInfo{Name: "bar", ViewIndex: driver.ViewIndexInfo{Signature: "abc"}})
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			info, err := c.DB("foo").DesignDocInfo(context.TODO(), "_design/bar")
+			if !testy.ErrorMatches("", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if info.ViewIndex.Signature != "abc" {
+				t.Errorf("Unexpected info: %+v", info)
+			}
+		},
+	})
+	tests.Run(t, testMock)
+}
+
+func TestWaitForIndex(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("success", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectStats().WillReturn(&driver.DBStats{UpdateSeq: "3-abc"})
+			db.ExpectUpdateIndex().WithDDoc("bar")
+			db.ExpectDesignDocInfo().WithDDoc("bar").
+				WillReturn(&driver.DesignDocInfo{ViewIndex: driver.ViewIndexInfo{UpdateSeq: 3}})
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			err := c.DB("foo").WaitForIndex(context.TODO(), "bar")
+			if !testy.ErrorMatches("", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+	})
+	tests.Add("update error", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectStats().WillReturn(&driver.DBStats{UpdateSeq: "3-abc"})
+			db.ExpectUpdateIndex().WillReturnError(errors.New("update failed"))
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			err := c.DB("foo").WaitForIndex(context.TODO(), "bar")
+			if !testy.ErrorMatches("update failed", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+	})
+	tests.Run(t, testMock)
+}
+
+func TestShow(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("error", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectShow().WillReturnError(errors.New("foo err"))
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			_, err := c.DB("foo").Show(context.TODO(), "bar", "baz", "")
+			if !testy.ErrorMatches("foo err", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+	})
+	tests.Add("success", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectShow().WithDDoc("bar").WithFuncName("baz").WithDocID("qux").
+				WillReturn(&driver.DesignResponse{StatusCode: 200, Body: io.NopCloser(strings.NewReader("hello"))})
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			resp, err := c.DB("foo").Show(context.TODO(), "_design/bar", "baz", "qux")
+			if !testy.ErrorMatches("", err) {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != "hello" {
+				t.Errorf("Unexpected body: %s", body)
+			}
+		},
+	})
+	tests.Add("wrong func", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectShow().WithFuncName("baz")
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			_, err := c.DB("foo").Show(context.TODO(), "bar", "qux", "")
+			if !testy.ErrorMatchesRE(`has funcName: baz`, err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+		err: "there is a remaining unmet expectation",
+	})
+	tests.Run(t, testMock)
+}
+
+func TestList(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("error", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectList().WillReturnError(errors.New("foo err"))
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			_, err := c.DB("foo").List(context.TODO(), "bar", "baz", "qux")
+			if !testy.ErrorMatches("foo err", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+	})
+	tests.Add("success", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectList().WithDDoc("bar").WithFuncName("baz").WithView("qux").
+				WillReturn(&driver.DesignResponse{StatusCode: 200, Body: io.NopCloser(strings.NewReader("hello"))})
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			resp, err := c.DB("foo").List(context.TODO(), "bar", "baz", "qux")
+			if !testy.ErrorMatches("", err) {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if resp.StatusCode != 200 {
+				t.Errorf("Unexpected status: %d", resp.StatusCode)
+			}
+		},
+	})
+	tests.Run(t, testMock)
+}
+
+func TestRewrite(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("error", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectRewrite().WillReturnError(errors.New("foo err"))
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			_, err := c.DB("foo").Rewrite(context.TODO(), "bar", "", "baz", nil)
+			if !testy.ErrorMatches("foo err", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+	})
+	tests.Add("success", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectRewrite().WithDDoc("bar").WithMethod("GET").WithPath("baz").
+				WillReturn(&driver.DesignResponse{StatusCode: 200, Body: io.NopCloser(strings.NewReader("hello"))})
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			resp, err := c.DB("foo").Rewrite(context.TODO(), "bar", "", "/baz", nil)
+			if !testy.ErrorMatches("", err) {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if resp.StatusCode != 200 {
+				t.Errorf("Unexpected status: %d", resp.StatusCode)
+			}
+		},
+	})
+	tests.Run(t, testMock)
+}
+
+func TestUpdateWithResponse(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("error", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectUpdateWithResponse().WillReturnError(errors.New("foo err"))
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			_, err := c.DB("foo").UpdateWithResponse(context.TODO(), "bar", "baz", "qux", nil)
+			if !testy.ErrorMatches("foo err", err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		},
+	})
+	tests.Add("success", mockTest{
+		setup: func(m *Client) {
+			db := m.NewDB()
+			m.ExpectDB().WillReturn(db)
+			db.ExpectUpdateWithResponse().WithDDoc("bar").WithFuncName("baz").WithDocID("qux").
+				WithDoc(map[string]string{"foo": "bar"}).
+				WillReturn(&driver.UpdateResponse{Rev: "1-abc", StatusCode: 201, Body: io.NopCloser(strings.NewReader("ok"))})
+		},
+		test: func(t *testing.T, c *kivik.Client) { //nolint:thelper // Not a helper
+			resp, err := c.DB("foo").UpdateWithResponse(context.TODO(), "_design/bar", "baz", "qux", map[string]string{"foo": "bar"})
+			if !testy.ErrorMatches("", err) {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if resp.Rev != "1-abc" {
+				t.Errorf("Unexpected rev: %s", resp.Rev)
+			}
+		},
+	})
+	tests.Run(t, testMock)
+}
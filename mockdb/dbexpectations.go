@@ -758,3 +758,295 @@ func (e *ExpectedPartitionStats) WithName(name string) *ExpectedPartitionStats {
 	e.arg0 = name
 	return e
 }
+
+func (e *ExpectedRevsLimit) String() string {
+	var rets []string
+	if e.ret0 != 0 {
+		rets = append(rets, fmt.Sprintf("should return limit: %d", e.ret0))
+	}
+	return dbStringer("RevsLimit", &e.commonExpectation, 0, nil, rets)
+}
+
+func (e *ExpectedSetRevsLimit) String() string {
+	var opts []string
+	if e.arg0 == 0 {
+		opts = append(opts, "has any limit")
+	} else {
+		opts = append(opts, fmt.Sprintf("has limit: %d", e.arg0))
+	}
+	return dbStringer("SetRevsLimit", &e.commonExpectation, 0, opts, nil)
+}
+
+// WithLimit sets the expected limit for the DB.SetRevsLimit() call.
+func (e *ExpectedSetRevsLimit) WithLimit(limit int) *ExpectedSetRevsLimit {
+	e.arg0 = limit
+	return e
+}
+
+func (e *ExpectedPurgedInfosLimit) String() string {
+	var rets []string
+	if e.ret0 != 0 {
+		rets = append(rets, fmt.Sprintf("should return limit: %d", e.ret0))
+	}
+	return dbStringer("PurgedInfosLimit", &e.commonExpectation, 0, nil, rets)
+}
+
+func (e *ExpectedSetPurgedInfosLimit) String() string {
+	var opts []string
+	if e.arg0 == 0 {
+		opts = append(opts, "has any limit")
+	} else {
+		opts = append(opts, fmt.Sprintf("has limit: %d", e.arg0))
+	}
+	return dbStringer("SetPurgedInfosLimit", &e.commonExpectation, 0, opts, nil)
+}
+
+// WithLimit sets the expected limit for the DB.SetPurgedInfosLimit() call.
+func (e *ExpectedSetPurgedInfosLimit) WithLimit(limit int) *ExpectedSetPurgedInfosLimit {
+	e.arg0 = limit
+	return e
+}
+
+func (e *ExpectedShards) String() string {
+	var rets []string
+	if e.ret0 != nil {
+		rets = append(rets, fmt.Sprintf("should return: %d shards", len(e.ret0)))
+	}
+	return dbStringer("Shards", &e.commonExpectation, 0, nil, rets)
+}
+
+func (e *ExpectedDocShards) String() string {
+	var opts, rets []string
+	if e.arg0 == "" {
+		opts = append(opts, "has any docID")
+	} else {
+		opts = append(opts, "has docID: "+e.arg0)
+	}
+	if e.ret0 != nil {
+		rets = append(rets, "should return range: "+e.ret0.Range)
+	}
+	return dbStringer("DocShards", &e.commonExpectation, 0, opts, rets)
+}
+
+// WithDocID sets the expected docID for the DB.DocShards() call.
+func (e *ExpectedDocShards) WithDocID(docID string) *ExpectedDocShards {
+	e.arg0 = docID
+	return e
+}
+
+func (e *ExpectedSyncShards) String() string {
+	return dbStringer("SyncShards", &e.commonExpectation, 0, nil, nil)
+}
+
+func (e *ExpectedDesignDocInfo) String() string {
+	var opts, rets []string
+	if e.arg0 == "" {
+		opts = append(opts, "has any ddoc")
+	} else {
+		opts = append(opts, "has ddoc: "+e.arg0)
+	}
+	if e.ret0 != nil {
+		rets = append(rets, "should return signature: "+e.ret0.ViewIndex.Signature)
+	}
+	return dbStringer("DesignDocInfo", &e.commonExpectation, 0, opts, rets)
+}
+
+// WithDDoc sets the expected design document for the DB.DesignDocInfo() call.
+func (e *ExpectedDesignDocInfo) WithDDoc(ddoc string) *ExpectedDesignDocInfo {
+	e.arg0 = ddoc
+	return e
+}
+
+func (e *ExpectedUpdateIndex) String() string {
+	var opts []string
+	if e.arg0 == "" {
+		opts = append(opts, "has any ddoc")
+	} else {
+		opts = append(opts, "has ddoc: "+e.arg0)
+	}
+	return dbStringer("UpdateIndex", &e.commonExpectation, 0, opts, nil)
+}
+
+// WithDDoc sets the expected design document for the DB.UpdateIndex() call,
+// made by DB.WaitForIndex().
+func (e *ExpectedUpdateIndex) WithDDoc(ddoc string) *ExpectedUpdateIndex {
+	e.arg0 = ddoc
+	return e
+}
+
+func (e *ExpectedShow) String() string {
+	var opts, rets []string
+	if e.arg0 == "" {
+		opts = append(opts, "has any ddoc")
+	} else {
+		opts = append(opts, "has ddoc: "+e.arg0)
+	}
+	if e.arg1 == "" {
+		opts = append(opts, "has any funcName")
+	} else {
+		opts = append(opts, "has funcName: "+e.arg1)
+	}
+	if e.arg2 == "" {
+		opts = append(opts, "has any docID")
+	} else {
+		opts = append(opts, "has docID: "+e.arg2)
+	}
+	if e.ret0 != nil {
+		rets = append(rets, fmt.Sprintf("should return status: %d", e.ret0.StatusCode))
+	}
+	return dbStringer("Show", &e.commonExpectation, withOptions, opts, rets)
+}
+
+// WithDDoc sets the expected design document for the DB.Show() call.
+func (e *ExpectedShow) WithDDoc(ddoc string) *ExpectedShow {
+	e.arg0 = ddoc
+	return e
+}
+
+// WithFuncName sets the expected show function name for the DB.Show() call.
+func (e *ExpectedShow) WithFuncName(funcName string) *ExpectedShow {
+	e.arg1 = funcName
+	return e
+}
+
+// WithDocID sets the expected docID for the DB.Show() call.
+func (e *ExpectedShow) WithDocID(docID string) *ExpectedShow {
+	e.arg2 = docID
+	return e
+}
+
+func (e *ExpectedList) String() string {
+	var opts, rets []string
+	if e.arg0 == "" {
+		opts = append(opts, "has any ddoc")
+	} else {
+		opts = append(opts, "has ddoc: "+e.arg0)
+	}
+	if e.arg1 == "" {
+		opts = append(opts, "has any funcName")
+	} else {
+		opts = append(opts, "has funcName: "+e.arg1)
+	}
+	if e.arg2 == "" {
+		opts = append(opts, "has any view")
+	} else {
+		opts = append(opts, "has view: "+e.arg2)
+	}
+	if e.ret0 != nil {
+		rets = append(rets, fmt.Sprintf("should return status: %d", e.ret0.StatusCode))
+	}
+	return dbStringer("List", &e.commonExpectation, withOptions, opts, rets)
+}
+
+// WithDDoc sets the expected design document for the DB.List() call.
+func (e *ExpectedList) WithDDoc(ddoc string) *ExpectedList {
+	e.arg0 = ddoc
+	return e
+}
+
+// WithFuncName sets the expected list function name for the DB.List() call.
+func (e *ExpectedList) WithFuncName(funcName string) *ExpectedList {
+	e.arg1 = funcName
+	return e
+}
+
+// WithView sets the expected view for the DB.List() call.
+func (e *ExpectedList) WithView(view string) *ExpectedList {
+	e.arg2 = view
+	return e
+}
+
+func (e *ExpectedRewrite) String() string {
+	var opts, rets []string
+	if e.arg0 == "" {
+		opts = append(opts, "has any ddoc")
+	} else {
+		opts = append(opts, "has ddoc: "+e.arg0)
+	}
+	if e.arg1 == "" {
+		opts = append(opts, "has any method")
+	} else {
+		opts = append(opts, "has method: "+e.arg1)
+	}
+	if e.arg2 == "" {
+		opts = append(opts, "has any path")
+	} else {
+		opts = append(opts, "has path: "+e.arg2)
+	}
+	if e.ret0 != nil {
+		rets = append(rets, fmt.Sprintf("should return status: %d", e.ret0.StatusCode))
+	}
+	return dbStringer("Rewrite", &e.commonExpectation, withOptions, opts, rets)
+}
+
+// WithDDoc sets the expected design document for the DB.Rewrite() call.
+func (e *ExpectedRewrite) WithDDoc(ddoc string) *ExpectedRewrite {
+	e.arg0 = ddoc
+	return e
+}
+
+// WithMethod sets the expected HTTP method for the DB.Rewrite() call.
+func (e *ExpectedRewrite) WithMethod(method string) *ExpectedRewrite {
+	e.arg1 = method
+	return e
+}
+
+// WithPath sets the expected path for the DB.Rewrite() call.
+func (e *ExpectedRewrite) WithPath(path string) *ExpectedRewrite {
+	e.arg2 = path
+	return e
+}
+
+func (e *ExpectedUpdateWithResponse) String() string {
+	var opts, rets []string
+	if e.arg0 == "" {
+		opts = append(opts, "has any ddoc")
+	} else {
+		opts = append(opts, "has ddoc: "+e.arg0)
+	}
+	if e.arg1 == "" {
+		opts = append(opts, "has any funcName")
+	} else {
+		opts = append(opts, "has funcName: "+e.arg1)
+	}
+	if e.arg2 == "" {
+		opts = append(opts, "has any docID")
+	} else {
+		opts = append(opts, "has docID: "+e.arg2)
+	}
+	if e.arg3 == nil {
+		opts = append(opts, "has any doc")
+	} else {
+		opts = append(opts, "has doc: "+jsonDoc(e.arg3))
+	}
+	if e.ret0 != nil {
+		rets = append(rets, fmt.Sprintf("should return status: %d", e.ret0.StatusCode))
+	}
+	return dbStringer("UpdateWithResponse", &e.commonExpectation, withOptions, opts, rets)
+}
+
+// WithDDoc sets the expected design document for the DB.UpdateWithResponse()
+// call.
+func (e *ExpectedUpdateWithResponse) WithDDoc(ddoc string) *ExpectedUpdateWithResponse {
+	e.arg0 = ddoc
+	return e
+}
+
+// WithFuncName sets the expected update function name for the
+// DB.UpdateWithResponse() call.
+func (e *ExpectedUpdateWithResponse) WithFuncName(funcName string) *ExpectedUpdateWithResponse {
+	e.arg1 = funcName
+	return e
+}
+
+// WithDocID sets the expected docID for the DB.UpdateWithResponse() call.
+func (e *ExpectedUpdateWithResponse) WithDocID(docID string) *ExpectedUpdateWithResponse {
+	e.arg2 = docID
+	return e
+}
+
+// WithDoc sets the expected doc for the DB.UpdateWithResponse() call.
+func (e *ExpectedUpdateWithResponse) WithDoc(doc any) *ExpectedUpdateWithResponse {
+	e.arg3 = doc
+	return e
+}
@@ -5,6 +5,7 @@ package mockdb
 import (
 	"context"
 	"fmt"
+	"io"
 	"reflect"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 
 var _ = &driver.Attachment{}
 var _ = reflect.Int
+var _ = io.EOF
 
 // ExpectedCompact represents an expectation for a call to DB.Compact().
 type ExpectedCompact struct {
@@ -446,6 +448,50 @@ func (e *ExpectedGetRev) method(v bool) string {
 	return fmt.Sprintf("DB(%s).GetRev(ctx, %s, %s)", e.dbo().name, arg0, options)
 }
 
+// ExpectedPurgedInfosLimit represents an expectation for a call to DB.PurgedInfosLimit().
+type ExpectedPurgedInfosLimit struct {
+	commonExpectation
+	callback func(ctx context.Context) (int, error)
+	ret0     int
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedPurgedInfosLimit) WillExecute(cb func(ctx context.Context) (int, error)) *ExpectedPurgedInfosLimit {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to DB.PurgedInfosLimit().
+func (e *ExpectedPurgedInfosLimit) WillReturn(ret0 int) *ExpectedPurgedInfosLimit {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.PurgedInfosLimit().
+func (e *ExpectedPurgedInfosLimit) WillReturnError(err error) *ExpectedPurgedInfosLimit {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.PurgedInfosLimit() to delay.
+func (e *ExpectedPurgedInfosLimit) WillDelay(delay time.Duration) *ExpectedPurgedInfosLimit {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedPurgedInfosLimit) met(_ expectation) bool {
+	return true
+}
+
+func (e *ExpectedPurgedInfosLimit) method(v bool) string {
+	if !v {
+		return "DB.PurgedInfosLimit()"
+	}
+	return fmt.Sprintf("DB(%s).PurgedInfosLimit(ctx)", e.dbo().name)
+}
+
 // ExpectedPut represents an expectation for a call to DB.Put().
 type ExpectedPut struct {
 	commonExpectation
@@ -512,6 +558,223 @@ func (e *ExpectedPut) method(v bool) string {
 	return fmt.Sprintf("DB(%s).Put(ctx, %s, %s, %s)", e.dbo().name, arg0, arg1, options)
 }
 
+// ExpectedRevsLimit represents an expectation for a call to DB.RevsLimit().
+type ExpectedRevsLimit struct {
+	commonExpectation
+	callback func(ctx context.Context) (int, error)
+	ret0     int
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedRevsLimit) WillExecute(cb func(ctx context.Context) (int, error)) *ExpectedRevsLimit {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to DB.RevsLimit().
+func (e *ExpectedRevsLimit) WillReturn(ret0 int) *ExpectedRevsLimit {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.RevsLimit().
+func (e *ExpectedRevsLimit) WillReturnError(err error) *ExpectedRevsLimit {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.RevsLimit() to delay.
+func (e *ExpectedRevsLimit) WillDelay(delay time.Duration) *ExpectedRevsLimit {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedRevsLimit) met(_ expectation) bool {
+	return true
+}
+
+func (e *ExpectedRevsLimit) method(v bool) string {
+	if !v {
+		return "DB.RevsLimit()"
+	}
+	return fmt.Sprintf("DB(%s).RevsLimit(ctx)", e.dbo().name)
+}
+
+// ExpectedSetPurgedInfosLimit represents an expectation for a call to DB.SetPurgedInfosLimit().
+type ExpectedSetPurgedInfosLimit struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 int) error
+	arg0     int
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedSetPurgedInfosLimit) WillExecute(cb func(ctx context.Context, arg0 int) error) *ExpectedSetPurgedInfosLimit {
+	e.callback = cb
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.SetPurgedInfosLimit().
+func (e *ExpectedSetPurgedInfosLimit) WillReturnError(err error) *ExpectedSetPurgedInfosLimit {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.SetPurgedInfosLimit() to delay.
+func (e *ExpectedSetPurgedInfosLimit) WillDelay(delay time.Duration) *ExpectedSetPurgedInfosLimit {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedSetPurgedInfosLimit) met(ex expectation) bool {
+	exp := ex.(*ExpectedSetPurgedInfosLimit)
+	if exp.arg0 != 0 && exp.arg0 != e.arg0 {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedSetPurgedInfosLimit) method(v bool) string {
+	if !v {
+		return "DB.SetPurgedInfosLimit()"
+	}
+	arg0 := "?"
+	if e.arg0 != 0 {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	return fmt.Sprintf("DB(%s).SetPurgedInfosLimit(ctx, %s)", e.dbo().name, arg0)
+}
+
+// ExpectedSetRevsLimit represents an expectation for a call to DB.SetRevsLimit().
+type ExpectedSetRevsLimit struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 int) error
+	arg0     int
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedSetRevsLimit) WillExecute(cb func(ctx context.Context, arg0 int) error) *ExpectedSetRevsLimit {
+	e.callback = cb
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.SetRevsLimit().
+func (e *ExpectedSetRevsLimit) WillReturnError(err error) *ExpectedSetRevsLimit {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.SetRevsLimit() to delay.
+func (e *ExpectedSetRevsLimit) WillDelay(delay time.Duration) *ExpectedSetRevsLimit {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedSetRevsLimit) met(ex expectation) bool {
+	exp := ex.(*ExpectedSetRevsLimit)
+	if exp.arg0 != 0 && exp.arg0 != e.arg0 {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedSetRevsLimit) method(v bool) string {
+	if !v {
+		return "DB.SetRevsLimit()"
+	}
+	arg0 := "?"
+	if e.arg0 != 0 {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	return fmt.Sprintf("DB(%s).SetRevsLimit(ctx, %s)", e.dbo().name, arg0)
+}
+
+// ExpectedShards represents an expectation for a call to DB.Shards().
+type ExpectedShards struct {
+	commonExpectation
+	callback func(ctx context.Context) (map[string][]string, error)
+	ret0     map[string][]string
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedShards) WillExecute(cb func(ctx context.Context) (map[string][]string, error)) *ExpectedShards {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to DB.Shards().
+func (e *ExpectedShards) WillReturn(ret0 map[string][]string) *ExpectedShards {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.Shards().
+func (e *ExpectedShards) WillReturnError(err error) *ExpectedShards {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.Shards() to delay.
+func (e *ExpectedShards) WillDelay(delay time.Duration) *ExpectedShards {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedShards) met(_ expectation) bool {
+	return true
+}
+
+func (e *ExpectedShards) method(v bool) string {
+	if !v {
+		return "DB.Shards()"
+	}
+	return fmt.Sprintf("DB(%s).Shards(ctx)", e.dbo().name)
+}
+
+// ExpectedSyncShards represents an expectation for a call to DB.SyncShards().
+type ExpectedSyncShards struct {
+	commonExpectation
+	callback func(ctx context.Context) error
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedSyncShards) WillExecute(cb func(ctx context.Context) error) *ExpectedSyncShards {
+	e.callback = cb
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.SyncShards().
+func (e *ExpectedSyncShards) WillReturnError(err error) *ExpectedSyncShards {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.SyncShards() to delay.
+func (e *ExpectedSyncShards) WillDelay(delay time.Duration) *ExpectedSyncShards {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedSyncShards) met(_ expectation) bool {
+	return true
+}
+
+func (e *ExpectedSyncShards) method(v bool) string {
+	if !v {
+		return "DB.SyncShards()"
+	}
+	return fmt.Sprintf("DB(%s).SyncShards(ctx)", e.dbo().name)
+}
+
 // ExpectedUpdate represents an expectation for a call to DB.Update().
 type ExpectedUpdate struct {
 	commonExpectation
@@ -974,6 +1237,59 @@ func (e *ExpectedDeleteAttachment) method(v bool) string {
 	return fmt.Sprintf("DB(%s).DeleteAttachment(ctx, %s, %s, %s)", e.dbo().name, arg0, arg1, options)
 }
 
+// ExpectedDesignDocInfo represents an expectation for a call to DB.DesignDocInfo().
+type ExpectedDesignDocInfo struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 string) (*driver.DesignDocInfo, error)
+	arg0     string
+	ret0     *driver.DesignDocInfo
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedDesignDocInfo) WillExecute(cb func(ctx context.Context, arg0 string) (*driver.DesignDocInfo, error)) *ExpectedDesignDocInfo {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to DB.DesignDocInfo().
+func (e *ExpectedDesignDocInfo) WillReturn(ret0 *driver.DesignDocInfo) *ExpectedDesignDocInfo {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.DesignDocInfo().
+func (e *ExpectedDesignDocInfo) WillReturnError(err error) *ExpectedDesignDocInfo {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.DesignDocInfo() to delay.
+func (e *ExpectedDesignDocInfo) WillDelay(delay time.Duration) *ExpectedDesignDocInfo {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedDesignDocInfo) met(ex expectation) bool {
+	exp := ex.(*ExpectedDesignDocInfo)
+	if exp.arg0 != "" && exp.arg0 != e.arg0 {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedDesignDocInfo) method(v bool) string {
+	if !v {
+		return "DB.DesignDocInfo()"
+	}
+	arg0 := "?"
+	if e.arg0 != "" {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	return fmt.Sprintf("DB(%s).DesignDocInfo(ctx, %s)", e.dbo().name, arg0)
+}
+
 // ExpectedDesignDocs represents an expectation for a call to DB.DesignDocs().
 type ExpectedDesignDocs struct {
 	commonExpectation
@@ -1025,6 +1341,59 @@ func (e *ExpectedDesignDocs) method(v bool) string {
 	return fmt.Sprintf("DB(%s).DesignDocs(ctx, %s)", e.dbo().name, options)
 }
 
+// ExpectedDocShards represents an expectation for a call to DB.DocShards().
+type ExpectedDocShards struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 string) (*driver.DocShards, error)
+	arg0     string
+	ret0     *driver.DocShards
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedDocShards) WillExecute(cb func(ctx context.Context, arg0 string) (*driver.DocShards, error)) *ExpectedDocShards {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to DB.DocShards().
+func (e *ExpectedDocShards) WillReturn(ret0 *driver.DocShards) *ExpectedDocShards {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.DocShards().
+func (e *ExpectedDocShards) WillReturnError(err error) *ExpectedDocShards {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.DocShards() to delay.
+func (e *ExpectedDocShards) WillDelay(delay time.Duration) *ExpectedDocShards {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedDocShards) met(ex expectation) bool {
+	exp := ex.(*ExpectedDocShards)
+	if exp.arg0 != "" && exp.arg0 != e.arg0 {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedDocShards) method(v bool) string {
+	if !v {
+		return "DB.DocShards()"
+	}
+	arg0 := "?"
+	if e.arg0 != "" {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	return fmt.Sprintf("DB(%s).DocShards(ctx, %s)", e.dbo().name, arg0)
+}
+
 // ExpectedExplain represents an expectation for a call to DB.Explain().
 type ExpectedExplain struct {
 	commonExpectation
@@ -1385,15 +1754,88 @@ func (e *ExpectedGetIndexes) method(v bool) string {
 	return fmt.Sprintf("DB(%s).GetIndexes(ctx, %s)", e.dbo().name, options)
 }
 
-// ExpectedLocalDocs represents an expectation for a call to DB.LocalDocs().
-type ExpectedLocalDocs struct {
+// ExpectedList represents an expectation for a call to DB.List().
+type ExpectedList struct {
 	commonExpectation
-	callback func(ctx context.Context, options driver.Options) (driver.Rows, error)
-	ret0     *Rows
+	callback func(ctx context.Context, arg0 string, arg1 string, arg2 string, options driver.Options) (*driver.DesignResponse, error)
+	arg0     string
+	arg1     string
+	arg2     string
+	ret0     *driver.DesignResponse
 }
 
-// WithOptions sets the expected options for the call to DB.LocalDocs().
-func (e *ExpectedLocalDocs) WithOptions(options ...kivik.Option) *ExpectedLocalDocs {
+// WithOptions sets the expected options for the call to DB.List().
+func (e *ExpectedList) WithOptions(options ...kivik.Option) *ExpectedList {
+	e.options = multiOptions{e.options, multiOptions(options)}
+	return e
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedList) WillExecute(cb func(ctx context.Context, arg0 string, arg1 string, arg2 string, options driver.Options) (*driver.DesignResponse, error)) *ExpectedList {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to DB.List().
+func (e *ExpectedList) WillReturn(ret0 *driver.DesignResponse) *ExpectedList {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.List().
+func (e *ExpectedList) WillReturnError(err error) *ExpectedList {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.List() to delay.
+func (e *ExpectedList) WillDelay(delay time.Duration) *ExpectedList {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedList) met(ex expectation) bool {
+	exp := ex.(*ExpectedList)
+	if exp.arg0 != "" && exp.arg0 != e.arg0 {
+		return false
+	}
+	if exp.arg1 != "" && exp.arg1 != e.arg1 {
+		return false
+	}
+	if exp.arg2 != "" && exp.arg2 != e.arg2 {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedList) method(v bool) string {
+	if !v {
+		return "DB.List()"
+	}
+	arg0, arg1, arg2, options := "?", "?", "?", formatOptions(e.options)
+	if e.arg0 != "" {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	if e.arg1 != "" {
+		arg1 = fmt.Sprintf("%q", e.arg1)
+	}
+	if e.arg2 != "" {
+		arg2 = fmt.Sprintf("%q", e.arg2)
+	}
+	return fmt.Sprintf("DB(%s).List(ctx, %s, %s, %s, %s)", e.dbo().name, arg0, arg1, arg2, options)
+}
+
+// ExpectedLocalDocs represents an expectation for a call to DB.LocalDocs().
+type ExpectedLocalDocs struct {
+	commonExpectation
+	callback func(ctx context.Context, options driver.Options) (driver.Rows, error)
+	ret0     *Rows
+}
+
+// WithOptions sets the expected options for the call to DB.LocalDocs().
+func (e *ExpectedLocalDocs) WithOptions(options ...kivik.Option) *ExpectedLocalDocs {
 	e.options = multiOptions{e.options, multiOptions(options)}
 	return e
 }
@@ -1793,6 +2235,86 @@ func (e *ExpectedRevsDiff) method(v bool) string {
 	return fmt.Sprintf("DB(%s).RevsDiff(ctx, %s)", e.dbo().name, arg0)
 }
 
+// ExpectedRewrite represents an expectation for a call to DB.Rewrite().
+type ExpectedRewrite struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 string, arg1 string, arg2 string, arg3 io.Reader, options driver.Options) (*driver.DesignResponse, error)
+	arg0     string
+	arg1     string
+	arg2     string
+	arg3     io.Reader
+	ret0     *driver.DesignResponse
+}
+
+// WithOptions sets the expected options for the call to DB.Rewrite().
+func (e *ExpectedRewrite) WithOptions(options ...kivik.Option) *ExpectedRewrite {
+	e.options = multiOptions{e.options, multiOptions(options)}
+	return e
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedRewrite) WillExecute(cb func(ctx context.Context, arg0 string, arg1 string, arg2 string, arg3 io.Reader, options driver.Options) (*driver.DesignResponse, error)) *ExpectedRewrite {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to DB.Rewrite().
+func (e *ExpectedRewrite) WillReturn(ret0 *driver.DesignResponse) *ExpectedRewrite {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.Rewrite().
+func (e *ExpectedRewrite) WillReturnError(err error) *ExpectedRewrite {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.Rewrite() to delay.
+func (e *ExpectedRewrite) WillDelay(delay time.Duration) *ExpectedRewrite {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedRewrite) met(ex expectation) bool {
+	exp := ex.(*ExpectedRewrite)
+	if exp.arg0 != "" && exp.arg0 != e.arg0 {
+		return false
+	}
+	if exp.arg1 != "" && exp.arg1 != e.arg1 {
+		return false
+	}
+	if exp.arg2 != "" && exp.arg2 != e.arg2 {
+		return false
+	}
+	if exp.arg3 != nil && !reflect.DeepEqual(exp.arg3, e.arg3) {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedRewrite) method(v bool) string {
+	if !v {
+		return "DB.Rewrite()"
+	}
+	arg0, arg1, arg2, arg3, options := "?", "?", "?", "?", formatOptions(e.options)
+	if e.arg0 != "" {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	if e.arg1 != "" {
+		arg1 = fmt.Sprintf("%q", e.arg1)
+	}
+	if e.arg2 != "" {
+		arg2 = fmt.Sprintf("%q", e.arg2)
+	}
+	if e.arg3 != nil {
+		arg3 = fmt.Sprintf("%v", e.arg3)
+	}
+	return fmt.Sprintf("DB(%s).Rewrite(ctx, %s, %s, %s, %s, %s)", e.dbo().name, arg0, arg1, arg2, arg3, options)
+}
+
 // ExpectedSecurity represents an expectation for a call to DB.Security().
 type ExpectedSecurity struct {
 	commonExpectation
@@ -1883,6 +2405,79 @@ func (e *ExpectedSetSecurity) method(v bool) string {
 	return fmt.Sprintf("DB(%s).SetSecurity(ctx, %s)", e.dbo().name, arg0)
 }
 
+// ExpectedShow represents an expectation for a call to DB.Show().
+type ExpectedShow struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 string, arg1 string, arg2 string, options driver.Options) (*driver.DesignResponse, error)
+	arg0     string
+	arg1     string
+	arg2     string
+	ret0     *driver.DesignResponse
+}
+
+// WithOptions sets the expected options for the call to DB.Show().
+func (e *ExpectedShow) WithOptions(options ...kivik.Option) *ExpectedShow {
+	e.options = multiOptions{e.options, multiOptions(options)}
+	return e
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedShow) WillExecute(cb func(ctx context.Context, arg0 string, arg1 string, arg2 string, options driver.Options) (*driver.DesignResponse, error)) *ExpectedShow {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to DB.Show().
+func (e *ExpectedShow) WillReturn(ret0 *driver.DesignResponse) *ExpectedShow {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.Show().
+func (e *ExpectedShow) WillReturnError(err error) *ExpectedShow {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.Show() to delay.
+func (e *ExpectedShow) WillDelay(delay time.Duration) *ExpectedShow {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedShow) met(ex expectation) bool {
+	exp := ex.(*ExpectedShow)
+	if exp.arg0 != "" && exp.arg0 != e.arg0 {
+		return false
+	}
+	if exp.arg1 != "" && exp.arg1 != e.arg1 {
+		return false
+	}
+	if exp.arg2 != "" && exp.arg2 != e.arg2 {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedShow) method(v bool) string {
+	if !v {
+		return "DB.Show()"
+	}
+	arg0, arg1, arg2, options := "?", "?", "?", formatOptions(e.options)
+	if e.arg0 != "" {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	if e.arg1 != "" {
+		arg1 = fmt.Sprintf("%q", e.arg1)
+	}
+	if e.arg2 != "" {
+		arg2 = fmt.Sprintf("%q", e.arg2)
+	}
+	return fmt.Sprintf("DB(%s).Show(ctx, %s, %s, %s, %s)", e.dbo().name, arg0, arg1, arg2, options)
+}
+
 // ExpectedStats represents an expectation for a call to DB.Stats().
 type ExpectedStats struct {
 	commonExpectation
@@ -1926,3 +2521,129 @@ func (e *ExpectedStats) method(v bool) string {
 	}
 	return fmt.Sprintf("DB(%s).Stats(ctx)", e.dbo().name)
 }
+
+// ExpectedUpdateIndex represents an expectation for a call to DB.UpdateIndex().
+type ExpectedUpdateIndex struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 string) error
+	arg0     string
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedUpdateIndex) WillExecute(cb func(ctx context.Context, arg0 string) error) *ExpectedUpdateIndex {
+	e.callback = cb
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.UpdateIndex().
+func (e *ExpectedUpdateIndex) WillReturnError(err error) *ExpectedUpdateIndex {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.UpdateIndex() to delay.
+func (e *ExpectedUpdateIndex) WillDelay(delay time.Duration) *ExpectedUpdateIndex {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedUpdateIndex) met(ex expectation) bool {
+	exp := ex.(*ExpectedUpdateIndex)
+	if exp.arg0 != "" && exp.arg0 != e.arg0 {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedUpdateIndex) method(v bool) string {
+	if !v {
+		return "DB.UpdateIndex()"
+	}
+	arg0 := "?"
+	if e.arg0 != "" {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	return fmt.Sprintf("DB(%s).UpdateIndex(ctx, %s)", e.dbo().name, arg0)
+}
+
+// ExpectedUpdateWithResponse represents an expectation for a call to DB.UpdateWithResponse().
+type ExpectedUpdateWithResponse struct {
+	commonExpectation
+	callback func(ctx context.Context, arg0 string, arg1 string, arg2 string, arg3 any, options driver.Options) (*driver.UpdateResponse, error)
+	arg0     string
+	arg1     string
+	arg2     string
+	arg3     any
+	ret0     *driver.UpdateResponse
+}
+
+// WithOptions sets the expected options for the call to DB.UpdateWithResponse().
+func (e *ExpectedUpdateWithResponse) WithOptions(options ...kivik.Option) *ExpectedUpdateWithResponse {
+	e.options = multiOptions{e.options, multiOptions(options)}
+	return e
+}
+
+// WillExecute sets a callback function to be called with any inputs to the
+// original function. Any values returned by the callback will be returned as
+// if generated by the driver.
+func (e *ExpectedUpdateWithResponse) WillExecute(cb func(ctx context.Context, arg0 string, arg1 string, arg2 string, arg3 any, options driver.Options) (*driver.UpdateResponse, error)) *ExpectedUpdateWithResponse {
+	e.callback = cb
+	return e
+}
+
+// WillReturn sets the values that will be returned by the call to DB.UpdateWithResponse().
+func (e *ExpectedUpdateWithResponse) WillReturn(ret0 *driver.UpdateResponse) *ExpectedUpdateWithResponse {
+	e.ret0 = ret0
+	return e
+}
+
+// WillReturnError sets the error value that will be returned by the call to DB.UpdateWithResponse().
+func (e *ExpectedUpdateWithResponse) WillReturnError(err error) *ExpectedUpdateWithResponse {
+	e.err = err
+	return e
+}
+
+// WillDelay causes the call to DB.UpdateWithResponse() to delay.
+func (e *ExpectedUpdateWithResponse) WillDelay(delay time.Duration) *ExpectedUpdateWithResponse {
+	e.delay = delay
+	return e
+}
+
+func (e *ExpectedUpdateWithResponse) met(ex expectation) bool {
+	exp := ex.(*ExpectedUpdateWithResponse)
+	if exp.arg0 != "" && exp.arg0 != e.arg0 {
+		return false
+	}
+	if exp.arg1 != "" && exp.arg1 != e.arg1 {
+		return false
+	}
+	if exp.arg2 != "" && exp.arg2 != e.arg2 {
+		return false
+	}
+	if exp.arg3 != nil && !jsonMeets(exp.arg3, e.arg3) {
+		return false
+	}
+	return true
+}
+
+func (e *ExpectedUpdateWithResponse) method(v bool) string {
+	if !v {
+		return "DB.UpdateWithResponse()"
+	}
+	arg0, arg1, arg2, arg3, options := "?", "?", "?", "?", formatOptions(e.options)
+	if e.arg0 != "" {
+		arg0 = fmt.Sprintf("%q", e.arg0)
+	}
+	if e.arg1 != "" {
+		arg1 = fmt.Sprintf("%q", e.arg1)
+	}
+	if e.arg2 != "" {
+		arg2 = fmt.Sprintf("%q", e.arg2)
+	}
+	if e.arg3 != nil {
+		arg3 = fmt.Sprintf("%v", e.arg3)
+	}
+	return fmt.Sprintf("DB(%s).UpdateWithResponse(ctx, %s, %s, %s, %s, %s)", e.dbo().name, arg0, arg1, arg2, arg3, options)
+}
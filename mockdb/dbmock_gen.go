@@ -3,12 +3,15 @@
 package mockdb
 
 import (
+	"io"
+
 	kivik "github.com/go-kivik/kivik/v4"
 	"github.com/go-kivik/kivik/v4/driver"
 )
 
 var _ = kivik.EndKeySuffix // To ensure a reference to kivik package
 var _ = (*driver.Attachment)(nil)
+var _ = io.EOF
 
 // ExpectCompact queues an expectation that DB.Compact will be called.
 func (db *DB) ExpectCompact() *ExpectedCompact {
@@ -90,6 +93,16 @@ func (db *DB) ExpectGetRev() *ExpectedGetRev {
 	return e
 }
 
+// ExpectPurgedInfosLimit queues an expectation that DB.PurgedInfosLimit will be called.
+func (db *DB) ExpectPurgedInfosLimit() *ExpectedPurgedInfosLimit {
+	e := &ExpectedPurgedInfosLimit{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
+
 // ExpectPut queues an expectation that DB.Put will be called.
 func (db *DB) ExpectPut() *ExpectedPut {
 	e := &ExpectedPut{
@@ -100,6 +113,56 @@ func (db *DB) ExpectPut() *ExpectedPut {
 	return e
 }
 
+// ExpectRevsLimit queues an expectation that DB.RevsLimit will be called.
+func (db *DB) ExpectRevsLimit() *ExpectedRevsLimit {
+	e := &ExpectedRevsLimit{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
+
+// ExpectSetPurgedInfosLimit queues an expectation that DB.SetPurgedInfosLimit will be called.
+func (db *DB) ExpectSetPurgedInfosLimit() *ExpectedSetPurgedInfosLimit {
+	e := &ExpectedSetPurgedInfosLimit{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
+
+// ExpectSetRevsLimit queues an expectation that DB.SetRevsLimit will be called.
+func (db *DB) ExpectSetRevsLimit() *ExpectedSetRevsLimit {
+	e := &ExpectedSetRevsLimit{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
+
+// ExpectShards queues an expectation that DB.Shards will be called.
+func (db *DB) ExpectShards() *ExpectedShards {
+	e := &ExpectedShards{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
+
+// ExpectSyncShards queues an expectation that DB.SyncShards will be called.
+func (db *DB) ExpectSyncShards() *ExpectedSyncShards {
+	e := &ExpectedSyncShards{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
+
 // ExpectUpdate queues an expectation that DB.Update will be called.
 func (db *DB) ExpectUpdate() *ExpectedUpdate {
 	e := &ExpectedUpdate{
@@ -180,6 +243,16 @@ func (db *DB) ExpectDeleteAttachment() *ExpectedDeleteAttachment {
 	return e
 }
 
+// ExpectDesignDocInfo queues an expectation that DB.DesignDocInfo will be called.
+func (db *DB) ExpectDesignDocInfo() *ExpectedDesignDocInfo {
+	e := &ExpectedDesignDocInfo{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
+
 // ExpectDesignDocs queues an expectation that DB.DesignDocs will be called.
 func (db *DB) ExpectDesignDocs() *ExpectedDesignDocs {
 	e := &ExpectedDesignDocs{
@@ -190,6 +263,16 @@ func (db *DB) ExpectDesignDocs() *ExpectedDesignDocs {
 	return e
 }
 
+// ExpectDocShards queues an expectation that DB.DocShards will be called.
+func (db *DB) ExpectDocShards() *ExpectedDocShards {
+	e := &ExpectedDocShards{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
+
 // ExpectExplain queues an expectation that DB.Explain will be called.
 func (db *DB) ExpectExplain() *ExpectedExplain {
 	e := &ExpectedExplain{
@@ -251,6 +334,16 @@ func (db *DB) ExpectGetIndexes() *ExpectedGetIndexes {
 	return e
 }
 
+// ExpectList queues an expectation that DB.List will be called.
+func (db *DB) ExpectList() *ExpectedList {
+	e := &ExpectedList{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
+
 // ExpectLocalDocs queues an expectation that DB.LocalDocs will be called.
 func (db *DB) ExpectLocalDocs() *ExpectedLocalDocs {
 	e := &ExpectedLocalDocs{
@@ -322,6 +415,16 @@ func (db *DB) ExpectRevsDiff() *ExpectedRevsDiff {
 	return e
 }
 
+// ExpectRewrite queues an expectation that DB.Rewrite will be called.
+func (db *DB) ExpectRewrite() *ExpectedRewrite {
+	e := &ExpectedRewrite{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
+
 // ExpectSecurity queues an expectation that DB.Security will be called.
 func (db *DB) ExpectSecurity() *ExpectedSecurity {
 	e := &ExpectedSecurity{
@@ -342,6 +445,16 @@ func (db *DB) ExpectSetSecurity() *ExpectedSetSecurity {
 	return e
 }
 
+// ExpectShow queues an expectation that DB.Show will be called.
+func (db *DB) ExpectShow() *ExpectedShow {
+	e := &ExpectedShow{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
+
 // ExpectStats queues an expectation that DB.Stats will be called.
 func (db *DB) ExpectStats() *ExpectedStats {
 	e := &ExpectedStats{
@@ -351,3 +464,23 @@ func (db *DB) ExpectStats() *ExpectedStats {
 	db.client.expected = append(db.client.expected, e)
 	return e
 }
+
+// ExpectUpdateIndex queues an expectation that DB.UpdateIndex will be called.
+func (db *DB) ExpectUpdateIndex() *ExpectedUpdateIndex {
+	e := &ExpectedUpdateIndex{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
+
+// ExpectUpdateWithResponse queues an expectation that DB.UpdateWithResponse will be called.
+func (db *DB) ExpectUpdateWithResponse() *ExpectedUpdateWithResponse {
+	e := &ExpectedUpdateWithResponse{
+		commonExpectation: commonExpectation{db: db},
+	}
+	db.count++
+	db.client.expected = append(db.client.expected, e)
+	return e
+}
@@ -452,3 +452,154 @@ func (e *ExpectedGetReplications) String() string {
 		delayString(e.delay) +
 		errorString(e.err)
 }
+
+func (e *ExpectedReshardSummary) String() string {
+	msg := "call to ReshardSummary()"
+	extra := delayString(e.delay) + errorString(e.err)
+	if e.ret0 != nil {
+		extra = "\n\t- should return state: " + e.ret0.State + extra
+	}
+	if extra != "" {
+		msg += " which:" + extra
+	}
+	return msg
+}
+
+func (e *ExpectedReshardState) String() string {
+	msg := "call to ReshardState()"
+	extra := delayString(e.delay) + errorString(e.err)
+	if e.ret0 != nil {
+		extra = "\n\t- should return state: " + e.ret0.State + extra
+	}
+	if extra != "" {
+		msg += " which:" + extra
+	}
+	return msg
+}
+
+func (e *ExpectedSetReshardState) String() string {
+	msg := "call to SetReshardState() which:"
+	if e.arg0 == nil {
+		msg += "\n\t- has any state"
+	} else {
+		msg += "\n\t- has state: " + e.arg0.State
+	}
+	return msg + delayString(e.delay) + errorString(e.err)
+}
+
+// WithState sets the expected state.
+func (e *ExpectedSetReshardState) WithState(state *driver.ReshardState) *ExpectedSetReshardState {
+	e.arg0 = state
+	return e
+}
+
+func (e *ExpectedReshardJobs) String() string {
+	msg := "call to ReshardJobs()"
+	extra := delayString(e.delay) + errorString(e.err)
+	if l := len(e.ret0); l > 0 {
+		extra = fmt.Sprintf("\n\t- should return: %d jobs", l) + extra
+	}
+	if extra != "" {
+		msg += " which:" + extra
+	}
+	return msg
+}
+
+func (e *ExpectedReshardJob) String() string {
+	msg := "call to ReshardJob() which:" +
+		fieldString("jobID", e.arg0)
+	if e.ret0 != nil {
+		msg += "\n\t- should return job: " + e.ret0.ID
+	}
+	return msg + delayString(e.delay) + errorString(e.err)
+}
+
+// WithJobID sets the expected job ID.
+func (e *ExpectedReshardJob) WithJobID(jobID string) *ExpectedReshardJob {
+	e.arg0 = jobID
+	return e
+}
+
+func (e *ExpectedCreateReshardJobs) String() string {
+	msg := "call to CreateReshardJobs() which:"
+	if e.arg0 == nil {
+		msg += "\n\t- has any request"
+	} else {
+		msg += fmt.Sprintf("\n\t- has request: %+v", *e.arg0)
+	}
+	if l := len(e.ret0); l > 0 {
+		msg += fmt.Sprintf("\n\t- should return: %d results", l)
+	}
+	return msg + delayString(e.delay) + errorString(e.err)
+}
+
+// WithRequest sets the expected request.
+func (e *ExpectedCreateReshardJobs) WithRequest(req *driver.ReshardJobRequest) *ExpectedCreateReshardJobs {
+	e.arg0 = req
+	return e
+}
+
+func (e *ExpectedDeleteReshardJob) String() string {
+	return "call to DeleteReshardJob() which:" +
+		fieldString("jobID", e.arg0) +
+		delayString(e.delay) +
+		errorString(e.err)
+}
+
+// WithJobID sets the expected job ID.
+func (e *ExpectedDeleteReshardJob) WithJobID(jobID string) *ExpectedDeleteReshardJob {
+	e.arg0 = jobID
+	return e
+}
+
+func (e *ExpectedReshardJobState) String() string {
+	msg := "call to ReshardJobState() which:" +
+		fieldString("jobID", e.arg0)
+	if e.ret0 != nil {
+		msg += "\n\t- should return state: " + e.ret0.State
+	}
+	return msg + delayString(e.delay) + errorString(e.err)
+}
+
+// WithJobID sets the expected job ID.
+func (e *ExpectedReshardJobState) WithJobID(jobID string) *ExpectedReshardJobState {
+	e.arg0 = jobID
+	return e
+}
+
+func (e *ExpectedSetReshardJobState) String() string {
+	msg := "call to SetReshardJobState() which:" +
+		fieldString("jobID", e.arg0)
+	if e.arg1 == nil {
+		msg += "\n\t- has any state"
+	} else {
+		msg += "\n\t- has state: " + e.arg1.State
+	}
+	return msg + delayString(e.delay) + errorString(e.err)
+}
+
+// WithJobID sets the expected job ID.
+func (e *ExpectedSetReshardJobState) WithJobID(jobID string) *ExpectedSetReshardJobState {
+	e.arg0 = jobID
+	return e
+}
+
+// WithState sets the expected state.
+func (e *ExpectedSetReshardJobState) WithState(state *driver.ReshardState) *ExpectedSetReshardJobState {
+	e.arg1 = state
+	return e
+}
+
+func (e *ExpectedBackup) String() string {
+	return "call to Backup() which:" +
+		optionsString(e.options) +
+		delayString(e.delay) +
+		errorString(e.err)
+}
+
+func (e *ExpectedRestore) String() string {
+	return "call to Restore() which:" +
+		optionsString(e.options) +
+		delayString(e.delay) +
+		errorString(e.err)
+}
@@ -26,6 +26,25 @@ var clientSkips = map[string]struct{}{
 	"Driver":   {},
 	"DSN":      {},
 	"CreateDB": {},
+
+	// These call the driver methods in clientDriverMethods.
+	"ReshardStart":      {},
+	"ReshardStop":       {},
+	"ReshardSplit":      {},
+	"ReshardDeleteJob":  {},
+	"ReshardStartJob":   {},
+	"ReshardStopJob":    {},
+	"ReshardWaitForJob": {},
+}
+
+// clientDriverMethods are driver methods with no kivik.Client method of the
+// same name, which are called by kivik.Client methods that wrap them. They are
+// mocked, so that expectations may be set for the wrapping methods.
+var clientDriverMethods = map[string]struct{}{
+	"SetReshardState":    {},
+	"CreateReshardJobs":  {},
+	"DeleteReshardJob":   {},
+	"SetReshardJobState": {},
 }
 
 var dbSkips = map[string]struct{}{
@@ -36,6 +55,21 @@ var dbSkips = map[string]struct{}{
 	"Search":        {},
 	"SearchAnalyze": {},
 	"SearchInfo":    {},
+
+	// These use Changes, OpenRevs and BulkDocs, which are mocked.
+	"Export": {},
+	"Import": {},
+
+	// WaitForIndex calls the driver methods Stats, UpdateIndex and
+	// DesignDocInfo.
+	"WaitForIndex": {},
+}
+
+// dbDriverMethods are driver methods with no kivik.DB method of the same
+// name, which are called by kivik.DB methods that wrap them. They are mocked,
+// so that expectations may be set for the wrapping methods.
+var dbDriverMethods = map[string]struct{}{
+	"UpdateIndex": {},
 }
 
 func main() {
@@ -63,6 +97,8 @@ type fullClient interface {
 	driver.ClientReplicator
 	driver.DBUpdater
 	driver.Configer
+	driver.Resharder
+	driver.Backuper
 }
 
 func client() error {
@@ -76,6 +112,9 @@ func client() error {
 		return err
 	}
 	same, cm, dm := compareMethods(client, dMethods)
+	driverOnly := selectMethods(dMethods, clientDriverMethods)
+	dm = sortMethods(append(dm, driverOnly...))
+	cm = sortMethods(append(cm, driverOnly...))
 
 	if err := renderExpectationsGo("clientexpectations_gen.go", append(same, dm...)); err != nil {
 		return err
@@ -104,6 +143,10 @@ type fullDB interface {
 	driver.SecurityDB
 	driver.OpenRever
 	driver.Updater
+	driver.DBMaintainer
+	driver.ViewIndexer
+	driver.DesignFunctioner
+	driver.UpdateResponder
 }
 
 func db() error {
@@ -117,6 +160,9 @@ func db() error {
 		return err
 	}
 	same, cm, dm := compareMethods(client, dMethods)
+	driverOnly := selectMethods(dMethods, dbDriverMethods)
+	dm = sortMethods(append(dm, driverOnly...))
+	cm = sortMethods(append(cm, driverOnly...))
 
 	for _, method := range same {
 		method.DBMethod = true
@@ -161,9 +207,24 @@ func toSlice(methods map[string]*method) []*method {
 	for _, method := range methods {
 		result = append(result, method)
 	}
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].Name < result[j].Name
+	return sortMethods(result)
+}
+
+func sortMethods(methods []*method) []*method {
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name < methods[j].Name
 	})
+	return methods
+}
+
+// selectMethods returns the methods with the given names.
+func selectMethods(methods []*method, names map[string]struct{}) []*method {
+	var result []*method
+	for _, method := range methods {
+		if _, ok := names[method.Name]; ok {
+			result = append(result, method)
+		}
+	}
 	return result
 }
 
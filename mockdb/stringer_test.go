@@ -1546,3 +1546,34 @@ func TestSecurityString(t *testing.T) {
 	})
 	tests.Run(t, testStringer)
 }
+
+func TestSetRevsLimitString(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("empty", stringerTest{
+		input: &ExpectedSetRevsLimit{commonExpectation: commonExpectation{db: &DB{name: "foo"}}},
+		expected: `call to DB(foo#0).SetRevsLimit() which:
+	- has any limit`,
+	})
+	tests.Add("limit", stringerTest{
+		input: &ExpectedSetRevsLimit{commonExpectation: commonExpectation{db: &DB{name: "foo"}}, arg0: 10},
+		expected: `call to DB(foo#0).SetRevsLimit() which:
+	- has limit: 10`,
+	})
+	tests.Run(t, testStringer)
+}
+
+func TestDocShardsString(t *testing.T) {
+	tests := testy.NewTable()
+	tests.Add("empty", stringerTest{
+		input: &ExpectedDocShards{commonExpectation: commonExpectation{db: &DB{name: "foo"}}},
+		expected: `call to DB(foo#0).DocShards() which:
+	- has any docID`,
+	})
+	tests.Add("return", stringerTest{
+		input: &ExpectedDocShards{commonExpectation: commonExpectation{db: &DB{name: "foo"}}, arg0: "bar", ret0: &driver.DocShards{Range: "00000000-ffffffff"}},
+		expected: `call to DB(foo#0).DocShards() which:
+	- has docID: bar
+	- should return range: 00000000-ffffffff`,
+	})
+	tests.Run(t, testStringer)
+}
@@ -0,0 +1,433 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package kivik
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+// Resharding states, as reported by [Client.ReshardState] and
+// [Client.ReshardJobState].
+const (
+	ReshardStateRunning = "running"
+	ReshardStateStopped = "stopped"
+)
+
+// Resharding job states, as reported in [ReshardJob].JobState.
+const (
+	ReshardJobStateNew       = "new"
+	ReshardJobStateRunning   = "running"
+	ReshardJobStateStopped   = "stopped"
+	ReshardJobStateCompleted = "completed"
+	ReshardJobStateFailed    = "failed"
+)
+
+// ReshardSummary is a summary of the resharding state of the cluster, as
+// returned by [Client.ReshardSummary].
+type ReshardSummary struct {
+	// State is the global resharding state, either "running" or "stopped".
+	State string `json:"state"`
+	// StateReason is the reason given when the state was last set.
+	StateReason string `json:"state_reason"`
+	Completed   int    `json:"completed"`
+	Failed      int    `json:"failed"`
+	Running     int    `json:"running"`
+	Stopped     int    `json:"stopped"`
+	Total       int    `json:"total"`
+}
+
+// ReshardState is the state of resharding, either globally or of a single
+// job, as returned by [Client.ReshardState] and [Client.ReshardJobState].
+type ReshardState struct {
+	State  string `json:"state"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ReshardJobEvent is a single entry in the progress history of a resharding
+// job.
+type ReshardJobEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Type is the type of event, such as "new", "start", "checkpoint",
+	// "completed", "stopped" or "job_state_change".
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+}
+
+// ReshardJob describes a single resharding job, as returned by
+// [Client.ReshardJobs] and [Client.ReshardJob].
+type ReshardJob struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Node string `json:"node"`
+	// Source is the shard being split.
+	Source string `json:"source"`
+	// Targets are the shards being created from the source.
+	Targets []string `json:"target"`
+	// JobState is the run state of the job. See the ReshardJobState* constants.
+	JobState string `json:"job_state"`
+	// SplitState is the step of the splitting process the job has reached.
+	SplitState string `json:"split_state"`
+	// Reason is the reason for the current job state, if any.
+	Reason string `json:"reason,omitempty"`
+	// History is the progress history of the job, oldest event first.
+	History    []ReshardJobEvent `json:"history"`
+	StartTime  time.Time         `json:"start_time"`
+	UpdateTime time.Time         `json:"update_time"`
+}
+
+func reshardJob(job *driver.ReshardJob) *ReshardJob {
+	if job == nil {
+		return nil
+	}
+	history := make([]ReshardJobEvent, len(job.History))
+	for i, event := range job.History {
+		history[i] = ReshardJobEvent(event)
+	}
+	return &ReshardJob{
+		ID:         job.ID,
+		Type:       job.Type,
+		Node:       job.Node,
+		Source:     job.Source,
+		Targets:    job.Targets,
+		JobState:   job.JobState,
+		SplitState: job.SplitState,
+		Reason:     job.Reason,
+		History:    history,
+		StartTime:  job.StartTime,
+		UpdateTime: job.UpdateTime,
+	}
+}
+
+// ReshardSplitRequest describes the shards to split by [Client.ReshardSplit].
+// At least one of DB or Shard must be set.
+type ReshardSplitRequest struct {
+	// DB limits splitting to the shards of the named database.
+	DB string
+	// Node limits splitting to shards on the named node.
+	Node string
+	// Range limits splitting to shards covering the named range, such as
+	// "00000000-1fffffff".
+	Range string
+	// Shard names a single shard to split, such as
+	// "shards/00000000-1fffffff/db.1554242778". It may not be combined with
+	// DB or Range.
+	Shard string
+}
+
+// ReshardJobResult is the result of creating a single resharding job, as
+// returned by [Client.ReshardSplit].
+type ReshardJobResult struct {
+	ID    string `json:"id"`
+	Node  string `json:"node"`
+	Shard string `json:"shard"`
+	// Error is non-nil if the job could not be created.
+	Error error `json:"-"`
+}
+
+func (c *Client) resharder() (driver.Resharder, error) {
+	r, ok := c.driverClient.(driver.Resharder)
+	if !ok {
+		return nil, errReshardNotImplemented
+	}
+	return r, nil
+}
+
+// ReshardSummary returns a summary of the resharding state of the cluster.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/server/common.html#get--_reshard
+func (c *Client) ReshardSummary(ctx context.Context) (*ReshardSummary, error) {
+	endQuery, err := c.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	r, err := c.resharder()
+	if err != nil {
+		return nil, err
+	}
+	summary, err := r.ReshardSummary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (*ReshardSummary)(summary), nil
+}
+
+// ReshardState returns the global resharding state.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/server/common.html#get--_reshard-state
+func (c *Client) ReshardState(ctx context.Context) (*ReshardState, error) {
+	endQuery, err := c.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	r, err := c.resharder()
+	if err != nil {
+		return nil, err
+	}
+	state, err := r.ReshardState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (*ReshardState)(state), nil
+}
+
+func (c *Client) setReshardState(ctx context.Context, state, reason string) error {
+	endQuery, err := c.startQuery()
+	if err != nil {
+		return err
+	}
+	defer endQuery()
+	r, err := c.resharder()
+	if err != nil {
+		return err
+	}
+	return r.SetReshardState(ctx, &driver.ReshardState{State: state, Reason: reason})
+}
+
+// ReshardStart resumes resharding globally, after a previous call to
+// [Client.ReshardStop].
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/server/common.html#put--_reshard-state
+func (c *Client) ReshardStart(ctx context.Context) error {
+	return c.setReshardState(ctx, ReshardStateRunning, "")
+}
+
+// ReshardStop stops resharding globally. Running jobs are stopped, and no new
+// jobs are started until [Client.ReshardStart] is called. reason is optional.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/server/common.html#put--_reshard-state
+func (c *Client) ReshardStop(ctx context.Context, reason string) error {
+	return c.setReshardState(ctx, ReshardStateStopped, reason)
+}
+
+// ReshardJobs returns all resharding jobs, including their progress history.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/server/common.html#get--_reshard-jobs
+func (c *Client) ReshardJobs(ctx context.Context) ([]*ReshardJob, error) {
+	endQuery, err := c.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	r, err := c.resharder()
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := r.ReshardJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*ReshardJob, len(jobs))
+	for i, job := range jobs {
+		result[i] = reshardJob(job)
+	}
+	return result, nil
+}
+
+// ReshardJob returns a single resharding job, including its progress history.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/server/common.html#get--_reshard-jobs-jobid
+func (c *Client) ReshardJob(ctx context.Context, jobID string) (*ReshardJob, error) {
+	if jobID == "" {
+		return nil, missingArg("jobID")
+	}
+	endQuery, err := c.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	r, err := c.resharder()
+	if err != nil {
+		return nil, err
+	}
+	job, err := r.ReshardJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return reshardJob(job), nil
+}
+
+// ReshardSplit creates jobs to split the shards described by req. One job is
+// created for each matching shard copy. Jobs which could not be created have
+// their Error field set.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/server/common.html#post--_reshard-jobs
+func (c *Client) ReshardSplit(ctx context.Context, req ReshardSplitRequest) ([]*ReshardJobResult, error) {
+	if req.DB == "" && req.Shard == "" {
+		return nil, &internal.Error{Status: http.StatusBadRequest, Message: "kivik: db or shard required"}
+	}
+	if req.Shard != "" && (req.DB != "" || req.Range != "") {
+		return nil, &internal.Error{Status: http.StatusBadRequest, Message: "kivik: shard may not be combined with db or range"}
+	}
+	endQuery, err := c.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	r, err := c.resharder()
+	if err != nil {
+		return nil, err
+	}
+	results, err := r.CreateReshardJobs(ctx, &driver.ReshardJobRequest{
+		Type:  "split",
+		DB:    req.DB,
+		Node:  req.Node,
+		Range: req.Range,
+		Shard: req.Shard,
+	})
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*ReshardJobResult, len(results))
+	for i, result := range results {
+		jobs[i] = &ReshardJobResult{
+			ID:    result.ID,
+			Node:  result.Node,
+			Shard: result.Shard,
+		}
+		if result.Error != "" {
+			jobs[i].Error = &internal.Error{Status: http.StatusBadRequest, Message: result.Error + ": " + result.Reason}
+		}
+	}
+	return jobs, nil
+}
+
+// ReshardDeleteJob stops and removes a resharding job.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/server/common.html#delete--_reshard-jobs-jobid
+func (c *Client) ReshardDeleteJob(ctx context.Context, jobID string) error {
+	if jobID == "" {
+		return missingArg("jobID")
+	}
+	endQuery, err := c.startQuery()
+	if err != nil {
+		return err
+	}
+	defer endQuery()
+	r, err := c.resharder()
+	if err != nil {
+		return err
+	}
+	return r.DeleteReshardJob(ctx, jobID)
+}
+
+// ReshardJobState returns the run state of a single resharding job.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/server/common.html#get--_reshard-jobs-jobid-state
+func (c *Client) ReshardJobState(ctx context.Context, jobID string) (*ReshardState, error) {
+	if jobID == "" {
+		return nil, missingArg("jobID")
+	}
+	endQuery, err := c.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	r, err := c.resharder()
+	if err != nil {
+		return nil, err
+	}
+	state, err := r.ReshardJobState(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return (*ReshardState)(state), nil
+}
+
+func (c *Client) setReshardJobState(ctx context.Context, jobID, state, reason string) error {
+	if jobID == "" {
+		return missingArg("jobID")
+	}
+	endQuery, err := c.startQuery()
+	if err != nil {
+		return err
+	}
+	defer endQuery()
+	r, err := c.resharder()
+	if err != nil {
+		return err
+	}
+	return r.SetReshardJobState(ctx, jobID, &driver.ReshardState{State: state, Reason: reason})
+}
+
+// ReshardStartJob resumes a stopped resharding job.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/server/common.html#put--_reshard-jobs-jobid-state
+func (c *Client) ReshardStartJob(ctx context.Context, jobID string) error {
+	return c.setReshardJobState(ctx, jobID, ReshardStateRunning, "")
+}
+
+// ReshardStopJob stops a single resharding job. reason is optional.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/server/common.html#put--_reshard-jobs-jobid-state
+func (c *Client) ReshardStopJob(ctx context.Context, jobID, reason string) error {
+	return c.setReshardJobState(ctx, jobID, ReshardStateStopped, reason)
+}
+
+// ReshardWaitForJob polls the named job every interval, until it has
+// completed or failed, or ctx is cancelled. It returns the final state of the
+// job. If the job failed, the job is returned along with an error. A stopped
+// job is waited on until it is resumed and completes, or ctx is cancelled.
+func (c *Client) ReshardWaitForJob(ctx context.Context, jobID string, interval time.Duration) (*ReshardJob, error) {
+	if interval <= 0 {
+		return nil, &internal.Error{Status: http.StatusBadRequest, Message: "kivik: interval must be positive"}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := c.ReshardJob(ctx, jobID)
+		if err != nil {
+			return nil, err
+		}
+		switch job.JobState {
+		case ReshardJobStateCompleted:
+			return job, nil
+		case ReshardJobStateFailed:
+			return job, &internal.Error{Status: http.StatusInternalServerError, Message: fmt.Sprintf("kivik: reshard job %s failed: %s", jobID, job.Reason)}
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package kivik
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+func TestReshardState(t *testing.T) {
+	type tt struct {
+		client *Client
+		want   *ReshardState
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("non-Resharder", tt{
+		client: &Client{
+			driverClient: &mock.Client{},
+		},
+		status: http.StatusNotImplemented,
+		err:    "kivik: driver does not support resharding",
+	})
+	tests.Add("error", tt{
+		client: &Client{
+			driverClient: &mock.Resharder{
+				ReshardStateFunc: func(context.Context) (*driver.ReshardState, error) {
+					return &driver.ReshardState{State: "running"}, errors.New("state error")
+				},
+			},
+		},
+		status: http.StatusInternalServerError,
+		err:    "state error",
+	})
+	tests.Add("success", tt{
+		client: &Client{
+			driverClient: &mock.Resharder{
+				ReshardStateFunc: func(context.Context) (*driver.ReshardState, error) {
+					return &driver.ReshardState{State: "stopped", Reason: "maintenance"}, nil
+				},
+			},
+		},
+		want: &ReshardState{State: "stopped", Reason: "maintenance"},
+	})
+	tests.Add("closed", tt{
+		client: &Client{
+			closed: true,
+		},
+		status: http.StatusServiceUnavailable,
+		err:    "kivik: client closed",
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.client.ReshardState(context.Background())
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected result (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestReshardStop(t *testing.T) {
+	client := &Client{
+		driverClient: &mock.Resharder{
+			SetReshardStateFunc: func(_ context.Context, state *driver.ReshardState) error {
+				want := &driver.ReshardState{State: "stopped", Reason: "maintenance"}
+				if d := cmp.Diff(want, state); d != "" {
+					return fmt.Errorf("Unexpected state:\n%s", d)
+				}
+				return nil
+			},
+		},
+	}
+	if err := client.ReshardStop(context.Background(), "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReshardJobs(t *testing.T) {
+	ts := time.Date(2019, 3, 28, 15, 28, 2, 0, time.UTC)
+	client := &Client{
+		driverClient: &mock.Resharder{
+			ReshardJobsFunc: func(context.Context) ([]*driver.ReshardJob, error) {
+				return []*driver.ReshardJob{
+					{
+						ID:       "001-abc",
+						Type:     "split",
+						JobState: "running",
+						Targets:  []string{"shards/00000000-7fffffff/db1", "shards/80000000-ffffffff/db1"},
+						History: []driver.ReshardJobEvent{
+							{Timestamp: ts, Type: "new"},
+						},
+						StartTime: ts,
+					},
+				}, nil
+			},
+		},
+	}
+	got, err := client.ReshardJobs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*ReshardJob{
+		{
+			ID:       "001-abc",
+			Type:     "split",
+			JobState: "running",
+			Targets:  []string{"shards/00000000-7fffffff/db1", "shards/80000000-ffffffff/db1"},
+			History: []ReshardJobEvent{
+				{Timestamp: ts, Type: "new"},
+			},
+			StartTime: ts,
+		},
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Unexpected result (-want +got):\n%s", d)
+	}
+}
+
+func TestReshardSplit(t *testing.T) {
+	type tt struct {
+		client *Client
+		req    ReshardSplitRequest
+		want   []*ReshardJobResult
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("missing db and shard", tt{
+		client: &Client{
+			driverClient: &mock.Resharder{},
+		},
+		req:    ReshardSplitRequest{Range: "00000000-7fffffff"},
+		status: http.StatusBadRequest,
+		err:    "kivik: db or shard required",
+	})
+	tests.Add("shard with db", tt{
+		client: &Client{
+			driverClient: &mock.Resharder{},
+		},
+		req:    ReshardSplitRequest{DB: "db1", Shard: "shards/00000000-ffffffff/db1.1554242778"},
+		status: http.StatusBadRequest,
+		err:    "kivik: shard may not be combined with db or range",
+	})
+	tests.Add("shard with range", tt{
+		client: &Client{
+			driverClient: &mock.Resharder{},
+		},
+		req:    ReshardSplitRequest{Range: "00000000-7fffffff", Shard: "shards/00000000-ffffffff/db1.1554242778"},
+		status: http.StatusBadRequest,
+		err:    "kivik: shard may not be combined with db or range",
+	})
+	tests.Add("success", tt{
+		client: &Client{
+			driverClient: &mock.Resharder{
+				CreateReshardJobsFunc: func(_ context.Context, req *driver.ReshardJobRequest) ([]*driver.ReshardJobResult, error) {
+					want := &driver.ReshardJobRequest{Type: "split", DB: "db1", Node: "node1"}
+					if d := cmp.Diff(want, req); d != "" {
+						return nil, fmt.Errorf("Unexpected request:\n%s", d)
+					}
+					return []*driver.ReshardJobResult{
+						{ID: "001-abc", Node: "node1", Shard: "shards/00000000-ffffffff/db1"},
+						{Node: "node1", Shard: "shards/00000000-ffffffff/db1", Error: "conflict", Reason: "Job already exists"},
+					}, nil
+				},
+			},
+		},
+		req: ReshardSplitRequest{DB: "db1", Node: "node1"},
+		want: []*ReshardJobResult{
+			{ID: "001-abc", Node: "node1", Shard: "shards/00000000-ffffffff/db1"},
+			{Node: "node1", Shard: "shards/00000000-ffffffff/db1", Error: &internal.Error{Status: http.StatusBadRequest, Message: "conflict: Job already exists"}},
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.client.ReshardSplit(context.Background(), tt.req)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected result (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestReshardStopJob(t *testing.T) {
+	type tt struct {
+		client *Client
+		jobID  string
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("missing jobID", tt{
+		client: &Client{
+			driverClient: &mock.Resharder{},
+		},
+		status: http.StatusBadRequest,
+		err:    "kivik: jobID required",
+	})
+	tests.Add("success", tt{
+		client: &Client{
+			driverClient: &mock.Resharder{
+				SetReshardJobStateFunc: func(_ context.Context, jobID string, state *driver.ReshardState) error {
+					if jobID != "001-abc" {
+						return fmt.Errorf("Unexpected jobID: %s", jobID)
+					}
+					want := &driver.ReshardState{State: "stopped", Reason: "later"}
+					if d := cmp.Diff(want, state); d != "" {
+						return fmt.Errorf("Unexpected state:\n%s", d)
+					}
+					return nil
+				},
+			},
+		},
+		jobID: "001-abc",
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		err := tt.client.ReshardStopJob(context.Background(), tt.jobID, "later")
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+	})
+}
+
+func TestReshardWaitForJob(t *testing.T) {
+	type tt struct {
+		client    *Client
+		timeout   time.Duration
+		wantState string
+		status    int
+		err       string
+	}
+	jobSequence := func(states ...string) *mock.Resharder {
+		var i int
+		return &mock.Resharder{
+			ReshardJobFunc: func(_ context.Context, jobID string) (*driver.ReshardJob, error) {
+				state := states[i]
+				if i < len(states)-1 {
+					i++
+				}
+				return &driver.ReshardJob{ID: jobID, JobState: state, Reason: "disk full"}, nil
+			},
+		}
+	}
+	tests := testy.NewTable()
+	tests.Add("completes", tt{
+		client:    &Client{driverClient: jobSequence("new", "running", "running", "completed")},
+		timeout:   time.Second,
+		wantState: ReshardJobStateCompleted,
+	})
+	tests.Add("fails", tt{
+		client:    &Client{driverClient: jobSequence("running", "failed")},
+		timeout:   time.Second,
+		wantState: ReshardJobStateFailed,
+		status:    http.StatusInternalServerError,
+		err:       "kivik: reshard job 001-abc failed: disk full",
+	})
+	tests.Add("context cancelled while stopped", tt{
+		client:    &Client{driverClient: jobSequence("stopped")},
+		timeout:   10 * time.Millisecond,
+		wantState: ReshardJobStateStopped,
+		status:    http.StatusInternalServerError,
+		err:       "context deadline exceeded",
+	})
+	tests.Add("error", tt{
+		client: &Client{driverClient: &mock.Resharder{
+			ReshardJobFunc: func(context.Context, string) (*driver.ReshardJob, error) {
+				return nil, &internal.Error{Status: http.StatusNotFound, Message: "not found"}
+			},
+		}},
+		timeout: time.Second,
+		status:  http.StatusNotFound,
+		err:     "not found",
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+		t.Cleanup(cancel)
+		job, err := tt.client.ReshardWaitForJob(ctx, "001-abc", time.Millisecond)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		var state string
+		if job != nil {
+			state = job.JobState
+		}
+		if state != tt.wantState {
+			t.Errorf("Unexpected job state: %s", state)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package users
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+const (
+	// SchemePBKDF2 is the CouchDB PBKDF2 password scheme.
+	SchemePBKDF2 = "pbkdf2"
+	// DefaultPRF is the pseudo-random function used by [HashPassword],
+	// matching the CouchDB 3.4 default.
+	DefaultPRF = "sha256"
+	// DefaultIterations is the number of iterations used by [HashPassword],
+	// matching the CouchDB 3.4 default.
+	DefaultIterations = 600000
+)
+
+// saltLength is the length, in bytes, of generated salts.
+const saltLength = 16
+
+// PasswordHash is a PBKDF2 password hash, in the form stored in CouchDB user
+// documents.
+type PasswordHash struct {
+	Scheme string `json:"password_scheme,omitempty"`
+	// PRF is the pseudo-random function. An empty value means sha1, as used
+	// by CouchDB before version 3.4.
+	PRF        string `json:"pbkdf2_prf,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       string `json:"salt,omitempty"`
+	DerivedKey string `json:"derived_key,omitempty"`
+}
+
+// prf returns the hash function, and the derived key length, for name.
+func prf(name string) (func() hash.Hash, int, error) {
+	switch name {
+	case "", "sha":
+		return sha1.New, sha1.Size, nil
+	case "sha224":
+		return sha256.New224, sha256.Size224, nil
+	case "sha256":
+		return sha256.New, sha256.Size, nil
+	case "sha384":
+		return sha512.New384, sha512.Size384, nil
+	case "sha512":
+		return sha512.New, sha512.Size, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported pbkdf2 prf: %s", name)
+}
+
+// HashPassword hashes password with PBKDF2, using a random salt and the
+// CouchDB default pseudo-random function. If iterations is 0,
+// [DefaultIterations] is used.
+func HashPassword(password string, iterations int) (*PasswordHash, error) {
+	if iterations <= 0 {
+		iterations = DefaultIterations
+	}
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	h := &PasswordHash{
+		Scheme:     SchemePBKDF2,
+		PRF:        DefaultPRF,
+		Iterations: iterations,
+		Salt:       hex.EncodeToString(salt),
+	}
+	key, err := h.derive(password)
+	if err != nil {
+		return nil, err
+	}
+	h.DerivedKey = key
+	return h, nil
+}
+
+func (h *PasswordHash) derive(password string) (string, error) {
+	fn, keyLen, err := prf(h.PRF)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(pbkdf2.Key([]byte(password), []byte(h.Salt), h.Iterations, keyLen, fn)), nil
+}
+
+// Verify returns true if password matches the hash.
+func (h *PasswordHash) Verify(password string) bool {
+	if h.Scheme != SchemePBKDF2 || h.Iterations <= 0 {
+		return false
+	}
+	key, err := h.derive(password)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(key), []byte(h.DerivedKey))
+}
+
+// HashDocPassword replaces the plaintext password field of a user document
+// with a PBKDF2 hash, as CouchDB does when a user document is stored. It is a
+// no-op if doc has no password field. If iterations is 0,
+// [DefaultIterations] is used.
+func HashDocPassword(doc map[string]any, iterations int) error {
+	raw, ok := doc["password"]
+	if !ok {
+		return nil
+	}
+	password, ok := raw.(string)
+	if !ok {
+		return &internal.Error{Status: http.StatusBadRequest, Message: "doc.password must be a string"}
+	}
+	h, err := HashPassword(password, iterations)
+	if err != nil {
+		return err
+	}
+	delete(doc, "password")
+	doc["password_scheme"] = h.Scheme
+	doc["pbkdf2_prf"] = h.PRF
+	doc["iterations"] = h.Iterations
+	doc["salt"] = h.Salt
+	doc["derived_key"] = h.DerivedKey
+	return nil
+}
@@ -0,0 +1,97 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package users
+
+import (
+	"testing"
+)
+
+func TestPasswordHashVerify(t *testing.T) {
+	// Test vector from RFC 6070.
+	h := &PasswordHash{
+		Scheme:     SchemePBKDF2,
+		Iterations: 1,
+		Salt:       "salt",
+		DerivedKey: "0c60c80f961f0e71f3a9b524af6012062fe037a6",
+	}
+	if !h.Verify("password") {
+		t.Error("expected password to verify")
+	}
+	if h.Verify("wrong") {
+		t.Error("expected wrong password to fail")
+	}
+	h.Scheme = "simple"
+	if h.Verify("password") {
+		t.Error("expected unsupported scheme to fail")
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	h, err := HashPassword("abc", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Scheme != SchemePBKDF2 || h.PRF != DefaultPRF || h.Iterations != 10 {
+		t.Errorf("Unexpected hash parameters: %+v", h)
+	}
+	if len(h.Salt) != saltLength*2 {
+		t.Errorf("Unexpected salt: %s", h.Salt)
+	}
+	if !h.Verify("abc") {
+		t.Error("expected password to verify")
+	}
+	h2, err := HashPassword("abc", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Salt == h2.Salt || h.DerivedKey == h2.DerivedKey {
+		t.Error("expected unique salts")
+	}
+}
+
+func TestHashDocPassword(t *testing.T) {
+	t.Run("no password", func(t *testing.T) {
+		doc := map[string]any{"name": "bob"}
+		if err := HashDocPassword(doc, 1); err != nil {
+			t.Fatal(err)
+		}
+		if len(doc) != 1 {
+			t.Errorf("Unexpected doc: %v", doc)
+		}
+	})
+	t.Run("invalid password", func(t *testing.T) {
+		doc := map[string]any{"password": 123}
+		if err := HashDocPassword(doc, 1); err == nil {
+			t.Error("expected an error")
+		}
+	})
+	t.Run("success", func(t *testing.T) {
+		doc := map[string]any{"name": "bob", "password": "abc"}
+		if err := HashDocPassword(doc, 1); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := doc["password"]; ok {
+			t.Error("plaintext password not removed")
+		}
+		h := &PasswordHash{
+			Scheme:     doc["password_scheme"].(string),
+			PRF:        doc["pbkdf2_prf"].(string),
+			Iterations: doc["iterations"].(int),
+			Salt:       doc["salt"].(string),
+			DerivedKey: doc["derived_key"].(string),
+		}
+		if !h.Verify("abc") {
+			t.Error("expected password to verify")
+		}
+	})
+}
@@ -0,0 +1,181 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+// Package users provides helpers for managing users stored in the CouchDB
+// [_users database]. It works with any driver which provides a _users
+// database.
+//
+// [_users database]: https://docs.couchdb.org/en/stable/intro/security.html#users-documents
+package users
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kivik/kivik/v4"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+const (
+	// DBName is the name of the users database.
+	DBName = "_users"
+	// IDPrefix is the prefix of all user document IDs.
+	IDPrefix = "org.couchdb.user:"
+	// TypeUser is the value of the type field of user documents.
+	TypeUser = "user"
+)
+
+// DocID returns the document ID of the named user.
+func DocID(name string) string {
+	return IDPrefix + name
+}
+
+// User represents a CouchDB user document.
+type User struct {
+	ID    string   `json:"_id"`
+	Rev   string   `json:"_rev,omitempty"`
+	Name  string   `json:"name"`
+	Type  string   `json:"type"`
+	Roles []string `json:"roles"`
+	// Password is the plaintext password. It is only set when a password is
+	// being changed, and is replaced by the derived key when stored.
+	Password string `json:"password,omitempty"`
+	PasswordHash
+}
+
+// Manager manages users stored in a users database.
+type Manager struct {
+	db *kivik.DB
+}
+
+// New returns a Manager for the _users database of client.
+func New(client *kivik.Client) *Manager {
+	return NewFromDB(client.DB(DBName))
+}
+
+// NewFromDB returns a Manager for db, which must be a users database. Use
+// this when the server is configured with a non-default authentication
+// database.
+func NewFromDB(db *kivik.DB) *Manager {
+	return &Manager{db: db}
+}
+
+// CreateUser creates a new user with the given name, password and roles,
+// and returns the revision of the new user document.
+func (m *Manager) CreateUser(ctx context.Context, name, password string, roles ...string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", &internal.Error{Status: http.StatusBadRequest, Message: "users: password required"}
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	if err := ValidateRoles(roles); err != nil {
+		return "", err
+	}
+	return m.db.Put(ctx, DocID(name), map[string]any{
+		"_id":      DocID(name),
+		"name":     name,
+		"type":     TypeUser,
+		"roles":    roles,
+		"password": password,
+	})
+}
+
+// GetUser returns the named user.
+func (m *Manager) GetUser(ctx context.Context, name string) (*User, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	var user User
+	if err := m.db.Get(ctx, DocID(name)).ScanDoc(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetPassword changes the password of the named user, and returns the new
+// revision of the user document.
+func (m *Manager) SetPassword(ctx context.Context, name, password string) (string, error) {
+	if password == "" {
+		return "", &internal.Error{Status: http.StatusBadRequest, Message: "users: password required"}
+	}
+	return m.update(ctx, name, func(doc map[string]any) {
+		doc["password"] = password
+	})
+}
+
+// SetRoles replaces the roles of the named user, and returns the new revision
+// of the user document.
+func (m *Manager) SetRoles(ctx context.Context, name string, roles ...string) (string, error) {
+	if roles == nil {
+		roles = []string{}
+	}
+	if err := ValidateRoles(roles); err != nil {
+		return "", err
+	}
+	return m.update(ctx, name, func(doc map[string]any) {
+		doc["roles"] = roles
+	})
+}
+
+// update fetches the named user document, applies fn, and stores the result.
+// The document is handled as a map, so that any additional fields are
+// preserved.
+func (m *Manager) update(ctx context.Context, name string, fn func(map[string]any)) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	var doc map[string]any
+	if err := m.db.Get(ctx, DocID(name)).ScanDoc(&doc); err != nil {
+		return "", err
+	}
+	fn(doc)
+	return m.db.Put(ctx, DocID(name), doc)
+}
+
+// DeleteUser deletes the named user, and returns the revision of the
+// deletion.
+func (m *Manager) DeleteUser(ctx context.Context, name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	rev, err := m.db.GetRev(ctx, DocID(name))
+	if err != nil {
+		return "", err
+	}
+	return m.db.Delete(ctx, DocID(name), rev)
+}
+
+// ListUsers returns all users in the users database, ordered by name.
+func (m *Manager) ListUsers(ctx context.Context) ([]*User, error) {
+	rows := m.db.AllDocs(ctx, kivik.Params(map[string]any{
+		"include_docs": true,
+		"startkey":     IDPrefix,
+		"endkey":       IDPrefix + "\ufff0",
+	}))
+	defer rows.Close()
+	users := []*User{}
+	for rows.Next() {
+		var user User
+		if err := rows.ScanDoc(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
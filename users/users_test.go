@@ -0,0 +1,318 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/mockdb"
+)
+
+// newManager returns a Manager backed by a mock _users database.
+func newManager(t *testing.T, setup func(*mockdb.DB)) *Manager {
+	t.Helper()
+	client, mock, err := mockdb.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(DBName).WillReturn(db)
+	if setup != nil {
+		setup(db)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return New(client)
+}
+
+// expectDoc returns a Put callback which checks the stored document.
+func expectDoc(want map[string]any) func(context.Context, string, any, driver.Options) (string, error) {
+	return func(_ context.Context, _ string, doc any, _ driver.Options) (string, error) {
+		if d := testy.DiffAsJSON(want, doc); d != nil {
+			return "", fmt.Errorf("Unexpected doc:\n%s", d)
+		}
+		return "2-xxx", nil
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	type tt struct {
+		manager  *Manager
+		name     string
+		password string
+		roles    []string
+		wantRev  string
+		status   int
+		err      string
+	}
+
+	tests := testy.NewTable()
+	tests.Add("invalid name", func(t *testing.T) any {
+		return tt{
+			manager:  newManager(t, nil),
+			name:     "_bob",
+			password: "abc",
+			status:   http.StatusForbidden,
+			err:      "Username may not start with underscore.",
+		}
+	})
+	tests.Add("missing password", func(t *testing.T) any {
+		return tt{
+			manager: newManager(t, nil),
+			name:    "bob",
+			status:  http.StatusBadRequest,
+			err:     "users: password required",
+		}
+	})
+	tests.Add("system role", func(t *testing.T) any {
+		return tt{
+			manager:  newManager(t, nil),
+			name:     "bob",
+			password: "abc",
+			roles:    []string{"_admin"},
+			status:   http.StatusForbidden,
+			err:      "No system roles (starting with underscore) in users db.",
+		}
+	})
+	tests.Add("conflict", func(t *testing.T) any {
+		return tt{
+			manager: newManager(t, func(db *mockdb.DB) {
+				db.ExpectPut().WithDocID("org.couchdb.user:bob").
+					WillReturnError(&internal.Error{Status: http.StatusConflict, Message: "Document update conflict."})
+			}),
+			name:     "bob",
+			password: "abc",
+			status:   http.StatusConflict,
+			err:      "Document update conflict.",
+		}
+	})
+	tests.Add("success", func(t *testing.T) any {
+		return tt{
+			manager: newManager(t, func(db *mockdb.DB) {
+				db.ExpectPut().WithDocID("org.couchdb.user:bob").WillExecute(expectDoc(map[string]any{
+					"_id":      "org.couchdb.user:bob",
+					"name":     "bob",
+					"type":     "user",
+					"roles":    []string{"_metrics", "editor"},
+					"password": "abc",
+				}))
+			}),
+			name:     "bob",
+			password: "abc",
+			roles:    []string{"_metrics", "editor"},
+			wantRev:  "2-xxx",
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		rev, err := tt.manager.CreateUser(context.Background(), tt.name, tt.password, tt.roles...)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if rev != tt.wantRev {
+			t.Errorf("Unexpected rev: %s", rev)
+		}
+	})
+}
+
+func TestGetUser(t *testing.T) {
+	m := newManager(t, func(db *mockdb.DB) {
+		db.ExpectGet().WithDocID("org.couchdb.user:bob").WillReturn(mockdb.DocumentT(t, map[string]any{
+			"_id":             "org.couchdb.user:bob",
+			"_rev":            "1-xxx",
+			"name":            "bob",
+			"type":            "user",
+			"roles":           []string{"editor"},
+			"password_scheme": "pbkdf2",
+			"iterations":      10,
+			"salt":            "abc",
+			"derived_key":     "def",
+		}))
+	})
+	user, err := m.GetUser(context.Background(), "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &User{
+		ID:    "org.couchdb.user:bob",
+		Rev:   "1-xxx",
+		Name:  "bob",
+		Type:  "user",
+		Roles: []string{"editor"},
+		PasswordHash: PasswordHash{
+			Scheme:     "pbkdf2",
+			Iterations: 10,
+			Salt:       "abc",
+			DerivedKey: "def",
+		},
+	}
+	if d := testy.DiffInterface(want, user); d != nil {
+		t.Error(d)
+	}
+}
+
+func TestSetPassword(t *testing.T) {
+	stored := map[string]any{
+		"_id":             "org.couchdb.user:bob",
+		"_rev":            "1-xxx",
+		"name":            "bob",
+		"type":            "user",
+		"roles":           []string{},
+		"email":           "bob@example.com",
+		"password_scheme": "pbkdf2",
+		"iterations":      10,
+		"salt":            "abc",
+		"derived_key":     "def",
+	}
+	m := newManager(t, func(db *mockdb.DB) {
+		db.ExpectGet().WithDocID("org.couchdb.user:bob").WillReturn(mockdb.DocumentT(t, stored))
+		db.ExpectPut().WithDocID("org.couchdb.user:bob").WillExecute(expectDoc(map[string]any{
+			"_id":             "org.couchdb.user:bob",
+			"_rev":            "1-xxx",
+			"name":            "bob",
+			"type":            "user",
+			"roles":           []string{},
+			"email":           "bob@example.com",
+			"password":        "new",
+			"password_scheme": "pbkdf2",
+			"iterations":      10,
+			"salt":            "abc",
+			"derived_key":     "def",
+		}))
+	})
+	rev, err := m.SetPassword(context.Background(), "bob", "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev != "2-xxx" {
+		t.Errorf("Unexpected rev: %s", rev)
+	}
+}
+
+func TestSetRoles(t *testing.T) {
+	t.Run("system role", func(t *testing.T) {
+		m := newManager(t, nil)
+		_, err := m.SetRoles(context.Background(), "bob", "_reader")
+		if d := internal.StatusErrorDiff("No system roles (starting with underscore) in users db.", http.StatusForbidden, err); d != "" {
+			t.Error(d)
+		}
+	})
+	t.Run("missing user", func(t *testing.T) {
+		m := newManager(t, func(db *mockdb.DB) {
+			db.ExpectGet().WithDocID("org.couchdb.user:bob").
+				WillReturnError(&internal.Error{Status: http.StatusNotFound, Message: "missing"})
+		})
+		_, err := m.SetRoles(context.Background(), "bob", "editor")
+		if d := internal.StatusErrorDiff("missing", http.StatusNotFound, err); d != "" {
+			t.Error(d)
+		}
+	})
+	t.Run("success", func(t *testing.T) {
+		m := newManager(t, func(db *mockdb.DB) {
+			db.ExpectGet().WithDocID("org.couchdb.user:bob").WillReturn(mockdb.DocumentT(t, map[string]any{
+				"_id":   "org.couchdb.user:bob",
+				"_rev":  "1-xxx",
+				"name":  "bob",
+				"type":  "user",
+				"roles": []string{"editor"},
+			}))
+			db.ExpectPut().WithDocID("org.couchdb.user:bob").WillExecute(expectDoc(map[string]any{
+				"_id":   "org.couchdb.user:bob",
+				"_rev":  "1-xxx",
+				"name":  "bob",
+				"type":  "user",
+				"roles": []string{},
+			}))
+		})
+		if _, err := m.SetRoles(context.Background(), "bob"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	m := newManager(t, func(db *mockdb.DB) {
+		db.ExpectGetRev().WithDocID("org.couchdb.user:bob").WillReturn("1-xxx")
+		db.ExpectDelete().WithDocID("org.couchdb.user:bob").
+			WillExecute(func(_ context.Context, _ string, opts driver.Options) (string, error) {
+				params := map[string]any{}
+				opts.Apply(params)
+				if params["rev"] != "1-xxx" {
+					return "", fmt.Errorf("Unexpected rev: %v", params["rev"])
+				}
+				return "2-xxx", nil
+			})
+	})
+	rev, err := m.DeleteUser(context.Background(), "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev != "2-xxx" {
+		t.Errorf("Unexpected rev: %s", rev)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		m := newManager(t, func(db *mockdb.DB) {
+			db.ExpectAllDocs().WillReturnError(errors.New("db error"))
+		})
+		_, err := m.ListUsers(context.Background())
+		if d := internal.StatusErrorDiff("db error", http.StatusInternalServerError, err); d != "" {
+			t.Error(d)
+		}
+	})
+	t.Run("success", func(t *testing.T) {
+		m := newManager(t, func(db *mockdb.DB) {
+			db.ExpectAllDocs().
+				WithOptions(kivik.Params(map[string]any{
+					"include_docs": true,
+					"startkey":     IDPrefix,
+					"endkey":       IDPrefix + "\ufff0",
+				})).
+				WillReturn(mockdb.NewRows().
+					AddRow(&driver.Row{
+						ID:  "org.couchdb.user:alice",
+						Doc: strings.NewReader(`{"_id":"org.couchdb.user:alice","name":"alice","type":"user","roles":[]}`),
+					}).
+					AddRow(&driver.Row{
+						ID:  "org.couchdb.user:bob",
+						Doc: strings.NewReader(`{"_id":"org.couchdb.user:bob","name":"bob","type":"user","roles":["editor"]}`),
+					}))
+		})
+		got, err := m.ListUsers(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []*User{
+			{ID: "org.couchdb.user:alice", Name: "alice", Type: "user", Roles: []string{}},
+			{ID: "org.couchdb.user:bob", Name: "bob", Type: "user", Roles: []string{"editor"}},
+		}
+		if d := testy.DiffInterface(want, got); d != nil {
+			t.Error(d)
+		}
+	})
+}
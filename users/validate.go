@@ -0,0 +1,99 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package users
+
+import (
+	"net/http"
+	"strings"
+
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+// roleMetrics is the only system role permitted in the users database.
+const roleMetrics = "_metrics"
+
+func forbidden(msg string) error {
+	return &internal.Error{Status: http.StatusForbidden, Message: msg}
+}
+
+// ValidateName returns an error if name is not a valid user name, following
+// the rules of CouchDB's _users validation function.
+func ValidateName(name string) error {
+	switch {
+	case name == "":
+		return forbidden("doc.name is required")
+	case name[0] == '_':
+		return forbidden("Username may not start with underscore.")
+	case strings.Contains(name, ":"):
+		return forbidden("Character `:` is not allowed in usernames.")
+	}
+	return nil
+}
+
+// ValidateRoles returns an error if roles contains a reserved system role,
+// which may not be assigned to users in the users database.
+func ValidateRoles(roles []string) error {
+	for _, role := range roles {
+		if role != roleMetrics && strings.HasPrefix(role, "_") {
+			return forbidden("No system roles (starting with underscore) in users db.")
+		}
+	}
+	return nil
+}
+
+// ValidateDoc validates a user document, following the rules of CouchDB's
+// _users validation function. Deleted documents are always valid.
+func ValidateDoc(doc map[string]any) error {
+	if deleted, _ := doc["_deleted"].(bool); deleted {
+		return nil
+	}
+	if doc["type"] != TypeUser {
+		return forbidden("doc.type must be user")
+	}
+	name, ok := doc["name"].(string)
+	if !ok || name == "" {
+		return forbidden("doc.name is required")
+	}
+	rawRoles, ok := doc["roles"]
+	if !ok || rawRoles == nil {
+		return forbidden("doc.roles must exist")
+	}
+	var roles []string
+	switch t := rawRoles.(type) {
+	case []string:
+		roles = t
+	case []any:
+		roles = make([]string, len(t))
+		for i, r := range t {
+			role, ok := r.(string)
+			if !ok {
+				return forbidden("doc.roles can only contain strings")
+			}
+			roles[i] = role
+		}
+	default:
+		return forbidden("doc.roles must be an array")
+	}
+	if doc["_id"] != DocID(name) {
+		return forbidden("Doc ID must be of the form " + IDPrefix + "name")
+	}
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if password, ok := doc["password"]; ok {
+		if _, ok := password.(string); !ok {
+			return forbidden("doc.password must be a string")
+		}
+	}
+	return ValidateRoles(roles)
+}
@@ -0,0 +1,119 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package users
+
+import (
+	"net/http"
+	"testing"
+
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name string
+		err  string
+	}{
+		{name: "bob"},
+		{name: "", err: "doc.name is required"},
+		{name: "_bob", err: "Username may not start with underscore."},
+		{name: "bob:smith", err: "Character `:` is not allowed in usernames."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateName(tt.name)
+			status := 0
+			if tt.err != "" {
+				status = http.StatusForbidden
+			}
+			if d := internal.StatusErrorDiff(tt.err, status, err); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestValidateDoc(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  map[string]any
+		err  string
+	}{
+		{
+			name: "valid",
+			doc: map[string]any{
+				"_id":      "org.couchdb.user:bob",
+				"name":     "bob",
+				"type":     "user",
+				"roles":    []any{"_metrics", "editor"},
+				"password": "abc",
+			},
+		},
+		{
+			name: "deleted",
+			doc:  map[string]any{"_id": "org.couchdb.user:bob", "_deleted": true},
+		},
+		{
+			name: "wrong type",
+			doc:  map[string]any{"_id": "org.couchdb.user:bob", "name": "bob", "type": "admin", "roles": []any{}},
+			err:  "doc.type must be user",
+		},
+		{
+			name: "missing name",
+			doc:  map[string]any{"_id": "org.couchdb.user:bob", "type": "user", "roles": []any{}},
+			err:  "doc.name is required",
+		},
+		{
+			name: "missing roles",
+			doc:  map[string]any{"_id": "org.couchdb.user:bob", "name": "bob", "type": "user"},
+			err:  "doc.roles must exist",
+		},
+		{
+			name: "roles not array",
+			doc:  map[string]any{"_id": "org.couchdb.user:bob", "name": "bob", "type": "user", "roles": "editor"},
+			err:  "doc.roles must be an array",
+		},
+		{
+			name: "non-string role",
+			doc:  map[string]any{"_id": "org.couchdb.user:bob", "name": "bob", "type": "user", "roles": []any{1}},
+			err:  "doc.roles can only contain strings",
+		},
+		{
+			name: "mismatched id",
+			doc:  map[string]any{"_id": "org.couchdb.user:alice", "name": "bob", "type": "user", "roles": []any{}},
+			err:  "Doc ID must be of the form org.couchdb.user:name",
+		},
+		{
+			name: "system role",
+			doc:  map[string]any{"_id": "org.couchdb.user:bob", "name": "bob", "type": "user", "roles": []any{"_admin"}},
+			err:  "No system roles (starting with underscore) in users db.",
+		},
+		{
+			name: "non-string password",
+			doc:  map[string]any{"_id": "org.couchdb.user:bob", "name": "bob", "type": "user", "roles": []any{}, "password": 123},
+			err:  "doc.password must be a string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDoc(tt.doc)
+			status := 0
+			if tt.err != "" {
+				status = http.StatusForbidden
+			}
+			if d := internal.StatusErrorDiff(tt.err, status, err); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
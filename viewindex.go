@@ -0,0 +1,174 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package kivik
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+// indexPollInterval is the interval at which [DB.WaitForIndex] polls the
+// index status.
+var indexPollInterval = 250 * time.Millisecond
+
+// DesignDocInfo contains information about a design document's view index, as
+// returned by [DB.DesignDocInfo].
+type DesignDocInfo struct {
+	// Name is the name of the design document, without the "_design/"
+	// prefix.
+	Name      string        `json:"name"`
+	ViewIndex ViewIndexInfo `json:"view_index"`
+}
+
+// ViewIndexInfo describes the state of a design document's view index.
+type ViewIndexInfo struct {
+	// Signature is a hash of the view definitions. It changes whenever the
+	// views are modified, which requires a full index rebuild.
+	Signature string         `json:"signature"`
+	Language  string         `json:"language"`
+	Sizes     ViewIndexSizes `json:"sizes"`
+	// UpdateSeq is the database sequence up to which the index is current.
+	UpdateSeq int64 `json:"update_seq"`
+	// PurgeSeq is the purge sequence processed by the index.
+	PurgeSeq       int64 `json:"purge_seq"`
+	UpdaterRunning bool  `json:"updater_running"`
+	CompactRunning bool  `json:"compact_running"`
+}
+
+// ViewIndexSizes contains the size, in bytes, of a view index.
+type ViewIndexSizes struct {
+	// Active is the size of live data in the index.
+	Active int64 `json:"active"`
+	// External is the uncompressed size of the index contents.
+	External int64 `json:"external"`
+	// File is the size of the index on disk.
+	File int64 `json:"file"`
+}
+
+func (db *DB) viewIndexer() (driver.ViewIndexer, error) {
+	if db.err != nil {
+		return nil, db.err
+	}
+	ixer, ok := db.driverDB.(driver.ViewIndexer)
+	if !ok {
+		return nil, errViewIndexerNotImplemented
+	}
+	return ixer, nil
+}
+
+// DesignDocInfo returns information about the view index of the named design
+// document. The "_design/" prefix of ddoc is optional.
+//
+// See the [CouchDB documentation].
+//
+// [CouchDB documentation]: https://docs.couchdb.org/en/stable/api/ddoc/common.html#get--db-_design-ddoc-_info
+func (db *DB) DesignDocInfo(ctx context.Context, ddoc string) (*DesignDocInfo, error) {
+	ixer, err := db.viewIndexer()
+	if err != nil {
+		return nil, err
+	}
+	ddoc = strings.TrimPrefix(ddoc, "_design/")
+	if ddoc == "" {
+		return nil, missingArg("ddoc")
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer endQuery()
+	info, err := ixer.DesignDocInfo(ctx, ddoc)
+	if err != nil {
+		return nil, err
+	}
+	return &DesignDocInfo{
+		Name: info.Name,
+		ViewIndex: ViewIndexInfo{
+			Signature:      info.ViewIndex.Signature,
+			Language:       info.ViewIndex.Language,
+			Sizes:          ViewIndexSizes(info.ViewIndex.Sizes),
+			UpdateSeq:      info.ViewIndex.UpdateSeq,
+			PurgeSeq:       info.ViewIndex.PurgeSeq,
+			UpdaterRunning: info.ViewIndex.UpdaterRunning,
+			CompactRunning: info.ViewIndex.CompactRunning,
+		},
+	}, nil
+}
+
+// WaitForIndex triggers an update of the view index of the named design
+// document, then blocks until the index has caught up with the database's
+// update sequence as of the time of the call, or until ctx is cancelled. The
+// "_design/" prefix of ddoc is optional.
+func (db *DB) WaitForIndex(ctx context.Context, ddoc string) error {
+	ixer, err := db.viewIndexer()
+	if err != nil {
+		return err
+	}
+	ddoc = strings.TrimPrefix(ddoc, "_design/")
+	if ddoc == "" {
+		return missingArg("ddoc")
+	}
+	endQuery, err := db.startQuery()
+	if err != nil {
+		return err
+	}
+	defer endQuery()
+
+	stats, err := db.driverDB.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	target, err := seqNum(stats.UpdateSeq)
+	if err != nil {
+		return err
+	}
+	if err := ixer.UpdateIndex(ctx, ddoc); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(indexPollInterval)
+	defer ticker.Stop()
+	for {
+		info, err := ixer.DesignDocInfo(ctx, ddoc)
+		if err != nil {
+			return err
+		}
+		if info.ViewIndex.UpdateSeq >= target {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// seqNum returns the numeric prefix of an update sequence, such as 12 for
+// "12-g1AAAA...".
+func seqNum(seq string) (int64, error) {
+	prefix, _, _ := strings.Cut(seq, "-")
+	if prefix == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil {
+		return 0, &internal.Error{Status: http.StatusBadGateway, Message: "kivik: invalid update sequence: " + seq}
+	}
+	return n, nil
+}
@@ -0,0 +1,226 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package kivik
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+func TestDesignDocInfo(t *testing.T) {
+	type tt struct {
+		db     *DB
+		ddoc   string
+		want   *DesignDocInfo
+		status int
+		err    string
+	}
+	tests := testy.NewTable()
+	tests.Add("non-ViewIndexer", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.DB{},
+		},
+		ddoc:   "foo",
+		status: http.StatusNotImplemented,
+		err:    "kivik: driver does not support view index operations",
+	})
+	tests.Add("missing ddoc", tt{
+		db: &DB{
+			client:   &Client{},
+			driverDB: &mock.ViewIndexer{},
+		},
+		ddoc:   "_design/",
+		status: http.StatusBadRequest,
+		err:    "kivik: ddoc required",
+	})
+	tests.Add("error", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.ViewIndexer{
+				DesignDocInfoFunc: func(context.Context, string) (*driver.DesignDocInfo, error) {
+					return nil, &internal.Error{Status: http.StatusNotFound, Err: errors.New("missing")}
+				},
+			},
+		},
+		ddoc:   "foo",
+		status: http.StatusNotFound,
+		err:    "missing",
+	})
+	tests.Add("success", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.ViewIndexer{
+				DesignDocInfoFunc: func(_ context.Context, ddoc string) (*driver.DesignDocInfo, error) {
+					if ddoc != "foo" {
+						return nil, fmt.Errorf("Unexpected ddoc: %s", ddoc)
+					}
+					return &driver.DesignDocInfo{
+						Name: "foo",
+						ViewIndex: driver.ViewIndexInfo{
+							Signature:      "a59a1bb13fdf8a8a584bc477919c97ac",
+							Language:       "javascript",
+							Sizes:          driver.ViewIndexSizes{Active: 1, External: 2, File: 3},
+							UpdateSeq:      19,
+							PurgeSeq:       1,
+							UpdaterRunning: true,
+						},
+					}, nil
+				},
+			},
+		},
+		ddoc: "_design/foo",
+		want: &DesignDocInfo{
+			Name: "foo",
+			ViewIndex: ViewIndexInfo{
+				Signature:      "a59a1bb13fdf8a8a584bc477919c97ac",
+				Language:       "javascript",
+				Sizes:          ViewIndexSizes{Active: 1, External: 2, File: 3},
+				UpdateSeq:      19,
+				PurgeSeq:       1,
+				UpdaterRunning: true,
+			},
+		},
+	})
+	tests.Add("db error", tt{
+		db: &DB{
+			err: errors.New("db error"),
+		},
+		ddoc:   "foo",
+		status: http.StatusInternalServerError,
+		err:    "db error",
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		got, err := tt.db.DesignDocInfo(context.Background(), tt.ddoc)
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected result (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestWaitForIndex(t *testing.T) {
+	interval := indexPollInterval
+	indexPollInterval = time.Millisecond
+	t.Cleanup(func() {
+		indexPollInterval = interval
+	})
+
+	type tt struct {
+		db      *DB
+		timeout time.Duration
+		status  int
+		err     string
+	}
+	stats := func(seq string) func(context.Context) (*driver.DBStats, error) {
+		return func(context.Context) (*driver.DBStats, error) {
+			return &driver.DBStats{UpdateSeq: seq}, nil
+		}
+	}
+	// progress returns a DesignDocInfoFunc reporting each of seqs in turn,
+	// repeating the last one.
+	progress := func(seqs ...int64) func(context.Context, string) (*driver.DesignDocInfo, error) {
+		var i int
+		return func(context.Context, string) (*driver.DesignDocInfo, error) {
+			seq := seqs[i]
+			if i < len(seqs)-1 {
+				i++
+			}
+			return &driver.DesignDocInfo{ViewIndex: driver.ViewIndexInfo{UpdateSeq: seq}}, nil
+		}
+	}
+	tests := testy.NewTable()
+	tests.Add("already current", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.ViewIndexer{
+				DB:                &mock.DB{StatsFunc: stats("5-g1AAAA")},
+				UpdateIndexFunc:   func(context.Context, string) error { return nil },
+				DesignDocInfoFunc: progress(5),
+			},
+		},
+		timeout: time.Second,
+	})
+	tests.Add("catches up", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.ViewIndexer{
+				DB:                &mock.DB{StatsFunc: stats("12")},
+				UpdateIndexFunc:   func(context.Context, string) error { return nil },
+				DesignDocInfoFunc: progress(0, 4, 9, 12),
+			},
+		},
+		timeout: time.Second,
+	})
+	tests.Add("update error", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.ViewIndexer{
+				DB: &mock.DB{StatsFunc: stats("12")},
+				UpdateIndexFunc: func(context.Context, string) error {
+					return &internal.Error{Status: http.StatusNotFound, Message: "design document has no views"}
+				},
+			},
+		},
+		timeout: time.Second,
+		status:  http.StatusNotFound,
+		err:     "design document has no views",
+	})
+	tests.Add("invalid update seq", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.ViewIndexer{
+				DB: &mock.DB{StatsFunc: stats("foo")},
+			},
+		},
+		timeout: time.Second,
+		status:  http.StatusBadGateway,
+		err:     "kivik: invalid update sequence: foo",
+	})
+	tests.Add("timeout", tt{
+		db: &DB{
+			client: &Client{},
+			driverDB: &mock.ViewIndexer{
+				DB:                &mock.DB{StatsFunc: stats("12")},
+				UpdateIndexFunc:   func(context.Context, string) error { return nil },
+				DesignDocInfoFunc: progress(3),
+			},
+		},
+		timeout: 10 * time.Millisecond,
+		status:  http.StatusInternalServerError,
+		err:     "context deadline exceeded",
+	})
+
+	tests.Run(t, func(t *testing.T, tt tt) {
+		ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+		t.Cleanup(cancel)
+		err := tt.db.WaitForIndex(ctx, "_design/foo")
+		if d := internal.StatusErrorDiff(tt.err, tt.status, err); d != "" {
+			t.Error(d)
+		}
+	})
+}
@@ -181,6 +181,12 @@ func (o Map) NewEdits() bool {
 	return newEdits
 }
 
+// AllOrNothing returns the all_or_nothing option.
+func (o Map) AllOrNothing() bool {
+	allOrNothing, _ := o["all_or_nothing"].(bool)
+	return allOrNothing
+}
+
 // Feed returns the feed option.
 func (o Map) Feed() (string, error) {
 	feed, ok := o["feed"].(string)
@@ -790,28 +796,42 @@ func (o Map) AttEncodingInfo() (bool, error) {
 const defaultWhereCap = 3
 
 // BuildReduceCacheWhere returns WHERE conditions for use when querying the
-// reduce cache.
+// reduce cache. A cached node is selected only when every row it summarizes
+// matches the query, and, when grouping, only when all of its rows share the
+// same key. The startkey_docid and endkey_docid options cannot be evaluated
+// against cached nodes, so they disable the cache entirely.
 func (v ViewOptions) BuildReduceCacheWhere(args *[]any) []string {
 	where := make([]string, 0, defaultWhereCap)
-	if v.endkey != "" {
-		op := endKeyOp(v.descending, v.inclusiveEnd)
-		where = append(where, fmt.Sprintf("view.last_key %s $%d", op, len(*args)+1))
-		*args = append(*args, v.endkey)
+	if v.startkeyDocID != "" || v.endkeyDocID != "" {
+		return append(where, "FALSE")
 	}
-	if v.startkey != "" {
-		op := startKeyOp(v.descending)
-		where = append(where, fmt.Sprintf("view.first_key %s $%d", op, len(*args)+1))
-		*args = append(*args, v.startkey)
+	if v.group || v.groupLevel > 0 || len(v.keys) > 0 {
+		where = append(where, "view.first_key IS view.last_key")
+	}
+	// The cache is stored in ascending order, so swap the bounds for
+	// descending queries.
+	lower, upper := v.startkey, v.endkey
+	lowerOp, upperOp := ">=", endKeyOp(false, v.inclusiveEnd)
+	if v.descending {
+		lower, upper = v.endkey, v.startkey
+		lowerOp, upperOp = endKeyOp(true, v.inclusiveEnd), "<="
+	}
+	if lower != "" {
+		where = append(where, fmt.Sprintf("view.first_key %s $%d", lowerOp, len(*args)+1))
+		*args = append(*args, lower)
+	}
+	if upper != "" {
+		where = append(where, fmt.Sprintf("view.last_key %s $%d", upperOp, len(*args)+1))
+		*args = append(*args, upper)
 	}
 	if v.key != "" {
 		idx := strconv.Itoa(len(*args) + 1)
-		where = append(where, "view.last_key = $"+idx, "view.first_key = $"+idx)
+		where = append(where, "view.first_key = $"+idx, "view.last_key = $"+idx)
 		*args = append(*args, v.key)
 	}
 	if len(v.keys) > 0 {
+		where = append(where, fmt.Sprintf("view.first_key IN (%s)", placeholders(len(*args)+1, len(v.keys))))
 		for _, key := range v.keys {
-			idx := strconv.Itoa(len(*args) + 1)
-			where = append(where, "view.last_key = $"+idx, "view.first_key = $"+idx)
 			*args = append(*args, key)
 		}
 	}
@@ -0,0 +1,159 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+// The search options below may be passed as native Go values, or as JSON
+// strings, as they appear in a search query URL.
+
+// decodeSearchOption decodes a JSON-encoded string value of key into a
+// generic value. Other values are returned unaltered.
+func (o Map) decodeSearchOption(key string) (any, bool, error) {
+	raw, ok := o[key]
+	if !ok {
+		return nil, false, nil
+	}
+	s, isString := raw.(string)
+	if !isString || len(s) == 0 || (s[0] != '[' && s[0] != '{') {
+		return raw, true, nil
+	}
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, false, &internal.Error{Status: http.StatusBadRequest, Message: fmt.Sprintf("invalid value for '%s': %v", key, raw)}
+	}
+	return v, true, nil
+}
+
+// searchStrings returns the value of key, which must be a string or a list of
+// strings, as a list of strings.
+func (o Map) searchStrings(key string) ([]string, error) {
+	raw, ok, err := o.decodeSearchOption(key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	switch t := raw.(type) {
+	case string:
+		return []string{t}, nil
+	case []string:
+		return t, nil
+	case []any:
+		result := make([]string, len(t))
+		for i, v := range t {
+			s, ok := v.(string)
+			if !ok {
+				return nil, &internal.Error{Status: http.StatusBadRequest, Message: fmt.Sprintf("invalid '%s' field: %v", key, v)}
+			}
+			result[i] = s
+		}
+		return result, nil
+	}
+	return nil, &internal.Error{Status: http.StatusBadRequest, Message: fmt.Sprintf("invalid value for '%s': %v", key, raw)}
+}
+
+// SearchSort returns the sort option of a search query. Each field is a field
+// name, optionally prefixed with '-' for descending order, and optionally
+// suffixed with <string> or <number>, or one of the special fields <score> and
+// <doc>.
+func (o Map) SearchSort() ([]string, error) {
+	return o.searchStrings("sort")
+}
+
+// Counts returns the counts option of a search query, a list of the fields
+// for which to return facet counts.
+func (o Map) Counts() ([]string, error) {
+	return o.searchStrings("counts")
+}
+
+// IncludeFields returns the include_fields option of a search query, a list
+// of the stored fields to return. A nil result means all stored fields.
+func (o Map) IncludeFields() ([]string, error) {
+	return o.searchStrings("include_fields")
+}
+
+// Ranges returns the ranges option of a search query, which maps field names
+// to labelled range queries, such as {"price": {"cheap": "[0 TO 100]"}}.
+func (o Map) Ranges() (map[string]map[string]string, error) {
+	raw, ok, err := o.decodeSearchOption("ranges")
+	if err != nil || !ok {
+		return nil, err
+	}
+	invalid := &internal.Error{Status: http.StatusBadRequest, Message: fmt.Sprintf("invalid value for 'ranges': %v", raw)}
+	switch t := raw.(type) {
+	case map[string]map[string]string:
+		return t, nil
+	case map[string]any:
+		result := make(map[string]map[string]string, len(t))
+		for field, v := range t {
+			ranges, ok := v.(map[string]any)
+			if !ok {
+				return nil, invalid
+			}
+			result[field] = make(map[string]string, len(ranges))
+			for label, r := range ranges {
+				query, ok := r.(string)
+				if !ok {
+					return nil, invalid
+				}
+				result[field][label] = query
+			}
+		}
+		return result, nil
+	}
+	return nil, invalid
+}
+
+// Drilldown returns the drilldown option of a search query, as a list of
+// field name and value lists. Documents must match at least one value of
+// every list. A single list, such as ["field", "value"], is also accepted.
+// Non-string values are returned JSON-encoded.
+func (o Map) Drilldown() ([][]string, error) {
+	raw, ok, err := o.decodeSearchOption("drilldown")
+	if err != nil || !ok {
+		return nil, err
+	}
+	invalid := &internal.Error{Status: http.StatusBadRequest, Message: fmt.Sprintf("invalid value for 'drilldown': %v", raw)}
+	list, ok := raw.([]any)
+	if !ok || len(list) == 0 {
+		return nil, invalid
+	}
+	if _, nested := list[0].([]any); !nested {
+		list = []any{list}
+	}
+	result := make([][]string, len(list))
+	for i, v := range list {
+		dd, ok := v.([]any)
+		if !ok || len(dd) < 2 {
+			return nil, invalid
+		}
+		result[i] = make([]string, len(dd))
+		for j, value := range dd {
+			if s, ok := value.(string); ok {
+				result[i][j] = s
+				continue
+			}
+			if j == 0 {
+				return nil, invalid
+			}
+			encoded, _ := json.Marshal(value)
+			result[i][j] = string(encoded)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,169 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package options
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4"
+)
+
+func TestSearchSort(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		input      Map
+		want       []string
+		wantErr    string
+		wantStatus int
+	}
+
+	tests := testy.NewTable()
+
+	tests.Add("unset", test{
+		input: Map{},
+	})
+	tests.Add("single field", test{
+		input: Map{"sort": "-year<number>"},
+		want:  []string{"-year<number>"},
+	})
+	tests.Add("list", test{
+		input: Map{"sort": []any{"title", "<score>"}},
+		want:  []string{"title", "<score>"},
+	})
+	tests.Add("JSON list", test{
+		input: Map{"sort": `["title","-year"]`},
+		want:  []string{"title", "-year"},
+	})
+	tests.Add("invalid JSON", test{
+		input:      Map{"sort": `["title"`},
+		wantErr:    `invalid value for 'sort': ["title"`,
+		wantStatus: http.StatusBadRequest,
+	})
+	tests.Add("invalid field", test{
+		input:      Map{"sort": []any{"title", 3}},
+		wantErr:    "invalid 'sort' field: 3",
+		wantStatus: http.StatusBadRequest,
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		got, err := tt.input.SearchSort()
+		if !testy.ErrorMatches(tt.wantErr, err) {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if status := kivik.HTTPStatus(err); err != nil && status != tt.wantStatus {
+			t.Errorf("unexpected status: %d", status)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected result:\n%s", d)
+		}
+	})
+}
+
+func TestRanges(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		input      Map
+		want       map[string]map[string]string
+		wantErr    string
+		wantStatus int
+	}
+
+	tests := testy.NewTable()
+
+	tests.Add("unset", test{
+		input: Map{},
+	})
+	tests.Add("map", test{
+		input: Map{"ranges": map[string]any{
+			"price": map[string]any{"cheap": "[0 TO 100]"},
+		}},
+		want: map[string]map[string]string{
+			"price": {"cheap": "[0 TO 100]"},
+		},
+	})
+	tests.Add("JSON", test{
+		input: Map{"ranges": `{"price":{"cheap":"[0 TO 100]","expensive":"{100 TO Infinity}"}}`},
+		want: map[string]map[string]string{
+			"price": {"cheap": "[0 TO 100]", "expensive": "{100 TO Infinity}"},
+		},
+	})
+	tests.Add("invalid range", test{
+		input:      Map{"ranges": map[string]any{"price": "[0 TO 100]"}},
+		wantErr:    "invalid value for 'ranges': map[price:[0 TO 100]]",
+		wantStatus: http.StatusBadRequest,
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		got, err := tt.input.Ranges()
+		if !testy.ErrorMatches(tt.wantErr, err) {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if status := kivik.HTTPStatus(err); err != nil && status != tt.wantStatus {
+			t.Errorf("unexpected status: %d", status)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected result:\n%s", d)
+		}
+	})
+}
+
+func TestDrilldown(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		input      Map
+		want       [][]string
+		wantErr    string
+		wantStatus int
+	}
+
+	tests := testy.NewTable()
+
+	tests.Add("unset", test{
+		input: Map{},
+	})
+	tests.Add("single list", test{
+		input: Map{"drilldown": []any{"genre", "fiction", "poetry"}},
+		want:  [][]string{{"genre", "fiction", "poetry"}},
+	})
+	tests.Add("nested lists with numbers", test{
+		input: Map{"drilldown": `[["genre","fiction"],["year",1851]]`},
+		want:  [][]string{{"genre", "fiction"}, {"year", "1851"}},
+	})
+	tests.Add("missing value", test{
+		input:      Map{"drilldown": []any{"genre"}},
+		wantErr:    "invalid value for 'drilldown': [genre]",
+		wantStatus: http.StatusBadRequest,
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		got, err := tt.input.Drilldown()
+		if !testy.ErrorMatches(tt.wantErr, err) {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if status := kivik.HTTPStatus(err); err != nil && status != tt.wantStatus {
+			t.Errorf("unexpected status: %d", status)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected result:\n%s", d)
+		}
+	})
+}
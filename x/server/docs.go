@@ -17,9 +17,13 @@ package server
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"gitlab.com/flimzy/httpe"
+
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/users"
 )
 
 func (s *Server) postDoc() httpe.HandlerWithError {
@@ -29,6 +33,9 @@ func (s *Server) postDoc() httpe.HandlerWithError {
 		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
 			return err
 		}
+		if err := prepareUserDoc(db, "", doc); err != nil {
+			return err
+		}
 		id, rev, err := s.client.DB(db).CreateDoc(r.Context(), doc, options(r))
 		if err != nil {
 			return err
@@ -53,3 +60,48 @@ func (s *Server) doc() httpe.HandlerWithError {
 		return serveJSON(w, http.StatusOK, doc)
 	})
 }
+
+func (s *Server) putDoc() httpe.HandlerWithError {
+	return httpe.HandlerWithErrorFunc(func(w http.ResponseWriter, r *http.Request) error {
+		db := chi.URLParam(r, "db")
+		id := chi.URLParam(r, "docid")
+		var doc any
+		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+			return err
+		}
+		if err := prepareUserDoc(db, id, doc); err != nil {
+			return err
+		}
+		rev, err := s.client.DB(db).Put(r.Context(), id, doc, options(r))
+		if err != nil {
+			return err
+		}
+		return serveJSON(w, http.StatusCreated, map[string]any{
+			"id":  id,
+			"rev": rev,
+			"ok":  true,
+		})
+	})
+}
+
+// prepareUserDoc validates documents written to the _users database, and
+// replaces plaintext passwords with a PBKDF2 hash, so that user documents are
+// stored the same way regardless of the backend.
+func prepareUserDoc(db, docID string, doc any) error {
+	if db != users.DBName || strings.HasPrefix(docID, "_design/") || strings.HasPrefix(docID, "_local/") {
+		return nil
+	}
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return &internal.Error{Status: http.StatusBadRequest, Message: "Document must be a JSON object"}
+	}
+	if docID != "" {
+		if _, ok := m["_id"]; !ok {
+			m["_id"] = docID
+		}
+	}
+	if err := users.ValidateDoc(m); err != nil {
+		return err
+	}
+	return users.HashDocPassword(m, 0)
+}
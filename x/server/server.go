@@ -187,7 +187,7 @@ func (s *Server) routes(mux *chi.Mux) {
 		// Documents
 		member.Post("/", e(s.postDoc()))
 		member.Get("/{docid}", e(s.doc()))
-		member.Put("/{docid}", e(s.notImplemented()))
+		member.Put("/{docid}", e(s.putDoc()))
 		member.Delete("/{docid}", e(s.notImplemented()))
 		member.Method("COPY", "/{db}/{docid}", httpe.ToHandler(s.notImplemented()))
 		member.Delete("/{docid}", e(s.notImplemented()))
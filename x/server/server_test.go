@@ -31,6 +31,7 @@ import (
 	"gitlab.com/flimzy/testy"
 
 	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/users"
 	_ "github.com/go-kivik/kivik/v4/x/fsdb"     // Filesystem driver
 	_ "github.com/go-kivik/kivik/v4/x/memorydb" // Memory driver
 	"github.com/go-kivik/kivik/v4/x/server/auth"
@@ -720,6 +721,74 @@ func TestServer(t *testing.T) {
 				OK  bool   `json:"ok" validate:"required,eq=true"`
 			}{},
 		},
+		{
+			name:   "put document",
+			driver: "memory",
+			init: func(t *testing.T, client *kivik.Client) { //nolint:thelper // not a helper
+				if err := client.CreateDB(context.Background(), "db1", nil); err != nil {
+					t.Fatal(err)
+				}
+			},
+			method:     http.MethodPut,
+			path:       "/db1/foo",
+			body:       strings.NewReader(`{"foo":"bar"}`),
+			authUser:   userAdmin,
+			wantStatus: http.StatusCreated,
+			target: &struct {
+				ID  string `json:"id" validate:"required,eq=foo"`
+				Rev string `json:"rev" validate:"required,startswith=1-"`
+				OK  bool   `json:"ok" validate:"required,eq=true"`
+			}{},
+		},
+		{
+			name:   "put invalid user document",
+			driver: "memory",
+			init: func(t *testing.T, client *kivik.Client) { //nolint:thelper // not a helper
+				if err := client.CreateDB(context.Background(), "_users", nil); err != nil {
+					t.Fatal(err)
+				}
+			},
+			method:     http.MethodPut,
+			path:       "/_users/org.couchdb.user:bob",
+			body:       strings.NewReader(`{"name":"bob","type":"user","roles":["_admin"],"password":"abc"}`),
+			authUser:   userAdmin,
+			wantStatus: http.StatusForbidden,
+			wantJSON: map[string]any{
+				"error":  "forbidden",
+				"reason": "No system roles (starting with underscore) in users db.",
+			},
+		},
+		{
+			name:   "put user document hashes password",
+			driver: "memory",
+			init: func(t *testing.T, client *kivik.Client) { //nolint:thelper // not a helper
+				if err := client.CreateDB(context.Background(), "_users", nil); err != nil {
+					t.Fatal(err)
+				}
+			},
+			method:     http.MethodPut,
+			path:       "/_users/org.couchdb.user:bob",
+			body:       strings.NewReader(`{"name":"bob","type":"user","roles":[],"password":"abc"}`),
+			authUser:   userAdmin,
+			wantStatus: http.StatusCreated,
+			target: &struct {
+				ID  string `json:"id" validate:"required,eq=org.couchdb.user:bob"`
+				Rev string `json:"rev" validate:"required,startswith=1-"`
+				OK  bool   `json:"ok" validate:"required,eq=true"`
+			}{},
+			check: func(t *testing.T, client *kivik.Client) { //nolint:thelper // not a helper
+				user, err := users.New(client).GetUser(context.Background(), "bob")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if user.Password != "" {
+					t.Error("plaintext password stored")
+				}
+				if !user.Verify("abc") {
+					t.Error("stored password hash does not verify")
+				}
+			},
+		},
 		{
 			name:       "get document",
 			method:     http.MethodGet,
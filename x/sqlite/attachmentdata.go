@@ -0,0 +1,371 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"modernc.org/sqlite"
+
+	"github.com/go-kivik/kivik/v4"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+const (
+	// attachmentChunkSize is the size of the chunks in which attachment
+	// content is stored, and so the most content held in memory while an
+	// attachment is read or written.
+	attachmentChunkSize = 64 << 10
+	// attachmentCompressionLevel matches CouchDB's default
+	// attachments/compression_level.
+	attachmentCompressionLevel = 8
+	encodingGzip               = "gzip"
+)
+
+type optionCompressibleTypes []string
+
+var _ kivik.Option = optionCompressibleTypes(nil)
+
+func (o optionCompressibleTypes) Apply(target any) {
+	if client, ok := target.(*client); ok {
+		client.compressibleTypes = o
+	}
+}
+
+// OptionCompressibleTypes enables gzip compression of stored attachments with
+// the given content types, like CouchDB's attachments/compressible_types
+// setting. A type ending in "/*", such as "text/*", matches any subtype.
+// Compression is transparent: content is always returned uncompressed, and the
+// encoding is reported only when the att_encoding_info option is set.
+//
+// CouchDB's default types are "text/*", "application/javascript",
+// "application/json" and "application/xml". By default, nothing is compressed.
+func OptionCompressibleTypes(types ...string) kivik.Option {
+	return optionCompressibleTypes(types)
+}
+
+// compressible returns true if content of the given type is stored gzipped.
+func (d *db) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	for _, t := range d.compressibleTypes {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// storeAttachmentData stores the content read from r, unless identical
+// content is already stored, and returns its digest and length. Content is
+// written in chunks, so that it need not be held in memory.
+func (d *db) storeAttachmentData(ctx context.Context, tx *sql.Tx, r io.Reader, contentType string) (digest md5sum, length int64, err error) {
+	var encoding *string
+	if d.compressible(contentType) {
+		encoding = &[]string{encodingGzip}[0]
+	}
+	var pk int64
+	if err := tx.QueryRowContext(ctx, d.query(`
+		INSERT INTO {{ .AttachmentData }} (encoding)
+		VALUES ($1)
+		RETURNING pk
+	`), encoding).Scan(&pk); err != nil {
+		return digest, 0, err
+	}
+
+	chunks, err := tx.PrepareContext(ctx, d.query(`
+		INSERT INTO {{ .AttachmentChunks }} (data_pk, seq, chunk)
+		VALUES ($1, $2, $3)
+	`))
+	if err != nil {
+		return digest, 0, err
+	}
+	defer chunks.Close()
+	cw := &chunkWriter{ctx: ctx, stmt: chunks, pk: pk}
+	var (
+		w  io.Writer = cw
+		gz *gzip.Writer
+	)
+	if encoding != nil {
+		gz, _ = gzip.NewWriterLevel(cw, attachmentCompressionLevel)
+		w = gz
+	}
+	h := md5.New()
+	length, err = io.Copy(io.MultiWriter(w, h), r)
+	if err != nil {
+		return digest, 0, err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return digest, 0, err
+		}
+	}
+	if err := cw.flush(); err != nil {
+		return digest, 0, err
+	}
+	copy(digest[:], h.Sum(nil))
+
+	// Content with the same digest may already be stored, in which case the
+	// new copy is discarded if it is identical.
+	result, err := tx.ExecContext(ctx, d.query(`
+		UPDATE OR IGNORE {{ .AttachmentData }}
+		SET digest = $1, length = $2, encoded_length = $3
+		WHERE pk = $4
+	`), digest, length, cw.written, pk)
+	if err != nil {
+		return digest, 0, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return digest, length, err
+	}
+	content, err := d.readAttachmentData(ctx, tx, pk, cw.written, encoding)
+	if err != nil {
+		return digest, 0, err
+	}
+	defer content.Close()
+	if err := d.matchAttachmentData(ctx, tx, digest, content); err != nil {
+		return digest, 0, err
+	}
+	_, err = tx.ExecContext(ctx, d.query(`
+		DELETE FROM {{ .AttachmentData }} WHERE pk = $1
+	`), pk)
+	return digest, length, err
+}
+
+// matchAttachmentData returns an error if the content stored with digest
+// differs from the content read from r. As MD5 is not collision resistant,
+// content is only shared once it is known to be identical.
+func (d *db) matchAttachmentData(ctx context.Context, tx *sql.Tx, digest md5sum, r io.Reader) error {
+	var (
+		pk, encodedLength int64
+		encoding          *string
+	)
+	if err := tx.QueryRowContext(ctx, d.query(`
+		SELECT pk, encoded_length, encoding
+		FROM {{ .AttachmentData }}
+		WHERE digest = $1
+	`), digest).Scan(&pk, &encodedLength, &encoding); err != nil {
+		return err
+	}
+	stored, err := d.readAttachmentData(ctx, tx, pk, encodedLength, encoding)
+	if err != nil {
+		return err
+	}
+	defer stored.Close()
+	equal, err := equalContent(stored, r)
+	if err != nil || equal {
+		return err
+	}
+	return &internal.Error{Status: http.StatusConflict, Message: "attachment content differs from stored content with digest " + digest.Digest()}
+}
+
+// equalContent returns true if a and b return the same content.
+func equalContent(a, b io.Reader) (bool, error) {
+	bufA := make([]byte, attachmentChunkSize)
+	bufB := make([]byte, attachmentChunkSize)
+	for {
+		n, errA := io.ReadFull(a, bufA)
+		if errA != nil && errA != io.EOF && errA != io.ErrUnexpectedEOF {
+			return false, errA
+		}
+		m, errB := io.ReadFull(b, bufB)
+		if errB != nil && errB != io.EOF && errB != io.ErrUnexpectedEOF {
+			return false, errB
+		}
+		if m != n || !bytes.Equal(bufA[:n], bufB[:m]) {
+			return false, nil
+		}
+		// A short read of equal length means both have ended.
+		if errA != nil {
+			return true, nil
+		}
+	}
+}
+
+// chunkWriter writes attachment content to the chunks table.
+type chunkWriter struct {
+	ctx     context.Context
+	stmt    *sql.Stmt
+	pk      int64
+	seq     int
+	buf     []byte
+	written int64
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		free := attachmentChunkSize - len(w.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		w.buf = append(w.buf, p[:free]...)
+		p = p[free:]
+		if len(w.buf) == attachmentChunkSize {
+			if err := w.writeChunk(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// flush writes any buffered content. At least one chunk is always written, so
+// that empty content is distinguishable from missing content.
+func (w *chunkWriter) flush() error {
+	if len(w.buf) == 0 && w.seq > 0 {
+		return nil
+	}
+	return w.writeChunk()
+}
+
+func (w *chunkWriter) writeChunk() error {
+	if w.buf == nil {
+		w.buf = []byte{}
+	}
+	if _, err := w.stmt.ExecContext(w.ctx, w.pk, w.seq, w.buf); err != nil {
+		return err
+	}
+	w.seq++
+	w.written += int64(len(w.buf))
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// attachmentDataReader reads stored attachment content, one chunk at a time.
+type attachmentDataReader struct {
+	ctx context.Context
+	d   *db
+	q   queryer
+	pk  int64
+	// length is the stored length, used to detect content deleted while it
+	// is being read.
+	length int64
+	read   int64
+	seq    int
+	buf    []byte
+}
+
+var _ io.Reader = (*attachmentDataReader)(nil)
+
+func (r *attachmentDataReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.read >= r.length && r.seq > 0 {
+			return 0, io.EOF
+		}
+		err := r.q.QueryRowContext(r.ctx, r.d.query(`
+			SELECT chunk
+			FROM {{ .AttachmentChunks }}
+			WHERE data_pk = $1 AND seq = $2
+		`), r.pk, r.seq).Scan(&r.buf)
+		if errors.Is(err, sql.ErrNoRows) {
+			if r.read < r.length {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		r.seq++
+		r.read += int64(len(r.buf))
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// openAttachmentData returns a reader for the stored content with the given
+// pk, decoding it if necessary.
+func (d *db) openAttachmentData(ctx context.Context, pk, encodedLength int64, encoding *string) (io.ReadCloser, error) {
+	return d.readAttachmentData(ctx, d.db, pk, encodedLength, encoding)
+}
+
+// readAttachmentData works like openAttachmentData, but reads the content
+// with q, which may be a transaction.
+func (d *db) readAttachmentData(ctx context.Context, q queryer, pk, encodedLength int64, encoding *string) (io.ReadCloser, error) {
+	r := &attachmentDataReader{ctx: ctx, d: d, q: q, pk: pk, length: encodedLength}
+	if encoding == nil {
+		return io.NopCloser(r), nil
+	}
+	return gzip.NewReader(r)
+}
+
+func init() {
+	sqlite.MustRegisterFunction("kivik_attachment_content", &sqlite.FunctionImpl{
+		NArgs:         2,
+		Deterministic: true,
+		MakeAggregate: func(sqlite.FunctionContext) (sqlite.AggregateFunction, error) {
+			return &attachmentContentFunc{}, nil
+		},
+	})
+}
+
+// attachmentContentFunc is the kivik_attachment_content(chunk, encoding) SQL
+// aggregate function, which assembles and decodes the chunks of stored
+// attachment content, for queries which return the content of many
+// attachments at once. Chunks must be passed in order.
+type attachmentContentFunc struct {
+	stepped  bool
+	encoding string
+	buf      bytes.Buffer
+}
+
+var _ sqlite.AggregateFunction = (*attachmentContentFunc)(nil)
+
+func (f *attachmentContentFunc) Step(_ *sqlite.FunctionContext, args []sqldriver.Value) error {
+	f.stepped = true
+	if chunk, ok := args[0].([]byte); ok {
+		f.buf.Write(chunk)
+	}
+	f.encoding, _ = args[1].(string)
+	return nil
+}
+
+func (*attachmentContentFunc) WindowInverse(*sqlite.FunctionContext, []sqldriver.Value) error {
+	return errors.New("kivik_attachment_content cannot be used as a window function")
+}
+
+func (f *attachmentContentFunc) WindowValue(*sqlite.FunctionContext) (sqldriver.Value, error) {
+	if !f.stepped {
+		return nil, nil
+	}
+	if f.encoding != encodingGzip {
+		return f.buf.Bytes(), nil
+	}
+	gz, err := gzip.NewReader(&f.buf)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(gz)
+}
+
+func (*attachmentContentFunc) Final(*sqlite.FunctionContext) {}
@@ -0,0 +1,151 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"modernc.org/sqlite"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+var _ driver.Backuper = (*client)(nil)
+
+// Backup writes a snapshot of the SQLite database file, which contains all
+// databases, to w. The snapshot is taken with VACUUM INTO, which runs in a
+// single read transaction, so it is consistent, and does not block writers.
+func (c *client) Backup(ctx context.Context, w io.Writer, _ driver.Options) error {
+	dir, err := os.MkdirTemp("", "kivik-sqlite-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "backup.db")
+
+	if _, err := c.db.ExecContext(ctx, "VACUUM INTO $1", path); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// restorer is implemented by the modernc.org/sqlite connection.
+type restorer interface {
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// Restore replaces the content of the SQLite database with the snapshot read
+// from r, using the SQLite online backup API. Replications are stopped while
+// the snapshot is restored, and are then resumed from the restored
+// _replicator database.
+func (c *client) Restore(ctx context.Context, r io.Reader, _ driver.Options) error {
+	dir, err := os.MkdirTemp("", "kivik-sqlite-restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "restore.db")
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := checkSnapshot(ctx, path); err != nil {
+		return err
+	}
+
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	c.replicatorMu.Lock()
+	defer c.replicatorMu.Unlock()
+	if c.closed {
+		return &internal.Error{Status: http.StatusServiceUnavailable, Message: "client is closed"}
+	}
+	c.replicator.stop()
+	defer func() {
+		c.replicator = newScheduler(c)
+		if err := c.resumeReplications(context.Background()); err != nil {
+			c.logger.Printf("Failed to resume replications: %s", err)
+		}
+	}()
+
+	err = conn.Raw(func(driverConn any) error {
+		rc, ok := driverConn.(restorer)
+		if !ok {
+			// Such as when query logging wraps the connection.
+			return &internal.Error{Status: http.StatusNotImplemented, Message: "restore is not supported by this connection"}
+		}
+		return restore(rc, path)
+	})
+	if errIsNotADB(err) {
+		return &internal.Error{Status: http.StatusBadRequest, Err: fmt.Errorf("invalid backup: %w", err)}
+	}
+	return err
+}
+
+// checkSnapshot returns an error if the snapshot at path is not a SQLite
+// database, or was written with an unsupported schema version.
+func checkSnapshot(ctx context.Context, path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	err = checkSchemaVersion(ctx, db)
+	if errIsNotADB(err) || errors.Is(err, errSchemaVersion) {
+		return &internal.Error{Status: http.StatusBadRequest, Err: fmt.Errorf("invalid backup: %w", err)}
+	}
+	return err
+}
+
+func restore(conn restorer, path string) error {
+	b, err := conn.NewRestore(path)
+	if err != nil {
+		return err
+	}
+	for {
+		more, err := b.Step(-1)
+		if err != nil {
+			_ = b.Finish()
+			return err
+		}
+		if !more {
+			return b.Finish()
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+func TestClientBackupRestore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := testClient(t).(*client)
+	d := c.createTestDB(t, "test", "a")
+	_ = d.tPut("att", map[string]any{
+		"_attachments": newAttachments().add("foo.txt", "This is a test"),
+	})
+
+	var backup bytes.Buffer
+	if err := c.Backup(ctx, &backup, mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = d.tPut("b", map[string]string{"name": "b"})
+	if err := c.CreateDB(ctx, "other", mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Restore(ctx, &backup, mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+	dbs, err := c.AllDBs(ctx, mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]string{"test"}, dbs); d != "" {
+		t.Errorf("Unexpected databases after restore:\n%s", d)
+	}
+	if _, err := d.GetRev(ctx, "a", mock.NilOption); err != nil {
+		t.Errorf("Expected a to be restored: %s", err)
+	}
+	if _, err := d.GetRev(ctx, "b", mock.NilOption); kivik.HTTPStatus(err) != http.StatusNotFound {
+		t.Errorf("Expected b to be removed, got: %v", err)
+	}
+	att, err := d.GetAttachment(ctx, "att", "foo.txt", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer att.Content.Close()
+	if content, _ := io.ReadAll(att.Content); string(content) != "This is a test" {
+		t.Errorf("Unexpected attachment content: %q", content)
+	}
+}
+
+func TestClientRestore_invalid(t *testing.T) {
+	t.Parallel()
+	c := testClient(t).(*client)
+	_ = c.createTestDB(t, "test", "a")
+
+	err := c.Restore(context.Background(), strings.NewReader(strings.Repeat("not a database", 100)), mock.NilOption)
+	if status := kivik.HTTPStatus(err); status != http.StatusBadRequest {
+		t.Errorf("Unexpected status: %d, error: %v", status, err)
+	}
+	dbs, err := c.AllDBs(context.Background(), mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]string{"test"}, dbs); d != "" {
+		t.Errorf("Unexpected databases after failed restore:\n%s", d)
+	}
+}
+
+func TestClientRestore_schemaVersion(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := testClient(t).(*client)
+	_ = c.createTestDB(t, "test", "a")
+
+	var backup bytes.Buffer
+	if _, err := c.db.Exec("PRAGMA user_version = 0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Backup(ctx, &backup, mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Restore(ctx, &backup, mock.NilOption)
+	if status := kivik.HTTPStatus(err); status != http.StatusBadRequest {
+		t.Errorf("Unexpected status: %d, error: %v", status, err)
+	}
+}
+
+func TestClientRestore_queryLogger(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dsn := fmt.Sprintf("file:restorelog%d?mode=memory&cache=shared", dbSeq.Add(1))
+	dc, err := drv{}.NewClient(dsn, OptionQueryLogger(log.New(io.Discard, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := dc.(*client)
+	t.Cleanup(func() { _ = c.Close() })
+	_ = c.createTestDB(t, "test", "a")
+
+	var backup bytes.Buffer
+	if err := c.Backup(ctx, &backup, mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+	err = c.Restore(ctx, &backup, mock.NilOption)
+	if status := kivik.HTTPStatus(err); status != http.StatusNotImplemented {
+		t.Errorf("Unexpected status: %d, error: %v", status, err)
+	}
+}
+
+func TestClientRestore_concurrent(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := testClient(t).(*client)
+	_ = c.createTestDB(t, replicatorDB)
+
+	var backup bytes.Buffer
+	if err := c.Backup(ctx, &backup, mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = c.Restore(ctx, bytes.NewReader(backup.Bytes()), mock.NilOption)
+	}()
+	_, _ = c.GetReplications(ctx, mock.NilOption)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	// Whether the restore finished before or after Close, no replications
+	// run once the client is closed.
+	s := c.currentScheduler()
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	if !s.stopped {
+		t.Error("Expected the scheduler to remain stopped")
+	}
+}
+
+func TestDBExportImport(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	client, err := kivik.New("sqlite", testDSN("export"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	for _, name := range []string{"source", "target"} {
+		if err := client.CreateDB(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := client.DB("source")
+	for _, doc := range []map[string]any{
+		{"_id": "a", "_rev": "1-abc", "name": "a"},
+		{"_id": "a", "_rev": "1-xyz", "name": "conflict"},
+		{"_id": "b", "_rev": "2-def", "_revisions": map[string]any{"start": 2, "ids": []string{"def", "abc"}}, "_deleted": true},
+		{
+			"_id": "c", "_rev": "1-ghi",
+			"_attachments": newAttachments().add("foo.txt", "This is a test"),
+		},
+	} {
+		if _, err := source.Put(ctx, doc["_id"].(string), doc, kivik.Param("new_edits", false)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var export bytes.Buffer
+	if err := source.Export(ctx, &export); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(export.String(), "\n"); n != 4 {
+		t.Errorf("Expected 4 revisions, got %d:\n%s", n, export.String())
+	}
+
+	target := client.DB("target")
+	if err := target.Import(ctx, bytes.NewReader(export.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	var reexport bytes.Buffer
+	if err := target.Export(ctx, &reexport); err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(export.String(), reexport.String()); d != "" {
+		t.Errorf("Unexpected export of imported database:\n%s", d)
+	}
+}
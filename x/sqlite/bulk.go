@@ -0,0 +1,120 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/x/options"
+)
+
+var _ driver.BulkDocer = (*db)(nil)
+
+// errBulkRolledBack is reported for documents which were valid, but not
+// stored, because another document in an all_or_nothing batch failed.
+var errBulkRolledBack = &internal.Error{Status: http.StatusExpectationFailed, Message: "all_or_nothing batch rolled back"}
+
+// BulkDocs stores docs in a single transaction. Each document is written
+// within its own savepoint, so that a failure affects only that document. If
+// the all_or_nothing option is set, any failure causes the entire batch to be
+// rolled back. As in CouchDB, only failures are reported when new_edits is
+// false.
+func (d *db) BulkDocs(ctx context.Context, docs []any, opts driver.Options) ([]driver.BulkResult, error) {
+	o := options.New(opts)
+	newEdits := o.NewEdits()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, d.query(`SELECT EXISTS(SELECT 1 FROM {{ .Docs }})`)).Scan(&exists)
+	if err != nil {
+		return nil, d.errDatabaseNotFound(err)
+	}
+
+	results := make([]driver.BulkResult, 0, len(docs))
+	var failed bool
+	for _, doc := range docs {
+		docID, rev, err := d.bulkPut(ctx, tx, doc, newEdits)
+		if err != nil {
+			failed = true
+			results = append(results, driver.BulkResult{ID: docID, Error: err})
+			continue
+		}
+		if newEdits {
+			results = append(results, driver.BulkResult{ID: docID, Rev: rev})
+		}
+	}
+
+	if failed && o.AllOrNothing() {
+		for i := range results {
+			if results[i].Error == nil {
+				results[i].Rev = ""
+				results[i].Error = errBulkRolledBack
+			}
+		}
+		return results, nil
+	}
+	return results, tx.Commit()
+}
+
+// bulkPut stores a single document of a bulk request within a savepoint, and
+// returns its ID and new rev. If the document cannot be stored, the savepoint
+// is rolled back.
+func (d *db) bulkPut(ctx context.Context, tx *sql.Tx, doc any, newEdits bool) (string, string, error) {
+	docID, err := bulkDocID(doc)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT bulk_doc`); err != nil {
+		return docID, "", err
+	}
+	rev, changed, err := d.put(ctx, tx, docID, doc, "", newEdits)
+	if err != nil || !changed {
+		if _, rbErr := tx.ExecContext(ctx, `ROLLBACK TO bulk_doc`); rbErr != nil {
+			return docID, "", rbErr
+		}
+	}
+	if _, relErr := tx.ExecContext(ctx, `RELEASE bulk_doc`); relErr != nil {
+		return docID, "", relErr
+	}
+	return docID, rev, err
+}
+
+// bulkDocID returns the _id of doc, or a new UUID if it has none.
+func bulkDocID(doc any) (string, error) {
+	tmp, err := json.Marshal(doc)
+	if err != nil {
+		return "", &internal.Error{Status: http.StatusBadRequest, Err: err}
+	}
+	var data struct {
+		ID string `json:"_id"`
+	}
+	if err := json.Unmarshal(tmp, &data); err != nil {
+		return "", &internal.Error{Status: http.StatusBadRequest, Err: err}
+	}
+	if data.ID == "" {
+		return uuid.NewString(), nil
+	}
+	return data.ID, nil
+}
@@ -0,0 +1,231 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package sqlite
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+// bulkResult is a simplified driver.BulkResult, for comparison in tests.
+type bulkResult struct {
+	// ID is matched as a regular expression.
+	ID string
+	// Rev is matched as a regular expression.
+	Rev    string
+	Status int
+	Err    string
+}
+
+func TestDBBulkDocs(t *testing.T) {
+	t.Parallel()
+	type test struct {
+		db      *testDB
+		docs    []any
+		options driver.Options
+		want    []bulkResult
+		check   func(*testing.T, *testDB)
+	}
+
+	tests := testy.NewTable()
+	tests.Add("new docs", test{
+		docs: []any{
+			map[string]any{"_id": "foo", "a": 1},
+			map[string]any{"_id": "bar", "b": 2},
+		},
+		want: []bulkResult{
+			{ID: "^foo$", Rev: "^1-[0-9a-f]{32}$"},
+			{ID: "^bar$", Rev: "^1-[0-9a-f]{32}$"},
+		},
+	})
+	tests.Add("doc without id", test{
+		docs: []any{
+			map[string]any{"a": 1},
+		},
+		want: []bulkResult{
+			{ID: "^[0-9a-f-]{36}$", Rev: "^1-[0-9a-f]{32}$"},
+		},
+	})
+	tests.Add("conflict affects only the conflicting doc", func(t *testing.T) any {
+		d := newDB(t)
+		_ = d.tPut("foo", map[string]any{"a": 1})
+		return test{
+			db: d,
+			docs: []any{
+				map[string]any{"_id": "foo", "a": 2},
+				map[string]any{"_id": "bar", "b": 2},
+			},
+			want: []bulkResult{
+				{ID: "^foo$", Status: http.StatusConflict, Err: "document update conflict"},
+				{ID: "^bar$", Rev: "^1-[0-9a-f]{32}$"},
+			},
+			check: func(t *testing.T, d *testDB) {
+				t.Helper()
+				if _, err := d.GetRev(context.Background(), "bar", mock.NilOption); err != nil {
+					t.Errorf("bar was not stored: %s", err)
+				}
+			},
+		}
+	})
+	tests.Add("update with rev", func(t *testing.T) any {
+		d := newDB(t)
+		rev := d.tPut("foo", map[string]any{"a": 1})
+		return test{
+			db: d,
+			docs: []any{
+				map[string]any{"_id": "foo", "_rev": rev, "a": 2},
+			},
+			want: []bulkResult{
+				{ID: "^foo$", Rev: "^2-[0-9a-f]{32}$"},
+			},
+		}
+	})
+	tests.Add("invalid doc id", test{
+		docs: []any{
+			map[string]any{"_id": "_invalid"},
+		},
+		want: []bulkResult{
+			{ID: "^_invalid$", Status: http.StatusBadRequest, Err: "only reserved document ids may start with underscore"},
+		},
+	})
+	tests.Add("new_edits=false", test{
+		docs: []any{
+			map[string]any{"_id": "foo", "_rev": "3-abc", "a": 1},
+			map[string]any{"_id": "bar", "_rev": "1-def", "b": 1},
+		},
+		options: kivik.Param("new_edits", false),
+		want:    []bulkResult{},
+		check: func(t *testing.T, d *testDB) {
+			t.Helper()
+			rev, err := d.GetRev(context.Background(), "foo", mock.NilOption)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rev != "3-abc" {
+				t.Errorf("Unexpected rev: %s", rev)
+			}
+		},
+	})
+	tests.Add("new_edits=false reports failures", test{
+		docs: []any{
+			map[string]any{"_id": "foo", "a": 1},
+		},
+		options: kivik.Param("new_edits", false),
+		want: []bulkResult{
+			{ID: "^foo$", Status: http.StatusBadRequest, Err: "When `new_edits: false`, the document needs `_rev` or `_revisions` specified"},
+		},
+	})
+	tests.Add("all_or_nothing rolls back on failure", func(t *testing.T) any {
+		d := newDB(t)
+		_ = d.tPut("foo", map[string]any{"a": 1})
+		return test{
+			db: d,
+			docs: []any{
+				map[string]any{"_id": "bar", "b": 2},
+				map[string]any{"_id": "foo", "a": 2},
+			},
+			options: kivik.Param("all_or_nothing", true),
+			want: []bulkResult{
+				{ID: "^bar$", Status: http.StatusExpectationFailed, Err: "all_or_nothing batch rolled back"},
+				{ID: "^foo$", Status: http.StatusConflict, Err: "document update conflict"},
+			},
+			check: func(t *testing.T, d *testDB) {
+				t.Helper()
+				_, err := d.GetRev(context.Background(), "bar", mock.NilOption)
+				if status := kivik.HTTPStatus(err); status != http.StatusNotFound {
+					t.Errorf("Expected bar not to be stored, got status %d", status)
+				}
+			},
+		}
+	})
+	tests.Add("all_or_nothing success", test{
+		docs: []any{
+			map[string]any{"_id": "foo", "a": 1},
+			map[string]any{"_id": "bar", "b": 2},
+		},
+		options: kivik.Param("all_or_nothing", true),
+		want: []bulkResult{
+			{ID: "^foo$", Rev: "^1-[0-9a-f]{32}$"},
+			{ID: "^bar$", Rev: "^1-[0-9a-f]{32}$"},
+		},
+		check: func(t *testing.T, d *testDB) {
+			t.Helper()
+			if _, err := d.GetRev(context.Background(), "bar", mock.NilOption); err != nil {
+				t.Errorf("bar was not stored: %s", err)
+			}
+		},
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		t.Parallel()
+		d := tt.db
+		if d == nil {
+			d = newDB(t)
+		}
+		opts := tt.options
+		if opts == nil {
+			opts = mock.NilOption
+		}
+		results, err := d.BulkDocs(context.Background(), tt.docs, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != len(tt.want) {
+			t.Fatalf("Expected %d results, got %d: %v", len(tt.want), len(results), results)
+		}
+		for i, want := range tt.want {
+			got := results[i]
+			if !regexp.MustCompile(want.ID).MatchString(got.ID) {
+				t.Errorf("result %d: unexpected ID: %s", i, got.ID)
+			}
+			if want.Rev == "" {
+				if got.Rev != "" {
+					t.Errorf("result %d: unexpected rev: %s", i, got.Rev)
+				}
+			} else if !regexp.MustCompile(want.Rev).MatchString(got.Rev) {
+				t.Errorf("result %d: unexpected rev: %s", i, got.Rev)
+			}
+			if !testy.ErrorMatches(want.Err, got.Error) {
+				t.Errorf("result %d: unexpected error: %v", i, got.Error)
+			}
+			if status := kivik.HTTPStatus(got.Error); got.Error != nil && status != want.Status {
+				t.Errorf("result %d: unexpected status: %d", i, status)
+			}
+		}
+		if tt.check != nil {
+			tt.check(t, d)
+		}
+	})
+}
+
+func TestDBBulkDocsDatabaseNotFound(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	if _, err := d.underlying().Exec(`DROP TABLE "kivik$test"`); err != nil {
+		t.Fatal(err)
+	}
+	_, err := d.BulkDocs(context.Background(), []any{map[string]any{"_id": "foo"}}, mock.NilOption)
+	if status := kivik.HTTPStatus(err); status != http.StatusNotFound {
+		t.Errorf("Unexpected status: %d (%v)", status, err)
+	}
+}
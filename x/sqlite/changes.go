@@ -204,7 +204,7 @@ func (d *db) newNormalChanges(ctx context.Context, opts options.Map) (*normalCha
 				att.length,
 				att.digest,
 				att.rev_pos,
-				IIF($2, data, NULL) AS data
+				IIF($2, {{ .AttachmentContent }}, NULL) AS data
 			FROM results
 			LEFT JOIN {{ .AttachmentsBridge }} AS bridge ON bridge.id = results.id AND bridge.rev = results.rev AND bridge.rev_id = results.rev_id AND $3
 			LEFT JOIN {{ .Attachments }} AS att ON att.pk = bridge.pk
@@ -250,14 +250,60 @@ func (d *db) newNormalChanges(ctx context.Context, opts options.Map) (*normalCha
 			return nil, &internal.Error{Status: http.StatusNotFound, Message: fmt.Sprintf("design doc '%s' missing %s function '%s'", filterDdoc, filterType, filterName)}
 		}
 
-		if filterType == "filter" {
-			c.filter, err = d.js.Filter(*filterFuncJS)
+		ddoc, ddocRev, err := d.getCoreDoc(ctx, d.db, filterDdoc, revision{}, false, false)
+		if err != nil {
+			return nil, err
+		}
+		funcName := "filters/" + filterName
+		if filterType != "filter" {
+			funcName = "views/" + filterName + "/map"
+		}
+		var lang struct {
+			Language string `json:"language"`
+		}
+		if err := json.Unmarshal(ddoc.Doc, &lang); err != nil {
+			return nil, err
+		}
+		pool, err := d.queryServer(lang.Language)
+		if err != nil {
+			return nil, err
+		}
+		rt, err := d.designJS(filterDdoc, funcName, ddoc.Doc)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case lang.Language == GoLanguage && filterType == "filter":
+			c.filter, err = d.goFuncs.filter(*filterFuncJS)
+			if err != nil {
+				return nil, err
+			}
+		case lang.Language == GoLanguage:
+			mapper, err := d.goFuncs.mapper(*filterFuncJS, 1)
+			if err != nil {
+				return nil, err
+			}
+			c.filter = func(ctx context.Context, doc, _ any) (bool, error) {
+				emitted, err := mapper.Map(ctx, doc)
+				return len(emitted) > 0, err
+			}
+		case pool != nil && filterType == "filter":
+			qs, err := d.qsDDoc(pool, filterDdoc, ddocRev, ddoc.Doc, funcName)
+			if err != nil {
+				return nil, err
+			}
+			c.filter = qs.Filter(filterName)
+		case pool != nil:
+			return nil, errUnsupportedLanguage(lang.Language, "_view filter")
+		case filterType == "filter":
+			c.filter, err = rt.Filter(*filterFuncJS)
 			if err != nil {
 				return nil, &internal.Error{Status: http.StatusInternalServerError, Err: err}
 			}
-		} else {
+		default:
 			var emitted bool
-			mapFunc, err := d.js.Map(*filterFuncJS, func(any, any) {
+			mapFunc, err := rt.Map(*filterFuncJS, func(any, any) {
 				emitted = true
 			})
 			if err != nil {
@@ -483,7 +529,7 @@ func (d *db) newLongpollChanges(ctx context.Context, includeDocs, attachments, c
 				att.length,
 				att.digest,
 				att.rev_pos,
-				IIF($2, att.data, NULL) AS data,
+				IIF($2, {{ .AttachmentContent }}, NULL) AS data,
 				ROW_NUMBER() OVER () AS row_number
 			FROM (
 				SELECT
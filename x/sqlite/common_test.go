@@ -44,6 +44,12 @@ type DB interface {
 	driver.DesignDocer
 	driver.DocCreator
 	driver.Finder
+	driver.DBMaintainer
+	driver.ViewIndexer
+	driver.DesignFunctioner
+	driver.UpdateResponder
+	driver.BulkDocer
+	driver.Searcher
 }
 
 type testDB struct {
@@ -104,7 +110,7 @@ func testClient(t *testing.T) driver.Client {
 		t.Fatal(err)
 	}
 	t.Cleanup(func() {
-		_ = c.(*client).db.Close()
+		_ = c.(*client).Close()
 	})
 	return c
 }
@@ -139,7 +145,7 @@ func newDB(t *testing.T) *testDB {
 		t.Fatal(err)
 	}
 	t.Cleanup(func() {
-		_ = c.(*client).db.Close()
+		_ = c.(*client).Close()
 	})
 	if err := c.CreateDB(context.Background(), "test", nil); err != nil {
 		t.Fatal(err)
@@ -0,0 +1,303 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// autoVacuumIncremental is the value of PRAGMA auto_vacuum when incremental
+// vacuuming is enabled.
+const autoVacuumIncremental = 2
+
+// nonLeavesCTE selects the revisions which have at least one child, and are
+// therefore no longer needed once their history has been recorded.
+const nonLeavesCTE = `
+	WITH non_leaves AS (
+		SELECT DISTINCT rev.id, rev.rev, rev.rev_id
+		FROM {{ .Revs }} AS rev
+		JOIN {{ .Revs }} AS child
+			ON child.id = rev.id
+			AND child.parent_rev = rev.rev
+			AND child.parent_rev_id = rev.rev_id
+	)
+`
+
+// Compact removes the bodies and attachments of non-leaf revisions, leaving
+// only their entries in the revision tree, for replication. The revision tree
+// is pruned to the revs_limit, and the freed space is returned to the file
+// system. Attachments no longer referenced by any revision are removed by
+// triggers, as their references are deleted.
+func (d *db) Compact(ctx context.Context) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := d.compactDesignDocs(ctx, tx); err != nil {
+		return d.errDatabaseNotFound(err)
+	}
+
+	limit, err := d.metadataInt(ctx, tx, "revs_limit", defaultRevsLimit)
+	if err != nil {
+		return err
+	}
+	if err := d.stem(ctx, tx, nil, limit); err != nil {
+		return err
+	}
+
+	// Index entries for non-leaf revisions are stale, and would be replaced
+	// during the next index update anyway. Remove them now to satisfy the
+	// foreign key constraint.
+	mapTables, err := d.viewMapTables(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, mapTable := range mapTables {
+		if _, err := tx.ExecContext(ctx, d.ddocQuery(mapTable.ddoc, mapTable.view, mapTable.rev, nonLeavesCTE+`
+			DELETE FROM {{ .Map }}
+			WHERE (id, rev, rev_id) IN (SELECT id, rev, rev_id FROM non_leaves)
+		`)); err != nil {
+			return err
+		}
+	}
+
+	for _, query := range []string{
+		`DELETE FROM {{ .AttachmentsBridge }}
+		WHERE (id, rev, rev_id) IN (SELECT id, rev, rev_id FROM non_leaves)`,
+		`DELETE FROM {{ .Docs }}
+		WHERE (id, rev, rev_id) IN (SELECT id, rev, rev_id FROM non_leaves)`,
+	} {
+		if _, err := tx.ExecContext(ctx, d.query(nonLeavesCTE+query)); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return d.vacuum(ctx)
+}
+
+// compactDesignDocs drops the index tables and functions of non-leaf design
+// document revisions. Index tables are normally dropped when a design
+// document is updated, but the functions are kept until compaction.
+func (d *db) compactDesignDocs(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, d.query(nonLeavesCTE+`
+		SELECT DISTINCT design.id, design.rev, design.rev_id
+		FROM {{ .Design }} AS design
+		JOIN non_leaves
+			ON non_leaves.id = design.id
+			AND non_leaves.rev = design.rev
+			AND non_leaves.rev_id = design.rev_id
+	`))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	type designRev struct {
+		id  string
+		rev revision
+	}
+	var revs []designRev
+	for rows.Next() {
+		var r designRev
+		if err := rows.Scan(&r.id, &r.rev.rev, &r.rev.id); err != nil {
+			return err
+		}
+		revs = append(revs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_ = rows.Close()
+
+	for _, r := range revs {
+		if err := d.dropMapTables(ctx, tx, r.id, r.rev); err != nil {
+			return err
+		}
+		if err := d.dropSearchTables(ctx, tx, r.id, r.rev); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, d.query(`
+			DELETE FROM {{ .Design }}
+			WHERE id = $1 AND rev = $2 AND rev_id = $3
+		`), r.id, r.rev.rev, r.rev.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompactView brings the views of the named design document up to date, and
+// rebuilds any which still hold entries for revisions other than the current
+// revision of each document, such as after a conflict was resolved in favor
+// of an older branch.
+func (d *db) CompactView(ctx context.Context, ddoc string) error {
+	ddoc = strings.TrimPrefix(ddoc, "_design/")
+	rev, err := d.winningRev(ctx, d.db, "_design/"+ddoc)
+	if err != nil {
+		return err
+	}
+	rows, err := d.db.QueryContext(ctx, d.query(`
+		SELECT func_name
+		FROM {{ .Design }}
+		WHERE id = $1
+			AND rev = $2
+			AND rev_id = $3
+			AND func_type = 'map'
+	`), "_design/"+ddoc, rev.rev, rev.id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var views []string
+	for rows.Next() {
+		var view string
+		if err := rows.Scan(&view); err != nil {
+			return err
+		}
+		views = append(views, view)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_ = rows.Close()
+
+	for _, view := range views {
+		if err := d.compactView(ctx, ddoc, view, rev); err != nil {
+			return err
+		}
+	}
+	return d.vacuum(ctx)
+}
+
+func (d *db) compactView(ctx context.Context, ddoc, view string, rev revision) error {
+	if _, err := d.updateIndex(ctx, ddoc, view, "true"); err != nil {
+		return err
+	}
+	var stale bool
+	err := d.db.QueryRowContext(ctx, d.ddocQuery(ddoc, view, rev.String(), `
+		SELECT EXISTS (
+			SELECT 1
+			FROM {{ .Map }} AS view
+			JOIN {{ .Docs }} AS newer
+				ON newer.id = view.id
+				AND (newer.rev > view.rev OR (newer.rev = view.rev AND newer.rev_id > view.rev_id))
+		)
+	`)).Scan(&stale)
+	if err != nil || !stale {
+		return err
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, d.ddocQuery(ddoc, view, rev.String(), `DELETE FROM {{ .Map }}`)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, d.query(`
+		UPDATE {{ .Design }}
+		SET last_seq = 0
+		WHERE id = $1
+			AND rev = $2
+			AND rev_id = $3
+			AND func_type = 'map'
+			AND func_name = $4
+	`), "_design/"+ddoc, rev.rev, rev.id, view); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	_, err = d.updateIndex(ctx, ddoc, view, "true")
+	return err
+}
+
+// vacuum returns free pages to the file system. Files created before
+// incremental vacuuming was enabled are converted by a full VACUUM.
+func (d *db) vacuum(ctx context.Context) error {
+	// PRAGMA settings are per connection.
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var mode int
+	if err := conn.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		return err
+	}
+	if mode != autoVacuumIncremental {
+		if _, err := conn.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, "VACUUM")
+		return err
+	}
+	// A row is returned for each page freed, so the statement must be run to
+	// completion.
+	rows, err := conn.QueryContext(ctx, "PRAGMA incremental_vacuum")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() { //nolint:revive // Each row is a freed page.
+	}
+	return rows.Err()
+}
+
+// sizes returns the disk, active and external sizes of the database, as
+// reported by [db.Stats]. The disk size is that of the pages used by the
+// database's tables and indexes, excluding view indexes, as the file may be
+// shared with other databases. The active size is that of the stored document
+// bodies and attachment content, which is stored once however many
+// attachments share it, and may be compressed. The external size counts the
+// bodies and attachments of leaf revisions, as if each were stored separately.
+func (d *db) sizes(ctx context.Context) (disk, active, external int64, err error) {
+	err = d.db.QueryRowContext(ctx, d.query(`
+		WITH leaves AS (
+			SELECT rev.id, rev.rev, rev.rev_id
+			FROM {{ .Revs }} AS rev
+			LEFT JOIN {{ .Revs }} AS child
+				ON child.id = rev.id
+				AND rev.rev = child.parent_rev
+				AND rev.rev_id = child.parent_rev_id
+			WHERE child.id IS NULL
+		)
+		SELECT
+			(
+				SELECT COALESCE(SUM(stat.pgsize), 0)
+				FROM dbstat AS stat
+				JOIN sqlite_schema AS obj ON obj.name = stat.name
+				WHERE obj.tbl_name = $1 OR obj.tbl_name GLOB $2
+			),
+			(SELECT COALESCE(SUM(LENGTH(doc)), 0) FROM {{ .Docs }})
+				+ (SELECT COALESCE(SUM(encoded_length), 0) FROM {{ .AttachmentData }}),
+			(
+				SELECT COALESCE(SUM(LENGTH(doc.doc)), 0)
+				FROM {{ .Docs }} AS doc
+				JOIN leaves USING (id, rev, rev_id)
+			) + (
+				SELECT COALESCE(SUM(att.length), 0)
+				FROM {{ .AttachmentsBridge }} AS bridge
+				JOIN leaves USING (id, rev, rev_id)
+				JOIN {{ .Attachments }} AS att ON att.pk = bridge.pk
+			)
+	`), tablePrefix+d.name, tablePrefix+d.name+"$*").Scan(&disk, &active, &external)
+	return disk, active, external, err
+}
@@ -0,0 +1,245 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+func (tdb *testDB) count(query string) int {
+	tdb.t.Helper()
+	var count int
+	if err := tdb.underlying().QueryRow(tdb.DB.(*db).query(query)).Scan(&count); err != nil {
+		tdb.t.Fatal(err)
+	}
+	return count
+}
+
+func (tdb *testDB) revisionIDs(docID string) []string {
+	tdb.t.Helper()
+	doc, err := tdb.Get(context.Background(), docID, kivik.Param("revs", true))
+	if err != nil {
+		tdb.t.Fatal(err)
+	}
+	var got struct {
+		Revisions struct {
+			IDs []string `json:"ids"`
+		} `json:"_revisions"`
+	}
+	if err := json.NewDecoder(doc.Body).Decode(&got); err != nil {
+		tdb.t.Fatal(err)
+	}
+	return got.Revisions.IDs
+}
+
+func TestDBCompact(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	ctx := context.Background()
+
+	// Large bodies and attachments, so that compaction frees whole pages.
+	big := strings.Repeat("x", 20000)
+	rev1 := d.tPut("foo", map[string]any{
+		"body":         big,
+		"_attachments": newAttachments().add("att.txt", "old "+big),
+	})
+	rev2 := d.tPut("foo", map[string]any{
+		"body":         big + "2",
+		"_attachments": newAttachments().add("att.txt", "new "+big),
+	}, kivik.Rev(rev1))
+	rev3 := d.tPut("foo", map[string]any{
+		"body":         "small",
+		"_attachments": newAttachments().addStub("att.txt"),
+	}, kivik.Rev(rev2))
+
+	// A conflict, both branches of which must be kept.
+	conflict := d.tPut("foo", map[string]any{"body": "conflict"}, kivik.Params(map[string]any{
+		"new_edits": false,
+		"rev":       "2-aaa",
+	}))
+
+	ddocRev := d.tPut("_design/foo", map[string]any{
+		"views": map[string]any{
+			"bar": map[string]string{"map": `function(doc) { emit(doc._id, doc.body.length); }`},
+		},
+	})
+	_ = d.tPut("_design/foo", map[string]any{
+		"views": map[string]any{
+			"bar": map[string]string{"map": `function(doc) { emit(doc._id, doc.body); }`},
+		},
+	}, kivik.Rev(ddocRev))
+
+	before, err := d.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Compact(ctx); err != nil {
+		t.Fatalf("Compact failed: %s", err)
+	}
+
+	for _, rev := range []string{rev1, rev2, ddocRev} {
+		docID := "foo"
+		if rev == ddocRev {
+			docID = "_design/foo"
+		}
+		_, err := d.Get(ctx, docID, kivik.Rev(rev))
+		if status := kivik.HTTPStatus(err); status != http.StatusNotFound {
+			t.Errorf("Unexpected status for compacted rev %s: %d", rev, status)
+		}
+	}
+	for _, rev := range []string{rev3, conflict} {
+		if _, err := d.Get(ctx, "foo", kivik.Rev(rev)); err != nil {
+			t.Errorf("Leaf %s was removed: %s", rev, err)
+		}
+	}
+	if ids := d.revisionIDs("foo"); len(ids) != 3 {
+		t.Errorf("Expected the revision history to be kept, got %v", ids)
+	}
+
+	att, err := d.GetAttachment(ctx, "foo", "att.txt", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(att.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "new "+big {
+		t.Errorf("Unexpected attachment content: %.10q", content)
+	}
+	if n := d.count(`SELECT COUNT(*) FROM {{ .Attachments }}`); n != 1 {
+		t.Errorf("Expected orphaned attachments to be removed, %d remain", n)
+	}
+	if n := d.count(`SELECT COUNT(DISTINCT rev) FROM {{ .Design }}`); n != 1 {
+		t.Errorf("Expected only the current design functions to remain, found %d revisions", n)
+	}
+	if n := d.count(`PRAGMA freelist_count`); n != 0 {
+		t.Errorf("Expected the file to be vacuumed, %d free pages remain", n)
+	}
+
+	rows, err := d.Query(ctx, "_design/foo", "_view/bar", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, rows, []rowResult{
+		{ID: "foo", Key: `"foo"`, Value: `"small"`},
+	})
+
+	after, err := d.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.ActiveSize >= before.ActiveSize {
+		t.Errorf("Active size did not shrink: %d -> %d", before.ActiveSize, after.ActiveSize)
+	}
+	if after.DiskSize >= before.DiskSize {
+		t.Errorf("Disk size did not shrink: %d -> %d", before.DiskSize, after.DiskSize)
+	}
+	if after.ExternalSize != before.ExternalSize {
+		t.Errorf("External size changed: %d -> %d", before.ExternalSize, after.ExternalSize)
+	}
+	if after.DocCount != before.DocCount || after.UpdateSeq != before.UpdateSeq {
+		t.Errorf("Unexpected stats after compaction: %+v", after)
+	}
+}
+
+func TestDBCompact_revsLimit(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	rev := d.tPut("foo", map[string]string{"foo": "one"})
+	rev = d.tPut("foo", map[string]string{"foo": "two"}, kivik.Rev(rev))
+	_ = d.tPut("foo", map[string]string{"foo": "three"}, kivik.Rev(rev))
+
+	// Lower the limit without pruning, as SetRevsLimit would.
+	if _, err := d.underlying().Exec(d.DB.(*db).query(`
+		INSERT INTO {{ .Metadata }} (key, value) VALUES ('revs_limit', '2')
+	`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Compact(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ids := d.revisionIDs("foo"); len(ids) != 2 {
+		t.Errorf("Expected the revision tree to be pruned to 2 revisions, got %v", ids)
+	}
+}
+
+func TestDBCompactView(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	ctx := context.Background()
+
+	err := d.CompactView(ctx, "some_ddoc")
+	if status := kivik.HTTPStatus(err); status != http.StatusNotFound {
+		t.Errorf("Unexpected status for missing design doc: %d", status)
+	}
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"views": map[string]any{
+			"bar": map[string]string{"map": `function(doc) { emit(doc._id, doc.v); }`},
+		},
+	})
+	rev1 := d.tPut("a", map[string]any{"v": 1})
+	_ = d.tPut("a", map[string]any{"v": 2}, kivik.Rev(rev1))
+
+	rows, err := d.Query(ctx, "_design/foo", "_view/bar", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rows.Close()
+
+	// An entry for an outdated revision, which an index update alone would
+	// never replace.
+	r1, _ := parseRev(rev1)
+	if _, err := d.underlying().Exec(d.DB.(*db).ddocQuery("_design/foo", "bar", d.ddocRev("foo"), `
+		UPDATE {{ .Map }} SET rev = $1, rev_id = $2, value = '"stale"'
+	`), r1.rev, r1.id); err != nil {
+		t.Fatal(err)
+	}
+	_ = d.tPut("b", map[string]any{"v": 3})
+
+	if err := d.CompactView(ctx, "_design/foo"); err != nil {
+		t.Fatalf("CompactView failed: %s", err)
+	}
+
+	rows, err = d.Query(ctx, "_design/foo", "_view/bar", kivik.Param("update", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, rows, []rowResult{
+		{ID: "a", Key: `"a"`, Value: "2"},
+		{ID: "b", Key: `"b"`, Value: "3"},
+	})
+}
+
+// ddocRev returns the current revision of the named design document.
+func (tdb *testDB) ddocRev(ddoc string) string {
+	tdb.t.Helper()
+	rev, err := tdb.DB.(*db).winningRev(context.Background(), tdb.underlying(), "_design/"+ddoc)
+	if err != nil {
+		tdb.t.Fatal(err)
+	}
+	return rev.String()
+}
@@ -46,5 +46,11 @@ func (c *client) CreateDB(ctx context.Context, name string, _ driver.Options) er
 		return err
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if name == replicatorDB {
+		c.currentScheduler().start()
+	}
+	return nil
 }
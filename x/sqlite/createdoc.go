@@ -25,6 +25,10 @@ import (
 )
 
 func (d *db) CreateDoc(ctx context.Context, doc any, _ driver.Options) (string, string, error) {
+	doc, err := d.prepareUserDoc("", doc)
+	if err != nil {
+		return "", "", err
+	}
 	data, err := prepareDoc("", doc)
 	if err != nil {
 		return "", "", err
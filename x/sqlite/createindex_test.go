@@ -21,6 +21,7 @@ import (
 
 	"gitlab.com/flimzy/testy"
 
+	"github.com/go-kivik/kivik/v4"
 	"github.com/go-kivik/kivik/v4/driver"
 	"github.com/go-kivik/kivik/v4/int/mock"
 )
@@ -49,6 +50,13 @@ func TestCreateIndex(t *testing.T) {
 		index:   json.RawMessage(`{"fields":[{"name":"asc"},{"age":"desc"}]}`),
 		wantErr: "Sorts currently only support a single direction for all fields",
 	})
+	tests.Add("text index", test{
+		ddoc:    "_design/text",
+		name:    "text",
+		index:   json.RawMessage(`{"fields":[{"name":"name","type":"string"}]}`),
+		opts:    kivik.Param("type", "text"),
+		wantErr: "only json indexes are supported",
+	})
 	tests.Add("creates a real SQLite index", func(t *testing.T) any {
 		db := newDB(t)
 		return test{
@@ -15,6 +15,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
@@ -23,6 +24,7 @@ import (
 	"github.com/go-kivik/kivik/v4/driver"
 	internal "github.com/go-kivik/kivik/v4/int/errors"
 	"github.com/go-kivik/kivik/x/sqlite/v4/js"
+	"github.com/go-kivik/kivik/x/sqlite/v4/queryserver"
 )
 
 type db struct {
@@ -30,6 +32,16 @@ type db struct {
 	name   string
 	logger *log.Logger
 	js     *js.Runtime
+	// queryServers holds the external query servers, by language.
+	queryServers map[string]*queryserver.Pool
+	goFuncs      *goFuncs
+	// compressibleTypes are the attachment content types stored gzipped.
+	compressibleTypes []string
+	reduceFailures    *reduceFailures
+	// replicationState is set for the replication scheduler's own writes to
+	// the _replicator database, so that _replication_* fields are stored
+	// rather than stripped.
+	replicationState bool
 }
 
 var (
@@ -41,10 +53,15 @@ var (
 
 func (c *client) newDB(name string) *db {
 	return &db{
-		db:     c.db,
-		name:   name,
-		logger: c.logger,
-		js:     c.js,
+		db:           c.db,
+		name:         name,
+		logger:       c.logger,
+		js:           c.js,
+		queryServers: c.queryServers,
+		goFuncs:      c.goFuncs,
+
+		compressibleTypes: c.compressibleTypes,
+		reduceFailures:    c.reduceFailures,
 	}
 }
 
@@ -93,28 +110,87 @@ func (d *db) Stats(ctx context.Context) (*driver.DBStats, error) {
 		return nil, d.errDatabaseNotFound(err)
 	}
 
-	return &driver.DBStats{
+	diskSize, activeSize, externalSize, err := d.sizes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attStats, err := d.attachmentStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &driver.DBStats{
 		Name:         d.name,
 		DocCount:     docCount,
 		DeletedCount: deletedCount,
 		UpdateSeq:    strconv.FormatUint(lastSeq, 10),
+		DiskSize:     diskSize,
+		ActiveSize:   activeSize,
+		ExternalSize: externalSize,
 		PurgeSeq:     purgeSeq,
-	}, nil
+	}
+	stats.RawResponse, err = json.Marshal(rawStats{
+		DBStats: stats,
+		Sizes: rawSizes{
+			File:     diskSize,
+			External: externalSize,
+			Active:   activeSize,
+		},
+		Attachments: attStats,
+	})
+	return stats, err
 }
 
-func (d *db) Compact(ctx context.Context) error {
-	_, err := d.db.ExecContext(ctx, "VACUUM")
-	return err
+// rawStats is the [driver.DBStats.RawResponse] document. It matches the
+// CouchDB format, with the addition of attachment storage statistics.
+type rawStats struct {
+	*driver.DBStats
+	Sizes       rawSizes         `json:"sizes"`
+	Attachments *attachmentStats `json:"attachments"`
 }
 
-func (db) CompactView(context.Context, string) error { return nil }
+type rawSizes struct {
+	File     int64 `json:"file"`
+	External int64 `json:"external"`
+	Active   int64 `json:"active"`
+}
 
-func (db) ViewCleanup(context.Context) error { return nil }
+// attachmentStats reports the savings of storing attachment content once per
+// digest, and of compressing it.
+type attachmentStats struct {
+	// Count is the number of attachments, counting each revision separately.
+	Count int64 `json:"count"`
+	// Size is the total length of those attachments.
+	Size int64 `json:"size"`
+	// StoredCount is the number of distinct contents stored.
+	StoredCount int64 `json:"stored_count"`
+	// StoredSize is the total stored length of that content.
+	StoredSize int64 `json:"stored_size"`
+	// Saved is the number of bytes not stored, as duplicates, or by
+	// compression.
+	Saved int64 `json:"saved"`
+}
 
-func (db) BulkDocs(context.Context, []any, driver.Options) ([]driver.BulkResult, error) {
-	return nil, errors.New("not implemented")
+func (d *db) attachmentStats(ctx context.Context) (*attachmentStats, error) {
+	var s attachmentStats
+	err := d.db.QueryRowContext(ctx, d.query(`
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(att.length), 0),
+			(SELECT COUNT(*) FROM {{ .AttachmentData }}),
+			(SELECT COALESCE(SUM(encoded_length), 0) FROM {{ .AttachmentData }})
+		FROM {{ .AttachmentsBridge }} AS bridge
+		JOIN {{ .Attachments }} AS att ON att.pk = bridge.pk
+	`)).Scan(&s.Count, &s.Size, &s.StoredCount, &s.StoredSize)
+	if err != nil {
+		return nil, err
+	}
+	s.Saved = s.Size - s.StoredSize
+	return &s, nil
 }
 
+func (db) ViewCleanup(context.Context) error { return nil }
+
 func (db) Copy(context.Context, string, string, driver.Options) (string, error) {
 	return "", errors.New("not implemented")
 }
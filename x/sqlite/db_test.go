@@ -92,6 +92,16 @@ func Test_db_not_found(t *testing.T) {
 			return err
 		},
 	})
+	tests.Add("Compact", test{
+		call: func(d *db) error {
+			return d.Compact(context.Background())
+		},
+	})
+	tests.Add("CompactView", test{
+		call: func(d *db) error {
+			return d.CompactView(context.Background(), "foo")
+		},
+	})
 	tests.Add("CreateDoc", test{
 		call: func(d *db) error {
 			_, _, err := d.CreateDoc(context.Background(), map[string]string{}, mock.NilOption)
@@ -218,6 +228,23 @@ func Test_db_not_found(t *testing.T) {
 			return err
 		},
 	})
+	tests.Add("RevsLimit", test{
+		call: func(d *db) error {
+			_, err := d.RevsLimit(context.Background())
+			return err
+		},
+	})
+	tests.Add("SetRevsLimit", test{
+		call: func(d *db) error {
+			return d.SetRevsLimit(context.Background(), 10)
+		},
+	})
+	tests.Add("Shards", test{
+		call: func(d *db) error {
+			_, err := d.Shards(context.Background())
+			return err
+		},
+	})
 	/*
 		TODO:
 	*/
@@ -287,33 +314,18 @@ func TestDBStats(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Stats failed: %s", err)
 		}
+		if got.DiskSize <= 0 || got.ActiveSize < got.ExternalSize {
+			t.Errorf("Unexpected sizes: %+v", got)
+		}
+		// Sizes depend on the storage layout, and are tested with compaction.
+		got.DiskSize, got.ActiveSize, got.ExternalSize = 0, 0, 0
+		got.RawResponse = nil
 		if diff := cmp.Diff(tt.want, got); diff != "" {
 			t.Errorf("Unexpected result:\n%s", diff)
 		}
 	})
 }
 
-func TestDBCompact(t *testing.T) {
-	t.Parallel()
-	d := newDB(t)
-	_ = d.tPut("doc1", map[string]string{"foo": "bar"})
-
-	err := d.Compact(context.Background())
-	if err != nil {
-		t.Fatalf("Compact failed: %s", err)
-	}
-}
-
-func TestDBCompactView(t *testing.T) {
-	t.Parallel()
-	d := newDB(t)
-
-	err := d.CompactView(context.Background(), "some_ddoc")
-	if err != nil {
-		t.Fatalf("CompactView failed: %s", err)
-	}
-}
-
 func TestDBViewCleanup(t *testing.T) {
 	t.Parallel()
 	d := newDB(t)
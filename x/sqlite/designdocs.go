@@ -27,6 +27,9 @@ func (d *db) updateDesignDoc(ctx context.Context, tx *sql.Tx, rev revision, curR
 	if err := d.dropMapTables(ctx, tx, data.ID, curRev); err != nil {
 		return err
 	}
+	if err := d.dropSearchTables(ctx, tx, data.ID, curRev); err != nil {
+		return err
+	}
 
 	stmt, err := tx.PrepareContext(ctx, d.query(`
 		INSERT INTO {{ .Design }} (id, rev, rev_id, language, func_type, func_name, func_body, auto_update, include_design, collation, local_seq)
@@ -38,6 +41,11 @@ func (d *db) updateDesignDoc(ctx context.Context, tx *sql.Tx, rev revision, curR
 	defer stmt.Close()
 
 	for name, view := range data.DesignFields.Views {
+		if name == "lib" {
+			// views.lib holds CommonJS modules for use by map functions, not
+			// a view.
+			continue
+		}
 		if view.Map != "" {
 			if _, err := stmt.ExecContext(ctx,
 				data.ID, rev.rev, rev.id, data.DesignFields.Language, "map", name, view.Map,
@@ -100,7 +108,10 @@ func (d *db) dropMapTables(ctx context.Context, tx *sql.Tx, docID string, curRev
 		if err := rows.Scan(&id, &rev.rev, &rev.id, &view); err != nil {
 			return err
 		}
-		queries = append(queries, d.ddocQuery(id, view, rev.String(), `DROP TABLE IF EXISTS {{ .Map }}`))
+		queries = append(queries,
+			d.ddocQuery(id, view, rev.String(), `DROP TABLE IF EXISTS {{ .Map }}`),
+			d.ddocQuery(id, view, rev.String(), `DROP TABLE IF EXISTS {{ .Reduce }}`),
+		)
 	}
 	if err := rows.Err(); err != nil {
 		return err
@@ -115,7 +126,7 @@ func (d *db) dropMapTables(ctx context.Context, tx *sql.Tx, docID string, curRev
 }
 
 func (d *db) createViewMap(ctx context.Context, tx *sql.Tx, ddoc, name, rev string, collation *string) error {
-	for _, query := range viewSchema {
+	for _, query := range append(viewSchema, reduceSchema...) {
 		if _, err := tx.ExecContext(ctx, d.createDdocQuery(ddoc, name, rev, query, collation)); err != nil {
 			return err
 		}
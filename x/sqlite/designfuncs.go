@@ -0,0 +1,667 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/x/sqlite/v4/js"
+)
+
+var _ driver.DesignFunctioner = (*db)(nil)
+
+// defaultDesignContentType is the Content-Type of show and list responses,
+// when the function does not set one.
+const defaultDesignContentType = "text/html; charset=utf-8"
+
+// designFuncs holds the show, list and rewrite functions of a design document.
+// These are read from the design document body, rather than the Design
+// table, as they are only needed on demand.
+type designFuncs struct {
+	Language string            `json:"language"`
+	Shows    map[string]string `json:"shows"`
+	Lists    map[string]string `json:"lists"`
+	Rewrites json.RawMessage   `json:"rewrites"`
+
+	// id and ddoc are the ID and full body of the design document, which
+	// the functions may access with require().
+	id   string
+	ddoc map[string]any
+}
+
+// js returns a runtime in which to compile the named function of the design
+// document.
+func (f *designFuncs) js(d *db, funcName string) *js.Runtime {
+	return d.ddocJS(f.id, funcName, f.ddoc)
+}
+
+func (d *db) designFuncs(ctx context.Context, ddoc string) (*designFuncs, error) {
+	doc, _, err := d.getCoreDoc(ctx, d.db, "_design/"+ddoc, revision{}, false, false)
+	if err != nil {
+		return nil, err
+	}
+	var funcs designFuncs
+	if err := json.Unmarshal(doc.Doc, &funcs); err != nil {
+		return nil, err
+	}
+	if funcs.Language != "" && funcs.Language != "javascript" {
+		// Shows, lists and rewrites are only supported in JavaScript.
+		return nil, errUnsupportedLanguage(funcs.Language, "show, list and rewrite")
+	}
+	if err := json.Unmarshal(doc.Doc, &funcs.ddoc); err != nil {
+		return nil, err
+	}
+	funcs.id = "_design/" + ddoc
+	return &funcs, nil
+}
+
+// designJS returns a JavaScript runtime in which to compile the named function
+// of a design document, given the document's ID and body. See [db.ddocJS].
+func (d *db) designJS(ddocID, funcName string, body []byte) (*js.Runtime, error) {
+	var ddoc map[string]any
+	if err := json.Unmarshal(body, &ddoc); err != nil {
+		return nil, err
+	}
+	return d.ddocJS(ddocID, funcName, ddoc), nil
+}
+
+// ddocJS returns a JavaScript runtime in which to compile the named function
+// of a design document. Functions may load CommonJS modules from ddoc with
+// require(), and messages passed to log() are written to the driver's
+// logger.
+func (d *db) ddocJS(ddocID, funcName string, ddoc map[string]any) *js.Runtime {
+	return d.js.WithDesignDoc(ddoc).WithLog(d.jsLog(ddocID, funcName))
+}
+
+// jsLog returns a function which writes messages passed to log() by the named
+// function of a design document to the driver's logger.
+func (d *db) jsLog(ddocID, funcName string) func(string) {
+	return func(message string) {
+		d.logger.Printf("log from %s %s: %s", ddocID, funcName, message)
+	}
+}
+
+// designCall holds the method, body and headers of the request passed to a
+// design function.
+type designCall struct {
+	method  string
+	body    string
+	headers map[string]string
+}
+
+// designRequest returns a request object in the shape CouchDB passes to
+// design functions.
+func (d *db) designRequest(call designCall, path []string, docID string, query map[string]any) map[string]any {
+	var id any
+	if docID != "" {
+		id = docID
+	}
+	body := call.body
+	if body == "" {
+		// CouchDB passes the literal string "undefined" when there is no
+		// request body.
+		body = "undefined"
+	}
+	escaped := make([]string, len(path))
+	for i, p := range path {
+		escaped[i] = url.PathEscape(p)
+	}
+	rawPath := "/" + strings.Join(escaped, "/")
+	if len(query) > 0 {
+		q := url.Values{}
+		for k, v := range query {
+			q.Set(k, v.(string))
+		}
+		rawPath += "?" + q.Encode()
+	}
+	headers := make(map[string]any, len(call.headers))
+	for k, v := range call.headers {
+		headers[k] = v
+	}
+	return map[string]any{
+		"body":    body,
+		"cookie":  map[string]any{},
+		"form":    map[string]any{},
+		"headers": headers,
+		"id":      id,
+		"info": map[string]any{
+			"db_name": d.name,
+		},
+		"method":         call.method,
+		"path":           path,
+		"peer":           "127.0.0.1",
+		"query":          query,
+		"raw_path":       rawPath,
+		"requested_path": path,
+		"userCtx": map[string]any{
+			"name":  nil,
+			"roles": []string{"_admin"},
+			"db":    d.name,
+		},
+		"secObj": map[string]any{
+			"admins":  map[string]any{"names": []string{}, "roles": []string{}},
+			"members": map[string]any{"names": []string{}, "roles": []string{}},
+		},
+		"uuid": uuid.NewString(),
+	}
+}
+
+// designQuery converts options to the query object passed to design
+// functions, in which all values are strings, as they would be when parsed
+// from a URL.
+func designQuery(opts driver.Options) map[string]any {
+	params := map[string]any{}
+	if opts != nil {
+		opts.Apply(params)
+	}
+	query := make(map[string]any, len(params))
+	for k, v := range params {
+		if s, ok := v.(string); ok {
+			query[k] = s
+			continue
+		}
+		j, _ := json.Marshal(v)
+		query[k] = string(j)
+	}
+	return query
+}
+
+func designResponse(resp *js.Response) *driver.DesignResponse {
+	header := make(http.Header, len(resp.Headers)+1)
+	for k, v := range resp.Headers {
+		header.Set(k, v)
+	}
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", defaultDesignContentType)
+	}
+	return &driver.DesignResponse{
+		StatusCode:  resp.Code,
+		ContentType: header.Get("Content-Type"),
+		Header:      header,
+		Body:        io.NopCloser(bytes.NewReader(resp.Body)),
+	}
+}
+
+// Show calls the named show function with the requested document.
+func (d *db) Show(ctx context.Context, ddoc, funcName, docID string, opts driver.Options) (*driver.DesignResponse, error) {
+	return d.show(ctx, ddoc, funcName, docID, designCall{method: http.MethodGet}, opts)
+}
+
+func (d *db) show(ctx context.Context, ddoc, funcName, docID string, call designCall, opts driver.Options) (*driver.DesignResponse, error) {
+	funcs, err := d.designFuncs(ctx, ddoc)
+	if err != nil {
+		return nil, err
+	}
+	funcBody, ok := funcs.Shows[funcName]
+	if !ok {
+		return nil, &internal.Error{Status: http.StatusNotFound, Message: "missing show function " + funcName + " on design doc _design/" + ddoc}
+	}
+	showFunc, err := funcs.js(d, "shows/"+funcName).Show(funcBody)
+	if err != nil {
+		return nil, err
+	}
+
+	path := []string{d.name, "_design", ddoc, "_show", funcName}
+	var doc any
+	if docID != "" {
+		path = append(path, docID)
+		existing, _, err := d.getCoreDoc(ctx, d.db, docID, revision{}, false, false)
+		if err != nil && internal.HTTPStatus(err) != http.StatusNotFound {
+			return nil, err
+		}
+		if err == nil {
+			doc = existing.toMap()
+		}
+	}
+
+	resp, err := showFunc(ctx, doc, d.designRequest(call, path, docID, designQuery(opts)))
+	if err != nil {
+		return nil, err
+	}
+	return designResponse(resp), nil
+}
+
+// List calls the named list function with the results of the view. The view
+// may be of the form "ddoc/view" to use a view from another design document.
+func (d *db) List(ctx context.Context, ddoc, funcName, view string, opts driver.Options) (*driver.DesignResponse, error) {
+	return d.list(ctx, ddoc, funcName, view, designCall{method: http.MethodGet}, opts)
+}
+
+func (d *db) list(ctx context.Context, ddoc, funcName, view string, call designCall, opts driver.Options) (*driver.DesignResponse, error) {
+	funcs, err := d.designFuncs(ctx, ddoc)
+	if err != nil {
+		return nil, err
+	}
+	funcBody, ok := funcs.Lists[funcName]
+	if !ok {
+		return nil, &internal.Error{Status: http.StatusNotFound, Message: "missing list function " + funcName + " on design doc _design/" + ddoc}
+	}
+	listFunc, err := funcs.js(d, "lists/"+funcName).List(funcBody)
+	if err != nil {
+		return nil, err
+	}
+
+	viewDDoc, viewName := ddoc, view
+	if parts := strings.SplitN(view, "/", 2); len(parts) == 2 {
+		viewDDoc, viewName = parts[0], parts[1]
+	}
+	rows, err := d.Query(ctx, "_design/"+viewDDoc, viewName, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	head := map[string]any{
+		"total_rows": rows.TotalRows(),
+		"offset":     rows.Offset(),
+	}
+	getRow := func() (any, error) {
+		var row driver.Row
+		if err := rows.Next(&row); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return listRow(&row)
+	}
+
+	path := append([]string{d.name, "_design", ddoc, "_list", funcName}, strings.Split(view, "/")...)
+	resp, err := listFunc(ctx, head, d.designRequest(call, path, "", designQuery(opts)), getRow)
+	if err != nil {
+		return nil, err
+	}
+	return designResponse(resp), nil
+}
+
+// listRow converts a view row to the object passed to list functions.
+func listRow(row *driver.Row) (map[string]any, error) {
+	result := map[string]any{}
+	if row.ID != "" {
+		result["id"] = row.ID
+	}
+	var key any
+	if len(row.Key) > 0 {
+		if err := json.Unmarshal(row.Key, &key); err != nil {
+			return nil, err
+		}
+	}
+	result["key"] = key
+	if row.Error != nil {
+		result["error"] = row.Error.Error()
+		return result, nil
+	}
+	if row.Value != nil {
+		var value any
+		if err := json.NewDecoder(row.Value).Decode(&value); err != nil {
+			return nil, err
+		}
+		result["value"] = value
+	}
+	if row.Doc != nil {
+		var doc any
+		if err := json.NewDecoder(row.Doc).Decode(&doc); err != nil {
+			return nil, err
+		}
+		result["doc"] = doc
+	}
+	return result, nil
+}
+
+// rewriteRule is a single declarative rewrite rule.
+type rewriteRule struct {
+	From   string         `json:"from"`
+	To     string         `json:"to"`
+	Method string         `json:"method"`
+	Query  map[string]any `json:"query"`
+}
+
+// Rewrite resolves the request against the design document's rewrite rules
+// or rewrite function, and dispatches the rewritten request. Rewritten
+// requests may target:
+//
+//   - show and list functions, with GET, HEAD or POST
+//   - views, with GET or HEAD
+//   - update functions, with POST or PUT
+//   - documents, with GET, HEAD, PUT or DELETE
+//   - the database, with POST, to create a document
+//
+// Other targets, such as attachments or other databases, are not supported,
+// and result in a 501 Not Implemented error. The method, body and headers
+// returned by a rewrite function replace those of the original request. The
+// headers are visible to show, list and update functions as req.headers.
+func (d *db) Rewrite(ctx context.Context, ddoc, method, path string, body io.Reader, opts driver.Options) (*driver.DesignResponse, error) {
+	funcs, err := d.designFuncs(ctx, ddoc)
+	if err != nil {
+		return nil, err
+	}
+	var reqBody string
+	if body != nil {
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = string(b)
+	}
+	params := map[string]any{}
+	if opts != nil {
+		opts.Apply(params)
+	}
+
+	var target *js.RewriteResult
+	var rules []rewriteRule
+	var funcBody string
+	switch {
+	case len(funcs.Rewrites) == 0:
+		return nil, &internal.Error{Status: http.StatusNotFound, Message: "missing rewrites on design doc _design/" + ddoc}
+	case json.Unmarshal(funcs.Rewrites, &rules) == nil:
+		target = matchRewriteRules(rules, method, path, params)
+		if target == nil {
+			return nil, &internal.Error{Status: http.StatusNotFound, Message: "no rewrite rule matches " + method + " /" + path}
+		}
+	case json.Unmarshal(funcs.Rewrites, &funcBody) == nil:
+		rewriteFunc, err := funcs.js(d, "rewrites").Rewrite(funcBody)
+		if err != nil {
+			return nil, err
+		}
+		reqPath := append([]string{d.name, "_design", ddoc, "_rewrite"}, splitPath(path)...)
+		target, err = rewriteFunc(ctx, d.designRequest(designCall{method: method, body: reqBody}, reqPath, "", designQuery(opts)))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &internal.Error{Status: http.StatusInternalServerError, Message: "invalid rewrites on design doc _design/" + ddoc}
+	}
+
+	call := designCall{method: method, body: reqBody, headers: target.Headers}
+	if target.Method != "" {
+		call.method = strings.ToUpper(target.Method)
+	}
+	if target.Body != nil {
+		call.body = *target.Body
+	}
+	if target.Query != nil {
+		params = target.Query
+	}
+	return d.dispatchRewrite(ctx, call, resolveRewritePath(ddoc, target.Path), kivik.Params(params))
+}
+
+// dispatchRewrite performs the rewritten request, identified by its path
+// segments relative to the database.
+func (d *db) dispatchRewrite(ctx context.Context, call designCall, segments []string, opts driver.Options) (*driver.DesignResponse, error) {
+	method := call.method
+	isDesign := len(segments) >= 4 && segments[0] == "_design"
+	switch {
+	case isDesign && segments[2] == "_show" && isMethod(method, http.MethodGet, http.MethodHead, http.MethodPost):
+		return d.show(ctx, segments[1], segments[3], strings.Join(segments[4:], "/"), call, opts)
+	case isDesign && len(segments) >= 5 && segments[2] == "_list" && isMethod(method, http.MethodGet, http.MethodHead, http.MethodPost):
+		return d.list(ctx, segments[1], segments[3], strings.Join(segments[4:], "/"), call, opts)
+	case isDesign && len(segments) == 4 && segments[2] == "_view" && isMethod(method, http.MethodGet, http.MethodHead):
+		return d.rewriteView(ctx, segments[1], segments[3], opts)
+	case isDesign && segments[2] == "_update" && isMethod(method, http.MethodPost, http.MethodPut):
+		resp, err := d.updateWithResponse(ctx, segments[1], segments[3], strings.Join(segments[4:], "/"), call, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &driver.DesignResponse{
+			StatusCode:  resp.StatusCode,
+			ContentType: resp.ContentType,
+			Header:      resp.Header,
+			Body:        resp.Body,
+		}, nil
+	case len(segments) == 0 && method == http.MethodPost:
+		doc, err := rewriteDoc(call.body)
+		if err != nil {
+			return nil, err
+		}
+		docID, rev, err := d.CreateDoc(ctx, doc, opts)
+		if err != nil {
+			return nil, err
+		}
+		return jsonDesignResponse(http.StatusCreated, map[string]any{"ok": true, "id": docID, "rev": rev})
+	case len(segments) == 2 && segments[0] == "_design",
+		len(segments) == 1 && !strings.HasPrefix(segments[0], "_"):
+		return d.rewriteDocument(ctx, call, strings.Join(segments, "/"), opts)
+	}
+	return nil, &internal.Error{Status: http.StatusNotImplemented, Message: "unsupported rewrite target: " + method + " /" + strings.Join(segments, "/")}
+}
+
+// rewriteDocument reads, writes or deletes the document docID, as the target
+// of a rewritten request.
+func (d *db) rewriteDocument(ctx context.Context, call designCall, docID string, opts driver.Options) (*driver.DesignResponse, error) {
+	switch call.method {
+	case http.MethodGet, http.MethodHead:
+		doc, err := d.Get(ctx, docID, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &driver.DesignResponse{
+			StatusCode:  http.StatusOK,
+			ContentType: "application/json",
+			Header:      http.Header{"Content-Type": {"application/json"}},
+			Body:        doc.Body,
+		}, nil
+	case http.MethodPut:
+		doc, err := rewriteDoc(call.body)
+		if err != nil {
+			return nil, err
+		}
+		rev, err := d.Put(ctx, docID, doc, opts)
+		if err != nil {
+			return nil, err
+		}
+		return jsonDesignResponse(http.StatusCreated, map[string]any{"ok": true, "id": docID, "rev": rev})
+	case http.MethodDelete:
+		rev, err := d.Delete(ctx, docID, opts)
+		if err != nil {
+			return nil, err
+		}
+		return jsonDesignResponse(http.StatusOK, map[string]any{"ok": true, "id": docID, "rev": rev})
+	}
+	return nil, &internal.Error{Status: http.StatusNotImplemented, Message: "unsupported rewrite target: " + call.method + " /" + docID}
+}
+
+// rewriteDoc parses the body of a rewritten request as a JSON document.
+func rewriteDoc(body string) (json.RawMessage, error) {
+	if !json.Valid([]byte(body)) {
+		return nil, &internal.Error{Status: http.StatusBadRequest, Message: "invalid JSON in rewritten request body"}
+	}
+	return json.RawMessage(body), nil
+}
+
+func isMethod(method string, methods ...string) bool {
+	for _, m := range methods {
+		if method == m {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonDesignResponse returns a response with v encoded as JSON.
+func jsonDesignResponse(status int, v any) (*driver.DesignResponse, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &driver.DesignResponse{
+		StatusCode:  status,
+		ContentType: "application/json",
+		Header:      http.Header{"Content-Type": {"application/json"}},
+		Body:        io.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
+
+// rewriteView renders the results of a view as JSON, as returned by the
+// CouchDB view endpoint.
+func (d *db) rewriteView(ctx context.Context, ddoc, view string, opts driver.Options) (*driver.DesignResponse, error) {
+	rows, err := d.Query(ctx, "_design/"+ddoc, view, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := []any{}
+	for {
+		var row driver.Row
+		if err := rows.Next(&row); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		r, err := listRow(&row)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return jsonDesignResponse(http.StatusOK, map[string]any{
+		"total_rows": rows.TotalRows(),
+		"offset":     rows.Offset(),
+		"rows":       result,
+	})
+}
+
+func splitPath(path string) []string {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// matchRewriteRules returns the rewritten request for the first rule which
+// matches method and path, or nil if no rule matches. Rule paths may contain
+// ":name" variables, which match a single path segment, and a trailing "*",
+// which matches the remainder of the path. Request parameters are also
+// available as variables.
+func matchRewriteRules(rules []rewriteRule, method, path string, params map[string]any) *js.RewriteResult {
+	pathSegments := splitPath(path)
+	for _, rule := range rules {
+		if rule.Method != "" && rule.Method != "*" && !strings.EqualFold(rule.Method, method) {
+			continue
+		}
+		vars := make(map[string]any, len(params))
+		for k, v := range params {
+			vars[k] = v
+		}
+		rest, ok := matchRewritePath(splitPath(rule.From), pathSegments, vars)
+		if !ok {
+			continue
+		}
+		to := splitPath(rule.To)
+		for i, s := range to {
+			switch {
+			case s == "*":
+				to[i] = rest
+			case strings.HasPrefix(s, ":"):
+				if v, ok := vars[s[1:]]; ok {
+					to[i] = rewriteString(v)
+				}
+			}
+		}
+		result := &js.RewriteResult{Path: strings.Join(to, "/"), Query: map[string]any{}}
+		for k, v := range params {
+			result.Query[k] = v
+		}
+		for k, v := range rule.Query {
+			result.Query[k] = rewriteQueryValue(v, vars, rest)
+		}
+		return result
+	}
+	return nil
+}
+
+// matchRewritePath matches path against the from segments of a rule,
+// populating vars, and returning the remainder matched by "*".
+func matchRewritePath(from, path []string, vars map[string]any) (string, bool) {
+	for i, s := range from {
+		if s == "*" {
+			return strings.Join(path[i:], "/"), true
+		}
+		if i >= len(path) {
+			return "", false
+		}
+		if strings.HasPrefix(s, ":") {
+			vars[s[1:]] = path[i]
+			continue
+		}
+		if s != path[i] {
+			return "", false
+		}
+	}
+	return "", len(from) == len(path)
+}
+
+// rewriteQueryValue substitutes variables in a rule's query value, which may
+// be a string, or an array containing variables.
+func rewriteQueryValue(v any, vars map[string]any, rest string) any {
+	switch t := v.(type) {
+	case string:
+		if t == "*" {
+			return rest
+		}
+		if strings.HasPrefix(t, ":") {
+			if val, ok := vars[t[1:]]; ok {
+				return val
+			}
+		}
+		return t
+	case []any:
+		result := make([]any, len(t))
+		for i, item := range t {
+			result[i] = rewriteQueryValue(item, vars, rest)
+		}
+		return result
+	}
+	return v
+}
+
+// rewriteString converts a variable value to a path segment.
+func rewriteString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	j, _ := json.Marshal(v)
+	return string(j)
+}
+
+// resolveRewritePath resolves path, which is relative to the design
+// document, to path segments relative to the database.
+func resolveRewritePath(ddoc, path string) []string {
+	segments := []string{"_design", ddoc}
+	for _, s := range splitPath(path) {
+		if s == ".." {
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+			continue
+		}
+		segments = append(segments, s)
+	}
+	return segments
+}
@@ -0,0 +1,410 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package sqlite
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+func TestDBDesignFuncs(t *testing.T) {
+	t.Parallel()
+	type test struct {
+		db         *testDB
+		call       func(*testDB) (*driver.DesignResponse, error)
+		wantCode   int
+		wantCT     string
+		wantBody   string
+		wantStatus int
+		wantErr    string
+	}
+
+	// newAppDB returns a database with a design document containing show,
+	// list and view functions, and the provided rewrites.
+	newAppDB := func(t *testing.T, rewrites any) *testDB {
+		d := newDB(t)
+		ddoc := map[string]any{
+			"shows": map[string]any{
+				"post": `function(doc, req) {
+					if (!doc) { return {code: 404, body: "no post " + req.id}; }
+					return "<h1>" + doc.title + "</h1>" + (req.query.format || "");
+				}`,
+				"json": `function(doc, req) { return {json: {path: req.path, method: req.method, db: req.info.db_name}}; }`,
+				"echo": `function(doc, req) { return req.method + " " + req.headers["X-Foo"] + " " + req.body; }`,
+				"formats": `function(doc, req) {
+					provides("html", function() { return "<b>" + doc.title + "</b>"; });
+					provides("json", function() { return {json: {title: doc.title}}; });
+				}`,
+			},
+			"lists": map[string]any{
+				"titles": `function(head, req) {
+					start({headers: {"Content-Type": "text/plain"}});
+					var row;
+					while (row = getRow()) {
+						send(row.key + ":" + row.value + "\n");
+					}
+					return "total:" + head.total_rows;
+				}`,
+			},
+			"updates": map[string]any{
+				"stamp": `function(doc, req) {
+					doc.stamp = JSON.parse(req.body).stamp;
+					return [doc, "stamped " + req.method];
+				}`,
+			},
+			"views": map[string]any{
+				"by_title": map[string]any{
+					"map": `function(doc) { if (doc.title) { emit(doc.title, doc._id); } }`,
+				},
+			},
+		}
+		if rewrites != nil {
+			ddoc["rewrites"] = rewrites
+		}
+		d.tPut("_design/app", ddoc)
+		d.tPut("a", map[string]any{"title": "Alpha"})
+		d.tPut("b", map[string]any{"title": "Bravo"})
+		return d
+	}
+
+	tests := testy.NewTable()
+	tests.Add("show, missing ddoc", test{
+		call: func(d *testDB) (*driver.DesignResponse, error) {
+			return d.Show(context.Background(), "app", "post", "a", mock.NilOption)
+		},
+		wantStatus: http.StatusNotFound,
+		wantErr:    "not found",
+	})
+	tests.Add("show, missing function", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, nil),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Show(context.Background(), "app", "nope", "a", mock.NilOption)
+			},
+			wantStatus: http.StatusNotFound,
+			wantErr:    "missing show function nope on design doc _design/app",
+		}
+	})
+	tests.Add("show", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, nil),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Show(context.Background(), "app", "post", "a", kivik.Param("format", "!"))
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "text/html; charset=utf-8",
+			wantBody: "<h1>Alpha</h1>!",
+		}
+	})
+	tests.Add("show, missing document", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, nil),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Show(context.Background(), "app", "post", "zzz", mock.NilOption)
+			},
+			wantCode: http.StatusNotFound,
+			wantCT:   "text/html; charset=utf-8",
+			wantBody: "no post zzz",
+		}
+	})
+	tests.Add("show, request object", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, nil),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Show(context.Background(), "app", "json", "", mock.NilOption)
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "application/json",
+			wantBody: `{"db":"test","method":"GET","path":["test","_design","app","_show","json"]}`,
+		}
+	})
+	tests.Add("show, provides default", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, nil),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Show(context.Background(), "app", "formats", "a", mock.NilOption)
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "text/html; charset=utf-8",
+			wantBody: "<b>Alpha</b>",
+		}
+	})
+	tests.Add("show, provides format", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, nil),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Show(context.Background(), "app", "formats", "a", kivik.Param("format", "json"))
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "application/json",
+			wantBody: `{"title":"Alpha"}`,
+		}
+	})
+	tests.Add("show, provides unsupported format", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, nil),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Show(context.Background(), "app", "formats", "a", kivik.Param("format", "xml"))
+			},
+			wantStatus: http.StatusNotAcceptable,
+			wantErr:    "Content-Type xml not supported",
+		}
+	})
+	tests.Add("list, missing function", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, nil),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.List(context.Background(), "app", "nope", "by_title", mock.NilOption)
+			},
+			wantStatus: http.StatusNotFound,
+			wantErr:    "missing list function nope on design doc _design/app",
+		}
+	})
+	tests.Add("list", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, nil),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.List(context.Background(), "app", "titles", "by_title", mock.NilOption)
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "text/plain",
+			wantBody: "Alpha:a\nBravo:b\ntotal:2",
+		}
+	})
+	tests.Add("list with view options", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, nil),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.List(context.Background(), "app", "titles", "app/by_title", kivik.Param("descending", true))
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "text/plain",
+			wantBody: "Bravo:b\nAlpha:a\ntotal:2",
+		}
+	})
+	tests.Add("rewrite, no rewrites", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, nil),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodGet, "posts/a", nil, mock.NilOption)
+			},
+			wantStatus: http.StatusNotFound,
+			wantErr:    "missing rewrites on design doc _design/app",
+		}
+	})
+	rules := []any{
+		map[string]any{"from": "/posts/:id", "to": "_show/post/:id", "method": "GET"},
+		map[string]any{"from": "/titles", "to": "_list/titles/by_title"},
+		map[string]any{"from": "/view", "to": "_view/by_title", "query": map[string]any{"key": ":title"}},
+		map[string]any{"from": "/stamp/:id", "to": "_update/stamp/:id", "method": "PUT"},
+		map[string]any{"from": "/new", "to": "../..", "method": "POST"},
+		map[string]any{"from": "/attachment", "to": "../../a/foo.txt"},
+		map[string]any{"from": "/doc/*", "to": "../../*"},
+	}
+	tests.Add("rewrite rule to show", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, rules),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodGet, "posts/b", nil, mock.NilOption)
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "text/html; charset=utf-8",
+			wantBody: "<h1>Bravo</h1>",
+		}
+	})
+	tests.Add("rewrite rule to list", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, rules),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodGet, "titles", nil, kivik.Param("limit", 1))
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "text/plain",
+			wantBody: "Alpha:a\ntotal:2",
+		}
+	})
+	tests.Add("rewrite rule to view with query variable", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, rules),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodGet, "view", nil, kivik.Param("title", "Bravo"))
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "application/json",
+			wantBody: `"rows":[{"id":"b","key":"Bravo","value":"b"}]`,
+		}
+	})
+	tests.Add("rewrite rule to document", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, rules),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodGet, "doc/a", nil, mock.NilOption)
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "application/json",
+			wantBody: `"title":"Alpha"`,
+		}
+	})
+	tests.Add("rewrite, no matching rule", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, rules),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodDelete, "posts/a", nil, mock.NilOption)
+			},
+			wantStatus: http.StatusNotFound,
+			wantErr:    "no rewrite rule matches DELETE /posts/a",
+		}
+	})
+	tests.Add("rewrite function", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, `function(req) {
+				if (req.method === "POST") { return {path: "_show/post/" + JSON.parse(req.body).id}; }
+				return "_show/post/" + req.path[4];
+			}`),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodPost, "anything", strings.NewReader(`{"id":"a"}`), mock.NilOption)
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "text/html; charset=utf-8",
+			wantBody: "<h1>Alpha</h1>",
+		}
+	})
+	tests.Add("rewrite function with body and headers", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, `function(req) { return {path: "_show/echo", body: "replaced", headers: {"X-Foo": "bar"}}; }`),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodPost, "anything", strings.NewReader("original"), mock.NilOption)
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "text/html; charset=utf-8",
+			wantBody: "POST bar replaced",
+		}
+	})
+	tests.Add("rewrite rule to update", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, rules),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodPut, "stamp/a", strings.NewReader(`{"stamp":1}`), mock.NilOption)
+			},
+			wantCode: http.StatusCreated,
+			wantCT:   "text/html; charset=utf-8",
+			wantBody: "stamped PUT",
+		}
+	})
+	tests.Add("rewrite rule to put document", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, rules),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodPut, "doc/c", strings.NewReader(`{"title":"Charlie"}`), mock.NilOption)
+			},
+			wantCode: http.StatusCreated,
+			wantCT:   "application/json",
+			wantBody: `"id":"c"`,
+		}
+	})
+	tests.Add("rewrite rule to put document, invalid JSON", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, rules),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodPut, "doc/c", strings.NewReader(`nope`), mock.NilOption)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    "invalid JSON in rewritten request body",
+		}
+	})
+	tests.Add("rewrite rule to delete document", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, rules),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodDelete, "doc/a", nil, mock.NilOption)
+			},
+			wantStatus: http.StatusConflict,
+			wantErr:    "conflict",
+		}
+	})
+	tests.Add("rewrite rule to create document", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, rules),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodPost, "new", strings.NewReader(`{"title":"Delta"}`), mock.NilOption)
+			},
+			wantCode: http.StatusCreated,
+			wantCT:   "application/json",
+			wantBody: `"ok":true`,
+		}
+	})
+	tests.Add("rewrite rule to unsupported target", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, rules),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodGet, "attachment", nil, mock.NilOption)
+			},
+			wantStatus: http.StatusNotImplemented,
+			wantErr:    "unsupported rewrite target: GET /a/foo.txt",
+		}
+	})
+	tests.Add("rewrite function with method", func(t *testing.T) any {
+		return test{
+			db: newAppDB(t, `function(req) { return {path: "_show/post/" + JSON.parse(req.body).id, method: "GET"}; }`),
+			call: func(d *testDB) (*driver.DesignResponse, error) {
+				return d.Rewrite(context.Background(), "app", http.MethodPost, "anything", strings.NewReader(`{"id":"a"}`), mock.NilOption)
+			},
+			wantCode: http.StatusOK,
+			wantCT:   "text/html; charset=utf-8",
+			wantBody: "<h1>Alpha</h1>",
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		t.Parallel()
+		dbc := tt.db
+		if dbc == nil {
+			dbc = newDB(t)
+		}
+		resp, err := tt.call(dbc)
+		if !testy.ErrorMatchesRE(tt.wantErr, err) {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if status := kivik.HTTPStatus(err); status != tt.wantStatus {
+			t.Errorf("Unexpected status: %d", status)
+		}
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != tt.wantCode {
+			t.Errorf("Unexpected status code: %d", resp.StatusCode)
+		}
+		if resp.ContentType != tt.wantCT {
+			t.Errorf("Unexpected Content-Type: %s", resp.ContentType)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), tt.wantBody) {
+			t.Errorf("Unexpected body: %s, want %s", body, tt.wantBody)
+		}
+	})
+}
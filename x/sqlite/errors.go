@@ -22,6 +22,7 @@ import (
 const (
 	// https://www.sqlite.org/rescode.html
 	codeSQLiteError           = 1
+	codeSQLiteNotADB          = 26
 	codeSQLiteConstraintCheck = 275
 )
 
@@ -63,3 +64,8 @@ func errIsInvalidCollation(err error) bool {
 	}
 	return false
 }
+
+func errIsNotADB(err error) bool {
+	sqliteErr := new(sqlite.Error)
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == codeSQLiteNotADB
+}
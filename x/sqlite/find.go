@@ -162,7 +162,8 @@ func (c indexCandidate) cmp(other indexCandidate) int {
 }
 
 // selectMangoIndex queries the MangoIndexes table and returns the best matching
-// index whose fields cover at least one selector key or all sort fields. When
+// index whose fields cover at least one selector key or all sort fields.
+// Partial indexes are never selected automatically, as in CouchDB. When
 // multiple indexes match, they are ranked by fewest fields, then alphabetical
 // ddoc name. Falls back to allDocsIndex when no match is found.
 func (d *db) selectMangoIndex(ctx context.Context, selector map[string]any, sortFields []options.SortField) (map[string]any, error) {
@@ -178,6 +179,10 @@ func (d *db) selectMangoIndex(ctx context.Context, selector map[string]any, sort
 		if err := rows.Scan(&ddoc, &name, &indexDef); err != nil {
 			return nil, err
 		}
+		if partialFilterSelector(indexDef) != nil {
+			// Partial indexes are only used when requested with use_index.
+			continue
+		}
 		idxFields, err := mango.ExtractIndexFields([]byte(indexDef))
 		if err != nil {
 			continue
@@ -230,15 +235,80 @@ func buildMangoIndexMap(ddoc, name, indexDef string) (map[string]any, error) {
 	if err != nil {
 		return nil, err
 	}
-	anyFields := mapFieldsToAny(normalizedFields)
+	def := map[string]any{"fields": mapFieldsToAny(normalizedFields)}
+	if err := addPartialFilterSelector(def, indexDef); err != nil {
+		return nil, err
+	}
 	return map[string]any{
 		"ddoc": ddoc,
 		"name": name,
 		"type": "json",
-		"def":  map[string]any{"fields": anyFields},
+		"def":  def,
 	}, nil
 }
 
+// partialFilterSelector returns the partial_filter_selector of the stored
+// index definition, or nil if it has none.
+func partialFilterSelector(indexDef string) json.RawMessage {
+	var def struct {
+		PartialFilterSelector json.RawMessage `json:"partial_filter_selector"`
+	}
+	if err := json.Unmarshal([]byte(indexDef), &def); err != nil {
+		return nil
+	}
+	if len(def.PartialFilterSelector) == 0 || string(def.PartialFilterSelector) == "null" {
+		return nil
+	}
+	return def.PartialFilterSelector
+}
+
+// addPartialFilterSelector adds the partial_filter_selector of the stored
+// index definition, if any, to def.
+func addPartialFilterSelector(def map[string]any, indexDef string) error {
+	filter := partialFilterSelector(indexDef)
+	if filter == nil {
+		return nil
+	}
+	var selector map[string]any
+	if err := json.Unmarshal(filter, &selector); err != nil {
+		return err
+	}
+	def["partial_filter_selector"] = selector
+	return nil
+}
+
+// partialFilterQuery returns query with its selector restricted by the
+// partial_filter_selector of the index requested with use_index, so that
+// only documents included in the partial index are returned. It returns nil
+// if no partial index was requested.
+func (d *db) partialFilterQuery(ctx context.Context, query json.RawMessage, ddoc, name string) (json.RawMessage, error) {
+	if ddoc == "" {
+		return nil, nil
+	}
+	where, args := mangoIndexWhere(ddoc, name)
+	var indexDef string
+	err := d.db.QueryRowContext(ctx, d.query(`SELECT index_def FROM {{ .MangoIndexes }} WHERE `)+where+` LIMIT 1`, args...).Scan(&indexDef)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	filter := partialFilterSelector(indexDef)
+	if filter == nil {
+		return nil, nil
+	}
+	var q map[string]json.RawMessage
+	if err := json.Unmarshal(query, &q); err != nil {
+		return nil, &internal.Error{Status: http.StatusBadRequest, Err: err}
+	}
+	q["selector"], err = json.Marshal(map[string][]json.RawMessage{"$and": {q["selector"], filter}})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(q)
+}
+
 // coversSelector returns the number of index fields that are top-level
 // non-operator selector keys. Returns 0 if selector is empty or no overlap.
 func coversSelector(indexFields []string, selector map[string]any) int {
@@ -266,6 +336,16 @@ func (d *db) Find(ctx context.Context, query any, _ driver.Options) (driver.Rows
 	if err != nil {
 		return nil, err
 	}
+	filtered, err := d.partialFilterQuery(ctx, query.(json.RawMessage), vopts.UseIndexDdoc(), vopts.UseIndexName())
+	if err != nil {
+		return nil, err
+	}
+	if filtered != nil {
+		query = filtered
+		if vopts, err = options.FindOptions(query); err != nil {
+			return nil, err
+		}
+	}
 
 	var sortOrderBy string
 	if sortFields := vopts.SortFields(); len(sortFields) > 0 {
@@ -335,6 +415,9 @@ func (d *db) sortOrderByFromIndex(ctx context.Context, sortFields []options.Sort
 		if err := rows.Scan(&indexDef); err != nil {
 			return "", err
 		}
+		if useIndexDdoc == "" && partialFilterSelector(indexDef) != nil {
+			continue
+		}
 		idxFields, err := mango.ExtractIndexFields([]byte(indexDef))
 		if err != nil {
 			continue
@@ -347,6 +347,42 @@ func TestFind(t *testing.T) {
 			},
 		}
 	})
+	tests.Add("use_index, partial index", func(t *testing.T) any {
+		d := newDB(t)
+		err := d.CreateIndex(context.Background(), "_design/myidx", "byName", json.RawMessage(`{"fields":["name"],"partial_filter_selector":{"type":"user"}}`), mock.NilOption)
+		if err != nil {
+			t.Fatalf("CreateIndex failed: %s", err)
+		}
+		revBob := d.tPut("bob", map[string]string{"name": "Bob", "type": "user"})
+		_ = d.tPut("bob2", map[string]string{"name": "Bob", "type": "robot"})
+
+		return test{
+			db:    d,
+			query: `{"selector":{"name":"Bob"},"use_index":["_design/myidx","byName"]}`,
+			want: []rowResult{
+				{Doc: `{"_id":"bob","_rev":"` + revBob + `","name":"Bob","type":"user"}`},
+			},
+		}
+	})
+	tests.Add("partial index is not selected automatically", func(t *testing.T) any {
+		d := newDB(t)
+		err := d.CreateIndex(context.Background(), "_design/myidx", "byName", json.RawMessage(`{"fields":["name"],"partial_filter_selector":{"type":"user"}}`), mock.NilOption)
+		if err != nil {
+			t.Fatalf("CreateIndex failed: %s", err)
+		}
+		revBob := d.tPut("bob", map[string]string{"name": "Bob", "type": "user"})
+		revBob2 := d.tPut("bob2", map[string]string{"name": "Bob", "type": "robot"})
+
+		return test{
+			db:    d,
+			query: `{"selector":{"name":"Bob"}}`,
+			want: []rowResult{
+				{Doc: `{"_id":"bob","_rev":"` + revBob + `","name":"Bob","type":"user"}`},
+				{Doc: `{"_id":"bob2","_rev":"` + revBob2 + `","name":"Bob","type":"robot"}`},
+			},
+			wantWarning: "no matching index found, create an index to optimize query time",
+		}
+	})
 	tests.Add("use_index with sort, wrong index", func(t *testing.T) any {
 		d := newDB(t)
 		err := d.CreateIndex(context.Background(), "_design/nameIdx", "byName", json.RawMessage(`{"fields":["name"]}`), mock.NilOption)
@@ -542,7 +578,7 @@ func TestFindUsesIndexWithUseIndex(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Cleanup(func() { _ = c.(*client).db.Close() })
+	t.Cleanup(func() { _ = c.(*client).Close() })
 
 	if err := c.CreateDB(context.Background(), "test", nil); err != nil {
 		t.Fatal(err)
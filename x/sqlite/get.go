@@ -145,7 +145,11 @@ func (d *db) Get(ctx context.Context, id string, opts driver.Options) (*driver.D
 	if err != nil {
 		return nil, err
 	}
-	atts, err := d.getAttachments(ctx, tx, id, r, attachments, o.AttsSince())
+	encodingInfo, err := o.AttEncodingInfo()
+	if err != nil {
+		return nil, err
+	}
+	atts, err := d.getAttachments(ctx, tx, id, r, attachments, encodingInfo, o.AttsSince())
 	if err != nil {
 		return nil, err
 	}
@@ -196,8 +200,9 @@ func (d *db) conflicts(ctx context.Context, tx dbOrTx, id string, r revision, de
 }
 
 // getAttachments returns the attachments for the given docID and revision.
-// It may return nil if there are no attachments.
-func (d *db) getAttachments(ctx context.Context, tx *sql.Tx, id string, rev revision, includeAttachments bool, since []string) (*attachments, error) {
+// It may return nil if there are no attachments. If encodingInfo is true, the
+// encoding of compressed attachments is included.
+func (d *db) getAttachments(ctx context.Context, tx *sql.Tx, id string, rev revision, includeAttachments, encodingInfo bool, since []string) (*attachments, error) {
 	for _, s := range since {
 		if _, err := parseRev(s); err != nil {
 			return nil, err
@@ -229,14 +234,17 @@ func (d *db) getAttachments(ctx context.Context, tx *sql.Tx, id string, rev revi
 				att.digest,
 				att.length,
 				att.rev_pos,
-				MAX(IIF($4 OR %s, att.data, NULL)) AS data
+				stored.encoding,
+				stored.encoded_length,
+				MAX(IIF($4 OR %s, {{ .AttachmentContent }}, NULL)) AS data
 			FROM {{ .Attachments }} AS att
 			JOIN {{ .AttachmentsBridge }} AS bridge ON att.pk = bridge.pk
+			JOIN {{ .AttachmentData }} AS stored ON stored.digest = att.digest
 			LEFT JOIN ancestors AS a ON att.rev_pos = a.rev
 			WHERE bridge.id = $1
 				AND bridge.rev = $2
 				AND bridge.rev_id = $3
-			GROUP BY att.filename, att.content_type, att.digest, att.length, att.rev_pos
+			GROUP BY att.filename, att.content_type, att.digest, att.length, att.rev_pos, stored.encoding, stored.encoded_length
 		`), sinceQuery)
 	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -248,9 +256,15 @@ func (d *db) getAttachments(ctx context.Context, tx *sql.Tx, id string, rev revi
 	for rows.Next() {
 		var a driver.Attachment
 		var data *[]byte
-		if err := rows.Scan(&a.Filename, &a.ContentType, &digest, &a.Size, &a.RevPos, &data); err != nil {
+		var encoding *string
+		var encodedLength int64
+		if err := rows.Scan(&a.Filename, &a.ContentType, &digest, &a.Size, &a.RevPos, &encoding, &encodedLength, &data); err != nil {
 			return nil, err
 		}
+		if encodingInfo && encoding != nil {
+			a.ContentEncoding = *encoding
+			a.EncodedLength = encodedLength
+		}
 		if data == nil {
 			a.Stub = true
 		} else {
@@ -296,11 +310,13 @@ func (a *attachments) inlineAttachments() map[string]*attachment {
 			panic(err)
 		}
 		newAtt := &attachment{
-			ContentType: att.ContentType,
-			Digest:      digest,
-			Length:      att.Size,
-			RevPos:      int(att.RevPos),
-			Stub:        att.Stub,
+			ContentType:   att.ContentType,
+			Digest:        digest,
+			Length:        att.Size,
+			RevPos:        int(att.RevPos),
+			Stub:          att.Stub,
+			Encoding:      att.ContentEncoding,
+			EncodedLength: att.EncodedLength,
 		}
 		if att.Content != nil {
 			var data bytes.Buffer
@@ -13,11 +13,9 @@
 package sqlite
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"errors"
-	"io"
 	"net/http"
 
 	"github.com/go-kivik/kivik/v4/driver"
@@ -65,7 +63,10 @@ func (d *db) getAttachment(
 	rev revision,
 ) (*driver.Attachment, error) {
 	var att driver.Attachment
-	var data []byte
+	var (
+		pk, encodedLength int64
+		encoding          *string
+	)
 	err := tx.QueryRowContext(ctx, d.query(`
 		SELECT
 			att.filename,
@@ -73,17 +74,25 @@ func (d *db) getAttachment(
 			att.digest,
 			att.length,
 			att.rev_pos,
-			att.data
+			data.pk,
+			data.encoding,
+			data.encoded_length
 		FROM {{ .Attachments }} AS att
 		JOIN {{ .AttachmentsBridge }} AS bridge ON bridge.pk = att.pk
+		JOIN {{ .AttachmentData }} AS data ON data.digest = att.digest
 		WHERE
 			bridge.id = $1
 			AND att.filename = $2
 			AND bridge.rev = $3
 			AND bridge.rev_id = $4	
 	`), docID, filename, rev.rev, rev.id).
-		Scan(&att.Filename, &att.ContentType, &att.Digest, &att.Size, &att.RevPos, &data)
+		Scan(&att.Filename, &att.ContentType, &att.Digest, &att.Size, &att.RevPos, &pk, &encoding, &encodedLength)
+	if err != nil {
+		return nil, err
+	}
 
-	att.Content = io.NopCloser(bytes.NewReader(data))
+	// The content is read after the transaction is committed, one chunk at a
+	// time, so that it need not be held in memory.
+	att.Content, err = d.openAttachmentData(ctx, pk, encodedLength, encoding)
 	return &att, err
 }
@@ -60,6 +60,34 @@ func TestGetIndexes(t *testing.T) {
 		}
 	})
 
+	tests.Add("partial index", func(t *testing.T) interface{} {
+		db := newDB(t)
+		err := db.CreateIndex(context.Background(), "_design/users", "users", json.RawMessage(`{"fields":["name"],"partial_filter_selector":{"type":"user"}}`), mock.NilOption)
+		if err != nil {
+			t.Fatalf("CreateIndex failed: %s", err)
+		}
+		return test{
+			db: db,
+			want: []driver.Index{
+				{
+					DesignDoc:  "",
+					Name:       "_all_docs",
+					Type:       "special",
+					Definition: map[string]interface{}{"fields": []map[string]string{{"_id": "asc"}}},
+				},
+				{
+					DesignDoc: "_design/users",
+					Name:      "users",
+					Type:      "json",
+					Definition: map[string]interface{}{
+						"fields":                  []map[string]string{{"name": "asc"}},
+						"partial_filter_selector": map[string]interface{}{"type": "user"},
+					},
+				},
+			},
+		}
+	})
+
 	tests.Run(t, func(t *testing.T, tt test) {
 		t.Parallel()
 		db := tt.db
@@ -0,0 +1,209 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kivik/kivik/v4"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/x/sqlite/v4/js"
+	"github.com/go-kivik/kivik/x/sqlite/v4/reduce"
+)
+
+// GoLanguage is the design document language which selects Go functions
+// registered with [OptionGoMapFunc], [OptionGoReduceFunc], [OptionGoFilterFunc]
+// and [OptionGoValidateFunc]. In such design documents, each function is
+// given as the ID under which it was registered, rather than as source code.
+// For example:
+//
+//	{
+//	  "language": "go",
+//	  "views": {
+//	    "by_name": {"map": "byName", "reduce": "_count"}
+//	  }
+//	}
+//
+// Built-in reduce functions, such as _count, may be used as usual. As view
+// indexes are tied to the design document revision, changing a registered
+// function does not rebuild existing indexes; update the design document to
+// do so.
+const GoLanguage = "go"
+
+// GoMapFunc is a view map function written in Go. It must be safe for
+// concurrent use, as documents may be mapped concurrently.
+type GoMapFunc func(doc map[string]any, emit func(key, value any))
+
+// GoReduceFunc is a view reduce function written in Go. As with JavaScript,
+// keys is nil when rereduce is true. If an error is returned, it is logged,
+// and the result is null.
+type GoReduceFunc func(keys [][2]any, values []any, rereduce bool) (any, error)
+
+// GoFilterFunc is a changes feed filter function written in Go.
+type GoFilterFunc func(doc, req map[string]any) bool
+
+// GoValidateFunc is a validate_doc_update function written in Go. oldDoc is
+// nil for new documents. Returning an error rejects the update with 403
+// Forbidden, unless the error has an HTTPStatus() int method, in which case
+// that status is used. As with JavaScript, a 401 Unauthorized status may be
+// used to ask the user to log in.
+type GoValidateFunc func(newDoc, oldDoc, userCtx, secObj map[string]any) error
+
+// goFuncs holds the Go functions registered with a client, by ID.
+type goFuncs struct {
+	maps      map[string]GoMapFunc
+	reduces   map[string]GoReduceFunc
+	filters   map[string]GoFilterFunc
+	validates map[string]GoValidateFunc
+}
+
+func newGoFuncs() *goFuncs {
+	return &goFuncs{
+		maps:      map[string]GoMapFunc{},
+		reduces:   map[string]GoReduceFunc{},
+		filters:   map[string]GoFilterFunc{},
+		validates: map[string]GoValidateFunc{},
+	}
+}
+
+type optionGoFunc func(*goFuncs)
+
+var _ kivik.Option = optionGoFunc(nil)
+
+func (o optionGoFunc) Apply(target any) {
+	if client, ok := target.(*client); ok {
+		o(client.goFuncs)
+	}
+}
+
+// OptionGoMapFunc registers a Go map function under id, for use by design
+// documents with the language [GoLanguage].
+func OptionGoMapFunc(id string, fn GoMapFunc) kivik.Option {
+	return optionGoFunc(func(f *goFuncs) { f.maps[id] = fn })
+}
+
+// OptionGoReduceFunc registers a Go reduce function under id, for use by
+// design documents with the language [GoLanguage].
+func OptionGoReduceFunc(id string, fn GoReduceFunc) kivik.Option {
+	return optionGoFunc(func(f *goFuncs) { f.reduces[id] = fn })
+}
+
+// OptionGoFilterFunc registers a Go filter function under id, for use by
+// design documents with the language [GoLanguage].
+func OptionGoFilterFunc(id string, fn GoFilterFunc) kivik.Option {
+	return optionGoFunc(func(f *goFuncs) { f.filters[id] = fn })
+}
+
+// OptionGoValidateFunc registers a Go validate_doc_update function under id,
+// for use by design documents with the language [GoLanguage].
+func OptionGoValidateFunc(id string, fn GoValidateFunc) kivik.Option {
+	return optionGoFunc(func(f *goFuncs) { f.validates[id] = fn })
+}
+
+func errUnknownGoFunc(kind, id string) error {
+	return &internal.Error{Status: http.StatusInternalServerError, Message: fmt.Sprintf("unknown Go %s function: %s", kind, id)}
+}
+
+// recoverPanic converts a panic in a Go design function into an error, as
+// an exception would be for JavaScript.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("panic: %v", r)
+	}
+}
+
+// goMapper maps documents with a Go map function.
+type goMapper struct {
+	fn   GoMapFunc
+	size int
+}
+
+var _ viewMapper = (*goMapper)(nil)
+
+func (f *goFuncs) mapper(id string, size int) (*goMapper, error) {
+	fn, ok := f.maps[id]
+	if !ok {
+		return nil, errUnknownGoFunc("map", id)
+	}
+	return &goMapper{fn: fn, size: size}, nil
+}
+
+func (m *goMapper) Size() int { return m.size }
+
+func (m *goMapper) Map(ctx context.Context, doc any) (emitted []js.Emitted, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer recoverPanic(&err)
+	docMap, _ := doc.(map[string]any)
+	m.fn(docMap, func(key, value any) {
+		emitted = append(emitted, js.Emitted{Key: key, Value: value})
+	})
+	return emitted, nil
+}
+
+var _ reduce.Compiler = (*goFuncs)(nil)
+
+// Reduce returns the Go reduce function registered under id.
+func (f *goFuncs) Reduce(id string) (js.ReduceFunc, error) {
+	fn, ok := f.reduces[id]
+	if !ok {
+		return nil, errUnknownGoFunc("reduce", id)
+	}
+	return func(_ context.Context, keys [][2]any, values []any, rereduce bool) (_ []any, err error) {
+		defer recoverPanic(&err)
+		result, err := fn(keys, values, rereduce)
+		if err != nil {
+			return nil, err
+		}
+		return []any{result}, nil
+	}, nil
+}
+
+// filter returns the Go filter function registered under id.
+func (f *goFuncs) filter(id string) (js.FilterFunc, error) {
+	fn, ok := f.filters[id]
+	if !ok {
+		return nil, errUnknownGoFunc("filter", id)
+	}
+	return func(_ context.Context, doc, req any) (_ bool, err error) {
+		defer recoverPanic(&err)
+		docMap, _ := doc.(map[string]any)
+		reqMap, _ := req.(map[string]any)
+		return fn(docMap, reqMap), nil
+	}, nil
+}
+
+// validate returns the Go validate_doc_update function registered under id.
+func (f *goFuncs) validate(id string) (js.ValidateFunc, error) {
+	fn, ok := f.validates[id]
+	if !ok {
+		return nil, errUnknownGoFunc("validate_doc_update", id)
+	}
+	return func(_ context.Context, newDoc, oldDoc, userCtx, secObj any) (err error) {
+		defer recoverPanic(&err)
+		newMap, _ := newDoc.(map[string]any)
+		oldMap, _ := oldDoc.(map[string]any)
+		userCtxMap, _ := userCtx.(map[string]any)
+		secObjMap, _ := secObj.(map[string]any)
+		err = fn(newMap, oldMap, userCtxMap, secObjMap)
+		var statusErr interface{ HTTPStatus() int }
+		if err != nil && !errors.As(err, &statusErr) {
+			return &internal.Error{Status: http.StatusForbidden, Err: err}
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,268 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+// newGoFuncsDB returns a test database, with the Go design functions
+// registered by opts.
+func newGoFuncsDB(t *testing.T, opts ...kivik.Option) *testDB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:gofuncs%d?mode=memory&cache=shared", dbSeq.Add(1))
+	logs := &bytes.Buffer{}
+	c, err := drv{}.NewClient(dsn, append(multiOptions{OptionLogger(log.New(logs, "", 0))}, opts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.(*client).Close() })
+	if err := c.CreateDB(context.Background(), "test", nil); err != nil {
+		t.Fatal(err)
+	}
+	rawDB, err := c.DB("test", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testDB{DB: rawDB.(DB), t: t, logs: logs}
+}
+
+func TestGoFuncs_views(t *testing.T) {
+	t.Parallel()
+	d := newGoFuncsDB(t,
+		OptionGoMapFunc("byName", func(doc map[string]any, emit func(key, value any)) {
+			if doc["_id"] == "boom" {
+				panic("boom")
+			}
+			if name, ok := doc["name"].(string); ok {
+				emit(name, doc["size"])
+			}
+		}),
+		OptionGoReduceFunc("maxSize", func(_ [][2]any, values []any, _ bool) (any, error) {
+			var max float64
+			for _, v := range values {
+				if n, _ := v.(float64); n > max {
+					max = n
+				}
+			}
+			return max, nil
+		}),
+	)
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"language": GoLanguage,
+		"views": map[string]any{
+			"names": map[string]string{
+				"map":    "byName",
+				"reduce": "_count",
+			},
+			"sizes": map[string]string{
+				"map":    "byName",
+				"reduce": "maxSize",
+			},
+		},
+	})
+	_ = d.tPut("a", map[string]any{"name": "alice", "size": 2})
+	_ = d.tPut("b", map[string]any{"name": "bob", "size": 5})
+	_ = d.tPut("boom", map[string]any{"name": "boom"})
+
+	ctx := context.Background()
+	rows, err := d.Query(ctx, "_design/foo", "_view/names", kivik.Param("reduce", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, rows, []rowResult{
+		{ID: "a", Key: `"alice"`, Value: "2"},
+		{ID: "b", Key: `"bob"`, Value: "5"},
+	})
+	if logs := d.logs.String(); !strings.Contains(logs, "map function threw exception for boom: panic: boom") {
+		t.Errorf("Expected the panic to be logged, got:\n%s", logs)
+	}
+
+	// The index is updated incrementally.
+	_ = d.tPut("c", map[string]any{"name": "carol", "size": 3})
+	rows, err = d.Query(ctx, "_design/foo", "_view/names", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, rows, []rowResult{
+		{Key: "null", Value: "3"},
+	})
+
+	rows, err = d.Query(ctx, "_design/foo", "_view/sizes", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, rows, []rowResult{
+		{Key: "null", Value: "5"},
+	})
+}
+
+func TestGoFuncs_unknown_map_func(t *testing.T) {
+	t.Parallel()
+	d := newGoFuncsDB(t)
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"language": GoLanguage,
+		"views": map[string]any{
+			"bar": map[string]string{"map": "missing"},
+		},
+	})
+
+	_, err := d.Query(context.Background(), "_design/foo", "_view/bar", mock.NilOption)
+	if !testy.ErrorMatches("unknown Go map function: missing", err) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if status := kivik.HTTPStatus(err); status != http.StatusInternalServerError {
+		t.Errorf("Unexpected status: %d", status)
+	}
+}
+
+func TestGoFuncs_filter(t *testing.T) {
+	t.Parallel()
+	d := newGoFuncsDB(t,
+		OptionGoFilterFunc("active", func(doc, _ map[string]any) bool {
+			active, _ := doc["active"].(bool)
+			return active
+		}),
+		OptionGoMapFunc("inactive", func(doc map[string]any, emit func(key, value any)) {
+			if active, _ := doc["active"].(bool); !active {
+				emit(doc["_id"], nil)
+			}
+		}),
+	)
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"language": GoLanguage,
+		"filters": map[string]string{
+			"active": "active",
+		},
+		"views": map[string]any{
+			"inactive": map[string]string{"map": "inactive"},
+		},
+	})
+	_ = d.tPut("a", map[string]any{"active": true})
+	_ = d.tPut("b", map[string]any{"active": false})
+
+	changeIDs := func(opts driver.Options) []string {
+		t.Helper()
+		feed, err := d.Changes(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for {
+			var change driver.Change
+			if err := feed.Next(&change); err != nil {
+				if err == io.EOF {
+					return ids
+				}
+				t.Fatal(err)
+			}
+			ids = append(ids, change.ID)
+		}
+	}
+
+	if d := cmp.Diff([]string{"a"}, changeIDs(kivik.Param("filter", "foo/active"))); d != "" {
+		t.Errorf("Unexpected filtered changes:\n%s", d)
+	}
+	got := changeIDs(kivik.Params(map[string]any{"filter": "_view", "view": "foo/inactive"}))
+	if d := cmp.Diff([]string{"_design/foo", "b"}, got); d != "" {
+		t.Errorf("Unexpected _view filtered changes:\n%s", d)
+	}
+}
+
+type unauthorizedError struct{}
+
+func (unauthorizedError) Error() string   { return "log in first" }
+func (unauthorizedError) HTTPStatus() int { return http.StatusUnauthorized }
+
+func TestGoFuncs_validate(t *testing.T) {
+	t.Parallel()
+	d := newGoFuncsDB(t,
+		OptionGoValidateFunc("requireName", func(newDoc, oldDoc, _, _ map[string]any) error {
+			if newDoc["_deleted"] == true {
+				return nil
+			}
+			if _, ok := newDoc["name"]; !ok {
+				return errors.New("name is required")
+			}
+			if oldDoc != nil && oldDoc["name"] != newDoc["name"] {
+				return unauthorizedError{}
+			}
+			return nil
+		}),
+	)
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"language":            GoLanguage,
+		"validate_doc_update": "requireName",
+	})
+
+	type test struct {
+		doc        map[string]any
+		wantErr    string
+		wantStatus int
+	}
+
+	rev := d.tPut("a", map[string]any{"name": "alice"})
+
+	tests := testy.NewTable()
+	tests.Add("valid", test{
+		doc: map[string]any{"name": "bob"},
+	})
+	tests.Add("plain error is forbidden", test{
+		doc:        map[string]any{},
+		wantErr:    "name is required",
+		wantStatus: http.StatusForbidden,
+	})
+	tests.Add("error with status", test{
+		doc:        map[string]any{"_id": "a", "_rev": rev, "name": "carol"},
+		wantErr:    "log in first",
+		wantStatus: http.StatusUnauthorized,
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		id, _ := tt.doc["_id"].(string)
+		if id == "" {
+			id = "new-" + t.Name()
+		}
+		_, err := d.Put(context.Background(), id, tt.doc, mock.NilOption)
+		if !testy.ErrorMatches(tt.wantErr, err) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if err == nil {
+			return
+		}
+		if status := kivik.HTTPStatus(err); status != tt.wantStatus {
+			t.Errorf("Unexpected status: %d", status)
+		}
+	})
+}
@@ -15,10 +15,12 @@ package sqlite
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
 	"github.com/go-kivik/kivik/v4/x/mango"
 )
 
@@ -55,11 +57,16 @@ func (d *db) GetIndexes(ctx context.Context, _ driver.Options) ([]driver.Index,
 			return nil, err
 		}
 
+		def := map[string]interface{}{"fields": normalizedFields}
+		if err := addPartialFilterSelector(def, indexDef); err != nil {
+			return nil, err
+		}
+
 		indexes = append(indexes, driver.Index{
 			DesignDoc:  ddoc,
 			Name:       name,
 			Type:       "json",
-			Definition: map[string]interface{}{"fields": normalizedFields},
+			Definition: def,
 		})
 	}
 
@@ -70,8 +77,13 @@ func (d *db) GetIndexes(ctx context.Context, _ driver.Options) ([]driver.Index,
 	return indexes, nil
 }
 
-// CreateIndex creates a Mango index.
-func (d *db) CreateIndex(ctx context.Context, ddoc, name string, index any, _ driver.Options) error {
+// CreateIndex creates a Mango index. Only json indexes are supported.
+func (d *db) CreateIndex(ctx context.Context, ddoc, name string, index any, opts driver.Options) error {
+	o := map[string]any{}
+	opts.Apply(o)
+	if indexType, _ := o["type"].(string); indexType != "" && indexType != "json" {
+		return &internal.Error{Status: http.StatusNotImplemented, Message: "only json indexes are supported"}
+	}
 	var indexDef []byte
 	switch t := index.(type) {
 	case json.RawMessage:
@@ -0,0 +1,224 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+// Package querystub implements a minimal query server, for testing the query
+// server protocol without depending on an external interpreter.
+//
+// Rather than a programming language, functions are written in a tiny DSL:
+//
+//   - Map functions name a document field. Documents with the field emit
+//     [doc[field], 1]. The special function "crash" makes the process exit.
+//   - Reduce functions are "sum" or "count".
+//   - Filter functions name a field, which must be truthy for the document to
+//     pass.
+//   - validate_doc_update functions name a field, without which the new
+//     document is forbidden.
+//   - Update functions name a field, which is set to the request body.
+//
+// Map functions which are empty, or contain whitespace, produce a compilation
+// error.
+package querystub
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// EnvVar is the environment variable which, when set to "1", makes a test
+// binary act as the stub query server. See [Main].
+const EnvVar = "KIVIK_QUERY_STUB"
+
+// Main runs the stub query server on stdin and stdout, and exits, if the
+// [EnvVar] environment variable is set. It is intended to be called from
+// TestMain, so that tests can use their own binary as a query server.
+func Main() {
+	if os.Getenv(EnvVar) != "1" {
+		return
+	}
+	if err := Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+type server struct {
+	out   *json.Encoder
+	funcs []string
+	ddocs map[string]map[string]any
+}
+
+// Serve reads commands from in, and writes responses to out, until in is
+// exhausted.
+func Serve(in io.Reader, out io.Writer) error {
+	s := &server{
+		out:   json.NewEncoder(out),
+		ddocs: map[string]map[string]any{},
+	}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var cmd []any
+		if err := json.Unmarshal(scanner.Bytes(), &cmd); err != nil || len(cmd) == 0 {
+			return fmt.Errorf("invalid command: %s", scanner.Text())
+		}
+		resp, err := s.handle(cmd)
+		if err != nil {
+			return err
+		}
+		if err := s.out.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func compilationError(source string) []any {
+	return []any{"error", "compilation_error", fmt.Sprintf("invalid function: %q", source)}
+}
+
+func (s *server) log(msg string) error {
+	return s.out.Encode([]any{"log", msg})
+}
+
+func (s *server) handle(cmd []any) (any, error) {
+	switch cmd[0] {
+	case "reset":
+		s.funcs = nil
+		return true, nil
+	case "add_lib":
+		return true, nil
+	case "add_fun":
+		source, _ := cmd[1].(string)
+		if source == "" || strings.ContainsAny(source, " \t\n") {
+			return compilationError(source), nil
+		}
+		s.funcs = append(s.funcs, source)
+		return true, nil
+	case "map_doc":
+		doc, _ := cmd[1].(map[string]any)
+		results := make([]any, 0, len(s.funcs))
+		for _, field := range s.funcs {
+			if field == "crash" {
+				return nil, fmt.Errorf("crashed while mapping %v", doc["_id"])
+			}
+			if err := s.log(fmt.Sprintf("mapping %v", doc["_id"])); err != nil {
+				return nil, err
+			}
+			emitted := []any{}
+			if value, ok := doc[field]; ok {
+				emitted = append(emitted, []any{value, 1})
+			}
+			results = append(results, emitted)
+		}
+		return results, nil
+	case "reduce", "rereduce":
+		funcs, _ := cmd[1].([]any)
+		values, _ := cmd[2].([]any)
+		if cmd[0] == "reduce" {
+			for i, kv := range values {
+				values[i] = kv.([]any)[1]
+			}
+		}
+		results := make([]any, 0, len(funcs))
+		for _, fn := range funcs {
+			switch {
+			case fn == "sum" || (fn == "count" && cmd[0] == "rereduce"):
+				var sum float64
+				for _, v := range values {
+					n, _ := v.(float64)
+					sum += n
+				}
+				results = append(results, sum)
+			case fn == "count":
+				results = append(results, len(values))
+			default:
+				source, _ := fn.(string)
+				return compilationError(source), nil
+			}
+		}
+		return []any{true, results}, nil
+	case "ddoc":
+		return s.ddoc(cmd[1:])
+	}
+	return []any{"error", "unknown_command", fmt.Sprintf("unknown command: %v", cmd[0])}, nil
+}
+
+func (s *server) ddoc(cmd []any) (any, error) {
+	if cmd[0] == "new" {
+		key, _ := cmd[1].(string)
+		ddoc, _ := cmd[2].(map[string]any)
+		s.ddocs[key] = ddoc
+		return true, nil
+	}
+	key, _ := cmd[0].(string)
+	ddoc, ok := s.ddocs[key]
+	if !ok {
+		return []any{"error", "query_protocol_error", "unknown design doc: " + key}, nil
+	}
+	path, _ := cmd[1].([]any)
+	args, _ := cmd[2].([]any)
+
+	var fn any = ddoc
+	for _, p := range path {
+		obj, _ := fn.(map[string]any)
+		fn = obj[p.(string)]
+	}
+	field, _ := fn.(string)
+	if field == "" {
+		return []any{"error", "not_found", fmt.Sprintf("missing function: %v", path)}, nil
+	}
+
+	switch path[0] {
+	case "filters":
+		docs, _ := args[0].([]any)
+		results := make([]bool, len(docs))
+		for i, doc := range docs {
+			d, _ := doc.(map[string]any)
+			results[i] = truthy(d[field])
+		}
+		return []any{true, results}, nil
+	case "validate_doc_update":
+		newDoc, _ := args[0].(map[string]any)
+		if _, ok := newDoc[field]; !ok {
+			return map[string]any{"forbidden": "missing field " + field}, nil
+		}
+		return 1, nil
+	case "updates":
+		doc, _ := args[0].(map[string]any)
+		req, _ := args[1].(map[string]any)
+		if doc == nil {
+			return []any{"up", nil, map[string]any{"code": 404, "body": "no document"}}, nil
+		}
+		doc[field] = req["body"]
+		return []any{"up", doc, map[string]any{"body": "updated " + field}}, nil
+	}
+	return []any{"error", "unknown_command", fmt.Sprintf("unsupported function: %v", path)}, nil
+}
+
+func truthy(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case float64:
+		return t != 0
+	case string:
+		return t != ""
+	}
+	return true
+}
@@ -0,0 +1,369 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package js
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// WithLog returns a copy of r, in which messages passed to the JavaScript
+// log() function are passed to fn. Without fn, messages are discarded.
+func (r *Runtime) WithLog(fn func(message string)) *Runtime {
+	rt := *r
+	rt.log = fn
+	return &rt
+}
+
+// prelude defines the builtin functions which need no access to Go.
+var prelude = goja.MustCompile("prelude.js", `
+function sum(values) {
+	var rv = 0;
+	for (var i in values) {
+		rv += values[i];
+	}
+	return rv;
+}
+function isArray(obj) {
+	return Array.isArray(obj);
+}
+function toJSON(obj) {
+	return JSON.stringify(obj);
+}
+`, false)
+
+// builtins holds the state of CouchDB's [query server builtin functions] for a
+// single VM.
+//
+// [query server builtin functions]: https://docs.couchdb.org/en/stable/query-server/javascript.html
+type builtins struct {
+	vm  *goja.Runtime
+	log func(string)
+
+	// types maps the keys registered with registerType() to MIME types.
+	types map[string][]string
+
+	// The following are set only for the duration of a show or list
+	// function call. Outside of a call, start() and send() are ignored, and
+	// getRow() returns null.
+	resp     *Response
+	getRow   func() (any, error)
+	rowErr   error
+	provides []provider
+}
+
+// provider is a function registered with provides().
+type provider struct {
+	key string
+	fn  goja.Callable
+}
+
+func newBuiltins(vm *goja.Runtime, log func(string)) (*builtins, error) {
+	b := &builtins{
+		vm:    vm,
+		log:   log,
+		types: make(map[string][]string, len(defaultTypes)),
+	}
+	for key, mimes := range defaultTypes {
+		b.types[key] = mimes
+	}
+	if _, err := vm.RunProgram(prelude); err != nil {
+		return nil, err
+	}
+	for name, fn := range map[string]any{
+		"log":          b.jsLog,
+		"getRow":       b.jsGetRow,
+		"start":        b.jsStart,
+		"send":         b.jsSend,
+		"provides":     b.jsProvides,
+		"registerType": b.jsRegisterType,
+	} {
+		if err := vm.Set(name, fn); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// begin prepares the builtins for a show or list function call, which
+// renders to resp. The returned function must be called when the call is
+// complete.
+func (b *builtins) begin(resp *Response, getRow func() (any, error)) func() {
+	b.resp = resp
+	b.getRow = getRow
+	b.rowErr = nil
+	b.provides = nil
+	return func() {
+		b.resp = nil
+		b.getRow = nil
+		b.provides = nil
+	}
+}
+
+func (b *builtins) jsLog(message goja.Value) {
+	if b.log == nil {
+		return
+	}
+	var msg string
+	switch {
+	case message == nil || goja.IsUndefined(message):
+		msg = "undefined"
+	default:
+		if s, ok := message.Export().(string); ok {
+			msg = s
+			break
+		}
+		j, err := json.Marshal(message.Export())
+		if err != nil {
+			msg = message.String()
+			break
+		}
+		msg = string(j)
+	}
+	b.log(msg)
+}
+
+func (b *builtins) jsGetRow() any {
+	if b.getRow == nil {
+		return nil
+	}
+	row, err := b.getRow()
+	if err != nil {
+		b.rowErr = err
+		panic(b.vm.NewGoError(err))
+	}
+	return row
+}
+
+func (b *builtins) jsStart(v map[string]any) {
+	if b.resp == nil {
+		return
+	}
+	delete(v, "body")
+	delete(v, "json")
+	delete(v, "base64")
+	if err := b.resp.merge(v); err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+}
+
+func (b *builtins) jsSend(chunk string) {
+	if b.resp == nil {
+		return
+	}
+	b.resp.Body = append(b.resp.Body, chunk...)
+}
+
+func (b *builtins) jsProvides(key string, fn goja.Value) {
+	callable, ok := goja.AssertFunction(fn)
+	if !ok {
+		panic(b.vm.NewTypeError("provides: expected a function for " + key))
+	}
+	b.provides = append(b.provides, provider{key: key, fn: callable})
+}
+
+func (b *builtins) jsRegisterType(key string, mimes ...string) {
+	b.types[key] = mimes
+}
+
+// defaultTypes are the MIME types registered by CouchDB's query server.
+var defaultTypes = map[string][]string{
+	"all":              {"*/*"},
+	"text":             {"text/plain; charset=utf-8", "txt"},
+	"html":             {"text/html; charset=utf-8"},
+	"xhtml":            {"application/xhtml+xml", "xhtml"},
+	"xml":              {"application/xml", "text/xml", "application/x-xml"},
+	"js":               {"text/javascript", "application/javascript", "application/x-javascript"},
+	"css":              {"text/css"},
+	"ics":              {"text/calendar"},
+	"csv":              {"text/csv"},
+	"rss":              {"application/rss+xml"},
+	"atom":             {"application/atom+xml"},
+	"yaml":             {"application/x-yaml", "text/yaml"},
+	"multipart_form":   {"multipart/form-data"},
+	"url_encoded_form": {"application/x-www-form-urlencoded"},
+	"json":             {"application/json", "text/x-json"},
+}
+
+// runProvides calls the function registered with provides() which best
+// matches the format query parameter, or else the Accept header, of req, and
+// merges its result into the response. If provides() was not called, it does
+// nothing.
+func (b *builtins) runProvides(req any) error {
+	if len(b.provides) == 0 {
+		return nil
+	}
+	format, accept := requestFormat(req)
+	var (
+		best        *provider
+		contentType string
+	)
+	switch {
+	case format != "":
+		best = b.provider(format)
+	case accept != "":
+		var bestKey string
+		contentType, bestKey = b.bestMatch(accept)
+		best = b.provider(bestKey)
+	default:
+		best = &b.provides[0]
+	}
+	if best == nil {
+		supported := make([]string, 0, len(b.provides))
+		for _, p := range b.provides {
+			if mimes := b.types[p.key]; len(mimes) > 0 {
+				supported = append(supported, strings.Join(mimes, ", "))
+				continue
+			}
+			supported = append(supported, p.key)
+		}
+		wanted := accept
+		if wanted == "" {
+			wanted = format
+		}
+		return &statusError{
+			Status:  http.StatusNotAcceptable,
+			Message: "Content-Type " + wanted + " not supported, try one of: " + strings.Join(supported, ", "),
+		}
+	}
+	if contentType == "" {
+		if mimes := b.types[best.key]; len(mimes) > 0 {
+			contentType = mimes[0]
+		}
+	}
+	result, err := best.fn(goja.Undefined())
+	if err != nil {
+		return err
+	}
+	if err := b.resp.merge(result.Export()); err != nil {
+		return err
+	}
+	if _, ok := b.resp.Headers["Content-Type"]; !ok && contentType != "" {
+		b.resp.Headers["Content-Type"] = contentType
+	}
+	return nil
+}
+
+func (b *builtins) provider(key string) *provider {
+	for i := range b.provides {
+		if b.provides[i].key == key {
+			return &b.provides[i]
+		}
+	}
+	return nil
+}
+
+// bestMatch returns the MIME type, and its key, of the registered providers
+// which best matches the Accept header. Ties are resolved in favor of the
+// provider registered first.
+func (b *builtins) bestMatch(accept string) (mime, key string) {
+	ranges := parseAccept(accept)
+	var bestFitness, bestQ float64 = -1, 0
+	for _, p := range b.provides {
+		for _, m := range b.types[p.key] {
+			fitness, q := fitnessAndQuality(m, ranges)
+			if fitness > bestFitness || (fitness == bestFitness && q > bestQ) {
+				bestFitness, bestQ = fitness, q
+				mime, key = m, p.key
+			}
+		}
+	}
+	if bestQ == 0 {
+		return "", ""
+	}
+	return mime, key
+}
+
+// requestFormat returns the format query parameter, and Accept header, of a
+// request object.
+func requestFormat(req any) (format, accept string) {
+	r, _ := req.(map[string]any)
+	if query, ok := r["query"].(map[string]any); ok {
+		format, _ = query["format"].(string)
+	}
+	if headers, ok := r["headers"].(map[string]any); ok {
+		accept, _ = headers["Accept"].(string)
+	}
+	return format, accept
+}
+
+// mediaRange is a single entry in an Accept header.
+type mediaRange struct {
+	typ, subtype string
+	params       map[string]string
+	q            float64
+}
+
+func parseMediaRange(s string) mediaRange {
+	parts := strings.Split(s, ";")
+	mr := mediaRange{q: 1, params: map[string]string{}}
+	full := strings.TrimSpace(parts[0])
+	if full == "*" {
+		full = "*/*"
+	}
+	mr.typ, mr.subtype, _ = strings.Cut(full, "/")
+	for _, p := range parts[1:] {
+		k, v, _ := strings.Cut(p, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if k == "q" {
+			if _, err := fmt.Sscanf(v, "%g", &mr.q); err != nil || mr.q < 0 || mr.q > 1 {
+				mr.q = 1
+			}
+			continue
+		}
+		mr.params[k] = v
+	}
+	return mr
+}
+
+func parseAccept(accept string) []mediaRange {
+	ranges := make([]mediaRange, 0, strings.Count(accept, ",")+1)
+	for _, r := range strings.Split(accept, ",") {
+		ranges = append(ranges, parseMediaRange(r))
+	}
+	return ranges
+}
+
+// fitnessAndQuality returns how well mime matches the best matching range,
+// along with the quality of that range, following the mimeparse algorithm
+// used by CouchDB.
+func fitnessAndQuality(mime string, ranges []mediaRange) (fitness, q float64) {
+	target := parseMediaRange(mime)
+	fitness = -1
+	for _, r := range ranges {
+		if (r.typ != target.typ && r.typ != "*" && target.typ != "*") ||
+			(r.subtype != target.subtype && r.subtype != "*" && target.subtype != "*") {
+			continue
+		}
+		var f float64
+		if r.typ == target.typ {
+			f += 100
+		}
+		if r.subtype == target.subtype {
+			f += 10
+		}
+		for k, v := range target.params {
+			if r.params[k] == v {
+				f++
+			}
+		}
+		if f > fitness {
+			fitness, q = f, r.q
+		}
+	}
+	return fitness, q
+}
@@ -0,0 +1,265 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package js
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+)
+
+func TestMapBuiltins(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		code     string
+		want     []Emitted
+		wantLogs []string
+	}
+
+	tests := testy.NewTable()
+	tests.Add("sum", test{
+		code: `function(doc) { emit(sum([1, 2, 3.5]), null); }`,
+		want: []Emitted{{Key: 6.5}},
+	})
+	tests.Add("isArray", test{
+		code: `function(doc) { emit(isArray([1]), isArray({})); }`,
+		want: []Emitted{{Key: true, Value: false}},
+	})
+	tests.Add("toJSON", test{
+		code: `function(doc) { emit(toJSON({a: [1, "b"]}), null); }`,
+		want: []Emitted{{Key: `{"a":[1,"b"]}`}},
+	})
+	tests.Add("JSON", test{
+		code: `function(doc) { emit(JSON.parse('{"a":1}').a, null); }`,
+		want: []Emitted{{Key: int64(1)}},
+	})
+	tests.Add("log", test{
+		code: `function(doc) {
+			log("hello");
+			log({a: 1});
+			log(42);
+			log();
+		}`,
+		wantLogs: []string{"hello", `{"a":1}`, "42", "undefined"},
+	})
+	tests.Add("list builtins are defined outside of lists", test{
+		code: `function(doc) {
+			registerType("foo", "application/foo");
+			provides("foo", function() { return "x"; });
+			start({code: 200});
+			send("ignored");
+			emit(getRow(), null);
+		}`,
+		want: []Emitted{{Key: nil}},
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		var (
+			got  []Emitted
+			logs []string
+		)
+		rt := New(0).WithLog(func(msg string) {
+			logs = append(logs, msg)
+		})
+		fn, err := rt.Map(tt.code, func(key, value any) {
+			got = append(got, Emitted{Key: key, Value: value})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fn(context.Background(), map[string]any{}); err != nil {
+			t.Fatal(err)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected emitted values:\n%s", d)
+		}
+		if d := cmp.Diff(tt.wantLogs, logs); d != "" {
+			t.Errorf("Unexpected logs:\n%s", d)
+		}
+	})
+}
+
+func TestLogWithoutLogger(t *testing.T) {
+	t.Parallel()
+
+	fn, err := New(0).Reduce(`function(keys, values) { log("discarded"); return sum(values); }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := fn(context.Background(), nil, []any{1, 2}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]any{int64(3)}, got); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestShowProvides(t *testing.T) {
+	t.Parallel()
+
+	const code = `function(doc, req) {
+		registerType("foo", "application/x-foo");
+		provides("html", function() { return "<p>" + doc.name + "</p>"; });
+		provides("json", function() { return {json: {name: doc.name}}; });
+		provides("foo", function() { return {body: "foo", headers: {"X-Foo": "yes"}}; });
+	}`
+
+	type test struct {
+		req        map[string]any
+		want       *Response
+		wantErr    string
+		wantStatus int
+	}
+
+	tests := testy.NewTable()
+	tests.Add("first provider by default", test{
+		req: map[string]any{},
+		want: &Response{
+			Code:    http.StatusOK,
+			Headers: map[string]string{"Content-Type": "text/html; charset=utf-8"},
+			Body:    []byte("<p>bob</p>"),
+		},
+	})
+	tests.Add("format query parameter", test{
+		req: map[string]any{"query": map[string]any{"format": "json"}},
+		want: &Response{
+			Code:    http.StatusOK,
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    []byte(`{"name":"bob"}`),
+		},
+	})
+	tests.Add("Accept header", test{
+		req: map[string]any{"headers": map[string]any{"Accept": "text/html;q=0.5, application/json"}},
+		want: &Response{
+			Code:    http.StatusOK,
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    []byte(`{"name":"bob"}`),
+		},
+	})
+	tests.Add("Accept wildcard picks first provider", test{
+		req: map[string]any{"headers": map[string]any{"Accept": "*/*"}},
+		want: &Response{
+			Code:    http.StatusOK,
+			Headers: map[string]string{"Content-Type": "text/html; charset=utf-8"},
+			Body:    []byte("<p>bob</p>"),
+		},
+	})
+	tests.Add("registered type", test{
+		req: map[string]any{"headers": map[string]any{"Accept": "application/x-foo"}},
+		want: &Response{
+			Code:    http.StatusOK,
+			Headers: map[string]string{"Content-Type": "application/x-foo", "X-Foo": "yes"},
+			Body:    []byte("foo"),
+		},
+	})
+	tests.Add("not acceptable", test{
+		req:        map[string]any{"headers": map[string]any{"Accept": "image/png"}},
+		wantErr:    "Content-Type image/png not supported, try one of: text/html; charset=utf-8, application/json, text/x-json, application/x-foo",
+		wantStatus: http.StatusNotAcceptable,
+	})
+	tests.Add("unknown format", test{
+		req:        map[string]any{"query": map[string]any{"format": "csv"}},
+		wantErr:    "Content-Type csv not supported, try one of: text/html; charset=utf-8, application/json, text/x-json, application/x-foo",
+		wantStatus: http.StatusNotAcceptable,
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		fn, err := New(0).Show(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := fn(context.Background(), map[string]any{"name": "bob"}, tt.req)
+		if !testy.ErrorMatches(tt.wantErr, err) {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err != nil {
+			var se *statusError
+			if !errors.As(err, &se) || se.HTTPStatus() != tt.wantStatus {
+				t.Errorf("Unexpected status for error: %v", err)
+			}
+			return
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Error(d)
+		}
+	})
+}
+
+func TestShowSend(t *testing.T) {
+	t.Parallel()
+
+	fn, err := New(0).Show(`function(doc, req) {
+		start({headers: {"Content-Type": "text/plain"}});
+		send("Hello, ");
+		return "world";
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := fn(context.Background(), nil, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Response{
+		Code:    http.StatusOK,
+		Headers: map[string]string{"Content-Type": "text/plain"},
+		Body:    []byte("Hello, world"),
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestListProvides(t *testing.T) {
+	t.Parallel()
+
+	fn, err := New(0).List(`function(head, req) {
+		provides("text", function() {
+			var row;
+			while (row = getRow()) {
+				send(row.key + "\n");
+			}
+			return "done";
+		});
+		return "ignored";
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []any{map[string]any{"key": "a"}, map[string]any{"key": "b"}}
+	got, err := fn(context.Background(), map[string]any{}, map[string]any{}, func() (any, error) {
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		row := rows[0]
+		rows = rows[1:]
+		return row, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Response{
+		Code:    http.StatusOK,
+		Headers: map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+		Body:    []byte("a\nb\ndone"),
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Error(d)
+	}
+}
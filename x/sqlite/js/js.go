@@ -15,6 +15,8 @@ package js
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -26,7 +28,13 @@ import (
 
 // Runtime holds configuration for JavaScript execution.
 type Runtime struct {
-	timeout time.Duration
+	timeout  time.Duration
+	poolSize int
+	// ddoc is the design document from which modules are loaded by
+	// require(). See [Runtime.WithDesignDoc].
+	ddoc map[string]any
+	// log receives messages passed to log(). See [Runtime.WithLog].
+	log func(message string)
 }
 
 // New creates a new Runtime with the given timeout. If timeout is zero, no
@@ -51,7 +59,10 @@ func Map(code string, emit func(key, value any)) (MapFunc, error) {
 // Map compiles the provided JavaScript code into a MapFunc, and makes emit
 // available to the JavaScript code.
 func (r *Runtime) Map(code string, emit func(key, value any)) (MapFunc, error) {
-	vm := goja.New()
+	vm, _, err := r.newVM(true)
+	if err != nil {
+		return nil, err
+	}
 
 	if err := vm.Set("emit", emit); err != nil {
 		return nil, err
@@ -79,6 +90,56 @@ func (r *Runtime) Map(code string, emit func(key, value any)) (MapFunc, error) {
 	}, nil
 }
 
+// IndexFunc is the Go representation of a CouchDB [search index function].
+// Exceptions are converted to errors. The context controls cancellation; if
+// the context is cancelled, the VM is interrupted and the context error is
+// returned.
+//
+// [search index function]: https://docs.couchdb.org/en/stable/ddocs/search.html#index-functions
+type IndexFunc func(ctx context.Context, doc any) error
+
+// Index compiles the provided JavaScript code into an IndexFunc, and makes
+// index available to the JavaScript code. It uses a zero-value Runtime (no
+// timeout).
+func Index(code string, index func(field string, value any, options map[string]any)) (IndexFunc, error) {
+	return new(Runtime).Index(code, index)
+}
+
+// Index compiles the provided JavaScript code into an IndexFunc, and makes
+// index available to the JavaScript code. options is nil when the JavaScript
+// code omits it.
+func (r *Runtime) Index(code string, index func(field string, value any, options map[string]any)) (IndexFunc, error) {
+	vm, _, err := r.newVM(true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vm.Set("index", index); err != nil {
+		return nil, err
+	}
+
+	if _, err := vm.RunString("const indexFunc = " + code); err != nil {
+		return nil, err
+	}
+
+	indexFunc, ok := goja.AssertFunction(vm.Get("indexFunc"))
+	if !ok {
+		return nil, fmt.Errorf("expected index to be a function, got %T", vm.Get("indexFunc"))
+	}
+
+	return func(ctx context.Context, doc any) error {
+		if r.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+		}
+		done := watchContext(ctx, vm)
+		defer done()
+		_, err := indexFunc(goja.Undefined(), vm.ToValue(doc))
+		return exception(err)
+	}, nil
+}
+
 // FilterFunc represents a CouchDB [filter function]. Exceptions are converted
 // to errors. The context controls cancellation; if the context is cancelled,
 // the VM is interrupted and the context error is returned.
@@ -94,7 +155,10 @@ func Filter(code string) (FilterFunc, error) {
 
 // Filter compiles the provided JavaScript code into a FilterFunc.
 func (r *Runtime) Filter(code string) (FilterFunc, error) {
-	vm := goja.New()
+	vm, _, err := r.newVM(false)
+	if err != nil {
+		return nil, err
+	}
 	if _, err := vm.RunString("const filter = " + code); err != nil {
 		return nil, fmt.Errorf("failed to compile filter function: %s", err)
 	}
@@ -136,7 +200,10 @@ func Reduce(code string) (ReduceFunc, error) {
 
 // Reduce compiles the provided JavaScript code into a ReduceFunc.
 func (r *Runtime) Reduce(code string) (ReduceFunc, error) {
-	vm := goja.New()
+	vm, _, err := r.newVM(true)
+	if err != nil {
+		return nil, err
+	}
 
 	if _, err := vm.RunString("const reduce = " + code); err != nil {
 		return nil, err
@@ -190,7 +257,10 @@ func Validate(code string) (ValidateFunc, error) {
 
 // Validate compiles the provided JavaScript code into a ValidateFunc.
 func (r *Runtime) Validate(code string) (ValidateFunc, error) {
-	vm := goja.New()
+	vm, _, err := r.newVM(false)
+	if err != nil {
+		return nil, err
+	}
 	if _, err := vm.RunString("const validate = " + code); err != nil {
 		return nil, fmt.Errorf("failed to compile validate function: %s", err)
 	}
@@ -214,18 +284,19 @@ func (r *Runtime) Validate(code string) (ValidateFunc, error) {
 	}, nil
 }
 
-// validateError represents an error returned by a validate_doc_update function.
-type validateError struct {
+// statusError is an error with an associated HTTP status, such as one thrown
+// by a validate_doc_update function.
+type statusError struct {
 	Status  int
 	Message string
 }
 
-func (e *validateError) Error() string {
+func (e *statusError) Error() string {
 	return e.Message
 }
 
 // HTTPStatus returns the HTTP status code associated with the error.
-func (e *validateError) HTTPStatus() int {
+func (e *statusError) HTTPStatus() int {
 	return e.Status
 }
 
@@ -237,16 +308,16 @@ func validateException(err error) error {
 	val := exc.Value().Export()
 	if m, ok := val.(map[string]any); ok {
 		if msg, ok := m["forbidden"]; ok {
-			return &validateError{Status: http.StatusForbidden, Message: fmt.Sprint(msg)}
+			return &statusError{Status: http.StatusForbidden, Message: fmt.Sprint(msg)}
 		}
 		if msg, ok := m["unauthorized"]; ok {
-			return &validateError{Status: http.StatusUnauthorized, Message: fmt.Sprint(msg)}
+			return &statusError{Status: http.StatusUnauthorized, Message: fmt.Sprint(msg)}
 		}
 		for _, v := range m {
-			return &validateError{Status: http.StatusInternalServerError, Message: fmt.Sprint(v)}
+			return &statusError{Status: http.StatusInternalServerError, Message: fmt.Sprint(v)}
 		}
 	}
-	return &validateError{Status: http.StatusInternalServerError, Message: fmt.Sprint(val)}
+	return &statusError{Status: http.StatusInternalServerError, Message: fmt.Sprint(val)}
 }
 
 // UpdateFunc represents a CouchDB [update function]. It accepts a document and
@@ -265,7 +336,10 @@ func Update(code string) (UpdateFunc, error) {
 
 // Update compiles the provided JavaScript code into an UpdateFunc.
 func (r *Runtime) Update(code string) (UpdateFunc, error) {
-	vm := goja.New()
+	vm, _, err := r.newVM(false)
+	if err != nil {
+		return nil, err
+	}
 	if _, err := vm.RunString("const update = " + code); err != nil {
 		return nil, fmt.Errorf("failed to compile update function: %s", err)
 	}
@@ -295,6 +369,340 @@ func (r *Runtime) Update(code string) (UpdateFunc, error) {
 	}, nil
 }
 
+// UpdateResponseFunc represents a CouchDB [update function], like
+// [UpdateFunc], but returns the full response, which the function may
+// return as either a string or a [response object].
+//
+// [update function]: https://docs.couchdb.org/en/stable/ddocs/ddocs.html#update-functions
+// [response object]: https://docs.couchdb.org/en/stable/json-structure.html#response-object
+type UpdateResponseFunc func(ctx context.Context, doc, req any) (any, *Response, error)
+
+// UpdateResponse compiles the provided JavaScript code into an
+// UpdateResponseFunc. It uses a zero-value Runtime (no timeout).
+func UpdateResponse(code string) (UpdateResponseFunc, error) {
+	return new(Runtime).UpdateResponse(code)
+}
+
+// UpdateResponse compiles the provided JavaScript code into an
+// UpdateResponseFunc.
+func (r *Runtime) UpdateResponse(code string) (UpdateResponseFunc, error) {
+	vm, _, err := r.newVM(false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := vm.RunString("const update = " + code); err != nil {
+		return nil, fmt.Errorf("failed to compile update function: %s", err)
+	}
+	updateFunc, ok := goja.AssertFunction(vm.Get("update"))
+	if !ok {
+		panic(fmt.Sprintf("expected update to be a function, got %T", vm.Get("update")))
+	}
+	return func(ctx context.Context, doc, req any) (any, *Response, error) {
+		if r.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+		}
+		done := watchContext(ctx, vm)
+		defer done()
+		result, err := updateFunc(goja.Undefined(), vm.ToValue(doc), vm.ToValue(req))
+		if err != nil {
+			return nil, nil, exception(err)
+		}
+		rv := result.Export()
+		arr, _ := rv.([]any)
+		if len(arr) != 2 {
+			return nil, nil, fmt.Errorf("update function must return [doc, response], got %v", rv)
+		}
+		resp := newResponse()
+		if err := resp.merge(arr[1]); err != nil {
+			return nil, nil, fmt.Errorf("update function returned invalid response: %w", err)
+		}
+		return arr[0], resp, nil
+	}, nil
+}
+
+// Response is the response produced by a show or list function.
+type Response struct {
+	// Code is the HTTP status code. It defaults to 200.
+	Code int
+	// Headers are the response headers set by the function.
+	Headers map[string]string
+	// Body is the response body.
+	Body []byte
+}
+
+// merge merges a value returned by a show or list function into r. The value
+// may be a string, which is appended to the body, or a [response object].
+//
+// [response object]: https://docs.couchdb.org/en/stable/json-structure.html#response-object
+func (r *Response) merge(v any) error {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case string:
+		r.Body = append(r.Body, t...)
+		return nil
+	case map[string]any:
+		if code, ok := t["code"]; ok {
+			switch c := code.(type) {
+			case int64:
+				r.Code = int(c)
+			case float64:
+				r.Code = int(c)
+			default:
+				return fmt.Errorf("invalid response code: %v", code)
+			}
+		}
+		if headers, ok := t["headers"].(map[string]any); ok {
+			for k, v := range headers {
+				r.Headers[k] = fmt.Sprint(v)
+			}
+		}
+		if j, ok := t["json"]; ok {
+			body, err := json.Marshal(j)
+			if err != nil {
+				return err
+			}
+			r.Body = append(r.Body, body...)
+			if _, ok := r.Headers["Content-Type"]; !ok {
+				r.Headers["Content-Type"] = "application/json"
+			}
+		}
+		if b64, ok := t["base64"].(string); ok {
+			body, err := base64.StdEncoding.DecodeString(b64)
+			if err != nil {
+				return fmt.Errorf("invalid base64 response body: %w", err)
+			}
+			r.Body = append(r.Body, body...)
+		}
+		if body, ok := t["body"].(string); ok {
+			r.Body = append(r.Body, body...)
+		}
+		return nil
+	}
+	return fmt.Errorf("expected string or response object, got %T", v)
+}
+
+func newResponse() *Response {
+	return &Response{Code: http.StatusOK, Headers: map[string]string{}}
+}
+
+// NewResponse returns the Response described by v, which may be a string or a
+// [response object], as returned by an update function run outside of this
+// package.
+//
+// [response object]: https://docs.couchdb.org/en/stable/json-structure.html#response-object
+func NewResponse(v any) (*Response, error) {
+	resp := newResponse()
+	if err := resp.merge(v); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ShowFunc represents a CouchDB [show function]. It accepts a document, which
+// may be nil, and a request object, and returns the rendered response. If the
+// show function calls provides(), the output of the provider which best
+// matches the request is appended to the response. The context controls
+// cancellation; if the context is cancelled, the VM is interrupted and the
+// context error is returned.
+//
+// [show function]: https://docs.couchdb.org/en/stable/ddocs/ddocs.html#show-functions
+type ShowFunc func(ctx context.Context, doc, req any) (*Response, error)
+
+// Show compiles the provided JavaScript code into a ShowFunc.
+// It uses a zero-value Runtime (no timeout).
+func Show(code string) (ShowFunc, error) {
+	return new(Runtime).Show(code)
+}
+
+// Show compiles the provided JavaScript code into a ShowFunc.
+func (r *Runtime) Show(code string) (ShowFunc, error) {
+	vm, b, err := r.newVM(false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := vm.RunString("const show = " + code); err != nil {
+		return nil, fmt.Errorf("failed to compile show function: %s", err)
+	}
+	showFunc, ok := goja.AssertFunction(vm.Get("show"))
+	if !ok {
+		panic(fmt.Sprintf("expected show to be a function, got %T", vm.Get("show")))
+	}
+	return func(ctx context.Context, doc, req any) (*Response, error) {
+		if r.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+		}
+		resp := newResponse()
+		defer b.begin(resp, nil)()
+		done := watchContext(ctx, vm)
+		defer done()
+		result, err := showFunc(goja.Undefined(), vm.ToValue(doc), vm.ToValue(req))
+		if err != nil {
+			return nil, exception(err)
+		}
+		if err := resp.merge(result.Export()); err != nil {
+			return nil, fmt.Errorf("show function returned invalid response: %w", err)
+		}
+		if err := b.runProvides(req); err != nil {
+			return nil, exception(err)
+		}
+		return resp, nil
+	}, nil
+}
+
+// ListFunc represents a CouchDB [list function]. It accepts the view head
+// and a request object. The JavaScript function getRow calls the provided
+// getRow function, which should return nil when there are no more rows. If
+// the list function calls provides(), the provider which best matches the
+// request renders the response. The context controls cancellation; if the
+// context is cancelled, the VM is interrupted and the context error is
+// returned.
+//
+// [list function]: https://docs.couchdb.org/en/stable/ddocs/ddocs.html#list-functions
+type ListFunc func(ctx context.Context, head, req any, getRow func() (any, error)) (*Response, error)
+
+// List compiles the provided JavaScript code into a ListFunc.
+// It uses a zero-value Runtime (no timeout).
+func List(code string) (ListFunc, error) {
+	return new(Runtime).List(code)
+}
+
+// List compiles the provided JavaScript code into a ListFunc.
+func (r *Runtime) List(code string) (ListFunc, error) {
+	vm, b, err := r.newVM(false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := vm.RunString("const list = " + code); err != nil {
+		return nil, fmt.Errorf("failed to compile list function: %s", err)
+	}
+	listFunc, ok := goja.AssertFunction(vm.Get("list"))
+	if !ok {
+		panic(fmt.Sprintf("expected list to be a function, got %T", vm.Get("list")))
+	}
+	return func(ctx context.Context, head, req any, getRow func() (any, error)) (*Response, error) {
+		if r.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+		}
+		resp := newResponse()
+		defer b.begin(resp, getRow)()
+		done := watchContext(ctx, vm)
+		defer done()
+		result, err := listFunc(goja.Undefined(), vm.ToValue(head), vm.ToValue(req))
+		if b.rowErr != nil {
+			return nil, b.rowErr
+		}
+		if err != nil {
+			return nil, exception(err)
+		}
+		// As in CouchDB, when provides() is used, the result of the matching
+		// provider replaces that of the list function.
+		if len(b.provides) > 0 {
+			err := b.runProvides(req)
+			if b.rowErr != nil {
+				return nil, b.rowErr
+			}
+			if err != nil {
+				return nil, exception(err)
+			}
+			return resp, nil
+		}
+		if err := resp.merge(result.Export()); err != nil {
+			return nil, fmt.Errorf("list function returned invalid response: %w", err)
+		}
+		return resp, nil
+	}, nil
+}
+
+// RewriteResult is the result of a CouchDB [rewrite function].
+//
+// [rewrite function]: https://docs.couchdb.org/en/stable/api/ddoc/rewrites.html#using-a-stringified-function-for-rewrites
+type RewriteResult struct {
+	// Method is the rewritten request method. If empty, the original method
+	// is used.
+	Method string
+	// Path is the rewritten path, relative to the design document.
+	Path string
+	// Query contains the rewritten query parameters.
+	Query map[string]any
+	// Headers contains the rewritten request headers.
+	Headers map[string]string
+	// Body is the rewritten request body, if set.
+	Body *string
+}
+
+// RewriteFunc represents a CouchDB [rewrite function]. It accepts a request
+// object, and returns the rewritten request. The context controls
+// cancellation; if the context is cancelled, the VM is interrupted and the
+// context error is returned.
+//
+// [rewrite function]: https://docs.couchdb.org/en/stable/api/ddoc/rewrites.html#using-a-stringified-function-for-rewrites
+type RewriteFunc func(ctx context.Context, req any) (*RewriteResult, error)
+
+// Rewrite compiles the provided JavaScript code into a RewriteFunc.
+// It uses a zero-value Runtime (no timeout).
+func Rewrite(code string) (RewriteFunc, error) {
+	return new(Runtime).Rewrite(code)
+}
+
+// Rewrite compiles the provided JavaScript code into a RewriteFunc.
+func (r *Runtime) Rewrite(code string) (RewriteFunc, error) {
+	vm, _, err := r.newVM(false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := vm.RunString("const rewrite = " + code); err != nil {
+		return nil, fmt.Errorf("failed to compile rewrite function: %s", err)
+	}
+	rewriteFunc, ok := goja.AssertFunction(vm.Get("rewrite"))
+	if !ok {
+		panic(fmt.Sprintf("expected rewrite to be a function, got %T", vm.Get("rewrite")))
+	}
+	return func(ctx context.Context, req any) (*RewriteResult, error) {
+		if r.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+		}
+		done := watchContext(ctx, vm)
+		defer done()
+		result, err := rewriteFunc(goja.Undefined(), vm.ToValue(req))
+		if err != nil {
+			return nil, exception(err)
+		}
+		switch t := result.Export().(type) {
+		case string:
+			return &RewriteResult{Path: t}, nil
+		case map[string]any:
+			path, ok := t["path"].(string)
+			if !ok {
+				return nil, fmt.Errorf("rewrite function must return a path, got %v", t["path"])
+			}
+			rr := &RewriteResult{Path: path}
+			rr.Method, _ = t["method"].(string)
+			rr.Query, _ = t["query"].(map[string]any)
+			if headers, ok := t["headers"].(map[string]any); ok {
+				rr.Headers = make(map[string]string, len(headers))
+				for k, v := range headers {
+					rr.Headers[k] = fmt.Sprint(v)
+				}
+			}
+			if body, ok := t["body"].(string); ok {
+				rr.Body = &body
+			}
+			return rr, nil
+		default:
+			return nil, fmt.Errorf("rewrite function must return a string or object, got %T", t)
+		}
+	}, nil
+}
+
 // watchContext arranges for the VM to be interrupted when the context is
 // done. The returned function must be called (via defer) to clean up. It
 // ensures any in-flight interrupt callback has completed, then calls
@@ -14,6 +14,7 @@ package js
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -62,6 +63,67 @@ func TestMap(t *testing.T) {
 	})
 }
 
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	type indexCall struct {
+		Field   string
+		Value   any
+		Options map[string]any
+	}
+
+	type test struct {
+		code           string
+		doc            any
+		want           []indexCall
+		wantCompileErr string
+		wantErr        string
+	}
+
+	tests := testy.NewTable()
+	tests.Add("index with and without options", test{
+		code: `function(doc) {
+			index("default", doc.title);
+			index("year", doc.year, {"store": true, "facet": true});
+		}`,
+		doc: map[string]any{"title": "Moby Dick", "year": 1851},
+		want: []indexCall{
+			{Field: "default", Value: "Moby Dick"},
+			{Field: "year", Value: int64(1851), Options: map[string]any{"store": true, "facet": true}},
+		},
+	})
+	tests.Add("exception", test{
+		code:    `function(doc) { throw("broken"); }`,
+		doc:     map[string]any{},
+		wantErr: "broken",
+	})
+	tests.Add("invalid code", test{
+		code:           `function(doc) {`,
+		wantCompileErr: "SyntaxError",
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		var got []indexCall
+		fn, err := Index(tt.code, func(field string, value any, options map[string]any) {
+			got = append(got, indexCall{Field: field, Value: value, Options: options})
+		})
+		if !testy.ErrorMatchesRE(tt.wantCompileErr, err) {
+			t.Fatalf("Index() error = %v, wantCompileErr /%s/", err, tt.wantCompileErr)
+		}
+		if err != nil {
+			return
+		}
+
+		err = fn(context.Background(), tt.doc)
+		if !testy.ErrorMatchesRE(tt.wantErr, err) {
+			t.Fatalf("fn() error = %v, wantErr /%s/", err, tt.wantErr)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected index calls:\n%s", d)
+		}
+	})
+}
+
 func TestReduce(t *testing.T) {
 	t.Parallel()
 
@@ -471,3 +533,253 @@ func TestRuntimeValidate(t *testing.T) {
 		}
 	})
 }
+
+func TestShow(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		code           string
+		doc            any
+		req            any
+		want           *Response
+		wantCompileErr string
+		wantErr        string
+	}
+
+	tests := testy.NewTable()
+	tests.Add("returns string", test{
+		code: `function(doc, req) { return "<p>" + doc.title + "</p>"; }`,
+		doc:  map[string]any{"title": "Hello"},
+		req:  map[string]any{},
+		want: &Response{Code: 200, Headers: map[string]string{}, Body: []byte("<p>Hello</p>")},
+	})
+	tests.Add("null doc", test{
+		code: `function(doc, req) { return doc === null ? "missing " + req.id : "found"; }`,
+		req:  map[string]any{"id": "foo"},
+		want: &Response{Code: 200, Headers: map[string]string{}, Body: []byte("missing foo")},
+	})
+	tests.Add("response object", test{
+		code: `function(doc, req) { return {code: 201, headers: {"X-Foo": "bar"}, body: "created"}; }`,
+		req:  map[string]any{},
+		want: &Response{Code: 201, Headers: map[string]string{"X-Foo": "bar"}, Body: []byte("created")},
+	})
+	tests.Add("json response", test{
+		code: `function(doc, req) { return {json: {ok: true}}; }`,
+		req:  map[string]any{},
+		want: &Response{Code: 200, Headers: map[string]string{"Content-Type": "application/json"}, Body: []byte(`{"ok":true}`)},
+	})
+	tests.Add("base64 response", test{
+		code: `function(doc, req) { return {base64: "aGVsbG8=", headers: {"Content-Type": "text/plain"}}; }`,
+		req:  map[string]any{},
+		want: &Response{Code: 200, Headers: map[string]string{"Content-Type": "text/plain"}, Body: []byte("hello")},
+	})
+	tests.Add("invalid response", test{
+		code:    `function(doc, req) { return 42; }`,
+		req:     map[string]any{},
+		wantErr: "show function returned invalid response",
+	})
+	tests.Add("compile error", test{
+		code:           `not valid javascript`,
+		wantCompileErr: "failed to compile show function",
+	})
+	tests.Add("JS exception", test{
+		code:    `function(doc, req) { throw "something went wrong"; }`,
+		req:     map[string]any{},
+		wantErr: "something went wrong",
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		fn, err := Show(tt.code)
+		if !testy.ErrorMatchesRE(tt.wantCompileErr, err) {
+			t.Fatalf("Show() error = %v, wantCompileErr /%s/", err, tt.wantCompileErr)
+		}
+		if err != nil {
+			return
+		}
+
+		got, err := fn(context.Background(), tt.doc, tt.req)
+		if !testy.ErrorMatchesRE(tt.wantErr, err) {
+			t.Fatalf("fn() error = %v, wantErr /%s/", err, tt.wantErr)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("response mismatch (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		code           string
+		head           any
+		rows           []any
+		rowErr         error
+		want           *Response
+		wantCompileErr string
+		wantErr        string
+	}
+
+	tests := testy.NewTable()
+	tests.Add("iterates rows", test{
+		code: `function(head, req) {
+			start({headers: {"Content-Type": "text/plain"}});
+			send("total:" + head.total_rows + "\n");
+			var row;
+			while (row = getRow()) {
+				send(row.key + "\n");
+			}
+			return "done";
+		}`,
+		head: map[string]any{"total_rows": 2, "offset": 0},
+		rows: []any{map[string]any{"key": "a"}, map[string]any{"key": "b"}},
+		want: &Response{Code: 200, Headers: map[string]string{"Content-Type": "text/plain"}, Body: []byte("total:2\na\nb\ndone")},
+	})
+	tests.Add("start sets code", test{
+		code: `function(head, req) { start({code: 404}); return "nothing"; }`,
+		want: &Response{Code: 404, Headers: map[string]string{}, Body: []byte("nothing")},
+	})
+	tests.Add("row error", test{
+		code:    `function(head, req) { try { getRow(); } catch (e) {} return "ignored"; }`,
+		rowErr:  errors.New("row failure"),
+		wantErr: "row failure",
+	})
+	tests.Add("compile error", test{
+		code:           `not valid javascript`,
+		wantCompileErr: "failed to compile list function",
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		fn, err := List(tt.code)
+		if !testy.ErrorMatchesRE(tt.wantCompileErr, err) {
+			t.Fatalf("List() error = %v, wantCompileErr /%s/", err, tt.wantCompileErr)
+		}
+		if err != nil {
+			return
+		}
+
+		rows := tt.rows
+		got, err := fn(context.Background(), tt.head, map[string]any{}, func() (any, error) {
+			if tt.rowErr != nil {
+				return nil, tt.rowErr
+			}
+			if len(rows) == 0 {
+				return nil, nil
+			}
+			row := rows[0]
+			rows = rows[1:]
+			return row, nil
+		})
+		if !testy.ErrorMatchesRE(tt.wantErr, err) {
+			t.Fatalf("fn() error = %v, wantErr /%s/", err, tt.wantErr)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("response mismatch (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestRewrite(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		code    string
+		req     any
+		want    *RewriteResult
+		wantErr string
+	}
+	body := `{"a":1}`
+
+	tests := testy.NewTable()
+	tests.Add("returns path", test{
+		code: `function(req) { return "_show/post/" + req.path[4]; }`,
+		req:  map[string]any{"path": []string{"db", "_design", "app", "_rewrite", "foo"}},
+		want: &RewriteResult{Path: "_show/post/foo"},
+	})
+	tests.Add("returns object", test{
+		code: `function(req) { return {path: "_list/posts/all", method: "POST", query: {limit: "5"}, headers: {"X-Foo": "bar"}, body: '{"a":1}'}; }`,
+		req:  map[string]any{},
+		want: &RewriteResult{
+			Method:  "POST",
+			Path:    "_list/posts/all",
+			Query:   map[string]any{"limit": "5"},
+			Headers: map[string]string{"X-Foo": "bar"},
+			Body:    &body,
+		},
+	})
+	tests.Add("object without path", test{
+		code:    `function(req) { return {method: "GET"}; }`,
+		req:     map[string]any{},
+		wantErr: "rewrite function must return a path",
+	})
+	tests.Add("invalid return", test{
+		code:    `function(req) { return 42; }`,
+		req:     map[string]any{},
+		wantErr: "rewrite function must return a string or object",
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		fn, err := Rewrite(tt.code)
+		if err != nil {
+			t.Fatalf("Rewrite() error = %v", err)
+		}
+
+		got, err := fn(context.Background(), tt.req)
+		if !testy.ErrorMatchesRE(tt.wantErr, err) {
+			t.Fatalf("fn() error = %v, wantErr /%s/", err, tt.wantErr)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("result mismatch (-want +got):\n%s", d)
+		}
+	})
+}
+
+func TestUpdateResponse(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		code       string
+		doc        any
+		wantNewDoc any
+		want       *Response
+		wantErr    string
+	}
+
+	tests := testy.NewTable()
+	tests.Add("string response", test{
+		code:       `function(doc, req) { doc.updated = true; return [doc, "OK"]; }`,
+		doc:        map[string]any{"_id": "foo"},
+		wantNewDoc: map[string]any{"_id": "foo", "updated": true},
+		want:       &Response{Code: 200, Headers: map[string]string{}, Body: []byte("OK")},
+	})
+	tests.Add("response object", test{
+		code: `function(doc, req) { return [null, {code: 202, headers: {"X-Foo": "bar"}, json: {ok: true}}]; }`,
+		want: &Response{Code: 202, Headers: map[string]string{"X-Foo": "bar", "Content-Type": "application/json"}, Body: []byte(`{"ok":true}`)},
+	})
+	tests.Add("invalid response", test{
+		code:    `function(doc, req) { return [doc, 42]; }`,
+		wantErr: "update function returned invalid response",
+	})
+	tests.Add("returns non-array", test{
+		code:    `function(doc, req) { return "oops"; }`,
+		wantErr: `update function must return \[doc, response\]`,
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		fn, err := UpdateResponse(tt.code)
+		if err != nil {
+			t.Fatalf("UpdateResponse() error = %v", err)
+		}
+
+		gotNewDoc, got, err := fn(context.Background(), tt.doc, map[string]any{})
+		if !testy.ErrorMatchesRE(tt.wantErr, err) {
+			t.Fatalf("fn() error = %v, wantErr /%s/", err, tt.wantErr)
+		}
+		if d := cmp.Diff(tt.wantNewDoc, gotNewDoc); d != "" {
+			t.Errorf("newDoc mismatch (-want +got):\n%s", d)
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("response mismatch (-want +got):\n%s", d)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package js
+
+import (
+	"context"
+	"time"
+)
+
+// NewPool creates a new Runtime with the given timeout, which compiles pooled
+// functions, such as [Runtime.MapPool], into up to size VMs. A size less than
+// 1 is treated as 1.
+func NewPool(timeout time.Duration, size int) *Runtime {
+	return &Runtime{timeout: timeout, poolSize: size}
+}
+
+// PoolSize returns the maximum number of VMs used by pooled functions.
+func (r *Runtime) PoolSize() int {
+	if r.poolSize < 1 {
+		return 1
+	}
+	return r.poolSize
+}
+
+// Emitted is a single key/value pair emitted by a map function.
+type Emitted struct {
+	Key, Value any
+}
+
+// MapPool runs a CouchDB map function in a pool of VMs. Unlike a [MapFunc], a
+// MapPool may be used concurrently by multiple goroutines.
+type MapPool struct {
+	code string
+	rt   *Runtime
+	// sem limits the number of VMs in use, and idle holds compiled VMs
+	// which are not in use.
+	sem  chan struct{}
+	idle chan *pooledMap
+}
+
+type pooledMap struct {
+	fn      MapFunc
+	emitted []Emitted
+}
+
+// MapPool compiles the provided JavaScript code into a MapPool of up to
+// [Runtime.PoolSize] VMs. The code is compiled into one VM immediately, to
+// report any errors; further VMs are compiled as needed.
+func (r *Runtime) MapPool(code string) (*MapPool, error) {
+	p := &MapPool{
+		code: code,
+		rt:   r,
+		sem:  make(chan struct{}, r.PoolSize()),
+		idle: make(chan *pooledMap, r.PoolSize()),
+	}
+	vm, err := p.compile()
+	if err != nil {
+		return nil, err
+	}
+	p.idle <- vm
+	return p, nil
+}
+
+func (p *MapPool) compile() (*pooledMap, error) {
+	vm := &pooledMap{}
+	fn, err := p.rt.Map(p.code, func(key, value any) {
+		vm.emitted = append(vm.emitted, Emitted{Key: key, Value: value})
+	})
+	if err != nil {
+		return nil, err
+	}
+	vm.fn = fn
+	return vm, nil
+}
+
+// Size returns the maximum number of documents mapped concurrently.
+func (p *MapPool) Size() int {
+	return cap(p.sem)
+}
+
+// Map runs the map function against doc, on the first available VM, and
+// returns the emitted key/value pairs. It blocks until a VM is available, or
+// ctx is cancelled.
+func (p *MapPool) Map(ctx context.Context, doc any) ([]Emitted, error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-p.sem }()
+
+	var vm *pooledMap
+	select {
+	case vm = <-p.idle:
+	default:
+		var err error
+		if vm, err = p.compile(); err != nil {
+			return nil, err
+		}
+	}
+	defer func() { p.idle <- vm }()
+
+	vm.emitted = nil
+	err := vm.fn(ctx, doc)
+	emitted := vm.emitted
+	vm.emitted = nil
+	return emitted, err
+}
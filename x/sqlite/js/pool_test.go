@@ -0,0 +1,131 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package js
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+)
+
+func TestMapPool(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid code", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewPool(0, 4).MapPool(`function(doc) {`)
+		if !testy.ErrorMatchesRE("SyntaxError", err) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		t.Parallel()
+		for size, want := range map[int]int{-1: 1, 0: 1, 1: 1, 4: 4} {
+			pool, err := NewPool(0, size).MapPool(`function(doc) {}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pool.Size(); got != want {
+				t.Errorf("NewPool(%d) size = %d, want %d", size, got, want)
+			}
+		}
+	})
+
+	t.Run("exception", func(t *testing.T) {
+		t.Parallel()
+		pool, err := NewPool(0, 1).MapPool(`function(doc) { emit(doc.n, null); if (doc.fail) { throw("broken"); } }`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = pool.Map(context.Background(), map[string]any{"n": 1, "fail": true})
+		if !testy.ErrorMatchesRE("^broken", err) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		// The partial output of the failed call must not leak into the next.
+		emitted, err := pool.Map(context.Background(), map[string]any{"n": 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := cmp.Diff([]Emitted{{Key: int64(2)}}, emitted); d != "" {
+			t.Error(d)
+		}
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		t.Parallel()
+		pool, err := NewPool(0, 4).MapPool(`function(doc) {
+			for (var i = 0; i < doc.n; i++) { emit(doc.n, i); }
+		}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		const docs = 50
+		results := make([][]Emitted, docs)
+		errs := make([]error, docs)
+		var wg sync.WaitGroup
+		for n := 0; n < docs; n++ {
+			wg.Add(1)
+			go func(n int) {
+				defer wg.Done()
+				results[n], errs[n] = pool.Map(context.Background(), map[string]any{"n": n})
+			}(n)
+		}
+		wg.Wait()
+		for n := 0; n < docs; n++ {
+			if errs[n] != nil {
+				t.Fatalf("doc %d: %s", n, errs[n])
+			}
+			var want []Emitted
+			for i := 0; i < n; i++ {
+				want = append(want, Emitted{Key: int64(n), Value: int64(i)})
+			}
+			if d := cmp.Diff(want, results[n]); d != "" {
+				t.Errorf("doc %d:\n%s", n, d)
+			}
+		}
+	})
+
+	t.Run("cancelled while waiting for a VM", func(t *testing.T) {
+		t.Parallel()
+		pool, err := NewPool(0, 1).MapPool(`function(doc) { if (doc.loop) { while(true) {} } }`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loopCtx, cancelLoop := context.WithCancel(context.Background())
+		defer cancelLoop()
+		started := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			close(started)
+			_, _ = pool.Map(loopCtx, map[string]any{"loop": true})
+		}()
+		<-started
+		time.Sleep(10 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = pool.Map(ctx, map[string]any{})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		cancelLoop()
+		<-done
+	})
+}
@@ -0,0 +1,204 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package js
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// WithDesignDoc returns a copy of r, in which compiled functions may load
+// [CommonJS modules] stored in ddoc with require(). Map functions may only
+// load modules stored under views.lib, as in CouchDB.
+//
+// [CommonJS modules]: https://docs.couchdb.org/en/stable/query-server/javascript.html#commonjs-modules
+func (r *Runtime) WithDesignDoc(ddoc map[string]any) *Runtime {
+	rt := *r
+	rt.ddoc = ddoc
+	return &rt
+}
+
+// newVM returns a new VM, with CouchDB's builtin functions installed. If r has
+// a design document, require() is also made available to the VM. When views
+// is true, only modules stored under views.lib may be loaded.
+func (r *Runtime) newVM(views bool) (*goja.Runtime, *builtins, error) {
+	vm := goja.New()
+	b, err := newBuiltins(vm, r.log)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.ddoc == nil {
+		return vm, b, nil
+	}
+	root := r.ddoc
+	if views {
+		root = map[string]any{}
+		if v, ok := r.ddoc["views"].(map[string]any); ok {
+			if lib, ok := v["lib"]; ok {
+				root["views"] = map[string]any{"lib": lib}
+			}
+		}
+	}
+	req := &requirer{
+		vm:    vm,
+		root:  root,
+		cache: map[string]*goja.Object{},
+	}
+	if err := vm.Set("require", func(name string) goja.Value {
+		return req.require(name, nil)
+	}); err != nil {
+		return nil, nil, err
+	}
+	return vm, b, nil
+}
+
+// module is a value in the design document, reached while resolving a
+// require() path.
+type module struct {
+	id      string
+	current any
+	parent  *module
+}
+
+// requirer implements CouchDB's require() semantics for a single VM.
+type requirer struct {
+	vm   *goja.Runtime
+	root map[string]any
+	// cache holds the module object of each module which has been loaded,
+	// by id.
+	cache map[string]*goja.Object
+}
+
+// require loads the module name, relative to parent, which is nil for
+// top-level calls, and returns its exports. Errors are thrown as JavaScript
+// exceptions.
+func (q *requirer) require(name string, parent *module) goja.Value {
+	mod, err := resolveModule(strings.Split(name, "/"), parent, q.root)
+	if err != nil {
+		panic(q.vm.NewGoError(err))
+	}
+	if cached, ok := q.cache[mod.id]; ok {
+		return cached.Get("exports")
+	}
+
+	src := mod.current.(string)
+	wrapper, err := q.vm.RunScript(mod.id, "(function (module, exports, require) { "+src+"\n });")
+	if err != nil {
+		panic(q.vm.NewGoError(fmt.Errorf("compilation_error: Module require('%s') raised error %s", name, err)))
+	}
+	fn, ok := goja.AssertFunction(wrapper)
+	if !ok {
+		panic(q.vm.NewGoError(fmt.Errorf("compilation_error: Module require('%s') is not a function", name)))
+	}
+
+	exports := q.vm.NewObject()
+	obj := q.vm.NewObject()
+	_ = obj.Set("id", mod.id)
+	_ = obj.Set("current", src)
+	_ = obj.Set("exports", exports)
+	// Cache the module before running it, so that circular requires receive
+	// the partially populated exports, rather than recursing forever.
+	q.cache[mod.id] = obj
+	child := func(name string) goja.Value {
+		return q.require(name, mod.parent)
+	}
+	if _, err := fn(goja.Undefined(), obj, exports, q.vm.ToValue(child)); err != nil {
+		delete(q.cache, mod.id)
+		// Re-panic, so that exceptions propagate to the caller unchanged,
+		// and interrupts are not caught.
+		panic(err)
+	}
+	return obj.Get("exports")
+}
+
+// resolveModule walks names through the design document. Paths beginning
+// with "." or ".." are relative to parent; all others are relative to root.
+func resolveModule(names []string, parent *module, root map[string]any) (*module, error) {
+	mod := parent
+	for i, name := range names {
+		switch name {
+		case "..":
+			if mod == nil || mod.parent == nil || mod.parent.parent == nil {
+				return nil, noParentError(mod)
+			}
+			id := mod.id
+			if i := strings.LastIndex(id, "/"); i >= 0 {
+				id = id[:i]
+			}
+			mod = &module{
+				id:      id,
+				current: mod.parent.current,
+				parent:  mod.parent.parent,
+			}
+		case ".":
+			if mod == nil || mod.parent == nil {
+				return nil, noParentError(mod)
+			}
+		default:
+			if i == 0 {
+				mod = &module{current: root}
+			}
+			obj, _ := mod.current.(map[string]any)
+			value, ok := obj[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid_require_path: Object has no property %q. %s", name, toJSON(mod.current))
+			}
+			id := name
+			if mod.id != "" {
+				id = mod.id + "/" + name
+			}
+			mod = &module{
+				id:      id,
+				current: value,
+				parent:  mod,
+			}
+		}
+	}
+	if mod == nil {
+		return nil, noParentError(mod)
+	}
+	if _, ok := mod.current.(string); !ok {
+		return nil, fmt.Errorf("invalid_require_path: Must require a JavaScript string, not: %s", typeOf(mod.current))
+	}
+	return mod, nil
+}
+
+func noParentError(mod *module) error {
+	var current any
+	if mod != nil {
+		current = mod.current
+	}
+	return fmt.Errorf("invalid_require_path: Object has no parent %s", toJSON(current))
+}
+
+func toJSON(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// typeOf returns the JavaScript typeof of a JSON value.
+func typeOf(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, int64, int, json.Number:
+		return "number"
+	default:
+		return "object"
+	}
+}
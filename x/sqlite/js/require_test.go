@@ -0,0 +1,181 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package js
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+)
+
+func TestMapRequire(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		ddoc    map[string]any
+		code    string
+		want    []Emitted
+		wantErr string
+	}
+
+	viewsLib := func(lib map[string]any) map[string]any {
+		return map[string]any{
+			"views": map[string]any{"lib": lib},
+		}
+	}
+
+	tests := testy.NewTable()
+	tests.Add("exports", test{
+		ddoc: viewsLib(map[string]any{
+			"math": `exports.double = function(x) { return x * 2; };`,
+		}),
+		code: `function(doc) { emit(require('views/lib/math').double(doc.n), null); }`,
+		want: []Emitted{{Key: int64(6)}},
+	})
+	tests.Add("module.exports", test{
+		ddoc: viewsLib(map[string]any{
+			"greet": `module.exports = function(name) { return "hello " + name; };`,
+		}),
+		code: `function(doc) { emit(require('views/lib/greet')("bob"), null); }`,
+		want: []Emitted{{Key: "hello bob"}},
+	})
+	tests.Add("module id", test{
+		ddoc: viewsLib(map[string]any{
+			"util": map[string]any{
+				"id": `exports.id = module.id;`,
+			},
+		}),
+		code: `function(doc) { emit(require('views/lib/util/id').id, null); }`,
+		want: []Emitted{{Key: "views/lib/util/id"}},
+	})
+	tests.Add("relative paths", test{
+		ddoc: viewsLib(map[string]any{
+			"a": `exports.value = require('./b').value + require('../lib/c').value;`,
+			"b": `exports.value = 1;`,
+			"c": `exports.value = 2;`,
+		}),
+		code: `function(doc) { emit(require('views/lib/a').value, null); }`,
+		want: []Emitted{{Key: int64(3)}},
+	})
+	tests.Add("modules are cached", test{
+		ddoc: viewsLib(map[string]any{
+			"counter": `var n = 0; exports.next = function() { return ++n; };`,
+		}),
+		code: `function(doc) {
+			require('views/lib/counter').next();
+			emit(require('views/lib/counter').next(), null);
+		}`,
+		want: []Emitted{{Key: int64(2)}},
+	})
+	tests.Add("circular require", test{
+		ddoc: viewsLib(map[string]any{
+			"a": `exports.name = "a"; exports.other = require('./b').name;`,
+			"b": `exports.name = "b"; exports.other = require('./a').name;`,
+		}),
+		code: `function(doc) { var a = require('views/lib/a'); emit(a.other, require('views/lib/b').other); }`,
+		want: []Emitted{{Key: "b", Value: "a"}},
+	})
+	tests.Add("map functions may only require views.lib", test{
+		ddoc: map[string]any{
+			"lib":   map[string]any{"x": `exports.x = 1;`},
+			"views": map[string]any{"lib": map[string]any{}},
+		},
+		code:    `function(doc) { require('lib/x'); }`,
+		wantErr: `invalid_require_path: Object has no property "lib"`,
+	})
+	tests.Add("missing module", test{
+		ddoc:    viewsLib(map[string]any{}),
+		code:    `function(doc) { require('views/lib/missing'); }`,
+		wantErr: `invalid_require_path: Object has no property "missing". \{\}`,
+	})
+	tests.Add("not a string", test{
+		ddoc:    viewsLib(map[string]any{"dir": map[string]any{}}),
+		code:    `function(doc) { require('views/lib/dir'); }`,
+		wantErr: "invalid_require_path: Must require a JavaScript string, not: object",
+	})
+	tests.Add("relative top-level require", test{
+		ddoc:    viewsLib(map[string]any{}),
+		code:    `function(doc) { require('./foo'); }`,
+		wantErr: "invalid_require_path: Object has no parent",
+	})
+	tests.Add("syntax error", test{
+		ddoc:    viewsLib(map[string]any{"bad": `exports.x = ;`}),
+		code:    `function(doc) { require('views/lib/bad'); }`,
+		wantErr: `compilation_error: Module require\('views/lib/bad'\) raised error`,
+	})
+	tests.Add("exception in module", test{
+		ddoc:    viewsLib(map[string]any{"bad": `throw("broken");`}),
+		code:    `function(doc) { require('views/lib/bad'); }`,
+		wantErr: "^broken",
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		var got []Emitted
+		fn, err := New(0).WithDesignDoc(tt.ddoc).Map(tt.code, func(key, value any) {
+			got = append(got, Emitted{Key: key, Value: value})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fn(context.Background(), map[string]any{"n": 3})
+		if !testy.ErrorMatchesRE(tt.wantErr, err) {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err != nil {
+			return
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Error(d)
+		}
+	})
+}
+
+func TestShowRequire(t *testing.T) {
+	t.Parallel()
+
+	ddoc := map[string]any{
+		"lib": map[string]any{
+			"templates": map[string]any{
+				"hello": `exports.render = function(doc) { return "Hello, " + doc.name; };`,
+			},
+		},
+	}
+	fn, err := New(0).WithDesignDoc(ddoc).Show(`function(doc, req) {
+		return require('lib/templates/hello').render(doc);
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := fn(context.Background(), map[string]any{"name": "bob"}, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(resp.Body), "Hello, bob"; got != want {
+		t.Errorf("Unexpected body: %q, want %q", got, want)
+	}
+}
+
+func TestNoDesignDoc(t *testing.T) {
+	t.Parallel()
+
+	fn, err := New(0).Map(`function(doc) { require('views/lib/x'); }`, func(any, any) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fn(context.Background(), map[string]any{})
+	if !testy.ErrorMatchesRE("ReferenceError: require is not defined", err) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -225,6 +225,14 @@ func (m md5sum) MarshalText() ([]byte, error) {
 	return b, nil
 }
 
+// UnmarshalText parses a digest in the format produced by
+// [md5sum.MarshalText], such as is included in attachment stubs.
+func (m *md5sum) UnmarshalText(text []byte) error {
+	var err error
+	*m, err = parseDigest(string(text))
+	return err
+}
+
 func (m md5sum) Bytes() []byte {
 	return m[:]
 }
@@ -240,11 +248,18 @@ type attachment struct {
 	Length      int64  `json:"length"`
 	RevPos      int    `json:"revpos"`
 	Stub        bool   `json:"stub,omitempty"`
+	// Encoding and EncodedLength describe compressed storage, and are set
+	// only when the att_encoding_info option is requested.
+	Encoding      string `json:"encoding,omitempty"`
+	EncodedLength int64  `json:"encoded_length,omitempty"`
 
 	// Data is the raw JSON representation of the attachment data. It is decoded
 	// into Content by the [attachment.calculate] method.
 	Data    json.RawMessage `json:"data,omitempty"`
 	Content []byte          `json:"-"`
+	// stored is true when the content has already been stored, by
+	// [db.storeAttachmentData], and Digest and Length are set.
+	stored bool
 }
 
 func (a *attachment) MarshalJSON() ([]byte, error) {
@@ -266,6 +281,14 @@ func (a *attachment) MarshalJSON() ([]byte, error) {
 	return json.Marshal(alias)
 }
 
+// contentType returns the attachment's content type, or the default.
+func (a *attachment) contentType() string {
+	if a.ContentType == "" {
+		return "application/octet-stream"
+	}
+	return a.ContentType
+}
+
 // calculate calculates the length, digest, and content of the attachment.
 func (a *attachment) calculate(filename string) error {
 	if a.Data == nil && len(a.Content) == 0 {
@@ -302,6 +325,12 @@ func (r *revsInfo) leaf() revision {
 // prepareDoc prepares the doc for insertion. It returns the new docID, rev, and
 // marshaled doc with rev and id removed.
 func prepareDoc(docID string, doc any) (*docData, error) {
+	return prepareDocKeeping(docID, doc, "")
+}
+
+// prepareDocKeeping works like prepareDoc, but keeps the reserved fields whose
+// names begin with keepPrefix.
+func prepareDocKeeping(docID string, doc any, keepPrefix string) (*docData, error) {
 	tmpJSON, err := json.Marshal(doc)
 	if err != nil {
 		return nil, &internal.Error{Status: http.StatusBadRequest, Err: err}
@@ -329,7 +358,7 @@ func prepareDoc(docID string, doc any) (*docData, error) {
 		return nil, &internal.Error{Status: http.StatusBadRequest, Err: err}
 	}
 	for key := range tmp {
-		if strings.HasPrefix(key, "_") {
+		if strings.HasPrefix(key, "_") && (keepPrefix == "" || !strings.HasPrefix(key, keepPrefix)) {
 			delete(tmp, key)
 		}
 	}
@@ -13,6 +13,7 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
@@ -69,8 +70,20 @@ func args(a []driver.NamedValue) string {
 	return strings.Join(result, ", ")
 }
 
+// connectionDSN returns the DSN with which each connection is opened. Foreign
+// keys are enabled with a DSN parameter, as the pragma applies only to the
+// connection which executes it, and connections are pooled.
+func (c *connector) connectionDSN() string {
+	sep := "?"
+	if strings.Contains(c.dsn, "?") {
+		sep = "&"
+	}
+	return c.dsn + sep + "_pragma=foreign_keys(1)"
+}
+
 func (c *connector) Connect() (*sql.DB, error) {
-	db, err := sql.Open("sqlite", c.dsn)
+	dsn := c.connectionDSN()
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -92,14 +105,19 @@ func (c *connector) Connect() (*sql.DB, error) {
 				return query, a, nil
 			},
 		})
-		cn, err := drv.OpenConnector(c.dsn)
+		cn, err := drv.OpenConnector(dsn)
 		if err != nil {
 			return nil, err
 		}
 		db = sql.OpenDB(cn)
 	}
-	_, err = db.Exec("PRAGMA foreign_keys = ON")
-	if err != nil {
+	// Incremental vacuuming can only be enabled before the first table is
+	// created; files created earlier are converted by [db.Compact].
+	if _, err := db.Exec("PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(context.Background(), db); err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 	return db, nil
@@ -0,0 +1,313 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/go-kivik/kivik/v4/driver"
+)
+
+const (
+	// defaultRevsLimit matches CouchDB's default [revs_limit].
+	//
+	// [revs_limit]: https://docs.couchdb.org/en/stable/api/database/misc.html#db-revs-limit
+	defaultRevsLimit = 1000
+	// defaultPurgedInfosLimit matches CouchDB's default [purged_infos_limit].
+	//
+	// [purged_infos_limit]: https://docs.couchdb.org/en/stable/api/database/misc.html#db-purged-infos-limit
+	defaultPurgedInfosLimit = 1000
+
+	// shardRange is the range reported for the single, implicit shard of
+	// a SQLite database.
+	shardRange = "00000000-ffffffff"
+	// nodeName is the node name reported for the single, implicit node. It
+	// matches the name used by a non-clustered Erlang node.
+	nodeName = "nonode@nohost"
+)
+
+var _ driver.DBMaintainer = (*db)(nil)
+
+func (d *db) metadataInt(ctx context.Context, tx queryer, key string, defaultValue int) (int, error) {
+	var value int
+	err := tx.QueryRowContext(ctx, d.query(`
+		SELECT CAST(value AS INTEGER) FROM {{ .Metadata }} WHERE key = $1
+	`), key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return defaultValue, nil
+	}
+	return value, d.errDatabaseNotFound(err)
+}
+
+func (d *db) setMetadata(ctx context.Context, tx *sql.Tx, key, value string) error {
+	_, err := tx.ExecContext(ctx, d.query(`
+		INSERT INTO {{ .Metadata }} (key, value)
+		VALUES ($1, $2)
+		ON CONFLICT(key) DO UPDATE SET value = $2
+	`), key, value)
+	return d.errDatabaseNotFound(err)
+}
+
+func (d *db) RevsLimit(ctx context.Context) (int, error) {
+	return d.metadataInt(ctx, d.db, "revs_limit", defaultRevsLimit)
+}
+
+// SetRevsLimit stores the new limit, and immediately prunes the revision
+// history of all documents to the new limit.
+func (d *db) SetRevsLimit(ctx context.Context, limit int) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := d.setMetadata(ctx, tx, "revs_limit", strconv.Itoa(limit)); err != nil {
+		return err
+	}
+	if err := d.stem(ctx, tx, nil, limit); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (d *db) PurgedInfosLimit(ctx context.Context) (int, error) {
+	return d.metadataInt(ctx, d.db, "purged_infos_limit", defaultPurgedInfosLimit)
+}
+
+func (d *db) SetPurgedInfosLimit(ctx context.Context, limit int) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := d.setMetadata(ctx, tx, "purged_infos_limit", strconv.Itoa(limit)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Shards reports a single shard covering the entire key range, as a SQLite
+// database is never sharded.
+func (d *db) Shards(ctx context.Context) (map[string][]string, error) {
+	if _, err := d.lastSeq(ctx); err != nil {
+		return nil, err
+	}
+	return map[string][]string{
+		shardRange: {nodeName},
+	}, nil
+}
+
+func (d *db) DocShards(ctx context.Context, _ string) (*driver.DocShards, error) {
+	if _, err := d.lastSeq(ctx); err != nil {
+		return nil, err
+	}
+	return &driver.DocShards{
+		Range: shardRange,
+		Nodes: []string{nodeName},
+	}, nil
+}
+
+// SyncShards is a no-op, as there are no shard replicas to synchronize.
+func (d *db) SyncShards(ctx context.Context) error {
+	_, err := d.lastSeq(ctx)
+	return err
+}
+
+// stemRevs prunes the revision history of docID, if the branch ending in
+// leaf, the newly written revision, is longer than the configured revs_limit.
+// Other branches were stemmed when they were written.
+func (d *db) stemRevs(ctx context.Context, tx *sql.Tx, docID string, leaf revision) error {
+	limit, err := d.metadataInt(ctx, tx, "revs_limit", defaultRevsLimit)
+	if err != nil {
+		return err
+	}
+	// The generation number is an upper bound of the branch length, which
+	// saves walking the branch in the common case.
+	if leaf.rev <= limit {
+		return nil
+	}
+	var depth int
+	if err := tx.QueryRowContext(ctx, d.query(`
+		WITH RECURSIVE branch (rev, rev_id, parent_rev, parent_rev_id, depth) AS (
+			SELECT rev, rev_id, parent_rev, parent_rev_id, 1
+			FROM {{ .Revs }}
+			WHERE id = $1 AND rev = $2 AND rev_id = $3
+
+			UNION ALL
+
+			SELECT parent.rev, parent.rev_id, parent.parent_rev, parent.parent_rev_id, branch.depth + 1
+			FROM branch
+			JOIN {{ .Revs }} AS parent
+				ON parent.id = $1
+				AND parent.rev = branch.parent_rev
+				AND parent.rev_id = branch.parent_rev_id
+			WHERE branch.depth <= $4
+		)
+		SELECT COALESCE(MAX(depth), 0) FROM branch
+	`), docID, leaf.rev, leaf.id, limit).Scan(&depth); err != nil {
+		return err
+	}
+	if depth <= limit {
+		return nil
+	}
+	return d.stem(ctx, tx, &docID, limit)
+}
+
+// stemmedRevsCTE selects the revisions to keep: every leaf, and up to limit
+// ($2) ancestors of each leaf. When $1 is not NULL, only the revisions of the
+// named document are considered.
+const stemmedRevsCTE = `
+	WITH RECURSIVE leaves AS (
+		SELECT rev.id, rev.rev, rev.rev_id, rev.parent_rev, rev.parent_rev_id
+		FROM {{ .Revs }} AS rev
+		LEFT JOIN {{ .Revs }} AS child
+			ON child.id = rev.id
+			AND rev.rev = child.parent_rev
+			AND rev.rev_id = child.parent_rev_id
+		WHERE child.id IS NULL
+			AND ($1 IS NULL OR rev.id = $1)
+	),
+	kept (id, rev, rev_id, parent_rev, parent_rev_id, depth) AS (
+		SELECT id, rev, rev_id, parent_rev, parent_rev_id, 1
+		FROM leaves
+
+		UNION
+
+		SELECT parent.id, parent.rev, parent.rev_id, parent.parent_rev, parent.parent_rev_id, kept.depth + 1
+		FROM kept
+		JOIN {{ .Revs }} AS parent
+			ON parent.id = kept.id
+			AND parent.rev = kept.parent_rev
+			AND parent.rev_id = kept.parent_rev_id
+		WHERE kept.depth < $2
+	)
+`
+
+// stem prunes the revision tree of docID, or of all documents if docID is nil,
+// so that no more than limit revisions are tracked for any branch. Document
+// bodies of pruned revisions are removed along with them.
+func (d *db) stem(ctx context.Context, tx *sql.Tx, docID *string, limit int) error {
+	// Stale view map entries may reference pruned revisions. They would be
+	// replaced during the next index update anyway, so remove them now to
+	// satisfy the foreign key constraint.
+	mapTables, err := d.viewMapTables(ctx, tx)
+	if err != nil {
+		return d.errDatabaseNotFound(err)
+	}
+	for _, mapTable := range mapTables {
+		if _, err := tx.ExecContext(ctx, d.ddocQuery(mapTable.ddoc, mapTable.view, mapTable.rev, stemmedRevsCTE+`
+			DELETE FROM {{ .Map }}
+			WHERE ($1 IS NULL OR id = $1)
+				AND (id, rev, rev_id) NOT IN (SELECT id, rev, rev_id FROM kept)
+		`), docID, limit); err != nil {
+			return err
+		}
+	}
+
+	// Collect the pruned revisions before detaching anything, as detaching
+	// changes which revisions the CTE considers leaves.
+	rows, err := tx.QueryContext(ctx, d.query(stemmedRevsCTE+`
+		SELECT id, rev, rev_id
+		FROM {{ .Revs }}
+		WHERE ($1 IS NULL OR id = $1)
+			AND (id, rev, rev_id) NOT IN (SELECT id, rev, rev_id FROM kept)
+	`), docID, limit)
+	if err != nil {
+		return d.errDatabaseNotFound(err)
+	}
+	defer rows.Close()
+	type prunedRev struct {
+		docID string
+		rev   revision
+	}
+	var pruned []prunedRev
+	for rows.Next() {
+		var r prunedRev
+		if err := rows.Scan(&r.docID, &r.rev.rev, &r.rev.id); err != nil {
+			return err
+		}
+		pruned = append(pruned, r)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_ = rows.Close()
+	if len(pruned) == 0 {
+		return nil
+	}
+
+	// Detach the oldest kept revisions from their pruned parents, so the
+	// cascading delete doesn't remove them as well.
+	if _, err := tx.ExecContext(ctx, d.query(stemmedRevsCTE+`
+		UPDATE {{ .Revs }}
+		SET parent_rev = NULL, parent_rev_id = NULL
+		WHERE ($1 IS NULL OR id = $1)
+			AND parent_rev IS NOT NULL
+			AND (id, rev, rev_id) IN (SELECT id, rev, rev_id FROM kept)
+			AND (id, parent_rev, parent_rev_id) NOT IN (SELECT id, rev, rev_id FROM kept)
+	`), docID, limit); err != nil {
+		return d.errDatabaseNotFound(err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, d.query(`
+		DELETE FROM {{ .Revs }}
+		WHERE id = $1 AND rev = $2 AND rev_id = $3
+	`))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range pruned {
+		if _, err := stmt.ExecContext(ctx, r.docID, r.rev.rev, r.rev.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type mapTable struct {
+	ddoc, view, rev string
+}
+
+// viewMapTables returns the identifiers of all view map tables in the
+// database. The rows cursor is closed before returning so that callers can
+// safely modify the Design table afterward.
+func (d *db) viewMapTables(ctx context.Context, tx *sql.Tx) ([]mapTable, error) {
+	rows, err := tx.QueryContext(ctx, d.query(`
+		SELECT id, rev, rev_id, func_name
+		FROM {{ .Design }}
+		WHERE func_type = 'map'
+	`))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []mapTable
+	for rows.Next() {
+		var (
+			table mapTable
+			rev   revision
+		)
+		if err := rows.Scan(&table.ddoc, &rev.rev, &rev.id, &table.view); err != nil {
+			return nil, err
+		}
+		table.rev = rev.String()
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
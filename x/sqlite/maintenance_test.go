@@ -0,0 +1,356 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+func TestDBLimits(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	ctx := context.Background()
+
+	revsLimit, err := d.RevsLimit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revsLimit != defaultRevsLimit {
+		t.Errorf("Unexpected default revs_limit: %d", revsLimit)
+	}
+	purgedInfosLimit, err := d.PurgedInfosLimit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purgedInfosLimit != defaultPurgedInfosLimit {
+		t.Errorf("Unexpected default purged_infos_limit: %d", purgedInfosLimit)
+	}
+
+	if err := d.SetRevsLimit(ctx, 50); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetPurgedInfosLimit(ctx, 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetPurgedInfosLimit(ctx, 30); err != nil {
+		t.Fatal(err)
+	}
+
+	revsLimit, err = d.RevsLimit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revsLimit != 50 {
+		t.Errorf("Unexpected revs_limit: %d", revsLimit)
+	}
+	purgedInfosLimit, err = d.PurgedInfosLimit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purgedInfosLimit != 30 {
+		t.Errorf("Unexpected purged_infos_limit: %d", purgedInfosLimit)
+	}
+}
+
+func TestDBSetRevsLimit(t *testing.T) {
+	t.Parallel()
+	type test struct {
+		db    *testDB
+		limit int
+		// write is called after setting the limit, if not nil.
+		write    func(*testDB)
+		wantRevs []leaf
+	}
+
+	tests := testy.NewTable()
+	tests.Add("history within limit is unchanged", func(t *testing.T) any {
+		d := newDB(t)
+		rev := d.tPut("foo", map[string]string{"foo": "bar"})
+		_ = d.tPut("foo", map[string]string{"foo": "baz"}, kivik.Rev(rev))
+
+		return test{
+			db:    d,
+			limit: 5,
+			wantRevs: []leaf{
+				{ID: "foo", Rev: 1},
+				{ID: "foo", Rev: 2, ParentRev: &[]int{1}[0]},
+			},
+		}
+	})
+	tests.Add("long history is pruned", func(t *testing.T) any {
+		d := newDB(t)
+		rev := d.tPut("foo", map[string]string{"foo": "one"})
+		rev = d.tPut("foo", map[string]string{"foo": "two"}, kivik.Rev(rev))
+		rev = d.tPut("foo", map[string]string{"foo": "three"}, kivik.Rev(rev))
+		rev = d.tPut("foo", map[string]string{"foo": "four"}, kivik.Rev(rev))
+		_ = d.tPut("foo", map[string]string{"foo": "five"}, kivik.Rev(rev))
+		_ = d.tPut("bar", map[string]string{"bar": "one"})
+
+		return test{
+			db:    d,
+			limit: 2,
+			wantRevs: []leaf{
+				{ID: "bar", Rev: 1},
+				{ID: "foo", Rev: 4},
+				{ID: "foo", Rev: 5, ParentRev: &[]int{4}[0]},
+			},
+		}
+	})
+	tests.Add("each conflicting branch keeps its own history", func(t *testing.T) any {
+		d := newDB(t)
+		_ = d.tPut("foo", map[string]any{
+			"version": "one",
+			"_revisions": map[string]any{
+				"start": 3,
+				"ids":   []string{"ccc", "bbb", "aaa"},
+			},
+		}, kivik.Param("new_edits", false))
+		_ = d.tPut("foo", map[string]any{
+			"version": "two",
+			"_revisions": map[string]any{
+				"start": 3,
+				"ids":   []string{"rrr", "qqq", "aaa"},
+			},
+		}, kivik.Param("new_edits", false))
+
+		return test{
+			db:    d,
+			limit: 2,
+			wantRevs: []leaf{
+				{ID: "foo", Rev: 2, RevID: "bbb"},
+				{ID: "foo", Rev: 2, RevID: "qqq"},
+				{ID: "foo", Rev: 3, RevID: "ccc", ParentRev: &[]int{2}[0], ParentRevID: &[]string{"bbb"}[0]},
+				{ID: "foo", Rev: 3, RevID: "rrr", ParentRev: &[]int{2}[0], ParentRevID: &[]string{"qqq"}[0]},
+			},
+		}
+	})
+	tests.Add("subsequent writes are stemmed", func(t *testing.T) any {
+		d := newDB(t)
+		rev := d.tPut("foo", map[string]string{"foo": "one"})
+
+		return test{
+			db:    d,
+			limit: 2,
+			write: func(d *testDB) {
+				rev := d.tPut("foo", map[string]string{"foo": "two"}, kivik.Rev(rev))
+				_ = d.tPut("foo", map[string]string{"foo": "three"}, kivik.Rev(rev))
+			},
+			wantRevs: []leaf{
+				{ID: "foo", Rev: 2},
+				{ID: "foo", Rev: 3, ParentRev: &[]int{2}[0]},
+			},
+		}
+	})
+	tests.Add("replicated writes are stemmed", func(t *testing.T) any {
+		d := newDB(t)
+
+		return test{
+			db:    d,
+			limit: 2,
+			write: func(d *testDB) {
+				_ = d.tPut("foo", map[string]any{
+					"_revisions": map[string]any{
+						"start": 4,
+						"ids":   []string{"ddd", "ccc", "bbb", "aaa"},
+					},
+				}, kivik.Param("new_edits", false))
+			},
+			wantRevs: []leaf{
+				{ID: "foo", Rev: 3, RevID: "ccc"},
+				{ID: "foo", Rev: 4, RevID: "ddd", ParentRev: &[]int{3}[0], ParentRevID: &[]string{"ccc"}[0]},
+			},
+		}
+	})
+	tests.Add("indexed view is updated after pruning", func(t *testing.T) any {
+		d := newDB(t)
+		_ = d.tPut("_design/foo", map[string]any{
+			"views": map[string]any{
+				"bar": map[string]string{
+					"map": `function(doc) { if (doc.foo) { emit(doc.foo, null); } }`,
+				},
+			},
+		})
+		rev := d.tPut("foo", map[string]string{"foo": "one"})
+		rev = d.tPut("foo", map[string]string{"foo": "two"}, kivik.Rev(rev))
+		rows, err := d.Query(context.Background(), "_design/foo", "_view/bar", mock.NilOption)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = rows.Close()
+
+		return test{
+			db:    d,
+			limit: 1,
+			write: func(d *testDB) {
+				_ = d.tPut("foo", map[string]string{"foo": "three"}, kivik.Rev(rev))
+				rows, err := d.Query(context.Background(), "_design/foo", "_view/bar", mock.NilOption)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_ = rows.Close()
+			},
+			wantRevs: []leaf{
+				{ID: "_design/foo", Rev: 1},
+				{ID: "foo", Rev: 3},
+			},
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		t.Parallel()
+		if err := tt.db.SetRevsLimit(context.Background(), tt.limit); err != nil {
+			t.Fatal(err)
+		}
+		if tt.write != nil {
+			tt.write(tt.db)
+		}
+		leaves := readRevisions(t, tt.db.underlying())
+		for i, r := range tt.wantRevs {
+			// allow tests to omit RevID
+			if r.RevID == "" && i < len(leaves) {
+				leaves[i].RevID = ""
+			}
+			if r.ParentRevID == nil && i < len(leaves) {
+				leaves[i].ParentRevID = nil
+			}
+		}
+		if d := cmp.Diff(tt.wantRevs, leaves); d != "" {
+			t.Errorf("Unexpected leaves: %s", d)
+		}
+	})
+}
+
+func TestDBSetRevsLimit_prunedRevNotFound(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	ctx := context.Background()
+	rev1 := d.tPut("foo", map[string]string{"foo": "one"})
+	rev := d.tPut("foo", map[string]string{"foo": "two"}, kivik.Rev(rev1))
+	rev = d.tPut("foo", map[string]string{"foo": "three"}, kivik.Rev(rev))
+
+	if err := d.SetRevsLimit(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := d.Get(ctx, "foo", kivik.Rev(rev1))
+	if status := kivik.HTTPStatus(err); status != http.StatusNotFound {
+		t.Errorf("Unexpected status for pruned rev: %d", status)
+	}
+
+	doc, err := d.Get(ctx, "foo", kivik.Params(map[string]any{"revs": true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Rev       string `json:"_rev"`
+		Revisions struct {
+			Start int      `json:"start"`
+			IDs   []string `json:"ids"`
+		} `json:"_revisions"`
+	}
+	if err := json.NewDecoder(doc.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Rev != rev {
+		t.Errorf("Unexpected rev: %s", got.Rev)
+	}
+	if got.Revisions.Start != 3 || len(got.Revisions.IDs) != 2 {
+		t.Errorf("Unexpected revisions: %+v", got.Revisions)
+	}
+}
+
+func TestDBSetRevsLimit_shortBranchNotStemmed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dsn := fmt.Sprintf("file:shortbranch%d?mode=memory&cache=shared", dbSeq.Add(1))
+	queryLog := &bytes.Buffer{}
+	c, err := drv{}.NewClient(dsn, multiOptions{
+		OptionLogger(log.New(io.Discard, "", 0)),
+		OptionQueryLogger(log.New(queryLog, "", 0)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.(*client).Close() })
+	if err := c.CreateDB(ctx, "test", mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+	d := &testDB{DB: c.(*client).newDB("test"), t: t}
+	if err := d.SetRevsLimit(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+	queryLog.Reset()
+
+	// The generation exceeds the limit, but the stored history does not.
+	rev := d.tPut("foo", map[string]any{
+		"_revisions": map[string]any{
+			"start": 10,
+			"ids":   []string{"jjj", "iii"},
+		},
+	}, kivik.Param("new_edits", false))
+	_ = d.tPut("foo", map[string]string{"foo": "bar"}, kivik.Rev(rev))
+
+	if strings.Contains(queryLog.String(), "WITH RECURSIVE leaves") {
+		t.Error("Expected the revision tree not to be stemmed")
+	}
+}
+
+func TestDBShards(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	ctx := context.Background()
+
+	shards, err := d.Shards(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantShards := map[string][]string{
+		"00000000-ffffffff": {"nonode@nohost"},
+	}
+	if d := cmp.Diff(wantShards, shards); d != "" {
+		t.Errorf("Unexpected shards:\n%s", d)
+	}
+
+	docShards, err := d.DocShards(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDocShards := &driver.DocShards{
+		Range: "00000000-ffffffff",
+		Nodes: []string{"nonode@nohost"},
+	}
+	if d := cmp.Diff(wantDocShards, docShards); d != "" {
+		t.Errorf("Unexpected doc shards:\n%s", d)
+	}
+
+	if err := d.SyncShards(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -36,14 +36,25 @@ func (d *db) OpenRevs(ctx context.Context, docID string, revs []string, opts dri
 	if err != nil {
 		return nil, err
 	}
+	encodingInfo, err := o.AttEncodingInfo()
+	if err != nil {
+		return nil, err
+	}
+	since := o.AttsSince()
+	for _, s := range since {
+		if _, err := parseRev(s); err != nil {
+			return nil, err
+		}
+	}
 	values := make([]string, 0, len(revs))
-	args := make([]any, 6, len(revs)*2+6)
+	args := make([]any, 7, len(revs)*2+len(since)+7)
 	args[0] = docID
 	args[1] = len(revs) == 0 // open_revs=[]
 	args[2] = false
 	args[3] = o.Latest()
 	args[4] = o.Revs()
 	args[5] = attachments
+	args[6] = encodingInfo
 	if len(revs) == 1 && revs[0] == "all" {
 		args[2] = true
 		revs = []string{}
@@ -64,6 +75,22 @@ func (d *db) OpenRevs(ctx context.Context, docID string, revs []string, opts dri
 		values = []string{"(NULL, NULL, NULL)"}
 	}
 
+	// atts_since includes the content of attachments added since any of the
+	// given revisions.
+	sinceQuery := "FALSE"
+	if len(since) > 0 {
+		sinceQuery = fmt.Sprintf(`EXISTS (
+			SELECT 1
+			FROM ancestors AS a
+			WHERE a.id = open_revs.id
+				AND a.rev = att.rev_pos
+				AND a.parent_rev || '-' || a.parent_rev_id IN (%s)
+		)`, placeholders(len(args)+1, len(since)))
+		for _, s := range since {
+			args = append(args, s)
+		}
+	}
+
 	query := fmt.Sprintf(d.query(`
 		WITH
 		RECURSIVE ancestors AS (
@@ -75,7 +102,7 @@ func (d *db) OpenRevs(ctx context.Context, docID string, revs []string, opts dri
 			JOIN ancestors AS a ON c.id = a.id AND c.parent_rev = a.rev AND c.parent_rev_id = a.rev_id
 		),
 		provided_revs (id, rev, rev_id) AS (
-			VALUES %s
+			VALUES %[1]s
 		),
 		open_revs (id, rev, rev_id) AS (
 			-- Provided revs
@@ -147,6 +174,8 @@ func (d *db) OpenRevs(ctx context.Context, docID string, revs []string, opts dri
 			length,
 			digest,
 			rev_pos,
+			encoding,
+			encoded_length,
 			data
 		FROM (
 			SELECT
@@ -163,7 +192,9 @@ func (d *db) OpenRevs(ctx context.Context, docID string, revs []string, opts dri
 				att.length,
 				att.digest,
 				att.rev_pos,
-				IIF($6, att.data, NULL) AS data,
+				IIF($7, stored.encoding, NULL) AS encoding,
+				IIF($7, stored.encoded_length, NULL) AS encoded_length,
+				IIF($6 OR %[2]s, {{ .AttachmentContent }}, NULL) AS data,
 				SUM(CASE WHEN bridge.pk IS NOT NULL THEN 1 ELSE 0 END) OVER (PARTITION BY open_revs.rev, open_revs.rev_id) AS attachment_count,
 				ROW_NUMBER() OVER (PARTITION BY open_revs.rev, open_revs.rev_id) AS row_number
 			FROM open_revs
@@ -171,10 +202,11 @@ func (d *db) OpenRevs(ctx context.Context, docID string, revs []string, opts dri
 			LEFT JOIN ancestors ON $5 AND open_revs.id = ancestors.id AND open_revs.rev = ancestors.rev AND open_revs.rev_id = ancestors.rev_id
 			LEFT JOIN {{ .AttachmentsBridge }} AS bridge ON open_revs.id = bridge.id AND open_revs.rev = bridge.rev AND open_revs.rev_id = bridge.rev_id
 			LEFT JOIN {{ .Attachments }} AS att ON bridge.pk = att.pk
+			LEFT JOIN {{ .AttachmentData }} AS stored ON att.digest = stored.digest
 			ORDER BY open_revs.rev, open_revs.rev_id, parent_rev DESC, parent_rev_id DESC
 		)
-		GROUP BY rev, rev_id, deleted, doc, attachment_count, filename, content_type, length, digest, rev_pos, data
-	`), strings.Join(values, ", "))
+		GROUP BY rev, rev_id, deleted, doc, attachment_count, filename, content_type, length, digest, rev_pos, encoding, encoded_length, data
+	`), strings.Join(values, ", "), sinceQuery)
 	rows, err := d.db.QueryContext(ctx, query, args...) //nolint:rowserrcheck // Err checked in Next
 	if err != nil {
 		return nil, d.errDatabaseNotFound(err)
@@ -234,11 +266,13 @@ func (r *openRevsRows) Next(row *driver.Row) error {
 			length                *int64
 			revPos                *int
 			digest                *md5sum
+			encoding              *string
+			encodedLength         *int64
 			data                  *[]byte
 		)
 		if err := r.rows.Scan(
 			&rowRev, &rowRevID, &rowDeleted, &rowDoc, &ancestors,
-			&attachmentCount, &filename, &contentType, &length, &digest, &revPos, &data,
+			&attachmentCount, &filename, &contentType, &length, &digest, &revPos, &encoding, &encodedLength, &data,
 		); err != nil {
 			return err
 		}
@@ -287,6 +321,10 @@ func (r *openRevsRows) Next(row *driver.Row) error {
 				Length:      *length,
 				RevPos:      *revPos,
 			}
+			if encoding != nil {
+				att.Encoding = *encoding
+				att.EncodedLength = *encodedLength
+			}
 			if data == nil {
 				att.Stub = true
 			} else {
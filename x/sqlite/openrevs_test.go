@@ -315,6 +315,37 @@ func TestDBOpenRevs(t *testing.T) {
 			},
 		}
 	})
+	tests.Add("atts_since includes only newer attachment data", func(t *testing.T) any {
+		d := newDB(t)
+		docID := "foo"
+		rev1 := d.tPut(docID, map[string]any{
+			"_attachments": newAttachments().
+				add("att.txt", "hello, world"),
+		})
+		rev2 := d.tPut(docID, map[string]any{
+			"_rev": rev1,
+			"_attachments": newAttachments().
+				addStub("att.txt").
+				add("att2.txt", "goodbye, world"),
+		})
+
+		return test{
+			db:      d,
+			docID:   docID,
+			revs:    []string{rev2},
+			options: kivik.Param("atts_since", []string{rev1}),
+			want: []rowResult{
+				{ID: docID, Rev: rev2, Doc: `{"_id":"` + docID + `","_rev":"` + rev2 + `","_attachments":{"att.txt":{"content_type":"text/plain","digest":"md5-5NfxtO0uQtFYmPSyewGdpA==","length":12,"revpos":1,"stub":true},"att2.txt":{"content_type":"text/plain","digest":"md5-18/CjiFEUaAYOzOUOD2UPQ==","length":14,"revpos":2,"data":"Z29vZGJ5ZSwgd29ybGQ="}}}`},
+			},
+		}
+	})
+	tests.Add("atts_since with invalid rev format", test{
+		docID:      "foo",
+		revs:       []string{"all"},
+		options:    kivik.Param("atts_since", []string{"this is an invalid rev"}),
+		wantErr:    "invalid rev format",
+		wantStatus: http.StatusBadRequest,
+	})
 	tests.Add("conflicts=true includes _conflicts", func(t *testing.T) interface{} {
 		d := newDB(t)
 		docID := "foo"
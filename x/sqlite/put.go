@@ -26,26 +26,43 @@ import (
 )
 
 func (d *db) Put(ctx context.Context, docID string, doc any, opts driver.Options) (string, error) {
+	o := options.New(opts)
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	rev, changed, err := d.put(ctx, tx, docID, doc, o.Rev(), o.NewEdits())
+	if err != nil || !changed {
+		return rev, err
+	}
+	return rev, tx.Commit()
+}
+
+// put stores doc within tx, and returns the new rev. changed is false if
+// nothing was written, which happens when a revision which already exists is
+// stored with new_edits=false.
+func (d *db) put(ctx context.Context, tx *sql.Tx, docID string, doc any, optsRev string, newEdits bool) (string, bool, error) {
 	if len(docID) > 0 && docID[0] == '_' && !strings.HasPrefix(docID, "_design/") && !strings.HasPrefix(docID, "_local/") {
-		return "", &internal.Error{Status: http.StatusBadRequest, Message: "only reserved document ids may start with underscore"}
+		return "", false, &internal.Error{Status: http.StatusBadRequest, Message: "only reserved document ids may start with underscore"}
 	}
 	docRev, err := extractRev(doc)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
-	o := options.New(opts)
-	optsRev := o.Rev()
-	newEdits := o.NewEdits()
-	data, err := prepareDoc(docID, doc)
+	doc, err = d.prepareUserDoc(docID, doc)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
-
-	tx, err := d.db.BeginTx(ctx, nil)
+	var keepPrefix string
+	if d.replicationState {
+		keepPrefix = "_replication_"
+	}
+	data, err := prepareDocKeeping(docID, doc, keepPrefix)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
-	defer tx.Rollback()
 
 	if data.Revisions.Start != 0 {
 		if newEdits {
@@ -59,7 +76,7 @@ func (d *db) Put(ctx context.Context, docID string, doc any, opts driver.Options
 				)
 			`))
 			if err != nil {
-				return "", err
+				return "", false, err
 			}
 			defer stmt.Close()
 			var exists bool
@@ -67,17 +84,17 @@ func (d *db) Put(ctx context.Context, docID string, doc any, opts driver.Options
 			for _, r := range revs[:len(revs)-1] {
 				err := stmt.QueryRowContext(ctx, data.ID, r.rev, r.id).Scan(&exists)
 				if err != nil {
-					return "", d.errDatabaseNotFound(err)
+					return "", false, d.errDatabaseNotFound(err)
 				}
 				if !exists {
-					return "", &internal.Error{Status: http.StatusConflict, Message: "document update conflict"}
+					return "", false, &internal.Error{Status: http.StatusConflict, Message: "document update conflict"}
 				}
 			}
 		}
 		docRev = data.Revisions.leaf().String()
 	}
 	if optsRev != "" && docRev != "" && optsRev != docRev {
-		return "", &internal.Error{Status: http.StatusBadRequest, Message: "document rev and option have different values"}
+		return "", false, &internal.Error{Status: http.StatusBadRequest, Message: "document rev and option have different values"}
 	}
 	if docRev == "" && optsRev != "" {
 		docRev = optsRev
@@ -94,7 +111,7 @@ func (d *db) Put(ctx context.Context, docID string, doc any, opts driver.Options
 				ON CONFLICT DO UPDATE SET parent_rev = $4, parent_rev_id = $5
 			`))
 			if err != nil {
-				return "", err
+				return "", false, err
 			}
 			defer stmt.Close()
 
@@ -106,7 +123,7 @@ func (d *db) Put(ctx context.Context, docID string, doc any, opts driver.Options
 				r := r
 				_, err := stmt.ExecContext(ctx, data.ID, r.rev, r.id, parentRev, parentRevID)
 				if err != nil {
-					return "", d.errDatabaseNotFound(err)
+					return "", false, d.errDatabaseNotFound(err)
 				}
 				parentRev = &r.rev
 				parentRevID = &r.id
@@ -114,11 +131,11 @@ func (d *db) Put(ctx context.Context, docID string, doc any, opts driver.Options
 			rev = data.Revisions.leaf()
 		} else {
 			if docRev == "" {
-				return "", &internal.Error{Status: http.StatusBadRequest, Message: "When `new_edits: false`, the document needs `_rev` or `_revisions` specified"}
+				return "", false, &internal.Error{Status: http.StatusBadRequest, Message: "When `new_edits: false`, the document needs `_rev` or `_revisions` specified"}
 			}
 			rev, err = parseRev(docRev)
 			if err != nil {
-				return "", err
+				return "", false, err
 			}
 			_, err = tx.ExecContext(ctx, d.query(`
 				INSERT INTO {{ .Revs }} (id, rev, rev_id)
@@ -126,7 +143,7 @@ func (d *db) Put(ctx context.Context, docID string, doc any, opts driver.Options
 				ON CONFLICT DO NOTHING
 			`), docID, rev.rev, rev.id)
 			if err != nil {
-				return "", d.errDatabaseNotFound(err)
+				return "", false, d.errDatabaseNotFound(err)
 			}
 		}
 		var newRev string
@@ -140,28 +157,32 @@ func (d *db) Put(ctx context.Context, docID string, doc any, opts driver.Options
 			// No rows means a conflict, so  we assume that the documents are
 			// identical, for the sake of idempotency, and return the current
 			// rev, to match CouchDB behavior.
-			return docRev, nil
+			return docRev, false, nil
 		}
 		if err != nil {
-			return "", err
+			return "", false, err
 		}
 
 		if err := d.createDocAttachments(ctx, data, tx, rev, ancestorRev); err != nil {
-			return "", err
+			return "", false, err
 		}
 
 		if err := d.updateDesignDoc(ctx, tx, rev, revision{}, data); err != nil {
-			return "", err
+			return "", false, err
+		}
+
+		if err := d.stemRevs(ctx, tx, data.ID, rev); err != nil {
+			return "", false, err
 		}
 
-		return newRev, tx.Commit()
+		return newRev, true, nil
 	}
 
 	var curRev revision
 	if docRev != "" {
 		curRev, err = parseRev(docRev)
 		if err != nil {
-			return "", err
+			return "", false, err
 		}
 	}
 
@@ -169,20 +190,20 @@ func (d *db) Put(ctx context.Context, docID string, doc any, opts driver.Options
 	switch {
 	case kivik.HTTPStatus(err) == http.StatusNotFound:
 		if docRev != "" {
-			return "", &internal.Error{Status: http.StatusConflict, Message: "document update conflict"}
+			return "", false, &internal.Error{Status: http.StatusConflict, Message: "document update conflict"}
 		}
 	case err != nil:
-		return "", d.errDatabaseNotFound(err)
+		return "", false, d.errDatabaseNotFound(err)
 	}
 
 	if err := d.runValidation(ctx, tx, data, curRev); err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	r, err := d.createRev(ctx, tx, data, curRev)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
-	return r.String(), tx.Commit()
+	return r.String(), true, nil
 }
@@ -1109,6 +1109,70 @@ func TestDBPut(t *testing.T) {
 		}
 	})
 
+	tests.Add("new_edits=false with attachment stub and digest of existing content works", func(t *testing.T) any {
+		d := newDB(t)
+		_ = d.tPut("bar", map[string]any{
+			"_attachments": newAttachments().add("bar.txt", "This is a base64 encoding"),
+		})
+
+		return test{
+			db:    d,
+			docID: "foo",
+			doc: map[string]any{
+				"_revisions": map[string]any{
+					"ids":   []string{"ghi", "def"},
+					"start": 6,
+				},
+				"_attachments": map[string]any{
+					"foo.txt": map[string]any{
+						"stub":   true,
+						"digest": "md5-TmfHxaRgUrE9l3tkAn4s0Q==",
+						"revpos": 5,
+					},
+				},
+			},
+			options: kivik.Param("new_edits", false),
+			wantRev: "6-ghi",
+			wantRevs: []leaf{
+				{ID: "bar", Rev: 1},
+				{ID: "foo", Rev: 5, RevID: "def"},
+				{ID: "foo", Rev: 6, RevID: "ghi", ParentRev: &[]int{5}[0], ParentRevID: &[]string{"def"}[0]},
+			},
+			wantAttachments: []attachmentRow{
+				{
+					DocID:    "bar",
+					RevPos:   1,
+					Rev:      1,
+					Filename: "bar.txt",
+					Digest:   "md5-TmfHxaRgUrE9l3tkAn4s0Q==",
+				},
+				{
+					DocID:    "foo",
+					RevPos:   5,
+					Rev:      6,
+					RevID:    "ghi",
+					Filename: "foo.txt",
+					Digest:   "md5-TmfHxaRgUrE9l3tkAn4s0Q==",
+				},
+			},
+		}
+	})
+	tests.Add("new_edits=false with attachment stub and unknown digest returns 412", test{
+		docID: "foo",
+		doc: map[string]any{
+			"_rev": "1-abc",
+			"_attachments": map[string]any{
+				"foo.txt": map[string]any{
+					"stub":   true,
+					"digest": "md5-TmfHxaRgUrE9l3tkAn4s0Q==",
+				},
+			},
+		},
+		options:    kivik.Param("new_edits", false),
+		wantStatus: http.StatusPreconditionFailed,
+		wantErr:    "invalid attachment stub in foo for foo.txt",
+	})
+
 	tests.Add("validate_doc_update rejects document", func(t *testing.T) interface{} {
 		d := newDB(t)
 		d.tAddValidation("_design/validation", `function(newDoc, oldDoc, userCtx, secObj) { throw({forbidden: "not allowed"}); }`)
@@ -14,7 +14,6 @@ package sqlite
 
 import (
 	"context"
-	"io"
 	"net/http"
 
 	"github.com/go-kivik/kivik/v4"
@@ -57,16 +56,16 @@ func (d *db) PutAttachment(ctx context.Context, docID string, att *driver.Attach
 		return "", d.errDatabaseNotFound(err)
 	}
 
-	content, err := io.ReadAll(att.Content)
+	// The content is streamed into storage, rather than read into memory.
+	digest, length, err := d.storeAttachmentData(ctx, tx, att.Content, att.ContentType)
 	if err != nil {
 		return "", err
 	}
 	file := attachment{
 		ContentType: att.ContentType,
-		Content:     content,
-	}
-	if err := file.calculate(att.Filename); err != nil {
-		return "", err
+		Digest:      digest,
+		Length:      length,
+		stored:      true,
 	}
 	data.Attachments = map[string]attachment{
 		att.Filename: file,
@@ -15,7 +15,11 @@
 package sqlite
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"regexp"
@@ -371,3 +375,260 @@ func TestDBPutAttachment(t *testing.T) {
 		checkAttachments(t, dbc.underlying(), tt.wantAttachments)
 	})
 }
+
+func TestDBPutAttachment_dedup(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	ctx := context.Background()
+	content := strings.Repeat("shared content ", 100)
+
+	revA := d.tPut("a", map[string]any{
+		"_attachments": newAttachments().add("one.txt", content),
+	})
+	revB := d.tPut("b", map[string]any{
+		"_attachments": newAttachments().add("two.txt", content),
+	})
+	revA2, err := d.PutAttachment(ctx, "a", &driver.Attachment{
+		Filename:    "three.txt",
+		ContentType: "text/plain",
+		Content:     io.NopCloser(strings.NewReader(content)),
+	}, kivik.Rev(revA))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := d.count(`SELECT COUNT(*) FROM {{ .AttachmentData }}`); n != 1 {
+		t.Errorf("Expected the content to be stored once, found %d copies", n)
+	}
+	att, err := d.GetAttachment(ctx, "b", "two.txt", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(att.Content)
+	if string(got) != content {
+		t.Errorf("Unexpected content: %.20q", got)
+	}
+
+	stats, err := d.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		Attachments attachmentStats `json:"attachments"`
+	}
+	if err := json.Unmarshal(stats.RawResponse, &raw); err != nil {
+		t.Fatal(err)
+	}
+	want := attachmentStats{
+		Count:       3,
+		Size:        3 * int64(len(content)),
+		StoredCount: 1,
+		StoredSize:  int64(len(content)),
+		Saved:       2 * int64(len(content)),
+	}
+	if d := cmp.Diff(want, raw.Attachments); d != "" {
+		t.Errorf("Unexpected attachment stats:\n%s", d)
+	}
+
+	// Content is removed with its last reference.
+	if _, err := d.Purge(ctx, map[string][]string{"a": {revA2, revA}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.count(`SELECT refs FROM {{ .AttachmentData }}`); n != 1 {
+		t.Errorf("Expected 1 reference to remain, got %d", n)
+	}
+	if _, err := d.Purge(ctx, map[string][]string{"b": {revB}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.count(`SELECT COUNT(*) FROM {{ .AttachmentData }}`); n != 0 {
+		t.Errorf("Expected unreferenced content to be removed, found %d", n)
+	}
+	if n := d.count(`SELECT COUNT(*) FROM {{ .AttachmentChunks }}`); n != 0 {
+		t.Errorf("Expected unreferenced chunks to be removed, found %d", n)
+	}
+	if n := d.count(`SELECT COUNT(*) FROM {{ .Attachments }}`); n != 0 {
+		t.Errorf("Expected unreferenced attachments to be removed, found %d", n)
+	}
+}
+
+func TestDBPutAttachment_digestCollision(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	ctx := context.Background()
+	content := "original content"
+
+	rev := d.tPut("a", map[string]any{
+		"_attachments": newAttachments().add("one.txt", content),
+	})
+	// Replacing the stored content simulates different content with the same
+	// MD5 digest.
+	if _, err := d.underlying().Exec(d.DB.(*db).query(`UPDATE {{ .AttachmentChunks }} SET chunk = 'colliding content'`)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := d.PutAttachment(ctx, "a", &driver.Attachment{
+		Filename:    "two.txt",
+		ContentType: "text/plain",
+		Content:     io.NopCloser(strings.NewReader(content)),
+	}, kivik.Rev(rev))
+	if status := kivik.HTTPStatus(err); status != http.StatusConflict {
+		t.Errorf("Unexpected status for streamed content: %d, error: %v", status, err)
+	}
+
+	_, err = d.Put(ctx, "b", map[string]any{
+		"_attachments": newAttachments().add("three.txt", content),
+	}, mock.NilOption)
+	if status := kivik.HTTPStatus(err); status != http.StatusConflict {
+		t.Errorf("Unexpected status for inline content: %d, error: %v", status, err)
+	}
+}
+
+func TestDBPutAttachment_chunked(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	ctx := context.Background()
+
+	content := make([]byte, 2*attachmentChunkSize+100)
+	if _, err := rand.Read(content); err != nil {
+		t.Fatal(err)
+	}
+	_, err := d.PutAttachment(ctx, "foo", &driver.Attachment{
+		Filename:    "random.bin",
+		ContentType: "application/octet-stream",
+		Content:     io.NopCloser(bytes.NewReader(content)),
+	}, mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := d.count(`SELECT COUNT(*) FROM {{ .AttachmentChunks }}`); n != 3 {
+		t.Errorf("Expected 3 chunks, got %d", n)
+	}
+
+	att, err := d.GetAttachment(ctx, "foo", "random.bin", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer att.Content.Close()
+	got, err := io.ReadAll(att.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("Unexpected content")
+	}
+	if att.Size != int64(len(content)) {
+		t.Errorf("Unexpected size: %d", att.Size)
+	}
+}
+
+func TestDBPutAttachment_contentDeleted(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	ctx := context.Background()
+
+	_ = d.tPut("foo", map[string]any{
+		"_attachments": newAttachments().add("foo.txt", "first content"),
+	})
+	firstPK := d.count(`SELECT pk FROM {{ .AttachmentData }}`)
+
+	// Chunks are deleted with their content, even where foreign keys are
+	// disabled.
+	conn, err := d.underlying().Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, query := range []string{
+		`PRAGMA foreign_keys = OFF`,
+		`DELETE FROM {{ .AttachmentData }}`,
+		`PRAGMA foreign_keys = ON`,
+	} {
+		if _, err := conn.ExecContext(ctx, d.DB.(*db).query(query)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := d.count(`SELECT COUNT(*) FROM {{ .AttachmentChunks }}`); n != 0 {
+		t.Errorf("Expected chunks to be deleted, found %d", n)
+	}
+
+	// The ID of deleted content is not reused.
+	_ = d.tPut("bar", map[string]any{
+		"_attachments": newAttachments().add("bar.txt", "second content"),
+	})
+	if pk := d.count(`SELECT pk FROM {{ .AttachmentData }}`); pk <= firstPK {
+		t.Errorf("Expected a new content ID, got %d after %d", pk, firstPK)
+	}
+}
+
+func TestDBPutAttachment_compressed(t *testing.T) {
+	t.Parallel()
+	dsn := fmt.Sprintf("file:compressed%d?mode=memory&cache=shared", dbSeq.Add(1))
+	c, err := drv{}.NewClient(dsn, OptionCompressibleTypes("text/*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.(*client).Close() })
+	ctx := context.Background()
+	if err := c.CreateDB(ctx, "test", mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+	d := &testDB{DB: c.(*client).newDB("test"), t: t}
+
+	content := strings.Repeat("All work and no play makes Jack a dull boy. ", 1000)
+	rev, err := d.PutAttachment(ctx, "foo", &driver.Attachment{
+		Filename:    "text.txt",
+		ContentType: "text/plain; charset=utf-8",
+		Content:     io.NopCloser(strings.NewReader(content)),
+	}, mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = d.tPut("bar", map[string]any{
+		"_attachments": newAttachments().add("bar.txt", content),
+	})
+
+	if n := d.count(`SELECT COUNT(*) FROM {{ .AttachmentData }} WHERE encoding = 'gzip'`); n != 1 {
+		t.Errorf("Expected the content to be stored compressed once, found %d", n)
+	}
+	if n := d.count(`SELECT encoded_length FROM {{ .AttachmentData }}`); n >= len(content) {
+		t.Errorf("Expected compressed content, stored %d bytes", n)
+	}
+
+	att, err := d.GetAttachment(ctx, "foo", "text.txt", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(att.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != content {
+		t.Errorf("Unexpected content: %.20q", got)
+	}
+
+	doc, err := d.Get(ctx, "foo", kivik.Params(map[string]any{
+		"rev":               rev,
+		"attachments":       true,
+		"att_encoding_info": true,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Attachments map[string]struct {
+			Data          []byte `json:"data"`
+			Encoding      string `json:"encoding"`
+			EncodedLength int    `json:"encoded_length"`
+		} `json:"_attachments"`
+	}
+	if err := json.NewDecoder(doc.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	text := body.Attachments["text.txt"]
+	if string(text.Data) != content {
+		t.Errorf("Unexpected inline content: %.20q", text.Data)
+	}
+	if text.Encoding != "gzip" || text.EncodedLength == 0 || text.EncodedLength >= len(content) {
+		t.Errorf("Unexpected encoding info: %q, %d", text.Encoding, text.EncodedLength)
+	}
+}
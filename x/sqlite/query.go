@@ -22,11 +22,13 @@ import (
 	"net/http"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/go-kivik/kivik/v4"
 	"github.com/go-kivik/kivik/v4/driver"
 	internal "github.com/go-kivik/kivik/v4/int/errors"
 	"github.com/go-kivik/kivik/v4/x/options"
+	"github.com/go-kivik/kivik/x/sqlite/v4/js"
 	"github.com/go-kivik/kivik/x/sqlite/v4/reduce"
 )
 
@@ -133,6 +135,12 @@ func (d *db) performQuery(
 					AND rev_id = $7
 					AND func_type = 'reduce'
 					AND func_name = $8
+			),
+			cached AS (
+				SELECT view.*
+				FROM {{ .Reduce }} AS view
+				WHERE TRUE
+					%[4]s -- WHERE
 			)
 
 			-- Metadata header
@@ -160,29 +168,50 @@ func (d *db) performQuery(
 
 			UNION ALL
 
-			-- View map to pass to reduce
+			-- View map to pass to reduce, with cached reduce nodes standing in
+			-- for the map rows they cover
+			SELECT * FROM (
 			SELECT
-				*
+				view.id       AS id,
+				view.key      AS first_key,
+				view.value    AS value,
+				view.pk       AS first_pk,
+				view.last_pk  AS last_pk,
+				view.last_key AS last_key, -- in place of conflicts
+				0,    -- attachment_count,
+				NULL, -- filename
+				NULL, -- content_type
+				NULL, -- length
+				NULL, -- digest
+				NULL, -- rev_pos
+				NULL  -- data
 			FROM (
 				SELECT
-					view.id    AS id,
-					view.key   AS first_key,
-					view.value AS value,
-					view.pk    AS first_pk,
-					view.pk    AS last_pk,
-					NULL, -- conflicts,
-					0,    -- attachment_count,
-					NULL, -- filename
-					NULL, -- content_type
-					NULL, -- length
-					NULL, -- digest
-					NULL, -- rev_pos
-					NULL  -- data
+					""               AS id,
+					cached.first_key AS key,
+					cached.value,
+					cached.first_pk  AS pk,
+					cached.last_pk,
+					cached.last_key
+				FROM cached
+				JOIN reduce ON reduce.reducible AND ($3 IS NULL OR $3 == TRUE)
+
+				UNION ALL
+
+				SELECT
+					view.id,
+					view.key,
+					view.value,
+					view.pk,
+					view.pk,
+					NULL
 				FROM {{ .Map }} AS view
 				JOIN reduce ON reduce.reducible AND ($3 IS NULL OR $3 == TRUE)
-				WHERE TRUE
+				LEFT JOIN cached ON cached.pk = view.reduce_node
+				WHERE cached.pk IS NULL
 					%[2]s -- WHERE
-				%[1]s -- ORDER BY
+			) AS view
+			%[1]s -- ORDER BY
 			)
 
 			UNION ALL
@@ -217,7 +246,7 @@ func (d *db) performQuery(
 					att.length AS length,
 					att.digest AS digest,
 					att.rev_pos AS rev_pos,
-					IIF($9, att.data, NULL) AS data
+					IIF($9, {{ .AttachmentContent }}, NULL) AS data
 				FROM (
 					SELECT
 						view.pk,
@@ -270,7 +299,7 @@ func (d *db) performQuery(
 				_ = results.Close() //nolint:sqlclosecheck // invalid option specified for reduce, so abort the query
 				return nil, &internal.Error{Status: http.StatusBadRequest, Message: "conflicts is invalid for reduce"}
 			}
-			result, err := d.reduce(ctx, results, meta.reduceFuncJS, vopts.ReduceGroupLevel())
+			result, err := d.reduce(ctx, results, ddoc, view, rev, meta.reduceFuncJS, vopts.ReduceGroupLevel())
 			if err != nil {
 				return nil, err
 			}
@@ -318,6 +347,11 @@ func (d *db) performGroupQuery(ctx context.Context, ddoc, view string, vopts *op
 
 		args := []any{"_design/" + ddoc, rev.rev, rev.id, view, kivik.EndKeySuffix, true, vopts.UpdateSeq()}
 		where := append([]string{""}, vopts.BuildGroupWhere(&args)...)
+		cacheWhere := append([]string{""}, vopts.BuildReduceCacheWhere(&args)...)
+		if limit := vopts.BuildLimit(); limit != "" {
+			// The limit applies to map rows, which cached nodes would skew.
+			cacheWhere = []string{"", "FALSE"}
+		}
 
 		query := fmt.Sprintf(d.ddocQuery(ddoc, view, rev.String(), `
 			WITH reduce AS (
@@ -330,6 +364,12 @@ func (d *db) performGroupQuery(ctx context.Context, ddoc, view string, vopts *op
 					AND rev_id = $3
 					AND func_type = 'reduce'
 					AND func_name = $4
+			),
+			cached AS (
+				SELECT view.*
+				FROM {{ .Reduce }} AS view
+				WHERE TRUE
+					%[4]s -- WHERE
 			)
 
 			-- Metadata
@@ -357,31 +397,59 @@ func (d *db) performGroupQuery(ctx context.Context, ddoc, view string, vopts *op
 
 			UNION ALL
 
-			-- Actual results
-			SELECT *
+			-- Actual results, with cached reduce nodes standing in for the map
+			-- rows they cover
+			SELECT * FROM (
+			SELECT
+				view.id    AS id,
+				COALESCE(view.key, "null") AS key,
+				view.value AS value,
+				view.pk    AS first,
+				view.last_pk AS last,
+				view.last_key AS last_key, -- in place of conflicts
+				0    AS attachment_count,
+				NULL, --
+				NULL AS content_type,
+				NULL AS length,
+				NULL AS digest,
+				NULL AS rev_pos,
+				NULL AS data
 			FROM (
 				SELECT
-					view.id    AS id,
-					COALESCE(view.key, "null") AS key,
-					view.value AS value,
-					view.pk    AS first,
-					view.pk    AS last,
-					NULL  AS conflicts,
-					0    AS attachment_count,
-					NULL, --
-					NULL AS content_type,
-					NULL AS length,
-					NULL AS digest,
-					NULL AS rev_pos,
-					NULL AS data
-				FROM {{ .Map }} AS view
+					""               AS id,
+					cached.first_key AS key,
+					cached.value,
+					cached.first_pk  AS pk,
+					cached.last_pk,
+					cached.last_key
+				FROM cached
 				JOIN reduce
 				WHERE reduce.reducible AND ($6 IS NULL OR $6 == TRUE)
-					%[2]s
-				%[1]s -- ORDER BY
-				%[3]s
+
+				UNION ALL
+
+				SELECT *
+				FROM (
+					SELECT
+						view.id,
+						view.key,
+						view.value,
+						view.pk,
+						view.pk AS last_pk,
+						NULL    AS last_key
+					FROM {{ .Map }} AS view
+					JOIN reduce
+					LEFT JOIN cached ON cached.pk = view.reduce_node
+					WHERE reduce.reducible AND ($6 IS NULL OR $6 == TRUE)
+						AND cached.pk IS NULL
+						%[2]s
+					%[1]s -- ORDER BY
+					%[3]s
+				)
+			) AS view
+			%[1]s -- ORDER BY
 			)
-		`), vopts.BuildOrderBy("pk"), strings.Join(where, " AND "), vopts.BuildLimit())
+		`), vopts.BuildOrderBy("pk"), strings.Join(where, " AND "), vopts.BuildLimit(), strings.Join(cacheWhere, " AND "))
 		results, err = d.db.QueryContext(ctx, query, args...) //nolint:rowserrcheck // Err checked in iterator
 
 		switch {
@@ -409,15 +477,27 @@ func (d *db) performGroupQuery(ctx context.Context, ddoc, view string, vopts *op
 		}
 	}
 
-	result, err := d.reduce(ctx, results, meta.reduceFuncJS, vopts.ReduceGroupLevel())
+	result, err := d.reduce(ctx, results, ddoc, view, rev, meta.reduceFuncJS, vopts.ReduceGroupLevel())
 	if err != nil {
 		return nil, err
 	}
 	return metaReduced{Rows: result, meta: meta}, nil
 }
 
-func (d *db) reduce(ctx context.Context, results *sql.Rows, reduceFuncJS string, groupLevel int) (driver.Rows, error) {
-	return reduce.Reduce(ctx, &reduceRowIter{results: results}, reduceFuncJS, d.logger, d.js, groupLevel)
+func (d *db) reduce(ctx context.Context, results *sql.Rows, ddoc, view string, rev revision, reduceFuncJS string, groupLevel int) (driver.Rows, error) {
+	compiler, release, err := d.reduceCompiler(ctx, ddoc, view, rev, reduceFuncJS)
+	if err != nil {
+		_ = results.Close()
+		return nil, err
+	}
+	defer release()
+	return reduce.Reduce(ctx, &reduceRowIter{results: results, reduceFuncJS: reduceFuncJS}, reduceFuncJS, d.logger, compiler, groupLevel)
+}
+
+// reduceJS returns the JavaScript runtime in which to compile the reduce
+// function of the view.
+func (d *db) reduceJS(ddoc, view string) *js.Runtime {
+	return d.js.WithLog(d.jsLog("_design/"+ddoc, "views/"+view+"/reduce"))
 }
 
 const batchSize = 100
@@ -428,23 +508,29 @@ func (d *db) updateIndex(ctx context.Context, ddoc, view, mode string) (revision
 	var (
 		ddocRev                 revision
 		mapFuncJS               *string
+		language                sql.NullString
 		lastSeq                 int
 		includeDesign, localSeq sql.NullBool
+		collation               *string
+		ddocBody                []byte
 	)
 	err := d.db.QueryRowContext(ctx, d.query(`
 		SELECT
 			docs.rev,
 			docs.rev_id,
+			docs.doc,
 			design.func_body,
+			design.language,
 			design.include_design,
 			design.local_seq,
+			design.collation,
 			COALESCE(design.last_seq, 0) AS last_seq
 		FROM {{ .Docs }} AS docs
-		LEFT JOIN {{ .Design }} AS design ON docs.id = design.id AND docs.rev = design.rev AND docs.rev_id = design.rev_id AND design.func_type = 'map'
+		LEFT JOIN {{ .Design }} AS design ON docs.id = design.id AND docs.rev = design.rev AND docs.rev_id = design.rev_id AND design.func_type = 'map' AND design.func_name = $2
 		WHERE docs.id = $1
 		ORDER BY docs.rev DESC, docs.rev_id DESC
 		LIMIT 1
-	`), "_design/"+ddoc).Scan(&ddocRev.rev, &ddocRev.id, &mapFuncJS, &includeDesign, &localSeq, &lastSeq)
+	`), "_design/"+ddoc, view).Scan(&ddocRev.rev, &ddocRev.id, &ddocBody, &mapFuncJS, &language, &includeDesign, &localSeq, &collation, &lastSeq)
 	switch {
 	case errors.Is(err, sql.ErrNoRows):
 		return revision{}, &internal.Error{Status: http.StatusNotFound, Message: "missing"}
@@ -452,6 +538,12 @@ func (d *db) updateIndex(ctx context.Context, ddoc, view, mode string) (revision
 		return revision{}, err
 	}
 
+	if mapFuncJS != nil {
+		if err := d.migrateViewMap(ctx, ddoc, view, ddocRev, collation); err != nil {
+			return revision{}, err
+		}
+	}
+
 	if mode != "true" {
 		return ddocRev, nil
 	}
@@ -460,7 +552,165 @@ func (d *db) updateIndex(ctx context.Context, ddoc, view, mode string) (revision
 		return revision{}, &internal.Error{Status: http.StatusNotFound, Message: "missing named view"}
 	}
 
-	query := d.query(`
+	docs, err := d.db.QueryContext(ctx, d.indexDocsQuery(), lastSeq)
+	if err != nil {
+		return revision{}, err
+	}
+	defer docs.Close()
+
+	mapper, release, err := d.viewMapper(ctx, ddoc, view, language.String, ddocBody, *mapFuncJS)
+	if err != nil {
+		return revision{}, err
+	}
+	defer release()
+
+	batch := newMapIndexBatch()
+
+	// Start from the previous position, so last_seq is preserved when there are
+	// no new changes to index.
+	seq := lastSeq
+	for {
+		jobs, err := readMapJobs(docs, &seq, includeDesign.Bool, localSeq.Bool)
+		if err != nil {
+			return revision{}, err
+		}
+		if len(jobs) == 0 {
+			break
+		}
+
+		if err := mapDocs(ctx, mapper, jobs); err != nil {
+			return revision{}, err
+		}
+
+		// Results are applied in sequence order, so that the index is written
+		// exactly as it would be by mapping one document at a time.
+		for _, job := range jobs {
+			switch {
+			case job.full.Deleted:
+				batch.delete(job.full.ID, job.rev)
+			case job.err != nil:
+				d.logger.Printf("map function threw exception for %s: %s", job.full.ID, job.err)
+				batch.delete(job.full.ID, job.rev)
+			default:
+				for _, e := range job.emitted {
+					batch.add(job.full.ID, job.rev, e.Key, e.Value)
+				}
+			}
+
+			if batch.insertCount >= batchSize {
+				if err := d.writeMapIndexBatch(ctx, job.seq, ddocRev, ddoc, view, batch); err != nil {
+					return revision{}, err
+				}
+				batch.clear()
+			}
+		}
+	}
+
+	if err := d.writeMapIndexBatch(ctx, seq, ddocRev, ddoc, view, batch); err != nil {
+		return revision{}, err
+	}
+	if err := docs.Err(); err != nil {
+		return revision{}, err
+	}
+
+	if seq != lastSeq {
+		if err := d.updateReduceCache(ctx, ddoc, view, ddocRev); err != nil {
+			return revision{}, err
+		}
+	}
+
+	return ddocRev, nil
+}
+
+// mapJob is a single document to be mapped while updating an index.
+type mapJob struct {
+	seq     int
+	full    *fullDoc
+	rev     revision
+	emitted []js.Emitted
+	err     error
+}
+
+// readMapJobs reads up to batchSize documents to be mapped from docs,
+// advancing seq past any documents which are skipped. An empty result means
+// there are no more documents.
+func readMapJobs(docs *sql.Rows, seq *int, includeDesign, localSeq bool) ([]*mapJob, error) {
+	jobs := make([]*mapJob, 0, batchSize)
+	for len(jobs) < batchSize {
+		full := &fullDoc{}
+		err := iter(docs, seq, full)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip design/local docs
+		if full.ID == "" {
+			continue
+		}
+
+		rev, err := full.rev()
+		if err != nil {
+			return nil, err
+		}
+
+		// TODO move this to the query
+		if strings.HasPrefix(full.ID, "_local/") ||
+			(!includeDesign && strings.HasPrefix(full.ID, "_design/")) {
+			continue
+		}
+
+		if localSeq {
+			full.LocalSeq = *seq
+		}
+
+		jobs = append(jobs, &mapJob{seq: *seq, full: full, rev: rev})
+	}
+	return jobs, nil
+}
+
+// mapDocs runs the map function against each non-deleted document in jobs,
+// using as many goroutines as the mapper allows. The results are stored in
+// each job. If the mapper fails entirely, the error is returned.
+func mapDocs(ctx context.Context, pool viewMapper, jobs []*mapJob) error {
+	work := make(chan *mapJob)
+	var wg sync.WaitGroup
+	for i := 0; i < min(pool.Size(), len(jobs)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range work {
+				job.emitted, job.err = pool.Map(ctx, job.full.toMap())
+			}
+		}()
+	}
+	for _, job := range jobs {
+		if job.full.Deleted {
+			continue
+		}
+		work <- job
+	}
+	close(work)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		var abort *mapAbortError
+		if errors.As(job.err, &abort) {
+			return abort.err
+		}
+	}
+	return nil
+}
+
+// indexDocsQuery returns the query used to read the documents changed since
+// the sequence $1, along with their attachment stubs, when updating an index.
+// Rows are read with [iter].
+func (d *db) indexDocsQuery() string {
+	return d.query(`
 		WITH leaves AS (
 			SELECT
 				rev.id                    AS id,
@@ -529,81 +779,6 @@ func (d *db) updateIndex(ctx context.Context, ddoc, view, mode string) (revision
 			ORDER BY seq.seq
 		)
 	`)
-	docs, err := d.db.QueryContext(ctx, query, lastSeq)
-	if err != nil {
-		return revision{}, err
-	}
-	defer docs.Close()
-
-	batch := newMapIndexBatch()
-
-	var (
-		emitID  string
-		emitRev revision
-	)
-	mapFunc, err := d.js.Map(*mapFuncJS, func(key, value any) {
-		batch.add(emitID, emitRev, key, value)
-	})
-	if err != nil {
-		return revision{}, err
-	}
-
-	var seq int
-	for {
-		full := &fullDoc{}
-		err := iter(docs, &seq, full)
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return revision{}, err
-		}
-
-		// Skip design/local docs
-		if full.ID == "" {
-			continue
-		}
-
-		rev, err := full.rev()
-		if err != nil {
-			return revision{}, err
-		}
-
-		// TODO move this to the query
-		if strings.HasPrefix(full.ID, "_local/") ||
-			(!includeDesign.Bool && strings.HasPrefix(full.ID, "_design/")) {
-			continue
-		}
-
-		if full.Deleted {
-			batch.delete(full.ID, rev)
-			continue
-		}
-
-		if localSeq.Bool {
-			full.LocalSeq = seq
-		}
-
-		emitID = full.ID
-		emitRev = rev
-		if err := mapFunc(ctx, full.toMap()); err != nil {
-			d.logger.Printf("map function threw exception for %s: %s", full.ID, err)
-			batch.delete(full.ID, rev)
-		}
-
-		if batch.insertCount >= batchSize {
-			if err := d.writeMapIndexBatch(ctx, seq, ddocRev, ddoc, view, batch); err != nil {
-				return revision{}, err
-			}
-			batch.clear()
-		}
-	}
-
-	if err := d.writeMapIndexBatch(ctx, seq, ddocRev, ddoc, view, batch); err != nil {
-		return revision{}, err
-	}
-
-	return ddocRev, docs.Err()
 }
 
 func iter(docs *sql.Rows, seq *int, full *fullDoc) error {
@@ -15,11 +15,17 @@
 package sqlite
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"gitlab.com/flimzy/testy"
 
 	"github.com/go-kivik/kivik/v4"
@@ -1268,6 +1274,129 @@ func TestDBQuery(t *testing.T) {
 			},
 		}
 	})
+	tests.Add("_approx_count_distinct", func(t *testing.T) any {
+		d := newDB(t)
+		_ = d.tPut("_design/foo", map[string]any{
+			"views": map[string]any{
+				"bar": map[string]string{
+					"map": `function(doc) {
+							if (doc.key) {
+								emit(doc.key, null);
+							}
+						}`,
+					"reduce": `_approx_count_distinct`,
+				},
+			},
+		})
+		_ = d.tPut("a", map[string]any{"key": []string{"a", "x"}})
+		_ = d.tPut("b", map[string]any{"key": []string{"a", "y"}})
+		_ = d.tPut("c", map[string]any{"key": []string{"a", "y"}})
+		_ = d.tPut("d", map[string]any{"key": []string{"b", "x"}})
+		_ = d.tPut("e", map[string]any{"key": []string{"c", "z"}})
+
+		return test{
+			db:   d,
+			ddoc: "_design/foo",
+			view: "_view/bar",
+			want: []rowResult{
+				{Key: `null`, Value: `4`},
+			},
+		}
+	})
+	tests.Add("_approx_count_distinct with start/end keys", func(t *testing.T) any {
+		d := newDB(t)
+		_ = d.tPut("_design/foo", map[string]any{
+			"views": map[string]any{
+				"bar": map[string]string{
+					"map": `function(doc) {
+							if (doc.key) {
+								emit(doc.key, null);
+							}
+						}`,
+					"reduce": `_approx_count_distinct`,
+				},
+			},
+		})
+		_ = d.tPut("a", map[string]any{"key": []string{"a", "x"}})
+		_ = d.tPut("b", map[string]any{"key": []string{"a", "y"}})
+		_ = d.tPut("c", map[string]any{"key": []string{"a", "y"}})
+		_ = d.tPut("d", map[string]any{"key": []string{"b", "x"}})
+		_ = d.tPut("e", map[string]any{"key": []string{"c", "z"}})
+
+		return test{
+			db:   d,
+			ddoc: "_design/foo",
+			view: "_view/bar",
+			options: kivik.Params(map[string]any{
+				"startkey": []string{"a", "y"},
+				"endkey":   []string{"b", "x"},
+			}),
+			want: []rowResult{
+				{Key: `null`, Value: `2`},
+			},
+		}
+	})
+	tests.Add("_approx_count_distinct with group_level", func(t *testing.T) any {
+		d := newDB(t)
+		_ = d.tPut("_design/foo", map[string]any{
+			"views": map[string]any{
+				"bar": map[string]string{
+					"map": `function(doc) {
+							if (doc.key) {
+								emit(doc.key, null);
+							}
+						}`,
+					"reduce": `_approx_count_distinct`,
+				},
+			},
+		})
+		_ = d.tPut("a", map[string]any{"key": []string{"a", "x"}})
+		_ = d.tPut("b", map[string]any{"key": []string{"a", "y"}})
+		_ = d.tPut("c", map[string]any{"key": []string{"a", "y"}})
+		_ = d.tPut("d", map[string]any{"key": []string{"b", "x"}})
+		_ = d.tPut("e", map[string]any{"key": []string{"c", "z"}})
+
+		return test{
+			db:      d,
+			ddoc:    "_design/foo",
+			view:    "_view/bar",
+			options: kivik.Param("group_level", 1),
+			want: []rowResult{
+				{Key: `["a"]`, Value: `2`},
+				{Key: `["b"]`, Value: `1`},
+				{Key: `["c"]`, Value: `1`},
+			},
+		}
+	})
+	tests.Add("_approx_count_distinct with group", func(t *testing.T) any {
+		d := newDB(t)
+		_ = d.tPut("_design/foo", map[string]any{
+			"views": map[string]any{
+				"bar": map[string]string{
+					"map": `function(doc) {
+							if (doc.key) {
+								emit(doc.key, null);
+							}
+						}`,
+					"reduce": `_approx_count_distinct`,
+				},
+			},
+		})
+		_ = d.tPut("a", map[string]any{"key": []string{"a", "x"}})
+		_ = d.tPut("b", map[string]any{"key": []string{"a", "y"}})
+		_ = d.tPut("c", map[string]any{"key": []string{"a", "y"}})
+
+		return test{
+			db:      d,
+			ddoc:    "_design/foo",
+			view:    "_view/bar",
+			options: kivik.Param("group", true),
+			want: []rowResult{
+				{Key: `["a","x"]`, Value: `1`},
+				{Key: `["a","y"]`, Value: `1`},
+			},
+		}
+	})
 	tests.Add("limit=1", func(t *testing.T) any {
 		d := newDB(t)
 		_ = d.tPut("_design/foo", map[string]any{
@@ -2578,10 +2707,6 @@ func TestDBQuery(t *testing.T) {
 		- _sum
 			- extended capabilities: https://docs.couchdb.org/en/stable/ddocs/ddocs.html#sum
 		- built-in reduce functions:
-			- _approx_count_distinct (https://docs.couchdb.org/en/stable/ddocs/ddocs.html#approx_count_distinct)
-				- _approx_count_distinct
-				- start/end keys
-				- group behavior
 			- _stats (https://docs.couchdb.org/en/stable/ddocs/ddocs.html#stats)
 		- map/reduce function takes too long
 		- include design docs
@@ -2886,3 +3011,142 @@ func TestDBQuery_update_lazy(t *testing.T) {
 		{ID: "foo", Key: `"foo"`, Value: "null"},
 	})
 }
+
+func TestDBQuery_js_pool(t *testing.T) {
+	t.Parallel()
+
+	dsn := fmt.Sprintf("file:jspool%d?mode=memory&cache=shared", dbSeq.Add(1))
+	logs := &bytes.Buffer{}
+	c, err := drv{}.NewClient(dsn, multiOptions{
+		OptionLogger(log.New(logs, "", 0)),
+		OptionJSPoolSize(4),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.(*client).Close() })
+	if got := c.(*client).js.PoolSize(); got != 4 {
+		t.Fatalf("Unexpected pool size: %d", got)
+	}
+	if err := c.CreateDB(context.Background(), "test", nil); err != nil {
+		t.Fatal(err)
+	}
+	rawDB, err := c.DB("test", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = rawDB.Close() })
+	d := &testDB{DB: rawDB.(DB), t: t, logs: logs}
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"views": map[string]any{
+			"bar": map[string]string{
+				"map": `function(doc) {
+					if (doc.n === 7) { throw("seven"); }
+					emit(doc.n, null);
+					emit(doc.n, 1);
+				}`,
+			},
+		},
+	})
+	// Enough documents to span several batches.
+	const docs = 250
+	var deleteRev string
+	for i := 0; i < docs; i++ {
+		rev := d.tPut(fmt.Sprintf("doc%03d", i), map[string]any{"n": i})
+		if i == 10 {
+			deleteRev = rev
+		}
+	}
+	_ = d.tDelete("doc010", kivik.Rev(deleteRev))
+
+	rows, err := d.Query(context.Background(), "_design/foo", "_view/bar", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := readRows(t, rows)
+	var want []rowResult
+	for i := 0; i < docs; i++ {
+		if i == 7 || i == 10 {
+			continue
+		}
+		id := fmt.Sprintf("doc%03d", i)
+		key := strconv.Itoa(i)
+		want = append(want,
+			rowResult{ID: id, Key: key, Value: "null"},
+			rowResult{ID: id, Key: key, Value: "1"},
+		)
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Unexpected rows:\n%s", d)
+	}
+	if !strings.Contains(logs.String(), "map function threw exception for doc007: seven") {
+		t.Errorf("Expected exception to be logged, got: %s", logs.String())
+	}
+}
+
+func TestDBQuery_views_lib(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"views": map[string]any{
+			"lib": map[string]any{
+				"keys": `exports.key = function(doc) { return require('./util').upper(doc._id); };`,
+				"util": `exports.upper = function(s) { return s.toUpperCase(); };`,
+				// A module named "map" must not be mistaken for a view.
+				"map": `exports.unused = true;`,
+			},
+			"bar": map[string]string{
+				"map": `function(doc) { emit(require('views/lib/keys').key(doc), null); }`,
+			},
+		},
+	})
+	_ = d.tPut("foo", map[string]string{"_id": "foo"})
+
+	rows, err := d.Query(context.Background(), "_design/foo", "_view/bar", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, rows, []rowResult{
+		{ID: "foo", Key: `"FOO"`, Value: "null"},
+	})
+
+	_, err = d.Query(context.Background(), "_design/foo", "_view/lib", mock.NilOption)
+	if status := kivik.HTTPStatus(err); status != http.StatusNotFound {
+		t.Errorf("Unexpected error querying views.lib: %v", err)
+	}
+}
+
+func TestDBQuery_log(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"views": map[string]any{
+			"bar": map[string]string{
+				"map":    `function(doc) { log("mapping " + doc._id); emit(doc._id, 1); }`,
+				"reduce": `function(keys, values, rereduce) { log({count: values.length}); return sum(values); }`,
+			},
+		},
+	})
+	_ = d.tPut("foo", map[string]string{"_id": "foo"})
+
+	rows, err := d.Query(context.Background(), "_design/foo", "_view/bar", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, rows, []rowResult{
+		{Key: "null", Value: "1"},
+	})
+
+	logs := d.logs.String()
+	for _, want := range []string{
+		"log from _design/foo views/bar/map: mapping foo",
+		`log from _design/foo views/bar/reduce: {"count":1}`,
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("Expected log %q, got:\n%s", want, logs)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+// Package queryserver implements the client side of the CouchDB [query server
+// protocol], which is used to run design document functions written in
+// languages other than JavaScript in external processes.
+//
+// [query server protocol]: https://docs.couchdb.org/en/stable/query-server/protocol.html
+package queryserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"sync"
+)
+
+// Error is an error reported by a query server.
+type Error struct {
+	// Type is the error type, such as "compilation_error". When the query
+	// server responds with a {"forbidden": "reason"} or
+	// {"unauthorized": "reason"} object, Type is "forbidden" or
+	// "unauthorized" respectively.
+	Type   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return e.Type + ": " + e.Reason
+}
+
+// HTTPStatus returns the HTTP status code associated with the error.
+func (e *Error) HTTPStatus() int {
+	switch e.Type {
+	case "forbidden":
+		return http.StatusForbidden
+	case "unauthorized":
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+// Process is a single query server process. A Process is not safe for
+// concurrent use; see [Pool].
+type Process struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+	log func(string)
+
+	// ddocs holds the keys of the design documents which have been sent to
+	// the process with the "ddoc new" command.
+	ddocs map[string]struct{}
+	// err is set when the process can no longer be used, for instance
+	// because a call was interrupted.
+	err error
+}
+
+// Start starts a new query server process. Messages logged by the query
+// server are passed to log, which may be nil.
+func Start(command string, args []string, log func(string)) (*Process, error) {
+	cmd := exec.Command(command, args...)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start query server: %w", err)
+	}
+	return &Process{
+		cmd:   cmd,
+		in:    in,
+		out:   bufio.NewReader(out),
+		log:   log,
+		ddocs: map[string]struct{}{},
+	}, nil
+}
+
+// SetLog sets the function to which messages logged by the query server are
+// passed.
+func (p *Process) SetLog(log func(string)) {
+	p.log = log
+}
+
+// Err returns the error which made the process unusable, if any.
+func (p *Process) Err() error {
+	return p.err
+}
+
+// Close stops the process.
+func (p *Process) Close() error {
+	_ = p.in.Close()
+	if p.err != nil {
+		// The process may be stuck; don't wait for it to exit on its own.
+		_ = p.cmd.Process.Kill()
+	}
+	_ = p.cmd.Wait()
+	return nil
+}
+
+// call sends a command to the query server, and returns the response. Log
+// messages received before the response are passed to the log function. If
+// ctx is cancelled before the response is received, the process is killed.
+func (p *Process) call(ctx context.Context, command ...any) (json.RawMessage, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	line, err := json.Marshal(command)
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = p.cmd.Process.Kill()
+	})
+	defer stop()
+	resp, err := p.roundTrip(append(line, '\n'))
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		p.err = ctxErr
+		return nil, ctxErr
+	}
+	var qsErr *Error
+	switch {
+	case errors.As(err, &qsErr):
+		// The query server reported an error, but remains usable.
+		return nil, err
+	case err != nil:
+		p.err = fmt.Errorf("query server failed: %w", err)
+		return nil, p.err
+	}
+	return resp, nil
+}
+
+func (p *Process) roundTrip(line []byte) (json.RawMessage, error) {
+	if _, err := p.in.Write(line); err != nil {
+		return nil, err
+	}
+	for {
+		resp, err := p.out.ReadBytes('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		resp = bytes.TrimSpace(resp)
+		var tuple []json.RawMessage
+		if json.Unmarshal(resp, &tuple) == nil && len(tuple) > 0 {
+			var tag string
+			_ = json.Unmarshal(tuple[0], &tag)
+			switch tag {
+			case "log":
+				if p.log != nil && len(tuple) > 1 {
+					var msg string
+					if json.Unmarshal(tuple[1], &msg) != nil {
+						msg = string(tuple[1])
+					}
+					p.log(msg)
+				}
+				continue
+			case "error":
+				qsErr := &Error{}
+				if len(tuple) > 1 {
+					_ = json.Unmarshal(tuple[1], &qsErr.Type)
+				}
+				if len(tuple) > 2 {
+					_ = json.Unmarshal(tuple[2], &qsErr.Reason)
+				}
+				return nil, qsErr
+			}
+		}
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(resp, &obj) == nil {
+			if qsErr := objectError(obj); qsErr != nil {
+				return nil, qsErr
+			}
+		}
+		return resp, nil
+	}
+}
+
+// objectError returns the error described by an error object, or nil if obj
+// does not describe an error.
+func objectError(obj map[string]json.RawMessage) *Error {
+	if raw, ok := obj["error"]; ok {
+		qsErr := &Error{}
+		_ = json.Unmarshal(raw, &qsErr.Type)
+		_ = json.Unmarshal(obj["reason"], &qsErr.Reason)
+		return qsErr
+	}
+	for _, typ := range []string{"forbidden", "unauthorized"} {
+		if raw, ok := obj[typ]; ok && len(obj) == 1 {
+			qsErr := &Error{Type: typ}
+			if json.Unmarshal(raw, &qsErr.Reason) != nil {
+				qsErr.Reason = string(raw)
+			}
+			return qsErr
+		}
+	}
+	return nil
+}
+
+// callTrue sends a command which is expected to return true.
+func (p *Process) callTrue(ctx context.Context, command ...any) error {
+	resp, err := p.call(ctx, command...)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(resp, []byte("true")) {
+		return fmt.Errorf("unexpected response to %v: %s", command[0], resp)
+	}
+	return nil
+}
+
+// Reset resets the query server state, removing any functions and libraries
+// added with [Process.AddFun] and [Process.AddLib].
+func (p *Process) Reset(ctx context.Context) error {
+	return p.callTrue(ctx, "reset")
+}
+
+// AddLib makes the views.lib modules of a design document available to map
+// functions.
+func (p *Process) AddLib(ctx context.Context, lib any) error {
+	return p.callTrue(ctx, "add_lib", lib)
+}
+
+// AddFun adds a map function, which is applied by [Process.MapDoc].
+func (p *Process) AddFun(ctx context.Context, source string) error {
+	return p.callTrue(ctx, "add_fun", source)
+}
+
+// MapDoc applies each function added with [Process.AddFun] to doc. The result
+// holds the emitted [key, value] pairs of each function, in the order in
+// which the functions were added.
+func (p *Process) MapDoc(ctx context.Context, doc any) ([][][2]any, error) {
+	resp, err := p.call(ctx, "map_doc", doc)
+	if err != nil {
+		return nil, err
+	}
+	var result [][][2]any
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("invalid map_doc response: %w", err)
+	}
+	return result, nil
+}
+
+// Reduce calls each of the reduce functions on the keys, which are
+// [key, docid] pairs, and values, and returns one result per function.
+func (p *Process) Reduce(ctx context.Context, funcs []string, keys [][2]any, values []any) ([]any, error) {
+	kvs := make([][2]any, len(keys))
+	for i, key := range keys {
+		kvs[i] = [2]any{key, values[i]}
+	}
+	return p.reduceResult(p.call(ctx, "reduce", funcs, kvs))
+}
+
+// Rereduce calls each of the reduce functions on values, which are the results
+// of previous reductions, and returns one result per function.
+func (p *Process) Rereduce(ctx context.Context, funcs []string, values []any) ([]any, error) {
+	return p.reduceResult(p.call(ctx, "rereduce", funcs, values))
+}
+
+func (p *Process) reduceResult(resp json.RawMessage, err error) ([]any, error) {
+	if err != nil {
+		return nil, err
+	}
+	var result []json.RawMessage
+	if err := json.Unmarshal(resp, &result); err != nil || len(result) != 2 {
+		return nil, fmt.Errorf("invalid reduce response: %s", resp)
+	}
+	var values []any
+	if err := json.Unmarshal(result[1], &values); err != nil {
+		return nil, fmt.Errorf("invalid reduce response: %w", err)
+	}
+	return values, nil
+}
+
+// DDoc calls the function at path, such as ["filters", "name"], of a design
+// document, with args. The design document is sent to the query server the
+// first time it is used, and cached thereafter; key must change whenever the
+// design document does, so a key of the form "id/rev" is recommended.
+func (p *Process) DDoc(ctx context.Context, key string, ddoc any, path []string, args []any) (json.RawMessage, error) {
+	if _, ok := p.ddocs[key]; !ok {
+		if err := p.callTrue(ctx, "ddoc", "new", key, ddoc); err != nil {
+			return nil, err
+		}
+		p.ddocs[key] = struct{}{}
+	}
+	return p.call(ctx, "ddoc", key, path, args)
+}
+
+// Pool is a pool of processes running the same query server command.
+type Pool struct {
+	command string
+	args    []string
+
+	mu     sync.Mutex
+	idle   []*Process
+	closed bool
+}
+
+// NewPool returns a new pool of processes, which run command with args.
+// Processes are started as needed.
+func NewPool(command string, args ...string) *Pool {
+	return &Pool{command: command, args: args}
+}
+
+// Get returns an idle process from the pool, or starts a new one. Messages
+// logged by the query server are passed to log. The process must be returned
+// to the pool with [Pool.Put] when it is no longer needed.
+func (p *Pool) Get(log func(string)) (*Process, error) {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		proc := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		proc.SetLog(log)
+		return proc, nil
+	}
+	p.mu.Unlock()
+	return Start(p.command, p.args, log)
+}
+
+// Put returns a process to the pool. Processes which can no longer be used
+// are stopped instead.
+func (p *Pool) Put(proc *Process) {
+	proc.SetLog(nil)
+	p.mu.Lock()
+	if proc.Err() == nil && !p.closed {
+		p.idle = append(p.idle, proc)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	_ = proc.Close()
+}
+
+// Close stops all idle processes. Processes returned to the pool after Close
+// is called are stopped.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+	for _, proc := range idle {
+		_ = proc.Close()
+	}
+	return nil
+}
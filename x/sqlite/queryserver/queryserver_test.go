@@ -0,0 +1,277 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package queryserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/x/sqlite/v4/internal/querystub"
+)
+
+func TestMain(m *testing.M) {
+	querystub.Main()
+	// Processes started by the tests re-run this binary as the stub query
+	// server.
+	_ = os.Setenv(querystub.EnvVar, "1")
+	os.Exit(m.Run())
+}
+
+func startStub(t *testing.T, log func(string)) *Process {
+	t.Helper()
+	p, err := Start(os.Args[0], nil, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	return p
+}
+
+func TestProcessMapDoc(t *testing.T) {
+	t.Parallel()
+
+	var logs []string
+	p := startStub(t, func(msg string) {
+		logs = append(logs, msg)
+	})
+	ctx := context.Background()
+	if err := p.Reset(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddLib(ctx, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range []string{"name", "age"} {
+		if err := p.AddFun(ctx, fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := p.MapDoc(ctx, map[string]any{"_id": "foo", "name": "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][][2]any{
+		{{"bob", float64(1)}},
+		{},
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Unexpected result:\n%s", d)
+	}
+	if d := cmp.Diff([]string{"mapping foo", "mapping foo"}, logs); d != "" {
+		t.Errorf("Unexpected logs:\n%s", d)
+	}
+
+	// Reset removes the functions.
+	if err := p.Reset(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, err = p.MapDoc(ctx, map[string]any{"_id": "foo", "name": "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([][][2]any{}, got); d != "" {
+		t.Errorf("Unexpected result after reset:\n%s", d)
+	}
+}
+
+func TestProcessErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("compilation error", func(t *testing.T) {
+		t.Parallel()
+		p := startStub(t, nil)
+		err := p.AddFun(ctx, "")
+		var qsErr *Error
+		if !errors.As(err, &qsErr) || qsErr.Type != "compilation_error" {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if status := qsErr.HTTPStatus(); status != http.StatusInternalServerError {
+			t.Errorf("Unexpected status: %d", status)
+		}
+		// The process remains usable after an error response.
+		if err := p.Reset(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("process exits", func(t *testing.T) {
+		t.Parallel()
+		p := startStub(t, nil)
+		if err := p.AddFun(ctx, "crash"); err != nil {
+			t.Fatal(err)
+		}
+		_, err := p.MapDoc(ctx, map[string]any{})
+		if !testy.ErrorMatches("query server failed: unexpected EOF", err) {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if p.Err() == nil {
+			t.Error("Expected the process to be unusable")
+		}
+		if err := p.Reset(ctx); !testy.ErrorMatches("query server failed: unexpected EOF", err) {
+			t.Errorf("Unexpected error after failure: %v", err)
+		}
+	})
+	t.Run("cancelled context", func(t *testing.T) {
+		t.Parallel()
+		p := startStub(t, nil)
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		if err := p.Reset(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !errors.Is(p.Err(), context.Canceled) {
+			t.Errorf("Unexpected process error: %v", p.Err())
+		}
+	})
+}
+
+func TestProcessReduce(t *testing.T) {
+	t.Parallel()
+
+	p := startStub(t, nil)
+	ctx := context.Background()
+	keys := [][2]any{{"a", "doc1"}, {"b", "doc2"}, {"c", "doc3"}}
+	got, err := p.Reduce(ctx, []string{"sum", "count"}, keys, []any{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]any{float64(6), float64(3)}, got); d != "" {
+		t.Errorf("Unexpected reduce result:\n%s", d)
+	}
+	got, err = p.Rereduce(ctx, []string{"sum", "count"}, []any{6, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]any{float64(9), float64(9)}, got); d != "" {
+		t.Errorf("Unexpected rereduce result:\n%s", d)
+	}
+}
+
+func TestProcessDDoc(t *testing.T) {
+	t.Parallel()
+
+	ddoc := map[string]any{
+		"_id":                 "_design/foo",
+		"filters":             map[string]any{"active": "active"},
+		"validate_doc_update": "name",
+	}
+
+	type test struct {
+		path    []string
+		args    []any
+		want    string
+		wantErr string
+		status  int
+	}
+
+	tests := testy.NewTable()
+	tests.Add("filter", test{
+		path: []string{"filters", "active"},
+		args: []any{[]any{map[string]any{"active": true}, map[string]any{}}, map[string]any{}},
+		want: `[true,[true,false]]`,
+	})
+	tests.Add("validate passes", test{
+		path: []string{"validate_doc_update"},
+		args: []any{map[string]any{"name": "bob"}, nil, map[string]any{}, map[string]any{}},
+		want: `1`,
+	})
+	tests.Add("validate forbidden", test{
+		path:    []string{"validate_doc_update"},
+		args:    []any{map[string]any{}, nil, map[string]any{}, map[string]any{}},
+		wantErr: "forbidden: missing field name",
+		status:  http.StatusForbidden,
+	})
+	tests.Add("missing function", test{
+		path:    []string{"filters", "missing"},
+		args:    []any{[]any{}, map[string]any{}},
+		wantErr: `not_found: missing function: \[filters missing\]`,
+		status:  http.StatusInternalServerError,
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		p := startStub(t, nil)
+		got, err := p.DDoc(context.Background(), "_design/foo/1-abc", ddoc, tt.path, tt.args)
+		if !testy.ErrorMatchesRE(tt.wantErr, err) {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err != nil {
+			var qsErr *Error
+			if !errors.As(err, &qsErr) || qsErr.HTTPStatus() != tt.status {
+				t.Errorf("Unexpected status for error: %v", err)
+			}
+			return
+		}
+		if string(got) != tt.want {
+			t.Errorf("Unexpected result: %s", got)
+		}
+	})
+}
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+
+	pool := NewPool(os.Args[0])
+	t.Cleanup(func() { _ = pool.Close() })
+	ctx := context.Background()
+
+	p1, err := pool.Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The design doc is only sent once per process.
+	ddoc := json.RawMessage(`{"filters":{"f":"x"}}`)
+	for i := 0; i < 2; i++ {
+		if _, err := p1.DDoc(ctx, "_design/foo/1-abc", ddoc, []string{"filters", "f"}, []any{[]any{}, nil}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(p1.ddocs) != 1 {
+		t.Errorf("Expected one cached design doc, got %d", len(p1.ddocs))
+	}
+	pool.Put(p1)
+
+	p2, err := pool.Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p2 != p1 {
+		t.Error("Expected the idle process to be reused")
+	}
+
+	// A broken process is not returned to the pool.
+	if err := p2.AddFun(ctx, "crash"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p2.MapDoc(ctx, map[string]any{}); err == nil {
+		t.Fatal("Expected an error")
+	}
+	pool.Put(p2)
+	p3, err := pool.Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p3 == p2 {
+		t.Error("Expected a broken process to be discarded")
+	}
+	pool.Put(p3)
+}
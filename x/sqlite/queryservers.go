@@ -0,0 +1,335 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-kivik/kivik/v4"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/x/sqlite/v4/js"
+	"github.com/go-kivik/kivik/x/sqlite/v4/queryserver"
+	"github.com/go-kivik/kivik/x/sqlite/v4/reduce"
+)
+
+type optionQueryServer struct {
+	language string
+	command  string
+	args     []string
+}
+
+var _ kivik.Option = optionQueryServer{}
+
+func (o optionQueryServer) Apply(target any) {
+	if client, ok := target.(*client); ok {
+		if client.queryServers == nil {
+			client.queryServers = map[string]*queryserver.Pool{}
+		}
+		client.queryServers[o.language] = queryserver.NewPool(o.command, o.args...)
+	}
+}
+
+// OptionQueryServer registers an external [query server] for design documents
+// whose language field is language, in the same way as CouchDB's
+// COUCHDB_QUERY_SERVER_<LANGUAGE> environment variables. Processes running
+// command with args are started as needed, and communicate using the line
+// based query server protocol over stdin and stdout.
+//
+// Views, filters, update functions and validate_doc_update functions are
+// supported. JavaScript functions are always run in-process.
+//
+// [query server]: https://docs.couchdb.org/en/stable/query-server/protocol.html
+func OptionQueryServer(language, command string, args ...string) kivik.Option {
+	return optionQueryServer{language: language, command: command, args: args}
+}
+
+// queryServer returns the query server pool for language, or nil for
+// JavaScript and Go, which are run in-process.
+func (d *db) queryServer(language string) (*queryserver.Pool, error) {
+	if language == "" || language == "javascript" || language == GoLanguage {
+		return nil, nil
+	}
+	if pool, ok := d.queryServers[language]; ok {
+		return pool, nil
+	}
+	return nil, &internal.Error{Status: http.StatusInternalServerError, Message: "unknown query language: " + language}
+}
+
+// errUnsupportedLanguage is returned for design functions which can only be
+// run in JavaScript.
+func errUnsupportedLanguage(language, funcName string) error {
+	return &internal.Error{Status: http.StatusNotImplemented, Message: fmt.Sprintf("%s functions are not supported for language %s", funcName, language)}
+}
+
+// viewMapper maps documents while updating a view index. It is implemented by
+// [js.MapPool], [qsMapper] and [goMapper].
+type viewMapper interface {
+	// Map returns the key/value pairs emitted for doc.
+	Map(ctx context.Context, doc any) ([]js.Emitted, error)
+	// Size returns the number of documents which may be mapped concurrently.
+	Size() int
+}
+
+// viewMapper returns the mapper for a view, and a function which must be
+// called to release it, once the index has been updated.
+func (d *db) viewMapper(ctx context.Context, ddoc, view, language string, ddocBody []byte, mapFunc string) (viewMapper, func(), error) {
+	if language == GoLanguage {
+		mapper, err := d.goFuncs.mapper(mapFunc, d.js.PoolSize())
+		return mapper, func() {}, err
+	}
+	pool, err := d.queryServer(language)
+	if err != nil {
+		return nil, nil, err
+	}
+	ddocID := "_design/" + ddoc
+	funcName := "views/" + view + "/map"
+	if pool == nil {
+		rt, err := d.designJS(ddocID, funcName, ddocBody)
+		if err != nil {
+			return nil, nil, err
+		}
+		mapPool, err := rt.MapPool(mapFunc)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mapPool, func() {}, nil
+	}
+
+	var body struct {
+		Views struct {
+			Lib any `json:"lib"`
+		} `json:"views"`
+	}
+	if err := json.Unmarshal(ddocBody, &body); err != nil {
+		return nil, nil, err
+	}
+	proc, err := pool.Get(d.jsLog(ddocID, funcName))
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() { pool.Put(proc) }
+	err = proc.Reset(ctx)
+	if err == nil && body.Views.Lib != nil {
+		err = proc.AddLib(ctx, body.Views.Lib)
+	}
+	if err == nil {
+		err = proc.AddFun(ctx, mapFunc)
+	}
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return &qsMapper{proc: proc}, release, nil
+}
+
+// qsMapper maps documents with a single query server process, to which the
+// map function has been added.
+type qsMapper struct {
+	proc *queryserver.Process
+}
+
+var _ viewMapper = (*qsMapper)(nil)
+
+func (m *qsMapper) Size() int { return 1 }
+
+func (m *qsMapper) Map(ctx context.Context, doc any) ([]js.Emitted, error) {
+	results, err := m.proc.MapDoc(ctx, doc)
+	if err != nil {
+		if m.proc.Err() != nil {
+			return nil, &mapAbortError{err: err}
+		}
+		return nil, err
+	}
+	if len(results) != 1 {
+		return nil, &mapAbortError{err: fmt.Errorf("query server returned %d map results, expected 1", len(results))}
+	}
+	emitted := make([]js.Emitted, len(results[0]))
+	for i, kv := range results[0] {
+		emitted[i] = js.Emitted{Key: kv[0], Value: kv[1]}
+	}
+	return emitted, nil
+}
+
+// mapAbortError is returned by a [viewMapper] when a failure prevents any
+// further documents from being mapped, as opposed to an exception raised by
+// the map function for a single document.
+type mapAbortError struct {
+	err error
+}
+
+func (e *mapAbortError) Error() string { return e.err.Error() }
+
+func (e *mapAbortError) Unwrap() error { return e.err }
+
+// reduceCompiler returns the compiler for the named view's user-defined reduce
+// function, reduceFunc, and a function which must be called to release it
+// once reduction is complete.
+func (d *db) reduceCompiler(ctx context.Context, ddoc, view string, rev revision, reduceFunc string) (reduce.Compiler, func(), error) {
+	if reduceFunc == "" || strings.HasPrefix(reduceFunc, "_") {
+		// Built-in reduce functions are never compiled.
+		return d.reduceJS(ddoc, view), func() {}, nil
+	}
+	var language string
+	err := d.db.QueryRowContext(ctx, d.query(`
+		SELECT language
+		FROM {{ .Design }}
+		WHERE id = $1
+			AND rev = $2
+			AND rev_id = $3
+			AND func_type = 'reduce'
+			AND func_name = $4
+	`), "_design/"+ddoc, rev.rev, rev.id, view).Scan(&language)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, err
+	}
+	if language == GoLanguage {
+		return d.goFuncs, func() {}, nil
+	}
+	pool, err := d.queryServer(language)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pool == nil {
+		return d.reduceJS(ddoc, view), func() {}, nil
+	}
+	proc, err := pool.Get(d.jsLog("_design/"+ddoc, "views/"+view+"/reduce"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return qsReducer{proc: proc}, func() { pool.Put(proc) }, nil
+}
+
+// qsReducer compiles reduce functions to be run by a query server process.
+type qsReducer struct {
+	proc *queryserver.Process
+}
+
+var _ reduce.Compiler = qsReducer{}
+
+func (r qsReducer) Reduce(code string) (js.ReduceFunc, error) {
+	funcs := []string{code}
+	return func(ctx context.Context, keys [][2]any, values []any, rereduce bool) ([]any, error) {
+		if rereduce {
+			return r.proc.Rereduce(ctx, funcs, values)
+		}
+		return r.proc.Reduce(ctx, funcs, keys, values)
+	}, nil
+}
+
+// qsDDoc is a design document whose functions are run by a query server.
+type qsDDoc struct {
+	pool *queryserver.Pool
+	// key identifies the revision of the design document to the query
+	// server.
+	key  string
+	ddoc map[string]any
+	log  func(string)
+}
+
+// qsDDoc returns the design document with the given id, revision and body,
+// to be run by pool.
+func (d *db) qsDDoc(pool *queryserver.Pool, ddocID string, rev revision, body []byte, funcName string) (*qsDDoc, error) {
+	var ddoc map[string]any
+	if err := json.Unmarshal(body, &ddoc); err != nil {
+		return nil, err
+	}
+	ddoc["_id"] = ddocID
+	ddoc["_rev"] = rev.String()
+	return &qsDDoc{
+		pool: pool,
+		key:  ddocID + "/" + rev.String(),
+		ddoc: ddoc,
+		log:  d.jsLog(ddocID, funcName),
+	}, nil
+}
+
+func (q *qsDDoc) call(ctx context.Context, path []string, args ...any) (json.RawMessage, error) {
+	proc, err := q.pool.Get(q.log)
+	if err != nil {
+		return nil, err
+	}
+	defer q.pool.Put(proc)
+	return proc.DDoc(ctx, q.key, q.ddoc, path, args)
+}
+
+// Filter returns the named filter function.
+func (q *qsDDoc) Filter(name string) js.FilterFunc {
+	return func(ctx context.Context, doc, req any) (bool, error) {
+		resp, err := q.call(ctx, []string{"filters", name}, []any{doc}, req)
+		if err != nil {
+			return false, err
+		}
+		var (
+			result []json.RawMessage
+			passes []bool
+		)
+		if err := json.Unmarshal(resp, &result); err != nil || len(result) != 2 {
+			return false, fmt.Errorf("invalid filter response: %s", resp)
+		}
+		if err := json.Unmarshal(result[1], &passes); err != nil || len(passes) != 1 {
+			return false, fmt.Errorf("invalid filter response: %s", resp)
+		}
+		return passes[0], nil
+	}
+}
+
+// Validate returns the validate_doc_update function.
+func (q *qsDDoc) Validate() js.ValidateFunc {
+	return func(ctx context.Context, newDoc, oldDoc, userCtx, secObj any) error {
+		_, err := q.call(ctx, []string{"validate_doc_update"}, newDoc, oldDoc, userCtx, secObj)
+		var qsErr *queryserver.Error
+		if errors.As(err, &qsErr) && qsErr.HTTPStatus() != http.StatusInternalServerError {
+			// As with JavaScript, only the reason is reported for
+			// forbidden and unauthorized errors.
+			return &internal.Error{Status: qsErr.HTTPStatus(), Message: qsErr.Reason}
+		}
+		return err
+	}
+}
+
+// UpdateResponse returns the named update function.
+func (q *qsDDoc) UpdateResponse(name string) js.UpdateResponseFunc {
+	return func(ctx context.Context, doc, req any) (any, *js.Response, error) {
+		raw, err := q.call(ctx, []string{"updates", name}, doc, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		var result []any
+		if err := json.Unmarshal(raw, &result); err != nil || len(result) != 3 || result[0] != "up" {
+			return nil, nil, fmt.Errorf("invalid update response: %s", raw)
+		}
+		resp, err := js.NewResponse(result[2])
+		if err != nil {
+			return nil, nil, fmt.Errorf("update function returned invalid response: %w", err)
+		}
+		return result[1], resp, nil
+	}
+}
+
+// Update returns the named update function, discarding its response.
+func (q *qsDDoc) Update(name string) js.UpdateFunc {
+	fn := q.UpdateResponse(name)
+	return func(ctx context.Context, doc, req any) (any, string, error) {
+		newDoc, resp, err := fn(ctx, doc, req)
+		if err != nil {
+			return nil, "", err
+		}
+		return newDoc, string(resp.Body), nil
+	}
+}
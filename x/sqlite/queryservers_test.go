@@ -0,0 +1,289 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	"github.com/go-kivik/kivik/v4/int/mock"
+	"github.com/go-kivik/kivik/x/sqlite/v4/internal/querystub"
+)
+
+func TestMain(m *testing.M) {
+	querystub.Main()
+	// Query servers started by the tests re-run this binary as the stub
+	// query server.
+	_ = os.Setenv(querystub.EnvVar, "1")
+	os.Exit(m.Run())
+}
+
+// newQueryServerDB returns a test database, in which design documents with
+// the language "stub" are run by the stub query server.
+func newQueryServerDB(t *testing.T) *testDB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:querystub%d?mode=memory&cache=shared", dbSeq.Add(1))
+	logs := &bytes.Buffer{}
+	c, err := drv{}.NewClient(dsn, multiOptions{
+		OptionLogger(log.New(logs, "", 0)),
+		OptionQueryServer("stub", os.Args[0]),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.(*client).Close() })
+	if err := c.CreateDB(context.Background(), "test", nil); err != nil {
+		t.Fatal(err)
+	}
+	rawDB, err := c.DB("test", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testDB{DB: rawDB.(DB), t: t, logs: logs}
+}
+
+func TestQueryServer_views(t *testing.T) {
+	t.Parallel()
+	d := newQueryServerDB(t)
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"language": "stub",
+		"views": map[string]any{
+			"names": map[string]string{
+				"map":    "name",
+				"reduce": "count",
+			},
+			"sizes": map[string]string{
+				"map":    "size",
+				"reduce": "sum",
+			},
+		},
+	})
+	_ = d.tPut("a", map[string]any{"name": "alice", "size": 2})
+	_ = d.tPut("b", map[string]any{"name": "bob", "size": 3})
+	_ = d.tPut("c", map[string]any{"name": "bob"})
+
+	ctx := context.Background()
+	rows, err := d.Query(ctx, "_design/foo", "_view/names", kivik.Param("reduce", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, rows, []rowResult{
+		{ID: "a", Key: `"alice"`, Value: "1"},
+		{ID: "b", Key: `"bob"`, Value: "1"},
+		{ID: "c", Key: `"bob"`, Value: "1"},
+	})
+
+	rows, err = d.Query(ctx, "_design/foo", "_view/names", kivik.Param("group", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, rows, []rowResult{
+		{Key: `"alice"`, Value: "1"},
+		{Key: `"bob"`, Value: "2"},
+	})
+
+	rows, err = d.Query(ctx, "_design/foo", "_view/sizes", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, rows, []rowResult{
+		{Key: "null", Value: "2"},
+	})
+
+	if logs := d.logs.String(); !strings.Contains(logs, "log from _design/foo views/names/map: mapping a") {
+		t.Errorf("Expected query server log messages, got:\n%s", logs)
+	}
+}
+
+func TestQueryServer_view_errors(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		ddoc       map[string]any
+		wantErr    string
+		wantStatus int
+	}
+
+	tests := testy.NewTable()
+	tests.Add("unknown language", test{
+		ddoc: map[string]any{
+			"language": "python",
+			"views": map[string]any{
+				"bar": map[string]string{"map": "name"},
+			},
+		},
+		wantErr:    "unknown query language: python",
+		wantStatus: http.StatusInternalServerError,
+	})
+	tests.Add("compilation error", test{
+		ddoc: map[string]any{
+			"language": "stub",
+			"views": map[string]any{
+				"bar": map[string]string{"map": "not a field"},
+			},
+		},
+		wantErr:    `compilation_error: invalid function: "not a field"`,
+		wantStatus: http.StatusInternalServerError,
+	})
+	tests.Add("query server exits", test{
+		ddoc: map[string]any{
+			"language": "stub",
+			"views": map[string]any{
+				"bar": map[string]string{"map": "crash"},
+			},
+		},
+		wantErr:    "query server failed: unexpected EOF",
+		wantStatus: http.StatusInternalServerError,
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		t.Parallel()
+		d := newQueryServerDB(t)
+		_ = d.tPut("_design/foo", tt.ddoc)
+		_ = d.tPut("a", map[string]any{"name": "alice"})
+
+		_, err := d.Query(context.Background(), "_design/foo", "_view/bar", mock.NilOption)
+		if !testy.ErrorMatches(tt.wantErr, err) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if status := kivik.HTTPStatus(err); status != tt.wantStatus {
+			t.Errorf("Unexpected status: %d", status)
+		}
+	})
+}
+
+func TestQueryServer_filter(t *testing.T) {
+	t.Parallel()
+	d := newQueryServerDB(t)
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"language": "stub",
+		"filters": map[string]string{
+			"active": "active",
+		},
+	})
+	_ = d.tPut("a", map[string]any{"active": true})
+	_ = d.tPut("b", map[string]any{"active": false})
+	_ = d.tPut("c", map[string]any{"active": true})
+
+	feed, err := d.Changes(context.Background(), kivik.Param("filter", "foo/active"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		var change driver.Change
+		if err := feed.Next(&change); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		got = append(got, change.ID)
+	}
+	if d := cmp.Diff([]string{"a", "c"}, got); d != "" {
+		t.Errorf("Unexpected changes:\n%s", d)
+	}
+}
+
+func TestQueryServer_validate(t *testing.T) {
+	t.Parallel()
+	d := newQueryServerDB(t)
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"language":            "stub",
+		"validate_doc_update": "name",
+	})
+
+	if _, err := d.Put(context.Background(), "a", map[string]any{"name": "alice"}, mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+	_, err := d.Put(context.Background(), "b", map[string]any{}, mock.NilOption)
+	if !testy.ErrorMatches("missing field name", err) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if status := kivik.HTTPStatus(err); status != http.StatusForbidden {
+		t.Errorf("Unexpected status: %d", status)
+	}
+}
+
+func TestQueryServer_update(t *testing.T) {
+	t.Parallel()
+	d := newQueryServerDB(t)
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"language": "stub",
+		"updates": map[string]string{
+			"stamp": "stamp",
+		},
+	})
+	_ = d.tPut("a", map[string]any{"name": "alice"})
+
+	resp, err := d.UpdateWithResponse(context.Background(), "foo", "stamp", "a", "today", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated || string(body) != "updated stamp" {
+		t.Errorf("Unexpected response: %d %s", resp.StatusCode, body)
+	}
+	doc, err := d.Get(context.Background(), "a", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docBody, err := io.ReadAll(doc.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(docBody), `"stamp":"\"today\""`) {
+		t.Errorf("Unexpected document: %s", docBody)
+	}
+}
+
+func TestQueryServer_show_unsupported(t *testing.T) {
+	t.Parallel()
+	d := newQueryServerDB(t)
+
+	_ = d.tPut("_design/foo", map[string]any{
+		"language": "stub",
+		"shows": map[string]string{
+			"bar": "name",
+		},
+	})
+
+	_, err := d.Show(context.Background(), "foo", "bar", "", mock.NilOption)
+	if !testy.ErrorMatches("show, list and rewrite functions are not supported for language stub", err) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if status := kivik.HTTPStatus(err); status != http.StatusNotImplemented {
+		t.Errorf("Unexpected status: %d", status)
+	}
+}
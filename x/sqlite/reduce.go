@@ -13,15 +13,330 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"sync"
 
 	"github.com/go-kivik/kivik/x/sqlite/v4/reduce"
 )
 
+const (
+	// reduceNodeSize is the maximum number of map rows summarized by a single
+	// cached reduce node.
+	reduceNodeSize = 100
+	// minReduceNodeSize is the size below which a cached node is merged with
+	// its neighbors when the cache is next updated, to limit fragmentation.
+	minReduceNodeSize = reduceNodeSize / 2
+)
+
+// reduceCacheRow is a map row not yet covered by a cached reduce node.
+type reduceCacheRow struct {
+	pk    int
+	id    string
+	key   *string
+	value *string
+}
+
+// updateReduceCache brings the reduce cache for the view up to date with its
+// map index. Map rows not covered by a cached node, along with those covered
+// by undersized nodes, are grouped into runs which are contiguous in (key,
+// pk) order, and each run is reduced into one or more new nodes.
+func (d *db) updateReduceCache(ctx context.Context, ddoc, view string, rev revision) error {
+	var reduceFuncJS string
+	err := d.db.QueryRowContext(ctx, d.query(`
+		SELECT func_body
+		FROM {{ .Design }}
+		WHERE id = $1
+			AND rev = $2
+			AND rev_id = $3
+			AND func_type = 'reduce'
+			AND func_name = $4
+	`), "_design/"+ddoc, rev.rev, rev.id, view).Scan(&reduceFuncJS)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return err
+	}
+	if reduceFuncJS == "" {
+		return nil
+	}
+	compiler, release, err := d.reduceCompiler(ctx, ddoc, view, rev, reduceFuncJS)
+	if err != nil {
+		return err
+	}
+	defer release()
+	fn, err := reduce.ParseFunc(reduceFuncJS, d.logger, compiler)
+	if err != nil {
+		// Leave the cache empty, so the error is reported when the view is
+		// queried.
+		return nil
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Deleting the undersized nodes first also takes the write lock, so that
+	// concurrent updates cannot create overlapping nodes.
+	if _, err := tx.ExecContext(ctx, d.ddocQuery(ddoc, view, rev.String(), `
+		DELETE FROM {{ .Reduce }}
+		WHERE row_count < $1
+	`), minReduceNodeSize); err != nil {
+		return err
+	}
+
+	runs, err := d.uncachedReduceRuns(ctx, tx, ddoc, view, rev)
+	if err != nil {
+		return err
+	}
+	err = d.insertReduceRuns(ctx, tx, ddoc, view, rev, reduceFuncJS, fn, runs)
+	var reduceErr *reduceCacheError
+	switch {
+	case errors.As(err, &reduceErr):
+		// Keep the nodes cached so far, and leave the remaining rows
+		// uncached, so the error is reported when the view is queried. The
+		// failure is logged only once, as it recurs on every update.
+		if d.reduceFailures.add(d.ddocQuery(ddoc, view, rev.String(), "{{ .Reduce }}")) == 1 {
+			d.logger.Printf("Failed to update reduce cache for _design/%s/_view/%s: %s", ddoc, view, reduceErr.err)
+		}
+	case err != nil:
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertReduceRuns reduces each run into one or more cached nodes.
+func (d *db) insertReduceRuns(ctx context.Context, tx *sql.Tx, ddoc, view string, rev revision, reduceFuncJS string, fn reduce.Func, runs [][]reduceCacheRow) error {
+	for _, run := range runs {
+		for len(run) > 0 {
+			n := min(len(run), reduceNodeSize)
+			if err := d.insertReduceNode(ctx, tx, ddoc, view, rev, reduceFuncJS, fn, run[:n]); err != nil {
+				return err
+			}
+			run = run[n:]
+		}
+	}
+	return nil
+}
+
+// reduceCacheError is returned by insertReduceNode when the reduce function
+// fails.
+type reduceCacheError struct {
+	err error
+}
+
+func (e *reduceCacheError) Error() string { return e.err.Error() }
+
+func (e *reduceCacheError) Unwrap() error { return e.err }
+
+// reduceFailures counts the reduce cache update failures of each view, so
+// that a failing reduce function is logged only once.
+type reduceFailures struct {
+	mu    sync.Mutex
+	count map[string]int
+}
+
+func newReduceFailures() *reduceFailures {
+	return &reduceFailures{count: map[string]int{}}
+}
+
+// add records a failure for the reduce table, and returns the number of
+// failures recorded for it so far.
+func (f *reduceFailures) add(table string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.count[table]++
+	return f.count[table]
+}
+
+// migrateViewMap adds the reduce cache to a map table created before the
+// cache existed.
+func (d *db) migrateViewMap(ctx context.Context, ddoc, view string, rev revision, collation *string) error {
+	migrated, err := d.viewMapMigrated(ctx, ddoc, view, rev)
+	if err != nil || migrated {
+		return err
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := append([]string{`ALTER TABLE {{ .Map }} ADD COLUMN reduce_node INTEGER`}, reduceSchema...)
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, d.createDdocQuery(ddoc, view, rev.String(), query, collation)); err != nil {
+			// A concurrent query may have migrated the table first.
+			if migrated, _ := d.viewMapMigrated(ctx, ddoc, view, rev); migrated {
+				return nil
+			}
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// viewMapMigrated returns true if the map table has the reduce_node column.
+func (d *db) viewMapMigrated(ctx context.Context, ddoc, view string, rev revision) (bool, error) {
+	rows, err := d.db.QueryContext(ctx, d.ddocQuery(ddoc, view, rev.String(), `
+		SELECT * FROM {{ .Map }} LIMIT 0
+	`))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+	for _, column := range columns {
+		if column == "reduce_node" {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// uncachedReduceRuns returns the map rows not covered by a cached node,
+// grouped into runs which are contiguous in (key, pk) order. Uncovered rows
+// are found with the reduce_node index, and two of them are contiguous when
+// the second is the first one's successor in the map index.
+func (d *db) uncachedReduceRuns(ctx context.Context, tx *sql.Tx, ddoc, view string, rev revision) ([][]reduceCacheRow, error) {
+	rows, err := tx.QueryContext(ctx, d.ddocQuery(ddoc, view, rev.String(), `
+		SELECT
+			view.pk,
+			view.id,
+			view.key,
+			view.value,
+			COALESCE(
+				(
+					SELECT next.pk
+					FROM {{ .Map }} AS next
+					WHERE next.key IS view.key
+						AND next.pk > view.pk
+					ORDER BY next.pk
+					LIMIT 1
+				),
+				(
+					SELECT next.pk
+					FROM {{ .Map }} AS next
+					WHERE next.key > view.key
+					ORDER BY next.key, next.pk
+					LIMIT 1
+				),
+				-- NULL keys sort first, so the successor of the last NULL
+				-- key is the first non-NULL key.
+				(
+					SELECT next.pk
+					FROM {{ .Map }} AS next
+					WHERE view.key IS NULL
+						AND next.key IS NOT NULL
+					ORDER BY next.key, next.pk
+					LIMIT 1
+				)
+			) AS next_pk
+		FROM {{ .Map }} AS view
+		WHERE view.reduce_node IS NULL
+		ORDER BY view.key, view.pk
+	`))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		runs     [][]reduceCacheRow
+		lastNext sql.NullInt64
+	)
+	for rows.Next() {
+		var (
+			row  reduceCacheRow
+			next sql.NullInt64
+		)
+		if err := rows.Scan(&row.pk, &row.id, &row.key, &row.value, &next); err != nil {
+			return nil, err
+		}
+		if len(runs) == 0 || !lastNext.Valid || lastNext.Int64 != int64(row.pk) {
+			runs = append(runs, nil)
+		}
+		runs[len(runs)-1] = append(runs[len(runs)-1], row)
+		lastNext = next
+	}
+	return runs, rows.Err()
+}
+
+// insertReduceNode reduces rows, and stores the result as a new cached node.
+func (d *db) insertReduceNode(ctx context.Context, tx *sql.Tx, ddoc, view string, rev revision, reduceFuncJS string, fn reduce.Func, rows []reduceCacheRow) error {
+	keys := make([][2]any, len(rows))
+	values := make([]any, len(rows))
+	for i, row := range rows {
+		var key any
+		if row.key != nil {
+			if err := json.Unmarshal([]byte(*row.key), &key); err != nil {
+				return err
+			}
+		}
+		keys[i] = [2]any{key, row.id}
+		if row.value != nil {
+			if err := json.Unmarshal([]byte(*row.value), &values[i]); err != nil {
+				return err
+			}
+		}
+	}
+	results, err := fn(ctx, keys, values, false)
+	if err != nil {
+		return &reduceCacheError{err: err}
+	}
+	if len(results) != 1 {
+		return nil
+	}
+	var value *string
+	if results[0] != nil {
+		data, err := reduce.MarshalResult(reduceFuncJS, results[0])
+		if err != nil {
+			return err
+		}
+		s := string(data)
+		value = &s
+	}
+
+	first, last := rows[0], rows[len(rows)-1]
+	result, err := tx.ExecContext(ctx, d.ddocQuery(ddoc, view, rev.String(), `
+		INSERT INTO {{ .Reduce }} (first_key, first_pk, last_key, last_pk, row_count, value)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`), first.key, first.pk, last.key, last.pk, len(rows), value)
+	if err != nil {
+		return err
+	}
+	nodePK, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	args := make([]any, 0, len(rows)+1)
+	args = append(args, nodePK)
+	for _, row := range rows {
+		args = append(args, row.pk)
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(d.ddocQuery(ddoc, view, rev.String(), `
+		UPDATE {{ .Map }}
+		SET reduce_node = $1
+		WHERE pk IN (%s)
+	`), placeholders(2, len(rows))), args...)
+	return err
+}
+
 type reduceRowIter struct {
 	results *sql.Rows
+	// reduceFuncJS is used to decode the values of cached reduce nodes.
+	reduceFuncJS string
 }
 
 func (r *reduceRowIter) ReduceNext(row *reduce.Row) error {
@@ -54,11 +369,16 @@ func (r *reduceRowIter) ReduceNext(row *reduce.Row) error {
 		row.LastKey = nil
 		row.LastPK = 0
 	}
-	if value != nil {
+	switch {
+	case value != nil && row.ID == "":
+		if row.Value, err = reduce.UnmarshalResult(r.reduceFuncJS, *value); err != nil {
+			return err
+		}
+	case value != nil:
 		if err = json.Unmarshal(*value, &row.Value); err != nil {
 			return err
 		}
-	} else {
+	default:
 		row.Value = nil
 	}
 	return nil
@@ -27,7 +27,6 @@ import (
 	"github.com/mitchellh/mapstructure"
 
 	internal "github.com/go-kivik/kivik/v4/int/errors"
-	"github.com/go-kivik/kivik/x/sqlite/v4/js"
 )
 
 // Count is the built-in reduce function, [_count].
@@ -383,10 +382,19 @@ func (h *hll) merge(other *hll) {
 	}
 }
 
+// hash64 returns a 64-bit hash of data. FNV-1a alone distributes short,
+// similar inputs poorly across the high bits, which select the register, so
+// the result is passed through the MurmurHash3 finalizer.
 func hash64(data []byte) uint64 {
 	h := fnv.New64a()
 	h.Write(data)
-	return h.Sum64()
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
 }
 
 // ApproxCountDistinct is the built-in reduce function,
@@ -413,12 +421,78 @@ func ApproxCountDistinct(_ context.Context, keys [][2]any, values []any, rereduc
 	return []any{h}, nil
 }
 
+// hllSketch is the serialized form of an hll, as stored between reductions.
+// Only the non-zero registers are stored, as [index, value] pairs, which keeps
+// sketches of small sets small.
+type hllSketch struct {
+	Registers [][2]uint32 `json:"hll"`
+}
+
+func (h *hll) sketch() hllSketch {
+	var s hllSketch
+	for i, val := range h.Registers {
+		if val != 0 {
+			s.Registers = append(s.Registers, [2]uint32{uint32(i), uint32(val)})
+		}
+	}
+	return s
+}
+
+func (s hllSketch) hll() (*hll, error) {
+	h := &hll{}
+	for _, reg := range s.Registers {
+		if reg[0] >= uint32(len(h.Registers)) || reg[1] > 64-hllPrecision+1 {
+			return nil, fmt.Errorf("invalid HyperLogLog register: %v", reg)
+		}
+		h.Registers[reg[0]] = uint8(reg[1])
+	}
+	return h, nil
+}
+
+// MarshalResult encodes a result returned by the reduce function javascript,
+// such that [UnmarshalResult] can restore it for rereduce. For most functions,
+// this is the JSON encoding of the result. The sketch built by
+// _approx_count_distinct is encoded in full, rather than as its estimate, so
+// that it can be merged with others.
+func MarshalResult(javascript string, v any) ([]byte, error) {
+	if h, ok := v.(*hll); ok && javascript == "_approx_count_distinct" {
+		return json.Marshal(h.sketch())
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalResult decodes a result previously encoded by [MarshalResult],
+// such that it can be passed back to the reduce function javascript for
+// rereduce.
+func UnmarshalResult(javascript string, data []byte) (any, error) {
+	if javascript == "_approx_count_distinct" {
+		var s hllSketch
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		return s.hll()
+	}
+	if javascript == "_stats" {
+		if len(data) > 0 && data[0] == '[' {
+			var result []stats
+			err := json.Unmarshal(data, &result)
+			return result, err
+		}
+		var result stats
+		err := json.Unmarshal(data, &result)
+		return result, err
+	}
+	var result any
+	err := json.Unmarshal(data, &result)
+	return result, err
+}
+
 // ParseFunc parses the passed javascript string, and returns a Go function that
 // implements the reduce function. Built-in functions (_count, _sum, _stats,
 // _approx_count_distinct) are returned directly. User-defined functions are
-// compiled using the provided Runtime. The logger is used to log any unhandled
-// exceptions thrown by user-defined JavaScript functions.
-func ParseFunc(javascript string, logger *log.Logger, rt *js.Runtime) (Func, error) {
+// compiled using the provided Compiler. The logger is used to log any unhandled
+// exceptions thrown by user-defined functions.
+func ParseFunc(javascript string, logger *log.Logger, rt Compiler) (Func, error) {
 	switch javascript {
 	case "":
 		return nil, nil
@@ -16,6 +16,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -419,3 +420,88 @@ func TestApproxCountDistinct(t *testing.T) {
 		}
 	})
 }
+
+func TestApproxCountDistinct_accuracy(t *testing.T) {
+	t.Parallel()
+
+	// The standard error of a HyperLogLog sketch with 2^14 registers is
+	// 1.04/sqrt(2^14), or about 0.8%. Allow for three times that.
+	const tolerance = 0.025
+
+	for _, distinct := range []int{1, 10, 100, 1000, 10000, 100000} {
+		t.Run(fmt.Sprint(distinct), func(t *testing.T) {
+			t.Parallel()
+			// Emit each key one to three times, and vary the key types.
+			var keys [][2]any
+			exact := map[string]struct{}{}
+			for i := range distinct {
+				var key any
+				switch i % 3 {
+				case 0:
+					key = float64(i)
+				case 1:
+					key = fmt.Sprintf("key-%d", i)
+				default:
+					key = []any{"key", float64(i)}
+				}
+				for j := 0; j <= i%3; j++ {
+					keys = append(keys, [2]any{key, fmt.Sprintf("doc-%d-%d", i, j)})
+				}
+				data, _ := json.Marshal(key)
+				exact[string(data)] = struct{}{}
+			}
+			want := float64(len(exact))
+
+			// Reduce in chunks, as cached reduce nodes are, round-tripping each
+			// result through its serialized form, before rereducing.
+			var chunks []any
+			for len(keys) > 0 {
+				n := min(len(keys), 100)
+				got, err := ApproxCountDistinct(context.Background(), keys[:n], make([]any, n), false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := MarshalResult("_approx_count_distinct", got[0])
+				if err != nil {
+					t.Fatal(err)
+				}
+				chunk, err := UnmarshalResult("_approx_count_distinct", data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				chunks = append(chunks, chunk)
+				keys = keys[n:]
+			}
+
+			estimate := approxCountDistinct(t, nil, chunks, true)
+			if diff := math.Abs(estimate - want); diff > 1 && diff/want > tolerance {
+				t.Errorf("estimate %v differs from exact count %v by %.1f%%", estimate, want, 100*diff/want)
+			}
+		})
+	}
+}
+
+func TestMarshalResult_approxCountDistinct(t *testing.T) {
+	t.Parallel()
+	keys := [][2]any{{"a", "doc1"}, {"b", "doc2"}, {"c", "doc3"}}
+	got, err := ApproxCountDistinct(context.Background(), keys, make([]any, 3), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalResult("_approx_count_distinct", got[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := UnmarshalResult("_approx_count_distinct", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got[0], restored); d != "" {
+		t.Errorf("Sketch did not survive serialization:\n%s", d)
+	}
+
+	_, err = UnmarshalResult("_approx_count_distinct", []byte(`{"hll":[[16384,1]]}`))
+	if !testy.ErrorMatches("invalid HyperLogLog register: [16384 1]", err) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -109,6 +109,12 @@ func (*Rows) UpdateSeq() string { return "" }
 // [CouchDB reduce function]: https://docs.couchdb.org/en/stable/ddocs/ddocs.html#reduce-and-rereduce-functions
 type Func func(ctx context.Context, keys [][2]any, values []any, rereduce bool) ([]any, error)
 
+// Compiler compiles user-defined reduce functions. It is implemented by
+// [github.com/go-kivik/kivik/x/sqlite/v4/js.Runtime].
+type Compiler interface {
+	Reduce(code string) (js.ReduceFunc, error)
+}
+
 // Callback is called with the group depth and result of each intermediate
 // reduce call. It can be used to cache intermediate results.
 type Callback func(depth uint, rows []Row)
@@ -126,11 +132,11 @@ const defaultBatchSize = 1000
 //	-1: Maximum grouping, same as group=true
 //	 0: No grouping, same as group=false
 //	1+: Group by the first N elements of the key, same as group_level=N
-func Reduce(ctx context.Context, rows Reducer, javascript string, logger *log.Logger, rt *js.Runtime, groupLevel int) (*Rows, error) {
+func Reduce(ctx context.Context, rows Reducer, javascript string, logger *log.Logger, rt Compiler, groupLevel int) (*Rows, error) {
 	return reduceWithBatchSize(ctx, rows, javascript, logger, rt, groupLevel, defaultBatchSize)
 }
 
-func reduceWithBatchSize(ctx context.Context, rows Reducer, javascript string, logger *log.Logger, rt *js.Runtime, groupLevel int, batchSize int) (*Rows, error) {
+func reduceWithBatchSize(ctx context.Context, rows Reducer, javascript string, logger *log.Logger, rt Compiler, groupLevel int, batchSize int) (*Rows, error) {
 	fn, err := ParseFunc(javascript, logger, rt)
 	if err != nil {
 		return nil, err
@@ -243,11 +249,12 @@ func reduce(ctx context.Context, rows Reducer, fn Func, groupLevel int, batchSiz
 	}
 
 	// If we received mixed map/reduce inputs, then we may need to re-reduce
-	// the output before returning.
-	finalKey := truncateKey(out[0].FirstKey, groupLevel)
+	// the output before returning. The output is in key order, so any
+	// duplicate keys are adjacent.
 	for i := 1; i < len(out); i++ {
+		prevKey := truncateKey(out[i-1].FirstKey, groupLevel)
 		key := truncateKey(out[i].FirstKey, groupLevel)
-		if reflect.DeepEqual(finalKey, key) {
+		if reflect.DeepEqual(prevKey, key) {
 			return reduce(ctx, &out, fn, groupLevel, batchSize)
 		}
 	}
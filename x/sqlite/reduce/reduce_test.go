@@ -140,6 +140,19 @@ func TestReduce(t *testing.T) {
 			{TargetKey: []any{1.0, 2.0, 4.0}, FirstKey: []any{1.0, 2.0, 4.0}, FirstPK: 6, LastKey: []any{1.0, 2.0, 4.0}, LastPK: 6, Value: 1.0},
 		},
 	})
+	tests.Add("mixed inputs, duplicate key after the first group", test{
+		input: &Rows{
+			{ID: "a", FirstKey: "a", FirstPK: 1},
+			{FirstKey: "b", FirstPK: 2, LastKey: "b", LastPK: 3, Value: 2.0},
+			{ID: "d", FirstKey: "b", FirstPK: 4},
+		},
+		groupLevel: -1,
+		javascript: "_count",
+		want: []Row{
+			{TargetKey: "a", FirstKey: "a", FirstPK: 1, LastKey: "a", LastPK: 1, Value: 1.0},
+			{TargetKey: "b", FirstKey: "b", FirstPK: 2, LastKey: "b", LastPK: 4, Value: 3.0},
+		},
+	})
 	tests.Add("group level 0", test{
 		input: &Rows{
 			{ID: "a", FirstKey: []any{1.0, 2.0, 3.0}, FirstPK: 1},
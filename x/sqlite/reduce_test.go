@@ -13,3 +13,285 @@
 //go:build !js
 
 package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+// reduceCacheDB returns a database with a design document with one view per
+// reduce function, and docCount documents emitting ["a"|"B"|"c", n] => n.
+// The group view emits only the group as the key, to produce duplicate keys.
+func reduceCacheDB(t *testing.T, docCount int) (*testDB, string) {
+	t.Helper()
+	d := newDB(t)
+	mapFunc := `function(doc) { if (doc.group) { emit([doc.group, doc.n], doc.n); } }`
+	ddocRev := d.tPut("_design/foo", map[string]any{
+		"views": map[string]any{
+			"count": map[string]string{"map": mapFunc, "reduce": "_count"},
+			"sum":   map[string]string{"map": mapFunc, "reduce": "_sum"},
+			"stats": map[string]string{"map": mapFunc, "reduce": "_stats"},
+			"approx": map[string]string{
+				"map":    `function(doc) { if (doc.group) { emit([doc.group, doc.n % 20], doc.n); } }`,
+				"reduce": "_approx_count_distinct",
+			},
+			"group": map[string]string{
+				"map":    `function(doc) { if (doc.group) { emit(doc.group, doc.n); } }`,
+				"reduce": "_sum",
+			},
+			"js": map[string]string{"map": mapFunc, "reduce": `function(keys, values, rereduce) {
+				var total = 0;
+				for (var i = 0; i < values.length; i++) { total += values[i]; }
+				return total;
+			}`},
+		},
+	})
+	groups := []string{"a", "B", "c"}
+	for i := 1; i <= docCount; i++ {
+		_ = d.tPut(fmt.Sprintf("doc%03d", i), map[string]any{"group": groups[i%3], "n": i})
+	}
+	return d, ddocRev
+}
+
+func (tdb *testDB) reduceCacheExec(ddocRev, view, query string) {
+	tdb.t.Helper()
+	if _, err := tdb.underlying().Exec(tdb.DB.(*db).ddocQuery("_design/foo", view, ddocRev, query)); err != nil {
+		tdb.t.Fatal(err)
+	}
+}
+
+func (tdb *testDB) reduceCacheNodes(ddocRev, view string) int {
+	tdb.t.Helper()
+	var count int
+	if err := tdb.underlying().QueryRow(tdb.DB.(*db).ddocQuery("_design/foo", view, ddocRev, `
+		SELECT COUNT(*) FROM {{ .Reduce }}
+	`)).Scan(&count); err != nil {
+		tdb.t.Fatal(err)
+	}
+	return count
+}
+
+func (tdb *testDB) queryRows(view string, opts map[string]any) []rowResult {
+	tdb.t.Helper()
+	rows, err := tdb.Query(context.Background(), "_design/foo", "_view/"+view, kivik.Params(opts))
+	if err != nil {
+		tdb.t.Fatal(err)
+	}
+	defer rows.Close()
+	return readRows(tdb.t, rows)
+}
+
+func TestDBQuery_reduceCache(t *testing.T) {
+	t.Parallel()
+	type test struct {
+		opts map[string]any
+	}
+	tests := map[string]test{
+		"full reduce":   {},
+		"group":         {opts: map[string]any{"group": true}},
+		"group level 1": {opts: map[string]any{"group_level": 1}},
+		"key range": {opts: map[string]any{
+			"startkey": []any{"B", 10},
+			"endkey":   []any{"c", 200},
+		}},
+		"key range, exclusive end": {opts: map[string]any{
+			"startkey":      []any{"B"},
+			"endkey":        []any{"B", 200},
+			"inclusive_end": false,
+		}},
+		"descending key range": {opts: map[string]any{
+			"descending": true,
+			"startkey":   []any{"c", 200},
+			"endkey":     []any{"B", 10},
+		}},
+		"descending, grouped, exclusive end": {opts: map[string]any{
+			"descending":    true,
+			"group_level":   1,
+			"startkey":      []any{"c", 200},
+			"endkey":        []any{"a", 150},
+			"inclusive_end": false,
+		}},
+		"single key": {opts: map[string]any{"key": []any{"a", 3}}},
+		"grouped keys": {opts: map[string]any{
+			"group": true,
+			"keys":  []any{[]any{"a", 3}, []any{"c", 5}, "a", "c"},
+		}},
+	}
+	views := []string{"count", "sum", "stats", "approx", "js", "group"}
+
+	d, ddocRev := reduceCacheDB(t, 250)
+	for name, tt := range tests {
+		for _, view := range views {
+			t.Run(name+"/"+view, func(t *testing.T) {
+				cached := d.queryRows(view, tt.opts)
+				if d.reduceCacheNodes(ddocRev, view) == 0 {
+					t.Fatal("reduce cache was not populated")
+				}
+
+				// Compare against the result of reducing the raw map rows.
+				d.reduceCacheExec(ddocRev, view, `DELETE FROM {{ .Reduce }}`)
+				opts := map[string]any{"update": false}
+				for k, v := range tt.opts {
+					opts[k] = v
+				}
+				uncached := d.queryRows(view, opts)
+				if d := cmp.Diff(uncached, cached); d != "" {
+					t.Errorf("Cached result differs from uncached result:\n%s", d)
+				}
+
+				// Restore the cache
+				rev, err := parseRev(ddocRev)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := d.DB.(*db).updateReduceCache(context.Background(), "foo", view, rev); err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	}
+}
+
+func TestDBQuery_reduceCache_used(t *testing.T) {
+	t.Parallel()
+	d, ddocRev := reduceCacheDB(t, 250)
+
+	want := []rowResult{
+		{Key: `["a"]`, Value: "83"},
+		{Key: `["B"]`, Value: "84"},
+		{Key: `["c"]`, Value: "83"},
+	}
+	if got := d.queryRows("count", map[string]any{"group_level": 1}); !cmp.Equal(want, got) {
+		t.Errorf("Unexpected result:\n%s", cmp.Diff(want, got))
+	}
+
+	// Zeroing the cached values shows that they are used, rather than the
+	// map rows they cover.
+	d.reduceCacheExec(ddocRev, "count", `UPDATE {{ .Reduce }} SET value = '0'`)
+	got := d.queryRows("count", map[string]any{"update": false})
+	if want := []rowResult{{Key: "null", Value: "250"}}; cmp.Equal(want, got) {
+		t.Errorf("Cached reduce nodes were not used")
+	}
+}
+
+func TestDBQuery_reduceCache_incremental(t *testing.T) {
+	t.Parallel()
+	d, ddocRev := reduceCacheDB(t, 250)
+
+	checkSum := func(want string) {
+		t.Helper()
+		got := d.queryRows("sum", nil)
+		if d := cmp.Diff([]rowResult{{Key: "null", Value: want}}, got); d != "" {
+			t.Error(d)
+		}
+	}
+	checkSum("31375")
+	nodes := d.reduceCacheNodes(ddocRev, "sum")
+	if nodes < 2 {
+		t.Fatalf("Expected several cached nodes, got %d", nodes)
+	}
+
+	// Update one document in the middle of the range, delete another, and
+	// add a third.
+	currentRev := func(docID string) driver.Options {
+		t.Helper()
+		rev, err := d.GetRev(context.Background(), docID, mock.NilOption)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return kivik.Rev(rev)
+	}
+	_ = d.tPut("doc100", map[string]any{"group": "a", "n": 1000}, currentRev("doc100"))
+	_ = d.tDelete("doc200", currentRev("doc200"))
+	_ = d.tPut("doc251", map[string]any{"group": "B", "n": 1})
+	checkSum(fmt.Sprint(31375 + 900 - 200 + 1))
+
+	var uncovered int
+	if err := d.underlying().QueryRow(d.DB.(*db).ddocQuery("_design/foo", "sum", ddocRev, `
+		SELECT COUNT(*)
+		FROM {{ .Map }} AS view
+		LEFT JOIN {{ .Reduce }} AS node ON node.pk = view.reduce_node
+		WHERE node.pk IS NULL
+	`)).Scan(&uncovered); err != nil {
+		t.Fatal(err)
+	}
+	if uncovered >= minReduceNodeSize {
+		t.Errorf("Expected the cache to be rebuilt, but %d rows are uncovered", uncovered)
+	}
+
+	// Deleting a node clears the references to it, so that uncovered rows
+	// can be found by the reduce_node index.
+	var dangling int
+	if err := d.underlying().QueryRow(d.DB.(*db).ddocQuery("_design/foo", "sum", ddocRev, `
+		SELECT COUNT(*)
+		FROM {{ .Map }} AS view
+		LEFT JOIN {{ .Reduce }} AS node ON node.pk = view.reduce_node
+		WHERE view.reduce_node IS NOT NULL
+			AND node.pk IS NULL
+	`)).Scan(&dangling); err != nil {
+		t.Fatal(err)
+	}
+	if dangling != 0 {
+		t.Errorf("Expected no references to deleted nodes, got %d", dangling)
+	}
+}
+
+func TestDBQuery_reduceCache_migrate(t *testing.T) {
+	t.Parallel()
+	d, ddocRev := reduceCacheDB(t, 150)
+
+	// Recreate the map table as it was before the reduce cache existed.
+	_ = d.queryRows("sum", nil)
+	for _, query := range []string{
+		`DROP TRIGGER {{ .TriggerMapInsert }}`,
+		`DROP TRIGGER {{ .TriggerMapDelete }}`,
+		`DROP TABLE {{ .Reduce }}`,
+		`DROP INDEX {{ .IndexMapReduceNode }}`,
+		`ALTER TABLE {{ .Map }} DROP COLUMN reduce_node`,
+	} {
+		d.reduceCacheExec(ddocRev, "sum", query)
+	}
+
+	got := d.queryRows("sum", map[string]any{"update": false})
+	if d := cmp.Diff([]rowResult{{Key: "null", Value: "11325"}}, got); d != "" {
+		t.Error(d)
+	}
+	_ = d.tPut("doc151", map[string]any{"group": "a", "n": 151})
+	got = d.queryRows("sum", nil)
+	if d := cmp.Diff([]rowResult{{Key: "null", Value: "11476"}}, got); d != "" {
+		t.Error(d)
+	}
+	if d.reduceCacheNodes(ddocRev, "sum") == 0 {
+		t.Error("reduce cache was not populated")
+	}
+}
+
+func TestDBQuery_reduceCache_failureLoggedOnce(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	_ = d.tPut("_design/foo", map[string]any{
+		"views": map[string]any{
+			"fail": map[string]string{
+				"map":    `function(doc) { emit(doc._id, "not a number"); }`,
+				"reduce": "_sum",
+			},
+		},
+	})
+	for i := 1; i <= 3; i++ {
+		_ = d.tPut(fmt.Sprintf("doc%d", i), map[string]any{})
+		if got := d.queryRows("fail", map[string]any{"reduce": false}); len(got) != i {
+			t.Fatalf("Expected %d rows, got %d", i, len(got))
+		}
+	}
+	if got := strings.Count(d.logs.String(), "Failed to update reduce cache"); got != 1 {
+		t.Errorf("Expected the failure to be logged once, got %d times:\n%s", got, d.logs.String())
+	}
+}
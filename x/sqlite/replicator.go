@@ -0,0 +1,783 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+const (
+	// replicatorDB is the database whose documents describe replications.
+	replicatorDB = "_replicator"
+	// defaultReplicatorInterval is the default for [OptionReplicatorInterval].
+	defaultReplicatorInterval = 5 * time.Second
+	// maxReplicationRetryDelay caps the delay between attempts of a crashing
+	// replication.
+	maxReplicationRetryDelay = 10 * time.Minute
+)
+
+type optionReplicatorInterval time.Duration
+
+var _ kivik.Option = optionReplicatorInterval(0)
+
+func (o optionReplicatorInterval) Apply(target any) {
+	if client, ok := target.(*client); ok {
+		client.replicatorInterval = time.Duration(o)
+	}
+}
+
+// OptionReplicatorInterval sets how often the _replicator database is checked
+// for replication documents written by other clients, and how often
+// continuous replications check their source for new changes. It is also the
+// initial delay before a crashing replication is retried; the delay doubles
+// with each consecutive failure, up to 10 minutes. The default is 5 seconds.
+func OptionReplicatorInterval(interval time.Duration) kivik.Option {
+	return optionReplicatorInterval(interval)
+}
+
+// optionSharedClient makes [drv.NewClient] return an existing client, so that
+// replications between local databases share its connection and options.
+type optionSharedClient struct {
+	*client
+}
+
+func (o optionSharedClient) Apply(target any) {
+	if c, ok := target.(**client); ok {
+		*c = o.client
+	}
+}
+
+// endpoint is the source or target of a replication: either a database name,
+// for a local database, or the URL of a remote database. As with CouchDB, it
+// may also be given as an object with a url field.
+type endpoint string
+
+func (e *endpoint) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*e = endpoint(name)
+		return nil
+	}
+	var obj struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*e = endpoint(obj.URL)
+	return nil
+}
+
+// replicationDoc is a document in the _replicator database.
+type replicationDoc struct {
+	Source       endpoint       `json:"source"`
+	Target       endpoint       `json:"target"`
+	Continuous   bool           `json:"continuous"`
+	CreateTarget bool           `json:"create_target"`
+	Filter       string         `json:"filter"`
+	DocIDs       []string       `json:"doc_ids"`
+	QueryParams  map[string]any `json:"query_params"`
+	State        string         `json:"_replication_state"`
+	StateTime    time.Time      `json:"_replication_state_time"`
+	StateReason  string         `json:"_replication_state_reason"`
+	Stats        struct {
+		DocWriteFailures int64 `json:"doc_write_failures"`
+		DocsRead         int64 `json:"docs_read"`
+		DocsWritten      int64 `json:"docs_written"`
+	} `json:"_replication_stats"`
+}
+
+// replicationID identifies the replication described by the document, such
+// that checkpoints are only reused by identical replications.
+func (doc *replicationDoc) replicationID() string {
+	spec, _ := json.Marshal([]any{doc.Source, doc.Target, doc.Continuous, doc.Filter, doc.DocIDs, doc.QueryParams})
+	return md5sumString(string(spec))
+}
+
+// terminal returns true if the document records a finished replication,
+// which must not be restarted.
+func (doc *replicationDoc) terminal() bool {
+	switch kivik.ReplicationState(doc.State) {
+	case kivik.ReplicationComplete, kivik.ReplicationFailed:
+		return true
+	}
+	return false
+}
+
+// replicationSpecError reports a replication which can never succeed as
+// described, such that it is failed rather than retried.
+type replicationSpecError struct {
+	err error
+}
+
+func (e *replicationSpecError) Error() string { return e.err.Error() }
+
+func (e *replicationSpecError) Unwrap() error { return e.err }
+
+// scheduler runs the replications described by the documents in the
+// _replicator database, in the background, for as long as the client is open.
+type scheduler struct {
+	c        *client
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	// runMu protects started and stopped, so that the scheduler is never
+	// started once it has been stopped.
+	runMu   sync.Mutex
+	started bool
+	stopped bool
+
+	localOnce sync.Once
+	local     *kivik.Client
+	localErr  error
+
+	// mu protects the fields below. It is held while the _replicator changes
+	// feed is processed, and while a job records its final state, so that a
+	// finished job is never mistaken for one to restart.
+	mu    sync.Mutex
+	since string
+	jobs  map[string]*replicationJob
+}
+
+func newScheduler(c *client) *scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	interval := c.replicatorInterval
+	if interval <= 0 {
+		interval = defaultReplicatorInterval
+	}
+	return &scheduler{
+		c:        c,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+		jobs:     map[string]*replicationJob{},
+	}
+}
+
+// start resumes the replications in the _replicator database, and starts
+// watching it for changes. Calls after the first, or after stop, have no
+// effect.
+func (s *scheduler) start() {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+	s.wg.Add(1)
+	go s.run()
+}
+
+// stop cancels all replications, and waits for them to exit.
+func (s *scheduler) stop() {
+	s.runMu.Lock()
+	s.stopped = true
+	s.cancel()
+	s.runMu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *scheduler) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if err := s.sync(s.ctx); err != nil && s.ctx.Err() == nil {
+			s.c.logger.Printf("Failed to read %s database: %s", replicatorDB, err)
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync starts, restarts and cancels jobs according to the changes made to the
+// _replicator database since the last call.
+func (s *scheduler) sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	opts := map[string]any{"include_docs": true}
+	if s.since != "" {
+		opts["since"] = s.since
+	}
+	changes, err := s.c.newDB(replicatorDB).Changes(ctx, kivik.Params(opts))
+	if kivik.HTTPStatus(err) == http.StatusNotFound {
+		// The database was deleted, or never created.
+		for docID, job := range s.jobs {
+			job.stop()
+			delete(s.jobs, docID)
+		}
+		s.since = ""
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer changes.Close()
+	for {
+		var change driver.Change
+		if err := changes.Next(&change); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if !strings.HasPrefix(change.ID, "_") {
+			s.docChanged(&change)
+		}
+	}
+	// The last sequence is only known once the feed is closed.
+	_ = changes.Close()
+	if seq := changes.LastSeq(); seq != "" {
+		s.since = seq
+	}
+	return nil
+}
+
+// docChanged updates the job for a changed replication document. s.mu must be
+// held.
+func (s *scheduler) docChanged(change *driver.Change) {
+	job := s.jobs[change.ID]
+	if change.Deleted {
+		if job != nil {
+			job.stop()
+			delete(s.jobs, change.ID)
+		}
+		return
+	}
+
+	var (
+		doc     replicationDoc
+		invalid error
+	)
+	if err := json.Unmarshal(change.Doc, &doc); err != nil {
+		invalid = fmt.Errorf("invalid replication document: %w", err)
+	}
+	if doc.terminal() {
+		return
+	}
+	switch {
+	case invalid != nil:
+	case doc.Source == "":
+		invalid = errors.New("replication document must specify a source")
+	case doc.Target == "":
+		invalid = errors.New("replication document must specify a target")
+	}
+	repID := doc.replicationID()
+	if job != nil {
+		if job.repID == repID && !job.finished() {
+			// Most likely the scheduler's own update.
+			return
+		}
+		job.stop()
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	job = &replicationJob{
+		s:       s,
+		ctx:     ctx,
+		cancel:  cancel,
+		docID:   change.ID,
+		repID:   repID,
+		doc:     doc,
+		invalid: invalid,
+		state:   string(kivik.ReplicationInitializing),
+	}
+	s.jobs[change.ID] = job
+	s.wg.Add(1)
+	go job.run()
+}
+
+// job returns the job for the replication document docID, if any.
+func (s *scheduler) job(docID string) *replicationJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[docID]
+}
+
+// localClient returns a kivik client for replications between local
+// databases, which shares the scheduler's client.
+func (s *scheduler) localClient() (*kivik.Client, error) {
+	s.localOnce.Do(func() {
+		s.local, s.localErr = kivik.New("sqlite", s.c.dsn, optionSharedClient{s.c})
+	})
+	return s.local, s.localErr
+}
+
+// endpointDB returns the database for a replication endpoint. Remote
+// databases require the CouchDB driver to be registered.
+func (s *scheduler) endpointDB(ctx context.Context, ep endpoint, create bool) (*kivik.DB, error) {
+	var (
+		client *kivik.Client
+		name   = string(ep)
+		err    error
+	)
+	if strings.Contains(name, "://") {
+		client, name, err = remoteClient(name)
+	} else {
+		client, err = s.localClient()
+	}
+	if err != nil {
+		return nil, &replicationSpecError{err: err}
+	}
+	if create {
+		if err := client.CreateDB(ctx, name); err != nil && kivik.HTTPStatus(err) != http.StatusPreconditionFailed {
+			return nil, err
+		}
+	}
+	db := client.DB(name)
+	return db, db.Err()
+}
+
+// remoteClient returns a CouchDB client for the server of the database at
+// dbURL, and the name of the database.
+func remoteClient(dbURL string) (*kivik.Client, string, error) {
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		return nil, "", err
+	}
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+	i := strings.LastIndex(path, "/")
+	name, err := url.PathUnescape(path[i+1:])
+	if err != nil {
+		return nil, "", err
+	}
+	if name == "" {
+		return nil, "", fmt.Errorf("no database in URL: %s", dbURL)
+	}
+	u.Path, u.RawPath = "", ""
+	if u, err = u.Parse(path[:i+1]); err != nil {
+		return nil, "", err
+	}
+	client, err := kivik.New("couch", u.String())
+	return client, name, err
+}
+
+// checkpoint is stored in the _replicator database as a local document, named
+// for the replication ID, after each successful pass of a replication.
+type checkpoint struct {
+	Rev           string `json:"_rev,omitempty"`
+	SourceLastSeq string `json:"source_last_seq"`
+}
+
+func (s *scheduler) checkpoint(ctx context.Context, repID string) (*checkpoint, error) {
+	var cp checkpoint
+	doc, err := s.c.newDB(replicatorDB).Get(ctx, "_local/"+repID, kivik.Params(nil))
+	if kivik.HTTPStatus(err) == http.StatusNotFound {
+		return &cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer doc.Body.Close()
+	return &cp, json.NewDecoder(doc.Body).Decode(&cp)
+}
+
+func (s *scheduler) saveCheckpoint(ctx context.Context, repID string, cp *checkpoint) error {
+	_, err := s.c.newDB(replicatorDB).Put(ctx, "_local/"+repID, cp, kivik.Params(nil))
+	return err
+}
+
+// replicationJob is a single replication, run in the background.
+type replicationJob struct {
+	s       *scheduler
+	ctx     context.Context
+	cancel  context.CancelFunc
+	docID   string
+	repID   string
+	doc     replicationDoc
+	invalid error
+
+	mu    sync.RWMutex
+	state string
+	err   error
+	start time.Time
+	end   time.Time
+	info  driver.ReplicationInfo
+}
+
+func (j *replicationJob) stop() {
+	j.cancel()
+}
+
+func (j *replicationJob) finished() bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return !j.end.IsZero()
+}
+
+func (j *replicationJob) setState(state string, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state = state
+	j.err = err
+	if state == string(kivik.ReplicationRunning) && j.start.IsZero() {
+		j.start = time.Now()
+	}
+}
+
+func (j *replicationJob) run() {
+	defer j.s.wg.Done()
+	if j.invalid != nil {
+		j.finish(kivik.ReplicationFailed, j.invalid)
+		return
+	}
+	delay := j.s.interval
+	for {
+		j.setState(string(kivik.ReplicationRunning), nil)
+		err := j.replicate(j.ctx)
+		if j.ctx.Err() != nil {
+			return
+		}
+		var specErr *replicationSpecError
+		wait := j.s.interval
+		switch {
+		case err == nil && !j.doc.Continuous:
+			j.finish(kivik.ReplicationComplete, nil)
+			return
+		case err == nil:
+			delay = j.s.interval
+		case errors.As(err, &specErr):
+			j.finish(kivik.ReplicationFailed, err)
+			return
+		default:
+			j.s.c.logger.Printf("Replication %s crashed: %s", j.docID, err)
+			j.setState(string(kivik.ReplicationCrashing), err)
+			wait = delay
+			delay = min(2*delay, maxReplicationRetryDelay)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-j.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// replicate performs a single pass of the replication, starting from the last
+// checkpoint.
+func (j *replicationJob) replicate(ctx context.Context) error {
+	source, err := j.s.endpointDB(ctx, j.doc.Source, false)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	target, err := j.s.endpointDB(ctx, j.doc.Target, j.doc.CreateTarget)
+	if err != nil {
+		return fmt.Errorf("target: %w", err)
+	}
+	cp, err := j.s.checkpoint(ctx, j.repID)
+	if err != nil {
+		return err
+	}
+	// Changes made while replicating may be replicated again in the next
+	// pass, which is harmless.
+	stats, err := source.Stats(ctx)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	if cp.SourceLastSeq == stats.UpdateSeq {
+		return nil
+	}
+
+	params := map[string]any{}
+	for k, v := range j.doc.QueryParams {
+		params[k] = v
+	}
+	if cp.SourceLastSeq != "" {
+		params["since"] = cp.SourceLastSeq
+	}
+	if j.doc.Filter != "" {
+		params["filter"] = j.doc.Filter
+	}
+	if len(j.doc.DocIDs) > 0 {
+		params["filter"] = "_doc_ids"
+		params["doc_ids"] = j.doc.DocIDs
+	}
+	if _, err := kivik.Replicate(ctx, target, source, kivik.Params(params), kivik.ReplicateCallback(j.event)); err != nil {
+		return err
+	}
+	cp.SourceLastSeq = stats.UpdateSeq
+	return j.s.saveCheckpoint(ctx, j.repID, cp)
+}
+
+// event counts the documents read and written by the replication.
+func (j *replicationJob) event(e kivik.ReplicationEvent) {
+	if e.Type != "document" {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch {
+	case e.Read:
+		j.info.DocsRead++
+	case e.Error != nil:
+		j.info.DocWriteFailures++
+	default:
+		j.info.DocsWritten++
+	}
+}
+
+// finish records the final state of the replication in its document.
+func (j *replicationJob) finish(state kivik.ReplicationState, err error) {
+	j.s.mu.Lock()
+	defer j.s.mu.Unlock()
+	if j.ctx.Err() != nil {
+		return
+	}
+	if recordErr := j.record(state, err); recordErr != nil {
+		j.s.c.logger.Printf("Failed to record state of replication %s: %s", j.docID, recordErr)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state = string(state)
+	j.err = err
+	j.end = time.Now()
+	if state == kivik.ReplicationComplete {
+		j.info.Progress = 100
+	}
+}
+
+func (j *replicationJob) record(state kivik.ReplicationState, err error) error {
+	d := j.s.c.newDB(replicatorDB)
+	d.replicationState = true
+	current, getErr := d.Get(j.ctx, j.docID, kivik.Params(nil))
+	if getErr != nil {
+		return getErr
+	}
+	defer current.Body.Close()
+	var doc map[string]any
+	if err := json.NewDecoder(current.Body).Decode(&doc); err != nil {
+		return err
+	}
+	j.mu.RLock()
+	doc["_replication_state"] = string(state)
+	doc["_replication_state_time"] = time.Now().UTC().Format(time.RFC3339)
+	doc["_replication_id"] = j.repID
+	doc["_replication_stats"] = map[string]any{
+		"doc_write_failures": j.info.DocWriteFailures,
+		"docs_read":          j.info.DocsRead,
+		"docs_written":       j.info.DocsWritten,
+	}
+	j.mu.RUnlock()
+	if err != nil {
+		doc["_replication_state_reason"] = err.Error()
+	}
+	_, putErr := d.Put(j.ctx, j.docID, doc, kivik.Params(nil))
+	return putErr
+}
+
+// Replicate stores a replication document in the _replicator database, which
+// is created if necessary, and starts the replication in the background.
+// Options are stored as fields of the document. Source and target may be the
+// names of databases of this client, or the URLs of remote databases, which
+// require the CouchDB driver to be imported.
+func (c *client) Replicate(ctx context.Context, targetDSN, sourceDSN string, options driver.Options) (driver.Replication, error) {
+	doc := map[string]any{}
+	options.Apply(doc)
+	if _, ok := doc["source"]; !ok {
+		doc["source"] = sourceDSN
+	}
+	if _, ok := doc["target"]; !ok {
+		doc["target"] = targetDSN
+	}
+	if doc["source"] == "" {
+		return nil, &internal.Error{Status: http.StatusBadRequest, Message: "source is required"}
+	}
+	if doc["target"] == "" {
+		return nil, &internal.Error{Status: http.StatusBadRequest, Message: "target is required"}
+	}
+	if err := c.CreateDB(ctx, replicatorDB, kivik.Params(nil)); err != nil && kivik.HTTPStatus(err) != http.StatusPreconditionFailed {
+		return nil, err
+	}
+	docID, _, err := c.newDB(replicatorDB).CreateDoc(ctx, doc, kivik.Params(nil))
+	if err != nil {
+		return nil, err
+	}
+	s := c.currentScheduler()
+	s.start()
+	if err := s.sync(ctx); err != nil {
+		return nil, err
+	}
+	rep := &replication{s: s, docID: docID}
+	if err := rep.update(ctx); err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// GetReplications returns the replications described by the documents in the
+// _replicator database.
+func (c *client) GetReplications(ctx context.Context, _ driver.Options) ([]driver.Replication, error) {
+	rows, err := c.newDB(replicatorDB).AllDocs(ctx, kivik.Param("include_docs", true))
+	if kivik.HTTPStatus(err) == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	// The database may have been created by another client.
+	s := c.currentScheduler()
+	s.start()
+	var reps []driver.Replication
+	for {
+		var row driver.Row
+		if err := rows.Next(&row); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if strings.HasPrefix(row.ID, "_") {
+			continue
+		}
+		var doc replicationDoc
+		if err := json.NewDecoder(row.Doc).Decode(&doc); err != nil {
+			return nil, err
+		}
+		rep := &replication{s: s, docID: row.ID}
+		rep.set(&doc)
+		reps = append(reps, rep)
+	}
+	return reps, nil
+}
+
+// replication is a handle to the replication described by a document in the
+// _replicator database.
+type replication struct {
+	s     *scheduler
+	docID string
+
+	// mu protects the fields below.
+	mu                 sync.RWMutex
+	replicationID      string
+	source, target     string
+	startTime, endTime time.Time
+	state              string
+	err                error
+	info               driver.ReplicationInfo
+}
+
+var _ driver.Replication = (*replication)(nil)
+
+func (r *replication) readLock() func() {
+	r.mu.RLock()
+	return r.mu.RUnlock
+}
+
+func (r *replication) ReplicationID() string { defer r.readLock()(); return r.replicationID }
+func (r *replication) Source() string        { defer r.readLock()(); return r.source }
+func (r *replication) Target() string        { defer r.readLock()(); return r.target }
+func (r *replication) StartTime() time.Time  { defer r.readLock()(); return r.startTime }
+func (r *replication) EndTime() time.Time    { defer r.readLock()(); return r.endTime }
+func (r *replication) State() string         { defer r.readLock()(); return r.state }
+func (r *replication) Err() error            { defer r.readLock()(); return r.err }
+
+// set updates the replication from its document, and from its job, if it is
+// running.
+func (r *replication) set(doc *replicationDoc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replicationID = doc.replicationID()
+	r.source = string(doc.Source)
+	r.target = string(doc.Target)
+	r.state = doc.State
+	r.err = nil
+	if doc.StateReason != "" {
+		r.err = errors.New(doc.StateReason)
+	}
+	if doc.terminal() {
+		r.endTime = doc.StateTime
+		r.info = driver.ReplicationInfo{
+			DocWriteFailures: doc.Stats.DocWriteFailures,
+			DocsRead:         doc.Stats.DocsRead,
+			DocsWritten:      doc.Stats.DocsWritten,
+		}
+		if kivik.ReplicationState(doc.State) == kivik.ReplicationComplete {
+			r.info.Progress = 100
+		}
+		return
+	}
+
+	job := r.s.job(r.docID)
+	if job == nil || job.repID != r.replicationID {
+		r.state = string(kivik.ReplicationPending)
+		return
+	}
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	r.state = job.state
+	r.err = job.err
+	r.startTime = job.start
+	r.endTime = job.end
+	r.info = job.info
+}
+
+func (r *replication) update(ctx context.Context) error {
+	d := r.s.c.newDB(replicatorDB)
+	current, err := d.Get(ctx, r.docID, kivik.Params(nil))
+	if err != nil {
+		return err
+	}
+	defer current.Body.Close()
+	var doc replicationDoc
+	if err := json.NewDecoder(current.Body).Decode(&doc); err != nil {
+		return err
+	}
+	r.set(&doc)
+	return nil
+}
+
+// Update refreshes the state of the replication.
+func (r *replication) Update(ctx context.Context, info *driver.ReplicationInfo) error {
+	if err := r.update(ctx); err != nil {
+		return err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	*info = r.info
+	return nil
+}
+
+// Delete deletes the replication document, which cancels the replication.
+func (r *replication) Delete(ctx context.Context) error {
+	d := r.s.c.newDB(replicatorDB)
+	rev, err := d.GetRev(ctx, r.docID, kivik.Params(nil))
+	if err != nil {
+		return err
+	}
+	if _, err := d.Delete(ctx, r.docID, kivik.Rev(rev)); err != nil {
+		return err
+	}
+	return r.s.sync(ctx)
+}
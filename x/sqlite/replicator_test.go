@@ -0,0 +1,283 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+const testReplicatorInterval = 20 * time.Millisecond
+
+// newReplicatorClient returns a client for dsn, which checks for replication
+// changes every testReplicatorInterval.
+func newReplicatorClient(t *testing.T, dsn string) *client {
+	t.Helper()
+	c, err := drv{}.NewClient(dsn, multiOptions{
+		OptionLogger(log.New(&bytes.Buffer{}, "", 0)),
+		OptionReplicatorInterval(testReplicatorInterval),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.(*client).Close() })
+	return c.(*client)
+}
+
+// createTestDB creates the named database, with the given documents.
+func (c *client) createTestDB(t *testing.T, name string, docIDs ...string) *testDB {
+	t.Helper()
+	if err := c.CreateDB(context.Background(), name, mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+	d := &testDB{DB: c.newDB(name), t: t}
+	for _, docID := range docIDs {
+		_ = d.tPut(docID, map[string]string{"name": docID})
+	}
+	return d
+}
+
+// waitForState polls the replication until it reaches state.
+func waitForState(t *testing.T, rep driver.Replication, state kivik.ReplicationState) *driver.ReplicationInfo {
+	t.Helper()
+	var info driver.ReplicationInfo
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if err := rep.Update(context.Background(), &info); err != nil {
+			t.Fatal(err)
+		}
+		if rep.State() == string(state) {
+			return &info
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Replication did not reach state %q; state: %q, error: %v", state, rep.State(), rep.Err())
+		}
+		time.Sleep(testReplicatorInterval)
+	}
+}
+
+// waitForDoc polls the database until the document exists.
+func waitForDoc(t *testing.T, d *testDB, docID string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		_, err := d.GetRev(context.Background(), docID, mock.NilOption)
+		if err == nil {
+			return
+		}
+		if kivik.HTTPStatus(err) != http.StatusNotFound {
+			t.Fatal(err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Document %q was not replicated", docID)
+		}
+		time.Sleep(testReplicatorInterval)
+	}
+}
+
+func testDSN(name string) string {
+	return fmt.Sprintf("file:%s%d?mode=memory&cache=shared", name, dbSeq.Add(1))
+}
+
+func TestClientReplicate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := newReplicatorClient(t, testDSN("replicate"))
+	_ = c.createTestDB(t, "source", "a", "b")
+
+	rep, err := c.Replicate(ctx, "target", "source", kivik.Param("create_target", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Source() != "source" || rep.Target() != "target" {
+		t.Errorf("Unexpected endpoints: %s -> %s", rep.Source(), rep.Target())
+	}
+	info := waitForState(t, rep, kivik.ReplicationComplete)
+	if info.DocsRead != 2 || info.DocsWritten != 2 || info.Progress != 100 {
+		t.Errorf("Unexpected replication info: %+v", info)
+	}
+	if rep.EndTime().IsZero() {
+		t.Error("Expected an end time")
+	}
+
+	target := &testDB{DB: c.newDB("target"), t: t}
+	waitForDoc(t, target, "a")
+	waitForDoc(t, target, "b")
+
+	replicator := &testDB{DB: c.newDB(replicatorDB), t: t}
+	if _, err := replicator.GetRev(ctx, "_local/"+rep.ReplicationID(), mock.NilOption); err != nil {
+		t.Errorf("Expected a checkpoint: %s", err)
+	}
+
+	reps, err := c.GetReplications(ctx, mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 1 || reps[0].State() != string(kivik.ReplicationComplete) {
+		t.Errorf("Unexpected replications: %d, state %q", len(reps), reps[0].State())
+	}
+}
+
+func TestClientReplicate_continuous(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := newReplicatorClient(t, testDSN("continuous"))
+	source := c.createTestDB(t, "source", "a")
+	target := c.createTestDB(t, "target")
+
+	rep, err := c.Replicate(ctx, "target", "source", kivik.Param("continuous", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForDoc(t, target, "a")
+	_ = source.tPut("b", map[string]string{"name": "b"})
+	waitForDoc(t, target, "b")
+	if state := waitForState(t, rep, kivik.ReplicationRunning); state.DocsWritten != 2 {
+		t.Errorf("Unexpected replication info: %+v", state)
+	}
+
+	if err := rep.Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if job := c.currentScheduler().job(rep.(*replication).docID); job != nil {
+		t.Error("Expected the job to be cancelled")
+	}
+	_ = source.tPut("c", map[string]string{"name": "c"})
+	time.Sleep(5 * testReplicatorInterval)
+	if _, err := target.GetRev(ctx, "c", mock.NilOption); kivik.HTTPStatus(err) != http.StatusNotFound {
+		t.Errorf("Document replicated after cancellation: %v", err)
+	}
+	err = rep.Update(ctx, &driver.ReplicationInfo{})
+	if status := kivik.HTTPStatus(err); status != http.StatusNotFound {
+		t.Errorf("Unexpected status for deleted replication: %d", status)
+	}
+}
+
+func TestClientReplicate_errors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := newReplicatorClient(t, testDSN("replicateerrors"))
+
+	_, err := c.Replicate(ctx, "", "source", mock.NilOption)
+	if status := kivik.HTTPStatus(err); status != http.StatusBadRequest {
+		t.Errorf("Unexpected status for missing target: %d", status)
+	}
+
+	rep, err := c.Replicate(ctx, "target", "missing", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = waitForState(t, rep, kivik.ReplicationCrashing)
+	if rep.Err() == nil {
+		t.Error("Expected an error for a missing source")
+	}
+
+	replicator := &testDB{DB: c.newDB(replicatorDB), t: t}
+	_ = replicator.tPut("invalid", map[string]string{"source": "source"})
+	rep = &replication{s: c.currentScheduler(), docID: "invalid"}
+	_ = waitForState(t, rep, kivik.ReplicationFailed)
+	if want, got := "replication document must specify a target", fmt.Sprint(rep.Err()); got != want {
+		t.Errorf("Unexpected error: %s", got)
+	}
+}
+
+func TestClientReplicate_resume(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "replicator.db") + "?_pragma=busy_timeout(5000)"
+
+	c := newReplicatorClient(t, dsn)
+	rep, err := c.Replicate(ctx, "target", "source", kivik.Param("create_target", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docID := rep.(*replication).docID
+	_ = waitForState(t, rep, kivik.ReplicationCrashing)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c = newReplicatorClient(t, dsn)
+	_ = c.createTestDB(t, "source", "a")
+	rep = &replication{s: c.currentScheduler(), docID: docID}
+	_ = waitForState(t, rep, kivik.ReplicationComplete)
+	waitForDoc(t, &testDB{DB: c.newDB("target"), t: t}, "a")
+}
+
+func TestClientReplicate_stateFieldsReserved(t *testing.T) {
+	t.Parallel()
+	c := newReplicatorClient(t, testDSN("replicatestatefields"))
+	_ = c.createTestDB(t, "source", "a")
+	_ = c.createTestDB(t, "target")
+	replicator := c.createTestDB(t, replicatorDB)
+
+	// A user-supplied state is not stored, so the replication still runs.
+	_ = replicator.tPut("rep", map[string]any{
+		"source":             "source",
+		"target":             "target",
+		"_replication_state": "completed",
+	})
+	rep := &replication{s: c.currentScheduler(), docID: "rep"}
+	_ = waitForState(t, rep, kivik.ReplicationComplete)
+	waitForDoc(t, &testDB{DB: c.newDB("target"), t: t}, "a")
+
+	// Other databases never store the fields.
+	d := newDB(t)
+	_ = d.tPut("doc", map[string]any{"_replication_state": "completed"})
+	doc, err := d.Get(context.Background(), "doc", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Body.Close()
+	body, err := io.ReadAll(doc.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(body, []byte("_replication_state")) {
+		t.Errorf("Unexpected replication state stored: %s", body)
+	}
+}
+
+func TestClientReplicate_lazyStart(t *testing.T) {
+	t.Parallel()
+	c := newReplicatorClient(t, testDSN("replicatelazy"))
+
+	notStarted := func() bool {
+		s := c.currentScheduler()
+		s.runMu.Lock()
+		defer s.runMu.Unlock()
+		return !s.started
+	}
+	if !notStarted() {
+		t.Fatal("Expected the scheduler not to be started without a _replicator database")
+	}
+
+	c = newReplicatorClient(t, testDSN("replicatelazy"))
+	_ = c.createTestDB(t, replicatorDB)
+	if notStarted() {
+		t.Error("Expected the scheduler to be started once the _replicator database exists")
+	}
+}
@@ -12,7 +12,265 @@
 
 package sqlite
 
-var schema = []string{
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+// schemaVersion is stored as the user_version of the SQLite file. It is
+// incremented when the schema changes such that files written by earlier
+// versions can no longer be read. Files written before the schema was
+// versioned (version 0) are migrated when opened.
+//
+//   - 1: Attachment content is stored once per digest, in chunks.
+//   - 2: Attachment content IDs are never reused, and chunks are deleted by
+//     trigger along with their content.
+const schemaVersion = 2
+
+// errSchemaVersion is returned for files written with an unsupported schema
+// version.
+var errSchemaVersion = errors.New("unsupported schema version")
+
+// checkSchemaVersion returns an error if the file was written with a different
+// schema version. Files without any databases are marked with the current
+// version, and files written before the schema was versioned are migrated.
+func checkSchemaVersion(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	switch {
+	case version == schemaVersion:
+		return nil
+	case version > schemaVersion:
+		return fmt.Errorf("%w %d: the newest supported version is %d", errSchemaVersion, version, schemaVersion)
+	}
+	var inUse bool
+	if err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM sqlite_schema
+			WHERE type = 'table'
+				AND name LIKE $1
+		)
+	`, tablePrefix+"%").Scan(&inUse); err != nil {
+		return err
+	}
+	switch {
+	case !inUse:
+		_, err := db.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", schemaVersion))
+		return err
+	case version == 0:
+		return migrateSchema(ctx, db)
+	}
+	return errSchemaExport(version)
+}
+
+// errSchemaExport returns an error for a file written with a schema version
+// which cannot be migrated.
+func errSchemaExport(version int) error {
+	return fmt.Errorf("%w %d: export the databases with the release which wrote the file, and import them into a new file", errSchemaVersion, version)
+}
+
+// migrateSchema migrates each database in a file written before the schema
+// was versioned, and then marks the file with the current version. The file
+// is migrated in a single transaction, so that it is left unchanged if any
+// database cannot be migrated.
+func migrateSchema(ctx context.Context, conn *sql.DB) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT SUBSTR(name, LENGTH($1) + 1)
+		FROM sqlite_schema AS s
+		WHERE type = 'table'
+			AND name LIKE $1 || '%'
+			AND EXISTS (
+				SELECT 1 FROM sqlite_schema
+				WHERE type = 'table'
+					AND name = s.name || '$revs'
+			)
+	`, tablePrefix)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		d := &db{name: name}
+		if err := d.migrateAttachments(ctx, tx); err != nil {
+			return fmt.Errorf("migrate database %s: %w", name, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrateAttachments moves the attachment content of a database written before
+// the schema was versioned, when it was stored in the data column of the
+// .Attachments table, into the content tables.
+func (d *db) migrateAttachments(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, d.query(`SELECT * FROM {{ .Attachments }} LIMIT 0`))
+	if err != nil {
+		return err
+	}
+	columns, err := rows.Columns()
+	_ = rows.Close()
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(columns, "data") {
+		return errSchemaExport(0)
+	}
+
+	var digest md5sum
+	err = tx.QueryRowContext(ctx, d.query(`
+		SELECT a.digest
+		FROM {{ .Attachments }} AS a
+		JOIN {{ .Attachments }} AS b ON b.digest = a.digest AND b.pk > a.pk
+		WHERE b.data != a.data
+		LIMIT 1
+	`)).Scan(&digest)
+	switch {
+	case err == nil:
+		return &internal.Error{Status: http.StatusConflict, Message: "attachments with digest " + digest.Digest() + " have different content"}
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	for _, query := range attachmentDataSchema {
+		if _, err := tx.ExecContext(ctx, d.query(query)); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, d.query(`
+		INSERT INTO {{ .AttachmentData }} (digest)
+		SELECT DISTINCT digest
+		FROM {{ .Attachments }}
+	`)); err != nil {
+		return err
+	}
+	if err := d.migrateAttachmentChunks(ctx, tx); err != nil {
+		return err
+	}
+
+	// The attachment tables are recreated, rather than altered, to add the
+	// foreign key to the content. The references to the content are counted
+	// by trigger, as the links to revisions are copied back.
+	queries := []string{
+		`CREATE TEMP TABLE migrate_attachments AS
+			SELECT pk, filename, content_type, length, digest, rev_pos
+			FROM {{ .Attachments }}`,
+		`CREATE TEMP TABLE migrate_attachments_bridge AS
+			SELECT pk, id, rev, rev_id
+			FROM {{ .AttachmentsBridge }}`,
+		`DROP TABLE {{ .AttachmentsBridge }}`,
+		`DROP TABLE {{ .Attachments }}`,
+	}
+	queries = append(queries, attachmentsSchema...)
+	queries = append(queries,
+		`INSERT INTO {{ .Attachments }} (pk, filename, content_type, length, digest, rev_pos)
+			SELECT pk, filename, content_type, length, digest, rev_pos
+			FROM temp.migrate_attachments`,
+		`INSERT INTO {{ .AttachmentsBridge }} (pk, id, rev, rev_id)
+			SELECT pk, id, rev, rev_id
+			FROM temp.migrate_attachments_bridge`,
+		`DROP TABLE temp.migrate_attachments_bridge`,
+		`DROP TABLE temp.migrate_attachments`,
+	)
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, d.query(query)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateAttachmentChunks writes the content of each digest, read from the
+// data column of the .Attachments table, to the chunks table. Each attachment
+// is held in memory in turn, as it was before migration.
+func (d *db) migrateAttachmentChunks(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, d.query(`
+		SELECT data.pk, MIN(att.pk)
+		FROM {{ .AttachmentData }} AS data
+		JOIN {{ .Attachments }} AS att ON att.digest = data.digest
+		GROUP BY data.pk
+	`))
+	if err != nil {
+		return err
+	}
+	var pks [][2]int64
+	for rows.Next() {
+		var pk [2]int64
+		if err := rows.Scan(&pk[0], &pk[1]); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		pks = append(pks, pk)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	chunks, err := tx.PrepareContext(ctx, d.query(`
+		INSERT INTO {{ .AttachmentChunks }} (data_pk, seq, chunk)
+		VALUES ($1, $2, $3)
+	`))
+	if err != nil {
+		return err
+	}
+	defer chunks.Close()
+	for _, pk := range pks {
+		var content []byte
+		if err := tx.QueryRowContext(ctx, d.query(`
+			SELECT data FROM {{ .Attachments }} WHERE pk = $1
+		`), pk[1]).Scan(&content); err != nil {
+			return err
+		}
+		cw := &chunkWriter{ctx: ctx, stmt: chunks, pk: pk[0]}
+		if _, err := cw.Write(content); err != nil {
+			return err
+		}
+		if err := cw.flush(); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, d.query(`
+			UPDATE {{ .AttachmentData }}
+			SET length = $1, encoded_length = $1
+			WHERE pk = $2
+		`), cw.written, pk[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schema creates the tables of a database.
+var schema = slices.Concat(docSchema, attachmentDataSchema, attachmentsSchema)
+
+var docSchema = []string{
 	// revs
 	`CREATE TABLE {{ .Revs }} (
 		id TEXT NOT NULL,
@@ -38,25 +296,6 @@ var schema = []string{
 		FOREIGN KEY (id, rev, rev_id) REFERENCES {{ .Revs }} (id, rev, rev_id) ON DELETE CASCADE,
 		UNIQUE(id, rev, rev_id)
 	)`,
-	// attachments
-	`CREATE TABLE {{ .Attachments }} (
-		pk INTEGER PRIMARY KEY,
-		filename TEXT NOT NULL,
-		content_type TEXT NOT NULL,
-		length INTEGER NOT NULL,
-		digest BLOB NOT NULL,
-		data BLOB NOT NULL,
-		rev_pos INTEGER NOT NULL
-	)`,
-	`CREATE TABLE {{ .AttachmentsBridge }} (
-		pk INTEGER,
-		id TEXT NOT NULL,
-		rev INTEGER NOT NULL,
-		rev_id TEXT NOT NULL,
-		FOREIGN KEY (pk) REFERENCES {{ .Attachments }} (pk),
-		FOREIGN KEY (id, rev, rev_id) REFERENCES {{ .Docs }} (id, rev, rev_id) ON DELETE CASCADE,
-		UNIQUE (id, rev, rev_id, pk)
-	)`,
 	/*
 		The .Design table is used to store design documents. The schema is as follows:
 		- id: The document ID.
@@ -97,6 +336,98 @@ var schema = []string{
 	)`,
 }
 
+// attachmentDataSchema creates the tables which hold attachment content.
+var attachmentDataSchema = []string{
+	/*
+		The .AttachmentData table stores attachment content, once per digest,
+		however many attachments share it. As MD5 is not collision resistant,
+		content is only shared once it is found to be identical. The schema is
+		as follows:
+		- pk: The ID of the content, referenced by the .AttachmentChunks table.
+		  AUTOINCREMENT ensures that IDs of deleted content are never reused,
+		  so a reader of deleted content never reads another's chunks.
+		- digest: The MD5 digest of the content. NULL while the content is
+		  being written, as it is only known once all content has been read.
+		- encoding: NULL, or 'gzip' if the content is stored compressed.
+		- length: The length of the content.
+		- encoded_length: The stored length of the content.
+		- refs: The number of .AttachmentsBridge rows which reference the
+		  content, through the .Attachments table. It is maintained by
+		  triggers, and the content is deleted when it drops to zero.
+	*/
+	`CREATE TABLE {{ .AttachmentData }} (
+		pk INTEGER PRIMARY KEY AUTOINCREMENT,
+		digest BLOB UNIQUE,
+		encoding TEXT CHECK (encoding IN ('gzip')),
+		length INTEGER NOT NULL DEFAULT 0,
+		encoded_length INTEGER NOT NULL DEFAULT 0,
+		refs INTEGER NOT NULL DEFAULT 0
+	)`,
+	// The content itself is stored in chunks, so that it can be streamed.
+	`CREATE TABLE {{ .AttachmentChunks }} (
+		data_pk INTEGER NOT NULL,
+		seq INTEGER NOT NULL,
+		chunk BLOB NOT NULL,
+		PRIMARY KEY (data_pk, seq),
+		FOREIGN KEY (data_pk) REFERENCES {{ .AttachmentData }} (pk) ON DELETE CASCADE
+	)`,
+	// Chunks are also deleted by trigger, as the cascade above applies only
+	// when foreign keys are enabled.
+	`CREATE TRIGGER {{ .TriggerAttachmentDataDelete }} AFTER DELETE ON {{ .AttachmentData }}
+	BEGIN
+		DELETE FROM {{ .AttachmentChunks }} WHERE data_pk = OLD.pk;
+	END`,
+}
+
+// attachmentsSchema creates the tables which describe attachments, and link
+// them to document revisions.
+var attachmentsSchema = []string{
+	`CREATE TABLE {{ .Attachments }} (
+		pk INTEGER PRIMARY KEY,
+		filename TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		length INTEGER NOT NULL,
+		digest BLOB NOT NULL,
+		rev_pos INTEGER NOT NULL,
+		-- Deferred, as the triggers below delete content before the
+		-- attachments which reference it.
+		FOREIGN KEY (digest) REFERENCES {{ .AttachmentData }} (digest) DEFERRABLE INITIALLY DEFERRED
+	)`,
+	`CREATE TABLE {{ .AttachmentsBridge }} (
+		pk INTEGER,
+		id TEXT NOT NULL,
+		rev INTEGER NOT NULL,
+		rev_id TEXT NOT NULL,
+		FOREIGN KEY (pk) REFERENCES {{ .Attachments }} (pk),
+		FOREIGN KEY (id, rev, rev_id) REFERENCES {{ .Docs }} (id, rev, rev_id) ON DELETE CASCADE,
+		UNIQUE (id, rev, rev_id, pk)
+	)`,
+	`CREATE INDEX {{ .IndexAttachmentsBridgePK }} ON {{ .AttachmentsBridge }} (pk)`,
+	`CREATE TRIGGER {{ .TriggerAttachmentsBridgeInsert }} AFTER INSERT ON {{ .AttachmentsBridge }}
+	WHEN NEW.pk IS NOT NULL
+	BEGIN
+		UPDATE {{ .AttachmentData }}
+		SET refs = refs + 1
+		WHERE digest = (SELECT digest FROM {{ .Attachments }} WHERE pk = NEW.pk);
+	END`,
+	// Removing the last reference to an attachment, such as when a revision
+	// is compacted or purged, removes the attachment, and its content if no
+	// other attachment shares it.
+	`CREATE TRIGGER {{ .TriggerAttachmentsBridgeDelete }} AFTER DELETE ON {{ .AttachmentsBridge }}
+	WHEN OLD.pk IS NOT NULL
+	BEGIN
+		UPDATE {{ .AttachmentData }}
+		SET refs = refs - 1
+		WHERE digest = (SELECT digest FROM {{ .Attachments }} WHERE pk = OLD.pk);
+		DELETE FROM {{ .AttachmentData }}
+		WHERE digest = (SELECT digest FROM {{ .Attachments }} WHERE pk = OLD.pk)
+			AND refs <= 0;
+		DELETE FROM {{ .Attachments }}
+		WHERE pk = OLD.pk
+			AND NOT EXISTS (SELECT 1 FROM {{ .AttachmentsBridge }} WHERE pk = OLD.pk);
+	END`,
+}
+
 var viewSchema = []string{
 	`CREATE TABLE {{ .Map }} (
 		pk INTEGER PRIMARY KEY,
@@ -105,17 +436,119 @@ var viewSchema = []string{
 		rev_id TEXT NOT NULL,
 		key TEXT COLLATE {{ .Collation }},
 		value TEXT,
+		reduce_node INTEGER,
 		FOREIGN KEY (id, rev, rev_id) REFERENCES {{ .Docs }} (id, rev, rev_id)
 	)`,
 	`CREATE INDEX {{ .IndexMap }} ON {{ .Map }} (key)`,
 }
 
+// reduceSchema creates the reduce cache of a view. It is created along with
+// viewSchema, and separately when migrating a map table created before the
+// reduce cache existed.
+var reduceSchema = []string{
+	/*
+		The .Reduce table caches intermediate reduce results. Each row (node)
+		holds the reduced value of a contiguous range of .Map rows, in (key, pk)
+		order. The schema is as follows:
+		- pk: The node ID, referenced by the reduce_node column of the .Map table.
+		  Deleting a node clears the references to it, so map rows with a
+		  NULL reduce_node are exactly those not covered by a node.
+		  AUTOINCREMENT ensures that IDs of deleted nodes are never reused.
+		- first_key, first_pk: The key and pk of the first map row in the range.
+		- last_key, last_pk: The key and pk of the last map row in the range.
+		- row_count: The number of map rows in the range.
+		- value: The JSON-encoded result of the reduce function over the range.
+	*/
+	`CREATE TABLE {{ .Reduce }} (
+		pk INTEGER PRIMARY KEY AUTOINCREMENT,
+		first_key TEXT COLLATE {{ .Collation }},
+		first_pk INTEGER NOT NULL,
+		last_key TEXT COLLATE {{ .Collation }},
+		last_pk INTEGER NOT NULL,
+		row_count INTEGER NOT NULL,
+		value TEXT
+	)`,
+	`CREATE INDEX {{ .IndexReduce }} ON {{ .Reduce }} (first_key, first_pk)`,
+	`CREATE INDEX {{ .IndexMapReduceNode }} ON {{ .Map }} (reduce_node)`,
+	`CREATE TRIGGER {{ .TriggerReduceDelete }} AFTER DELETE ON {{ .Reduce }}
+	BEGIN
+		UPDATE {{ .Map }} SET reduce_node = NULL WHERE reduce_node = OLD.pk;
+	END`,
+	// Deleting a map row invalidates the node which covers it.
+	`CREATE TRIGGER {{ .TriggerMapDelete }} AFTER DELETE ON {{ .Map }}
+	WHEN OLD.reduce_node IS NOT NULL
+	BEGIN
+		DELETE FROM {{ .Reduce }} WHERE pk = OLD.reduce_node;
+	END`,
+	// Inserting a map row invalidates the node whose range it falls within.
+	// New rows always have the highest pk, so the row falls within a node
+	// only when its key is at least first_key, and strictly less than
+	// last_key. NULL keys sort first.
+	`CREATE TRIGGER {{ .TriggerMapInsert }} AFTER INSERT ON {{ .Map }}
+	BEGIN
+		DELETE FROM {{ .Reduce }}
+		WHERE (first_key IS NULL OR (NEW.key IS NOT NULL AND first_key <= NEW.key))
+			AND last_key IS NOT NULL
+			AND (NEW.key IS NULL OR last_key > NEW.key);
+	END`,
+}
+
 var destroySchema = []string{
 	`DROP TABLE {{ .Metadata }}`,
 	`DROP TABLE {{ .MangoIndexes }}`,
 	`DROP TABLE {{ .Design }}`,
 	`DROP TABLE {{ .AttachmentsBridge }}`,
 	`DROP TABLE {{ .Attachments }}`,
+	`DROP TABLE {{ .AttachmentChunks }}`,
+	`DROP TABLE {{ .AttachmentData }}`,
 	`DROP TABLE {{ .Docs }}`,
 	`DROP TABLE {{ .Revs }}`,
 }
+
+// searchSchema creates the tables of a search index. The tables are created
+// on first use, rather than when the design document is stored.
+var searchSchema = []string{
+	/*
+		The .SearchFields table holds the fields indexed by the search index
+		function. The schema is as follows:
+		- id: The ID of the indexed document.
+		- field: The field name, as passed to index().
+		- value: The field value, converted to a string.
+		- num: The numeric field value, or NULL for non-numeric values.
+		- store: Whether the value is returned with search results.
+		- facet: Whether the value may be used for counts and ranges facets.
+		- indexed: Whether the value is full-text indexed, in the .SearchFTS
+		  table.
+	*/
+	`CREATE TABLE IF NOT EXISTS {{ .SearchFields }} (
+		pk INTEGER PRIMARY KEY,
+		id TEXT NOT NULL,
+		field TEXT NOT NULL,
+		value TEXT NOT NULL,
+		num REAL,
+		store BOOLEAN NOT NULL DEFAULT FALSE,
+		facet BOOLEAN NOT NULL DEFAULT FALSE,
+		indexed BOOLEAN NOT NULL DEFAULT TRUE
+	)`,
+	`CREATE INDEX IF NOT EXISTS {{ .IndexSearchID }} ON {{ .SearchFields }} (id)`,
+	`CREATE INDEX IF NOT EXISTS {{ .IndexSearchField }} ON {{ .SearchFields }} (field, value)`,
+	// The full-text index, an external content FTS5 table over the indexed
+	// values.
+	`CREATE VIRTUAL TABLE IF NOT EXISTS {{ .SearchFTS }} USING fts5(
+		field UNINDEXED,
+		value,
+		content = {{ .SearchFields }},
+		content_rowid = pk,
+		tokenize = {{ .Tokenizer }}
+	)`,
+	`CREATE TRIGGER IF NOT EXISTS {{ .TriggerSearchInsert }} AFTER INSERT ON {{ .SearchFields }}
+	WHEN NEW.indexed
+	BEGIN
+		INSERT INTO {{ .SearchFTS }} (rowid, field, value) VALUES (NEW.pk, NEW.field, NEW.value);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS {{ .TriggerSearchDelete }} AFTER DELETE ON {{ .SearchFields }}
+	WHEN OLD.indexed
+	BEGIN
+		INSERT INTO {{ .SearchFTS }} ({{ .SearchFTS }}, rowid, field, value) VALUES ('delete', OLD.pk, OLD.field, OLD.value);
+	END`,
+}
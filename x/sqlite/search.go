@@ -0,0 +1,1196 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/x/options"
+)
+
+var _ driver.Searcher = (*db)(nil)
+
+// defaultSearchLimit is the number of results returned by a search, when no
+// limit is given.
+const defaultSearchLimit = 25
+
+// searchIndexDef is a search index definition, from the indexes field of a
+// design document.
+type searchIndexDef struct {
+	Analyzer json.RawMessage `json:"analyzer"`
+	Index    string          `json:"index"`
+}
+
+// searchIndex is a search index of a specific design document revision.
+type searchIndex struct {
+	ddoc     string
+	rev      revision
+	name     string
+	def      searchIndexDef
+	analyzer *searchAnalyzer
+	// ddocBody is the design document, from whose views.lib the index
+	// function may load CommonJS modules with require().
+	ddocBody []byte
+}
+
+// searchIndex reads the named search index definition from the winning
+// revision of the design document. Like show and list functions, indexes are
+// read from the design document body, rather than the Design table.
+func (d *db) searchIndex(ctx context.Context, ddoc, name string) (*searchIndex, error) {
+	ddoc = strings.TrimPrefix(ddoc, "_design/")
+	doc, rev, err := d.getCoreDoc(ctx, d.db, "_design/"+ddoc, revision{}, false, false)
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Indexes map[string]searchIndexDef `json:"indexes"`
+	}
+	if err := json.Unmarshal(doc.Doc, &body); err != nil {
+		return nil, err
+	}
+	def, ok := body.Indexes[name]
+	if !ok || def.Index == "" {
+		return nil, &internal.Error{Status: http.StatusNotFound, Message: "missing search index " + name + " on design doc _design/" + ddoc}
+	}
+	analyzer, err := parseSearchAnalyzer(def.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+	return &searchIndex{
+		ddoc:     ddoc,
+		rev:      rev,
+		name:     name,
+		def:      def,
+		analyzer: analyzer,
+		ddocBody: doc.Doc,
+	}, nil
+}
+
+// query does variable substitution on a query string, enabling the search
+// index table names.
+func (i *searchIndex) query(d *db, format string) string {
+	return d.createSearchQuery(i.ddoc, i.name, i.rev.String(), format, i.analyzer.tokenizer)
+}
+
+// seqKey returns the Metadata key under which the last indexed sequence is
+// stored.
+func (i *searchIndex) seqKey() string {
+	return searchSeqKey(i.ddoc, i.rev, i.name)
+}
+
+func searchSeqKey(ddoc string, rev revision, name string) string {
+	return "search_seq:" + ddoc + "/" + rev.String() + "/" + name
+}
+
+// searchAnalyzer describes how the values of a search index are analyzed.
+// Values are full-text indexed with a single FTS5 tokenizer, chosen by the
+// default analyzer. Fields using the keyword analyzer are instead matched
+// exactly.
+type searchAnalyzer struct {
+	tokenizer string
+	keyword   bool
+	// fields holds the keyword setting of fields with their own analyzer.
+	fields map[string]bool
+}
+
+// searchTokenizers maps the supported analyzers to FTS5 tokenizers.
+var searchTokenizers = map[string]string{
+	"standard": "unicode61",
+	"classic":  "unicode61",
+	"simple":   "unicode61",
+	"email":    "unicode61",
+	"english":  "porter unicode61",
+	"keyword":  "unicode61",
+}
+
+func parseSearchAnalyzer(raw json.RawMessage) (*searchAnalyzer, error) {
+	var def struct {
+		Name    string            `json:"name"`
+		Default string            `json:"default"`
+		Fields  map[string]string `json:"fields"`
+	}
+	if len(raw) > 0 && raw[0] == '"' {
+		if err := json.Unmarshal(raw, &def.Name); err != nil {
+			return nil, err
+		}
+	} else if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &def); err != nil {
+			return nil, &internal.Error{Status: http.StatusBadRequest, Message: "invalid analyzer: " + string(raw)}
+		}
+	}
+	name := def.Name
+	if name == "perfield" {
+		name = def.Default
+	}
+	if name == "" {
+		name = "standard"
+	}
+	tokenizer, ok := searchTokenizers[name]
+	if !ok {
+		return nil, &internal.Error{Status: http.StatusBadRequest, Message: "unsupported analyzer: " + name}
+	}
+	a := &searchAnalyzer{
+		tokenizer: tokenizer,
+		keyword:   name == "keyword",
+	}
+	if def.Name == "perfield" && len(def.Fields) > 0 {
+		a.fields = make(map[string]bool, len(def.Fields))
+		for field, name := range def.Fields {
+			if _, ok := searchTokenizers[name]; !ok {
+				return nil, &internal.Error{Status: http.StatusBadRequest, Message: "unsupported analyzer: " + name}
+			}
+			a.fields[field] = name == "keyword"
+		}
+	}
+	return a, nil
+}
+
+func (a *searchAnalyzer) isKeyword(field string) bool {
+	if keyword, ok := a.fields[field]; ok {
+		return keyword
+	}
+	return a.keyword
+}
+
+// analyzeText splits text into lowercase terms, similar to the standard
+// analyzer.
+func analyzeText(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// SearchAnalyze returns the terms produced by the standard analyzer for text.
+func (*db) SearchAnalyze(_ context.Context, text string) ([]string, error) {
+	return analyzeText(text), nil
+}
+
+// createSearchTables creates the tables of the search index, if they do not
+// already exist.
+func (d *db) createSearchTables(ctx context.Context, idx *searchIndex) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range searchSchema {
+		if _, err := tx.ExecContext(ctx, idx.query(d, query)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// dropSearchTables drops the tables of all search indexes of the design
+// document revision curRev.
+func (d *db) dropSearchTables(ctx context.Context, tx *sql.Tx, docID string, curRev revision) error {
+	if curRev.rev == 0 {
+		return nil
+	}
+	var doc []byte
+	err := tx.QueryRowContext(ctx, d.query(`
+		SELECT doc
+		FROM {{ .Docs }}
+		WHERE id = $1 AND rev = $2 AND rev_id = $3
+	`), docID, curRev.rev, curRev.id).Scan(&doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var body struct {
+		Indexes map[string]json.RawMessage `json:"indexes"`
+	}
+	if err := json.Unmarshal(doc, &body); err != nil {
+		return nil
+	}
+	ddoc := strings.TrimPrefix(docID, "_design/")
+	for name := range body.Indexes {
+		for _, query := range []string{
+			`DROP TABLE IF EXISTS {{ .SearchFTS }}`,
+			`DROP TABLE IF EXISTS {{ .SearchFields }}`,
+		} {
+			if _, err := tx.ExecContext(ctx, d.ddocQuery(ddoc, name, curRev.String(), query)); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, d.query(`DELETE FROM {{ .Metadata }} WHERE key = $1`), searchSeqKey(ddoc, curRev, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchDropQueries returns DROP TABLE queries for all search index tables in
+// the database. Full-text tables are dropped first, as that also drops their
+// shadow tables.
+func (d *db) searchDropQueries(ctx context.Context, tx *sql.Tx) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT name
+		FROM sqlite_schema
+		WHERE type = 'table' AND name GLOB $1
+		ORDER BY name GLOB '*$fts' DESC
+	`, tablePrefix+d.name+"$search$*")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var queries []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		queries = append(queries, "DROP TABLE IF EXISTS "+strconv.Quote(name))
+	}
+	return queries, rows.Err()
+}
+
+// searchField is a single value indexed by a search index function.
+type searchField struct {
+	id, field, value      string
+	num                   *float64
+	store, facet, indexed bool
+}
+
+// newSearchField converts the arguments of a call to index() to a
+// searchField. Values must be strings, numbers or booleans.
+func newSearchField(id, field string, value any, opts map[string]any) (searchField, error) {
+	f := searchField{id: id, field: field, indexed: true}
+	switch t := value.(type) {
+	case string:
+		f.value = t
+	case bool:
+		f.value = strconv.FormatBool(t)
+	case int64:
+		num := float64(t)
+		f.value, f.num = strconv.FormatInt(t, 10), &num
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return f, fmt.Errorf("invalid value for field %s: %v", field, value)
+		}
+		encoded, _ := json.Marshal(t)
+		f.value, f.num = string(encoded), &t
+	default:
+		return f, fmt.Errorf("invalid value for field %s: %v", field, value)
+	}
+	f.store, _ = opts["store"].(bool)
+	f.facet, _ = opts["facet"].(bool)
+	if indexed, ok := opts["index"].(bool); ok {
+		f.indexed = indexed
+	}
+	return f, nil
+}
+
+type searchIndexBatch struct {
+	// ids lists the documents whose existing entries are replaced.
+	ids    []any
+	fields []searchField
+}
+
+func (b *searchIndexBatch) clear() {
+	b.ids = b.ids[:0]
+	b.fields = b.fields[:0]
+}
+
+// drop discards the fields added for id, which are always the last ones.
+func (b *searchIndexBatch) drop(id string) {
+	for len(b.fields) > 0 && b.fields[len(b.fields)-1].id == id {
+		b.fields = b.fields[:len(b.fields)-1]
+	}
+}
+
+// updateSearchIndex brings the search index up to date with the database.
+func (d *db) updateSearchIndex(ctx context.Context, idx *searchIndex) error {
+	if err := d.createSearchTables(ctx, idx); err != nil {
+		return err
+	}
+	lastSeq, err := d.metadataInt(ctx, d.db, idx.seqKey(), 0)
+	if err != nil {
+		return err
+	}
+
+	docs, err := d.db.QueryContext(ctx, d.indexDocsQuery(), lastSeq)
+	if err != nil {
+		return err
+	}
+	defer docs.Close()
+
+	batch := &searchIndexBatch{}
+	var docID string
+	rt, err := d.designJS("_design/"+idx.ddoc, "indexes/"+idx.name, idx.ddocBody)
+	if err != nil {
+		return err
+	}
+	indexFunc, err := rt.Index(idx.def.Index, func(field string, value any, opts map[string]any) {
+		f, err := newSearchField(docID, field, value, opts)
+		if err != nil {
+			d.logger.Printf("search index function indexed invalid value for %s: %s", docID, err)
+			return
+		}
+		batch.fields = append(batch.fields, f)
+	})
+	if err != nil {
+		return err
+	}
+
+	seq := lastSeq
+	for {
+		full := &fullDoc{}
+		err := iter(docs, &seq, full)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if full.ID == "" || strings.HasPrefix(full.ID, "_local/") || strings.HasPrefix(full.ID, "_design/") {
+			continue
+		}
+
+		batch.ids = append(batch.ids, full.ID)
+		if full.Deleted {
+			continue
+		}
+		docID = full.ID
+		if err := indexFunc(ctx, full.toMap()); err != nil {
+			d.logger.Printf("search index function threw exception for %s: %s", full.ID, err)
+			batch.drop(full.ID)
+		}
+
+		if len(batch.ids) >= batchSize {
+			if err := d.writeSearchIndexBatch(ctx, idx, seq, batch); err != nil {
+				return err
+			}
+			batch.clear()
+		}
+	}
+	if err := docs.Err(); err != nil {
+		return err
+	}
+	if seq == lastSeq {
+		return nil
+	}
+	return d.writeSearchIndexBatch(ctx, idx, seq, batch)
+}
+
+func (d *db) writeSearchIndexBatch(ctx context.Context, idx *searchIndex, seq int, batch *searchIndexBatch) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if len(batch.ids) > 0 {
+		query := fmt.Sprintf(idx.query(d, `
+			DELETE FROM {{ .SearchFields }}
+			WHERE id IN (%s)
+		`), placeholders(1, len(batch.ids)))
+		if _, err := tx.ExecContext(ctx, query, batch.ids...); err != nil {
+			return err
+		}
+	}
+
+	if len(batch.fields) > 0 {
+		stmt, err := tx.PrepareContext(ctx, idx.query(d, `
+			INSERT INTO {{ .SearchFields }} (id, field, value, num, store, facet, indexed)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, f := range batch.fields {
+			if _, err := stmt.ExecContext(ctx, f.id, f.field, f.value, f.num, f.store, f.facet, f.indexed); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := d.setMetadata(ctx, tx, idx.seqKey(), strconv.Itoa(seq)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// searchHits maps the IDs of matching documents to their scores.
+type searchHits map[string]float64
+
+// searchIDs runs query, which must return document IDs and scores, and
+// returns the results as searchHits. Documents returned more than once get
+// their highest score.
+func (d *db) searchIDs(ctx context.Context, query string, args ...any) (searchHits, error) {
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hits := searchHits{}
+	for rows.Next() {
+		var (
+			id    string
+			score float64
+		)
+		if err := rows.Scan(&id, &score); err != nil {
+			return nil, err
+		}
+		if existing, ok := hits[id]; !ok || score > existing {
+			hits[id] = score
+		}
+	}
+	return hits, rows.Err()
+}
+
+// searchEval returns the documents matching q.
+func (d *db) searchEval(ctx context.Context, idx *searchIndex, q searchQuery) (searchHits, error) {
+	switch t := q.(type) {
+	case *searchBool:
+		return d.searchEvalBool(ctx, idx, t)
+	case *searchTerm:
+		return d.searchEvalTerm(ctx, idx, t)
+	case *searchRange:
+		return d.searchEvalRange(ctx, idx, t)
+	case searchMatchAll:
+		return d.searchIDs(ctx, idx.query(d, `
+			SELECT DISTINCT id, 1.0
+			FROM {{ .SearchFields }}
+		`))
+	}
+	panic(fmt.Sprintf("unexpected search query type %T", q))
+}
+
+func (d *db) searchEvalBool(ctx context.Context, idx *searchIndex, q *searchBool) (searchHits, error) {
+	results := make([]searchHits, len(q.clauses))
+	for i, clause := range q.clauses {
+		hits, err := d.searchEval(ctx, idx, clause.query)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = hits
+	}
+
+	var matched searchHits
+	for i, clause := range q.clauses {
+		if clause.occur != occurMust {
+			continue
+		}
+		if matched == nil {
+			matched = results[i]
+			continue
+		}
+		for id, score := range matched {
+			other, ok := results[i][id]
+			if !ok {
+				delete(matched, id)
+				continue
+			}
+			matched[id] = score + other
+		}
+	}
+	required := matched != nil
+	if !required {
+		matched = searchHits{}
+	}
+	for i, clause := range q.clauses {
+		if clause.occur != occurShould {
+			continue
+		}
+		for id, score := range results[i] {
+			if _, ok := matched[id]; ok || !required {
+				matched[id] += score
+			}
+		}
+	}
+	for i, clause := range q.clauses {
+		if clause.occur != occurMustNot {
+			continue
+		}
+		for id := range results[i] {
+			delete(matched, id)
+		}
+	}
+	return matched, nil
+}
+
+func (d *db) searchEvalTerm(ctx context.Context, idx *searchIndex, q *searchTerm) (searchHits, error) {
+	switch {
+	case q.prefix && q.text == "":
+		return d.searchIDs(ctx, idx.query(d, `
+			SELECT DISTINCT id, 1.0
+			FROM {{ .SearchFields }}
+			WHERE field = $1
+		`), q.field)
+	case idx.analyzer.isKeyword(q.field) && q.prefix:
+		return d.searchIDs(ctx, idx.query(d, `
+			SELECT DISTINCT id, 1.0
+			FROM {{ .SearchFields }}
+			WHERE field = $1 AND substr(value, 1, length($2)) = $2
+		`), q.field, q.text)
+	case idx.analyzer.isKeyword(q.field):
+		return d.searchIDs(ctx, idx.query(d, `
+			SELECT DISTINCT id, 1.0
+			FROM {{ .SearchFields }}
+			WHERE field = $1 AND value = $2
+		`), q.field, q.text)
+	}
+	if len(analyzeText(q.text)) == 0 {
+		return searchHits{}, nil
+	}
+	match := `"` + strings.ReplaceAll(q.text, `"`, `""`) + `"`
+	if q.prefix {
+		match += " *"
+	}
+	return d.searchIDs(ctx, idx.query(d, `
+		SELECT fields.id, -bm25({{ .SearchFTS }})
+		FROM {{ .SearchFTS }}
+		JOIN {{ .SearchFields }} AS fields ON fields.pk = {{ .SearchFTS }}.rowid
+		WHERE {{ .SearchFTS }} MATCH $1 AND fields.field = $2
+	`), match, q.field)
+}
+
+func (d *db) searchEvalRange(ctx context.Context, idx *searchIndex, q *searchRange) (searchHits, error) {
+	lower, lowerNum := searchNumber(q.lower)
+	upper, upperNum := searchNumber(q.upper)
+	numeric := (q.lower == "" || lowerNum) && (q.upper == "" || upperNum)
+
+	where := []string{"field = $1"}
+	args := []any{q.field}
+	column := "lower(value)"
+	switch {
+	case numeric:
+		column = "num"
+		where = append(where, "num IS NOT NULL")
+	case idx.analyzer.isKeyword(q.field):
+		column = "value"
+	default:
+		q.lower, q.upper = strings.ToLower(q.lower), strings.ToLower(q.upper)
+	}
+	addBound := func(bound string, num float64, op string) {
+		if bound == "" {
+			return
+		}
+		if numeric {
+			args = append(args, num)
+		} else {
+			args = append(args, bound)
+		}
+		where = append(where, fmt.Sprintf("%s %s $%d", column, op, len(args)))
+	}
+	addBound(q.lower, lower, map[bool]string{true: ">=", false: ">"}[q.inclLower])
+	addBound(q.upper, upper, map[bool]string{true: "<=", false: "<"}[q.inclUpper])
+
+	return d.searchIDs(ctx, idx.query(d, `
+		SELECT DISTINCT id, 1.0
+		FROM {{ .SearchFields }}
+		WHERE `+strings.Join(where, " AND ")), args...)
+}
+
+// searchDrilldown removes the hits which do not match all drilldown lists.
+func (d *db) searchDrilldown(ctx context.Context, idx *searchIndex, hits searchHits, drilldown [][]string) error {
+	for _, dd := range drilldown {
+		args := make([]any, len(dd))
+		for i, v := range dd {
+			args[i] = v
+		}
+		matched, err := d.searchIDs(ctx, fmt.Sprintf(idx.query(d, `
+			SELECT DISTINCT id, 1.0
+			FROM {{ .SearchFields }}
+			WHERE field = $1 AND value IN (%s)
+		`), placeholders(2, len(dd)-1)), args...)
+		if err != nil {
+			return err
+		}
+		for id := range hits {
+			if _, ok := matched[id]; !ok {
+				delete(hits, id)
+			}
+		}
+	}
+	return nil
+}
+
+// searchCounts returns the number of hits with each value of the faceted
+// string fields.
+func (d *db) searchCounts(ctx context.Context, idx *searchIndex, hits searchHits, fields []string) (map[string]map[string]int64, error) {
+	counts := make(map[string]map[string]int64, len(fields))
+	for _, field := range fields {
+		counts[field] = map[string]int64{}
+		rows, err := d.db.QueryContext(ctx, idx.query(d, `
+			SELECT DISTINCT id, value
+			FROM {{ .SearchFields }}
+			WHERE field = $1 AND facet AND num IS NULL
+		`), field)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id, value string
+			if err := rows.Scan(&id, &value); err != nil {
+				_ = rows.Close()
+				return nil, err
+			}
+			if _, ok := hits[id]; ok {
+				counts[field][value]++
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		_ = rows.Close()
+	}
+	return counts, nil
+}
+
+// searchRanges returns the number of hits with a value of the faceted numeric
+// fields within each range.
+func (d *db) searchRanges(ctx context.Context, idx *searchIndex, hits searchHits, ranges map[string]map[string]string) (map[string]map[string]int64, error) {
+	result := make(map[string]map[string]int64, len(ranges))
+	for field, labels := range ranges {
+		type numRange struct {
+			label        string
+			lower, upper float64
+			inclusive    [2]bool
+		}
+		parsed := make([]numRange, 0, len(labels))
+		for label, query := range labels {
+			r, err := parseSearchRange(field, query)
+			if err != nil {
+				return nil, err
+			}
+			nr := numRange{label: label, lower: math.Inf(-1), upper: math.Inf(1), inclusive: [2]bool{r.inclLower, r.inclUpper}}
+			var ok bool
+			if r.lower != "" {
+				if nr.lower, ok = searchNumber(r.lower); !ok {
+					return nil, &internal.Error{Status: http.StatusBadRequest, Message: "invalid numeric range: " + query}
+				}
+			}
+			if r.upper != "" {
+				if nr.upper, ok = searchNumber(r.upper); !ok {
+					return nil, &internal.Error{Status: http.StatusBadRequest, Message: "invalid numeric range: " + query}
+				}
+			}
+			parsed = append(parsed, nr)
+		}
+
+		result[field] = make(map[string]int64, len(labels))
+		for _, nr := range parsed {
+			result[field][nr.label] = 0
+		}
+		rows, err := d.db.QueryContext(ctx, idx.query(d, `
+			SELECT id, num
+			FROM {{ .SearchFields }}
+			WHERE field = $1 AND facet AND num IS NOT NULL
+		`), field)
+		if err != nil {
+			return nil, err
+		}
+		counted := map[string]map[string]bool{}
+		for rows.Next() {
+			var (
+				id  string
+				num float64
+			)
+			if err := rows.Scan(&id, &num); err != nil {
+				_ = rows.Close()
+				return nil, err
+			}
+			if _, ok := hits[id]; !ok {
+				continue
+			}
+			for _, nr := range parsed {
+				if (num > nr.lower || (nr.inclusive[0] && num == nr.lower)) &&
+					(num < nr.upper || (nr.inclusive[1] && num == nr.upper)) &&
+					!counted[nr.label][id] {
+					if counted[nr.label] == nil {
+						counted[nr.label] = map[string]bool{}
+					}
+					counted[nr.label][id] = true
+					result[field][nr.label]++
+				}
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		_ = rows.Close()
+	}
+	return result, nil
+}
+
+// parseSearchRange parses a single range query, such as "[0 TO 100]".
+func parseSearchRange(field, query string) (*searchRange, error) {
+	p := &searchParser{input: []rune(strings.TrimSpace(query))}
+	if p.peek() != '[' && p.peek() != '{' {
+		return nil, p.errorf("expected range")
+	}
+	q, err := p.parseRange(field)
+	if err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		return nil, p.errorf("unexpected '%c'", p.peek())
+	}
+	return q.(*searchRange), nil
+}
+
+// searchResult is a single search result, with its sort order.
+type searchResult struct {
+	id    string
+	score float64
+	order []any
+}
+
+// searchSortField is a parsed field of the sort option.
+type searchSortField struct {
+	field string
+	desc  bool
+	// typ is "number", "string", or "" to choose by the indexed values.
+	typ string
+}
+
+func parseSearchSort(fields []string) ([]searchSortField, error) {
+	result := make([]searchSortField, len(fields))
+	for i, f := range fields {
+		var sf searchSortField
+		if strings.HasPrefix(f, "-") {
+			sf.desc, f = true, f[1:]
+		}
+		if name, typ, ok := strings.Cut(f, "<"); ok && name != "" {
+			switch typ {
+			case "number>", "string>":
+				sf.typ = strings.TrimSuffix(typ, ">")
+			default:
+				return nil, &internal.Error{Status: http.StatusBadRequest, Message: "invalid sort field: " + fields[i]}
+			}
+			f = name
+		}
+		if f == "" {
+			return nil, &internal.Error{Status: http.StatusBadRequest, Message: "invalid sort field: " + fields[i]}
+		}
+		sf.field = f
+		result[i] = sf
+	}
+	return result, nil
+}
+
+// searchSortValues returns the sort value of each hit for a field: the
+// lowest value of the field, as a string or a number.
+func (d *db) searchSortValues(ctx context.Context, idx *searchIndex, sf searchSortField) (map[string]any, error) {
+	rows, err := d.db.QueryContext(ctx, idx.query(d, `
+		SELECT id, value, num
+		FROM {{ .SearchFields }}
+		WHERE field = $1
+		ORDER BY pk
+	`), sf.field)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	strs := map[string]string{}
+	nums := map[string]float64{}
+	allNumeric := true
+	for rows.Next() {
+		var (
+			id, value string
+			num       *float64
+		)
+		if err := rows.Scan(&id, &value, &num); err != nil {
+			return nil, err
+		}
+		if s, ok := strs[id]; !ok || value < s {
+			strs[id] = value
+		}
+		if num == nil {
+			allNumeric = false
+			continue
+		}
+		if n, ok := nums[id]; !ok || *num < n {
+			nums[id] = *num
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	values := make(map[string]any, len(strs))
+	if sf.typ == "number" || (sf.typ == "" && allNumeric) {
+		for id, n := range nums {
+			values[id] = n
+		}
+		return values, nil
+	}
+	for id, s := range strs {
+		values[id] = s
+	}
+	return values, nil
+}
+
+// compareSortValues compares two sort values of the same type. Missing values
+// sort last.
+func compareSortValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	switch t := a.(type) {
+	case float64:
+		u, _ := b.(float64)
+		switch {
+		case t < u:
+			return -1
+		case t > u:
+			return 1
+		}
+	case string:
+		return strings.Compare(t, b.(string))
+	}
+	return 0
+}
+
+// searchSort sorts the hits, according to the sort option, and returns them
+// with the sort order of each.
+func (d *db) searchSort(ctx context.Context, idx *searchIndex, hits searchHits, sortFields []searchSortField) ([]*searchResult, error) {
+	results := make([]*searchResult, 0, len(hits))
+	for id, score := range hits {
+		results = append(results, &searchResult{id: id, score: score})
+	}
+	if len(sortFields) == 0 {
+		sortFields = []searchSortField{{field: "<score>"}}
+	}
+	for _, sf := range sortFields {
+		var values map[string]any
+		if sf.field != "<score>" && sf.field != "<doc>" {
+			var err error
+			if values, err = d.searchSortValues(ctx, idx, sf); err != nil {
+				return nil, err
+			}
+		}
+		for _, r := range results {
+			switch sf.field {
+			case "<score>":
+				r.order = append(r.order, r.score)
+			case "<doc>":
+				r.order = append(r.order, r.id)
+			default:
+				r.order = append(r.order, values[r.id])
+			}
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		for k, sf := range sortFields {
+			c := compareSortValues(a.order[k], b.order[k])
+			if a.order[k] != nil && b.order[k] != nil {
+				// Scores sort descending by default, as in Lucene.
+				if (sf.field == "<score>") != sf.desc {
+					c = -c
+				}
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return a.id < b.id
+	})
+	return results, nil
+}
+
+// Search performs a full-text search against a search index. Each result row
+// has the matching document ID, the sort order of the result as the key, and
+// the stored fields as the value. The returned rows also implement
+// [driver.Bookmarker] and [driver.SearchFacets].
+func (d *db) Search(ctx context.Context, ddoc, index, query string, opts map[string]any) (driver.Rows, error) {
+	optsMap := options.Map(opts)
+	if optsMap == nil {
+		optsMap = options.Map{}
+	}
+	idx, err := d.searchIndex(ctx, ddoc, index)
+	if err != nil {
+		return nil, err
+	}
+	update, err := optsMap.Update()
+	if err != nil {
+		return nil, err
+	}
+	if optsMap["stale"] == "ok" {
+		update = options.UpdateModeFalse
+	}
+	if update == options.UpdateModeFalse {
+		err = d.createSearchTables(ctx, idx)
+	} else {
+		err = d.updateSearchIndex(ctx, idx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := parseSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := d.searchEval(ctx, idx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	drilldown, err := optsMap.Drilldown()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.searchDrilldown(ctx, idx, hits, drilldown); err != nil {
+		return nil, err
+	}
+
+	r := &searchRows{totalRows: int64(len(hits))}
+	if counts, err := optsMap.Counts(); err != nil {
+		return nil, err
+	} else if len(counts) > 0 {
+		if r.counts, err = d.searchCounts(ctx, idx, hits, counts); err != nil {
+			return nil, err
+		}
+	}
+	if ranges, err := optsMap.Ranges(); err != nil {
+		return nil, err
+	} else if len(ranges) > 0 {
+		if r.ranges, err = d.searchRanges(ctx, idx, hits, ranges); err != nil {
+			return nil, err
+		}
+	}
+
+	sortOpt, err := optsMap.SearchSort()
+	if err != nil {
+		return nil, err
+	}
+	sortFields, err := parseSearchSort(sortOpt)
+	if err != nil {
+		return nil, err
+	}
+	results, err := d.searchSort(ctx, idx, hits, sortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := searchBookmarkOffset(optsMap)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := optsMap.Limit()
+	if err != nil {
+		return nil, err
+	}
+	if limit < 0 {
+		limit = defaultSearchLimit
+	}
+	start := min(offset, len(results))
+	results = results[start:min(start+int(limit), len(results))]
+	r.bookmark = base64.StdEncoding.EncodeToString([]byte(strconv.Itoa(start + len(results))))
+
+	includeFields, err := optsMap.IncludeFields()
+	if err != nil {
+		return nil, err
+	}
+	stored, err := d.searchStoredFields(ctx, idx, results, includeFields)
+	if err != nil {
+		return nil, err
+	}
+	includeDocs, err := optsMap.IncludeDocs()
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		order, _ := json.Marshal(result.order)
+		fields, _ := json.Marshal(stored[result.id])
+		row := &driver.Row{
+			ID:    result.id,
+			Key:   order,
+			Value: bytes.NewReader(fields),
+		}
+		if includeDocs {
+			doc, _, err := d.getCoreDoc(ctx, d.db, result.id, revision{}, false, false)
+			if err != nil {
+				return nil, err
+			}
+			doc.LocalSeq = 0
+			row.Doc = bytes.NewReader(doc.toRaw())
+		}
+		r.rows = append(r.rows, row)
+	}
+	return r, nil
+}
+
+// searchBookmarkOffset returns the number of results to skip, encoded in the
+// bookmark option.
+func searchBookmarkOffset(opts options.Map) (int, error) {
+	bookmark, err := opts.Bookmark()
+	if err != nil || bookmark == "" {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(bookmark)
+	if err != nil || offset < 0 {
+		return 0, &internal.Error{Status: http.StatusBadRequest, Message: fmt.Sprintf("invalid value for 'bookmark': %v", opts["bookmark"])}
+	}
+	return offset, nil
+}
+
+// searchStoredFields returns the stored fields of each result. Fields with
+// several values are returned as arrays.
+func (d *db) searchStoredFields(ctx context.Context, idx *searchIndex, results []*searchResult, include []string) (map[string]map[string]any, error) {
+	stored := make(map[string]map[string]any, len(results))
+	if len(results) == 0 {
+		return stored, nil
+	}
+	args := make([]any, len(results))
+	for i, r := range results {
+		args[i] = r.id
+		stored[r.id] = map[string]any{}
+	}
+	rows, err := d.db.QueryContext(ctx, fmt.Sprintf(idx.query(d, `
+		SELECT id, field, value, num
+		FROM {{ .SearchFields }}
+		WHERE store AND id IN (%s)
+		ORDER BY pk
+	`), placeholders(1, len(args))), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	included := make(map[string]bool, len(include))
+	for _, field := range include {
+		included[field] = true
+	}
+	for rows.Next() {
+		var (
+			id, field, value string
+			num              *float64
+		)
+		if err := rows.Scan(&id, &field, &value, &num); err != nil {
+			return nil, err
+		}
+		if include != nil && !included[field] {
+			continue
+		}
+		var v any = value
+		if num != nil {
+			v = *num
+		}
+		switch existing := stored[id][field].(type) {
+		case nil:
+			stored[id][field] = v
+		case []any:
+			stored[id][field] = append(existing, v)
+		default:
+			stored[id][field] = []any{existing, v}
+		}
+	}
+	return stored, rows.Err()
+}
+
+type searchRows struct {
+	rows           []*driver.Row
+	totalRows      int64
+	bookmark       string
+	counts, ranges map[string]map[string]int64
+}
+
+var (
+	_ driver.Rows         = (*searchRows)(nil)
+	_ driver.Bookmarker   = (*searchRows)(nil)
+	_ driver.SearchFacets = (*searchRows)(nil)
+)
+
+func (r *searchRows) Next(row *driver.Row) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	*row = *r.rows[0]
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func (*searchRows) Close() error      { return nil }
+func (*searchRows) UpdateSeq() string { return "" }
+func (*searchRows) Offset() int64     { return 0 }
+
+func (r *searchRows) TotalRows() int64                    { return r.totalRows }
+func (r *searchRows) Bookmark() string                    { return r.bookmark }
+func (r *searchRows) Counts() map[string]map[string]int64 { return r.counts }
+func (r *searchRows) Ranges() map[string]map[string]int64 { return r.ranges }
+
+// SearchInfo returns statistics about a search index. The index is not
+// updated.
+func (d *db) SearchInfo(ctx context.Context, ddoc, index string) (*driver.SearchInfo, error) {
+	idx, err := d.searchIndex(ctx, ddoc, index)
+	if err != nil {
+		return nil, err
+	}
+	pendingSeq, err := d.lastSeq(ctx)
+	if err != nil {
+		return nil, err
+	}
+	committedSeq, err := d.metadataInt(ctx, d.db, idx.seqKey(), 0)
+	if err != nil {
+		return nil, err
+	}
+	var docCount, diskSize int64
+	err = d.db.QueryRowContext(ctx, idx.query(d, `
+		SELECT
+			COUNT(DISTINCT id),
+			(SELECT COALESCE(SUM(pgsize), 0) FROM dbstat WHERE name GLOB $1)
+		FROM {{ .SearchFields }}
+	`), strings.Trim(idx.query(d, `{{ .SearchFields }}`), `"`)+"*").Scan(&docCount, &diskSize)
+	if err != nil && !errIsNoSuchTable(err) {
+		return nil, err
+	}
+
+	info := &driver.SearchInfo{
+		Name: "_design/" + idx.ddoc + "/" + idx.name,
+		SearchIndex: driver.SearchIndex{
+			PendingSeq:   int64(pendingSeq),
+			DocCount:     docCount,
+			DiskSize:     diskSize,
+			CommittedSeq: int64(committedSeq),
+		},
+	}
+	info.RawResponse, _ = json.Marshal(map[string]any{
+		"name": info.Name,
+		"search_index": map[string]any{
+			"pending_seq":   info.SearchIndex.PendingSeq,
+			"doc_del_count": info.SearchIndex.DocDelCount,
+			"doc_count":     info.SearchIndex.DocCount,
+			"disk_size":     info.SearchIndex.DiskSize,
+			"committed_seq": info.SearchIndex.CommittedSeq,
+		},
+	})
+	return info, nil
+}
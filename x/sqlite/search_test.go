@@ -0,0 +1,596 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4"
+	"github.com/go-kivik/kivik/v4/driver"
+	"github.com/go-kivik/kivik/v4/int/mock"
+)
+
+// newSearchDB returns a database with a design document containing search
+// indexes, and a few books.
+func newSearchDB(t *testing.T) *testDB {
+	t.Helper()
+	d := newDB(t)
+	d.tPut("_design/books", map[string]any{
+		"indexes": map[string]any{
+			"books": map[string]any{
+				"index": `function(doc) {
+					if (doc.title) { index("default", doc.title); index("title", doc.title, {"store": true}); }
+					if (doc.year) { index("year", doc.year, {"store": true, "facet": true}); }
+					if (doc.genre) { index("genre", doc.genre, {"facet": true}); }
+				}`,
+			},
+			"english": map[string]any{
+				"analyzer": "english",
+				"index":    `function(doc) { if (doc.title) { index("default", doc.title); } }`,
+			},
+			"perfield": map[string]any{
+				"analyzer": map[string]any{
+					"name":    "perfield",
+					"default": "standard",
+					"fields":  map[string]any{"genre": "keyword"},
+				},
+				"index": `function(doc) { if (doc.genre) { index("genre", doc.genre); } }`,
+			},
+		},
+	})
+	d.tPut("moby", map[string]any{"title": "Moby Dick", "year": 1851, "genre": "adventure"})
+	d.tPut("sea", map[string]any{"title": "The Old Man and the Sea", "year": 1952, "genre": "Literary fiction"})
+	d.tPut("dick", map[string]any{"title": "Philip K. Dick Stories", "year": 1987, "genre": "science fiction"})
+	d.tPut("island", map[string]any{"title": "Treasure Island", "year": 1883, "genre": "adventure"})
+	d.tPut("notitle", map[string]any{"year": 2000})
+	return d
+}
+
+func TestDBSearch(t *testing.T) {
+	t.Parallel()
+	type test struct {
+		db         *testDB
+		index      string
+		query      string
+		options    map[string]any
+		want       []rowResult
+		wantTotal  int64
+		wantCounts map[string]map[string]int64
+		wantRanges map[string]map[string]int64
+		wantStatus int
+		wantErr    string
+	}
+
+	// ids returns rows with only the IDs set, for tests which sort by <doc>.
+	ids := func(ids ...string) []rowResult {
+		rows := make([]rowResult, len(ids))
+		for i, id := range ids {
+			rows[i] = rowResult{ID: id, Key: `["` + id + `"]`, Value: "{}"}
+		}
+		return rows
+	}
+	byDoc := map[string]any{"sort": "<doc>", "include_fields": []any{}}
+
+	tests := testy.NewTable()
+	tests.Add("missing design doc", test{
+		db:         newDB(t),
+		index:      "books",
+		query:      "foo",
+		wantStatus: http.StatusNotFound,
+		wantErr:    "not found",
+	})
+	tests.Add("missing index", test{
+		index:      "nope",
+		query:      "foo",
+		wantStatus: http.StatusNotFound,
+		wantErr:    "missing search index nope on design doc _design/books",
+	})
+	tests.Add("invalid query", test{
+		index:      "books",
+		query:      "title:(moby",
+		wantStatus: http.StatusBadRequest,
+		wantErr:    "cannot parse query at position 11: missing ')'",
+	})
+	tests.Add("single term, with stored fields", test{
+		index: "books",
+		query: "moby",
+		want: []rowResult{
+			{ID: "moby", Value: `{"title":"Moby Dick","year":1851}`},
+		},
+		wantTotal: 1,
+	})
+	tests.Add("term matches several docs", test{
+		index:     "books",
+		query:     "dick",
+		options:   byDoc,
+		want:      ids("dick", "moby"),
+		wantTotal: 2,
+	})
+	tests.Add("case insensitive field term", test{
+		index:     "books",
+		query:     "title:ISLAND",
+		options:   byDoc,
+		want:      ids("island"),
+		wantTotal: 1,
+	})
+	tests.Add("phrase", test{
+		index:     "books",
+		query:     `"old man"`,
+		options:   byDoc,
+		want:      ids("sea"),
+		wantTotal: 1,
+	})
+	tests.Add("phrase out of order", test{
+		index:   "books",
+		query:   `"man old"`,
+		options: byDoc,
+	})
+	tests.Add("prefix", test{
+		index:     "books",
+		query:     "treas*",
+		options:   byDoc,
+		want:      ids("island"),
+		wantTotal: 1,
+	})
+	tests.Add("field exists", test{
+		index:     "books",
+		query:     "genre:*",
+		options:   byDoc,
+		want:      ids("dick", "island", "moby", "sea"),
+		wantTotal: 4,
+	})
+	tests.Add("match all", test{
+		index:     "books",
+		query:     "*:*",
+		options:   byDoc,
+		want:      ids("dick", "island", "moby", "notitle", "sea"),
+		wantTotal: 5,
+	})
+	tests.Add("and", test{
+		index:     "books",
+		query:     "dick AND genre:fiction",
+		options:   byDoc,
+		want:      ids("dick"),
+		wantTotal: 1,
+	})
+	tests.Add("or", test{
+		index:     "books",
+		query:     "moby OR island",
+		options:   byDoc,
+		want:      ids("island", "moby"),
+		wantTotal: 2,
+	})
+	tests.Add("not", test{
+		index:     "books",
+		query:     "genre:* NOT genre:adventure",
+		options:   byDoc,
+		want:      ids("dick", "sea"),
+		wantTotal: 2,
+	})
+	tests.Add("required and prohibited", test{
+		index:     "books",
+		query:     "+genre:fiction -dick",
+		options:   byDoc,
+		want:      ids("sea"),
+		wantTotal: 1,
+	})
+	tests.Add("numeric range", test{
+		index:     "books",
+		query:     "year:[1851 TO 1900}",
+		options:   byDoc,
+		want:      ids("island", "moby"),
+		wantTotal: 2,
+	})
+	tests.Add("open numeric range", test{
+		index:     "books",
+		query:     "year:{1952 TO *]",
+		options:   byDoc,
+		want:      ids("dick", "notitle"),
+		wantTotal: 2,
+	})
+	tests.Add("string range", test{
+		index:     "books",
+		query:     "title:[a TO n]",
+		options:   byDoc,
+		want:      ids("moby"),
+		wantTotal: 1,
+	})
+	tests.Add("sort by number", test{
+		index:   "books",
+		query:   "genre:*",
+		options: map[string]any{"sort": "-year", "include_fields": []any{"year"}},
+		want: []rowResult{
+			{ID: "dick", Key: "[1987]", Value: `{"year":1987}`},
+			{ID: "sea", Key: "[1952]", Value: `{"year":1952}`},
+			{ID: "island", Key: "[1883]", Value: `{"year":1883}`},
+			{ID: "moby", Key: "[1851]", Value: `{"year":1851}`},
+		},
+		wantTotal: 4,
+	})
+	tests.Add("sort by string", test{
+		index:   "books",
+		query:   "year:[1850 TO 1900]",
+		options: map[string]any{"sort": []any{"title<string>"}, "include_fields": []any{}},
+		want: []rowResult{
+			{ID: "moby", Key: `["Moby Dick"]`, Value: "{}"},
+			{ID: "island", Key: `["Treasure Island"]`, Value: "{}"},
+		},
+		wantTotal: 2,
+	})
+	tests.Add("invalid sort", test{
+		index:      "books",
+		query:      "moby",
+		options:    map[string]any{"sort": "year<date>"},
+		wantStatus: http.StatusBadRequest,
+		wantErr:    "invalid sort field: year<date>",
+	})
+	tests.Add("limit", test{
+		index:     "books",
+		query:     "*:*",
+		options:   map[string]any{"sort": "<doc>", "include_fields": []any{}, "limit": 2},
+		want:      ids("dick", "island"),
+		wantTotal: 5,
+	})
+	tests.Add("include_docs", test{
+		index:   "books",
+		query:   "moby",
+		options: map[string]any{"include_docs": true, "include_fields": []any{}},
+		want: []rowResult{
+			{ID: "moby", Value: "{}", Doc: `{"_id":"moby","_rev":"1-","genre":"adventure","title":"Moby Dick","year":1851}`},
+		},
+		wantTotal: 1,
+	})
+	tests.Add("counts", test{
+		index:     "books",
+		query:     "*:*",
+		options:   map[string]any{"counts": []any{"genre"}, "limit": 0},
+		wantTotal: 5,
+		wantCounts: map[string]map[string]int64{
+			"genre": {"adventure": 2, "Literary fiction": 1, "science fiction": 1},
+		},
+	})
+	tests.Add("ranges", test{
+		index: "books",
+		query: "*:*",
+		options: map[string]any{"limit": 0, "ranges": `{"year": {
+			"old": "[0 TO 1900}",
+			"new": "[1900 TO Infinity]"
+		}}`},
+		wantTotal: 5,
+		wantRanges: map[string]map[string]int64{
+			"year": {"old": 2, "new": 3},
+		},
+	})
+	tests.Add("drilldown", test{
+		index:     "books",
+		query:     "*:*",
+		options:   map[string]any{"sort": "<doc>", "include_fields": []any{}, "drilldown": []any{"genre", "adventure"}},
+		want:      ids("island", "moby"),
+		wantTotal: 2,
+	})
+	tests.Add("english analyzer stems terms", test{
+		index:     "english",
+		query:     "stories",
+		options:   map[string]any{"sort": "<doc>"},
+		want:      ids("dick"),
+		wantTotal: 1,
+	})
+	tests.Add("standard analyzer does not stem terms", test{
+		index: "books",
+		query: "story",
+	})
+	tests.Add("keyword analyzer matches whole values", test{
+		index:     "perfield",
+		query:     `genre:"science fiction"`,
+		options:   map[string]any{"sort": "<doc>"},
+		want:      ids("dick"),
+		wantTotal: 1,
+	})
+	tests.Add("keyword analyzer does not match terms", test{
+		index: "perfield",
+		query: "genre:fiction",
+	})
+	tests.Add("update=false", func(t *testing.T) any {
+		d := newSearchDB(t)
+		d.tPut("whale", map[string]any{"title": "Whale Tales"})
+		return test{
+			db:      d,
+			index:   "books",
+			query:   "whale",
+			options: map[string]any{"update": false},
+		}
+	})
+	tests.Add("deleted and updated docs are reindexed", func(t *testing.T) any {
+		d := newSearchDB(t)
+		rows, err := d.Search(context.Background(), "books", "books", "*:*", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = rows.Close()
+		d.tDelete("island", kivik.Rev("1-"+revSuffix(t, d, "island")))
+		d.tPut("moby", map[string]any{"title": "Moby Dick, or, The Whale", "year": 1851}, kivik.Rev("1-"+revSuffix(t, d, "moby")))
+		return test{
+			db:        d,
+			index:     "books",
+			query:     "whale OR island",
+			options:   map[string]any{"sort": "<doc>"},
+			want:      []rowResult{{ID: "moby", Key: `["moby"]`, Value: `{"title":"Moby Dick, or, The Whale","year":1851}`}},
+			wantTotal: 1,
+		}
+	})
+	tests.Add("index function exception", func(t *testing.T) any {
+		d := newDB(t)
+		d.tPut("_design/books", map[string]any{
+			"indexes": map[string]any{
+				"books": map[string]any{
+					"index": `function(doc) { index("default", doc.title); if (doc.bad) { throw("bad doc"); } }`,
+				},
+			},
+		})
+		d.tPut("a", map[string]any{"title": "thing one"})
+		d.tPut("b", map[string]any{"title": "thing two", "bad": true})
+		return test{
+			db:        d,
+			index:     "books",
+			query:     "thing",
+			options:   map[string]any{"sort": "<doc>"},
+			want:      ids("a"),
+			wantTotal: 1,
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		t.Parallel()
+		db := tt.db
+		if db == nil {
+			db = newSearchDB(t)
+		}
+		rows, err := db.Search(context.Background(), "_design/books", tt.index, tt.query, tt.options)
+		if !testy.ErrorMatches(tt.wantErr, err) {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if status := kivik.HTTPStatus(err); status != tt.wantStatus {
+			t.Errorf("Unexpected status: %d", status)
+		}
+		if err != nil {
+			return
+		}
+		got := readRows(t, rows)
+		for i := range got {
+			// Scores are not deterministic enough to compare, so only
+			// compare keys when sorting by something else.
+			if tt.options["sort"] == nil {
+				got[i].Key = ""
+			}
+			if got[i].Doc != "" {
+				got[i].Doc = stripRevID(t, got[i].Doc)
+			}
+		}
+		if d := cmp.Diff(tt.want, got); d != "" {
+			t.Errorf("Unexpected rows:\n%s", d)
+		}
+		if total := rows.TotalRows(); total != tt.wantTotal {
+			t.Errorf("Unexpected total rows: %d", total)
+		}
+		facets := rows.(driver.SearchFacets)
+		if d := cmp.Diff(tt.wantCounts, facets.Counts()); d != "" {
+			t.Errorf("Unexpected counts:\n%s", d)
+		}
+		if d := cmp.Diff(tt.wantRanges, facets.Ranges()); d != "" {
+			t.Errorf("Unexpected ranges:\n%s", d)
+		}
+	})
+}
+
+// revSuffix returns the rev ID of the current revision of docID.
+func revSuffix(t *testing.T, d *testDB, docID string) string {
+	t.Helper()
+	rev, err := d.GetRev(context.Background(), docID, mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := parseRev(rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.id
+}
+
+// stripRevID removes the rev ID from the _rev field of a JSON document, to
+// allow comparison.
+func stripRevID(t *testing.T, doc string) string {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal([]byte(doc), &m); err != nil {
+		t.Fatal(err)
+	}
+	rev, err := parseRev(m["_rev"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m["_rev"] = strconv.Itoa(rev.rev) + "-"
+	out, _ := json.Marshal(m)
+	return string(out)
+}
+
+func TestDBSearch_bookmark(t *testing.T) {
+	t.Parallel()
+	d := newSearchDB(t)
+
+	var got []string
+	opts := map[string]any{"sort": "<doc>", "limit": 2}
+	for i := 0; i < 4; i++ {
+		rows, err := d.Search(context.Background(), "books", "books", "*:*", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := readRows(t, rows)
+		for _, row := range page {
+			got = append(got, row.ID)
+		}
+		opts["bookmark"] = rows.(driver.Bookmarker).Bookmark()
+		if len(page) == 0 {
+			break
+		}
+	}
+	want := []string{"dick", "island", "moby", "notitle", "sea"}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Unexpected results:\n%s", d)
+	}
+
+	_, err := d.Search(context.Background(), "books", "books", "*:*", map[string]any{"bookmark": "invalid"})
+	if status := kivik.HTTPStatus(err); status != http.StatusBadRequest {
+		t.Errorf("Unexpected status for invalid bookmark: %d", status)
+	}
+}
+
+func TestDBSearch_require(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	_ = d.tPut("_design/books", map[string]any{
+		"views": map[string]any{
+			"lib": map[string]any{
+				"title": `exports.title = function(doc) { return doc.name.toUpperCase(); };`,
+			},
+		},
+		"indexes": map[string]any{
+			"books": map[string]any{
+				"index": `function(doc) { index("title", require('views/lib/title').title(doc), {"store": true}); }`,
+			},
+		},
+	})
+	_ = d.tPut("moby", map[string]any{"name": "Moby Dick"})
+
+	rows, err := d.Search(context.Background(), "books", "books", "title:moby", map[string]any{"sort": "<doc>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rowResult{{ID: "moby", Key: `["moby"]`, Value: `{"title":"MOBY DICK"}`}}
+	if d := cmp.Diff(want, readRows(t, rows)); d != "" {
+		t.Errorf("Unexpected results:\n%s", d)
+	}
+}
+
+func TestDBSearchInfo(t *testing.T) {
+	t.Parallel()
+	d := newSearchDB(t)
+
+	if _, err := d.SearchInfo(context.Background(), "books", "nope"); kivik.HTTPStatus(err) != http.StatusNotFound {
+		t.Errorf("Unexpected error for missing index: %v", err)
+	}
+
+	info, err := d.SearchInfo(context.Background(), "books", "books")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.SearchIndex.CommittedSeq != 0 || info.SearchIndex.DocCount != 0 {
+		t.Errorf("Unexpected info before indexing: %+v", info.SearchIndex)
+	}
+
+	rows, err := d.Search(context.Background(), "books", "books", "*:*", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rows.Close()
+
+	info, err = d.SearchInfo(context.Background(), "books", "books")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "_design/books/books" {
+		t.Errorf("Unexpected name: %s", info.Name)
+	}
+	if info.SearchIndex.DocCount != 5 {
+		t.Errorf("Unexpected doc count: %d", info.SearchIndex.DocCount)
+	}
+	if info.SearchIndex.CommittedSeq != 6 || info.SearchIndex.PendingSeq != 6 {
+		t.Errorf("Unexpected seqs: %+v", info.SearchIndex)
+	}
+	if info.SearchIndex.DiskSize <= 0 {
+		t.Errorf("Unexpected disk size: %d", info.SearchIndex.DiskSize)
+	}
+}
+
+func TestDBSearchAnalyze(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	got, err := d.SearchAnalyze(context.Background(), "Hello, World! It's 2024.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"hello", "world", "it", "s", "2024"}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Unexpected terms:\n%s", d)
+	}
+}
+
+func TestDBSearch_dropTables(t *testing.T) {
+	t.Parallel()
+	d := newSearchDB(t)
+	searchTables := func() int {
+		t.Helper()
+		var count int
+		err := d.underlying().QueryRow(`
+			SELECT COUNT(*) FROM sqlite_schema WHERE name GLOB 'kivik$test$search$*'
+		`).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	for _, index := range []string{"books", "english"} {
+		rows, err := d.Search(context.Background(), "books", index, "*:*", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = rows.Close()
+	}
+	if searchTables() == 0 {
+		t.Fatal("Expected search tables to be created")
+	}
+
+	// Updating the design document drops the tables of the old revision.
+	rev := d.tPut("_design/books", map[string]any{"indexes": map[string]any{}}, kivik.Rev("1-"+revSuffix(t, d, "_design/books")))
+	if n := searchTables(); n != 0 {
+		t.Errorf("Expected search tables to be dropped, found %d", n)
+	}
+
+	d.tPut("_design/books", map[string]any{
+		"indexes": map[string]any{
+			"books": map[string]any{"index": `function(doc) { index("default", doc.title || ""); }`},
+		},
+	}, kivik.Rev(rev))
+	rows, err := d.Search(context.Background(), "books", "books", "*:*", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rows.Close()
+
+	c := &client{db: d.underlying(), logger: d.DB.(*db).logger}
+	if err := c.DestroyDB(context.Background(), "test", mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+	if n := searchTables(); n != 0 {
+		t.Errorf("Expected search tables to be dropped with the database, found %d", n)
+	}
+}
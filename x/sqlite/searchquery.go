@@ -0,0 +1,422 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+)
+
+// defaultSearchField is the field searched by query terms which do not name a
+// field, as in CouchDB.
+const defaultSearchField = "default"
+
+// searchQuery is a parsed Lucene query. It is one of *searchBool,
+// *searchTerm, *searchRange or searchMatchAll.
+type searchQuery interface{}
+
+type occur int
+
+const (
+	occurShould occur = iota
+	occurMust
+	occurMustNot
+)
+
+type searchClause struct {
+	occur occur
+	query searchQuery
+}
+
+// searchBool matches documents according to the Lucene BooleanQuery rules:
+// all MUST clauses must match, no MUST_NOT clause may match, and if there are
+// no MUST clauses, at least one SHOULD clause must match.
+type searchBool struct {
+	clauses []searchClause
+}
+
+// searchTerm matches a single term or phrase in a field.
+type searchTerm struct {
+	field, text string
+	// prefix is true for terms ending with a wildcard. A prefix query with
+	// empty text matches any document with the field.
+	prefix bool
+	phrase bool
+}
+
+// searchRange matches field values within a range. An empty bound is open.
+type searchRange struct {
+	field                string
+	lower, upper         string
+	inclLower, inclUpper bool
+}
+
+// searchMatchAll matches all indexed documents, as *:*.
+type searchMatchAll struct{}
+
+// parseSearchQuery parses the supported subset of the Lucene query syntax:
+// terms, phrases, prefix queries, field names, ranges, grouping and the
+// AND/OR/NOT, &&/||/!, and +/- operators. Boosts and fuzzy or proximity
+// modifiers are accepted but ignored.
+func parseSearchQuery(query string) (searchQuery, error) {
+	p := &searchParser{input: []rune(query)}
+	q, err := p.parseOr(defaultSearchField)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return nil, p.errorf("unexpected '%c'", p.peek())
+	}
+	return q, nil
+}
+
+type searchParser struct {
+	input []rune
+	pos   int
+}
+
+func (p *searchParser) errorf(format string, args ...any) error {
+	return &internal.Error{
+		Status:  http.StatusBadRequest,
+		Message: fmt.Sprintf("cannot parse query at position %d: ", p.pos) + fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *searchParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *searchParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *searchParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+// consume consumes op if it is next in the input. Operators made of letters
+// must be followed by a character that cannot continue a term.
+func (p *searchParser) consume(op string) bool {
+	p.skipSpace()
+	ops := []rune(op)
+	if p.pos+len(ops) > len(p.input) || string(p.input[p.pos:p.pos+len(ops)]) != op {
+		return false
+	}
+	if unicode.IsLetter(ops[0]) {
+		if next := p.pos + len(ops); next < len(p.input) && isTermChar(p.input[next]) {
+			return false
+		}
+	}
+	p.pos += len(ops)
+	return true
+}
+
+func isTermChar(r rune) bool {
+	if unicode.IsSpace(r) {
+		return false
+	}
+	return !strings.ContainsRune(`()[]{}^"~:`, r)
+}
+
+func (p *searchParser) parseOr(field string) (searchQuery, error) {
+	var clauses []searchClause
+	for {
+		p.skipSpace()
+		if p.eof() || p.peek() == ')' {
+			break
+		}
+		if len(clauses) > 0 {
+			_ = p.consume("OR") || p.consume("||")
+		}
+		clause, err := p.parseAnd(field)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+	switch {
+	case len(clauses) == 0:
+		return nil, p.errorf("empty query")
+	case len(clauses) == 1 && clauses[0].occur != occurMustNot:
+		return clauses[0].query, nil
+	}
+	return &searchBool{clauses: clauses}, nil
+}
+
+func (p *searchParser) parseAnd(field string) (searchClause, error) {
+	first, err := p.parseUnary(field)
+	if err != nil {
+		return searchClause{}, err
+	}
+	clauses := []searchClause{first}
+	for p.consume("AND") || p.consume("&&") {
+		clause, err := p.parseUnary(field)
+		if err != nil {
+			return searchClause{}, err
+		}
+		clauses = append(clauses, clause)
+	}
+	if len(clauses) == 1 {
+		return first, nil
+	}
+	for i := range clauses {
+		if clauses[i].occur == occurShould {
+			clauses[i].occur = occurMust
+		}
+	}
+	return searchClause{occur: occurShould, query: &searchBool{clauses: clauses}}, nil
+}
+
+func (p *searchParser) parseUnary(field string) (searchClause, error) {
+	occ := occurShould
+	switch {
+	case p.consume("NOT"), p.consume("!"), p.consume("-"):
+		occ = occurMustNot
+	case p.consume("+"):
+		occ = occurMust
+	}
+	q, err := p.parsePrimary(field)
+	if err != nil {
+		return searchClause{}, err
+	}
+	return searchClause{occur: occ, query: q}, nil
+}
+
+func (p *searchParser) parsePrimary(field string) (searchQuery, error) {
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf("unexpected end of query")
+	}
+	if p.peek() == '(' {
+		return p.parseGroup(field)
+	}
+	if p.peek() == '"' || p.peek() == '[' || p.peek() == '{' {
+		return p.parseValue(field)
+	}
+	start := p.pos
+	text, _, err := p.readTerm()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ':' {
+		p.pos = start
+		return p.parseValue(field)
+	}
+	p.pos++
+	p.skipSpace()
+	if p.peek() == '(' {
+		return p.parseGroup(text)
+	}
+	if text == "*" {
+		valueStart := p.pos
+		if value, _, err := p.readTerm(); err == nil && value == "*" {
+			p.skipModifiers()
+			return searchMatchAll{}, nil
+		}
+		p.pos = valueStart
+	}
+	return p.parseValue(text)
+}
+
+func (p *searchParser) parseGroup(field string) (searchQuery, error) {
+	p.pos++ // (
+	q, err := p.parseOr(field)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.peek() != ')' {
+		return nil, p.errorf("missing ')'")
+	}
+	p.pos++
+	p.skipModifiers()
+	return q, nil
+}
+
+func (p *searchParser) parseValue(field string) (searchQuery, error) {
+	switch p.peek() {
+	case '"':
+		text, err := p.readPhrase()
+		if err != nil {
+			return nil, err
+		}
+		p.skipModifiers()
+		return &searchTerm{field: field, text: text, phrase: true}, nil
+	case '[', '{':
+		return p.parseRange(field)
+	}
+	text, wildcard, err := p.readTerm()
+	if err != nil {
+		return nil, err
+	}
+	p.skipModifiers()
+	term := &searchTerm{field: field, text: text}
+	switch {
+	case wildcard == len(text)-1 && strings.HasSuffix(text, "*"):
+		term.text, term.prefix = text[:len(text)-1], true
+	case wildcard >= 0:
+		return nil, p.errorf("wildcards are only supported at the end of a term")
+	}
+	return term, nil
+}
+
+func (p *searchParser) parseRange(field string) (searchQuery, error) {
+	r := &searchRange{field: field, inclLower: p.peek() == '['}
+	p.pos++
+	var err error
+	if r.lower, err = p.readRangeBound(); err != nil {
+		return nil, err
+	}
+	if !p.consume("TO") {
+		return nil, p.errorf("expected TO in range")
+	}
+	if r.upper, err = p.readRangeBound(); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	switch p.peek() {
+	case ']':
+		r.inclUpper = true
+	case '}':
+	default:
+		return nil, p.errorf("missing end of range")
+	}
+	p.pos++
+	p.skipModifiers()
+	return r, nil
+}
+
+// readRangeBound reads one bound of a range. A bound of * is open, and is
+// returned as an empty string.
+func (p *searchParser) readRangeBound() (string, error) {
+	p.skipSpace()
+	if p.peek() == '"' {
+		return p.readPhrase()
+	}
+	start := p.pos
+	for !p.eof() && isTermChar(p.peek()) {
+		if p.peek() == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	bound := unescapeSearchTerm(string(p.input[start:min(p.pos, len(p.input))]))
+	if bound == "" {
+		return "", p.errorf("missing range bound")
+	}
+	if bound == "*" {
+		return "", nil
+	}
+	return bound, nil
+}
+
+// readTerm reads a bare term, and returns it with escapes removed, along with
+// the position in the result of the first unescaped wildcard, or -1.
+func (p *searchParser) readTerm() (string, int, error) {
+	var (
+		buf      strings.Builder
+		wildcard = -1
+	)
+	for !p.eof() && isTermChar(p.peek()) {
+		r := p.peek()
+		p.pos++
+		switch r {
+		case '\\':
+			if p.eof() {
+				return "", -1, p.errorf("incomplete escape sequence")
+			}
+			r = p.peek()
+			p.pos++
+		case '*', '?':
+			if wildcard < 0 {
+				wildcard = buf.Len()
+			}
+		}
+		buf.WriteRune(r)
+	}
+	if buf.Len() == 0 {
+		if p.eof() {
+			return "", -1, p.errorf("unexpected end of query")
+		}
+		return "", -1, p.errorf("unexpected '%c'", p.peek())
+	}
+	return buf.String(), wildcard, nil
+}
+
+func (p *searchParser) readPhrase() (string, error) {
+	p.pos++ // opening quote
+	var buf strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated phrase")
+		}
+		r := p.peek()
+		p.pos++
+		switch r {
+		case '"':
+			return buf.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated phrase")
+			}
+			r = p.peek()
+			p.pos++
+		}
+		buf.WriteRune(r)
+	}
+}
+
+// skipModifiers skips any boost (^2) or fuzzy/proximity (~, ~2) modifiers,
+// which are not supported.
+func (p *searchParser) skipModifiers() {
+	for !p.eof() && (p.peek() == '^' || p.peek() == '~') {
+		p.pos++
+		for !p.eof() && (unicode.IsDigit(p.peek()) || p.peek() == '.') {
+			p.pos++
+		}
+	}
+}
+
+func unescapeSearchTerm(term string) string {
+	if !strings.Contains(term, `\`) {
+		return term
+	}
+	var buf strings.Builder
+	escaped := false
+	for _, r := range term {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// searchNumber returns the numeric value of a query term, if it is a number.
+func searchNumber(text string) (float64, bool) {
+	f, err := strconv.ParseFloat(text, 64)
+	return f, err == nil
+}
@@ -0,0 +1,184 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package sqlite
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4"
+)
+
+func Test_parseSearchQuery(t *testing.T) {
+	t.Parallel()
+	type test struct {
+		query      string
+		want       searchQuery
+		wantErr    string
+		wantStatus int
+	}
+
+	tests := testy.NewTable()
+	tests.Add("single term", test{
+		query: "foo",
+		want:  &searchTerm{field: "default", text: "foo"},
+	})
+	tests.Add("field term", test{
+		query: "title:foo",
+		want:  &searchTerm{field: "title", text: "foo"},
+	})
+	tests.Add("phrase", test{
+		query: `title:"foo bar"`,
+		want:  &searchTerm{field: "title", text: "foo bar", phrase: true},
+	})
+	tests.Add("prefix", test{
+		query: "fo*",
+		want:  &searchTerm{field: "default", text: "fo", prefix: true},
+	})
+	tests.Add("field exists", test{
+		query: "title:*",
+		want:  &searchTerm{field: "title", prefix: true},
+	})
+	tests.Add("match all", test{
+		query: "*:*",
+		want:  searchMatchAll{},
+	})
+	tests.Add("escaped characters", test{
+		query: `title:foo\:bar\*`,
+		want:  &searchTerm{field: "title", text: "foo:bar*"},
+	})
+	tests.Add("hyphenated term", test{
+		query: "foo-bar",
+		want:  &searchTerm{field: "default", text: "foo-bar"},
+	})
+	tests.Add("implicit or", test{
+		query: "foo bar",
+		want: &searchBool{clauses: []searchClause{
+			{occur: occurShould, query: &searchTerm{field: "default", text: "foo"}},
+			{occur: occurShould, query: &searchTerm{field: "default", text: "bar"}},
+		}},
+	})
+	tests.Add("and binds tighter than or", test{
+		query: "a AND b OR c",
+		want: &searchBool{clauses: []searchClause{
+			{occur: occurShould, query: &searchBool{clauses: []searchClause{
+				{occur: occurMust, query: &searchTerm{field: "default", text: "a"}},
+				{occur: occurMust, query: &searchTerm{field: "default", text: "b"}},
+			}}},
+			{occur: occurShould, query: &searchTerm{field: "default", text: "c"}},
+		}},
+	})
+	tests.Add("symbolic operators", test{
+		query: "a && !b || c",
+		want: &searchBool{clauses: []searchClause{
+			{occur: occurShould, query: &searchBool{clauses: []searchClause{
+				{occur: occurMust, query: &searchTerm{field: "default", text: "a"}},
+				{occur: occurMustNot, query: &searchTerm{field: "default", text: "b"}},
+			}}},
+			{occur: occurShould, query: &searchTerm{field: "default", text: "c"}},
+		}},
+	})
+	tests.Add("required and prohibited", test{
+		query: "+a -b c",
+		want: &searchBool{clauses: []searchClause{
+			{occur: occurMust, query: &searchTerm{field: "default", text: "a"}},
+			{occur: occurMustNot, query: &searchTerm{field: "default", text: "b"}},
+			{occur: occurShould, query: &searchTerm{field: "default", text: "c"}},
+		}},
+	})
+	tests.Add("single negation", test{
+		query: "NOT a",
+		want: &searchBool{clauses: []searchClause{
+			{occur: occurMustNot, query: &searchTerm{field: "default", text: "a"}},
+		}},
+	})
+	tests.Add("operator words as term prefixes", test{
+		query: "ANDROID NOTE",
+		want: &searchBool{clauses: []searchClause{
+			{occur: occurShould, query: &searchTerm{field: "default", text: "ANDROID"}},
+			{occur: occurShould, query: &searchTerm{field: "default", text: "NOTE"}},
+		}},
+	})
+	tests.Add("field group", test{
+		query: "title:(foo OR bar)",
+		want: &searchBool{clauses: []searchClause{
+			{occur: occurShould, query: &searchTerm{field: "title", text: "foo"}},
+			{occur: occurShould, query: &searchTerm{field: "title", text: "bar"}},
+		}},
+	})
+	tests.Add("inclusive range", test{
+		query: "year:[1800 TO 1900]",
+		want:  &searchRange{field: "year", lower: "1800", upper: "1900", inclLower: true, inclUpper: true},
+	})
+	tests.Add("exclusive open range", test{
+		query: "year:{1800 TO *}",
+		want:  &searchRange{field: "year", lower: "1800"},
+	})
+	tests.Add("boost and fuzzy ignored", test{
+		query: `foo^2 bar~ "a b"~3`,
+		want: &searchBool{clauses: []searchClause{
+			{occur: occurShould, query: &searchTerm{field: "default", text: "foo"}},
+			{occur: occurShould, query: &searchTerm{field: "default", text: "bar"}},
+			{occur: occurShould, query: &searchTerm{field: "default", text: "a b", phrase: true}},
+		}},
+	})
+	tests.Add("empty query", test{
+		query:      "  ",
+		wantErr:    "cannot parse query at position 2: empty query",
+		wantStatus: http.StatusBadRequest,
+	})
+	tests.Add("unbalanced parens", test{
+		query:      "(foo",
+		wantErr:    "cannot parse query at position 4: missing ')'",
+		wantStatus: http.StatusBadRequest,
+	})
+	tests.Add("unexpected close paren", test{
+		query:      "foo)",
+		wantErr:    "cannot parse query at position 3: unexpected ')'",
+		wantStatus: http.StatusBadRequest,
+	})
+	tests.Add("unterminated phrase", test{
+		query:      `"foo`,
+		wantErr:    "cannot parse query at position 4: unterminated phrase",
+		wantStatus: http.StatusBadRequest,
+	})
+	tests.Add("range without TO", test{
+		query:      "year:[1 2]",
+		wantErr:    "cannot parse query at position 8: expected TO in range",
+		wantStatus: http.StatusBadRequest,
+	})
+	tests.Add("inner wildcard", test{
+		query:      "f?o",
+		wantErr:    "cannot parse query at position 3: wildcards are only supported at the end of a term",
+		wantStatus: http.StatusBadRequest,
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		t.Parallel()
+		got, err := parseSearchQuery(tt.query)
+		if !testy.ErrorMatches(tt.wantErr, err) {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if status := kivik.HTTPStatus(err); err != nil && status != tt.wantStatus {
+			t.Errorf("Unexpected status: %d", status)
+		}
+		if d := cmp.Diff(tt.want, got, cmp.AllowUnexported(searchBool{}, searchClause{}, searchTerm{}, searchRange{})); d != "" {
+			t.Errorf("Unexpected result:\n%s", d)
+		}
+	})
+}
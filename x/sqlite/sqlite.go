@@ -19,6 +19,8 @@ import (
 	"log"
 	"net/http"
 	"regexp"
+	"runtime"
+	"sync"
 	"time"
 
 	"modernc.org/sqlite"
@@ -28,6 +30,7 @@ import (
 	internal "github.com/go-kivik/kivik/v4/int/errors"
 	"github.com/go-kivik/kivik/v4/x/collate"
 	"github.com/go-kivik/kivik/x/sqlite/v4/js"
+	"github.com/go-kivik/kivik/x/sqlite/v4/queryserver"
 )
 
 func init() {
@@ -56,6 +59,12 @@ var _ driver.Driver = (*drv)(nil)
 // NewClient returns a new SQLite client. dsn should be the full path to your
 // SQLite database file.
 func (drv) NewClient(dsn string, options driver.Options) (driver.Client, error) {
+	var shared *client
+	options.Apply(&shared)
+	if shared != nil {
+		return shared, nil
+	}
+
 	cn := &connector{dsn: dsn}
 	options.Apply(cn)
 	db, err := cn.Connect()
@@ -64,12 +73,21 @@ func (drv) NewClient(dsn string, options driver.Options) (driver.Client, error)
 	}
 
 	c := &client{
-		dsn:    dsn,
-		db:     db,
-		logger: log.Default(),
-		js:     js.New(defaultJSTimeout),
+		dsn:        dsn,
+		db:         db,
+		logger:     log.Default(),
+		jsPoolSize: runtime.GOMAXPROCS(0),
+		goFuncs:    newGoFuncs(),
+
+		reduceFailures: newReduceFailures(),
 	}
 	options.Apply(c)
+	c.js = js.NewPool(defaultJSTimeout, c.jsPoolSize)
+	c.replicator = newScheduler(c)
+	if err := c.resumeReplications(context.Background()); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 
 	return c, nil
 }
@@ -79,15 +97,87 @@ type client struct {
 	db     *sql.DB
 	logger *log.Logger
 	js     *js.Runtime
+	// jsPoolSize is the number of JavaScript VMs used to map documents
+	// concurrently when updating a view index.
+	jsPoolSize int
+	// queryServers holds the external query servers registered with
+	// [OptionQueryServer], by language.
+	queryServers map[string]*queryserver.Pool
+	// goFuncs holds the Go design functions registered with
+	// [OptionGoMapFunc] and similar options.
+	goFuncs *goFuncs
+	// compressibleTypes is set by [OptionCompressibleTypes].
+	compressibleTypes []string
+	// reduceFailures counts failed reduce cache updates, by view.
+	reduceFailures *reduceFailures
+	// replicatorInterval is set by [OptionReplicatorInterval].
+	replicatorInterval time.Duration
+	// replicatorMu protects replicator and closed.
+	replicatorMu sync.Mutex
+	// replicator runs the replications in the _replicator database. It is
+	// replaced when the database is restored.
+	replicator *scheduler
+	// closed is set by Close, after which replications are not restarted.
+	closed bool
+}
+
+type optionJSPoolSize int
+
+var _ kivik.Option = optionJSPoolSize(0)
+
+func (o optionJSPoolSize) Apply(target any) {
+	if client, ok := target.(*client); ok {
+		client.jsPoolSize = int(o)
+	}
+}
+
+// OptionJSPoolSize is an option to set the number of JavaScript VMs used to
+// map documents concurrently when updating a view index. Documents are still
+// written to the index in sequence order. The default is
+// [runtime.GOMAXPROCS]. A size less than 1 is treated as 1.
+func OptionJSPoolSize(size int) kivik.Option {
+	return optionJSPoolSize(size)
 }
 
 var (
-	_ driver.Client       = (*client)(nil)
-	_ driver.ClientCloser = (*client)(nil)
+	_ driver.Client           = (*client)(nil)
+	_ driver.ClientCloser     = (*client)(nil)
+	_ driver.ClientReplicator = (*client)(nil)
 )
 
-// Close closes the underlying sql.DB connection.
+// currentScheduler returns the replication scheduler.
+func (c *client) currentScheduler() *scheduler {
+	c.replicatorMu.Lock()
+	defer c.replicatorMu.Unlock()
+	return c.replicator
+}
+
+// resumeReplications starts the replication scheduler if the _replicator
+// database exists. Otherwise it is started when the database is created, so
+// that clients which never replicate don't poll for replications. The caller
+// must hold c.replicatorMu, unless the client is not yet in use.
+func (c *client) resumeReplications(ctx context.Context) error {
+	exists, err := c.DBExists(ctx, replicatorDB, nil)
+	if err != nil {
+		return err
+	}
+	if exists {
+		c.replicator.start()
+	}
+	return nil
+}
+
+// Close stops any running replications, and closes the underlying sql.DB
+// connection.
 func (c *client) Close() error {
+	c.replicatorMu.Lock()
+	c.closed = true
+	s := c.replicator
+	c.replicatorMu.Unlock()
+	s.stop()
+	for _, pool := range c.queryServers {
+		_ = pool.Close()
+	}
 	return c.db.Close()
 }
 
@@ -96,7 +186,7 @@ const (
 	vendor  = "Kivik"
 )
 
-func (client) Version(context.Context) (*driver.Version, error) {
+func (*client) Version(context.Context) (*driver.Version, error) {
 	return &driver.Version{
 		Version: version,
 		Vendor:  vendor,
@@ -135,7 +225,11 @@ func (c *client) DestroyDB(ctx context.Context, name string, _ driver.Options) e
 		}
 		return err
 	}
-	for _, query := range mapDropQueries {
+	searchDropQueries, err := d.searchDropQueries(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, query := range append(mapDropQueries, searchDropQueries...) {
 		if _, err := tx.ExecContext(ctx, query); err != nil {
 			return err
 		}
@@ -164,37 +258,19 @@ func (c *client) DB(name string, _ driver.Options) (driver.DB, error) {
 	return c.newDB(name), nil
 }
 
-// viewMapDropQueries returns DROP TABLE queries for all view map tables in the
-// database. The rows cursor is closed before returning so that callers can
-// safely DROP the Design table afterward.
+// viewMapDropQueries returns DROP TABLE queries for all view map and reduce
+// cache tables in the database.
 func (d *db) viewMapDropQueries(ctx context.Context, tx *sql.Tx) ([]string, error) {
-	rows, err := tx.QueryContext(ctx, d.query(`
-		SELECT
-			id,
-			rev,
-			rev_id,
-			func_name
-		FROM {{ .Design }}
-		WHERE func_type = 'map'
-	`))
+	tables, err := d.viewMapTables(ctx, tx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var queries []string
-	for rows.Next() {
-		var (
-			id, view string
-			rev      revision
+	queries := make([]string, 0, 2*len(tables))
+	for _, table := range tables {
+		queries = append(queries,
+			d.ddocQuery(table.ddoc, table.view, table.rev, `DROP TABLE {{ .Map }}`),
+			d.ddocQuery(table.ddoc, table.view, table.rev, `DROP TABLE IF EXISTS {{ .Reduce }}`),
 		)
-		if err := rows.Scan(&id, &rev.rev, &rev.id, &view); err != nil {
-			return nil, err
-		}
-		queries = append(queries, d.ddocQuery(id, view, rev.String(), `DROP TABLE {{ .Map }}`))
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
 	}
 	return queries, nil
 }
@@ -15,10 +15,17 @@
 package sqlite
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -38,6 +45,194 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
+func TestNewClient_schemaVersion(t *testing.T) {
+	t.Parallel()
+	dsn := filepath.Join(t.TempDir(), "old.db")
+	c, err := drv{}.NewClient(dsn, mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateDB(context.Background(), "test", mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+	// A file which is not versioned, but doesn't have the schema used before
+	// the schema was versioned, can't be migrated.
+	if _, err := c.(*client).db.Exec("PRAGMA user_version = 0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.(*client).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = drv{}.NewClient(dsn, mock.NilOption)
+	if !errors.Is(err, errSchemaVersion) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestNewClient_migrateBaselineSchema(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "baseline.db")
+
+	// Write a file with the schema used before the schema was versioned,
+	// when attachment content was stored in the .Attachments table.
+	raw, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large := bytes.Repeat([]byte("0123456789abcdef"), attachmentChunkSize/8+1)
+	small := []byte("Hello, world!")
+	d := &db{name: "test"}
+	// The tables other than the attachment tables are unchanged.
+	baseline := append(slices.Clone(docSchema),
+		`CREATE TABLE {{ .Attachments }} (
+			pk INTEGER PRIMARY KEY,
+			filename TEXT NOT NULL,
+			content_type TEXT NOT NULL,
+			length INTEGER NOT NULL,
+			digest BLOB NOT NULL,
+			data BLOB NOT NULL,
+			rev_pos INTEGER NOT NULL
+		)`,
+		`CREATE TABLE {{ .AttachmentsBridge }} (
+			pk INTEGER,
+			id TEXT NOT NULL,
+			rev INTEGER NOT NULL,
+			rev_id TEXT NOT NULL,
+			FOREIGN KEY (pk) REFERENCES {{ .Attachments }} (pk),
+			FOREIGN KEY (id, rev, rev_id) REFERENCES {{ .Docs }} (id, rev, rev_id) ON DELETE CASCADE,
+			UNIQUE (id, rev, rev_id, pk)
+		)`,
+		`INSERT INTO {{ .Revs }} (id, rev, rev_id) VALUES ('foo', 1, 'abc'), ('bar', 1, 'def')`,
+		`INSERT INTO {{ .Docs }} (id, rev, rev_id, doc, md5sum) VALUES
+			('foo', 1, 'abc', '{}', X'00000000000000000000000000000000'),
+			('bar', 1, 'def', '{}', X'00000000000000000000000000000000')`,
+	)
+	for _, query := range baseline {
+		if _, err := raw.Exec(d.query(query)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, att := range []struct {
+		docID, revID, filename string
+		content                []byte
+	}{
+		{"foo", "abc", "hello.txt", small},
+		{"foo", "abc", "large.bin", large},
+		{"bar", "def", "hello.txt", small},
+	} {
+		digest := md5.Sum(att.content)
+		if _, err := raw.Exec(d.query(`
+			INSERT INTO {{ .Attachments }} (pk, filename, content_type, length, digest, data, rev_pos)
+			VALUES ($1, $2, 'application/octet-stream', $3, $4, $5, 1)
+		`), i+1, att.filename, len(att.content), digest[:], att.content); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := raw.Exec(d.query(`
+			INSERT INTO {{ .AttachmentsBridge }} (pk, id, rev, rev_id)
+			VALUES ($1, $2, 1, $3)
+		`), i+1, att.docID, att.revID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := raw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := drv{}.NewClient(dsn, mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = c.(*client).Close()
+	})
+	var version int
+	if err := c.(*client).db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != schemaVersion {
+		t.Errorf("Unexpected schema version: %d", version)
+	}
+
+	testDB, err := c.DB("test", mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		docID, filename string
+		want            []byte
+	}{
+		{"foo", "hello.txt", small},
+		{"foo", "large.bin", large},
+		{"bar", "hello.txt", small},
+	} {
+		att, err := testDB.GetAttachment(ctx, tt.docID, tt.filename, mock.NilOption)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(att.Content)
+		_ = att.Content.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("Unexpected content of %s/%s: %d bytes", tt.docID, tt.filename, len(got))
+		}
+	}
+
+	// Identical content is stored once, and its references counted.
+	var refs []int
+	rows, err := c.(*client).db.Query(d.query(`
+		SELECT refs FROM {{ .AttachmentData }} ORDER BY refs
+	`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		refs = append(refs, n)
+	}
+	if d := cmp.Diff([]int{1, 2}, refs); d != "" {
+		t.Errorf("Unexpected content references:\n%s", d)
+	}
+	var chunks int
+	if err := c.(*client).db.QueryRow(d.query(`
+		SELECT COUNT(*) FROM {{ .AttachmentChunks }}
+	`)).Scan(&chunks); err != nil {
+		t.Fatal(err)
+	}
+	if want := 1 + (len(large)+attachmentChunkSize-1)/attachmentChunkSize; chunks != want {
+		t.Errorf("Expected %d chunks, got %d", want, chunks)
+	}
+}
+
+func TestNewClient_foreignKeys(t *testing.T) {
+	t.Parallel()
+	c := testClient(t).(*client)
+	ctx := context.Background()
+
+	// Each pooled connection enforces foreign keys.
+	for i := 0; i < 3; i++ {
+		conn, err := c.db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		var enabled bool
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enabled); err != nil {
+			t.Fatal(err)
+		}
+		if !enabled {
+			t.Errorf("Foreign keys not enabled on connection %d", i)
+		}
+	}
+}
+
 func TestClientVersion(t *testing.T) {
 	c := client{}
 
@@ -35,6 +35,7 @@ type tmplFuncs struct {
 	ddoc, viewName, rev string
 	hash                string
 	collation           *string
+	tokenizer           string
 }
 
 const tablePrefix = "kivik$"
@@ -51,12 +52,41 @@ func (t *tmplFuncs) Docs() string              { return t.tableName("") }
 func (t *tmplFuncs) Revs() string              { return t.tableName("$revs") }
 func (t *tmplFuncs) Attachments() string       { return t.tableName("$attachments") }
 func (t *tmplFuncs) AttachmentsBridge() string { return t.tableName("$attachments_bridge") }
+func (t *tmplFuncs) AttachmentData() string    { return t.tableName("$attachment_data") }
+func (t *tmplFuncs) AttachmentChunks() string  { return t.tableName("$attachment_chunks") }
 func (t *tmplFuncs) Design() string            { return t.tableName("$design") }
 func (t *tmplFuncs) MangoIndexes() string      { return t.tableName("$mango_indexes") }
 func (t *tmplFuncs) Metadata() string          { return t.tableName("$metadata") }
 func (t *tmplFuncs) IndexKey() string          { return t.indexName("$key") }
 func (t *tmplFuncs) IndexParent() string       { return t.indexName("$parent") }
 
+func (t *tmplFuncs) IndexAttachmentsBridgePK() string {
+	return t.indexName("$attachments_bridge$pk")
+}
+
+func (t *tmplFuncs) TriggerAttachmentsBridgeInsert() string {
+	return strconv.Quote("trg_" + tablePrefix + t.db.name + "$attachments_bridge$insert")
+}
+
+func (t *tmplFuncs) TriggerAttachmentDataDelete() string {
+	return strconv.Quote("trg_" + tablePrefix + t.db.name + "$attachment_data$delete")
+}
+
+func (t *tmplFuncs) TriggerAttachmentsBridgeDelete() string {
+	return strconv.Quote("trg_" + tablePrefix + t.db.name + "$attachments_bridge$delete")
+}
+
+// AttachmentContent returns a subquery which selects the decoded content of
+// the attachment aliased as att.
+func (t *tmplFuncs) AttachmentContent() string {
+	return `(
+		SELECT kivik_attachment_content(chunk.chunk, data.encoding ORDER BY chunk.seq)
+		FROM ` + t.AttachmentData() + ` AS data
+		JOIN ` + t.AttachmentChunks() + ` AS chunk ON chunk.data_pk = data.pk
+		WHERE data.digest = att.digest
+	)`
+}
+
 const maxTableLen = 59 // 64 minus the `idx_` prefix, and one more `_` separator
 
 // hashedName returns a table name in the format "{{db name}}_{{ddoc}}_{{typ}}_{{hash}}"
@@ -64,6 +94,26 @@ const maxTableLen = 59 // 64 minus the `idx_` prefix, and one more `_` separator
 // If the final version is longer than 64 characters, it is truncated to size,
 // before appending the hash.
 func (t *tmplFuncs) hashedName(typ string) string {
+	name := t.baseName() + "_" + typ
+	if len(name) > maxTableLen-len(t.hash) {
+		name = name[:maxTableLen-len(t.hash)]
+	}
+	return name + "_" + t.hash
+}
+
+// suffixedName works like hashedName, but truncates the ddoc, rev and view
+// name rather than typ, so that names of different types never collide.
+func (t *tmplFuncs) suffixedName(typ string) string {
+	name := t.baseName()
+	if maxLen := maxTableLen - len(t.hash) - len(typ) - 1; len(name) > maxLen {
+		name = name[:maxLen]
+	}
+	return name + "_" + typ + "_" + t.hash
+}
+
+// baseName returns the ddoc, rev and view name joined with underscores, and
+// calculates the hash used by hashedName and suffixedName.
+func (t *tmplFuncs) baseName() string {
 	if t.ddoc == "" {
 		panic("ddoc template method called outside of a ddoc template")
 	}
@@ -71,11 +121,7 @@ func (t *tmplFuncs) hashedName(typ string) string {
 	if t.hash == "" {
 		t.hash = md5sumString(name)[:8]
 	}
-	name += "_" + typ
-	if len(name) > maxTableLen-len(t.hash) {
-		name = name[:maxTableLen-len(t.hash)]
-	}
-	return name + "_" + t.hash
+	return name
 }
 
 func (t *tmplFuncs) Map() string {
@@ -86,6 +132,57 @@ func (t *tmplFuncs) IndexMap() string {
 	return strconv.Quote("idx_" + t.hashedName("map"))
 }
 
+func (t *tmplFuncs) Reduce() string {
+	return strconv.Quote(tablePrefix + t.suffixedName("reduce"))
+}
+
+func (t *tmplFuncs) IndexReduce() string {
+	return strconv.Quote("idx_" + t.suffixedName("reduce"))
+}
+
+func (t *tmplFuncs) IndexMapReduceNode() string {
+	return strconv.Quote("idx_" + t.suffixedName("map_reduce_node"))
+}
+
+func (t *tmplFuncs) TriggerReduceDelete() string {
+	return strconv.Quote("trg_" + t.suffixedName("reduce_delete"))
+}
+
+func (t *tmplFuncs) TriggerMapInsert() string {
+	return strconv.Quote("trg_" + t.suffixedName("map_insert"))
+}
+
+func (t *tmplFuncs) TriggerMapDelete() string {
+	return strconv.Quote("trg_" + t.suffixedName("map_delete"))
+}
+
+// searchName returns a search index table name, in the format
+// "kivik${{db name}}$search${{hash}}{{suffix}}", where hash identifies the
+// ddoc, rev and index name.
+func (t *tmplFuncs) searchName(suffix string) string {
+	_ = t.baseName()
+	return "$search$" + t.hash + suffix
+}
+
+func (t *tmplFuncs) SearchFields() string     { return t.tableName(t.searchName("")) }
+func (t *tmplFuncs) SearchFTS() string        { return t.tableName(t.searchName("$fts")) }
+func (t *tmplFuncs) IndexSearchID() string    { return t.indexName(t.searchName("$id")) }
+func (t *tmplFuncs) IndexSearchField() string { return t.indexName(t.searchName("$field")) }
+
+func (t *tmplFuncs) TriggerSearchInsert() string {
+	return strconv.Quote("trg_" + tablePrefix + t.db.name + t.searchName("$insert"))
+}
+
+func (t *tmplFuncs) TriggerSearchDelete() string {
+	return strconv.Quote("trg_" + tablePrefix + t.db.name + t.searchName("$delete"))
+}
+
+// Tokenizer returns the FTS5 tokenize option of a search index, as an SQL
+// string literal.
+func (t *tmplFuncs) Tokenizer() string {
+	return "'" + strings.ReplaceAll(t.tokenizer, "'", "''") + "'"
+}
+
 func (t *tmplFuncs) Collation() string {
 	if t.collation == nil {
 		return "COUCHDB_UCI"
@@ -115,6 +212,9 @@ func executeTmpl(format string, data *tmplFuncs) string {
 //	{{ .Revs }}              -> "kivik$" + db.name + "$revs"
 //	{{ .Attachments }}       -> "kivik$" + db.name + "$attachments"
 //	{{ .AttachmentsBridge }} -> "kivik$" + db.name + "$attachments_bridge"
+//	{{ .AttachmentData }}    -> "kivik$" + db.name + "$attachment_data"
+//	{{ .AttachmentChunks }}  -> "kivik$" + db.name + "$attachment_chunks"
+//	{{ .AttachmentContent }} -> a subquery selecting the content of att
 //	{{ .Design }}            -> "kivik$" + db.name + "$design"
 func (d *db) query(format string) string {
 	return executeTmpl(format, &tmplFuncs{db: d})
@@ -123,8 +223,16 @@ func (d *db) query(format string) string {
 // ddocQuery works just like [db.query], but also enables access to the
 // following translations:
 //
-//	{{ .Map }}      -> the view map table name
-//	{{ .IndexMap }} -> the view map index name
+//	{{ .Map }}                 -> the view map table name
+//	{{ .IndexMap }}            -> the view map index name
+//	{{ .Reduce }}              -> the view reduce cache table name
+//	{{ .IndexReduce }}         -> the view reduce cache index name
+//	{{ .IndexMapReduceNode }}  -> the view map reduce_node index name
+//	{{ .TriggerReduceDelete }} -> the view reduce cache delete trigger name
+//	{{ .TriggerMapInsert }}    -> the view map insert trigger name
+//	{{ .TriggerMapDelete }}    -> the view map delete trigger name
+//	{{ .SearchFields }}        -> the search index fields table name
+//	{{ .SearchFTS }}           -> the search index full-text table name
 func (d *db) ddocQuery(docID, viewOrFuncName, rev, format string) string {
 	return executeTmpl(format, &tmplFuncs{
 		db:       d,
@@ -148,6 +256,20 @@ func (d *db) createDdocQuery(docID, viewOrFuncName, rev, format string, collatio
 	})
 }
 
+// createSearchQuery works just like [db.ddocQuery], but also enables access
+// to the following translations:
+//
+//	{{ .Tokenizer }} -> the search index's FTS5 tokenizer
+func (d *db) createSearchQuery(docID, index, rev, format, tokenizer string) string {
+	return executeTmpl(format, &tmplFuncs{
+		db:        d,
+		ddoc:      strings.TrimPrefix(docID, "_design/"),
+		viewName:  index,
+		rev:       rev,
+		tokenizer: tokenizer,
+	})
+}
+
 func getTmpl(format string) *template.Template {
 	mu.Lock()
 	defer mu.Unlock()
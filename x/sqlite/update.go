@@ -20,23 +20,121 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/google/uuid"
-
 	"github.com/go-kivik/kivik/v4/driver"
 	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/x/sqlite/v4/js"
 )
 
+var _ driver.UpdateResponder = (*db)(nil)
+
 // Update calls the named update function with the provided document.
 func (d *db) Update(ctx context.Context, ddoc, funcName, docID string, doc any, opts driver.Options) (string, error) {
-	var funcBody string
-	err := d.db.QueryRowContext(ctx, d.query(`
-		SELECT design.func_body
+	funcBody, rt, qs, err := d.updateFuncBody(ctx, ddoc, funcName)
+	if err != nil {
+		return "", err
+	}
+
+	var updateFunc js.UpdateFunc
+	if qs != nil {
+		updateFunc = qs.Update(funcName)
+	} else {
+		updateFunc, err = rt.Update(funcBody)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	existingDoc, req, err := d.updateArgs(ctx, ddoc, funcName, docID, updateCall(doc), opts)
+	if err != nil {
+		return "", err
+	}
+	updatedDoc, _, err := updateFunc(ctx, existingDoc, req)
+	if err != nil {
+		return "", err
+	}
+
+	if updatedDoc == nil {
+		return "", nil
+	}
+
+	return d.Put(ctx, docID, updatedDoc, opts)
+}
+
+// UpdateWithResponse calls the named update function with the provided
+// document, and returns the function's response.
+func (d *db) UpdateWithResponse(ctx context.Context, ddoc, funcName, docID string, doc any, opts driver.Options) (*driver.UpdateResponse, error) {
+	return d.updateWithResponse(ctx, ddoc, funcName, docID, updateCall(doc), opts)
+}
+
+func (d *db) updateWithResponse(ctx context.Context, ddoc, funcName, docID string, call designCall, opts driver.Options) (*driver.UpdateResponse, error) {
+	ddocID := "_design/" + ddoc
+	funcBody, rt, qs, err := d.updateFuncBody(ctx, ddocID, funcName)
+	if err != nil {
+		return nil, err
+	}
+
+	var updateFunc js.UpdateResponseFunc
+	if qs != nil {
+		updateFunc = qs.UpdateResponse(funcName)
+	} else {
+		updateFunc, err = rt.UpdateResponse(funcBody)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	existingDoc, req, err := d.updateArgs(ctx, ddocID, funcName, docID, call, opts)
+	if err != nil {
+		return nil, err
+	}
+	updatedDoc, resp, err := updateFunc(ctx, existingDoc, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var rev string
+	if updatedDoc != nil {
+		rev, err = d.Put(ctx, docID, updatedDoc, opts)
+		if err != nil {
+			return nil, err
+		}
+		resp.Headers["X-Couch-Id"] = docID
+		resp.Headers["X-Couch-Update-NewRev"] = rev
+	}
+	// As with CouchDB, a saved document implies 201 Created, unless the
+	// function set a different status code.
+	if updatedDoc != nil && resp.Code == http.StatusOK {
+		resp.Code = http.StatusCreated
+	}
+	result := designResponse(resp)
+	return &driver.UpdateResponse{
+		Rev:         rev,
+		StatusCode:  result.StatusCode,
+		ContentType: result.ContentType,
+		Header:      result.Header,
+		Body:        result.Body,
+	}, nil
+}
+
+// updateFuncBody returns the body of the named update function, and a
+// runtime in which to compile it, with access to the design document. If the
+// design document is written in a language other than JavaScript, the
+// function is instead run by a query server, and qs is returned.
+func (d *db) updateFuncBody(ctx context.Context, ddoc, funcName string) (funcBody string, rt *js.Runtime, qs *qsDDoc, err error) {
+	var (
+		language string
+		rev      revision
+		ddocBody []byte
+	)
+	err = d.db.QueryRowContext(ctx, d.query(`
+		SELECT design.rev, design.rev_id, design.language, design.func_body, leaves.doc
 		FROM {{ .Design }} AS design
 		JOIN (
 			SELECT
 				rev.id,
 				rev.rev,
-				rev.rev_id
+				rev.rev_id,
+				doc.doc
 			FROM {{ .Revs }} AS rev
 			LEFT JOIN {{ .Revs }} AS child
 				ON child.id = rev.id
@@ -52,22 +150,37 @@ func (d *db) Update(ctx context.Context, ddoc, funcName, docID string, doc any,
 		WHERE design.func_type = 'update'
 			AND design.id = $1
 			AND design.func_name = $2
-	`), ddoc, funcName).Scan(&funcBody)
+	`), ddoc, funcName).Scan(&rev.rev, &rev.id, &language, &funcBody, &ddocBody)
 	if errors.Is(err, sql.ErrNoRows) {
-		return "", &internal.Error{Status: http.StatusNotFound, Message: "missing update function " + funcName + " on " + ddoc}
+		return "", nil, nil, &internal.Error{Status: http.StatusNotFound, Message: "missing update function " + funcName + " on " + ddoc}
 	}
 	if err != nil {
-		return "", err
+		return "", nil, nil, err
 	}
-
-	updateFunc, err := d.js.Update(funcBody)
+	if language == GoLanguage {
+		return "", nil, nil, errUnsupportedLanguage(language, "update")
+	}
+	pool, err := d.queryServer(language)
 	if err != nil {
-		return "", err
+		return "", nil, nil, err
 	}
+	if pool != nil {
+		qs, err = d.qsDDoc(pool, ddoc, rev, ddocBody, "updates/"+funcName)
+		return funcBody, nil, qs, err
+	}
+	rt, err = d.designJS(ddoc, "updates/"+funcName, ddocBody)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return funcBody, rt, nil, nil
+}
 
+// updateArgs returns the existing document, and the request object, to pass
+// to an update function.
+func (d *db) updateArgs(ctx context.Context, ddoc, funcName, docID string, call designCall, opts driver.Options) (any, map[string]any, error) {
 	existingDoc, _, err := d.getCoreDoc(ctx, d.db, docID, revision{}, false, false)
 	if err != nil && internal.HTTPStatus(err) != http.StatusNotFound {
-		return "", err
+		return nil, nil, err
 	}
 
 	var existingDocMap map[string]any
@@ -78,31 +191,14 @@ func (d *db) Update(ctx context.Context, ddoc, funcName, docID string, doc any,
 	ddocParts := strings.SplitN(strings.TrimPrefix(ddoc, "_design/"), "/", 2)
 	ddocName := ddocParts[0]
 
-	bodyBytes, _ := json.Marshal(doc)
-	req := map[string]any{
-		"id":     docID,
-		"body":   string(bodyBytes),
-		"uuid":   uuid.NewString(),
-		"method": http.MethodPut,
-		"userCtx": map[string]any{
-			"name":  nil,
-			"roles": []string{"_admin"},
-			"db":    d.name,
-		},
-		"secObj": map[string]any{
-			"admins":  map[string]any{"names": []string{}, "roles": []string{}},
-			"members": map[string]any{"names": []string{}, "roles": []string{}},
-		},
-		"path": []string{d.name, "_design", ddocName, "_update", funcName, docID},
-	}
-	updatedDoc, _, err := updateFunc(ctx, existingDocMap, req)
-	if err != nil {
-		return "", err
-	}
-
-	if updatedDoc == nil {
-		return "", nil
-	}
+	path := []string{d.name, "_design", ddocName, "_update", funcName, docID}
+	req := d.designRequest(call, path, docID, designQuery(opts))
+	return existingDocMap, req, nil
+}
 
-	return d.Put(ctx, docID, updatedDoc, opts)
+// updateCall returns the request to pass to an update function, with doc
+// encoded as the JSON body.
+func updateCall(doc any) designCall {
+	body, _ := json.Marshal(doc)
+	return designCall{method: http.MethodPut, body: string(body)}
 }
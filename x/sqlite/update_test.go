@@ -16,10 +16,12 @@ package sqlite
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"regexp"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"gitlab.com/flimzy/testy"
 
 	"github.com/go-kivik/kivik/v4"
@@ -66,6 +68,26 @@ func TestDBUpdate(t *testing.T) {
 		}
 	})
 
+	tests.Add("update function requires a module", func(t *testing.T) any {
+		d := newDB(t)
+		d.tPut("_design/myddoc", map[string]any{
+			"lib": map[string]any{
+				"mark": `exports.mark = function(doc) { doc.updated = true; return doc; };`,
+			},
+			"updates": map[string]any{
+				"myfunc": `function(doc, req) { return [require('lib/mark').mark(doc), "OK"]; }`,
+			},
+		})
+		d.tPut("foo", map[string]any{"_id": "foo"})
+		return test{
+			db:       d,
+			ddoc:     "_design/myddoc",
+			funcName: "myfunc",
+			docID:    "foo",
+			wantRev:  `^2-`,
+		}
+	})
+
 	tests.Add("update function returns null doc", func(t *testing.T) any {
 		d := newDB(t)
 		d.tPut("_design/myddoc", map[string]any{
@@ -174,3 +196,137 @@ func TestDBUpdate(t *testing.T) {
 		}
 	})
 }
+
+func TestDBUpdateWithResponse(t *testing.T) {
+	t.Parallel()
+	type test struct {
+		db         *testDB
+		funcName   string
+		docID      string
+		doc        any
+		wantRev    string
+		wantCode   int
+		wantHeader http.Header
+		wantBody   string
+		wantStatus int
+		wantErr    string
+	}
+
+	newUpdateDB := func(t *testing.T) *testDB {
+		d := newDB(t)
+		d.tPut("_design/myddoc", map[string]any{
+			"updates": map[string]any{
+				"bump": `function(doc, req) {
+					doc.count = (doc.count || 0) + JSON.parse(req.body).by;
+					return [doc, "count is " + doc.count];
+				}`,
+				"noop": `function(doc, req) {
+					return [null, {code: 202, headers: {"Content-Type": "text/plain", "X-Foo": "bar"}, body: "unchanged " + req.id}];
+				}`,
+				"json": `function(doc, req) { return [doc, {json: {id: doc._id}}]; }`,
+				"bad":  `function(doc, req) { return [doc, 42]; }`,
+			},
+		})
+		d.tPut("foo", map[string]any{"count": 1})
+		return d
+	}
+
+	tests := testy.NewTable()
+	tests.Add("update function not found", test{
+		funcName:   "myfunc",
+		docID:      "foo",
+		wantStatus: http.StatusNotFound,
+		wantErr:    "missing update function myfunc on _design/myddoc",
+	})
+	tests.Add("string response", func(t *testing.T) any {
+		return test{
+			db:       newUpdateDB(t),
+			funcName: "bump",
+			docID:    "foo",
+			doc:      map[string]any{"by": 2},
+			wantRev:  `^2-`,
+			wantCode: http.StatusCreated,
+			wantHeader: http.Header{
+				"Content-Type": {"text/html; charset=utf-8"},
+				"X-Couch-Id":   {"foo"},
+			},
+			wantBody: "count is 3",
+		}
+	})
+	tests.Add("response object without document", func(t *testing.T) any {
+		return test{
+			db:       newUpdateDB(t),
+			funcName: "noop",
+			docID:    "foo",
+			wantRev:  `^$`,
+			wantCode: http.StatusAccepted,
+			wantHeader: http.Header{
+				"Content-Type": {"text/plain"},
+				"X-Foo":        {"bar"},
+			},
+			wantBody: "unchanged foo",
+		}
+	})
+	tests.Add("json response", func(t *testing.T) any {
+		return test{
+			db:       newUpdateDB(t),
+			funcName: "json",
+			docID:    "foo",
+			wantRev:  `^2-`,
+			wantCode: http.StatusCreated,
+			wantHeader: http.Header{
+				"Content-Type": {"application/json"},
+				"X-Couch-Id":   {"foo"},
+			},
+			wantBody: `{"id":"foo"}`,
+		}
+	})
+	tests.Add("invalid response", func(t *testing.T) any {
+		return test{
+			db:         newUpdateDB(t),
+			funcName:   "bad",
+			docID:      "foo",
+			wantStatus: http.StatusInternalServerError,
+			wantErr:    "update function returned invalid response",
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		t.Parallel()
+		dbc := tt.db
+		if dbc == nil {
+			dbc = newDB(t)
+		}
+		resp, err := dbc.UpdateWithResponse(context.Background(), "myddoc", tt.funcName, tt.docID, tt.doc, mock.NilOption)
+		if !testy.ErrorMatchesRE(tt.wantErr, err) {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if status := kivik.HTTPStatus(err); status != tt.wantStatus {
+			t.Errorf("Unexpected status: %d", status)
+		}
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		if !regexp.MustCompile(tt.wantRev).MatchString(resp.Rev) {
+			t.Errorf("Unexpected rev: %s, want /%s/", resp.Rev, tt.wantRev)
+		}
+		if resp.StatusCode != tt.wantCode {
+			t.Errorf("Unexpected status code: %d", resp.StatusCode)
+		}
+		if resp.Rev != "" && resp.Header.Get("X-Couch-Update-NewRev") != resp.Rev {
+			t.Errorf("Unexpected X-Couch-Update-NewRev header: %s", resp.Header.Get("X-Couch-Update-NewRev"))
+		}
+		resp.Header.Del("X-Couch-Update-NewRev")
+		if d := cmp.Diff(tt.wantHeader, resp.Header); d != "" {
+			t.Errorf("Unexpected headers (-want +got):\n%s", d)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != tt.wantBody {
+			t.Errorf("Unexpected body: %s", body)
+		}
+	})
+}
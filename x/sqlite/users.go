@@ -0,0 +1,58 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/users"
+)
+
+// prepareUserDoc validates user documents stored in the _users database, and
+// replaces any plaintext password with a PBKDF2 hash, as CouchDB does. Docs
+// in other databases, and design and local docs, are returned unaltered.
+func (d *db) prepareUserDoc(docID string, doc any) (any, error) {
+	if d.name != users.DBName {
+		return doc, nil
+	}
+	tmpJSON, err := json.Marshal(doc)
+	if err != nil {
+		return nil, &internal.Error{Status: http.StatusBadRequest, Err: err}
+	}
+	var m map[string]any
+	if err := json.Unmarshal(tmpJSON, &m); err != nil {
+		return nil, &internal.Error{Status: http.StatusBadRequest, Err: err}
+	}
+	if docID == "" {
+		docID, _ = m["_id"].(string)
+	}
+	if strings.HasPrefix(docID, "_design/") || strings.HasPrefix(docID, "_local/") {
+		return doc, nil
+	}
+	if _, ok := m["_id"]; !ok && docID != "" {
+		m["_id"] = docID
+	}
+	if err := users.ValidateDoc(m); err != nil {
+		return nil, err
+	}
+	if deleted, _ := m["_deleted"].(bool); deleted {
+		return m, nil
+	}
+	if err := users.HashDocPassword(m, 0); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,117 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/int/mock"
+	"github.com/go-kivik/kivik/v4/users"
+)
+
+func newUsersDB(t *testing.T) DB {
+	t.Helper()
+	c := testClient(t)
+	if err := c.CreateDB(context.Background(), users.DBName, mock.NilOption); err != nil {
+		t.Fatal(err)
+	}
+	d, err := c.DB(users.DBName, mock.NilOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.(DB)
+}
+
+func TestUsersDB(t *testing.T) {
+	t.Parallel()
+	t.Run("invalid user doc", func(t *testing.T) {
+		t.Parallel()
+		d := newUsersDB(t)
+		_, err := d.Put(context.Background(), "org.couchdb.user:bob", map[string]any{
+			"name":  "bob",
+			"type":  "user",
+			"roles": []string{"_admin"},
+		}, mock.NilOption)
+		if d := internal.StatusErrorDiff("No system roles (starting with underscore) in users db.", http.StatusForbidden, err); d != "" {
+			t.Error(d)
+		}
+	})
+	t.Run("design doc is not validated", func(t *testing.T) {
+		t.Parallel()
+		d := newUsersDB(t)
+		_, err := d.Put(context.Background(), "_design/foo", map[string]any{"foo": "bar"}, mock.NilOption)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("password is hashed", func(t *testing.T) {
+		t.Parallel()
+		d := newUsersDB(t)
+		_, err := d.Put(context.Background(), "org.couchdb.user:bob", map[string]any{
+			"name":     "bob",
+			"type":     "user",
+			"roles":    []string{},
+			"password": "abc",
+		}, mock.NilOption)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := d.Get(context.Background(), "org.couchdb.user:bob", mock.NilOption)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer doc.Body.Close()
+		var user users.User
+		if err := json.NewDecoder(doc.Body).Decode(&user); err != nil {
+			t.Fatal(err)
+		}
+		if user.Password != "" {
+			t.Errorf("plaintext password stored")
+		}
+		if !user.Verify("abc") {
+			t.Errorf("stored password hash does not verify")
+		}
+	})
+	t.Run("CreateDoc hashes password", func(t *testing.T) {
+		t.Parallel()
+		d := newUsersDB(t)
+		docID, _, err := d.CreateDoc(context.Background(), map[string]any{
+			"_id":      "org.couchdb.user:alice",
+			"name":     "alice",
+			"type":     "user",
+			"roles":    []string{},
+			"password": "xyz",
+		}, mock.NilOption)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := d.Get(context.Background(), docID, mock.NilOption)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer doc.Body.Close()
+		var user users.User
+		if err := json.NewDecoder(doc.Body).Decode(&user); err != nil {
+			t.Fatal(err)
+		}
+		if !user.Verify("xyz") {
+			t.Errorf("stored password hash does not verify")
+		}
+	})
+}
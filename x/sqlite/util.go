@@ -13,6 +13,7 @@
 package sqlite
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
 	"database/sql"
@@ -162,8 +163,10 @@ func (d *db) createRev(ctx context.Context, tx *sql.Tx, data *docData, curRev re
 	if err := d.createDocAttachments(ctx, data, tx, r, &curRev); err != nil {
 		return r, err
 	}
-	err = d.updateDesignDoc(ctx, tx, r, curRev, data)
-	return r, err
+	if err := d.updateDesignDoc(ctx, tx, r, curRev, data); err != nil {
+		return r, err
+	}
+	return r, d.stemRevs(ctx, tx, data.ID, r)
 }
 
 func (d *db) createDocAttachments(ctx context.Context, data *docData, tx *sql.Tx, r revision, curRev *revision) error {
@@ -183,69 +186,129 @@ func (d *db) createDocAttachments(ctx context.Context, data *docData, tx *sql.Tx
 
 		var pk int
 		if att.Stub {
-			if curRev == nil {
-				return &internal.Error{Status: http.StatusPreconditionFailed, Message: fmt.Sprintf("invalid attachment stub in %s for %s", data.ID, filename)}
-			}
-			stubStmt, err := stmts.prepare(ctx, tx, d.query(`
-				INSERT INTO {{ .AttachmentsBridge }} (pk, id, rev, rev_id)
-				SELECT att.pk, $1, $2, $3
-				FROM {{ .Attachments }} AS att
-				JOIN {{ .AttachmentsBridge }} AS bridge ON att.pk = bridge.pk
-				WHERE bridge.id = $1
-					AND bridge.rev = $4
-					AND bridge.rev_id = $5
-					AND att.filename = $6
-				RETURNING pk
-			`))
+			var err error
+			pk, err = d.reuseAttachment(ctx, tx, stmts, data.ID, filename, att, r, curRev)
 			if err != nil {
 				return err
 			}
-			err = stubStmt.QueryRowContext(ctx, data.ID, r.rev, r.id, curRev.rev, curRev.id, filename).Scan(&pk)
-			switch {
-			case errors.Is(err, sql.ErrNoRows):
-				return &internal.Error{Status: http.StatusPreconditionFailed, Message: fmt.Sprintf("invalid attachment stub in %s for %s", data.ID, filename)}
-			case err != nil:
-				return err
-			}
 		} else {
-			if err := att.calculate(filename); err != nil {
+			if err := d.storeAttachment(ctx, tx, stmts, filename, &att); err != nil {
 				return err
 			}
-			contentType := att.ContentType
-			if contentType == "" {
-				contentType = "application/octet-stream"
-			}
 
 			attStmt, err := stmts.prepare(ctx, tx, d.query(`
-				INSERT INTO {{ .Attachments }} (rev_pos, filename, content_type, length, digest, data)
-				VALUES ($1, $2, $3, $4, $5, $6)
+				INSERT INTO {{ .Attachments }} (rev_pos, filename, content_type, length, digest)
+				VALUES ($1, $2, $3, $4, $5)
 				RETURNING pk
 			`))
 			if err != nil {
 				return err
 			}
 
-			err = attStmt.QueryRowContext(ctx, r.rev, filename, contentType, att.Length, att.Digest, att.Content).Scan(&pk)
+			err = attStmt.QueryRowContext(ctx, r.rev, filename, att.contentType(), att.Length, att.Digest).Scan(&pk)
 			if err != nil {
 				return err
 			}
+		}
 
-			bridgeStmt, err := stmts.prepare(ctx, tx, d.query(`
-				INSERT INTO {{ .AttachmentsBridge }} (pk, id, rev, rev_id)
-				VALUES ($1, $2, $3, $4)
-			`))
-			if err != nil {
-				return err
-			}
-			_, err = bridgeStmt.ExecContext(ctx, pk, data.ID, r.rev, r.id)
-			if err != nil {
-				return err
-			}
+		bridgeStmt, err := stmts.prepare(ctx, tx, d.query(`
+			INSERT INTO {{ .AttachmentsBridge }} (pk, id, rev, rev_id)
+			VALUES ($1, $2, $3, $4)
+		`))
+		if err != nil {
+			return err
+		}
+		_, err = bridgeStmt.ExecContext(ctx, pk, data.ID, r.rev, r.id)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// storeAttachment stores the content of an inline attachment, if it is not
+// already stored, and calculates its digest and length.
+func (d *db) storeAttachment(ctx context.Context, tx *sql.Tx, stmts stmtCache, filename string, att *attachment) error {
+	if att.stored {
+		return nil
+	}
+	if err := att.calculate(filename); err != nil {
+		return err
+	}
+	existsStmt, err := stmts.prepare(ctx, tx, d.query(`
+		SELECT EXISTS (SELECT 1 FROM {{ .AttachmentData }} WHERE digest = $1)
+	`))
+	if err != nil {
+		return err
+	}
+	var exists bool
+	if err := existsStmt.QueryRowContext(ctx, att.Digest).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return d.matchAttachmentData(ctx, tx, att.Digest, bytes.NewReader(att.Content))
+	}
+	_, _, err = d.storeAttachmentData(ctx, tx, bytes.NewReader(att.Content), att.contentType())
+	return err
+}
+
+// reuseAttachment returns the pk of the attachment to which a stub refers.
+// This is the attachment of the same name in curRev, if any. Failing that, if
+// the stub includes a digest, a new attachment is created for existing content
+// with the same digest, as happens when a replicator omits the content of an
+// attachment the target already holds, under a revision unknown to the
+// target.
+func (d *db) reuseAttachment(ctx context.Context, tx *sql.Tx, stmts stmtCache, docID, filename string, att attachment, r revision, curRev *revision) (int, error) {
+	errInvalidStub := &internal.Error{Status: http.StatusPreconditionFailed, Message: fmt.Sprintf("invalid attachment stub in %s for %s", docID, filename)}
+	var pk int
+	if curRev != nil {
+		stubStmt, err := stmts.prepare(ctx, tx, d.query(`
+			SELECT att.pk
+			FROM {{ .Attachments }} AS att
+			JOIN {{ .AttachmentsBridge }} AS bridge ON att.pk = bridge.pk
+			WHERE bridge.id = $1
+				AND bridge.rev = $2
+				AND bridge.rev_id = $3
+				AND att.filename = $4
+		`))
+		if err != nil {
+			return 0, err
+		}
+		err = stubStmt.QueryRowContext(ctx, docID, curRev.rev, curRev.id, filename).Scan(&pk)
+		if err == nil {
+			return pk, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+	}
+	if att.Digest.IsZero() {
+		return 0, errInvalidStub
+	}
+	revPos := att.RevPos
+	if revPos == 0 {
+		revPos = r.rev
+	}
+	digestStmt, err := stmts.prepare(ctx, tx, d.query(`
+		INSERT INTO {{ .Attachments }} (rev_pos, filename, content_type, length, digest)
+		SELECT $1, $2, $3, length, digest
+		FROM {{ .AttachmentData }}
+		WHERE digest = $4
+		RETURNING pk
+	`))
+	if err != nil {
+		return 0, err
+	}
+	err = digestStmt.QueryRowContext(ctx, revPos, filename, att.contentType(), att.Digest).Scan(&pk)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, errInvalidStub
+	case err != nil:
+		return 0, err
+	}
+	return pk, nil
+}
+
 func (d *db) lastSeq(ctx context.Context) (uint64, error) {
 	var lastSeq uint64
 	err := d.db.QueryRowContext(ctx, d.query(`
@@ -73,13 +73,14 @@ func (d *db) runValidation(ctx context.Context, tx *sql.Tx, data *docData, curRe
 // design document leaf revisions.
 func (d *db) getValidateFuncs(ctx context.Context, tx *sql.Tx) ([]js.ValidateFunc, error) {
 	rows, err := tx.QueryContext(ctx, d.query(`
-		SELECT design.func_body
+		SELECT design.id, design.rev, design.rev_id, design.language, design.func_body, leaves.doc
 		FROM {{ .Design }} AS design
 		JOIN (
 			SELECT
 				rev.id,
 				rev.rev,
-				rev.rev_id
+				rev.rev_id,
+				doc.doc
 			FROM {{ .Revs }} AS rev
 			LEFT JOIN {{ .Revs }} AS child
 				ON child.id = rev.id
@@ -101,11 +102,39 @@ func (d *db) getValidateFuncs(ctx context.Context, tx *sql.Tx) ([]js.ValidateFun
 
 	var funcs []js.ValidateFunc
 	for rows.Next() {
-		var funcBody string
-		if err := rows.Scan(&funcBody); err != nil {
+		var (
+			ddocID, language, funcBody string
+			rev                        revision
+			ddocBody                   []byte
+		)
+		if err := rows.Scan(&ddocID, &rev.rev, &rev.id, &language, &funcBody, &ddocBody); err != nil {
 			return nil, err
 		}
-		fn, err := d.js.Validate(funcBody)
+		if language == GoLanguage {
+			fn, err := d.goFuncs.validate(funcBody)
+			if err != nil {
+				return nil, err
+			}
+			funcs = append(funcs, fn)
+			continue
+		}
+		pool, err := d.queryServer(language)
+		if err != nil {
+			return nil, err
+		}
+		if pool != nil {
+			qs, err := d.qsDDoc(pool, ddocID, rev, ddocBody, "validate_doc_update")
+			if err != nil {
+				return nil, err
+			}
+			funcs = append(funcs, qs.Validate())
+			continue
+		}
+		rt, err := d.designJS(ddocID, "validate_doc_update", ddocBody)
+		if err != nil {
+			return nil, err
+		}
+		fn, err := rt.Validate(funcBody)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,132 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package sqlite
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-kivik/kivik/v4/driver"
+	internal "github.com/go-kivik/kivik/v4/int/errors"
+	"github.com/go-kivik/kivik/v4/x/options"
+)
+
+var _ driver.ViewIndexer = (*db)(nil)
+
+type viewFunc struct {
+	funcType, name, body string
+	lastSeq              int64
+}
+
+// designViews returns the current revision of the named design document, its
+// language, and its map and reduce functions.
+func (d *db) designViews(ctx context.Context, ddoc string) (revision, string, []viewFunc, error) {
+	rev, err := d.winningRev(ctx, d.db, "_design/"+ddoc)
+	if err != nil {
+		return revision{}, "", nil, err
+	}
+	rows, err := d.db.QueryContext(ctx, d.query(`
+		SELECT func_type, func_name, func_body, language, COALESCE(last_seq, 0)
+		FROM {{ .Design }}
+		WHERE id = $1 AND rev = $2 AND rev_id = $3
+			AND func_type IN ('map', 'reduce')
+		ORDER BY func_name, func_type
+	`), "_design/"+ddoc, rev.rev, rev.id)
+	if err != nil {
+		return revision{}, "", nil, d.errDatabaseNotFound(err)
+	}
+	defer rows.Close()
+
+	language := "javascript"
+	var funcs []viewFunc
+	for rows.Next() {
+		var f viewFunc
+		if err := rows.Scan(&f.funcType, &f.name, &f.body, &language, &f.lastSeq); err != nil {
+			return revision{}, "", nil, err
+		}
+		funcs = append(funcs, f)
+	}
+	return rev, language, funcs, rows.Err()
+}
+
+// DesignDocInfo returns the view index info for the named design document.
+// The index update sequence is that of the least up-to-date view, and the
+// signature is derived from the view function definitions.
+func (d *db) DesignDocInfo(ctx context.Context, ddoc string) (*driver.DesignDocInfo, error) {
+	rev, language, funcs, err := d.designViews(ctx, ddoc)
+	if err != nil {
+		return nil, err
+	}
+	purgeSeq, err := d.metadataInt(ctx, d.db, "purge_seq", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &driver.DesignDocInfo{
+		Name: ddoc,
+		ViewIndex: driver.ViewIndexInfo{
+			Language: language,
+			PurgeSeq: int64(purgeSeq),
+		},
+	}
+	signature := make([]string, 0, len(funcs))
+	updateSeq := int64(-1)
+	for _, f := range funcs {
+		signature = append(signature, f.funcType+":"+f.name+":"+f.body)
+		if f.funcType != "map" {
+			continue
+		}
+		if updateSeq < 0 || f.lastSeq < updateSeq {
+			updateSeq = f.lastSeq
+		}
+		var size int64
+		if err := d.db.QueryRowContext(ctx, d.ddocQuery(ddoc, f.name, rev.String(), `
+			SELECT COALESCE(SUM(LENGTH(key) + COALESCE(LENGTH(value), 0)), 0)
+			FROM {{ .Map }}
+		`)).Scan(&size); err != nil {
+			return nil, err
+		}
+		info.ViewIndex.Sizes.Active += size
+	}
+	if updateSeq > 0 {
+		info.ViewIndex.UpdateSeq = updateSeq
+	}
+	info.ViewIndex.Sizes.External = info.ViewIndex.Sizes.Active
+	info.ViewIndex.Sizes.File = info.ViewIndex.Sizes.Active
+	info.ViewIndex.Signature = md5sumString(strings.Join(signature, "\n"))
+	return info, nil
+}
+
+// UpdateIndex brings all views of the design document up to date. Unlike
+// CouchDB, the update completes before UpdateIndex returns.
+func (d *db) UpdateIndex(ctx context.Context, ddoc string) error {
+	_, _, funcs, err := d.designViews(ctx, ddoc)
+	if err != nil {
+		return err
+	}
+	var updated bool
+	for _, f := range funcs {
+		if f.funcType != "map" {
+			continue
+		}
+		if _, err := d.updateIndex(ctx, ddoc, f.name, options.UpdateModeTrue); err != nil {
+			return err
+		}
+		updated = true
+	}
+	if !updated {
+		return &internal.Error{Status: http.StatusNotFound, Message: "design document has no views"}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+//go:build !js
+
+package sqlite
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"gitlab.com/flimzy/testy"
+
+	"github.com/go-kivik/kivik/v4"
+)
+
+func TestDBDesignDocInfo(t *testing.T) {
+	t.Parallel()
+	type test struct {
+		db            *testDB
+		ddoc          string
+		updateIndex   bool
+		wantUpdateSeq int64
+		wantSize      bool
+		wantErr       string
+		wantStatus    int
+	}
+
+	tests := testy.NewTable()
+	tests.Add("missing design doc", test{
+		ddoc:       "foo",
+		wantErr:    "missing",
+		wantStatus: http.StatusNotFound,
+	})
+	tests.Add("not yet indexed", func(t *testing.T) any {
+		d := newDB(t)
+		_ = d.tPut("_design/foo", map[string]any{
+			"views": map[string]any{
+				"bar": map[string]string{
+					"map": `function(doc) { emit(doc._id, null); }`,
+				},
+			},
+		})
+		_ = d.tPut("doc1", map[string]string{"foo": "bar"})
+
+		return test{
+			db:   d,
+			ddoc: "foo",
+		}
+	})
+	tests.Add("indexed", func(t *testing.T) any {
+		d := newDB(t)
+		_ = d.tPut("_design/foo", map[string]any{
+			"views": map[string]any{
+				"bar": map[string]string{
+					"map": `function(doc) { emit(doc._id, null); }`,
+				},
+				"baz": map[string]string{
+					"map": `function(doc) { emit(doc._id, 1); }`,
+				},
+			},
+		})
+		_ = d.tPut("doc1", map[string]string{"foo": "bar"})
+		_ = d.tPut("doc2", map[string]string{"foo": "baz"})
+
+		return test{
+			db:            d,
+			ddoc:          "foo",
+			updateIndex:   true,
+			wantUpdateSeq: 3,
+			wantSize:      true,
+		}
+	})
+
+	tests.Run(t, func(t *testing.T, tt test) {
+		t.Parallel()
+		db := tt.db
+		if db == nil {
+			db = newDB(t)
+		}
+		if tt.updateIndex {
+			if err := db.UpdateIndex(context.Background(), tt.ddoc); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := db.DesignDocInfo(context.Background(), tt.ddoc)
+		if !testy.ErrorMatches(tt.wantErr, err) {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if status := kivik.HTTPStatus(err); status != tt.wantStatus {
+			t.Errorf("Unexpected status: %d", status)
+		}
+		if err != nil {
+			return
+		}
+		if got.Name != tt.ddoc {
+			t.Errorf("Unexpected name: %s", got.Name)
+		}
+		if got.ViewIndex.Language != "javascript" {
+			t.Errorf("Unexpected language: %s", got.ViewIndex.Language)
+		}
+		if got.ViewIndex.Signature == "" {
+			t.Error("Expected a signature")
+		}
+		if got.ViewIndex.UpdateSeq != tt.wantUpdateSeq {
+			t.Errorf("Unexpected update seq: %d", got.ViewIndex.UpdateSeq)
+		}
+		if hasSize := got.ViewIndex.Sizes.Active > 0; hasSize != tt.wantSize {
+			t.Errorf("Unexpected active size: %d", got.ViewIndex.Sizes.Active)
+		}
+	})
+}
+
+func TestDBDesignDocInfo_signature(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	ctx := context.Background()
+	views := map[string]any{
+		"bar": map[string]string{
+			"map": `function(doc) { emit(doc._id, null); }`,
+		},
+	}
+	rev := d.tPut("_design/foo", map[string]any{"views": views})
+	before, err := d.DesignDocInfo(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rev = d.tPut("_design/foo", map[string]any{"views": views, "extra": true}, kivik.Rev(rev))
+	unchanged, err := d.DesignDocInfo(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unchanged.ViewIndex.Signature != before.ViewIndex.Signature {
+		t.Error("Signature changed without a view change")
+	}
+
+	_ = d.tPut("_design/foo", map[string]any{"views": map[string]any{
+		"bar": map[string]string{
+			"map": `function(doc) { emit(doc._id, 1); }`,
+		},
+	}}, kivik.Rev(rev))
+	changed, err := d.DesignDocInfo(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.ViewIndex.Signature == before.ViewIndex.Signature {
+		t.Error("Signature unchanged after a view change")
+	}
+}
+
+func TestDBUpdateIndex(t *testing.T) {
+	t.Parallel()
+	d := newDB(t)
+	ctx := context.Background()
+	_ = d.tPut("_design/foo", map[string]any{
+		"validate_doc_update": `function(newDoc, oldDoc, userCtx, secObj) {}`,
+	})
+
+	err := d.UpdateIndex(ctx, "foo")
+	if status := kivik.HTTPStatus(err); status != http.StatusNotFound {
+		t.Errorf("Unexpected status for design doc without views: %d", status)
+	}
+
+	_ = d.tPut("_design/bar", map[string]any{
+		"views": map[string]any{
+			"baz": map[string]string{
+				"map": `function(doc) { emit(doc._id, null); }`,
+			},
+		},
+	})
+	_ = d.tPut("doc1", map[string]string{"foo": "bar"})
+	for i := 0; i < 2; i++ {
+		if err := d.UpdateIndex(ctx, "bar"); err != nil {
+			t.Fatal(err)
+		}
+		info, err := d.DesignDocInfo(ctx, "bar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The second update has no new changes, and must not lose its place.
+		if info.ViewIndex.UpdateSeq != 3 {
+			t.Errorf("Unexpected update seq after update %d: %d", i+1, info.ViewIndex.UpdateSeq)
+		}
+	}
+}
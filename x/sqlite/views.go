@@ -112,7 +112,7 @@ func (d *db) queryBuiltinView(
 					att.length AS length,
 					att.digest AS digest,
 					att.rev_pos AS rev_pos,
-					IIF($4, att.data, NULL) AS data,
+					IIF($4, {{ .AttachmentContent }}, NULL) AS data,
 					ROW_NUMBER() OVER (%[1]s) AS doc_number
 				FROM (
 					SELECT